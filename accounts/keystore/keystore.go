@@ -78,7 +78,17 @@ type unlocked struct {
 // NewKeyStore creates a keystore for the given directory.
 func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
 	keydir, _ = filepath.Abs(keydir)
-	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP}}
+	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP, KDFScrypt}}
+	ks.init(keydir)
+	return ks
+}
+
+// NewKeyStoreWithKDF creates a keystore for the given directory which encrypts
+// new and updated keys with the given key derivation function. The scrypt
+// parameters are only used if kdf is KDFScrypt.
+func NewKeyStoreWithKDF(keydir, kdf string, scryptN, scryptP int) *KeyStore {
+	keydir, _ = filepath.Abs(keydir)
+	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP, kdf}}
 	ks.init(keydir)
 	return ks
 }
@@ -439,13 +449,10 @@ func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		return store.encryptKey(key, newPassphrase)
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return EncryptKey(key, newPassphrase, StandardScryptN, StandardScryptP)
 }
 
 // Import stores the given encrypted JSON key into the key directory.
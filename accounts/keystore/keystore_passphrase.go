@@ -41,6 +41,7 @@ import (
 	"github.com/neatlab/neatio/crypto"
 	"github.com/neatlab/neatio/crypto/randentropy"
 	"github.com/pborman/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
@@ -48,6 +49,12 @@ import (
 const (
 	keyHeaderKDF = "scrypt"
 
+	// KDFScrypt selects scrypt as the key derivation function of a keystore.
+	KDFScrypt = keyHeaderKDF
+
+	// KDFArgon2id selects Argon2id as the key derivation function of a keystore.
+	KDFArgon2id = "argon2id"
+
 	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
 	// memory and taking approximately 1s CPU time on a modern processor.
 	StandardScryptN = 1 << 18
@@ -66,12 +73,31 @@ const (
 
 	scryptR     = 8
 	scryptDKLen = 32
+
+	// Argon2Time is the number of passes over the memory done by Argon2id.
+	Argon2Time = 3
+
+	// Argon2Memory is the memory in KiB used by Argon2id, 64MB in total.
+	Argon2Memory = 64 * 1024
+
+	// Argon2Threads is the degree of parallelism used by Argon2id.
+	Argon2Threads = 4
+
+	argon2DKLen = 32
+
+	// Bounds of the Argon2id parameters of a key file, which could otherwise
+	// make decrypting it take all the memory or time of the node.
+	maxArgon2Time    = 16
+	maxArgon2Memory  = 1024 * 1024 // 1GB
+	maxArgon2Threads = 255
+	maxArgon2DKLen   = 64
 )
 
 type keyStorePassphrase struct {
 	keysDirPath string
 	scryptN     int
 	scryptP     int
+	kdf         string
 }
 
 func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string) (*Key, error) {
@@ -93,18 +119,37 @@ func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string)
 
 // StoreKey generates a key, encrypts with 'auth' and stores in the given directory
 func StoreKey(dir, auth string, scryptN, scryptP int) (common.Address, error) {
-	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP}, crand.Reader, auth)
+	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP, KDFScrypt}, crand.Reader, auth)
+	return a.Address, err
+}
+
+// StoreKeyWithKDF generates a key, encrypts it with 'auth' using the given key
+// derivation function and stores it in the given directory.
+func StoreKeyWithKDF(dir, auth, kdf string, scryptN, scryptP int) (common.Address, error) {
+	if err := ValidateKDF(kdf); err != nil {
+		return common.Address{}, err
+	}
+	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP, kdf}, crand.Reader, auth)
 	return a.Address, err
 }
 
 func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) error {
-	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	keyjson, err := ks.encryptKey(key, auth)
 	if err != nil {
 		return err
 	}
 	return writeKeyFile(filename, keyjson)
 }
 
+// encryptKey encrypts the key with the key derivation function configured for
+// the store.
+func (ks keyStorePassphrase) encryptKey(key *Key, auth string) ([]byte, error) {
+	if ks.kdf == KDFArgon2id {
+		return EncryptKeyArgon2(key, auth)
+	}
+	return EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+}
+
 func (ks keyStorePassphrase) JoinPath(filename string) string {
 	if filepath.IsAbs(filename) {
 		return filename
@@ -113,25 +158,74 @@ func (ks keyStorePassphrase) JoinPath(filename string) string {
 	}
 }
 
+// ValidateKDF checks that the given name denotes a supported key derivation
+// function for newly encrypted keys.
+func ValidateKDF(kdf string) error {
+	switch kdf {
+	case KDFScrypt, KDFArgon2id:
+		return nil
+	}
+	return fmt.Errorf("Unsupported KDF: %s", kdf)
+}
+
 // EncryptKey encrypts a key using the specified scrypt parameters into a json
 // blob that can be decrypted later on.
 func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
-	authArray := []byte(auth)
 	salt := randentropy.GetEntropyCSPRNG(32)
-	derivedKey, err := scrypt.Key(authArray, salt, scryptN, scryptR, scryptP, scryptDKLen)
+	derivedKey, err := scrypt.Key([]byte(auth), salt, scryptN, scryptR, scryptP, scryptDKLen)
 	if err != nil {
 		return nil, err
 	}
-	encryptKey := derivedKey[:16]
-	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	scryptParamsJSON := make(map[string]interface{}, 5)
+	scryptParamsJSON["n"] = scryptN
+	scryptParamsJSON["r"] = scryptR
+	scryptParamsJSON["p"] = scryptP
+	scryptParamsJSON["dklen"] = scryptDKLen
+	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
 
-	iv := randentropy.GetEntropyCSPRNG(aes.BlockSize) // 16
-	cipherText, err := aesCTRXOR(encryptKey, keyBytes, iv)
+	return encryptKeyWithDerivedKey(key, derivedKey, keyHeaderKDF, scryptParamsJSON)
+}
+
+// EncryptKeyArgon2 encrypts a key using the Argon2id key derivation function
+// into a json blob that can be decrypted later on.
+func EncryptKeyArgon2(key *Key, auth string) ([]byte, error) {
+	salt := randentropy.GetEntropyCSPRNG(32)
+	derivedKey := argon2.IDKey([]byte(auth), salt, Argon2Time, Argon2Memory, Argon2Threads, argon2DKLen)
+
+	argon2ParamsJSON := make(map[string]interface{}, 5)
+	argon2ParamsJSON["t"] = Argon2Time
+	argon2ParamsJSON["m"] = Argon2Memory
+	argon2ParamsJSON["p"] = Argon2Threads
+	argon2ParamsJSON["dklen"] = argon2DKLen
+	argon2ParamsJSON["salt"] = hex.EncodeToString(salt)
+
+	return encryptKeyWithDerivedKey(key, derivedKey, KDFArgon2id, argon2ParamsJSON)
+}
+
+func encryptKeyWithDerivedKey(key *Key, derivedKey []byte, kdf string, kdfParams map[string]interface{}) ([]byte, error) {
+	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	cryptoStruct, err := encryptDataWithDerivedKey(keyBytes, derivedKey, kdf, kdfParams)
 	if err != nil {
 		return nil, err
 	}
-	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+	encryptedKeyJSONV3 := encryptedKeyJSONV3{
+		//hex.EncodeToString(key.Address[:]),
+		key.Address.String(),
+		cryptoStruct,
+		key.Id.String(),
+		version,
+	}
+	return json.Marshal(encryptedKeyJSONV3)
+}
 
+// EncryptDataV3 encrypts the data given as 'data' with the password 'auth'
+// using the specified scrypt parameters.
+func EncryptDataV3(data, auth []byte, scryptN, scryptP int) (CryptoJSON, error) {
+	salt := randentropy.GetEntropyCSPRNG(32)
+	derivedKey, err := scrypt.Key(auth, salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return CryptoJSON{}, err
+	}
 	scryptParamsJSON := make(map[string]interface{}, 5)
 	scryptParamsJSON["n"] = scryptN
 	scryptParamsJSON["r"] = scryptR
@@ -139,26 +233,31 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 	scryptParamsJSON["dklen"] = scryptDKLen
 	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
 
+	return encryptDataWithDerivedKey(data, derivedKey, keyHeaderKDF, scryptParamsJSON)
+}
+
+func encryptDataWithDerivedKey(data, derivedKey []byte, kdf string, kdfParams map[string]interface{}) (CryptoJSON, error) {
+	encryptKey := derivedKey[:16]
+
+	iv := randentropy.GetEntropyCSPRNG(aes.BlockSize) // 16
+	cipherText, err := aesCTRXOR(encryptKey, data, iv)
+	if err != nil {
+		return CryptoJSON{}, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
 	cipherParamsJSON := cipherparamsJSON{
 		IV: hex.EncodeToString(iv),
 	}
 
-	cryptoStruct := cryptoJSON{
+	return CryptoJSON{
 		Cipher:       "aes-128-ctr",
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
-		KDF:          keyHeaderKDF,
-		KDFParams:    scryptParamsJSON,
+		KDF:          kdf,
+		KDFParams:    kdfParams,
 		MAC:          hex.EncodeToString(mac),
-	}
-	encryptedKeyJSONV3 := encryptedKeyJSONV3{
-		//hex.EncodeToString(key.Address[:]),
-		key.Address.String(),
-		cryptoStruct,
-		key.Id.String(),
-		version,
-	}
-	return json.Marshal(encryptedKeyJSONV3)
+	}, nil
 }
 
 // DecryptKey decrypts a key from a json blob, returning the private key itself.
@@ -209,36 +308,44 @@ func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byt
 	}
 
 	keyId = uuid.Parse(keyProtected.Id)
-	mac, err := hex.DecodeString(keyProtected.Crypto.MAC)
+	plainText, err := DecryptDataV3(keyProtected.Crypto, auth)
 	if err != nil {
 		return nil, nil, err
 	}
+	return plainText, keyId, err
+}
+
+// DecryptDataV3 decrypts data encrypted by EncryptDataV3 or a version 3 key file.
+func DecryptDataV3(cryptoJson CryptoJSON, auth string) ([]byte, error) {
+	if cryptoJson.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("Cipher not supported: %v", cryptoJson.Cipher)
+	}
+	mac, err := hex.DecodeString(cryptoJson.MAC)
+	if err != nil {
+		return nil, err
+	}
 
-	iv, err := hex.DecodeString(keyProtected.Crypto.CipherParams.IV)
+	iv, err := hex.DecodeString(cryptoJson.CipherParams.IV)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	cipherText, err := hex.DecodeString(keyProtected.Crypto.CipherText)
+	cipherText, err := hex.DecodeString(cryptoJson.CipherText)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	derivedKey, err := getKDFKey(keyProtected.Crypto, auth)
+	derivedKey, err := getKDFKey(cryptoJson, auth)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
 	if !bytes.Equal(calculatedMAC, mac) {
-		return nil, nil, ErrDecrypt
+		return nil, ErrDecrypt
 	}
 
-	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
-	if err != nil {
-		return nil, nil, err
-	}
-	return plainText, keyId, err
+	return aesCTRXOR(derivedKey[:16], cipherText, iv)
 }
 
 func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byte, keyId []byte, err error) {
@@ -275,7 +382,7 @@ func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byt
 	return plainText, keyId, err
 }
 
-func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
+func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 	authArray := []byte(auth)
 	salt, err := hex.DecodeString(cryptoJSON.KDFParams["salt"].(string))
 	if err != nil {
@@ -289,6 +396,15 @@ func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
 		p := ensureInt(cryptoJSON.KDFParams["p"])
 		return scrypt.Key(authArray, salt, n, r, p, dkLen)
 
+	} else if cryptoJSON.KDF == KDFArgon2id {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if t < 1 || t > maxArgon2Time || m < 8*p || m > maxArgon2Memory || p < 1 || p > maxArgon2Threads || dkLen < argon2DKLen || dkLen > maxArgon2DKLen {
+			return nil, fmt.Errorf("Argon2id parameters out of range: t=%d, m=%d, p=%d, dklen=%d", t, m, p, dkLen)
+		}
+		return argon2.IDKey(authArray, salt, uint32(t), uint32(m), uint8(p), uint32(dkLen)), nil
+
 	} else if cryptoJSON.KDF == "pbkdf2" {
 		c := ensureInt(cryptoJSON.KDFParams["c"])
 		prf := cryptoJSON.KDFParams["prf"].(string)
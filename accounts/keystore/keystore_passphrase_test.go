@@ -62,6 +62,77 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 	}
 }
 
+// Tests that a key encrypted with Argon2id can be decrypted again.
+func TestKeyEncryptDecryptArgon2(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if keyjson, err = EncryptKeyArgon2(key, "foo"); err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	if _, err := DecryptKey(keyjson, "bar"); err != ErrDecrypt {
+		t.Errorf("json key decrypted with bad password: %v", err)
+	}
+	decrypted, err := DecryptKey(keyjson, "foo")
+	if err != nil {
+		t.Fatalf("json key failed to decrypt: %v", err)
+	}
+	if decrypted.Address != key.Address {
+		t.Errorf("key address mismatch: have %x, want %x", decrypted.Address, key.Address)
+	}
+}
+
+// Tests that Argon2id parameters taking too much memory or time are refused.
+func TestArgon2ParamsBounds(t *testing.T) {
+	params := func(time, memory, threads, dkLen int) CryptoJSON {
+		return CryptoJSON{
+			KDF: KDFArgon2id,
+			KDFParams: map[string]interface{}{
+				"t": time, "m": memory, "p": threads, "dklen": dkLen, "salt": "00",
+			},
+		}
+	}
+	for i, tt := range []CryptoJSON{
+		params(0, 1024, 1, 32),
+		params(maxArgon2Time+1, 1024, 1, 32),
+		params(1, maxArgon2Memory+1, 1, 32),
+		params(1, 4, 1, 32),
+		params(1, 1024, 0, 32),
+		params(1, 1024, 1, 16),
+		params(1, 1024, 1, maxArgon2DKLen+1),
+	} {
+		if _, err := getKDFKey(tt, "foo"); err == nil {
+			t.Errorf("test %d: parameters out of range accepted", i)
+		}
+	}
+	if _, err := getKDFKey(params(1, 1024, 1, 32), "foo"); err != nil {
+		t.Errorf("parameters in range refused: %v", err)
+	}
+}
+
+func TestEncryptDecryptData(t *testing.T) {
+	data := []byte("validator secrets")
+	cryptoJson, err := EncryptDataV3(data, []byte("foo"), veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatalf("failed to encrypt data: %v", err)
+	}
+	if _, err := DecryptDataV3(cryptoJson, "bar"); err != ErrDecrypt {
+		t.Errorf("data decrypted with bad password: %v", err)
+	}
+	decrypted, err := DecryptDataV3(cryptoJson, "foo")
+	if err != nil {
+		t.Fatalf("failed to decrypt data: %v", err)
+	}
+	if string(decrypted) != string(data) {
+		t.Errorf("data mismatch: have %q, want %q", decrypted, data)
+	}
+}
+
 func TestEncryptKey(t *testing.T) {
 	//privateKeyHex := "182e4cc598610e2e8fe3c23a9b3145c8500cb8bc1ec44d0faf4e7b3452ac5ca0"
 	//key, err := crypto.HexToECDSA(privateKeyHex)
@@ -0,0 +1,96 @@
+package accounts
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil/hdkeychain"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// bip39Words indexes the words of the English BIP-39 wordlist.
+var bip39Words = func() map[string]int {
+	words := strings.Fields(bip39EnglishWords)
+	index := make(map[string]int, len(words))
+	for i, word := range words {
+		index[word] = i
+	}
+	return index
+}()
+
+// ValidateMnemonic checks that a mnemonic sentence is made of 12 to 24 words of
+// the English BIP-39 wordlist, and that its checksum matches the entropy the
+// words encode.
+func ValidateMnemonic(mnemonic string) error {
+	words := strings.Fields(strings.ToLower(mnemonic))
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return fmt.Errorf("invalid mnemonic: %d words, want 12, 15, 18, 21 or 24", len(words))
+	}
+	// Each word encodes 11 bits: the entropy, followed by one checksum bit
+	// per 32 bits of entropy
+	bits := make([]byte, 0, len(words)*11)
+	for _, word := range words {
+		index, ok := bip39Words[word]
+		if !ok {
+			return fmt.Errorf("invalid mnemonic: unknown word %q", word)
+		}
+		for i := 10; i >= 0; i-- {
+			bits = append(bits, byte(index>>uint(i))&1)
+		}
+	}
+	checksumBits := len(bits) / 33
+	entropy := make([]byte, (len(bits)-checksumBits)/8)
+	for i := range entropy {
+		for _, bit := range bits[i*8 : i*8+8] {
+			entropy[i] = entropy[i]<<1 | bit
+		}
+	}
+	hash := sha256.Sum256(entropy)
+	for i, bit := range bits[len(entropy)*8:] {
+		if hash[i/8]>>uint(7-i%8)&1 != bit {
+			return fmt.Errorf("invalid mnemonic: checksum mismatch")
+		}
+	}
+	return nil
+}
+
+// NewSeedFromMnemonic creates the BIP-39 seed of a mnemonic sentence, after
+// validating it. The passphrase is the optional BIP-39 password protecting the
+// seed; a mnemonic backed up together with a passphrase cannot be restored
+// without it.
+func NewSeedFromMnemonic(mnemonic, passphrase string) ([]byte, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	sentence := strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+	return pbkdf2.Key([]byte(sentence), []byte("mnemonic"+passphrase), 2048, 64, sha512.New), nil
+}
+
+// DeriveKeyFromMnemonic derives the private key found at the given BIP-32
+// derivation path of the wallet described by a BIP-39 mnemonic.
+func DeriveKeyFromMnemonic(mnemonic, passphrase string, path DerivationPath) (*ecdsa.PrivateKey, error) {
+	seed, err := NewSeedFromMnemonic(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range path {
+		if key, err = key.Child(n); err != nil {
+			return nil, fmt.Errorf("failed to derive %v: %v", path, err)
+		}
+	}
+	priv, err := key.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	return priv.ToECDSA(), nil
+}
@@ -0,0 +1,56 @@
+package accounts
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// Tests that mnemonic seeds match the official BIP-39 test vectors.
+func TestNewSeedFromMnemonic(t *testing.T) {
+	tests := []struct {
+		mnemonic string
+		seed     string
+	}{
+		{
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+			"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		},
+		{
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+			"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+		},
+	}
+	for i, tt := range tests {
+		seed, err := NewSeedFromMnemonic(tt.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatalf("test %d: failed to create seed: %v", i, err)
+		}
+		if have := hex.EncodeToString(seed); have != tt.seed {
+			t.Errorf("test %d: seed mismatch: have %s, want %s", i, have, tt.seed)
+		}
+	}
+	if _, err := NewSeedFromMnemonic("abandon about", ""); err == nil {
+		t.Errorf("short mnemonic accepted")
+	}
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	tests := []struct {
+		mnemonic string
+		valid    bool
+	}{
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", true},
+		{"Legal Winner Thank Year Wave Sausage Worth Useful Legal Winner Thank Yellow", true},
+		{"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo vote", true},
+		// The last word carries the checksum
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", false},
+		{"legal winner thank year wave sausage worth useful legal winner thank year", false},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abut", false},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", false},
+	}
+	for i, tt := range tests {
+		if err := ValidateMnemonic(tt.mnemonic); (err == nil) != tt.valid {
+			t.Errorf("test %d: have error %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
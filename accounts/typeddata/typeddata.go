@@ -0,0 +1,371 @@
+// Package typeddata implements EIP-712 hashing of typed structured data.
+//
+// The encoding follows eth_signTypedData_v4, arrays of structs included. As
+// Neatio addresses are 32 bytes long, an "address" value fills its 32 byte
+// word without padding; 20 byte hex addresses are left padded for dapps that
+// were written for Ethereum.
+package typeddata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/common/math"
+	"github.com/neatlab/neatio/common/neataddr"
+	"github.com/neatlab/neatio/crypto"
+)
+
+// domainType is the name of the type describing the signing domain.
+const domainType = "EIP712Domain"
+
+// Type is a single field of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types maps struct type names to their fields.
+type Types map[string][]Type
+
+// TypedDataMessage is the JSON decoded value of a struct.
+type TypedDataMessage = map[string]interface{}
+
+// TypedDataDomain is the signing domain. Only the fields listed in the
+// EIP712Domain type take part in the domain separator.
+type TypedDataDomain struct {
+	Name              string   `json:"name"`
+	Version           string   `json:"version"`
+	ChainId           *ChainID `json:"chainId"`
+	VerifyingContract string   `json:"verifyingContract"`
+	Salt              string   `json:"salt"`
+}
+
+// ChainID is a chain id given as JSON number or as decimal or hex string,
+// dapps use all of them.
+type ChainID big.Int
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (id *ChainID) UnmarshalJSON(input []byte) error {
+	s := strings.Trim(string(input), `"`)
+	n, ok := math.ParseBig256(s)
+	if !ok {
+		return fmt.Errorf("invalid chain id %s", input)
+	}
+	*id = ChainID(*n)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (id *ChainID) MarshalJSON() ([]byte, error) {
+	return json.Marshal((*big.Int)(id))
+}
+
+// Map returns the domain as a message to be hashed.
+func (domain *TypedDataDomain) Map() TypedDataMessage {
+	m := TypedDataMessage{}
+	if domain.Name != "" {
+		m["name"] = domain.Name
+	}
+	if domain.Version != "" {
+		m["version"] = domain.Version
+	}
+	if domain.ChainId != nil {
+		m["chainId"] = (*big.Int)(domain.ChainId)
+	}
+	if domain.VerifyingContract != "" {
+		m["verifyingContract"] = domain.VerifyingContract
+	}
+	if domain.Salt != "" {
+		m["salt"] = domain.Salt
+	}
+	return m
+}
+
+// TypedData is the payload of eth_signTypedData_v4.
+type TypedData struct {
+	Types       Types            `json:"types"`
+	PrimaryType string           `json:"primaryType"`
+	Domain      TypedDataDomain  `json:"domain"`
+	Message     TypedDataMessage `json:"message"`
+}
+
+// Hash returns the digest to be signed:
+// keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message)).
+func (typedData *TypedData) Hash() ([]byte, error) {
+	if _, ok := typedData.Types[domainType]; !ok {
+		return nil, fmt.Errorf("missing %s type", domainType)
+	}
+	domainSeparator, err := typedData.HashStruct(domainType, typedData.Domain.Map())
+	if err != nil {
+		return nil, err
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256([]byte("\x19\x01"), domainSeparator, messageHash), nil
+}
+
+// HashStruct returns hashStruct(s) = keccak256(typeHash ‖ encodeData(s)).
+func (typedData *TypedData) HashStruct(primaryType string, data TypedDataMessage) ([]byte, error) {
+	encoded, err := typedData.EncodeData(primaryType, data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// TypeHash returns keccak256(encodeType(primaryType)).
+func (typedData *TypedData) TypeHash(primaryType string) []byte {
+	return crypto.Keccak256([]byte(typedData.EncodeType(primaryType)))
+}
+
+// Dependencies returns all struct types referenced by primaryType, itself included.
+func (typedData *TypedData) Dependencies(primaryType string, found []string) []string {
+	primaryType = strings.TrimSuffix(primaryType, "[]")
+	for _, f := range found {
+		if f == primaryType {
+			return found
+		}
+	}
+	if typedData.Types[primaryType] == nil {
+		return found
+	}
+	found = append(found, primaryType)
+	for _, field := range typedData.Types[primaryType] {
+		found = typedData.Dependencies(baseType(field.Type), found)
+	}
+	return found
+}
+
+// EncodeType returns the type signature, e.g.
+// Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (typedData *TypedData) EncodeType(primaryType string) string {
+	deps := typedData.Dependencies(primaryType, []string{})
+	if len(deps) > 0 {
+		rest := deps[1:]
+		sort.Strings(rest)
+		deps = append([]string{primaryType}, rest...)
+	}
+
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, field := range typedData.Types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(field.Type)
+			buffer.WriteString(" ")
+			buffer.WriteString(field.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.String()
+}
+
+// EncodeData returns typeHash ‖ enc(value1) ‖ enc(value2) ‖ ...
+func (typedData *TypedData) EncodeData(primaryType string, data TypedDataMessage) ([]byte, error) {
+	fields, ok := typedData.Types[primaryType]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", primaryType)
+	}
+	if len(fields) < len(data) {
+		return nil, fmt.Errorf("%s has %d fields but the value has %d", primaryType, len(fields), len(data))
+	}
+
+	buffer := bytes.Buffer{}
+	buffer.Write(typedData.TypeHash(primaryType))
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing value for field %s of %s", field.Name, primaryType)
+		}
+		encoded, err := typedData.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", primaryType, field.Name, err)
+		}
+		buffer.Write(encoded)
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeValue returns the 32 byte encoding of a single value of the given type.
+func (typedData *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	if strings.HasSuffix(typ, "]") {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected array for type %s, got %T", typ, value)
+		}
+		elemType := typ[:strings.LastIndex(typ, "[")]
+		if size := typ[len(elemType)+1 : len(typ)-1]; size != "" {
+			if n, err := strconv.Atoi(size); err != nil || n != len(items) {
+				return nil, fmt.Errorf("expected %s elements for type %s, got %d", size, typ, len(items))
+			}
+		}
+		var buffer bytes.Buffer
+		for _, item := range items {
+			encoded, err := typedData.encodeValue(elemType, item)
+			if err != nil {
+				return nil, err
+			}
+			buffer.Write(encoded)
+		}
+		return crypto.Keccak256(buffer.Bytes()), nil
+	}
+	if _, ok := typedData.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected object for type %s, got %T", typ, value)
+		}
+		encoded, err := typedData.EncodeData(typ, data)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(encoded), nil
+	}
+	return encodePrimitiveValue(typ, value)
+}
+
+var (
+	bytesTypeRegex   = regexp.MustCompile(`^bytes([0-9]+)$`)
+	integerTypeRegex = regexp.MustCompile(`^(u?)int([0-9]*)$`)
+)
+
+// encodePrimitiveValue returns the 32 byte encoding of an atomic or dynamic value.
+func encodePrimitiveValue(typ string, value interface{}) ([]byte, error) {
+	switch typ {
+	case "address":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string for address, got %T", value)
+		}
+		if addr, err := neataddr.Parse(s); err == nil {
+			return addr.Bytes(), nil
+		}
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != 20 {
+			return nil, fmt.Errorf("invalid address %q", s)
+		}
+		return common.LeftPadBytes(b, 32), nil
+	case "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		if b {
+			return math.PaddedBigBytes(common.Big1, 32), nil
+		}
+		return math.PaddedBigBytes(common.Big0, 32), nil
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return crypto.Keccak256([]byte(s)), nil
+	case "bytes":
+		b, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(b), nil
+	}
+	if match := bytesTypeRegex.FindStringSubmatch(typ); match != nil {
+		size, _ := strconv.Atoi(match[1])
+		if size < 1 || size > 32 {
+			return nil, fmt.Errorf("invalid type %s", typ)
+		}
+		b, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > size {
+			return nil, fmt.Errorf("%d bytes do not fit into %s", len(b), typ)
+		}
+		return common.RightPadBytes(b, 32), nil
+	}
+	if match := integerTypeRegex.FindStringSubmatch(typ); match != nil {
+		bits := 256
+		if match[2] != "" {
+			bits, _ = strconv.Atoi(match[2])
+		}
+		if bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("invalid type %s", typ)
+		}
+		n, err := parseInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		if match[1] == "u" {
+			if n.Sign() < 0 || n.BitLen() > bits {
+				return nil, fmt.Errorf("%v out of range for %s", n, typ)
+			}
+		} else {
+			limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+			if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, fmt.Errorf("%v out of range for %s", n, typ)
+			}
+		}
+		return math.PaddedBigBytes(math.U256(n), 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %s", typ)
+}
+
+func parseBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case hexutil.Bytes:
+		return v, nil
+	case string:
+		return hexutil.Decode(v)
+	}
+	return nil, fmt.Errorf("expected hex string for bytes, got %T", value)
+}
+
+func parseInteger(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return new(big.Int).Set(v), nil
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("%v is not an integer", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case json.Number:
+		n, ok := new(big.Int).SetString(string(v), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %s", v)
+		}
+		return n, nil
+	case string:
+		negative := strings.HasPrefix(v, "-")
+		n, ok := math.ParseBig256(strings.TrimPrefix(v, "-"))
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", v)
+		}
+		if negative {
+			n.Neg(n)
+		}
+		return n, nil
+	}
+	return nil, errors.New("expected number or numeric string for integer")
+}
+
+// baseType strips array suffixes from a type name.
+func baseType(typ string) string {
+	if i := strings.Index(typ, "["); i >= 0 {
+		return typ[:i]
+	}
+	return typ
+}
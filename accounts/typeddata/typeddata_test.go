@@ -0,0 +1,103 @@
+package typeddata
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/neatlab/neatio/common/hexutil"
+)
+
+// mailJSON is the example of the EIP-712 specification.
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestMailExample(t *testing.T) {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(mailJSON), &typedData); err != nil {
+		t.Fatal(err)
+	}
+
+	if have, want := typedData.EncodeType("Mail"), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Errorf("encodeType mismatch: have %s, want %s", have, want)
+	}
+	domainSeparator, err := typedData.HashStruct(domainType, typedData.Domain.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := hexutil.Encode(domainSeparator), "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"; have != want {
+		t.Errorf("domain separator mismatch: have %s, want %s", have, want)
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := hexutil.Encode(messageHash), "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"; have != want {
+		t.Errorf("message hash mismatch: have %s, want %s", have, want)
+	}
+	hash, err := typedData.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := hexutil.Encode(hash), "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; have != want {
+		t.Errorf("signing hash mismatch: have %s, want %s", have, want)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	var typedData TypedData
+	if err := json.Unmarshal([]byte(mailJSON), &typedData); err != nil {
+		t.Fatal(err)
+	}
+	tests := []TypedDataMessage{
+		{"from": typedData.Message["from"], "to": typedData.Message["to"]},
+		{"from": typedData.Message["from"], "to": "Bob", "contents": "Hello"},
+		{"from": map[string]interface{}{"name": "Cow", "wallet": "0x1234"}, "to": typedData.Message["to"], "contents": "Hello"},
+	}
+	for i, message := range tests {
+		if _, err := typedData.HashStruct("Mail", message); err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+
+	var arrays = TypedData{
+		Types: Types{"Group": {{Name: "members", Type: "uint8[2]"}}},
+	}
+	if _, err := arrays.HashStruct("Group", TypedDataMessage{"members": []interface{}{1.0, 2.0}}); err != nil {
+		t.Errorf("fixed array: %v", err)
+	}
+	if _, err := arrays.HashStruct("Group", TypedDataMessage{"members": []interface{}{1.0}}); err == nil {
+		t.Errorf("fixed array with wrong length: expected error")
+	}
+	if _, err := arrays.HashStruct("Group", TypedDataMessage{"members": []interface{}{256.0, 2.0}}); err == nil {
+		t.Errorf("uint8 overflow: expected error")
+	}
+}
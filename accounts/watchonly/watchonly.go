@@ -0,0 +1,108 @@
+// Package watchonly implements an account backend tracking addresses without
+// holding their keys, so balances and history of cold wallets can be followed
+// from an online node while signing happens elsewhere.
+package watchonly
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+
+	ethereum "github.com/neatlab/neatio"
+	"github.com/neatlab/neatio/accounts"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/event"
+)
+
+// Scheme is the protocol scheme prefixing account and wallet URLs.
+const Scheme = "watch"
+
+// ErrWatchOnly is returned for any signing request against a watch-only account.
+var ErrWatchOnly = errors.New("watch-only account, sign offline and broadcast the transaction")
+
+// Backend is an accounts.Backend serving a fixed set of watch-only addresses.
+type Backend struct {
+	wallets []accounts.Wallet
+}
+
+// NewBackend creates a watch-only backend for the given addresses.
+func NewBackend(addrs []common.Address) *Backend {
+	b := &Backend{wallets: make([]accounts.Wallet, 0, len(addrs))}
+	seen := make(map[common.Address]bool)
+	for _, addr := range addrs {
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		b.wallets = append(b.wallets, &wallet{account: accounts.Account{
+			Address: addr,
+			URL:     accounts.URL{Scheme: Scheme, Path: addr.String()},
+		}})
+	}
+	sort.Slice(b.wallets, func(i, j int) bool {
+		return b.wallets[i].URL().Cmp(b.wallets[j].URL()) < 0
+	})
+	return b
+}
+
+// Wallets implements accounts.Backend, returning one wallet per watched address.
+func (b *Backend) Wallets() []accounts.Wallet {
+	cpy := make([]accounts.Wallet, len(b.wallets))
+	copy(cpy, b.wallets)
+	return cpy
+}
+
+// Subscribe implements accounts.Backend. The set of watched addresses is fixed
+// for the lifetime of the backend, so no events are ever fired.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// wallet is a single watched address, refusing every signing request.
+type wallet struct {
+	account accounts.Account
+}
+
+func (w *wallet) URL() accounts.URL { return w.account.URL }
+
+func (w *wallet) Status() (string, error) { return "Watch-only", nil }
+
+func (w *wallet) Open(passphrase string) error { return nil }
+
+func (w *wallet) Close() error { return nil }
+
+func (w *wallet) Accounts() []accounts.Account { return []accounts.Account{w.account} }
+
+func (w *wallet) Contains(account accounts.Account) bool {
+	return account.Address == w.account.Address && (account.URL == (accounts.URL{}) || account.URL == w.account.URL)
+}
+
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+func (w *wallet) SelfDerive(base accounts.DerivationPath, chain ethereum.ChainStateReader) {}
+
+func (w *wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, ErrWatchOnly
+}
+
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, ErrWatchOnly
+}
+
+func (w *wallet) SignTxWithAddress(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, ErrWatchOnly
+}
+
+func (w *wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, ErrWatchOnly
+}
+
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, ErrWatchOnly
+}
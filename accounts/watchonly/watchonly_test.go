@@ -0,0 +1,42 @@
+package watchonly
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/accounts"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/types"
+)
+
+// Tests that watched addresses are listed once and refuse to sign anything.
+func TestWatchOnlyBackend(t *testing.T) {
+	a := common.StringToAddress("NEATukPbL3mCHbeuAnwCRftpUzVs2C7x")
+	b := common.StringToAddress("NEAThpGsrRdtzHuvT4N6mUvsJz9DqU2d")
+
+	backend := NewBackend([]common.Address{b, a, b})
+	wallets := backend.Wallets()
+	if len(wallets) != 2 {
+		t.Fatalf("wallet count mismatch: have %d, want 2", len(wallets))
+	}
+	if wallets[0].URL().Cmp(wallets[1].URL()) >= 0 {
+		t.Errorf("wallets not sorted by URL: %v, %v", wallets[0].URL(), wallets[1].URL())
+	}
+	account := accounts.Account{Address: a}
+	var wallet accounts.Wallet
+	for _, w := range wallets {
+		if w.Contains(account) {
+			wallet = w
+		}
+	}
+	if wallet == nil {
+		t.Fatalf("watched address %s not found", a.String())
+	}
+	if _, err := wallet.SignHash(account, make([]byte, 32)); err != ErrWatchOnly {
+		t.Errorf("SignHash error mismatch: have %v, want %v", err, ErrWatchOnly)
+	}
+	tx := types.NewTransaction(0, b, big.NewInt(1), 21000, big.NewInt(1), nil)
+	if _, err := wallet.SignTxWithPassphrase(account, "", tx, big.NewInt(1)); err != ErrWatchOnly {
+		t.Errorf("SignTxWithPassphrase error mismatch: have %v, want %v", err, ErrWatchOnly)
+	}
+}
@@ -17,8 +17,12 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"strings"
 
 	"github.com/neatlab/neatio/params"
 
@@ -32,6 +36,34 @@ import (
 )
 
 var (
+	importFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: `Format of the key file to import ("hex", "keystore" or "mnemonic")`,
+		Value: "hex",
+	}
+	exportFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: `Format of the exported key ("keystore" or "hex")`,
+		Value: "keystore",
+	}
+	expectedAddressFlag = cli.StringFlag{
+		Name:  "address",
+		Usage: "Address the imported key must resolve to, checked before anything is written",
+	}
+	hdPathFlag = cli.StringFlag{
+		Name:  "hdpath",
+		Usage: "BIP-32 derivation path of the key within a mnemonic wallet",
+		Value: accounts.DefaultBaseDerivationPath.String(),
+	}
+	dryRunFlag = cli.BoolFlag{
+		Name:  "dryrun",
+		Usage: "Decode and verify the key without writing anything to the keystore",
+	}
+	exportOutputFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the exported key to (default = standard output)",
+	}
+
 	walletCommand = cli.Command{
 		Name:      "wallet",
 		Usage:     "Manage Ethereum presale wallets",
@@ -83,7 +115,8 @@ safe environments.
 Make sure you remember the password you gave when creating a new account (with
 either new or import). Without it you are not able to unlock your account.
 
-Note that exporting your key in unencrypted format is NOT supported.
+Keys can be exported either re-encrypted as keystore JSON or, explicitly,
+as an unencrypted hex private key.
 
 Keys are stored under <DATADIR>/keystore.
 It is safe to transfer the entire directory or the individual keys therein
@@ -109,6 +142,9 @@ Print a short summary of all accounts`,
 				Flags: []cli.Flag{
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreKDFFlag,
 					utils.PasswordFileFlag,
 				},
 				Description: `
@@ -152,6 +188,33 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Name:      "reencrypt",
+				Usage:     "Re-encrypt existing accounts with the configured KDF parameters",
+				Action:    utils.MigrateFlags(accountReencrypt),
+				ArgsUsage: "[<address> ...]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.KeyStoreKDFFlag,
+					utils.PasswordFileFlag,
+				},
+				Description: `
+    neatio account reencrypt [options] [<address> ...]
+
+Decrypts the given accounts (all accounts if none are given) and writes them
+back encrypted with the key derivation function selected by --keystore.kdf and,
+for scrypt, the --keystore.scryptn and --keystore.scryptp parameters.
+
+The passphrase of the accounts is kept unchanged. Use this to upgrade key files
+created with weak parameters, or to lighten them on constrained machines.
+
+For non-interactive use the passphrases can be specified with the --password
+flag, one per line in the order of the accounts.
 `,
 			},
 			{
@@ -162,21 +225,40 @@ changing your password is only possible interactively.
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
+					importFormatFlag,
+					expectedAddressFlag,
+					hdPathFlag,
+					dryRunFlag,
 				},
 				ArgsUsage: "<keyFile>",
 				Description: `
-    neatio account import <keyfile>
+    neatio account import [options] <keyfile>
 
-Imports an unencrypted private key from <keyfile> and creates a new account.
+Imports a private key from <keyfile> and creates a new account.
 Prints the address.
 
-The keyfile is assumed to contain an unencrypted private key in hexadecimal format.
+The format of the keyfile is selected with --format:
+
+    hex       an unencrypted private key in hexadecimal format (default)
+    keystore  an encrypted keystore JSON file, as written by neatio and most
+              other wallets; you are prompted for its passphrase first
+    mnemonic  a BIP-39 mnemonic sentence, or its encrypted backup written by
+              'neatio account backup-mnemonic'; you are prompted for the
+              passphrase of the backup, then for the optional mnemonic
+              passphrase, and the key is derived at --hdpath
+
+If --address is given, the decoded key must resolve to that address, otherwise
+the import is aborted. With --dryrun the key is only decoded and verified, and
+nothing is written to the keystore.
 
 The account is saved in encrypted format, you are prompted for a passphrase.
 
 You must remember this passphrase to unlock your account in the future.
 
-For non-interactive use the passphrase can be specified with the -password flag:
+For non-interactive use the passphrases can be specified with the -password
+flag. For keystore and mnemonic imports the first line is the passphrase of the
+source, the second one the passphrase of the new account. An encrypted mnemonic
+backup takes the passphrase of the backup first, then the two others:
 
     neatio account import [options] <keyfile>
 
@@ -184,6 +266,55 @@ Note:
 As you can directly copy your encrypted accounts to another ethereum instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the private key of an existing account",
+				Action:    utils.MigrateFlags(accountExport),
+				ArgsUsage: "<address>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					exportFormatFlag,
+					exportOutputFlag,
+				},
+				Description: `
+    neatio account export [options] <address>
+
+Exports the private key of an existing account. You are prompted for the
+passphrase of the account.
+
+The format of the output is selected with --format:
+
+    keystore  a keystore JSON file encrypted with a new passphrase (default)
+    hex       the unencrypted private key in hexadecimal format
+
+The key is written to standard output, or to the file given with --out, which
+is created with owner-only permissions.
+
+Note that a hex export leaves your key unprotected, handle it with care.
+`,
+			},
+			{
+				Name:      "backup-mnemonic",
+				Usage:     "Encrypt a mnemonic sentence into a backup file",
+				Action:    utils.MigrateFlags(accountBackupMnemonic),
+				ArgsUsage: "<mnemonicFile>",
+				Flags: []cli.Flag{
+					utils.PasswordFileFlag,
+					exportOutputFlag,
+				},
+				Description: `
+    neatio account backup-mnemonic [options] <mnemonicfile>
+
+Validates the BIP-39 mnemonic sentence in <mnemonicfile> and encrypts it with a
+passphrase you are prompted for. The backup is written to standard output, or
+to the file given with --out, which is created with owner-only permissions.
+
+The backup can be imported with 'neatio account import --format mnemonic'. The
+optional mnemonic passphrase is not part of the backup.
 `,
 			},
 		},
@@ -299,19 +430,20 @@ func ambiguousAddrRecovery(ks *keystore.KeyStore, err *keystore.AmbiguousAddrErr
 // accountCreate creates a new account into the keystore defined by the CLI flags.
 func accountCreate(ctx *cli.Context) error {
 	cfg := gethConfig{Node: defaultNodeConfig()}
+
+	chainId := params.MainnetChainConfig.NeatChainId
+	if ctx.GlobalIsSet(utils.TestnetFlag.Name) {
+		fmt.Printf("testnet: %v\n", params.TestnetChainConfig.NeatChainId)
+		chainId = params.TestnetChainConfig.NeatChainId
+	}
+
 	// Load config file.
 	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
-		if err := loadConfig(file, &cfg); err != nil {
+		if _, err := loadConfig(file, &cfg, chainId); err != nil {
 			utils.Fatalf("%v", err)
 		}
 	}
-
-	cfg.Node.ChainId = params.MainnetChainConfig.NeatChainId
-
-	if ctx.GlobalIsSet(utils.TestnetFlag.Name) {
-		fmt.Printf("testnet: %v\n", params.TestnetChainConfig.NeatChainId)
-		cfg.Node.ChainId = params.TestnetChainConfig.NeatChainId
-	}
+	cfg.Node.ChainId = chainId
 
 	utils.SetNodeConfig(ctx, &cfg.Node)
 	scryptN, scryptP, keydir, err := cfg.Node.AccountConfig()
@@ -322,7 +454,11 @@ func accountCreate(ctx *cli.Context) error {
 
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
-	address, err := keystore.StoreKey(keydir, password, scryptN, scryptP)
+	kdf := cfg.Node.KeyStoreKDF
+	if kdf == "" {
+		kdf = keystore.KDFScrypt
+	}
+	address, err := keystore.StoreKeyWithKDF(keydir, password, kdf, scryptN, scryptP)
 
 	if err != nil {
 		utils.Fatalf("Failed to create account: %v", err)
@@ -350,6 +486,35 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountReencrypt re-encrypts existing accounts with the currently configured
+// key derivation function, keeping their passphrases.
+func accountReencrypt(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	addrs := []string(ctx.Args())
+	if len(addrs) == 0 {
+		for _, account := range ks.Accounts() {
+			addrs = append(addrs, account.Address.String())
+		}
+	}
+	if len(addrs) == 0 {
+		utils.Fatalf("No accounts found to re-encrypt")
+	}
+	passwords := utils.MakePasswordList(ctx)
+	for i, addr := range addrs {
+		account, password := unlockAccount(ctx, ks, addr, i, passwords)
+		if err := ks.Update(account, password, password); err != nil {
+			utils.Fatalf("Could not re-encrypt the account %s: %v", addr, err)
+		}
+		if err := ks.Lock(account.Address); err != nil {
+			utils.Fatalf("Could not lock the account %s: %v", addr, err)
+		}
+		fmt.Printf("Re-encrypted account: %s\n", addr)
+	}
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
@@ -377,12 +542,67 @@ func accountImport(ctx *cli.Context) error {
 	if len(keyfile) == 0 {
 		utils.Fatalf("keyfile must be given as argument")
 	}
-	key, err := crypto.LoadECDSA(keyfile)
+	passwords := utils.MakePasswordList(ctx)
+
+	var (
+		key *ecdsa.PrivateKey
+		err error
+		// index of the new account passphrase in the password list
+		next int
+	)
+	switch format := ctx.String(importFormatFlag.Name); format {
+	case "hex":
+		key, err = crypto.LoadECDSA(keyfile)
+	case "keystore":
+		var keyJson []byte
+		if keyJson, err = ioutil.ReadFile(keyfile); err != nil {
+			utils.Fatalf("Could not read key file: %v", err)
+		}
+		passphrase := getPassPhrase("Please give the passphrase of the key file.", false, 0, passwords)
+		var k *keystore.Key
+		if k, err = keystore.DecryptKey(keyJson, passphrase); err == nil {
+			key = k.PrivateKey
+		}
+		next = 1
+	case "mnemonic":
+		var mnemonic []byte
+		if mnemonic, err = ioutil.ReadFile(keyfile); err != nil {
+			utils.Fatalf("Could not read mnemonic file: %v", err)
+		}
+		if bytes.HasPrefix(bytes.TrimSpace(mnemonic), []byte("{")) {
+			mnemonic = decryptMnemonicBackup(mnemonic, passwords)
+			next = 1
+		}
+		var path accounts.DerivationPath
+		if path, err = accounts.ParseDerivationPath(ctx.String(hdPathFlag.Name)); err != nil {
+			utils.Fatalf("Invalid derivation path: %v", err)
+		}
+		passphrase := getPassPhrase("Please give the mnemonic passphrase, if any.", false, next, passwords)
+		key, err = accounts.DeriveKeyFromMnemonic(string(mnemonic), passphrase, path)
+		next++
+	default:
+		utils.Fatalf("Unknown key format %q", format)
+	}
 	if err != nil {
 		utils.Fatalf("Failed to load the private key: %v", err)
 	}
+
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	if expected := ctx.String(expectedAddressFlag.Name); expected != "" {
+		if !crypto.ValidateNeatAddr(expected) {
+			utils.Fatalf("Invalid address %s", expected)
+		}
+		if address.String() != expected {
+			utils.Fatalf("Address mismatch: key resolves to %s, want %s", address.String(), expected)
+		}
+	}
+	if ctx.Bool(dryRunFlag.Name) {
+		fmt.Printf("Address: {%x} (dry run, nothing imported)\n", address)
+		return nil
+	}
+
 	stack, _ := makeConfigNode(ctx, clientIdentifier)
-	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, next, passwords)
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	acct, err := ks.ImportECDSA(key, passphrase)
@@ -392,3 +612,117 @@ func accountImport(ctx *cli.Context) error {
 	fmt.Printf("Address: {%x}\n", acct.Address)
 	return nil
 }
+
+// mnemonicBackupVersion is the format version of encrypted mnemonic backups.
+const mnemonicBackupVersion = 1
+
+// mnemonicBackup is the encrypted backup file of a mnemonic sentence.
+type mnemonicBackup struct {
+	Version int                 `json:"version"`
+	Crypto  keystore.CryptoJSON `json:"crypto"`
+}
+
+// decryptMnemonicBackup decrypts an encrypted mnemonic backup, prompting for
+// its passphrase, the first of the password list.
+func decryptMnemonicBackup(blob []byte, passwords []string) []byte {
+	var backup mnemonicBackup
+	if err := json.Unmarshal(blob, &backup); err != nil {
+		utils.Fatalf("Invalid mnemonic backup: %v", err)
+	}
+	if backup.Version != mnemonicBackupVersion {
+		utils.Fatalf("Unsupported mnemonic backup version %d", backup.Version)
+	}
+	passphrase := getPassPhrase("Please give the passphrase of the mnemonic backup.", false, 0, passwords)
+	mnemonic, err := keystore.DecryptDataV3(backup.Crypto, passphrase)
+	if err != nil {
+		utils.Fatalf("Could not decrypt the mnemonic backup: %v", err)
+	}
+	return mnemonic
+}
+
+// accountBackupMnemonic encrypts a mnemonic sentence with a passphrase.
+func accountBackupMnemonic(ctx *cli.Context) error {
+	file := ctx.Args().First()
+	if len(file) == 0 {
+		utils.Fatalf("mnemonic file must be given as argument")
+	}
+	mnemonic, err := ioutil.ReadFile(file)
+	if err != nil {
+		utils.Fatalf("Could not read mnemonic file: %v", err)
+	}
+	if err := accounts.ValidateMnemonic(string(mnemonic)); err != nil {
+		utils.Fatalf("%v", err)
+	}
+	sentence := strings.ToLower(strings.Join(strings.Fields(string(mnemonic)), " "))
+
+	passphrase := getPassPhrase("Please give a passphrase to encrypt the backup. Do not forget this passphrase.", true, 0, utils.MakePasswordList(ctx))
+	cryptoJson, err := keystore.EncryptDataV3([]byte(sentence), []byte(passphrase), keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		utils.Fatalf("Failed to encrypt the mnemonic: %v", err)
+	}
+	backup, err := json.MarshalIndent(mnemonicBackup{Version: mnemonicBackupVersion, Crypto: cryptoJson}, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode the backup: %v", err)
+	}
+
+	if out := ctx.String(exportOutputFlag.Name); out != "" {
+		if err := ioutil.WriteFile(out, backup, 0600); err != nil {
+			utils.Fatalf("Could not write %s: %v", out, err)
+		}
+		fmt.Printf("Encrypted mnemonic backup written to %s\n", out)
+		return nil
+	}
+	fmt.Println(string(backup))
+	return nil
+}
+
+// accountExport writes the private key of an account either as a re-encrypted
+// keystore JSON or as an unencrypted hex string.
+func accountExport(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		utils.Fatalf("No account specified to export")
+	}
+	format := ctx.String(exportFormatFlag.Name)
+	if format != "keystore" && format != "hex" {
+		utils.Fatalf("Unknown key format %q", format)
+	}
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	passwords := utils.MakePasswordList(ctx)
+	account, err := utils.MakeAddress(ks, ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not find the account: %v", err)
+	}
+	passphrase := getPassPhrase("Please give the passphrase of the account.", false, 0, passwords)
+
+	var output []byte
+	if format == "keystore" {
+		newPassphrase := getPassPhrase("Please give a passphrase for the exported key. Do not forget this password.", true, 1, passwords)
+		if output, err = ks.Export(account, passphrase, newPassphrase); err != nil {
+			utils.Fatalf("Could not export the account: %v", err)
+		}
+	} else {
+		// Round trip through a throwaway encryption, the keystore never hands
+		// out decrypted keys directly.
+		keyJson, err := ks.Export(account, passphrase, passphrase)
+		if err != nil {
+			utils.Fatalf("Could not export the account: %v", err)
+		}
+		key, err := keystore.DecryptKey(keyJson, passphrase)
+		if err != nil {
+			utils.Fatalf("Could not export the account: %v", err)
+		}
+		output = []byte(fmt.Sprintf("%x", crypto.FromECDSA(key.PrivateKey)))
+	}
+
+	if out := ctx.String(exportOutputFlag.Name); out != "" {
+		if err := ioutil.WriteFile(out, output, 0600); err != nil {
+			utils.Fatalf("Could not write %s: %v", out, err)
+		}
+		fmt.Printf("Exported account %s to %s\n", account.Address.String(), out)
+		return nil
+	}
+	fmt.Println(strings.TrimSpace(string(output)))
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/neatptc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	backupOutFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "Directory to write the backup to",
+	}
+	backupIncrementalFlag = cli.BoolFlag{
+		Name:  "incremental",
+		Usage: "Update an existing backup in the output directory instead of creating a new one",
+	}
+	backupAttachFlag = cli.StringFlag{
+		Name:  "attach",
+		Usage: "API endpoint of the node to back up (default: the IPC endpoint in the data directory)",
+	}
+	backupCommand = cli.Command{
+		Action:    utils.MigrateFlags(backup),
+		Name:      "backup",
+		Usage:     "Back up the chain database and keystore of a running node",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			backupOutFlag,
+			backupIncrementalFlag,
+			backupAttachFlag,
+			utils.DataDirFlag,
+			utils.TestnetFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The backup command asks a running node to copy its chain database, epoch
+database, keystore and priv validator into <out>/<chainId>. The databases are
+read from snapshots, so the node keeps validating while the backup is written
+and the copy is consistent, like the data directory of a node which crashed at
+the time of the snapshot. Restoring it needs no further steps, the node rewinds
+to the last block with a persisted state on startup.
+
+With --incremental an earlier backup in the output directory is updated and
+only changed entries are written. The backup.json manifest is written last, a
+backup without it is incomplete.`,
+	}
+)
+
+func backup(ctx *cli.Context) error {
+	out := ctx.String(backupOutFlag.Name)
+	if out == "" {
+		utils.Fatalf("The --%s flag is required", backupOutFlag.Name)
+	}
+	// The path is resolved by the node, which may run in another directory
+	out, err := filepath.Abs(out)
+	if err != nil {
+		utils.Fatalf("Invalid output directory: %v", err)
+	}
+
+	endpoint := ctx.String(backupAttachFlag.Name)
+	if endpoint == "" {
+		endpoint = defaultIPCEndpoint(ctx)
+	}
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		utils.Fatalf("Unable to attach to neatio node: %v", err)
+	}
+	defer client.Close()
+
+	var result neatptc.BackupResult
+	if err := client.Call(&result, "admin_backup", out, ctx.Bool(backupIncrementalFlag.Name)); err != nil {
+		utils.Fatalf("Backup failed: %v", err)
+	}
+	summary, _ := json.MarshalIndent(&result, "", "  ")
+	fmt.Println(string(summary))
+	return nil
+}
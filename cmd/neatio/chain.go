@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math/big"
 	"path/filepath"
 
 	"github.com/neatlab/neatio/accounts/keystore"
@@ -77,7 +78,7 @@ func StartChain(ctx *cli.Context, chain *Chain, startDone chan<- struct{}) error
 	return nil
 }
 
-func CreateSideChain(ctx *cli.Context, chainId string, validator ncTypes.PrivValidator, keyJson []byte, validators []ncTypes.GenesisValidator) error {
+func CreateSideChain(ctx *cli.Context, chainId string, numericChainId *big.Int, validator ncTypes.PrivValidator, keyJson []byte, validators []ncTypes.GenesisValidator) error {
 
 	// Get NeatCon config base on chain id
 	config := utils.GetNeatConConfig(chainId, ctx)
@@ -98,7 +99,7 @@ func CreateSideChain(ctx *cli.Context, chainId string, validator ncTypes.PrivVal
 	validator.Save()
 
 	// Init the Neatio Genesis
-	err := initEthGenesisFromExistValidator(chainId, config, validators)
+	err := initEthGenesisFromExistValidator(chainId, numericChainId, config, validators)
 	if err != nil {
 		return err
 	}
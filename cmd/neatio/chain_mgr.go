@@ -2,11 +2,13 @@ package main
 
 import (
 	"io/ioutil"
+	"math/big"
 	"net"
 	"os"
 	"path"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/neatlab/neatio/accounts"
 	"github.com/neatlab/neatio/cmd/utils"
@@ -20,7 +22,6 @@ import (
 	"github.com/neatlab/neatio/neatcli"
 	"github.com/neatlab/neatio/neatptc"
 	"github.com/neatlab/neatio/node"
-	crypto "github.com/neatlib/crypto-go"
 	dbm "github.com/neatlib/db-go"
 	"github.com/pkg/errors"
 	"gopkg.in/urfave/cli.v1"
@@ -39,8 +40,9 @@ type ChainManager struct {
 
 	stop chan struct{} // Channel wait for Neatio stop
 
-	server *utils.NeatChainP2PServer
-	cch    *CrossChainHelper
+	server  *utils.NeatChainP2PServer
+	cch     *CrossChainHelper
+	relayer *relayer // Relays the withdrawals from the side chains, nil if disabled
 }
 
 var chainMgr *ChainManager
@@ -117,7 +119,18 @@ func (cm *ChainManager) LoadChains(sideIDs []string) error {
 	//log.Infof("Number of side chain to be loaded :%v", len(readyToLoadChains))
 	//log.Infof("Start to load side chain: %v", readyToLoadChains)
 
+	mainHeight := cm.cch.GetHeightFromMainChain().Uint64()
 	for chainId := range readyToLoadChains {
+		_, sunset, retired, err := cm.cch.GetSideChainRetirement(chainId, mainHeight)
+		if err != nil {
+			log.Errorf("Side chain: %s not loaded, failed to check its retirement: %v", chainId, err)
+			continue
+		}
+		if retired && mainHeight >= sunset {
+			log.Infof("Side chain: %s retired at main chain block %d, not loaded", chainId, sunset)
+			continue
+		}
+
 		chain := LoadSideChain(cm.ctx, chainId)
 		if chain == nil {
 			log.Errorf("Load side chain: %s Failed.", chainId)
@@ -224,12 +237,14 @@ func (cm *ChainManager) StartRPC() error {
 	if err != nil {
 		return err
 	} else {
+		utils.HookupRouter(cm.mainChain.Id)
 		if utils.IsHTTPRunning() {
 			if h, err := cm.mainChain.NeatNode.GetHTTPHandler(); err == nil {
 				utils.HookupHTTP(cm.mainChain.Id, h)
 			} else {
 				log.Errorf("Load Main Chain RPC HTTP handler failed: %v", err)
 			}
+			utils.HookupHealth(cm.healthHandler(false), cm.healthHandler(true))
 			for _, chain := range cm.sideChains {
 				if h, err := chain.NeatNode.GetHTTPHandler(); err == nil {
 					utils.HookupHTTP(chain.Id, h)
@@ -253,6 +268,35 @@ func (cm *ChainManager) StartRPC() error {
 				}
 			}
 		}
+
+		if utils.IsAuthRunning() {
+			if h, err := cm.mainChain.NeatNode.GetAuthHandler(); err == nil {
+				utils.HookupAuth(cm.mainChain.Id, h)
+			} else {
+				log.Errorf("Load Main Chain authenticated RPC handler failed: %v", err)
+			}
+			for _, chain := range cm.sideChains {
+				if h, err := chain.NeatNode.GetAuthHandler(); err == nil {
+					utils.HookupAuth(chain.Id, h)
+				} else {
+					log.Errorf("Load Child Chain authenticated RPC handler failed: %v", err)
+				}
+			}
+		}
+
+		if utils.IsRosettaRunning() {
+			utils.HookupRosetta(cm.mainChain.Id, MustGetNeatChainFromNode(cm.mainChain.NeatNode).ApiBackend)
+			for _, chain := range cm.sideChains {
+				utils.HookupRosetta(chain.Id, MustGetNeatChainFromNode(chain.NeatNode).ApiBackend)
+			}
+		}
+
+		if utils.IsGRPCRunning() {
+			utils.HookupGRPC(cm.mainChain.Id, MustGetNeatChainFromNode(cm.mainChain.NeatNode).ApiBackend, true)
+			for _, chain := range cm.sideChains {
+				utils.HookupGRPC(chain.Id, MustGetNeatChainFromNode(chain.NeatNode).ApiBackend, false)
+			}
+		}
 	}
 
 	return nil
@@ -282,6 +326,60 @@ func (cm *ChainManager) StartInspectEvent() {
 			}
 		}
 	}()
+
+	chainHeadCh := make(chan core.ChainHeadEvent, 10)
+	chainHeadSub := MustGetNeatChainFromNode(cm.mainChain.NeatNode).BlockChain().SubscribeChainHeadEvent(chainHeadCh)
+
+	go func() {
+		defer chainHeadSub.Unsubscribe()
+
+		for {
+			select {
+			case event := <-chainHeadCh:
+				cm.retireSideChains(event.Block.NumberU64())
+			case <-chainHeadSub.Err():
+				return
+			}
+		}
+	}()
+}
+
+// retireSideChains stops the side chains whose retirement grace period ended at
+// the main chain block number, and deletes their data if requested.
+func (cm *ChainManager) retireSideChains(number uint64) {
+	cm.createSideChainLock.Lock()
+	defer cm.createSideChainLock.Unlock()
+
+	for chainId, chain := range cm.sideChains {
+		_, sunset, retired, err := cm.cch.GetSideChainRetirement(chainId, number)
+		if err != nil {
+			log.Errorf("Side chain: %s, failed to check its retirement at main chain block %d: %v", chainId, number, err)
+			continue
+		}
+		if !retired || number < sunset {
+			continue
+		}
+		log.Infof("Side chain: %s retired at main chain block %d, stopping it", chainId, sunset)
+
+		if address, ok := cm.getNodeValidator(chain.NeatNode); ok {
+			cm.server.RemoveLocalValidator(chainId, address)
+		}
+		dataDir := chain.NeatNode.DataDir()
+		if err := chain.NeatNode.Close(); err != nil {
+			log.Error("Error when closing side chain", "side id", chainId, "err", err)
+			continue
+		}
+		delete(cm.sideChains, chainId)
+		delete(cm.sideQuits, chainId)
+
+		if cm.ctx.GlobalBool(utils.PruneRetiredChainsFlag.Name) {
+			if err := os.RemoveAll(dataDir); err != nil {
+				log.Error("Error when deleting the data of side chain", "side id", chainId, "err", err)
+			} else {
+				log.Infof("Side chain: %s data deleted from %s", chainId, dataDir)
+			}
+		}
+	}
 }
 
 func (cm *ChainManager) LoadSideChainInRT(chainId string) {
@@ -293,7 +391,7 @@ func (cm *ChainManager) LoadSideChainInRT(chainId string) {
 		return
 	}
 
-	validators := make([]types.GenesisValidator, 0, len(cci.JoinedValidators))
+	validators := sideChainGenesisValidators(cci)
 
 	validator := false
 
@@ -309,22 +407,13 @@ func (cm *ChainManager) LoadSideChainInRT(chainId string) {
 		if v.Address == localEtherbase {
 			validator = true
 		}
-
-		// dereference the PubKey
-		if pubkey, ok := v.PubKey.(*crypto.BLSPubKey); ok {
-			v.PubKey = *pubkey
-		}
-
-		// append the Validator
-		validators = append(validators, types.GenesisValidator{
-			EthAccount: v.Address,
-			PubKey:     v.PubKey,
-			Amount:     v.DepositAmount,
-		})
 	}
 
+	// Side chains started before the fork keep the chain id of their genesis
+	sideChainId := ethereum.BlockChain().Config().SideChainIdAt(chainId, cci.StartBlock)
+
 	// Write down the genesis into chain info db when exit the routine
-	defer writeGenesisIntoChainInfoDB(cm.cch.chainInfoDB, chainId, validators)
+	defer writeGenesisIntoChainInfoDB(cm.cch.chainInfoDB, chainId, sideChainId, validators)
 
 	if !validator {
 		log.Warnf("You are not in the validators of side chain %v, no need to start the side chain", chainId)
@@ -354,7 +443,7 @@ func (cm *ChainManager) LoadSideChainInRT(chainId string) {
 	privValidatorFile := cm.mainChain.Config.GetString("priv_validator_file")
 	self := types.LoadPrivValidator(privValidatorFile)
 
-	err := CreateSideChain(cm.ctx, chainId, *self, keyJson, validators)
+	err := CreateSideChain(cm.ctx, chainId, sideChainId, *self, keyJson, validators)
 	if err != nil {
 		log.Errorf("Create Child Chain %v failed! %v", chainId, err)
 		return
@@ -417,6 +506,19 @@ func (cm *ChainManager) LoadSideChainInRT(chainId string) {
 			log.Errorf("Unable Hook up Child Chain (%v) RPC WS Handler: %v", chainId, err)
 		}
 	}
+	if utils.IsAuthRunning() {
+		if h, err := chain.NeatNode.GetAuthHandler(); err == nil {
+			utils.HookupAuth(chain.Id, h)
+		} else {
+			log.Errorf("Unable Hook up Child Chain (%v) authenticated RPC Handler: %v", chainId, err)
+		}
+	}
+	if utils.IsRosettaRunning() {
+		utils.HookupRosetta(chain.Id, sideNeatio.ApiBackend)
+	}
+	if utils.IsGRPCRunning() {
+		utils.HookupGRPC(chain.Id, sideNeatio.ApiBackend, false)
+	}
 
 }
 
@@ -439,7 +541,27 @@ func (cm *ChainManager) checkCoinbaseInSideChain(sideEpoch *epoch.Epoch) bool {
 	return sideEpoch.Validators.HasAddress(localEtherbase[:])
 }
 
+// ShutdownTimeout returns the longest time limit for stopping configured by
+// the chains, 0 if none sets one.
+func (cm *ChainManager) ShutdownTimeout() time.Duration {
+	chains := []*Chain{cm.mainChain}
+	cm.createSideChainLock.Lock()
+	for _, side := range cm.sideChains {
+		chains = append(chains, side)
+	}
+	cm.createSideChainLock.Unlock()
+
+	var timeout time.Duration
+	for _, chain := range chains {
+		if neatChain, err := getNeatChainFromNode(chain.NeatNode); err == nil && neatChain.ShutdownTimeout() > timeout {
+			timeout = neatChain.ShutdownTimeout()
+		}
+	}
+	return timeout
+}
+
 func (cm *ChainManager) StopChain() {
+	cm.stopRelayer()
 	go func() {
 		mainChainError := cm.mainChain.NeatNode.Close()
 		if mainChainError != nil {
@@ -492,8 +614,8 @@ func (cm *ChainManager) getNodeValidator(neatnode *node.Node) (common.Address, b
 	return coinbase, epoch.Validators.HasAddress(coinbase[:])
 }
 
-func writeGenesisIntoChainInfoDB(db dbm.DB, sideChainId string, validators []types.GenesisValidator) {
-	ethByte, _ := generateETHGenesis(sideChainId, validators)
-	tdmByte, _ := generateNCGenesis(sideChainId, validators)
+func writeGenesisIntoChainInfoDB(db dbm.DB, sideChainId string, numericChainId *big.Int, validators []types.GenesisValidator) {
+	ethByte, _ := generateETHGenesis(sideChainId, numericChainId, validators)
+	tdmByte, _ := generateNCGenesis(sideChainId, validators, time.Now())
 	core.SaveChainGenesis(db, sideChainId, ethByte, tdmByte)
 }
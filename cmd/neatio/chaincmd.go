@@ -38,11 +38,17 @@ import (
 	"github.com/neatlab/neatio/event"
 	"github.com/neatlab/neatio/log"
 	"github.com/neatlab/neatio/neatptc/downloader"
+	"github.com/neatlab/neatio/node"
 	"github.com/neatlab/neatio/rlp"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	chainArchiveFlag = cli.BoolFlag{
+		Name:  "archive",
+		Usage: "Use the chain archive format carrying commits, epochs and TX3 proofs",
+	}
+
 	initNeatGenesisCmd = cli.Command{
 		Action:    utils.MigrateFlags(initNeatGenesis),
 		Name:      "init-neatio",
@@ -116,6 +122,7 @@ It expects the genesis file as argument.`,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
 			utils.CacheDatabaseFlag,
+			chainArchiveFlag,
 			utils.CacheGCFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
@@ -124,7 +131,14 @@ The import command imports blocks from an RLP-encoded form. The form can be one
 with several RLP-encoded blocks, or several files can be used.
 
 If only one file is used, import error will result in failure. If several files are used,
-processing will proceed even if an individual RLP-file import failure occurs.`,
+processing will proceed even if an individual RLP-file import failure occurs.
+
+With --archive the files are chain archives written by "export --archive". The
+seen commit of every block is verified against the validator set of its epoch
+and the blocks are processed by the consensus engine, the TX3 proofs of the
+archive are stored as well. The epochs are trusted from the local epoch
+database, the genesis on a new node, and each next one from the end block of
+the epoch before it; the epoch records of the archive must agree with them.`,
 	}
 	exportCommand = cli.Command{
 		Action:    utils.MigrateFlags(exportChain),
@@ -135,13 +149,18 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 			utils.DataDirFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
+			chainArchiveFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
-if already existing.`,
+if already existing.
+
+With --archive a versioned chain archive is written instead, holding the
+blocks together with their epochs and the local TX3 proofs. The file is
+always overwritten in this mode.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -289,6 +308,10 @@ func importChain(ctx *cli.Context) error {
 	//stack := makeFullNode(ctx)
 	defer stack.Close()
 
+	if ctx.Bool(chainArchiveFlag.Name) {
+		return importChainArchive(ctx, stack, cfg, cch)
+	}
+
 	chain, db := utils.MakeChain(ctx, stack)
 	defer db.Close()
 
@@ -376,6 +399,24 @@ func importChain(ctx *cli.Context) error {
 	return nil
 }
 
+// importChainArchive imports chain archives through a chain driven by the
+// consensus engine, which keeps the epoch database in step with the blocks.
+func importChainArchive(ctx *cli.Context, stack *node.Node, cfg gethConfig, cch core.CrossChainHelper) error {
+	chain, db, engine := utils.MakeChainWithEngine(ctx, stack, cfg.Node.NodeKey(), cch)
+	defer db.Close()
+	defer engine.Close()
+
+	start := time.Now()
+	for _, arg := range ctx.Args()[1:] {
+		if err := utils.ImportChainArchive(chain, db, arg); err != nil {
+			log.Error("Import error", "file", arg, "err", err)
+		}
+	}
+	chain.Stop()
+	fmt.Printf("Import done in %v.\n", time.Since(start))
+	return nil
+}
+
 func exportChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -391,12 +432,25 @@ func exportChain(ctx *cli.Context) error {
 	//stack := makeFullNode(ctx)
 	defer stack.Close()
 
-	chain, _ := utils.MakeChain(ctx, stack)
+	chain, chainDb := utils.MakeChain(ctx, stack)
 	start := time.Now()
 
 	var err error
 	fp := ctx.Args().Get(1)
-	if len(ctx.Args()) < 4 {
+	if ctx.Bool(chainArchiveFlag.Name) {
+		first, last := uint64(0), chain.CurrentBlock().NumberU64()
+		if len(ctx.Args()) >= 4 {
+			var ferr, lerr error
+			first, ferr = strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+			last, lerr = strconv.ParseUint(ctx.Args().Get(3), 10, 64)
+			if ferr != nil || lerr != nil {
+				utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+			}
+		}
+		epochDB := utils.MakeEpochDatabase(ctx, chainName)
+		err = utils.ExportChainArchive(chain, chainDb, epochDB, fp, first, last)
+		epochDB.Close()
+	} else if len(ctx.Args()) < 4 {
 		err = utils.ExportChain(chain, fp)
 	} else {
 		// This can be improved to allow for numbers larger than 9223372036854775807
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"time"
+
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/common"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/log"
+	neatAbi "github.com/neatlab/neatio/neatabi/abi"
+	goCrypto "github.com/neatlib/crypto-go"
+	dbm "github.com/neatlib/db-go"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	genesisOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "Directory the genesis files are written to",
+		Value: ".",
+	}
+
+	childChainCommand = cli.Command{
+		Name:     "childchain",
+		Usage:    "Manage the side chains of the main chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "export-genesis",
+				Usage:     "Rebuild the genesis of a side chain from the main chain",
+				ArgsUsage: "<chainId>",
+				Action:    utils.MigrateFlags(exportSideChainGenesis),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					genesisOutputFlag,
+				},
+				Description: `
+    neatio childchain export-genesis [--output <dir>] <chainId>
+
+Rebuilds the genesis of the side chain from the data of the main chain: the
+validators which joined the side chain and their deposits, in the order of
+their JoinSideChain transactions between its creation and its launch, and the
+chain id the main chain assigned to the side chain at its start block. The
+launch is found in the state of the main chain from the start block on, which
+must be available: the export of an old side chain needs an archive node.
+Two files are written to the output
+directory, <chainId>_neat_genesis.json and <chainId>_genesis.json, and the hash
+of the genesis block is printed, to be compared with the block 0 of the side
+chain.
+
+The same data gives the same files on every node. The genesis time of the
+consensus genesis is the time of the start block on the main chain; the nodes
+of the side chain stamp their own with the time they launched it, which is not
+part of any block hash.`,
+			},
+		},
+	}
+)
+
+func exportSideChainGenesis(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the side chain id as argument.")
+	}
+	chainId := ctx.Args().First()
+	if chainId == MainChain || chainId == TestnetChain {
+		utils.Fatalf("%s is not a side chain", chainId)
+	}
+
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	chainInfoDb := dbm.NewDB("chaininfo", "leveldb", ctx.GlobalString(utils.DataDirFlag.Name))
+	if chainInfoDb == nil {
+		utils.Fatalf("Could not open chain info database")
+	}
+	defer chainInfoDb.Close()
+
+	ci := core.GetChainInfo(chainInfoDb, chainId)
+	if ci == nil {
+		if core.GetPendingSideChainData(chainInfoDb, chainId) != nil {
+			utils.Fatalf("Side chain %s is not launched yet", chainId)
+		}
+		utils.Fatalf("Side chain %s not found", chainId)
+	}
+	cci := &ci.CoreChainInfo
+	if len(cci.JoinedValidators) == 0 || cci.StartBlock == nil {
+		utils.Fatalf("No genesis data recorded for side chain %s", chainId)
+	}
+
+	startBlock := chain.GetHeaderByNumber(cci.StartBlock.Uint64())
+	if startBlock == nil {
+		utils.Fatalf("Start block %v of side chain %s not found on the main chain", cci.StartBlock, chainId)
+	}
+	joins, launch, err := sideChainJoinsAtLaunch(chain, chainId, cci.StartBlock.Uint64(), cci.EndBlock.Uint64())
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	validators := make([]ncTypes.GenesisValidator, len(joins))
+	for i, join := range joins {
+		validators[i] = ncTypes.GenesisValidator{EthAccount: join.from, PubKey: join.pubkey, Amount: join.amount}
+	}
+	if local := sideChainGenesisValidators(cci); len(local) != len(validators) {
+		log.Warn("Validators of the side chain recorded locally differ from the main chain", "chain", chainId, "local", len(local), "main", len(validators))
+	}
+
+	numericChainId := chain.Config().SideChainIdAt(chainId, cci.StartBlock)
+	neatGenesis, err := generateETHGenesis(chainId, numericChainId, validators)
+	if err != nil {
+		utils.Fatalf("Failed to build the genesis: %v", err)
+	}
+	ncGenesis, err := generateNCGenesis(chainId, validators, time.Unix(startBlock.Time.Int64(), 0).UTC())
+	if err != nil {
+		utils.Fatalf("Failed to build the consensus genesis: %v", err)
+	}
+
+	genesis, err := core.WriteGenesisBlock(rawdb.NewMemoryDatabase(), bytes.NewReader(neatGenesis))
+	if err != nil {
+		utils.Fatalf("Failed to build the genesis block: %v", err)
+	}
+
+	dir := ctx.String(genesisOutputFlag.Name)
+	neatGenesisPath := filepath.Join(dir, chainId+"_neat_genesis.json")
+	if err := ioutil.WriteFile(neatGenesisPath, neatGenesis, 0644); err != nil {
+		utils.Fatalf("Failed to write the genesis: %v", err)
+	}
+	ncGenesisPath := filepath.Join(dir, chainId+"_genesis.json")
+	if err := ioutil.WriteFile(ncGenesisPath, ncGenesis, 0644); err != nil {
+		utils.Fatalf("Failed to write the consensus genesis: %v", err)
+	}
+	log.Info("Side chain genesis exported", "chain", chainId, "start", cci.StartBlock, "launch", launch, "validators", len(validators), "genesis", neatGenesisPath, "consensus", ncGenesisPath)
+
+	fmt.Printf("Genesis hash of side chain %s: %x\n", chainId, genesis.Hash())
+	return nil
+}
+
+// sideChainJoin is a successful JoinSideChain transaction of the main chain.
+type sideChainJoin struct {
+	from   common.Address
+	pubkey goCrypto.BLSPubKey
+	amount *big.Int
+}
+
+// sideChainTxs returns the JoinSideChain transactions of the side chain in the
+// main chain block, and whether the block carries its CreateSideChain one.
+func sideChainTxs(chain *core.BlockChain, block *types.Block, chainId string) ([]sideChainJoin, bool, error) {
+	var (
+		joins    []sideChainJoin
+		created  bool
+		receipts types.Receipts
+	)
+	for i, tx := range block.Transactions() {
+		if !neatAbi.IsNeatChainContractAddr(tx.To()) || len(tx.Data()) < 4 {
+			continue
+		}
+		function, err := neatAbi.FunctionTypeFromId(tx.Data()[:4])
+		if err != nil || (function != neatAbi.CreateSideChain && function != neatAbi.JoinSideChain) {
+			continue
+		}
+		if receipts == nil {
+			if receipts = chain.GetReceiptsByHash(block.Hash()); len(receipts) != len(block.Transactions()) {
+				return nil, false, fmt.Errorf("receipts of main chain block %d not found", block.NumberU64())
+			}
+		}
+		if receipts[i].Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+
+		if function == neatAbi.CreateSideChain {
+			var args neatAbi.CreateSideChainArgs
+			if err := neatAbi.ChainABI.UnpackMethodInputs(&args, function.String(), tx.Data()[4:]); err == nil && args.ChainId == chainId {
+				created = true
+			}
+			continue
+		}
+		var args neatAbi.JoinSideChainArgs
+		if err := neatAbi.ChainABI.UnpackMethodInputs(&args, function.String(), tx.Data()[4:]); err != nil || args.ChainId != chainId {
+			continue
+		}
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid JoinSideChain transaction %x: %v", tx.Hash(), err)
+		}
+		join := sideChainJoin{from: from, amount: tx.Value()}
+		copy(join.pubkey[:], args.PubKey)
+		joins = append(joins, join)
+	}
+	return joins, created, nil
+}
+
+// sideChainJoinsAtLaunch returns the validators which joined the side chain on
+// the main chain, in the order they joined, and the main chain block which
+// launched it. The launch moves the deposits of all of them out of the state,
+// at the first block from the start block it is possible.
+func sideChainJoinsAtLaunch(chain *core.BlockChain, chainId string, start, end uint64) ([]sideChainJoin, uint64, error) {
+	var joins []sideChainJoin
+	for number := start; number > 0; {
+		number--
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, 0, fmt.Errorf("main chain block %d not found", number)
+		}
+		txs, created, err := sideChainTxs(chain, block, chainId)
+		if err != nil {
+			return nil, 0, err
+		}
+		joins = append(txs, joins...)
+		if created {
+			break
+		}
+		if number == 0 {
+			return nil, 0, fmt.Errorf("CreateSideChain transaction of side chain %s not found on the main chain", chainId)
+		}
+	}
+
+	for number := start; number <= end; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		txs, _, err := sideChainTxs(chain, block, chainId)
+		if err != nil {
+			return nil, 0, err
+		}
+		if joins = append(joins, txs...); len(joins) == 0 {
+			continue
+		}
+		statedb, err := chain.StateAt(block.Root())
+		if err != nil {
+			return nil, 0, fmt.Errorf("state of main chain block %d not available, an archive node is needed: %v", number, err)
+		}
+		if statedb.GetSideChainDepositBalance(chainId, joins[0].from).Sign() != 0 {
+			continue
+		}
+		for _, join := range joins {
+			if statedb.GetSideChainDepositBalance(chainId, join.from).Sign() != 0 {
+				return nil, 0, fmt.Errorf("validator %x of side chain %s still has its deposit at the launch, block %d", join.from, chainId, number)
+			}
+		}
+		return uniqueSideChainJoins(joins), number, nil
+	}
+	return nil, 0, fmt.Errorf("side chain %s is not launched on the main chain", chainId)
+}
+
+// uniqueSideChainJoins keeps the first join of every validator, as the chain
+// info of the side chain ignores the later ones.
+func uniqueSideChainJoins(joins []sideChainJoin) []sideChainJoin {
+	seen := make(map[common.Address]bool, len(joins))
+	unique := joins[:0]
+	for _, join := range joins {
+		if !seen[join.from] {
+			seen[join.from] = true
+			unique = append(unique, join)
+		}
+	}
+	return unique
+}
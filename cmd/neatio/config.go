@@ -17,10 +17,10 @@
 package main
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"unicode"
@@ -32,19 +32,26 @@ import (
 	"gopkg.in/urfave/cli.v1"
 
 	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
 	"github.com/neatlab/neatio/cmd/utils"
+	tmcfg "github.com/neatlab/neatio/consensus/neatpos/config/neatcon"
 	"github.com/neatlab/neatio/node"
 	"github.com/neatlab/neatio/params"
 )
 
 var (
 	dumpConfigCommand = cli.Command{
-		Action:      utils.MigrateFlags(dumpConfig),
-		Name:        "dumpconfig",
-		Usage:       "Show configuration values",
-		ArgsUsage:   "",
-		Category:    "MISCELLANEOUS COMMANDS",
-		Description: `The dumpconfig command shows configuration values.`,
+		Action:    utils.MigrateFlags(dumpConfig),
+		Name:      "dumpconfig",
+		Usage:     "Show configuration values",
+		ArgsUsage: "[<chainId>]",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `The dumpconfig command shows the effective configuration values of the main
+chain, or of the side chain <chainId>, after applying the --config file and the
+command line flags. The output can be used as configuration file.
+
+Side chain specific values go into [SideChains.<chainId>] sections of the file,
+which take the same sections as the top level, e.g. [SideChains.side_0.Eth].`,
 	}
 
 	configFileFlag = cli.StringFlag{
@@ -75,24 +82,71 @@ type ethstatsConfig struct {
 }
 
 type gethConfig struct {
-	Eth      neatptc.Config
-	Node     node.Config
-	Ethstats ethstatsConfig
+	Eth       neatptc.Config
+	Node      node.Config
+	Ethstats  ethstatsConfig
+	Consensus tmcfg.ConsensusConfig
 }
 
-func loadConfig(file string, cfg *gethConfig) error {
-	f, err := os.Open(file)
+// sideChainsKey is the table of the configuration file holding the per side
+// chain sections.
+const sideChainsKey = "SideChains"
+
+// consensusKey is the section of the configuration file holding the consensus
+// settings.
+const consensusKey = "Consensus"
+
+// loadConfig loads the configuration file, applying the section of the given
+// chain in the SideChains table on top of the top level values. It returns the
+// fields of the consensus settings set in the file.
+func loadConfig(file string, cfg *gethConfig, chainId string) ([]string, error) {
+	data, err := ioutil.ReadFile(file)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	table, err := toml.Parse(data)
+	if err != nil {
+		return nil, errors.New(file + ", " + err.Error())
+	}
+
+	var sideChain *ast.Table
+	if field, ok := table.Fields[sideChainsKey]; ok {
+		sides, ok := field.(*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("%s, line %d: %s must be a table", file, table.Line, sideChainsKey)
+		}
+		if field, ok := sides.Fields[chainId]; ok {
+			if sideChain, ok = field.(*ast.Table); !ok {
+				return nil, fmt.Errorf("%s, line %d: %s.%s must be a table", file, sides.Line, sideChainsKey, chainId)
+			}
+		}
+		delete(table.Fields, sideChainsKey)
 	}
-	defer f.Close()
 
-	err = tomlSettings.NewDecoder(bufio.NewReader(f)).Decode(cfg)
+	err = tomlSettings.UnmarshalTable(table, cfg)
+	if err == nil && sideChain != nil {
+		err = tomlSettings.UnmarshalTable(sideChain, cfg)
+	}
 	// Add file name to errors that have a line number.
 	if _, ok := err.(*toml.LineError); ok {
 		err = errors.New(file + ", " + err.Error())
 	}
-	return err
+	if err != nil {
+		return nil, err
+	}
+
+	var consensus []string
+	for _, t := range []*ast.Table{table, sideChain} {
+		if t == nil {
+			continue
+		}
+		if section, ok := t.Fields[consensusKey].(*ast.Table); ok {
+			for field := range section.Fields {
+				consensus = append(consensus, field)
+			}
+		}
+	}
+	return consensus, nil
 }
 
 func defaultNodeConfig() node.Config {
@@ -108,15 +162,18 @@ func defaultNodeConfig() node.Config {
 func makeConfigNode(ctx *cli.Context, chainId string) (*node.Node, gethConfig) {
 	// Load defaults.
 	cfg := gethConfig{
-		Eth:  neatptc.DefaultConfig,
-		Node: defaultNodeConfig(),
+		Eth:       neatptc.DefaultConfig,
+		Node:      defaultNodeConfig(),
+		Consensus: tmcfg.DefaultConsensusConfig,
 	}
 
 	// Load config file.
 	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
-		if err := loadConfig(file, &cfg); err != nil {
+		consensus, err := loadConfig(file, &cfg, chainId)
+		if err != nil {
 			utils.Fatalf("%v", err)
 		}
+		tmcfg.SetConsensusConfig(chainId, cfg.Consensus, consensus)
 	}
 
 	// Apply flags.
@@ -124,7 +181,7 @@ func makeConfigNode(ctx *cli.Context, chainId string) (*node.Node, gethConfig) {
 
 	// Setup Log
 	//logDir := path.Join(ctx.GlobalString("datadir"), ctx.GlobalString("logDir"), chainId)
-	cfg.Node.Logger = log.NewLogger(chainId, "", ctx.GlobalInt("verbosity"), ctx.GlobalBool("debug"), ctx.GlobalString("vmodule"), ctx.GlobalString("backtrace"))
+	cfg.Node.Logger = log.NewLogger(chainId, "", ctx.GlobalInt("verbosity"), ctx.GlobalBool("debug"), ctx.GlobalString("vmodule"), ctx.GlobalString("backtrace"), ctx.GlobalString("log.format"))
 
 	utils.SetNodeConfig(ctx, &cfg.Node)
 	stack, err := node.New(&cfg.Node)
@@ -173,7 +230,11 @@ func makeFullNode(ctx *cli.Context, cch core.CrossChainHelper, chainId string) *
 
 // dumpConfig is the dumpconfig command.
 func dumpConfig(ctx *cli.Context) error {
-	_, cfg := makeConfigNode(ctx, clientIdentifier)
+	chainId := clientIdentifier
+	if ctx.NArg() > 0 {
+		chainId = ctx.Args().First()
+	}
+	_, cfg := makeConfigNode(ctx, chainId)
 	comment := ""
 
 	if cfg.Eth.Genesis != nil {
@@ -122,16 +122,7 @@ func remoteConsole(ctx *cli.Context) error {
 	// Attach to a remotely running neatio instance and start the JavaScript console
 	endpoint := ctx.Args().First()
 	if endpoint == "" {
-		path := node.DefaultDataDir()
-		if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
-			path = ctx.GlobalString(utils.DataDirFlag.Name)
-		}
-		if path != "" {
-			if ctx.GlobalBool(utils.TestnetFlag.Name) {
-				path = filepath.Join(path, "testnet")
-			}
-		}
-		endpoint = fmt.Sprintf("%s/neatio.ipc", path)
+		endpoint = defaultIPCEndpoint(ctx)
 	}
 	client, err := dialRPC(endpoint)
 	if err != nil {
@@ -162,6 +153,21 @@ func remoteConsole(ctx *cli.Context) error {
 	return nil
 }
 
+// defaultIPCEndpoint returns the IPC endpoint of a local neatio instance
+// running with the data directory flags of ctx.
+func defaultIPCEndpoint(ctx *cli.Context) string {
+	path := node.DefaultDataDir()
+	if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
+		path = ctx.GlobalString(utils.DataDirFlag.Name)
+	}
+	if path != "" {
+		if ctx.GlobalBool(utils.TestnetFlag.Name) {
+			path = filepath.Join(path, "testnet")
+		}
+	}
+	return fmt.Sprintf("%s/neatio.ipc", path)
+}
+
 // dialRPC returns a RPC client which connects to the given endpoint.
 // The check for empty endpoint implements the defaulting logic
 // for "neatio attach" and "neatio monitor" with no argument.
@@ -90,6 +90,19 @@ func (cch *CrossChainHelper) CanCreateSideChain(from common.Address, chainId str
 		return fmt.Errorf("Chain %s has already applied, try use other name instead", chainId)
 	}
 
+	ethereum := MustGetNeatChainFromNode(chainMgr.mainChain.NeatNode)
+
+	// Check the chain id derived from "chainId" is not used by another chain
+	if ethereum.BlockChain().Config().IsSideChainId(startBlock) {
+		sideChainId := params.SideChainId(chainId)
+		otherIds := append(core.GetSideChainIds(cch.chainInfoDB), core.GetPendingSideChainIds(cch.chainInfoDB)...)
+		for _, id := range otherIds {
+			if params.SideChainId(id).Cmp(sideChainId) == 0 {
+				return fmt.Errorf("Chain %s has the same chain id as chain %s, try use other name instead", chainId, id)
+			}
+		}
+	}
+
 	// Check the minimum validators
 	if minValidators < core.OfficialMinimumValidators {
 		return fmt.Errorf("Validators count is not meet the minimum official validator count (%v)", core.OfficialMinimumValidators)
@@ -112,7 +125,6 @@ func (cch *CrossChainHelper) CanCreateSideChain(from common.Address, chainId str
 	}
 
 	// Check End Block already passed
-	ethereum := MustGetNeatChainFromNode(chainMgr.mainChain.NeatNode)
 	currentBlock := ethereum.BlockChain().CurrentBlock()
 	if endBlock.Cmp(currentBlock.Number()) <= 0 {
 		return errors.New("end block number has already passed")
@@ -319,6 +331,37 @@ func (cch *CrossChainHelper) GetTxFromMainChain(txHash common.Hash) *types.Trans
 	return tx
 }
 
+// GetSideChainRetirement looks the retirement of the side chain up in the state
+// of the main chain block at number. It fails if the main chain doesn't have
+// the block or its state: the retirements scheduled at the head may not be
+// the ones in effect at the block.
+func (cch *CrossChainHelper) GetSideChainRetirement(chainId string, number uint64) (uint64, uint64, bool, error) {
+	bc := MustGetNeatChainFromNode(chainMgr.mainChain.NeatNode).BlockChain()
+
+	header := bc.GetHeaderByNumber(number)
+	if header == nil {
+		return 0, 0, false, fmt.Errorf("main chain block %d not found", number)
+	}
+	statedb, err := bc.StateAt(header.Root)
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("state of main chain block %d not available: %v", number, err)
+	}
+	freeze, sunset, retired := statedb.SideChainRetirement(chainId)
+	return freeze, sunset, retired, nil
+}
+
+// GetValidatorSideChains returns the side chains joined by the validator, as
+// recorded in the state of the main chain, the retired ones excepted.
+func (cch *CrossChainHelper) GetValidatorSideChains(addr common.Address) ([]string, uint64) {
+	bc := MustGetNeatChainFromNode(chainMgr.mainChain.NeatNode).BlockChain()
+	statedb, err := bc.State()
+	if err != nil {
+		log.Errorf("GetValidatorSideChains, failed to load the main chain state, err: %v", err)
+		return nil, 0
+	}
+	return statedb.GetValidatorSideChains(addr), statedb.MaxSideChains()
+}
+
 func (cch *CrossChainHelper) GetEpochFromMainChain() (string, *epoch.Epoch) {
 	ethereum := MustGetNeatChainFromNode(chainMgr.mainChain.NeatNode)
 	var ep *epoch.Epoch
@@ -498,44 +541,76 @@ func (cch *CrossChainHelper) ValidateTX3ProofData(proofData *types.TX3ProofData)
 		//return errors.New("block in the future")
 	}
 
-	ncExtra, err := ncTypes.ExtractNeatconExtra(header)
+	ncExtra, err := checkSideChainHeader(header)
 	if err != nil {
 		return err
 	}
 
+	// special case: epoch 0 update
+	// TODO: how to verify this block which includes epoch 0?
+	if ncExtra.EpochBytes != nil && len(ncExtra.EpochBytes) != 0 {
+		ep := epoch.FromBytes(ncExtra.EpochBytes)
+		if ep != nil && ep.Number == 0 {
+			return nil
+		}
+	}
+
+	if err = cch.verifySideChainCommit(ncExtra); err != nil {
+		return err
+	}
+
+	// tx merkle proof verify
+	keybuf := new(bytes.Buffer)
+	for i, txIndex := range proofData.TxIndexs {
+		keybuf.Reset()
+		rlp.Encode(keybuf, uint(txIndex))
+		_, _, err := trie.VerifyProof(header.TxHash, keybuf.Bytes(), proofData.TxProofs[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Debug("ValidateTX3ProofData - end")
+	return nil
+}
+
+// checkSideChainHeader checks the fields of a side chain header fixed by the
+// consensus, and returns its extra data.
+func checkSideChainHeader(header *types.Header) (*ncTypes.NeatconExtra, error) {
+	ncExtra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil {
+		return nil, err
+	}
+
 	chainId := ncExtra.ChainID
 	if chainId == "" || chainId == MainChain || chainId == TestnetChain {
-		return fmt.Errorf("invalid side chain id: %s", chainId)
+		return nil, fmt.Errorf("invalid side chain id: %s", chainId)
 	}
 
 	if header.Nonce != (types.NeatconEmptyNonce) && !bytes.Equal(header.Nonce[:], types.NeatconNonce) {
-		return errors.New("invalid nonce")
+		return nil, errors.New("invalid nonce")
 	}
 
 	if header.MixDigest != types.NeatconDigest {
-		return errors.New("invalid mix digest")
+		return nil, errors.New("invalid mix digest")
 	}
 
 	if header.UncleHash != types.NeatconNilUncleHash {
-		return errors.New("invalid uncle Hash")
+		return nil, errors.New("invalid uncle Hash")
 	}
 
 	if header.Difficulty == nil || header.Difficulty.Cmp(types.NeatconDefaultDifficulty) != 0 {
-		return errors.New("invalid difficulty")
-	}
-
-	// special case: epoch 0 update
-	// TODO: how to verify this block which includes epoch 0?
-	if ncExtra.EpochBytes != nil && len(ncExtra.EpochBytes) != 0 {
-		ep := epoch.FromBytes(ncExtra.EpochBytes)
-		if ep != nil && ep.Number == 0 {
-			return nil
-		}
+		return nil, errors.New("invalid difficulty")
 	}
+	return ncExtra, nil
+}
 
-	ci := core.GetChainInfo(cch.chainInfoDB, chainId)
+// verifySideChainCommit verifies the commit carried by a side chain header
+// against the validators of its epoch, as saved on the main chain.
+func (cch *CrossChainHelper) verifySideChainCommit(ncExtra *ncTypes.NeatconExtra) error {
+	ci := core.GetChainInfo(cch.chainInfoDB, ncExtra.ChainID)
 	if ci == nil {
-		return fmt.Errorf("chain info %s not found", chainId)
+		return fmt.Errorf("chain info %s not found", ncExtra.ChainID)
 	}
 	epoch := ci.GetEpochByBlockNumber(ncExtra.Height)
 	if epoch == nil {
@@ -547,27 +622,64 @@ func (cch *CrossChainHelper) ValidateTX3ProofData(proofData *types.TX3ProofData)
 	}
 
 	seenCommit := ncExtra.SeenCommit
-	if !bytes.Equal(ncExtra.SeenCommitHash, seenCommit.Hash()) {
+	if seenCommit == nil || !bytes.Equal(ncExtra.SeenCommitHash, seenCommit.Hash()) {
 		return errors.New("invalid committed seals")
 	}
 
-	if err = valSet.VerifyCommit(ncExtra.ChainID, ncExtra.Height, seenCommit); err != nil {
+	return valSet.VerifyCommit(ncExtra.ChainID, ncExtra.Height, seenCommit)
+}
+
+// ValidateWithdrawalChallenge checks a fraud proof against the withdrawal of
+// a TX3: the block of the side chain including the TX3, and another block the
+// validators of the side chain committed at the same height. Unlike the TX3
+// proofs, both commits are verified whatever the epoch of the blocks.
+func (cch *CrossChainHelper) ValidateWithdrawalChallenge(chainId string, txHash common.Hash, proofData *types.TX3ProofData, header *types.Header) error {
+	if proofData.Header == nil || header == nil {
+		return errors.New("missing side chain header")
+	}
+
+	withdrawn, err := checkSideChainHeader(proofData.Header)
+	if err != nil {
+		return err
+	}
+	conflicting, err := checkSideChainHeader(header)
+	if err != nil {
+		return err
+	}
+	if withdrawn.ChainID != chainId || conflicting.ChainID != chainId {
+		return fmt.Errorf("blocks not from side chain %s", chainId)
+	}
+	if withdrawn.Height != conflicting.Height {
+		return fmt.Errorf("blocks at different heights: %v vs %v", withdrawn.Height, conflicting.Height)
+	}
+	if err := cch.verifySideChainCommit(withdrawn); err != nil {
 		return err
 	}
+	if err := cch.verifySideChainCommit(conflicting); err != nil {
+		return err
+	}
+	if withdrawn.SeenCommit.BlockID.Equals(conflicting.SeenCommit.BlockID) {
+		return errors.New("blocks not conflicting, both commits are for the same block")
+	}
 
-	// tx merkle proof verify
+	// The TX3 must be in the block it is withdrawn from
 	keybuf := new(bytes.Buffer)
 	for i, txIndex := range proofData.TxIndexs {
+		if i >= len(proofData.TxProofs) {
+			break
+		}
 		keybuf.Reset()
 		rlp.Encode(keybuf, uint(txIndex))
-		_, _, err := trie.VerifyProof(header.TxHash, keybuf.Bytes(), proofData.TxProofs[i])
+		val, _, err := trie.VerifyProof(proofData.Header.TxHash, keybuf.Bytes(), proofData.TxProofs[i])
 		if err != nil {
 			return err
 		}
+		var tx3 types.Transaction
+		if err := rlp.DecodeBytes(val, &tx3); err == nil && tx3.Hash() == txHash {
+			return nil
+		}
 	}
-
-	log.Debug("ValidateTX3ProofData - end")
-	return nil
+	return fmt.Errorf("tx3 %x not proven in the side chain block", txHash)
 }
 
 func (cch *CrossChainHelper) ValidateTX4WithInMemTX3ProofData(tx4 *types.Transaction, tx3ProofData *types.TX3ProofData) error {
@@ -615,6 +727,10 @@ func (cch *CrossChainHelper) ValidateTX4WithInMemTX3ProofData(tx4 *types.Transac
 	if err != nil {
 		return err
 	}
+	// The TX4 redeems the TX3 it names, which is the one recorded as withdrawn
+	if tx3.Hash() != args.TxHash {
+		return errors.New("params are not consistent with tx in side chain")
+	}
 
 	signer2 := types.NewEIP155Signer(tx3.ChainId())
 	tx3From, err := types.Sender(signer2, &tx3)
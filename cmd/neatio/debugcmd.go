@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/core/vm"
+	"github.com/neatlab/neatio/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	replayFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block to replay",
+		Value: 1,
+	}
+	replayToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block to replay (default = --from)",
+	}
+
+	debugCommand = cli.Command{
+		Name:     "debug",
+		Usage:    "Debugging tools for the local chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:   "replay",
+				Usage:  "Re-execute a block range and compare the results with the stored ones",
+				Action: utils.MigrateFlags(replayBlocks),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.VMEnableDebugFlag,
+					replayFromFlag,
+					replayToFlag,
+				},
+				Description: `
+    neatio debug replay --from <number> [--to <number>]
+
+Re-executes every block of the range on top of the stored state of its parent
+and compares the gas used, the receipts, the receipt root and the state root
+with the stored values. The first divergence is printed and the command stops
+there. Every block is replayed on a copy of the epoch, so the stored epoch is
+left as is. Nothing is written to the database; the state of the parent of --from
+must be available, which it is not on pruned nodes for older blocks.`,
+			},
+			walCommand,
+		},
+	}
+)
+
+func replayBlocks(ctx *cli.Context) error {
+	from := ctx.Uint64(replayFromFlag.Name)
+	to := from
+	if ctx.IsSet(replayToFlag.Name) {
+		to = ctx.Uint64(replayToFlag.Name)
+	}
+	if from == 0 || to < from {
+		utils.Fatalf("Invalid block range %d..%d", from, to)
+	}
+
+	stack, cfg := makeConfigNode(ctx, clientIdentifier)
+	defer stack.Close()
+
+	chain, chainDb, engine := utils.MakeChainWithEngine(ctx, stack, cfg.Node.NodeKey(), GetCMInstance(ctx).cch)
+	defer chainDb.Close()
+	defer engine.Close()
+	defer chain.Stop()
+
+	if head := chain.CurrentBlock().NumberU64(); to > head {
+		utils.Fatalf("Block %d is beyond the current head %d", to, head)
+	}
+
+	// Finalize updates the epoch of the engine in place, every block is
+	// replayed on a copy of the epoch as loaded
+	neatpos, ok := engine.(consensus.NeatPoS)
+	if !ok {
+		utils.Fatalf("Replay needs the NeatPoS engine")
+	}
+	current := neatpos.GetEpoch()
+	defer neatpos.SetEpoch(current)
+
+	start := time.Now()
+	for number := from; number <= to; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			utils.Fatalf("Block %d not found", number)
+		}
+		parent := chain.GetBlock(block.ParentHash(), number-1)
+		if parent == nil {
+			utils.Fatalf("Parent of block %d not found", number)
+		}
+		statedb, err := state.New(parent.Root(), chain.StateCache())
+		if err != nil {
+			utils.Fatalf("State of block %d is not available: %v", number-1, err)
+		}
+		neatpos.SetEpoch(current.Copy())
+
+		if divergence := replayBlock(chain, block, statedb, vm.Config{EnablePreimageRecording: ctx.GlobalBool(utils.VMEnableDebugFlag.Name)}); divergence != "" {
+			fmt.Printf("Divergence at block %d (%x):\n%s\n", number, block.Hash(), divergence)
+			return fmt.Errorf("replay diverged at block %d", number)
+		}
+		log.Info("Replayed block", "number", number, "hash", block.Hash(), "txs", len(block.Transactions()))
+	}
+	fmt.Printf("Replayed blocks %d..%d without divergence in %v\n", from, to, time.Since(start))
+	return nil
+}
+
+// replayBlock re-executes the block on statedb and describes the first
+// difference to the stored results, empty if there is none.
+func replayBlock(chain *core.BlockChain, block *types.Block, statedb *state.StateDB, cfg vm.Config) string {
+	receipts, _, usedGas, _, err := chain.Processor().Process(block, statedb, cfg)
+	if err != nil {
+		return fmt.Sprintf("  execution failed: %v", err)
+	}
+
+	stored := chain.GetReceiptsByHash(block.Hash())
+	if len(stored) != len(receipts) {
+		return fmt.Sprintf("  receipt count: stored %d, replayed %d", len(stored), len(receipts))
+	}
+	for i, receipt := range receipts {
+		if diff := diffReceipt(stored[i], receipt); diff != "" {
+			return fmt.Sprintf("  tx %d (%x): %s", i, block.Transactions()[i].Hash(), diff)
+		}
+	}
+	if block.GasUsed() != usedGas {
+		return fmt.Sprintf("  gas used: stored %d, replayed %d", block.GasUsed(), usedGas)
+	}
+	if root := types.DeriveSha(receipts); root != block.ReceiptHash() {
+		return fmt.Sprintf("  receipt root: stored %x, replayed %x", block.ReceiptHash(), root)
+	}
+	if root := statedb.IntermediateRoot(chain.Config().IsEIP158(block.Number())); root != block.Root() {
+		return fmt.Sprintf("  state root: stored %x, replayed %x", block.Root(), root)
+	}
+	return ""
+}
+
+// diffReceipt compares the consensus fields of two receipts.
+func diffReceipt(stored, replayed *types.Receipt) string {
+	switch {
+	case stored.Status != replayed.Status:
+		return fmt.Sprintf("status: stored %d, replayed %d", stored.Status, replayed.Status)
+	case !bytes.Equal(stored.PostState, replayed.PostState):
+		return fmt.Sprintf("post state: stored %x, replayed %x", stored.PostState, replayed.PostState)
+	case stored.CumulativeGasUsed != replayed.CumulativeGasUsed:
+		return fmt.Sprintf("cumulative gas: stored %d, replayed %d", stored.CumulativeGasUsed, replayed.CumulativeGasUsed)
+	case stored.GasUsed != replayed.GasUsed:
+		return fmt.Sprintf("gas used: stored %d, replayed %d", stored.GasUsed, replayed.GasUsed)
+	case len(stored.Logs) != len(replayed.Logs):
+		return fmt.Sprintf("log count: stored %d, replayed %d", len(stored.Logs), len(replayed.Logs))
+	case stored.Bloom != replayed.Bloom:
+		return "logs bloom differs"
+	}
+	for i := range stored.Logs {
+		s, r := stored.Logs[i], replayed.Logs[i]
+		if s.Address != r.Address || !bytes.Equal(s.Data, r.Data) || !equalTopics(s.Topics, r.Topics) {
+			return fmt.Sprintf("log %d: stored %v, replayed %v", i, s, r)
+		}
+	}
+	return ""
+}
+
+func equalTopics(a, b []common.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
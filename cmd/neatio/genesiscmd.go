@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neatlab/neatio/accounts/keystore"
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/math"
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/params"
+	blsCrypto "github.com/neatlib/crypto-go"
+	"gopkg.in/urfave/cli.v1"
+	"gopkg.in/yaml.v2"
+)
+
+var initGenesisCmd = cli.Command{
+	Action:    utils.MigrateFlags(initGenesisFromSpec),
+	Name:      "init-genesis",
+	Usage:     "Build the genesis files of a network from a JSON or YAML spec",
+	ArgsUsage: "<specPath>",
+	Flags: []cli.Flag{
+		utils.DataDirFlag,
+	},
+	Category: "BLOCKCHAIN COMMANDS",
+	Description: `
+    neatio init-genesis <spec.json|spec.yaml>
+
+Writes the NEAT genesis and the consensus genesis of the chain named in the
+spec into the data directory, the same files init-neatio creates. Example:
+
+    chainId: testnet
+    consensus:
+      epochLength: 14400
+      rewardPerBlock: "50000000000000000000"
+    validators:
+      - name: val0
+        stake: "100000000000000000000000"
+        balance: "1000000000000000000000000"
+      - name: val1
+        address: NEATxxxxxxxxxxxxxxxxxxxxxxxxxxxx
+        pubkey: 0x...
+        stake: "100000000000000000000000"
+    accounts:
+      - address: NEATyyyyyyyyyyyyyyyyyyyyyyyyyyyy
+        balance: "5000000000000000000000"
+
+The chainId is the main chain, neatio or testnet, or the name of a side chain
+run on its own, for instance a devnet. The transactions of a side chain are
+signed with the chain id derived from its name, numericChainId sets another.
+
+A validator without a pubkey gets a new consensus key written to a priv
+validator file, and one without an address also gets a new account in the
+keystore protected by the default password. Amounts are in wei.`,
+}
+
+// genesisSpec is the input of init-genesis.
+type genesisSpec struct {
+	ChainId        string                 `json:"chainId" yaml:"chainId"`
+	NumericChainId uint64                 `json:"numericChainId" yaml:"numericChainId"` // Side chains only
+	GasLimit       uint64                 `json:"gasLimit" yaml:"gasLimit"`
+	Consensus      genesisConsensusSpec   `json:"consensus" yaml:"consensus"`
+	Validators     []genesisValidatorSpec `json:"validators" yaml:"validators"`
+	Accounts       []genesisAccountSpec   `json:"accounts" yaml:"accounts"`
+}
+
+// sideChainName matches the names CanCreateSideChain accepts.
+var sideChainName = regexp.MustCompile("^[a-z]+[a-z0-9_]*$")
+
+type genesisConsensusSpec struct {
+	EpochLength    uint64 `json:"epochLength" yaml:"epochLength"`
+	RewardPerBlock string `json:"rewardPerBlock" yaml:"rewardPerBlock"`
+	TotalReward    string `json:"totalReward" yaml:"totalReward"`
+	TotalYear      uint64 `json:"totalYear" yaml:"totalYear"`
+	EpochsPerYear  uint64 `json:"epochsPerYear" yaml:"epochsPerYear"`
+}
+
+type genesisValidatorSpec struct {
+	Name    string `json:"name" yaml:"name"`
+	Address string `json:"address" yaml:"address"`
+	PubKey  string `json:"pubkey" yaml:"pubkey"`
+	Stake   string `json:"stake" yaml:"stake"`
+	Balance string `json:"balance" yaml:"balance"`
+}
+
+type genesisAccountSpec struct {
+	Address string `json:"address" yaml:"address"`
+	Balance string `json:"balance" yaml:"balance"`
+}
+
+func initGenesisFromSpec(ctx *cli.Context) error {
+	specPath := ctx.Args().First()
+	if len(specPath) == 0 {
+		utils.Fatalf("must supply path to genesis spec file")
+	}
+	spec, err := loadGenesisSpec(specPath)
+	if err != nil {
+		utils.Fatalf("Invalid genesis spec: %v", err)
+	}
+	if err := spec.validate(); err != nil {
+		utils.Fatalf("Invalid genesis spec: %v", err)
+	}
+
+	config := utils.GetNeatConConfig(spec.ChainId, ctx)
+	neatGenesisPath, genesisPath := config.GetString("neat_genesis_file"), config.GetString("genesis_file")
+	for _, path := range []string{neatGenesisPath, genesisPath} {
+		if _, err := os.Stat(path); err == nil {
+			utils.Fatalf("Genesis file %s already exists", path)
+		}
+	}
+
+	// Keys are only generated once the whole spec has been checked
+	var (
+		ks            *keystore.KeyStore
+		privValidator []*types.PrivValidator
+		validators    = make([]types.GenesisValidator, len(spec.Validators))
+	)
+	for i, v := range spec.Validators {
+		validators[i] = types.GenesisValidator{
+			Name:   v.Name,
+			Amount: math.MustParseBig256(v.Stake),
+		}
+		if v.Address != "" {
+			validators[i].EthAccount = common.StringToAddress(v.Address)
+		} else {
+			if ks == nil {
+				ks = keystore.NewKeyStore(config.GetString("keystore"), keystore.StandardScryptN, keystore.StandardScryptP)
+			}
+			account, err := ks.NewAccount(DefaultAccountPassword)
+			if err != nil {
+				utils.Fatalf("Failed to create account: %v", err)
+			}
+			validators[i].EthAccount = account.Address
+		}
+		if v.PubKey != "" {
+			validators[i].PubKey, _ = parseBLSPubKey(v.PubKey)
+		} else {
+			pv := types.GenPrivValidatorKey(validators[i].EthAccount)
+			validators[i].PubKey = pv.PubKey
+			privValidator = append(privValidator, pv)
+		}
+	}
+
+	coreGenesis := spec.coreGenesis(validators)
+	genDoc := spec.genesisDoc(validators)
+	if err := verifyGenesis(coreGenesis, genDoc); err != nil {
+		utils.Fatalf("Genesis verification failed: %v", err)
+	}
+
+	contents, err := json.MarshalIndent(coreGenesis, "", "\t")
+	if err != nil {
+		utils.Fatalf("marshal coreGenesis failed")
+	}
+	if err := os.MkdirAll(filepath.Dir(neatGenesisPath), 0700); err != nil {
+		utils.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := ioutil.WriteFile(neatGenesisPath, contents, 0644); err != nil {
+		utils.Fatalf("write neat_genesis_file failed: %v", err)
+	}
+	if err := genDoc.SaveAs(genesisPath); err != nil {
+		utils.Fatalf("write genesis_file failed: %v", err)
+	}
+
+	privValFile := config.GetString("priv_validator_file_root")
+	for i, pv := range privValidator {
+		if i > 0 {
+			pv.SetFile(privValFile + strconv.Itoa(i) + ".json")
+		} else {
+			pv.SetFile(privValFile + ".json")
+		}
+		pv.Save()
+		log.Info("Created priv validator file", "address", pv.Address, "index", i)
+	}
+
+	fmt.Printf("Genesis of %s written to %s and %s (%d validators, %d priv validator files)\n",
+		spec.ChainId, neatGenesisPath, genesisPath, len(validators), len(privValidator))
+	return nil
+}
+
+func loadGenesisSpec(path string) (*genesisSpec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spec := new(genesisSpec)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, spec)
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(spec)
+	}
+	return spec, err
+}
+
+// validate checks the spec and fills in the defaults of the consensus
+// parameters, nothing is generated or written before it passed.
+func (s *genesisSpec) validate() error {
+	if params.IsMainChain(s.ChainId) {
+		if s.NumericChainId != 0 {
+			return fmt.Errorf("numericChainId is fixed for %s", s.ChainId)
+		}
+	} else {
+		if !sideChainName.MatchString(s.ChainId) || len(s.ChainId) > 30 {
+			return fmt.Errorf("chainId must be %q, %q or a side chain name of lower case letters, digits and underscores", MainChain, TestnetChain)
+		}
+		if s.NumericChainId == 0 {
+			s.NumericChainId = params.SideChainId(s.ChainId).Uint64()
+		}
+	}
+	if s.GasLimit == 0 {
+		s.GasLimit = 0x7270e00
+	}
+
+	c := &s.Consensus
+	if c.EpochLength == 0 {
+		c.EpochLength = 14400
+	}
+	if c.RewardPerBlock == "" {
+		c.RewardPerBlock = new(big.Int).Mul(big.NewInt(1e+18), big.NewInt(50)).String()
+	}
+	if c.TotalReward == "" {
+		c.TotalReward = POSReward
+	}
+	if c.TotalYear == 0 {
+		c.TotalYear = TotalYear
+	}
+	if c.EpochsPerYear == 0 {
+		c.EpochsPerYear = 2191
+	}
+	for name, amount := range map[string]string{"rewardPerBlock": c.RewardPerBlock, "totalReward": c.TotalReward} {
+		if _, ok := math.ParseBig256(amount); !ok {
+			return fmt.Errorf("invalid consensus %s %q", name, amount)
+		}
+	}
+
+	if len(s.Validators) == 0 {
+		return errors.New("at least one validator is required")
+	}
+	seen := make(map[string]bool)
+	checkAddress := func(address string) error {
+		if address == "" {
+			return nil
+		}
+		if !crypto.ValidateNeatAddr(address) {
+			return fmt.Errorf("invalid address %s", address)
+		}
+		if seen[address] {
+			return fmt.Errorf("duplicate address %s", address)
+		}
+		seen[address] = true
+		return nil
+	}
+	pubkeys := make(map[string]bool)
+	for i, v := range s.Validators {
+		if err := checkAddress(v.Address); err != nil {
+			return fmt.Errorf("validator %d: %v", i, err)
+		}
+		if v.PubKey != "" {
+			if v.Address == "" {
+				return fmt.Errorf("validator %d: pubkey given without address", i)
+			}
+			if _, err := parseBLSPubKey(v.PubKey); err != nil {
+				return fmt.Errorf("validator %d: %v", i, err)
+			}
+			if pubkeys[strings.ToLower(v.PubKey)] {
+				return fmt.Errorf("validator %d: duplicate pubkey", i)
+			}
+			pubkeys[strings.ToLower(v.PubKey)] = true
+		}
+		if stake, ok := math.ParseBig256(v.Stake); !ok || stake.Sign() <= 0 {
+			return fmt.Errorf("validator %d: stake must be a positive amount", i)
+		}
+		if v.Balance != "" {
+			if _, ok := math.ParseBig256(v.Balance); !ok {
+				return fmt.Errorf("validator %d: invalid balance %q", i, v.Balance)
+			}
+		}
+	}
+	for i, a := range s.Accounts {
+		if a.Address == "" {
+			return fmt.Errorf("account %d: address is required", i)
+		}
+		if err := checkAddress(a.Address); err != nil {
+			return fmt.Errorf("account %d: %v", i, err)
+		}
+		if _, ok := math.ParseBig256(a.Balance); !ok {
+			return fmt.Errorf("account %d: invalid balance %q", i, a.Balance)
+		}
+	}
+	return nil
+}
+
+func (s *genesisSpec) coreGenesis(validators []types.GenesisValidator) *core.GenesisWrite {
+	var chainConfig *params.ChainConfig
+	switch s.ChainId {
+	case MainChain:
+		chainConfig = params.MainnetChainConfig
+	case TestnetChain:
+		chainConfig = params.TestnetChainConfig
+	default:
+		chainConfig = params.NewSideChainConfig(s.ChainId, new(big.Int).SetUint64(s.NumericChainId))
+	}
+	genesis := &core.GenesisWrite{
+		Config:     chainConfig,
+		Nonce:      0xdeadbeefdeadbeef,
+		Timestamp:  uint64(time.Now().Unix()),
+		ParentHash: common.Hash{},
+		GasLimit:   s.GasLimit,
+		Difficulty: new(big.Int).SetUint64(0x01),
+		Mixhash:    common.Hash{},
+		Coinbase:   "NEATAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+		Alloc:      core.GenesisAllocWrite{},
+	}
+	for i, v := range validators {
+		balance := big.NewInt(0)
+		if s.Validators[i].Balance != "" {
+			balance = math.MustParseBig256(s.Validators[i].Balance)
+		}
+		genesis.Alloc[v.EthAccount.String()] = core.GenesisAccount{
+			Balance: balance,
+			Amount:  v.Amount,
+		}
+	}
+	for _, a := range s.Accounts {
+		genesis.Alloc[a.Address] = core.GenesisAccount{
+			Balance: math.MustParseBig256(a.Balance),
+			Amount:  common.Big0,
+		}
+	}
+	return genesis
+}
+
+func (s *genesisSpec) genesisDoc(validators []types.GenesisValidator) *types.GenesisDoc {
+	totalReward := math.MustParseBig256(s.Consensus.TotalReward)
+	rewardFirstYear := big.NewInt(0)
+	if s.Consensus.TotalYear > 0 {
+		rewardFirstYear.Div(totalReward, new(big.Int).SetUint64(s.Consensus.TotalYear))
+	}
+	return &types.GenesisDoc{
+		ChainID:     s.ChainId,
+		Consensus:   types.Consensus_NeatPoS,
+		GenesisTime: time.Now(),
+		RewardScheme: types.RewardSchemeDoc{
+			TotalReward:        totalReward,
+			RewardFirstYear:    rewardFirstYear,
+			EpochNumberPerYear: s.Consensus.EpochsPerYear,
+			TotalYear:          s.Consensus.TotalYear,
+		},
+		CurrentEpoch: types.OneEpochDoc{
+			Number:         0,
+			RewardPerBlock: math.MustParseBig256(s.Consensus.RewardPerBlock),
+			StartBlock:     0,
+			EndBlock:       s.Consensus.EpochLength,
+			Status:         0,
+			Validators:     validators,
+		},
+	}
+}
+
+// verifyGenesis round-trips both genesis files through the decoders used by
+// the node, commits the genesis state into a scratch database and checks that
+// the stake of every validator is locked in its genesis account.
+func verifyGenesis(genesisW *core.GenesisWrite, genDoc *types.GenesisDoc) error {
+	contents, err := json.Marshal(genesisW)
+	if err != nil {
+		return err
+	}
+	block, err := core.WriteGenesisBlock(rawdb.NewMemoryDatabase(), bytes.NewReader(contents))
+	if err != nil {
+		return err
+	}
+	log.Info("Genesis block verified", "hash", block.Hash())
+
+	file, err := ioutil.TempFile("", "neatio-genesis")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	if err := genDoc.SaveAs(file.Name()); err != nil {
+		return err
+	}
+	docBytes, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	decoded, err := types.GenesisDocFromJSON(docBytes)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range decoded.CurrentEpoch.Validators {
+		account, ok := genesisW.Alloc[v.EthAccount.String()]
+		if !ok {
+			return fmt.Errorf("validator %s has no genesis account", v.EthAccount.String())
+		}
+		if account.Amount == nil || account.Amount.Cmp(v.Amount) != 0 {
+			return fmt.Errorf("stake of validator %s is not locked in its genesis account", v.EthAccount.String())
+		}
+	}
+	return nil
+}
+
+func parseBLSPubKey(s string) (blsCrypto.BLSPubKey, error) {
+	var pubkey blsCrypto.BLSPubKey
+	b := common.FromHex(s)
+	if len(b) != len(pubkey) {
+		return pubkey, fmt.Errorf("invalid BLS pubkey %s", s)
+	}
+	copy(pubkey[:], b)
+	return pubkey, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/neatptc"
+)
+
+// healthResponse is served by the /health and /ready endpoints.
+type healthResponse struct {
+	Healthy bool                             `json:"healthy"`
+	Ready   bool                             `json:"ready"`
+	Chains  map[string]*neatptc.HealthStatus `json:"chains"`
+}
+
+// health checks all running chains.
+func (cm *ChainManager) health() *healthResponse {
+	minPeers := cm.ctx.GlobalInt(utils.HealthMinPeersFlag.Name)
+	signWindow := cm.ctx.GlobalUint64(utils.HealthSignWindowFlag.Name)
+
+	chains := []*Chain{cm.mainChain}
+	cm.createSideChainLock.Lock()
+	for _, chain := range cm.sideChains {
+		chains = append(chains, chain)
+	}
+	cm.createSideChainLock.Unlock()
+
+	resp := &healthResponse{Healthy: true, Ready: true, Chains: make(map[string]*neatptc.HealthStatus)}
+	for _, chain := range chains {
+		neatChain, err := getNeatChainFromNode(chain.NeatNode)
+		if err != nil {
+			// The chain is not running (yet)
+			resp.Ready = false
+			continue
+		}
+		status := neatChain.Health(minPeers, signWindow)
+		resp.Chains[chain.Id] = status
+		resp.Healthy = resp.Healthy && status.Healthy
+		resp.Ready = resp.Ready && status.Ready
+	}
+	return resp
+}
+
+// healthHandler serves the health of the chains, replying with 503 if the
+// checked condition is not met: liveness for /health, readiness for /ready.
+func (cm *ChainManager) healthHandler(readiness bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := cm.health()
+		ok := resp.Healthy
+		if readiness {
+			ok = resp.Ready
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+}
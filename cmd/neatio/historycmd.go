@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"time"
+
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/neatptc/history"
+	"github.com/neatlab/neatio/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	historyAddressFlag = cli.StringFlag{
+		Name:  "address",
+		Usage: "Address whose history is exported",
+	}
+	historyFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block exported",
+		Value: 1,
+	}
+	historyToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block exported (default = current head)",
+	}
+	historyFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the export, csv or json",
+		Value: "csv",
+	}
+	historyOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File the history is written to (default = stdout)",
+	}
+	historyNoRewardsFlag = cli.BoolFlag{
+		Name:  "norewards",
+		Usage: "Do not export the staking rewards, which need the states of an archive node",
+	}
+
+	exportHistoryCommand = cli.Command{
+		Name:     "export-history",
+		Usage:    "Export the value movements of an address for accounting",
+		Action:   utils.MigrateFlags(exportHistory),
+		Category: "BLOCKCHAIN COMMANDS",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.TestnetFlag,
+			historyAddressFlag,
+			historyFromFlag,
+			historyToFlag,
+			historyFormatFlag,
+			historyOutputFlag,
+			historyNoRewardsFlag,
+		},
+		Description: `
+    neatio export-history --address <address> [--from <number>] [--to <number>] [--format csv|json] [--output <file>]
+
+Scans the blocks of the range and their receipts and writes, in the order of
+the chain, every movement of value of the address: the transfers it sent and
+received, the fees it paid, its deposits to and withdrawals from the side
+chains, its delegations, registrations and reward withdrawals, and the staking
+rewards credited to it by the blocks. The amounts are in wei and the times in
+UTC. The transfers made by contracts are not listed.
+
+The staking rewards and the amounts of the reward withdrawals are found from
+the states of the blocks, which only archive nodes keep; --norewards exports
+the rest of the history on the other nodes.`,
+	}
+)
+
+func exportHistory(ctx *cli.Context) error {
+	address := ctx.String(historyAddressFlag.Name)
+	if !crypto.ValidateNeatAddr(address) {
+		utils.Fatalf("Invalid address %q", address)
+	}
+
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	from, to := ctx.Uint64(historyFromFlag.Name), chain.CurrentBlock().NumberU64()
+	if ctx.IsSet(historyToFlag.Name) {
+		if to = ctx.Uint64(historyToFlag.Name); to > chain.CurrentBlock().NumberU64() {
+			utils.Fatalf("Block %d is beyond the current head %d", to, chain.CurrentBlock().NumberU64())
+		}
+	}
+	if from == 0 || to < from {
+		utils.Fatalf("Invalid block range %d..%d", from, to)
+	}
+
+	var out io.Writer = os.Stdout
+	if ctx.IsSet(historyOutputFlag.Name) {
+		file, err := os.OpenFile(ctx.String(historyOutputFlag.Name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			utils.Fatalf("Failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	buffered := bufio.NewWriter(out)
+	writer, err := history.NewWriter(buffered, ctx.String(historyFormatFlag.Name))
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+
+	mainChain := params.MainnetChainConfig.NeatChainId
+	if ctx.GlobalBool(utils.TestnetFlag.Name) {
+		mainChain = params.TestnetChainConfig.NeatChainId
+	}
+	exporter := history.NewExporter(chain, mainChain, common.StringToAddress(address))
+	exporter.Rewards = !ctx.Bool(historyNoRewardsFlag.Name)
+
+	var (
+		start   = time.Now()
+		entries int
+	)
+	err = exporter.Export(from, to, func(entry *history.Entry) error {
+		entries++
+		return writer.Write(entry)
+	})
+	if err != nil {
+		utils.Fatalf("History export failed: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		utils.Fatalf("Failed to write history: %v", err)
+	}
+	if err := buffered.Flush(); err != nil {
+		utils.Fatalf("Failed to write history: %v", err)
+	}
+	log.Info("History exported", "address", address, "from", from, "to", to, "entries", entries, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/neatlab/neatio/params"
 	cmn "github.com/neatlib/common-go"
 	cfg "github.com/neatlib/config-go"
+	crypto "github.com/neatlib/crypto-go"
 	dbm "github.com/neatlib/db-go"
 	"github.com/pkg/errors"
 )
@@ -350,7 +351,26 @@ func createGenesisDoc(config cfg.Config, chainId string, coreGenesis *core.Genes
 	return nil
 }
 
-func generateNCGenesis(sideChainID string, validators []types.GenesisValidator) ([]byte, error) {
+// sideChainGenesisValidators returns the validators of the genesis of a side
+// chain, the ones which joined it on the main chain, in the order they joined.
+func sideChainGenesisValidators(cci *core.CoreChainInfo) []types.GenesisValidator {
+	validators := make([]types.GenesisValidator, 0, len(cci.JoinedValidators))
+	for _, v := range cci.JoinedValidators {
+		pubkey := v.PubKey
+		// dereference the PubKey
+		if bls, ok := pubkey.(*crypto.BLSPubKey); ok {
+			pubkey = *bls
+		}
+		validators = append(validators, types.GenesisValidator{
+			EthAccount: v.Address,
+			PubKey:     pubkey,
+			Amount:     v.DepositAmount,
+		})
+	}
+	return validators
+}
+
+func generateNCGenesis(sideChainID string, validators []types.GenesisValidator, genesisTime time.Time) ([]byte, error) {
 	var rewardScheme = types.RewardSchemeDoc{
 		TotalReward:        big.NewInt(0),
 		RewardFirstYear:    big.NewInt(0),
@@ -361,7 +381,7 @@ func generateNCGenesis(sideChainID string, validators []types.GenesisValidator)
 	genDoc := types.GenesisDoc{
 		ChainID:      sideChainID,
 		Consensus:    types.Consensus_NeatPoS,
-		GenesisTime:  time.Now(),
+		GenesisTime:  genesisTime,
 		RewardScheme: rewardScheme,
 		CurrentEpoch: types.OneEpochDoc{
 			Number:         0,
@@ -455,9 +475,9 @@ func checkAccount(coreGenesis core.Genesis) (common.Address, *big.Int, error) {
 	return act, amount, nil
 }
 
-func initEthGenesisFromExistValidator(sideChainID string, childConfig cfg.Config, validators []types.GenesisValidator) error {
+func initEthGenesisFromExistValidator(sideChainID string, chainId *big.Int, childConfig cfg.Config, validators []types.GenesisValidator) error {
 
-	contents, err := generateETHGenesis(sideChainID, validators)
+	contents, err := generateETHGenesis(sideChainID, chainId, validators)
 	if err != nil {
 		return err
 	}
@@ -469,9 +489,9 @@ func initEthGenesisFromExistValidator(sideChainID string, childConfig cfg.Config
 	return nil
 }
 
-func generateETHGenesis(sideChainID string, validators []types.GenesisValidator) ([]byte, error) {
+func generateETHGenesis(sideChainID string, chainId *big.Int, validators []types.GenesisValidator) ([]byte, error) {
 	var coreGenesis = core.Genesis{
-		Config:     params.NewSideChainConfig(sideChainID),
+		Config:     params.NewSideChainConfig(sideChainID, chainId),
 		Nonce:      0xdeadbeefdeadbeef,
 		Timestamp:  0x0,
 		ParentHash: common.Hash{},
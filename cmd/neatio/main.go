@@ -13,6 +13,7 @@ import (
 	"github.com/neatlab/neatio/cmd/utils"
 	"github.com/neatlab/neatio/console"
 	"github.com/neatlab/neatio/internal/debug"
+	"github.com/neatlab/neatio/internal/tracing"
 	"github.com/neatlab/neatio/metrics"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -38,6 +39,10 @@ var (
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
+		utils.KeyStoreScryptNFlag,
+		utils.KeyStoreScryptPFlag,
+		utils.KeyStoreKDFFlag,
+		utils.WatchOnlyFlag,
 		utils.NoUSBFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
@@ -52,6 +57,13 @@ var (
 		//utils.FastSyncFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.PruneFlag,
+		utils.PruneBlockFlag,
+		utils.PruneMainChainFlag,
+		utils.PruneRetentionFlag,
+		utils.TxSearchFlag,
+		utils.PipelinedCommitFlag,
+		utils.ShutdownTimeoutFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
@@ -69,6 +81,7 @@ var (
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.RequireAEADFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.TestnetFlag,
@@ -76,16 +89,38 @@ var (
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
+		utils.RosettaAddrFlag,
+		utils.RosettaPortFlag,
+		utils.GRPCAddrFlag,
+		utils.GRPCPortFlag,
+		utils.HealthMinPeersFlag,
+		utils.HealthSignWindowFlag,
 		//utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsHTTPFlag,
+		utils.MetricsPortFlag,
+		utils.TracingEndpointFlag,
+		utils.StallProfileFlag,
+		utils.FirehoseFlag,
+		utils.FirehoseStartFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.AlertWebhookFlag,
+		utils.AlertCommandFlag,
+		utils.AlertStallFlag,
+		utils.AlertRoundFlag,
+		utils.AlertMissedFlag,
 		utils.ExtraDataFlag,
-		//configFileFlag,
+		configFileFlag,
 
 		//utils.LogDirFlag,
 		utils.SideChainFlag,
+		utils.PruneRetiredChainsFlag,
+		utils.RelayerFlag,
+		utils.RelayerAccountsFlag,
+		utils.RelayerMaxGasPriceFlag,
+		utils.RelayerRetriesFlag,
 	}
 
 	rpcFlags = []cli.Flag{
@@ -98,6 +133,16 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCRouterFlag,
+		utils.RPCMaxConnsFlag,
+		utils.RPCAuditLogFlag,
+		utils.RPCTLSCertFlag,
+		utils.RPCTLSKeyFlag,
+		utils.RPCTLSClientCAFlag,
+		utils.AuthRPCEnabledFlag,
+		utils.AuthRPCListenAddrFlag,
+		utils.AuthRPCPortFlag,
+		utils.AuthRPCJWTSecretFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -112,6 +157,8 @@ func init() {
 		// See chaincmd.go:
 		createValidatorCmd,
 		initNeatGenesisCmd,
+		// See genesiscmd.go:
+		initGenesisCmd,
 		initCommand,
 		//initSideChainCmd,
 		importCommand,
@@ -119,10 +166,23 @@ func init() {
 		copydbCommand,
 		removedbCommand,
 		dumpCommand,
+		// See backupcmd.go:
+		backupCommand,
+		// See debugcmd.go:
+		debugCommand,
+		// See snapshotcmd.go:
+		snapshotCommand,
+		// See historycmd.go:
+		exportHistoryCommand,
+		// See childchaincmd.go:
+		childChainCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
 		accountCommand,
+		txCommand,
+		// See validatorcmd.go:
+		validatorCommand,
 		//walletCommand,
 		// See consolecmd.go:
 		consoleCommand,
@@ -146,8 +206,10 @@ func init() {
 		runtime.GOMAXPROCS(runtime.NumCPU())
 
 		// Setup the Global Logger
-
-		log.NewLogger("", "", ctx.GlobalInt("verbosity"), ctx.GlobalBool("debug"), ctx.GlobalString("vmodule"), ctx.GlobalString("backtrace"))
+		if format := ctx.GlobalString("log.format"); !log.ValidFormat(format) {
+			return fmt.Errorf("unknown log format %q", format)
+		}
+		log.NewLogger("", "", ctx.GlobalInt("verbosity"), ctx.GlobalBool("debug"), ctx.GlobalString("vmodule"), ctx.GlobalString("backtrace"), ctx.GlobalString("log.format"))
 
 		if err := debug.Setup(ctx); err != nil {
 			return err
@@ -155,11 +217,14 @@ func init() {
 
 		// Start system runtime metrics collection
 		go metrics.CollectProcessMetrics(3 * time.Second)
+		utils.SetupMetrics(ctx)
+		utils.SetupTracing(ctx)
 
 		return nil
 	}
 
 	app.After = func(ctx *cli.Context) error {
+		tracing.Stop()
 		debug.Exit()
 		console.Stdin.Close() // Resets terminal mode.
 		return nil
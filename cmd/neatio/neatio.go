@@ -6,6 +6,7 @@ import (
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/neatlab/neatio/cmd/utils"
 	"github.com/neatlab/neatio/consensus/neatpos/consensus"
@@ -73,6 +74,7 @@ func neatioCmd(ctx *cli.Context) error {
 	}
 
 	chainMgr.StartInspectEvent()
+	chainMgr.StartRelayer()
 
 	go func() {
 		sigc := make(chan os.Signal, 1)
@@ -81,6 +83,14 @@ func neatioCmd(ctx *cli.Context) error {
 		<-sigc
 		log.Info("Got interrupt, shutting down...")
 
+		if timeout := chainMgr.ShutdownTimeout(); timeout > 0 {
+			time.AfterFunc(timeout, func() {
+				log.Error("Graceful shutdown timed out, exiting", "timeout", timeout)
+				debug.Exit()
+				os.Exit(1)
+			})
+		}
+
 		chainMgr.StopChain()
 		chainMgr.WaitChainsStop()
 		chainMgr.Stop()
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/accounts"
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/log"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/neatptc"
+	dbm "github.com/neatlib/db-go"
+)
+
+const (
+	relayInterval    = 3 * time.Second // Interval the relayer checks the chains at
+	relayLookback    = 1000            // Side chain blocks before the head scanned when the relayer starts
+	relayBatch       = 1000            // Most side chain blocks scanned per chain and interval
+	relayRetryBlocks = 10              // Main chain blocks waited for a TX4 before sending it again
+	relayExpiry      = 7200            // Main chain blocks a TX3 is relayed for before it is dropped
+
+	// relayerScannedPrefix + chainId is the key of the chain info database the
+	// side chain block the relayer is done with, and resumes the scan after,
+	// is saved at
+	relayerScannedPrefix = "relayer-scanned-"
+)
+
+// relayer relays the withdrawals from the side chains run by the node to the
+// main chain, so that a side chain works without an external relayer. It
+// watches the side chains for TX3s, builds their proofs and saves them on the
+// main chain until they are accepted there. For the accounts configured, it
+// also sends the TX4s redeeming the TX3s, again with a higher gas price when
+// they are not included, until they are or the retries are exhausted. The
+// TX3s neither redeemed nor proven after relayExpiry blocks are dropped.
+type relayer struct {
+	cm *ChainManager
+	db dbm.DB // Database the scanned blocks are saved in
+
+	accounts    map[common.Address]bool // Senders of the TX3s the TX4s are sent for
+	maxGasPrice *big.Int                // Highest gas price bid for a TX4 sent again
+	retries     int                     // Times a TX4 is sent before giving up
+
+	scanned   map[string]uint64                // Last block scanned, by side chain
+	saved     map[string]uint64                // Last block done with saved, by side chain
+	transfers map[common.Hash]*relayedTransfer // TX3s being relayed, by hash
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// relayedTransfer is a TX3 being relayed to the main chain.
+type relayedTransfer struct {
+	chainId string
+	number  uint64 // Block of the side chain including the TX3
+	from    common.Address
+	amount  *big.Int
+	proof   *types.TX3ProofData // Proof of the block of the side chain including the TX3
+	found   uint64              // Main chain block the TX3 was found at
+
+	proven   bool               // Whether the proof is saved on the main chain
+	tx4      *types.Transaction // Last TX4 sent, nil if none
+	sent     uint64             // Main chain block the last TX4 was sent at
+	attempts int                // Times the TX4 was sent
+}
+
+func newRelayer(cm *ChainManager) *relayer {
+	r := &relayer{
+		cm:          cm,
+		db:          cm.cch.chainInfoDB,
+		accounts:    make(map[common.Address]bool),
+		maxGasPrice: utils.GlobalBig(cm.ctx, utils.RelayerMaxGasPriceFlag.Name),
+		retries:     cm.ctx.GlobalInt(utils.RelayerRetriesFlag.Name),
+		scanned:     make(map[string]uint64),
+		saved:       make(map[string]uint64),
+		transfers:   make(map[common.Hash]*relayedTransfer),
+		quit:        make(chan struct{}),
+	}
+	for _, account := range strings.Split(cm.ctx.GlobalString(utils.RelayerAccountsFlag.Name), ",") {
+		if account = strings.TrimSpace(account); account == "" {
+			continue
+		}
+		if !common.IsHexAddress(account) {
+			utils.Fatalf("Invalid relayer account %q", account)
+		}
+		r.accounts[common.HexToAddress(account)] = true
+	}
+	return r
+}
+
+// StartRelayer starts relaying the withdrawals from the side chains if it is
+// enabled.
+func (cm *ChainManager) StartRelayer() {
+	if !cm.ctx.GlobalBool(utils.RelayerFlag.Name) {
+		return
+	}
+	cm.relayer = newRelayer(cm)
+	cm.relayer.wg.Add(1)
+	go cm.relayer.loop()
+
+	log.Info("Cross chain relayer started", "accounts", len(cm.relayer.accounts), "maxgasprice", cm.relayer.maxGasPrice, "retries", cm.relayer.retries)
+}
+
+// stopRelayer stops the relayer, if it runs, and waits for it to exit.
+func (cm *ChainManager) stopRelayer() {
+	if cm.relayer == nil {
+		return
+	}
+	close(cm.relayer.quit)
+	cm.relayer.wg.Wait()
+	cm.relayer = nil
+	log.Info("Cross chain relayer stopped")
+}
+
+func (r *relayer) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(relayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.relay()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// relay scans the side chains for new TX3s and moves the transfers being
+// relayed forward.
+func (r *relayer) relay() {
+	main, err := getNeatChainFromNode(r.cm.mainChain.NeatNode)
+	if err != nil {
+		return
+	}
+
+	// The side chains launched while the node runs are picked up as well
+	sides := make(map[string]*neatptc.NeatChain)
+	r.cm.createSideChainLock.Lock()
+	for chainId, chain := range r.cm.sideChains {
+		if side, err := getNeatChainFromNode(chain.NeatNode); err == nil {
+			sides[chainId] = side
+		}
+	}
+	r.cm.createSideChainLock.Unlock()
+
+	number := main.BlockChain().CurrentBlock().NumberU64()
+	for chainId, side := range sides {
+		r.scan(chainId, side.BlockChain(), number)
+	}
+
+	statedb, err := main.BlockChain().State()
+	if err != nil {
+		log.Error("Relayer failed to load the main chain state", "err", err)
+		return
+	}
+	for hash, transfer := range r.transfers {
+		if r.advance(main, statedb, number, hash, transfer) {
+			delete(r.transfers, hash)
+		}
+	}
+	for chainId := range sides {
+		r.save(chainId)
+	}
+}
+
+// done returns the last block of the side chain the relayer is done with, the
+// one before the first block including a TX3 still relayed.
+func (r *relayer) done(chainId string) uint64 {
+	done := r.scanned[chainId]
+	for _, transfer := range r.transfers {
+		if transfer.chainId == chainId && transfer.number <= done {
+			done = transfer.number - 1
+		}
+	}
+	return done
+}
+
+// save saves the last block of the side chain the relayer is done with, for
+// the scan to resume after it once the node restarts.
+func (r *relayer) save(chainId string) {
+	if _, ok := r.scanned[chainId]; !ok {
+		return
+	}
+	done := r.done(chainId)
+	if saved, ok := r.saved[chainId]; ok && saved == done {
+		return
+	}
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], done)
+	r.db.Set([]byte(relayerScannedPrefix+chainId), enc[:])
+	r.saved[chainId] = done
+}
+
+// loadScanned returns the last block of the side chain the relayer saved it
+// was done with.
+func (r *relayer) loadScanned(chainId string) (uint64, bool) {
+	enc := r.db.Get([]byte(relayerScannedPrefix + chainId))
+	if len(enc) != 8 {
+		return 0, false
+	}
+	done := binary.BigEndian.Uint64(enc)
+	r.saved[chainId] = done
+	return done, true
+}
+
+// scan records the TX3s of the side chain blocks imported since the last scan.
+func (r *relayer) scan(chainId string, bc *core.BlockChain, mainNumber uint64) {
+	head := bc.CurrentBlock().NumberU64()
+	from, ok := r.scanned[chainId]
+	if !ok {
+		if from, ok = r.loadScanned(chainId); !ok && head > relayLookback {
+			from = head - relayLookback
+		}
+	}
+	to := head
+	if to > from+relayBatch {
+		to = from + relayBatch
+	}
+
+	for number := from + 1; number <= to; number++ {
+		block := bc.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		var proof *types.TX3ProofData
+		for _, tx := range block.Transactions() {
+			if !isTX3(tx) {
+				continue
+			}
+			sender, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+			if err != nil {
+				continue
+			}
+			if proof == nil {
+				if proof, err = types.NewTX3ProofData(block); err != nil {
+					log.Error("Relayer failed to build the TX3 proof", "chain", chainId, "number", number, "err", err)
+					break
+				}
+			}
+			r.transfers[tx.Hash()] = &relayedTransfer{
+				chainId: chainId,
+				number:  number,
+				from:    sender,
+				amount:  tx.Value(),
+				proof:   proof,
+				found:   mainNumber,
+			}
+			log.Debug("Relayer found TX3", "chain", chainId, "number", number, "hash", tx.Hash(), "from", sender, "amount", tx.Value())
+		}
+		r.scanned[chainId] = number
+	}
+}
+
+// isTX3 returns whether the transaction withdraws from the side chain.
+func isTX3(tx *types.Transaction) bool {
+	if !neatabi.IsNeatChainContractAddr(tx.To()) || len(tx.Data()) < 4 {
+		return false
+	}
+	function, err := neatabi.FunctionTypeFromId(tx.Data()[:4])
+	return err == nil && function == neatabi.WithdrawFromSideChain
+}
+
+// advance moves the relay of the TX3 forward, returning true once there is
+// nothing left to do for it.
+func (r *relayer) advance(main *neatptc.NeatChain, statedb *state.StateDB, number uint64, hash common.Hash, transfer *relayedTransfer) bool {
+	// Redeemed by a TX4, from the relayer or not
+	if statedb.HasTX3(transfer.from, hash) {
+		if transfer.tx4 != nil {
+			log.Info("Relayer withdrawal completed", "chain", transfer.chainId, "tx3", hash, "tx4", transfer.tx4.Hash(), "from", transfer.from, "amount", transfer.amount)
+		}
+		return true
+	}
+	if transfer.expired(number) {
+		log.Warn("Relayer dropped withdrawal not completed in time", "chain", transfer.chainId, "tx3", hash, "from", transfer.from, "proven", transfer.proven, "attempts", transfer.attempts)
+		return true
+	}
+
+	cch := r.cm.cch
+	if !transfer.proven {
+		if cch.GetTX3ProofData(transfer.chainId, hash) == nil {
+			// The main chain may not know the validators of the block yet
+			if err := cch.ValidateTX3ProofData(transfer.proof); err != nil {
+				log.Debug("Relayer TX3 proof not accepted yet", "chain", transfer.chainId, "tx3", hash, "err", err)
+				return false
+			}
+			if err := cch.WriteTX3ProofData(transfer.proof); err != nil {
+				log.Error("Relayer failed to save the TX3 proof", "chain", transfer.chainId, "tx3", hash, "err", err)
+				return false
+			}
+			main.ApiBackend.BroadcastTX3ProofData(transfer.proof)
+			log.Info("Relayer saved TX3 proof", "chain", transfer.chainId, "tx3", hash, "from", transfer.from)
+		}
+		transfer.proven = true
+	}
+
+	// The other senders redeem their TX3s themselves
+	if !r.accounts[transfer.from] {
+		return true
+	}
+	if fee, _ := statedb.CrossChainFee(transfer.amount); fee.Cmp(transfer.amount) >= 0 {
+		log.Warn("Relayer skipped withdrawal consumed by the cross chain fee", "chain", transfer.chainId, "tx3", hash, "amount", transfer.amount, "fee", fee)
+		return true
+	}
+
+	if transfer.tx4 != nil && number < transfer.sent+relayRetryBlocks {
+		return false
+	}
+	if transfer.attempts >= r.retries {
+		log.Error("Relayer gave up sending TX4", "chain", transfer.chainId, "tx3", hash, "from", transfer.from, "attempts", transfer.attempts)
+		return true
+	}
+	tx4, err := r.sendTX4(main, hash, transfer)
+	if err != nil {
+		log.Warn("Relayer failed to send TX4", "chain", transfer.chainId, "tx3", hash, "from", transfer.from, "err", err)
+	} else {
+		log.Info("Relayer sent TX4", "chain", transfer.chainId, "tx3", hash, "tx4", tx4.Hash(), "gasprice", tx4.GasPrice(), "attempt", transfer.attempts+1)
+		transfer.tx4 = tx4
+	}
+	transfer.sent, transfer.attempts = number, transfer.attempts+1
+	return false
+}
+
+// expired returns whether the TX3 is relayed for relayExpiry main chain blocks
+// at the block.
+func (transfer *relayedTransfer) expired(number uint64) bool {
+	return number >= transfer.found+relayExpiry
+}
+
+// sendTX4 signs and sends the TX4 redeeming the TX3. A TX4 still pending is
+// replaced, bidding a gas price higher by the price bump of the pool, up to
+// the highest gas price of the relayer.
+func (r *relayer) sendTX4(main *neatptc.NeatChain, hash common.Hash, transfer *relayedTransfer) (*types.Transaction, error) {
+	ctx := context.Background()
+
+	price, err := main.ApiBackend.SuggestPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := main.ApiBackend.GetPoolNonce(ctx, transfer.from)
+	if err != nil {
+		return nil, err
+	}
+	if transfer.tx4 != nil && main.TxPool().Get(transfer.tx4.Hash()) != nil {
+		nonce = transfer.tx4.Nonce()
+		bumped := new(big.Int).Mul(transfer.tx4.GasPrice(), big.NewInt(int64(100+core.DefaultTxPoolConfig.PriceBump)))
+		bumped.Div(bumped, big.NewInt(100))
+		if bumped.Cmp(price) > 0 {
+			price = bumped
+		}
+	}
+	if price.Cmp(r.maxGasPrice) > 0 {
+		price = new(big.Int).Set(r.maxGasPrice)
+	}
+
+	input, err := neatabi.ChainABI.Pack(neatabi.WithdrawFromMainChain.String(), transfer.chainId, transfer.amount, hash)
+	if err != nil {
+		return nil, err
+	}
+	// The TX4s are free of gas, as the API sends them
+	tx := types.NewTransaction(nonce, neatabi.ChainContractMagicAddr, new(big.Int), 0, price, input)
+
+	account := accounts.Account{Address: transfer.from}
+	wallet, err := main.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := wallet.SignTxWithAddress(account, tx, main.ChainConfig().ChainId)
+	if err != nil {
+		return nil, err
+	}
+	if err := main.ApiBackend.SendTx(ctx, signed); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	dbm "github.com/neatlib/db-go"
+)
+
+func newTestRelayer(db dbm.DB) *relayer {
+	return &relayer{
+		db:        db,
+		scanned:   make(map[string]uint64),
+		saved:     make(map[string]uint64),
+		transfers: make(map[common.Hash]*relayedTransfer),
+	}
+}
+
+func TestRelayerSaveScanned(t *testing.T) {
+	db := dbm.NewMemDB()
+	r := newTestRelayer(db)
+
+	// Nothing is saved before the chain is scanned
+	r.save("side_0")
+	if _, ok := newTestRelayer(db).loadScanned("side_0"); ok {
+		t.Fatal("scanned block saved before the scan")
+	}
+
+	// The TX3s still relayed hold the saved block back
+	r.scanned["side_0"], r.scanned["side_1"] = 100, 50
+	r.transfers[common.HexToHash("0x01")] = &relayedTransfer{chainId: "side_0", number: 80}
+	r.transfers[common.HexToHash("0x02")] = &relayedTransfer{chainId: "side_0", number: 90}
+	r.transfers[common.HexToHash("0x03")] = &relayedTransfer{chainId: "side_1", number: 20}
+	r.save("side_0")
+	r.save("side_1")
+
+	restarted := newTestRelayer(db)
+	if done, ok := restarted.loadScanned("side_0"); !ok || done != 79 {
+		t.Errorf("side_0 scanned block: have %d %v, want 79", done, ok)
+	}
+	if done, ok := restarted.loadScanned("side_1"); !ok || done != 19 {
+		t.Errorf("side_1 scanned block: have %d %v, want 19", done, ok)
+	}
+
+	// Once done with the TX3s, the scan resumes after the last block scanned
+	delete(r.transfers, common.HexToHash("0x01"))
+	delete(r.transfers, common.HexToHash("0x02"))
+	r.save("side_0")
+	if done, ok := newTestRelayer(db).loadScanned("side_0"); !ok || done != 100 {
+		t.Errorf("side_0 scanned block: have %d %v, want 100", done, ok)
+	}
+	if _, ok := newTestRelayer(db).loadScanned("side_2"); ok {
+		t.Error("scanned block loaded for a side chain never scanned")
+	}
+}
+
+func TestRelayedTransferExpired(t *testing.T) {
+	transfer := &relayedTransfer{found: 1000}
+	if transfer.expired(1000) || transfer.expired(1000+relayExpiry-1) {
+		t.Error("transfer expired early")
+	}
+	if !transfer.expired(1000 + relayExpiry) {
+		t.Error("transfer not expired")
+	}
+}
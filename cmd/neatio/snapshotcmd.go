@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/datareduction"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/neatdb"
+	"github.com/neatlab/neatio/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const snapshotVersion = 1
+
+var (
+	snapshotHeightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "Block height of the state (default = current head)",
+	}
+	pruneBodyFlag = cli.BoolFlag{
+		Name:  "prunebody",
+		Usage: "Also delete the bodies of the pruned blocks",
+	}
+
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "Create, verify and prune state snapshots",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Export the state at a block into a snapshot file",
+				Action:    utils.MigrateFlags(snapshotCreate),
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					snapshotHeightFlag,
+				},
+				Description: `
+    neatio snapshot create [--height <number>] <file>
+
+Writes every trie node and contract code reachable from the state root of the
+block at --height into <file>. Every entry is keyed by its own hash, so the
+snapshot can be checked without trusting the node that produced it.`,
+			},
+			{
+				Name:      "verify",
+				Usage:     "Verify a snapshot file against the local chain",
+				Action:    utils.MigrateFlags(snapshotVerify),
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+				},
+				Description: `
+    neatio snapshot verify <file>
+
+Re-hashes all entries of <file>, rebuilds the state tries from them and checks
+that the snapshot is complete and that its root matches the state root of the
+header at the same height in the local chain.`,
+			},
+			{
+				Name:   "prune",
+				Usage:  "Remove historical state from the database",
+				Action: utils.MigrateFlags(snapshotPrune),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					snapshotHeightFlag,
+					pruneBodyFlag,
+					utils.PruneRetentionFlag,
+				},
+				Description: `
+    neatio snapshot prune [--height <number>] [--prunebody] [--prune.retention <blocks>]
+
+Runs the data reduction of the node offline: the state of the blocks up to
+--height is scanned and every trie node not referenced by the retained states
+is deleted. The most recent --prune.retention blocks, or the PruneRetention of
+the configuration file, keep their state. The node must not be running.`,
+			},
+		},
+	}
+)
+
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// snapshotHeader is the first item of a snapshot file, it is followed by the
+// RLP encoded trie nodes and codes.
+type snapshotHeader struct {
+	Version uint64
+	Number  uint64
+	Hash    common.Hash
+	Root    common.Hash
+}
+
+func snapshotCreate(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the snapshot file as argument.")
+	}
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	defer stack.Close()
+
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	header := snapshotBlockHeader(ctx, chainDb)
+
+	file, err := os.OpenFile(ctx.Args().First(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		utils.Fatalf("Failed to create snapshot file: %v", err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+
+	head := &snapshotHeader{
+		Version: snapshotVersion,
+		Number:  header.Number.Uint64(),
+		Hash:    header.Hash(),
+		Root:    header.Root,
+	}
+	if err := rlp.Encode(writer, head); err != nil {
+		utils.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	var (
+		start   = time.Now()
+		seen    = make(map[common.Hash]struct{})
+		keys    = make(map[common.Hash][]byte)
+		entries int
+		size    int
+	)
+	// The keys of the chain data held per account are exported too, keyed by
+	// their hash like the other entries, to be told apart from the accounts
+	preimage := func(hash common.Hash) []byte {
+		key := rawdb.ReadPreimage(chainDb, hash)
+		if key != nil && state.IsChainDataKey(key) {
+			keys[hash] = key
+		}
+		return key
+	}
+	err = iterateState(state.NewDatabase(chainDb), header.Root, preimage, func(hash common.Hash) error {
+		if _, ok := seen[hash]; ok {
+			return nil
+		}
+		seen[hash] = struct{}{}
+
+		blob, ok := keys[hash]
+		if !ok {
+			stored, err := chainDb.Get(hash.Bytes())
+			if err != nil {
+				return fmt.Errorf("missing entry %x: %v", hash, err)
+			}
+			blob = stored
+		}
+		entries++
+		size += len(blob)
+		return rlp.Encode(writer, blob)
+	})
+	if err != nil {
+		utils.Fatalf("Failed to export state: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		utils.Fatalf("Failed to write snapshot: %v", err)
+	}
+	log.Info("Snapshot created", "number", head.Number, "root", head.Root, "entries", entries, "size", common.StorageSize(size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func snapshotVerify(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires the snapshot file as argument.")
+	}
+	file, err := os.Open(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to open snapshot file: %v", err)
+	}
+	defer file.Close()
+	stream := rlp.NewStream(bufio.NewReader(file), 0)
+
+	var head snapshotHeader
+	if err := stream.Decode(&head); err != nil {
+		utils.Fatalf("Failed to read snapshot header: %v", err)
+	}
+	if head.Version != snapshotVersion {
+		utils.Fatalf("Unsupported snapshot version %d", head.Version)
+	}
+
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	defer stack.Close()
+
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	header := rawdb.ReadHeader(chainDb, rawdb.ReadCanonicalHash(chainDb, head.Number), head.Number)
+	chainDb.Close()
+	if header == nil {
+		utils.Fatalf("Block %d is not known to the local chain", head.Number)
+	}
+	if header.Hash() != head.Hash || header.Root != head.Root {
+		utils.Fatalf("Snapshot does not belong to the local chain: block %d is %x with root %x, snapshot has %x with root %x",
+			head.Number, header.Hash(), header.Root, head.Hash, head.Root)
+	}
+
+	// Key every entry by its own hash, a tampered entry will then simply not
+	// be found when the tries are rebuilt from the root.
+	start := time.Now()
+	memdb := rawdb.NewMemoryDatabase()
+	entries := 0
+	for {
+		blob, err := stream.Bytes()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			utils.Fatalf("Failed to read snapshot entry %d: %v", entries, err)
+		}
+		if err := memdb.Put(crypto.Keccak256(blob), blob); err != nil {
+			utils.Fatalf("Failed to load snapshot: %v", err)
+		}
+		entries++
+	}
+
+	reached := make(map[common.Hash]struct{})
+	preimage := func(hash common.Hash) []byte {
+		key, _ := memdb.Get(hash.Bytes())
+		return key
+	}
+	err = iterateState(state.NewDatabase(memdb), head.Root, preimage, func(hash common.Hash) error {
+		if ok, _ := memdb.Has(hash.Bytes()); !ok {
+			return fmt.Errorf("missing entry %x", hash)
+		}
+		reached[hash] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		utils.Fatalf("Snapshot verification failed: %v", err)
+	}
+	if len(reached) != entries {
+		log.Warn("Snapshot contains unreferenced entries", "count", entries-len(reached))
+	}
+	log.Info("Snapshot verified", "number", head.Number, "root", head.Root, "entries", entries, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func snapshotPrune(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx, clientIdentifier)
+	defer stack.Close()
+
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	defer chain.Stop()
+
+	pruneDb, err := stack.OpenDatabase("prunedata", ctx.GlobalInt(utils.CacheFlag.Name)/4, 0, "neatio/db/prune/")
+	if err != nil {
+		utils.Fatalf("Could not open prune database: %v", err)
+	}
+	defer pruneDb.Close()
+
+	blockNumber := chain.CurrentHeader().Number.Uint64()
+	if ctx.IsSet(snapshotHeightFlag.Name) {
+		if height := ctx.Uint64(snapshotHeightFlag.Name); height < blockNumber {
+			blockNumber = height
+		}
+	}
+
+	var scanNumber, pruneNumber uint64
+	if ps := rawdb.ReadHeadScanNumber(pruneDb); ps != nil {
+		scanNumber = *ps
+	}
+	if pp := rawdb.ReadHeadPruneNumber(pruneDb); pp != nil {
+		pruneNumber = *pp
+	}
+	log.Info("Pruning state", "number", blockNumber, "scanned", scanNumber, "pruned", pruneNumber)
+
+	start := time.Now()
+	pruneBody := ctx.Bool(pruneBodyFlag.Name)
+	processor := datareduction.NewPruneProcessor(chainDb, pruneDb, chain, pruneBody, cfg.Eth.PruneRetention)
+	lastScanNumber, lastPruneNumber := processor.Process(blockNumber, scanNumber, pruneNumber)
+	if pruneBody {
+		for i := uint64(1); i < lastPruneNumber; i++ {
+			rawdb.DeleteBody(chainDb, rawdb.ReadCanonicalHash(chainDb, i), i)
+		}
+	}
+	log.Info("State pruned", "scanned", lastScanNumber, "pruned", lastPruneNumber, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// snapshotBlockHeader returns the canonical header at --height, or the head
+// header if the flag is not set.
+func snapshotBlockHeader(ctx *cli.Context, db neatdb.Database) *types.Header {
+	var hash common.Hash
+	if ctx.IsSet(snapshotHeightFlag.Name) {
+		hash = rawdb.ReadCanonicalHash(db, ctx.Uint64(snapshotHeightFlag.Name))
+	} else {
+		hash = rawdb.ReadHeadHeaderHash(db)
+	}
+	number := rawdb.ReadHeaderNumber(db, hash)
+	if number == nil {
+		utils.Fatalf("Block not found")
+	}
+	return rawdb.ReadHeader(db, hash, *number)
+}
+
+// iterateState walks the account trie at root together with all tries and
+// codes referenced by the accounts, calling onEntry with the hash of every
+// trie node and code. Nodes embedded in their parent have no hash of their own
+// and are skipped. The leaves holding chain data rather than an account are
+// told apart by their key, the keys of the chain data held per account being
+// looked up with preimage and passed to onEntry too.
+func iterateState(db state.Database, root common.Hash, preimage func(common.Hash) []byte, onEntry func(common.Hash) error) error {
+	walk := func(t state.Trie, onLeaf func(key, blob []byte) error) error {
+		it := t.NodeIterator(nil)
+		for it.Next(true) {
+			if hash := it.Hash(); hash != (common.Hash{}) {
+				if err := onEntry(hash); err != nil {
+					return err
+				}
+			}
+			if it.Leaf() && onLeaf != nil {
+				if err := onLeaf(it.LeafKey(), it.LeafBlob()); err != nil {
+					return err
+				}
+			}
+		}
+		return it.Error()
+	}
+
+	accountTrie, err := db.OpenTrie(root)
+	if err != nil {
+		return err
+	}
+	return walk(accountTrie, func(key, blob []byte) error {
+		hash := common.BytesToHash(key)
+		if state.IsChainDataKeyHash(hash) {
+			return nil
+		}
+		if key := preimage(hash); key != nil && state.IsChainDataKey(key) {
+			return onEntry(hash)
+		}
+		var account state.Account
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			return fmt.Errorf("invalid account %x: %v", hash, err)
+		}
+		subTries := []struct {
+			root common.Hash
+			open func(addrHash, root common.Hash) (state.Trie, error)
+		}{
+			{account.Root, db.OpenStorageTrie},
+			{account.TX1Root, db.OpenTX1Trie},
+			{account.TX3Root, db.OpenTX3Trie},
+			{account.ProxiedRoot, db.OpenProxiedTrie},
+			{account.RewardRoot, db.OpenRewardTrie},
+		}
+		for _, sub := range subTries {
+			if sub.root == emptyRoot || sub.root == (common.Hash{}) {
+				continue
+			}
+			t, err := sub.open(common.Hash{}, sub.root)
+			if err != nil {
+				return err
+			}
+			if err := walk(t, nil); err != nil {
+				return err
+			}
+		}
+		if len(account.CodeHash) > 0 && !bytes.Equal(account.CodeHash, emptyCodeHash.Bytes()) {
+			return onEntry(common.BytesToHash(account.CodeHash))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+)
+
+func TestIterateStateChainData(t *testing.T) {
+	diskdb := rawdb.NewMemoryDatabase()
+	db := state.NewDatabase(diskdb)
+	statedb, _ := state.New(common.Hash{}, db)
+
+	candidate := common.BytesToAddress([]byte{0x01})
+	statedb.SetBalance(candidate, big.NewInt(1))
+	statedb.ApplyForCandidate(candidate, "", 10)
+	statedb.RecordRewardClaim(candidate, big.NewInt(1), 1)
+	statedb.AddSideChain("side_0")
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	preimage := func(hash common.Hash) []byte {
+		return rawdb.ReadPreimage(diskdb, hash)
+	}
+	if err := iterateState(db, root, preimage, func(common.Hash) error { return nil }); err != nil {
+		t.Fatalf("state with chain data rejected: %v", err)
+	}
+
+	// An account leaf that does not decode fails the walk
+	tr, _ := db.OpenTrie(root)
+	if err := tr.TryUpdate(common.BytesToAddress([]byte{0x02}).Bytes(), []byte{0x01, 0x02}); err != nil {
+		t.Fatal(err)
+	}
+	if root, err = tr.Commit(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := iterateState(db, root, preimage, func(common.Hash) error { return nil }); err == nil {
+		t.Error("corrupt account skipped")
+	}
+}
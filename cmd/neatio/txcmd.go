@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/neatlab/neatio/accounts"
+	"github.com/neatlab/neatio/accounts/keystore"
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	txOfflineFlag = cli.BoolFlag{
+		Name:  "offline",
+		Usage: "Sign without contacting a node, all transaction fields must be given",
+	}
+	txEndpointFlag = cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "RPC endpoint of the node to query or broadcast to (default = local IPC)",
+	}
+	txOutputFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the signed transaction to (default = standard output)",
+	}
+
+	txCommand = cli.Command{
+		Name:     "tx",
+		Usage:    "Sign and broadcast transactions",
+		Category: "ACCOUNT COMMANDS",
+		Description: `
+Sign transactions with a local account and broadcast them later, possibly from
+another machine. Together with watch-only accounts (--watch) this allows keys
+to stay on an air-gapped machine while the online node only relays.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "sign",
+				Usage:     "Sign a prepared JSON transaction",
+				Action:    utils.MigrateFlags(txSign),
+				ArgsUsage: "<txFile>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					txOfflineFlag,
+					txEndpointFlag,
+					txOutputFlag,
+				},
+				Description: `
+    neatio tx sign [options] <txFile>
+
+Signs the transaction described by <txFile> with the key of its "from" account
+and prints the RLP encoded signed transaction in hex. The file is a JSON object:
+
+    {
+      "from":     "NEAT...",
+      "to":       "NEAT...",
+      "nonce":    "0x0",
+      "gas":      "0x5208",
+      "gasPrice": "0x3b9aca00",
+      "value":    "0xde0b6b3a7640000",
+      "input":    "0x",
+      "chainId":  "0x1"
+    }
+
+Leave out "to" to create a contract. With --offline every field except "to",
+"value" and "input" must be present and the node is never contacted, which is
+what an air-gapped machine should use. Otherwise missing nonce, gas price and
+chain id are fetched from the node at --endpoint.`,
+			},
+			{
+				Name:      "broadcast",
+				Usage:     "Broadcast a signed transaction",
+				Action:    utils.MigrateFlags(txBroadcast),
+				ArgsUsage: "<rawTx | rawTxFile>",
+				Flags: []cli.Flag{
+					txEndpointFlag,
+				},
+				Description: `
+    neatio tx broadcast [options] <rawTx | rawTxFile>
+
+Submits a signed transaction, as produced by "neatio tx sign", to the node at
+--endpoint and prints its hash.`,
+			},
+		},
+	}
+)
+
+// offlineTx is the JSON description of a transaction to be signed.
+type offlineTx struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Nonce    *hexutil.Uint64 `json:"nonce"`
+	Gas      *hexutil.Uint64 `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Input    hexutil.Bytes   `json:"input"`
+	ChainId  *hexutil.Big    `json:"chainId"`
+}
+
+// fill retrieves the missing nonce, gas price and chain id from a node.
+func (args *offlineTx) fill(endpoint string) error {
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if args.Nonce == nil {
+		args.Nonce = new(hexutil.Uint64)
+		if err := client.Call(args.Nonce, "neat_getTransactionCount", args.From, "pending"); err != nil {
+			return err
+		}
+	}
+	if args.GasPrice == nil {
+		args.GasPrice = new(hexutil.Big)
+		if err := client.Call(args.GasPrice, "neat_gasPrice"); err != nil {
+			return err
+		}
+	}
+	if args.ChainId == nil {
+		args.ChainId = new(hexutil.Big)
+		if err := client.Call(args.ChainId, "neat_chainId"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (args *offlineTx) toTransaction() *types.Transaction {
+	value := new(big.Int)
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+	if args.To == nil {
+		return types.NewContractCreation(uint64(*args.Nonce), value, uint64(*args.Gas), args.GasPrice.ToInt(), args.Input)
+	}
+	return types.NewTransaction(uint64(*args.Nonce), *args.To, value, uint64(*args.Gas), args.GasPrice.ToInt(), args.Input)
+}
+
+func txSign(ctx *cli.Context) error {
+	txfile := ctx.Args().First()
+	if len(txfile) == 0 {
+		utils.Fatalf("txFile must be given as argument")
+	}
+	blob, err := ioutil.ReadFile(txfile)
+	if err != nil {
+		utils.Fatalf("Could not read transaction file: %v", err)
+	}
+	var args offlineTx
+	if err := json.Unmarshal(blob, &args); err != nil {
+		utils.Fatalf("Invalid transaction file: %v", err)
+	}
+	if ctx.Bool(txOfflineFlag.Name) {
+		if args.Nonce == nil || args.GasPrice == nil || args.ChainId == nil {
+			utils.Fatalf("Offline signing requires nonce, gasPrice and chainId")
+		}
+	} else if err := args.fill(ctx.String(txEndpointFlag.Name)); err != nil {
+		utils.Fatalf("Could not complete the transaction from the node: %v", err)
+	}
+	if args.Gas == nil {
+		utils.Fatalf("Transaction gas limit must be given")
+	}
+
+	return writeSignedTx(ctx, signOfflineTx(ctx, &args))
+}
+
+// signOfflineTx signs a completed transaction with the keystore key of its sender.
+func signOfflineTx(ctx *cli.Context, args *offlineTx) *types.Transaction {
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	account := accounts.Account{Address: args.From}
+	if !ks.HasAddress(args.From) {
+		utils.Fatalf("Account %s not found in the keystore", args.From.String())
+	}
+	passphrase := getPassPhrase(fmt.Sprintf("Signing transaction from %s", args.From.String()), false, 0, utils.MakePasswordList(ctx))
+
+	signed, err := ks.SignTxWithPassphrase(account, passphrase, args.toTransaction(), args.ChainId.ToInt())
+	if err != nil {
+		utils.Fatalf("Failed to sign the transaction: %v", err)
+	}
+	return signed
+}
+
+// writeSignedTx prints the RLP encoded transaction or writes it to the --out file.
+func writeSignedTx(ctx *cli.Context, signed *types.Transaction) error {
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		utils.Fatalf("Failed to encode the transaction: %v", err)
+	}
+	if out := ctx.String(txOutputFlag.Name); out != "" {
+		if err := ioutil.WriteFile(out, []byte(hexutil.Encode(raw)), 0600); err != nil {
+			utils.Fatalf("Could not write %s: %v", out, err)
+		}
+		fmt.Printf("Signed transaction %s written to %s\n", signed.Hash().Hex(), out)
+		return nil
+	}
+	fmt.Println(hexutil.Encode(raw))
+	return nil
+}
+
+func txBroadcast(ctx *cli.Context) error {
+	input := ctx.Args().First()
+	if len(input) == 0 {
+		utils.Fatalf("Signed transaction must be given as argument")
+	}
+	if !strings.HasPrefix(input, "0x") {
+		blob, err := ioutil.ReadFile(input)
+		if err != nil {
+			utils.Fatalf("Could not read transaction file: %v", err)
+		}
+		input = strings.TrimSpace(string(blob))
+	}
+	raw, err := hexutil.Decode(input)
+	if err != nil {
+		utils.Fatalf("Invalid signed transaction: %v", err)
+	}
+	hash, err := broadcastRawTx(ctx.String(txEndpointFlag.Name), raw)
+	if err != nil {
+		utils.Fatalf("Failed to broadcast the transaction: %v", err)
+	}
+	fmt.Printf("Transaction hash: %s\n", hash.Hex())
+	return nil
+}
+
+// broadcastRawTx submits a signed RLP encoded transaction to the node at endpoint.
+func broadcastRawTx(endpoint string, raw []byte) (common.Hash, error) {
+	client, err := dialRPC(endpoint)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer client.Close()
+
+	var hash common.Hash
+	err = client.Call(&hash, "neat_sendRawTransaction", hexutil.Bytes(raw))
+	return hash, err
+}
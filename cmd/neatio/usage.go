@@ -68,11 +68,21 @@ var AppHelpFlagGroups = []flagGroup{
 			configFileFlag,
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
+			utils.KeyStoreScryptNFlag,
+			utils.KeyStoreScryptPFlag,
+			utils.KeyStoreKDFFlag,
 			utils.NoUSBFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.PruneFlag,
+			utils.PruneBlockFlag,
+			utils.PruneMainChainFlag,
+			utils.PruneRetentionFlag,
+			utils.TxSearchFlag,
+			utils.PipelinedCommitFlag,
+			utils.ShutdownTimeoutFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 		},
@@ -106,6 +116,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
+			utils.WatchOnlyFlag,
 		},
 	},
 	{
@@ -120,10 +131,26 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCRouterFlag,
+			utils.RPCMaxConnsFlag,
+			utils.RPCAuditLogFlag,
+			utils.RPCTLSCertFlag,
+			utils.RPCTLSKeyFlag,
+			utils.RPCTLSClientCAFlag,
+			utils.AuthRPCEnabledFlag,
+			utils.AuthRPCListenAddrFlag,
+			utils.AuthRPCPortFlag,
+			utils.AuthRPCJWTSecretFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RosettaAddrFlag,
+			utils.RosettaPortFlag,
+			utils.GRPCAddrFlag,
+			utils.GRPCPortFlag,
+			utils.HealthMinPeersFlag,
+			utils.HealthSignWindowFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -142,6 +169,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.RequireAEADFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -158,6 +186,15 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ExtraDataFlag,
 		},
 	},
+	{
+		Name: "RELAYER",
+		Flags: []cli.Flag{
+			utils.RelayerFlag,
+			utils.RelayerAccountsFlag,
+			utils.RelayerMaxGasPriceFlag,
+			utils.RelayerRetriesFlag,
+		},
+	},
 	{
 		Name: "GAS PRICE ORACLE",
 		Flags: []cli.Flag{
@@ -165,6 +202,16 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.GpoPercentileFlag,
 		},
 	},
+	{
+		Name: "ALERTING",
+		Flags: []cli.Flag{
+			utils.AlertWebhookFlag,
+			utils.AlertCommandFlag,
+			utils.AlertStallFlag,
+			utils.AlertRoundFlag,
+			utils.AlertMissedFlag,
+		},
+	},
 	{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
@@ -175,6 +222,12 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.MetricsHTTPFlag,
+			utils.MetricsPortFlag,
+			utils.TracingEndpointFlag,
+			utils.StallProfileFlag,
+			utils.FirehoseFlag,
+			utils.FirehoseStartFlag,
 			utils.NoCompactionFlag,
 		}, debug.Flags...),
 	},
@@ -0,0 +1,689 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neatlab/neatio/accounts/keystore"
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/crypto"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	privValidatorFileFlag = cli.StringFlag{
+		Name:  "privvalidator",
+		Usage: "Consensus key file of the validator (default = <datadir>/<chain>/priv_validator.json)",
+	}
+	broadcastFlag = cli.BoolFlag{
+		Name:  "broadcast",
+		Usage: "Broadcast the signed transaction to the node at --endpoint instead of printing it",
+	}
+	backupHeightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "Block height recorded in the backup (default = current height of the node at --endpoint)",
+	}
+	signWindowFlag = cli.Uint64Flag{
+		Name:  "window",
+		Usage: "Number of recent blocks searched for a signature",
+		Value: 10000,
+	}
+	skipSignCheckFlag = cli.BoolFlag{
+		Name:  "skipsigncheck",
+		Usage: "Restore without asking the node at --endpoint whether the validator signed after the backup",
+	}
+	auditFromFlag = cli.Uint64Flag{
+		Name:  "from",
+		Usage: "Lowest block height of the exported signatures",
+	}
+	auditToFlag = cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Highest block height of the exported signatures (default = all)",
+	}
+
+	validatorCommand = cli.Command{
+		Name:     "validator",
+		Usage:    "Manage the validator of this node",
+		Category: "VALIDATOR COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:  "key",
+				Usage: "Manage the consensus key",
+				Subcommands: []cli.Command{
+					{
+						Name:      "rotate",
+						Usage:     "Generate a new consensus key and bind it to the validator account",
+						Action:    utils.MigrateFlags(validatorKeyRotate),
+						ArgsUsage: "<address>",
+						Flags: []cli.Flag{
+							utils.DataDirFlag,
+							utils.KeyStoreDirFlag,
+							utils.PasswordFileFlag,
+							utils.TestnetFlag,
+							privValidatorFileFlag,
+							txEndpointFlag,
+							txOutputFlag,
+							broadcastFlag,
+						},
+						Description: `
+    neatio validator key rotate [options] <address>
+
+Generates a new BLS consensus key pair for the validator <address> and signs a
+RotateConsensusKey transaction with the account key, proving ownership of both.
+The new key is stored next to the current one with a ".next" suffix; the
+running node keeps signing with the current key and switches to the new one as
+soon as the validator set of the next epoch carries it.
+
+If a rotation is already pending its key is reused, so the command can safely
+be repeated when the transaction did not make it into a block.`,
+					},
+				},
+			},
+			{
+				Name:      "status",
+				Usage:     "Show the bonding status and voting power of a validator",
+				Action:    utils.MigrateFlags(validatorStatus),
+				ArgsUsage: "[<address>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					privValidatorFileFlag,
+					txEndpointFlag,
+				},
+				Description: `
+    neatio validator status [options] [<address>]
+
+Prints as JSON whether <address> is a candidate, its commission, whether it is
+in the validator set of the current epoch and with which voting power, and its
+banned status. Without <address> the account of the consensus key file is used.`,
+			},
+			{
+				Name:      "sign-info",
+				Usage:     "Show the last block signed by a validator",
+				Action:    utils.MigrateFlags(validatorSignInfo),
+				ArgsUsage: "[<address>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					privValidatorFileFlag,
+					txEndpointFlag,
+					signWindowFlag,
+				},
+				Description: `
+    neatio validator sign-info [options] [<address>]
+
+Prints as JSON the height, round and epoch of the last block committed with a
+signature of the validator within the last --window blocks. The consensus key
+file does not keep a signing state, so the information is taken from the
+commits stored in the chain of the node at --endpoint.`,
+			},
+			{
+				Name:      "unjail",
+				Usage:     "Sign a transaction releasing a banned validator",
+				Action:    utils.MigrateFlags(validatorUnjail),
+				ArgsUsage: "[<address>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.TestnetFlag,
+					privValidatorFileFlag,
+					txEndpointFlag,
+					txOutputFlag,
+					broadcastFlag,
+				},
+				Description: `
+    neatio validator unjail [options] [<address>]
+
+Signs an UnBanned transaction for the validator account, once its banned
+period is over. The transaction is printed, written to --out or sent with
+--broadcast; with --password the command runs without prompting.`,
+			},
+			{
+				Name:      "withdraw",
+				Usage:     "Sign a transaction withdrawing the rewards of an account",
+				Action:    utils.MigrateFlags(validatorWithdraw),
+				ArgsUsage: "[<address> [<candidate>]]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.TestnetFlag,
+					privValidatorFileFlag,
+					txEndpointFlag,
+					txOutputFlag,
+					broadcastFlag,
+				},
+				Description: `
+    neatio validator withdraw [options] [<address> [<candidate>]]
+
+Signs a WithdrawReward transaction moving the rewards <address> earned with
+<candidate> to its balance. <candidate> defaults to <address>, i.e. the own
+rewards of a validator. The transaction is printed, written to --out or sent
+with --broadcast.`,
+			},
+			{
+				Name:      "backup",
+				Usage:     "Write the validator identity to an encrypted backup file",
+				Action:    utils.MigrateFlags(validatorBackup),
+				ArgsUsage: "<backupFile>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.PasswordFileFlag,
+					utils.TestnetFlag,
+					privValidatorFileFlag,
+					txEndpointFlag,
+					backupHeightFlag,
+				},
+				Description: `
+    neatio validator backup [options] <backupFile>
+
+Bundles the node key, the consensus key and a pending rotated consensus key, if
+any, into a single file encrypted with a passphrase. The bundle records the
+current block height, which is what "neatio validator restore" checks against.`,
+			},
+			{
+				Name:      "restore",
+				Usage:     "Restore the validator identity from an encrypted backup file",
+				Action:    utils.MigrateFlags(validatorRestore),
+				ArgsUsage: "<backupFile>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.PasswordFileFlag,
+					utils.TestnetFlag,
+					privValidatorFileFlag,
+					txEndpointFlag,
+					skipSignCheckFlag,
+				},
+				Description: `
+    neatio validator restore [options] <backupFile>
+
+Restores the keys written by "neatio validator backup". Existing key files are
+never overwritten.
+
+Before restoring, the synced node at --endpoint is asked whether the validator
+signed any block above the height recorded in the bundle. If it did, another
+instance is or was running with these keys and the restore is refused, since
+starting a second one would double sign.`,
+			},
+			{
+				Name:   "audit",
+				Usage:  "Verify and export the signing audit log of the validator",
+				Action: utils.MigrateFlags(validatorAudit),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					privValidatorFileFlag,
+					txOutputFlag,
+					auditFromFlag,
+					auditToFlag,
+				},
+				Description: `
+    neatio validator audit [options]
+
+Every proposal and vote signed by the node is recorded, before the signature
+leaves it, in the sign_audit.log file next to the consensus key. Each entry
+holds the height, round, type and block hash signed along with the time and
+the signature, and commits to the hash of the previous entry.
+
+The command verifies the chain of hashes and exports the entries between
+--from and --to as JSON, to standard output or to --out. If the log was
+altered or truncated the entries up to the break are exported and the command
+fails, reporting where the chain breaks.`,
+			},
+		},
+	}
+)
+
+// validatorBundleVersion is the format version of validator backup files.
+const validatorBundleVersion = 1
+
+// validatorBundle is the encrypted validator backup file.
+type validatorBundle struct {
+	Version int                 `json:"version"`
+	Address string              `json:"address"`
+	Height  hexutil.Uint64      `json:"height"`
+	Crypto  keystore.CryptoJSON `json:"crypto"`
+}
+
+// validatorSecrets is the plain content of a validator backup.
+type validatorSecrets struct {
+	NodeKey              string          `json:"nodekey"`
+	PrivValidator        json.RawMessage `json:"priv_validator"`
+	PendingPrivValidator json.RawMessage `json:"pending_priv_validator,omitempty"`
+}
+
+// privValidatorFile returns the consensus key file given by --privvalidator or
+// the default one of the selected network.
+func privValidatorFile(ctx *cli.Context) string {
+	if file := ctx.String(privValidatorFileFlag.Name); file != "" {
+		return file
+	}
+	chainId := params.MainnetChainConfig.NeatChainId
+	if ctx.GlobalIsSet(utils.TestnetFlag.Name) {
+		chainId = params.TestnetChainConfig.NeatChainId
+	}
+	return filepath.Join(ctx.GlobalString(utils.DataDirFlag.Name), chainId, "priv_validator.json")
+}
+
+func validatorKeyRotate(ctx *cli.Context) error {
+	address := ctx.Args().First()
+	if len(address) == 0 {
+		utils.Fatalf("Validator address must be given as argument")
+	}
+	if !crypto.ValidateNeatAddr(address) {
+		utils.Fatalf("Invalid address: %s", address)
+	}
+	from := common.StringToAddress(address)
+
+	privValFile := privValidatorFile(ctx)
+	if _, err := os.Stat(privValFile); err != nil {
+		utils.Fatalf("Consensus key file not found: %v", err)
+	}
+	current := types.LoadPrivValidator(privValFile)
+	if current.Address != from {
+		utils.Fatalf("Consensus key file %s belongs to %s", privValFile, current.Address.String())
+	}
+
+	// Reuse a pending key, it may already be bound on chain
+	var next *types.PrivValidator
+	pendingFile := types.PendingPrivValidatorFile(privValFile)
+	if _, err := os.Stat(pendingFile); err == nil {
+		next = types.LoadPrivValidator(pendingFile)
+		if next.Address != from {
+			utils.Fatalf("Pending consensus key file %s belongs to %s", pendingFile, next.Address.String())
+		}
+		fmt.Printf("Reusing pending consensus key from %s\n", pendingFile)
+	} else {
+		next = types.GenPrivValidatorKey(from)
+		next.SetFile(pendingFile)
+		next.Save()
+		fmt.Printf("New consensus key written to %s\n", pendingFile)
+	}
+	fmt.Printf("New consensus public key: %s\n", next.PubKey.KeyString())
+
+	signature := next.PrivKey.Sign(from.Bytes())
+	input, err := neatabi.ChainABI.Pack(neatabi.RotateConsensusKey.String(), next.PubKey.Bytes(), signature.Bytes())
+	if err != nil {
+		utils.Fatalf("Failed to pack the transaction input: %v", err)
+	}
+	return submitValidatorTx(ctx, from, input, neatabi.RotateConsensusKey.RequiredGas())
+}
+
+func validatorBackup(ctx *cli.Context) error {
+	out := ctx.Args().First()
+	if len(out) == 0 {
+		utils.Fatalf("Backup file must be given as argument")
+	}
+	if _, err := os.Stat(out); err == nil {
+		utils.Fatalf("Backup file %s already exists", out)
+	}
+
+	privValFile := privValidatorFile(ctx)
+	if _, err := os.Stat(privValFile); err != nil {
+		utils.Fatalf("Consensus key file not found: %v", err)
+	}
+	privVal := types.LoadPrivValidator(privValFile)
+
+	var secrets validatorSecrets
+	blob, err := ioutil.ReadFile(privValFile)
+	if err != nil {
+		utils.Fatalf("Could not read %s: %v", privValFile, err)
+	}
+	secrets.PrivValidator = blob
+	if blob, err := ioutil.ReadFile(types.PendingPrivValidatorFile(privValFile)); err == nil {
+		secrets.PendingPrivValidator = blob
+	}
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	nodeKeyFile := stack.ResolvePath("nodekey")
+	if blob, err = ioutil.ReadFile(nodeKeyFile); err != nil {
+		utils.Fatalf("Could not read node key: %v", err)
+	}
+	secrets.NodeKey = strings.TrimSpace(string(blob))
+
+	var height hexutil.Uint64
+	if ctx.IsSet(backupHeightFlag.Name) {
+		height = hexutil.Uint64(ctx.Uint64(backupHeightFlag.Name))
+	} else {
+		client, err := dialRPC(ctx.String(txEndpointFlag.Name))
+		if err != nil {
+			utils.Fatalf("Unable to connect to neatio, pass --height to record the height manually: %v", err)
+		}
+		err = client.Call(&height, "neat_blockNumber")
+		client.Close()
+		if err != nil {
+			utils.Fatalf("Could not retrieve the current height: %v", err)
+		}
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		utils.Fatalf("Failed to encode the backup: %v", err)
+	}
+	passphrase := getPassPhrase("Please give a passphrase to encrypt the backup. Do not forget this passphrase.", true, 0, utils.MakePasswordList(ctx))
+	cryptoJson, err := keystore.EncryptDataV3(plain, []byte(passphrase), keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		utils.Fatalf("Failed to encrypt the backup: %v", err)
+	}
+	bundle, err := json.MarshalIndent(validatorBundle{
+		Version: validatorBundleVersion,
+		Address: privVal.Address.String(),
+		Height:  height,
+		Crypto:  cryptoJson,
+	}, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode the backup: %v", err)
+	}
+	if err := ioutil.WriteFile(out, bundle, 0600); err != nil {
+		utils.Fatalf("Could not write %s: %v", out, err)
+	}
+	fmt.Printf("Backup of validator %s at height %d written to %s\n", privVal.Address.String(), uint64(height), out)
+	return nil
+}
+
+func validatorRestore(ctx *cli.Context) error {
+	in := ctx.Args().First()
+	if len(in) == 0 {
+		utils.Fatalf("Backup file must be given as argument")
+	}
+	blob, err := ioutil.ReadFile(in)
+	if err != nil {
+		utils.Fatalf("Could not read backup file: %v", err)
+	}
+	var bundle validatorBundle
+	if err := json.Unmarshal(blob, &bundle); err != nil {
+		utils.Fatalf("Invalid backup file: %v", err)
+	}
+	if bundle.Version != validatorBundleVersion {
+		utils.Fatalf("Unsupported backup version %d", bundle.Version)
+	}
+
+	privValFile := privValidatorFile(ctx)
+	pendingFile := types.PendingPrivValidatorFile(privValFile)
+	stack, _ := makeConfigNode(ctx, clientIdentifier)
+	nodeKeyFile := stack.ResolvePath("nodekey")
+	for _, file := range []string{privValFile, pendingFile, nodeKeyFile} {
+		if _, err := os.Stat(file); err == nil {
+			utils.Fatalf("Refusing to overwrite existing key file %s", file)
+		}
+	}
+
+	if !ctx.Bool(skipSignCheckFlag.Name) {
+		client, err := dialRPC(ctx.String(txEndpointFlag.Name))
+		if err != nil {
+			utils.Fatalf("Unable to connect to neatio to check for signatures after the backup: %v", err)
+		}
+		var signed hexutil.Uint64
+		err = client.Call(&signed, "neat_getLastSignedBlock", bundle.Address, bundle.Height)
+		client.Close()
+		if err != nil {
+			utils.Fatalf("Could not check for signatures after the backup: %v", err)
+		}
+		if signed > bundle.Height {
+			utils.Fatalf("Validator %s signed block %d after the backup at height %d, restoring could double sign", bundle.Address, uint64(signed), uint64(bundle.Height))
+		}
+	}
+
+	passphrase := getPassPhrase("Passphrase of the backup:", false, 0, utils.MakePasswordList(ctx))
+	plain, err := keystore.DecryptDataV3(bundle.Crypto, passphrase)
+	if err != nil {
+		utils.Fatalf("Failed to decrypt the backup: %v", err)
+	}
+	var secrets validatorSecrets
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		utils.Fatalf("Invalid backup content: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(privValFile), 0700); err != nil {
+		utils.Fatalf("Could not create %s: %v", filepath.Dir(privValFile), err)
+	}
+	if err := ioutil.WriteFile(privValFile, secrets.PrivValidator, 0600); err != nil {
+		utils.Fatalf("Could not write %s: %v", privValFile, err)
+	}
+	if len(secrets.PendingPrivValidator) > 0 {
+		if err := ioutil.WriteFile(pendingFile, secrets.PendingPrivValidator, 0600); err != nil {
+			utils.Fatalf("Could not write %s: %v", pendingFile, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(nodeKeyFile), 0700); err != nil {
+		utils.Fatalf("Could not create %s: %v", filepath.Dir(nodeKeyFile), err)
+	}
+	if err := ioutil.WriteFile(nodeKeyFile, []byte(secrets.NodeKey), 0600); err != nil {
+		utils.Fatalf("Could not write %s: %v", nodeKeyFile, err)
+	}
+	fmt.Printf("Validator %s restored from backup at height %d\n", bundle.Address, uint64(bundle.Height))
+	return nil
+}
+
+// validatorAddress returns the i-th argument as address, or the account of the
+// consensus key file if the argument is missing.
+func validatorAddress(ctx *cli.Context, i int) common.Address {
+	if address := ctx.Args().Get(i); address != "" {
+		if !crypto.ValidateNeatAddr(address) {
+			utils.Fatalf("Invalid address: %s", address)
+		}
+		return common.StringToAddress(address)
+	}
+	privValFile := privValidatorFile(ctx)
+	if _, err := os.Stat(privValFile); err != nil {
+		utils.Fatalf("No address given and consensus key file not found: %v", err)
+	}
+	return types.LoadPrivValidator(privValFile).Address
+}
+
+// submitValidatorTx signs a transaction to the chain contract with the account
+// key and prints it, or broadcasts it with --broadcast.
+func submitValidatorTx(ctx *cli.Context, from common.Address, input []byte, gas uint64) error {
+	args := &offlineTx{
+		From:  from,
+		To:    &neatabi.ChainContractMagicAddr,
+		Gas:   (*hexutil.Uint64)(&gas),
+		Input: input,
+	}
+	endpoint := ctx.String(txEndpointFlag.Name)
+	if err := args.fill(endpoint); err != nil {
+		utils.Fatalf("Could not complete the transaction from the node: %v", err)
+	}
+	signed := signOfflineTx(ctx, args)
+
+	if !ctx.Bool(broadcastFlag.Name) {
+		return writeSignedTx(ctx, signed)
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		utils.Fatalf("Failed to encode the transaction: %v", err)
+	}
+	hash, err := broadcastRawTx(endpoint, raw)
+	if err != nil {
+		utils.Fatalf("Failed to broadcast the transaction: %v", err)
+	}
+	fmt.Printf("Transaction hash: %s\n", hash.Hex())
+	return nil
+}
+
+// validatorStatusInfo is the output of "neatio validator status".
+type validatorStatusInfo struct {
+	Address     string         `json:"address"`
+	Candidate   bool           `json:"candidate"`
+	Commission  uint8          `json:"commission"`
+	Active      bool           `json:"active"`
+	Epoch       hexutil.Uint64 `json:"epoch"`
+	VotingPower *hexutil.Big   `json:"votingPower"`
+	Banned      bool           `json:"banned"`
+	BannedEpoch *hexutil.Big   `json:"bannedEpoch"`
+	Blocks      *hexutil.Big   `json:"blocks"`
+}
+
+func validatorStatus(ctx *cli.Context) error {
+	address := validatorAddress(ctx, 0)
+
+	client, err := dialRPC(ctx.String(txEndpointFlag.Name))
+	if err != nil {
+		utils.Fatalf("Unable to connect to neatio: %v", err)
+	}
+	defer client.Close()
+
+	status := validatorStatusInfo{Address: address.String()}
+
+	var candidate struct {
+		Candidate  bool  `json:"candidate"`
+		Commission uint8 `json:"commission"`
+	}
+	if err := client.Call(&candidate, "neat_checkCandidate", address, "latest"); err != nil {
+		utils.Fatalf("Could not query the candidate status: %v", err)
+	}
+	status.Candidate, status.Commission = candidate.Candidate, candidate.Commission
+
+	// The banned status is returned as JSON numbers
+	var banned struct {
+		Banned      bool     `json:"banned"`
+		BannedEpoch *big.Int `json:"bannedEpoch"`
+		Blocks      *big.Int `json:"blocks"`
+	}
+	if err := client.Call(&banned, "neat_getBannedStatus", address, "latest"); err != nil {
+		utils.Fatalf("Could not query the banned status: %v", err)
+	}
+	status.Banned, status.BannedEpoch, status.Blocks = banned.Banned, (*hexutil.Big)(banned.BannedEpoch), (*hexutil.Big)(banned.Blocks)
+
+	if err := client.Call(&status.Epoch, "neat_getCurrentEpochNumber"); err != nil {
+		utils.Fatalf("Could not query the current epoch: %v", err)
+	}
+	var epoch struct {
+		Validators []struct {
+			Address     string       `json:"address"`
+			VotingPower *hexutil.Big `json:"votingPower"`
+		} `json:"validators"`
+	}
+	if err := client.Call(&epoch, "neat_getEpoch", status.Epoch); err != nil {
+		utils.Fatalf("Could not query epoch %d: %v", uint64(status.Epoch), err)
+	}
+	for _, v := range epoch.Validators {
+		if v.Address == status.Address {
+			status.Active, status.VotingPower = true, v.VotingPower
+			break
+		}
+	}
+	return printJSON(status)
+}
+
+func validatorSignInfo(ctx *cli.Context) error {
+	address := validatorAddress(ctx, 0)
+
+	client, err := dialRPC(ctx.String(txEndpointFlag.Name))
+	if err != nil {
+		utils.Fatalf("Unable to connect to neatio: %v", err)
+	}
+	defer client.Close()
+
+	var head hexutil.Uint64
+	if err := client.Call(&head, "neat_blockNumber"); err != nil {
+		utils.Fatalf("Could not query the current block: %v", err)
+	}
+	var after hexutil.Uint64
+	if window := ctx.Uint64(signWindowFlag.Name); uint64(head) > window {
+		after = head - hexutil.Uint64(window)
+	}
+
+	info := struct {
+		Address string          `json:"address"`
+		Head    hexutil.Uint64  `json:"head"`
+		Height  *hexutil.Uint64 `json:"lastSignedHeight"`
+		Round   *int            `json:"lastSignedRound"`
+		Epoch   *hexutil.Uint64 `json:"lastSignedEpoch"`
+	}{Address: address.String(), Head: head}
+
+	var signed hexutil.Uint64
+	if err := client.Call(&signed, "neat_getLastSignedBlock", address, after); err != nil {
+		utils.Fatalf("Could not query the last signed block: %v", err)
+	}
+	if signed > 0 {
+		var block struct {
+			ExtraData hexutil.Bytes `json:"extraData"`
+		}
+		if err := client.Call(&block, "neat_getBlockByNumber", signed, false); err != nil {
+			utils.Fatalf("Could not fetch block %d: %v", uint64(signed), err)
+		}
+		var extra types.NeatconExtraApi
+		if err := client.Call(&extra, "neat_decodeExtraData", block.ExtraData.String()); err != nil {
+			utils.Fatalf("Could not decode the extra data of block %d: %v", uint64(signed), err)
+		}
+		info.Height, info.Epoch = &signed, &extra.EpochNumber
+		if extra.SeenCommit != nil {
+			info.Round = &extra.SeenCommit.Round
+		}
+	}
+	return printJSON(info)
+}
+
+func validatorUnjail(ctx *cli.Context) error {
+	from := validatorAddress(ctx, 0)
+	input, err := neatabi.ChainABI.Pack(neatabi.UnBanned.String())
+	if err != nil {
+		utils.Fatalf("Failed to pack the transaction input: %v", err)
+	}
+	return submitValidatorTx(ctx, from, input, neatabi.UnBanned.RequiredGas())
+}
+
+func validatorWithdraw(ctx *cli.Context) error {
+	from := validatorAddress(ctx, 0)
+	candidate := from
+	if ctx.NArg() > 1 {
+		candidate = validatorAddress(ctx, 1)
+	}
+	input, err := neatabi.ChainABI.Pack(neatabi.WithdrawReward.String(), candidate)
+	if err != nil {
+		utils.Fatalf("Failed to pack the transaction input: %v", err)
+	}
+	return submitValidatorTx(ctx, from, input, neatabi.WithdrawReward.RequiredGas())
+}
+
+func validatorAudit(ctx *cli.Context) error {
+	path := types.SignAuditFile(privValidatorFile(ctx))
+	entries, verifyErr := types.ReadSignAuditLog(path)
+	if os.IsNotExist(verifyErr) {
+		utils.Fatalf("No signing audit log at %s", path)
+	}
+	from, to := ctx.Uint64(auditFromFlag.Name), ctx.Uint64(auditToFlag.Name)
+	export := make([]*types.SignAuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Height >= from && (to == 0 || entry.Height <= to) {
+			export = append(export, entry)
+		}
+	}
+	out, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	if file := ctx.String(txOutputFlag.Name); file != "" {
+		if err := ioutil.WriteFile(file, out, 0600); err != nil {
+			utils.Fatalf("Could not write %s: %v", file, err)
+		}
+		fmt.Printf("%d signatures written to %s\n", len(export), file)
+	} else {
+		fmt.Println(string(out))
+	}
+	if verifyErr != nil {
+		utils.Fatalf("Signing audit log %s is broken after %d entries: %v", path, len(entries), verifyErr)
+	}
+	return nil
+}
+
+func printJSON(v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/neatlab/neatio/cmd/utils"
+	"github.com/neatlab/neatio/consensus"
+	ncConsensus "github.com/neatlab/neatio/consensus/neatpos/consensus"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	walFileFlag = cli.StringFlag{
+		Name:  "file",
+		Usage: "Consensus WAL file (<datadir>/<chain>/data/cs.wal/wal)",
+	}
+	walHeightFlag = cli.Uint64Flag{
+		Name:  "height",
+		Usage: "Height to select (default = all heights)",
+	}
+
+	walCommand = cli.Command{
+		Name:  "wal",
+		Usage: "Inspect the consensus WAL",
+		Subcommands: []cli.Command{
+			{
+				Name:   "dump",
+				Usage:  "Print the records of a consensus WAL",
+				Action: utils.MigrateFlags(dumpWAL),
+				Flags:  []cli.Flag{walFileFlag, walHeightFlag},
+				Description: `
+    neatio debug wal dump --file <wal> [--height <number>]
+
+Prints the records of the WAL one per line, with the consensus messages
+decoded. The WAL records every message, timeout and step the consensus state
+handled, in the order it handled them.`,
+			},
+			{
+				Name:   "verify",
+				Usage:  "Check the framing and checksums of a consensus WAL",
+				Action: utils.MigrateFlags(verifyWAL),
+				Flags:  []cli.Flag{walFileFlag},
+				Description: `
+    neatio debug wal verify --file <wal>
+
+Reads every record of the WAL and reports the first one failing its checksum.
+A torn last record is expected from a node which crashed while writing it.`,
+			},
+			{
+				Name:   "replay",
+				Usage:  "Replay the consensus WAL of a height against the local chain",
+				Action: utils.MigrateFlags(replayWAL),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					walFileFlag,
+					walHeightFlag,
+				},
+				Description: `
+    neatio debug wal replay --file <wal> --height <number>
+
+Replays the records of the height, verifying every message against the
+validator set of the height in the local chain, and prints the rounds the
+node went through: proposal, block parts, votes, 2/3+ aggregations and
+timeouts. If the local chain has the block of the height, the block the WAL
+committed is compared with it. The WAL may come from another node.`,
+			},
+		},
+	}
+)
+
+func openWAL(ctx *cli.Context) *os.File {
+	path := ctx.String(walFileFlag.Name)
+	if path == "" {
+		utils.Fatalf("The WAL file must be given with --%s", walFileFlag.Name)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		utils.Fatalf("Failed to open WAL: %v", err)
+	}
+	return file
+}
+
+func dumpWAL(ctx *cli.Context) error {
+	file := openWAL(ctx)
+	defer file.Close()
+
+	dec := ncConsensus.NewWALDecoder(file)
+	for {
+		record, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ctx.IsSet(walHeightFlag.Name) && record.Height != ctx.Uint64(walHeightFlag.Name) {
+			continue
+		}
+		switch record.Type {
+		case ncConsensus.WALRecordMsg:
+			msg, err := record.Message()
+			if err != nil {
+				fmt.Printf("%v %d/%d msg from %q: undecodable: %v\n", record.Time, record.Height, record.Round, record.PeerKey, err)
+				continue
+			}
+			fmt.Printf("%v %d/%d msg from %q: %v\n", record.Time, record.Height, record.Round, record.PeerKey, msg)
+		case ncConsensus.WALRecordTimeout:
+			fmt.Printf("%v %d/%d timeout %v after %v\n", record.Time, record.Height, record.Round, record.Step, record.Duration)
+		case ncConsensus.WALRecordStep:
+			fmt.Printf("%v %d/%d step %v\n", record.Time, record.Height, record.Round, record.Step)
+		case ncConsensus.WALRecordEndHeight:
+			fmt.Printf("%v %d end of height\n", record.Time, record.Height)
+		}
+	}
+}
+
+func verifyWAL(ctx *cli.Context) error {
+	file := openWAL(ctx)
+	defer file.Close()
+
+	var (
+		dec      = ncConsensus.NewWALDecoder(file)
+		records  int
+		from, to uint64
+	)
+	for {
+		record, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Printf("%d records, heights %d..%d, then %v\n", records, from, to, err)
+			return err
+		}
+		if records == 0 || record.Height < from {
+			from = record.Height
+		}
+		if record.Height > to {
+			to = record.Height
+		}
+		records++
+	}
+	fmt.Printf("%d records, heights %d..%d, no corruption\n", records, from, to)
+	return nil
+}
+
+func replayWAL(ctx *cli.Context) error {
+	if !ctx.IsSet(walHeightFlag.Name) {
+		utils.Fatalf("The height to replay must be given with --%s", walHeightFlag.Name)
+	}
+	height := ctx.Uint64(walHeightFlag.Name)
+
+	file := openWAL(ctx)
+	defer file.Close()
+
+	stack, cfg := makeConfigNode(ctx, clientIdentifier)
+	defer stack.Close()
+
+	chain, chainDb, engine := utils.MakeChainWithEngine(ctx, stack, cfg.Node.NodeKey(), GetCMInstance(ctx).cch)
+	defer chainDb.Close()
+	defer engine.Close()
+	defer chain.Stop()
+
+	neatPoS, ok := engine.(consensus.NeatPoS)
+	if !ok || neatPoS.GetEpoch() == nil {
+		utils.Fatalf("The chain has no validator epochs")
+	}
+	ep := neatPoS.GetEpoch().GetEpochByBlockNumber(height)
+	if ep == nil || ep.Validators == nil {
+		utils.Fatalf("No validator set known for height %d", height)
+	}
+
+	replay, err := ncConsensus.ReplayWAL(file, height, chain.Config().NeatChainId, ep.Validators)
+	if err != nil {
+		return err
+	}
+	out, _ := json.MarshalIndent(replay, "", "  ")
+	fmt.Println(string(out))
+
+	block := chain.GetBlockByNumber(height)
+	if block == nil {
+		fmt.Printf("Block %d is not in the local chain\n", height)
+		return nil
+	}
+	ncExtra, err := ncTypes.ExtractNeatconExtra(block.Header())
+	if err != nil || ncExtra.SeenCommit == nil {
+		utils.Fatalf("Block %d has no commit: %v", height, err)
+	}
+	switch {
+	case replay.Committed == nil:
+		fmt.Printf("The WAL has no 2/3+ precommits for block %d (%x)\n", height, ncExtra.SeenCommit.BlockID.Hash)
+	case !replay.Committed.Equals(ncExtra.SeenCommit.BlockID):
+		fmt.Printf("The WAL committed %X, the local chain has %X\n", replay.Committed.Hash, ncExtra.SeenCommit.BlockID.Hash)
+		return fmt.Errorf("commit mismatch at height %d", height)
+	default:
+		fmt.Printf("The WAL committed the local block %d\n", height)
+	}
+	return nil
+}
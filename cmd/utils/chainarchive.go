@@ -0,0 +1,344 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/neatlab/neatio/consensus"
+	neatconBackend "github.com/neatlab/neatio/consensus/neatpos"
+	"github.com/neatlab/neatio/consensus/neatpos/epoch"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/core/vm"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/neatdb"
+	"github.com/neatlab/neatio/node"
+	"github.com/neatlab/neatio/rlp"
+	dbm "github.com/neatlib/db-go"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	chainArchiveMagic   = "neatio-chain"
+	chainArchiveVersion = 1
+)
+
+// Kinds of the items following the header of a chain archive. Epoch records
+// come first so they are checked against the trusted epochs while streaming.
+const (
+	archiveEpoch uint64 = iota + 1
+	archiveBlock
+	archiveTX3Proof
+)
+
+// chainArchiveHeader is the first item of a chain archive.
+type chainArchiveHeader struct {
+	Magic   string
+	Version uint64
+	ChainId string
+	First   uint64
+	Last    uint64
+}
+
+type chainArchiveItem struct {
+	Kind uint64
+	Data []byte
+}
+
+// MakeEpochDatabase opens the epoch database of the consensus engine. The node
+// must not be running.
+func MakeEpochDatabase(ctx *cli.Context, chainId string) dbm.DB {
+	config := GetNeatConConfig(chainId, ctx)
+	return dbm.NewDB("epoch", config.GetString("db_backend"), config.GetString("db_dir"))
+}
+
+// MakeChainWithEngine creates a chain backed by the NeatPoS engine, so blocks
+// can be inserted offline with their commits and epoch switches processed.
+func MakeChainWithEngine(ctx *cli.Context, stack *node.Node, nodeKey *ecdsa.PrivateKey, cch core.CrossChainHelper) (*core.BlockChain, neatdb.Database, consensus.Engine) {
+	chainDb := MakeChainDatabase(ctx, stack)
+	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
+	if err != nil {
+		Fatalf("%v", err)
+	}
+	config.ChainLogger = stack.GetLogger()
+
+	engine := neatconBackend.New(config, ctx, nodeKey, cch)
+	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	chain, err := core.NewBlockChain(chainDb, nil, config, engine, vmcfg, cch)
+	if err != nil {
+		Fatalf("Can't create BlockChain: %v", err)
+	}
+	return chain, chainDb, engine
+}
+
+// ExportChainArchive writes the blocks first..last together with the epochs
+// they belong to and the local TX3 proofs into fn.
+func ExportChainArchive(chain *core.BlockChain, chainDb neatdb.Database, epochDB dbm.DB, fn string, first, last uint64) error {
+	if head := chain.CurrentBlock().NumberU64(); last > head {
+		last = head
+	}
+	if first > last {
+		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
+	}
+	log.Info("Exporting chain archive", "file", fn, "first", first, "last", last)
+
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+
+	header := &chainArchiveHeader{
+		Magic:   chainArchiveMagic,
+		Version: chainArchiveVersion,
+		ChainId: chain.Config().NeatChainId,
+		First:   first,
+		Last:    last,
+	}
+	if err := rlp.Encode(writer, header); err != nil {
+		return err
+	}
+
+	epochs := 0
+	for number := uint64(0); ; number++ {
+		buf := epoch.LoadEpochBytes(epochDB, number)
+		if buf == nil {
+			break
+		}
+		ep := epoch.FromBytes(buf)
+		if ep == nil {
+			return fmt.Errorf("corrupted epoch %d", number)
+		}
+		if ep.EndBlock < first {
+			continue
+		}
+		if ep.StartBlock > last {
+			break
+		}
+		if err := rlp.Encode(writer, &chainArchiveItem{archiveEpoch, buf}); err != nil {
+			return err
+		}
+		epochs++
+	}
+
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("export failed on #%d: not found", number)
+		}
+		data, err := rlp.EncodeToBytes(block)
+		if err != nil {
+			return err
+		}
+		if err := rlp.Encode(writer, &chainArchiveItem{archiveBlock, data}); err != nil {
+			return err
+		}
+	}
+
+	proofs := rawdb.GetAllTX3ProofData(chainDb)
+	for _, proof := range proofs {
+		data, err := rlp.EncodeToBytes(proof)
+		if err != nil {
+			return err
+		}
+		if err := rlp.Encode(writer, &chainArchiveItem{archiveTX3Proof, data}); err != nil {
+			return err
+		}
+	}
+	log.Info("Exported chain archive", "file", fn, "blocks", last-first+1, "epochs", epochs, "tx3proofs", len(proofs))
+	return nil
+}
+
+// ImportChainArchive imports a chain archive written by ExportChainArchive.
+// The commit of every block is verified against the validator set of its
+// epoch before the block is handed to the chain. The epochs are trusted from
+// the local epoch database, or from the end block of the epoch before them
+// once verified, the epoch records of the archive only have to agree.
+func ImportChainArchive(chain *core.BlockChain, chainDb neatdb.Database, fn string) error {
+	log.Info("Importing chain archive", "file", fn)
+	trusted, err := trustedEpochs(chain)
+	if err != nil {
+		return err
+	}
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	var header chainArchiveHeader
+	if err := stream.Decode(&header); err != nil {
+		return fmt.Errorf("invalid archive header: %v", err)
+	}
+	if header.Magic != chainArchiveMagic {
+		return errors.New("not a chain archive")
+	}
+	if header.Version != chainArchiveVersion {
+		return fmt.Errorf("unsupported archive version %d", header.Version)
+	}
+	if header.ChainId != chain.Config().NeatChainId {
+		return fmt.Errorf("archive of chain %s cannot be imported into chain %s", header.ChainId, chain.Config().NeatChainId)
+	}
+
+	var (
+		epochs []*epoch.Epoch
+		blocks = make(types.Blocks, 0, importBatchSize)
+		proofs int
+		n      int
+	)
+	flush := func() error {
+		if len(blocks) == 0 {
+			return nil
+		}
+		if missing := missingBlocks(chain, blocks); len(missing) > 0 {
+			if _, err := chain.InsertChain(missing); err != nil {
+				return fmt.Errorf("invalid block %d: %v", n, err)
+			}
+		}
+		blocks = blocks[:0]
+		return nil
+	}
+	for {
+		var item chainArchiveItem
+		if err := stream.Decode(&item); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("at item after block %d: %v", n, err)
+		}
+		switch item.Kind {
+		case archiveEpoch:
+			ep := epoch.FromBytes(item.Data)
+			if ep == nil {
+				return errors.New("invalid epoch record")
+			}
+			epochs = append(epochs, ep)
+
+		case archiveBlock:
+			block := new(types.Block)
+			if err := rlp.DecodeBytes(item.Data, block); err != nil {
+				return fmt.Errorf("at block %d: %v", n, err)
+			}
+			n++
+			// don't import first block
+			if block.NumberU64() == 0 {
+				continue
+			}
+			if err := verifyArchivedCommit(header.ChainId, trusted, epochs, block.Header()); err != nil {
+				return fmt.Errorf("block %d: %v", block.NumberU64(), err)
+			}
+			if blocks = append(blocks, block); len(blocks) == importBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+
+		case archiveTX3Proof:
+			if err := flush(); err != nil {
+				return err
+			}
+			proof := new(types.TX3ProofData)
+			if err := rlp.DecodeBytes(item.Data, proof); err != nil {
+				return fmt.Errorf("invalid tx3 proof: %v", err)
+			}
+			if err := rawdb.WriteTX3ProofData(chainDb, proof); err != nil {
+				log.Warn("Skipping tx3 proof", "err", err)
+				continue
+			}
+			proofs++
+
+		default:
+			return fmt.Errorf("unknown archive item kind %d", item.Kind)
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	log.Info("Imported chain archive", "file", fn, "blocks", n, "epochs", len(epochs), "tx3proofs", proofs)
+	return nil
+}
+
+// trustedEpochs returns the epochs of the local epoch database, by number, the
+// verification of a chain archive is anchored to.
+func trustedEpochs(chain *core.BlockChain) (map[uint64]*epoch.Epoch, error) {
+	neatpos, ok := chain.Engine().(consensus.NeatPoS)
+	if !ok || neatpos.GetEpoch() == nil {
+		return nil, errors.New("no local epoch to verify the archive from")
+	}
+	current := neatpos.GetEpoch()
+	trusted := map[uint64]*epoch.Epoch{current.Number: current.Copy()}
+	for number := uint64(0); number < current.Number; number++ {
+		if ep := epoch.LoadOneEpoch(current.GetDB(), number, nil); ep != nil {
+			trusted[number] = ep
+		}
+	}
+	return trusted, nil
+}
+
+// verifyArchivedCommit checks the seen commit of the header against the
+// validators of the trusted epoch the header belongs to. The end block of a
+// trusted epoch makes the next epoch it carries trusted as well.
+func verifyArchivedCommit(chainId string, trusted map[uint64]*epoch.Epoch, epochs []*epoch.Epoch, header *types.Header) error {
+	number := header.Number.Uint64()
+	var ep *epoch.Epoch
+	for _, e := range trusted {
+		if e.StartBlock <= number && number <= e.EndBlock {
+			ep = e
+			break
+		}
+	}
+	if ep == nil || ep.Validators == nil {
+		return errors.New("epoch not anchored to a trusted epoch")
+	}
+	for _, e := range epochs {
+		if e.Number == ep.Number && (e.Validators == nil || !bytes.Equal(e.Validators.Hash(), ep.Validators.Hash())) {
+			return fmt.Errorf("epoch %d of the archive differs from the trusted one", e.Number)
+		}
+	}
+
+	ncExtra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(ep.Validators.Hash(), ncExtra.ValidatorsHash) {
+		return errors.New("validator set does not match the epoch")
+	}
+	if ncExtra.SeenCommit == nil || !bytes.Equal(ncExtra.SeenCommitHash, ncExtra.SeenCommit.Hash()) {
+		return errors.New("seen commit does not match its hash")
+	}
+	if err := ep.Validators.VerifyCommit(chainId, ncExtra.Height, ncExtra.SeenCommit); err != nil {
+		return err
+	}
+
+	if number == ep.EndBlock {
+		next := epoch.FromBytes(ncExtra.EpochBytes)
+		if next == nil || next.Number != ep.Number+1 || next.Validators == nil {
+			return errors.New("end block of the epoch carries no next epoch")
+		}
+		if _, ok := trusted[next.Number]; !ok {
+			trusted[next.Number] = next
+		}
+	}
+	return nil
+}
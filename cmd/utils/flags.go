@@ -21,6 +21,7 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -32,12 +33,16 @@ import (
 	"github.com/neatlab/neatio/common/fdlimit"
 	"github.com/neatlab/neatio/consensus"
 	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/datareduction"
 	"github.com/neatlab/neatio/core/vm"
 	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/internal/tracing"
 	"github.com/neatlab/neatio/log"
 	"github.com/neatlab/neatio/metrics"
+	"github.com/neatlab/neatio/metrics/exp"
 	"github.com/neatlab/neatio/neatdb"
 	"github.com/neatlab/neatio/neatptc"
+	"github.com/neatlab/neatio/neatptc/alert"
 	"github.com/neatlab/neatio/neatptc/downloader"
 	"github.com/neatlab/neatio/neatptc/gasprice"
 	"github.com/neatlab/neatio/node"
@@ -116,6 +121,23 @@ var (
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
 	}
+	KeyStoreScryptNFlag = cli.IntFlag{
+		Name:  "keystore.scryptn",
+		Usage: "Scrypt N parameter used to encrypt keys (default = 262144, must be a power of 2)",
+	}
+	KeyStoreScryptPFlag = cli.IntFlag{
+		Name:  "keystore.scryptp",
+		Usage: "Scrypt P parameter used to encrypt keys (default = 1)",
+	}
+	KeyStoreKDFFlag = cli.StringFlag{
+		Name:  "keystore.kdf",
+		Usage: `Key derivation function used to encrypt keys ("scrypt" or "argon2id")`,
+		Value: "scrypt",
+	}
+	WatchOnlyFlag = cli.StringFlag{
+		Name:  "watch",
+		Usage: "Comma separated list of addresses to track as watch-only accounts",
+	}
 	NoUSBFlag = cli.BoolFlag{
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
@@ -161,6 +183,19 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "archive",
 	}
+	TxSearchFlag = cli.BoolFlag{
+		Name:  "txsearch",
+		Usage: "Index the transactions by hash, height, sender, cross chain destination and validator for neat_txSearch",
+	}
+	PipelinedCommitFlag = cli.BoolFlag{
+		Name:  "pipelinedcommit",
+		Usage: "Persist the state and receipts of the committed blocks in the background while the next block executes",
+	}
+	ShutdownTimeoutFlag = cli.DurationFlag{
+		Name:  "shutdown.timeout",
+		Usage: "Time limit for a graceful shutdown before the node exits forcibly",
+		Value: neatptc.DefaultConfig.ShutdownTimeout,
+	}
 
 	// Transaction pool settings
 	TxPoolNoLocalsFlag = cli.BoolFlag{
@@ -292,6 +327,32 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Enable the stand-alone metrics HTTP server listening interface, serving Prometheus metrics on /metrics",
+		Value: "",
+	}
+	MetricsPortFlag = cli.IntFlag{
+		Name:  "metrics.port",
+		Usage: "Metrics HTTP server listening port",
+		Value: 6061,
+	}
+	StallProfileFlag = cli.DurationFlag{
+		Name:  "profile.stall",
+		Usage: "Capture CPU and heap profiles into <datadir>/profiles when no block is imported for this long (0 = disabled)",
+	}
+	FirehoseFlag = cli.StringFlag{
+		Name:  "firehose",
+		Usage: `Stream the execution data of the blocks as they are imported to "stdout", or to the clients of a "unix:<path>" or "tcp:<host:port>" socket`,
+	}
+	FirehoseStartFlag = cli.Uint64Flag{
+		Name:  "firehose.start",
+		Usage: "Block to stream the execution data from (0 = the next block imported)",
+	}
+	TracingEndpointFlag = cli.StringFlag{
+		Name:  "tracing.endpoint",
+		Usage: "OTLP/HTTP endpoint to export block lifecycle traces to (e.g. http://127.0.0.1:4318/v1/traces)",
+	}
 
 	NoCompactionFlag = cli.BoolFlag{
 		Name:  "nocompaction",
@@ -322,6 +383,82 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
 	}
+	RPCRouterFlag = cli.BoolFlag{
+		Name:  "rpc.router",
+		Usage: "Serve the HTTP and WebSocket RPC of all the chains on the HTTP-RPC server, routed by the URL path /<chainId> or the X-Chain-Id header (default = main chain)",
+	}
+	RPCMaxConnsFlag = cli.IntFlag{
+		Name:  "rpc.maxconns",
+		Usage: "Maximum number of connections open to the HTTP-RPC server, shared by all the chains (0 = unlimited)",
+	}
+	RPCAuditLogFlag = DirectoryFlag{
+		Name:  "rpc.auditlog",
+		Usage: "Directory to record every HTTP and WebSocket RPC call in, as rotating JSON logs",
+	}
+	RPCTLSCertFlag = cli.StringFlag{
+		Name:  "rpc.tlscert",
+		Usage: "PEM encoded certificate to serve the HTTP and WebSocket RPC endpoints over HTTPS and WSS with",
+		Value: "",
+	}
+	RPCTLSKeyFlag = cli.StringFlag{
+		Name:  "rpc.tlskey",
+		Usage: "PEM encoded private key of the RPC TLS certificate",
+		Value: "",
+	}
+	RPCTLSClientCAFlag = cli.StringFlag{
+		Name:  "rpc.tlsclientca",
+		Usage: "PEM encoded CA certificates RPC clients must present a certificate signed by (requires --rpc.tlscert)",
+		Value: "",
+	}
+	AuthRPCEnabledFlag = cli.BoolFlag{
+		Name:  "authrpc",
+		Usage: "Enable the JWT authenticated HTTP and WS RPC server, serving all the API modules, and reserve the admin, debug, personal, miner and operator modules to it",
+	}
+	AuthRPCListenAddrFlag = cli.StringFlag{
+		Name:  "authrpc.addr",
+		Usage: "Authenticated RPC server listening interface",
+		Value: node.DefaultAuthHost,
+	}
+	AuthRPCPortFlag = cli.IntFlag{
+		Name:  "authrpc.port",
+		Usage: "Authenticated RPC server listening port",
+		Value: node.DefaultAuthPort,
+	}
+	AuthRPCJWTSecretFlag = cli.StringFlag{
+		Name:  "authrpc.jwtsecret",
+		Usage: "Path to the hex encoded secret the JWT bearer tokens are signed with (generated if missing, default = <datadir>/jwtsecret)",
+		Value: "",
+	}
+	RosettaAddrFlag = cli.StringFlag{
+		Name:  "rosetta.addr",
+		Usage: "Enable the Rosetta Data and Construction API server listening interface, serving every chain of the node",
+		Value: "",
+	}
+	RosettaPortFlag = cli.IntFlag{
+		Name:  "rosetta.port",
+		Usage: "Rosetta API server listening port",
+		Value: 8080,
+	}
+	GRPCAddrFlag = cli.StringFlag{
+		Name:  "grpc.addr",
+		Usage: "Enable the gRPC server listening interface, serving the block, transaction, validator set and epoch queries of every chain of the node",
+		Value: "",
+	}
+	GRPCPortFlag = cli.IntFlag{
+		Name:  "grpc.port",
+		Usage: "gRPC server listening port",
+		Value: 9090,
+	}
+	HealthMinPeersFlag = cli.IntFlag{
+		Name:  "health.minpeers",
+		Usage: "Minimum number of peers of each chain for the /ready endpoint to report ready",
+		Value: 1,
+	}
+	HealthSignWindowFlag = cli.Uint64Flag{
+		Name:  "health.signwindow",
+		Usage: "Number of recent blocks of which a validator must have signed one for the /ready endpoint to report ready (0 = don't check)",
+		Value: 20,
+	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
 		Usage: "API's offered over the HTTP-RPC interface",
@@ -424,6 +561,10 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	RequireAEADFlag = cli.BoolFlag{
+		Name:  "requireaead",
+		Usage: "Rejects peers which don't support authenticated encryption of the transport (legacy RLPx framing)",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -450,11 +591,49 @@ var (
 		Value: neatptc.DefaultConfig.GPO.Percentile,
 	}
 
+	// Alerting settings
+	AlertWebhookFlag = cli.StringFlag{
+		Name:  "alert.webhook",
+		Usage: "URL to POST consensus anomaly alerts to as JSON",
+	}
+	AlertCommandFlag = cli.StringFlag{
+		Name:  "alert.exec",
+		Usage: "Shell command to run on consensus anomalies, receiving the alert as JSON on stdin",
+	}
+	AlertStallFlag = cli.DurationFlag{
+		Name:  "alert.stall",
+		Usage: "Alert when no block is imported for this long (0 = disabled)",
+		Value: neatptc.DefaultConfig.Alerts.StallThreshold,
+	}
+	AlertRoundFlag = cli.IntFlag{
+		Name:  "alert.round",
+		Usage: "Alert when a block commits in a round above this one (0 = disabled)",
+		Value: neatptc.DefaultConfig.Alerts.RoundThreshold,
+	}
+	AlertMissedFlag = cli.Uint64Flag{
+		Name:  "alert.missed",
+		Usage: "Alert when the precommit of this validator is missing from this many consecutive commits (0 = disabled)",
+		Value: neatptc.DefaultConfig.Alerts.MissedCommits,
+	}
+
 	// Data Reduction Flag
 	PruneFlag = cli.BoolFlag{
 		Name:  "prune",
 		Usage: "Enable the Data Reduction feature, history state data will be pruned by default",
 	}
+	PruneBlockFlag = cli.BoolFlag{
+		Name:  "prune.blocks",
+		Usage: "Delete the bodies of the blocks whose state the Data Reduction prunes",
+	}
+	PruneMainChainFlag = cli.BoolFlag{
+		Name:  "prune.mainchain",
+		Usage: "Let the Data Reduction prune the main chain too, it only prunes the side chains by default",
+	}
+	PruneRetentionFlag = cli.Uint64Flag{
+		Name:  "prune.retention",
+		Usage: "Number of most recent blocks whose data the Data Reduction keeps",
+		Value: neatptc.DefaultConfig.PruneRetention,
+	}
 
 	//for performance test
 	PerfTestFlag = cli.BoolFlag{
@@ -477,6 +656,29 @@ var (
 		Name:  "sideChain",
 		Usage: "Specify one or more side chain should be start. Ex: side-1,side-2",
 	}
+	PruneRetiredChainsFlag = cli.BoolFlag{
+		Name:  "pruneRetiredChains",
+		Usage: "Delete the data of the side chains once retired by governance",
+	}
+	RelayerFlag = cli.BoolFlag{
+		Name:  "relayer",
+		Usage: "Relay the withdrawals from the side chains run by the node to the main chain, saving the proofs of their TX3s there",
+	}
+	RelayerAccountsFlag = cli.StringFlag{
+		Name:  "relayer.accounts",
+		Usage: "Comma separated accounts, unlocked on the main chain, the relayer also sends the TX4s of",
+		Value: "",
+	}
+	RelayerMaxGasPriceFlag = BigFlag{
+		Name:  "relayer.maxgasprice",
+		Usage: "Highest gas price the relayer bids for the TX4s it sends again",
+		Value: big.NewInt(100 * params.GWei),
+	}
+	RelayerRetriesFlag = cli.IntFlag{
+		Name:  "relayer.retries",
+		Usage: "Times the relayer sends a TX4 not included in the main chain before giving up",
+		Value: 5,
+	}
 
 	// ----------------------------
 	// NeatCon Flags
@@ -753,6 +955,22 @@ func SetWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// SetAuthRPC sets up the authenticated RPC endpoint from the set command line
+// flags, leaving it disabled unless requested.
+func SetAuthRPC(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalBool(AuthRPCEnabledFlag.Name) && cfg.AuthHost == "" {
+		cfg.AuthHost = ctx.GlobalString(AuthRPCListenAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(AuthRPCPortFlag.Name) {
+		cfg.AuthPort = ctx.GlobalInt(AuthRPCPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(AuthRPCJWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(AuthRPCJWTSecretFlag.Name)
+	} else if cfg.JWTSecret == "" {
+		cfg.JWTSecret = filepath.Join(MakeDataDir(ctx), "jwtsecret")
+	}
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -879,6 +1097,9 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.GlobalIsSet(RequireAEADFlag.Name) {
+		cfg.RequireAEAD = true
+	}
 }
 
 // SetNodeConfig applies node-related command line flags to the config.
@@ -887,6 +1108,7 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	SetHTTP(ctx, cfg)
 	SetWS(ctx, cfg)
+	SetAuthRPC(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
@@ -902,6 +1124,19 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
+	if ctx.GlobalIsSet(KeyStoreScryptNFlag.Name) {
+		cfg.KeyStoreScryptN = ctx.GlobalInt(KeyStoreScryptNFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptPFlag.Name) {
+		cfg.KeyStoreScryptP = ctx.GlobalInt(KeyStoreScryptPFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreKDFFlag.Name) {
+		cfg.KeyStoreKDF = ctx.GlobalString(KeyStoreKDFFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(WatchOnlyFlag.Name) {
+		cfg.WatchOnlyAddresses = strings.Split(ctx.GlobalString(WatchOnlyFlag.Name), ",")
+	}
 
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
@@ -917,6 +1152,24 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	}
 }
 
+func setAlerts(ctx *cli.Context, cfg *alert.Config) {
+	if ctx.GlobalIsSet(AlertWebhookFlag.Name) {
+		cfg.Webhook = ctx.GlobalString(AlertWebhookFlag.Name)
+	}
+	if ctx.GlobalIsSet(AlertCommandFlag.Name) {
+		cfg.Command = ctx.GlobalString(AlertCommandFlag.Name)
+	}
+	if ctx.GlobalIsSet(AlertStallFlag.Name) {
+		cfg.StallThreshold = ctx.GlobalDuration(AlertStallFlag.Name)
+	}
+	if ctx.GlobalIsSet(AlertRoundFlag.Name) {
+		cfg.RoundThreshold = ctx.GlobalInt(AlertRoundFlag.Name)
+	}
+	if ctx.GlobalIsSet(AlertMissedFlag.Name) {
+		cfg.MissedCommits = ctx.GlobalUint64(AlertMissedFlag.Name)
+	}
+}
+
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
@@ -1007,6 +1260,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *neatptc.Config) {
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setCoinbase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
+	setAlerts(ctx, &cfg.Alerts)
 	setTxPool(ctx, &cfg.TxPool)
 
 	switch {
@@ -1025,10 +1279,25 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *neatptc.Config) {
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
 
-	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
-		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	// The retention settings of the config file apply per chain, the flags
+	// override them for all the chains
+	if ctx.GlobalIsSet(GCModeFlag.Name) {
+		if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
+			Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+		}
+		cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 	}
-	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	cfg.TxSearch = ctx.GlobalBool(TxSearchFlag.Name)
+	cfg.PipelinedCommit = ctx.GlobalBool(PipelinedCommitFlag.Name)
+
+	if ctx.GlobalIsSet(ShutdownTimeoutFlag.Name) {
+		cfg.ShutdownTimeout = ctx.GlobalDuration(ShutdownTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(StallProfileFlag.Name) {
+		cfg.StallProfileThreshold = ctx.GlobalDuration(StallProfileFlag.Name)
+	}
+	cfg.Firehose = ctx.GlobalString(FirehoseFlag.Name)
+	cfg.FirehoseStart = ctx.GlobalUint64(FirehoseStartFlag.Name)
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
@@ -1067,14 +1336,43 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *neatptc.Config) {
 	}
 
 	// Data Reduction Config
-	cfg.PruneStateData = ctx.GlobalBool(PruneFlag.Name)
-	//cfg.PruneBlockData = ctx.GlobalBool(PruneBlockFlag.Name)
+	if ctx.GlobalIsSet(PruneFlag.Name) {
+		cfg.PruneStateData = ctx.GlobalBool(PruneFlag.Name)
+	}
+	if ctx.GlobalIsSet(PruneBlockFlag.Name) {
+		cfg.PruneBlockData = ctx.GlobalBool(PruneBlockFlag.Name)
+	}
+	if ctx.GlobalIsSet(PruneMainChainFlag.Name) {
+		cfg.PruneMainChain = ctx.GlobalBool(PruneMainChainFlag.Name)
+	}
+	if ctx.GlobalIsSet(PruneRetentionFlag.Name) {
+		cfg.PruneRetention = ctx.GlobalUint64(PruneRetentionFlag.Name)
+	}
+	if cfg.PruneRetention < datareduction.MinRetention {
+		Fatalf("Data Reduction must keep at least %d blocks, have %d", datareduction.MinRetention, cfg.PruneRetention)
+	}
 }
 
 func SetGeneralConfig(ctx *cli.Context) {
 	params.GenCfg.PerfTest = ctx.GlobalBool(PerfTestFlag.Name)
 }
 
+// SetupMetrics starts the stand-alone metrics HTTP server if it is enabled.
+func SetupMetrics(ctx *cli.Context) {
+	if !metrics.Enabled || !ctx.GlobalIsSet(MetricsHTTPFlag.Name) {
+		return
+	}
+	address := fmt.Sprintf("%s:%d", ctx.GlobalString(MetricsHTTPFlag.Name), ctx.GlobalInt(MetricsPortFlag.Name))
+	exp.Setup(address)
+}
+
+// SetupTracing starts exporting block lifecycle traces if an endpoint is set.
+func SetupTracing(ctx *cli.Context) {
+	if endpoint := ctx.GlobalString(TracingEndpointFlag.Name); endpoint != "" {
+		tracing.Setup(endpoint, ctx.App.Name)
+	}
+}
+
 // registerIntService adds an NEAT Chain client to the stack.
 func RegisterIntService(stack *node.Node, cfg *neatptc.Config, cliCtx *cli.Context, cch core.CrossChainHelper) {
 	var err error
@@ -38,6 +38,13 @@ func NewP2PServer(ctx *cli.Context) *NeatChainP2PServer {
 	if serverConfig.NodeDatabase == "" {
 		serverConfig.NodeDatabase = config.NodeDB()
 	}
+	if serverConfig.PeerFilter == nil {
+		filter, err := config.PeerFilter()
+		if err != nil {
+			Fatalf("Failed to set up the peer filter: %v", err)
+		}
+		serverConfig.PeerFilter = filter
+	}
 	serverConfig.LocalValidators = make([]p2p.P2PValidator, 0)
 	serverConfig.Validators = make(map[p2p.P2PValidator]*p2p.P2PValidatorNodeInfo)
 
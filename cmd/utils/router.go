@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// RouterChainHeader is the header naming the chain a request to the RPC router
+// is for, in place of the URL path.
+const RouterChainHeader = "X-Chain-Id"
+
+// rpcRouter serves the HTTP and WebSocket RPC of all the chains of the node on
+// a single endpoint. The target chain of a request is the one of the URL path,
+// /<chainId>, or of the X-Chain-Id header, the main chain if neither is set.
+// Every chain is served by its own handler, with the API modules configured
+// for it.
+type rpcRouter struct {
+	lock      sync.RWMutex
+	mainChain string
+	http      map[string]http.Handler
+	ws        map[string]http.Handler
+}
+
+func newRPCRouter() *rpcRouter {
+	return &rpcRouter{
+		http: make(map[string]http.Handler),
+		ws:   make(map[string]http.Handler),
+	}
+}
+
+func (r *rpcRouter) setMainChain(chainId string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.mainChain = chainId
+}
+
+func (r *rpcRouter) setHTTP(chainId string, handler http.Handler) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.http[chainId] = handler
+}
+
+func (r *rpcRouter) setWS(chainId string, handler http.Handler) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.ws[chainId] = handler
+}
+
+// chain returns the chain a request is for.
+func (r *rpcRouter) chain(req *http.Request) (string, bool) {
+	path := strings.Trim(req.URL.Path, "/")
+	header := req.Header.Get(RouterChainHeader)
+	switch {
+	case path != "" && header != "" && path != header:
+		return "", false
+	case path != "":
+		return path, true
+	case header != "":
+		return header, true
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.mainChain, true
+}
+
+func (r *rpcRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	chainId, ok := r.chain(req)
+	if !ok {
+		http.Error(w, "chain of the URL path and of the "+RouterChainHeader+" header differ", http.StatusBadRequest)
+		return
+	}
+
+	r.lock.RLock()
+	handlers := r.http
+	if strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		handlers = r.ws
+	}
+	handler := handlers[chainId]
+	r.lock.RUnlock()
+
+	if handler == nil {
+		http.Error(w, "unknown chain "+chainId, http.StatusNotFound)
+		return
+	}
+	handler.ServeHTTP(w, req)
+}
@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRPCRouter(t *testing.T) {
+	router := newRPCRouter()
+	router.setMainChain("neatio")
+	for _, chainId := range []string{"neatio", "side_0"} {
+		chainId := chainId
+		router.setHTTP(chainId, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("http " + chainId))
+		}))
+		router.setWS(chainId, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ws " + chainId))
+		}))
+	}
+
+	tests := []struct {
+		path   string
+		header string
+		ws     bool
+		code   int
+		body   string
+	}{
+		{path: "/", code: http.StatusOK, body: "http neatio"},
+		{path: "/side_0", code: http.StatusOK, body: "http side_0"},
+		{path: "/side_0/", code: http.StatusOK, body: "http side_0"},
+		{path: "/", header: "side_0", code: http.StatusOK, body: "http side_0"},
+		{path: "/side_0", header: "side_0", code: http.StatusOK, body: "http side_0"},
+		{path: "/side_0", ws: true, code: http.StatusOK, body: "ws side_0"},
+		{path: "/", ws: true, code: http.StatusOK, body: "ws neatio"},
+		{path: "/neatio", header: "side_0", code: http.StatusBadRequest},
+		{path: "/side_1", code: http.StatusNotFound},
+		{path: "/", header: "side_1", code: http.StatusNotFound},
+	}
+	for i, tt := range tests {
+		req := httptest.NewRequest("POST", tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set(RouterChainHeader, tt.header)
+		}
+		if tt.ws {
+			req.Header.Set("Upgrade", "websocket")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.code {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, rec.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("test %d: routed to %q, want %q", i, rec.Body.String(), tt.body)
+		}
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/neatlab/neatio/grpcapi"
 	"github.com/neatlab/neatio/log"
 	"github.com/neatlab/neatio/node"
+	"github.com/neatlab/neatio/rosetta"
 	"github.com/neatlab/neatio/rpc"
+	"golang.org/x/net/netutil"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -16,11 +19,24 @@ var (
 	httpListener       net.Listener
 	httpMux            *http.ServeMux
 	httpHandlerMapping map[string]*rpc.Server
+	router             *rpcRouter // Serves all the chains on the HTTP endpoint in router mode
 
 	wsListener       net.Listener
 	wsMux            *http.ServeMux
 	wsOrigins        []string
 	wsHandlerMapping map[string]*rpc.Server
+
+	rosettaListener net.Listener
+	rosettaServer   *rosetta.Server
+
+	grpcListener net.Listener
+	grpcServer   *grpcapi.Server
+
+	authListener       net.Listener
+	authMux            *http.ServeMux
+	authHandlerMapping map[string]*rpc.Server
+
+	rpcTLS *rpc.TLSConfig // TLS of the HTTP, WebSocket and authenticated endpoints
 )
 
 func StartRPC(ctx *cli.Context) error {
@@ -31,18 +47,59 @@ func StartRPC(ctx *cli.Context) error {
 	// Setup the config from context
 	SetHTTP(ctx, &rpcConfig)
 	SetWS(ctx, &rpcConfig)
+	SetAuthRPC(ctx, &rpcConfig)
 	wsOrigins = rpcConfig.WSOrigins
+	rpcTLS = &rpc.TLSConfig{
+		CertFile:     ctx.GlobalString(RPCTLSCertFlag.Name),
+		KeyFile:      ctx.GlobalString(RPCTLSKeyFlag.Name),
+		ClientCAFile: ctx.GlobalString(RPCTLSClientCAFlag.Name),
+	}
+	if rpcTLS.ClientCAFile != "" && !rpcTLS.Enabled() {
+		return fmt.Errorf("--%s requires --%s and --%s", RPCTLSClientCAFlag.Name, RPCTLSCertFlag.Name, RPCTLSKeyFlag.Name)
+	}
 
-	httperr := startHTTP(rpcConfig.HTTPEndpoint(), rpcConfig.HTTPCors, rpcConfig.HTTPVirtualHosts, rpcConfig.HTTPTimeouts)
+	if dir := ctx.GlobalString(RPCAuditLogFlag.Name); dir != "" {
+		if err := rpc.SetAuditLog(dir); err != nil {
+			return err
+		}
+	}
+
+	routed := ctx.GlobalBool(RPCRouterFlag.Name)
+	if routed && rpcConfig.HTTPEndpoint() == "" {
+		return fmt.Errorf("--%s requires --%s", RPCRouterFlag.Name, RPCEnabledFlag.Name)
+	}
+	httperr := startHTTP(rpcConfig.HTTPEndpoint(), rpcConfig.HTTPCors, rpcConfig.HTTPVirtualHosts, rpcConfig.HTTPTimeouts, ctx.GlobalInt(RPCMaxConnsFlag.Name))
 	if httperr != nil {
 		return httperr
 	}
+	if routed {
+		router = newRPCRouter()
+		wsHandlerMapping = make(map[string]*rpc.Server)
+		httpMux.Handle("/", router)
+		log.Info("RPC router enabled", "url", fmt.Sprintf("%s://%s", rpcTLS.Scheme("http"), httpListener.Addr()), "header", RouterChainHeader, "maxconns", ctx.GlobalInt(RPCMaxConnsFlag.Name))
+	}
 
 	wserr := startWS(rpcConfig.WSEndpoint())
 	if wserr != nil {
 		return wserr
 	}
 
+	if err := startAuth(rpcConfig.AuthEndpoint(), rpcConfig.JWTSecretPath()); err != nil {
+		return err
+	}
+
+	if addr := ctx.GlobalString(RosettaAddrFlag.Name); addr != "" {
+		if err := startRosetta(fmt.Sprintf("%s:%d", addr, ctx.GlobalInt(RosettaPortFlag.Name))); err != nil {
+			return err
+		}
+	}
+
+	if addr := ctx.GlobalString(GRPCAddrFlag.Name); addr != "" {
+		if err := startGRPC(fmt.Sprintf("%s:%d", addr, ctx.GlobalInt(GRPCPortFlag.Name))); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -52,7 +109,7 @@ func StopRPC() {
 		httpAddr := httpListener.Addr().String()
 		httpListener.Close()
 		httpListener = nil
-		log.Info("HTTP endpoint closed", "url", fmt.Sprintf("http://%s", httpAddr))
+		log.Info("HTTP endpoint closed", "url", fmt.Sprintf("%s://%s", rpcTLS.Scheme("http"), httpAddr))
 	}
 	if httpMux != nil {
 		for _, httpHandler := range httpHandlerMapping {
@@ -65,70 +122,179 @@ func StopRPC() {
 		wsAddr := wsListener.Addr().String()
 		wsListener.Close()
 		wsListener = nil
-		log.Info("WebSocket endpoint closed", "url", fmt.Sprintf("ws://%s", wsAddr))
+		log.Info("WebSocket endpoint closed", "url", fmt.Sprintf("%s://%s", rpcTLS.Scheme("ws"), wsAddr))
 	}
-	if wsMux != nil {
+	if wsMux != nil || router != nil {
 		for _, wsHandler := range wsHandlerMapping {
 			wsHandler.Stop()
 		}
 	}
+	router = nil
+
+	// Stop the authenticated RPC Listener
+	if authListener != nil {
+		authAddr := authListener.Addr().String()
+		authListener.Close()
+		authListener = nil
+		log.Info("Authenticated RPC endpoint closed", "url", fmt.Sprintf("%s://%s", rpcTLS.Scheme("http"), authAddr))
+	}
+	if authMux != nil {
+		for _, authHandler := range authHandlerMapping {
+			authHandler.Stop()
+		}
+	}
+
+	// Stop Rosetta Listener
+	if rosettaListener != nil {
+		rosettaAddr := rosettaListener.Addr().String()
+		rosettaListener.Close()
+		rosettaListener = nil
+		log.Info("Rosetta endpoint closed", "url", fmt.Sprintf("http://%s", rosettaAddr))
+	}
+
+	// Stop gRPC Listener
+	if grpcListener != nil {
+		grpcAddr := grpcListener.Addr().String()
+		grpcListener.Close()
+		grpcListener = nil
+		log.Info("gRPC endpoint closed", "addr", grpcAddr)
+	}
 }
 
 func IsHTTPRunning() bool {
 	return httpListener != nil && httpMux != nil
 }
 
+// IsWSRunning returns whether the WebSocket RPC is served, on its own endpoint
+// or by the RPC router.
 func IsWSRunning() bool {
-	return wsListener != nil && wsMux != nil
+	return wsListener != nil && wsMux != nil || router != nil
+}
+
+func IsAuthRunning() bool {
+	return authListener != nil && authMux != nil
+}
+
+func IsRosettaRunning() bool {
+	return rosettaListener != nil && rosettaServer != nil
+}
+
+func IsGRPCRunning() bool {
+	return grpcListener != nil && grpcServer != nil
 }
 
 func HookupHTTP(chainId string, httpHandler *rpc.Server) error {
 	if httpMux != nil {
 		log.Infof("Hookup HTTP for (chainId, http Handler): (%v, %v)", chainId, httpHandler)
 		if httpHandler != nil {
-			httpMux.Handle("/"+chainId, httpHandler)
+			if router != nil {
+				router.setHTTP(chainId, httpHandler)
+			} else {
+				httpMux.Handle("/"+chainId, httpHandler)
+			}
 			httpHandlerMapping[chainId] = httpHandler
 		}
 	}
 	return nil
 }
 
+// HookupRouter sets the chain the RPC router serves the requests naming no
+// chain to.
+func HookupRouter(mainChainId string) {
+	if router != nil {
+		router.setMainChain(mainChainId)
+	}
+}
+
+// HookupAuth serves the APIs of a chain over both HTTP and WebSocket on the
+// authenticated endpoint.
+func HookupAuth(chainId string, authHandler *rpc.Server) {
+	if authMux != nil && authHandler != nil {
+		log.Infof("Hookup authenticated RPC for chainId: %v", chainId)
+		ws := authHandler.WebsocketHandler(wsOrigins)
+		authMux.Handle("/"+chainId, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				ws.ServeHTTP(w, r)
+				return
+			}
+			authHandler.ServeHTTP(w, r)
+		}))
+		authHandlerMapping[chainId] = authHandler
+	}
+}
+
+// HookupHealth serves the health and readiness probes on the HTTP endpoint.
+func HookupHealth(health, ready http.Handler) {
+	if httpMux != nil {
+		httpMux.Handle("/health", health)
+		httpMux.Handle("/ready", ready)
+	}
+}
+
+// HookupRosetta serves the Rosetta APIs of a chain on the Rosetta endpoint.
+func HookupRosetta(chainId string, backend rosetta.Backend) {
+	if rosettaServer != nil {
+		log.Infof("Hookup Rosetta for chainId: %v", chainId)
+		rosettaServer.AddChain(chainId, backend)
+	}
+}
+
+// HookupGRPC serves the gRPC service of a chain on the gRPC endpoint, the
+// calls without chain id go to the main chain.
+func HookupGRPC(chainId string, backend grpcapi.Backend, mainChain bool) {
+	if grpcServer != nil {
+		log.Infof("Hookup gRPC for chainId: %v", chainId)
+		grpcServer.AddChain(chainId, backend)
+		if mainChain {
+			grpcServer.SetMainChain(chainId)
+		}
+	}
+}
+
 func HookupWS(chainId string, wsHandler *rpc.Server) error {
-	if wsMux != nil {
+	if wsMux != nil || router != nil {
 		log.Infof("Hookup WS for (chainId, ws Handler): (%v, %v)", chainId, wsHandler)
 		if wsHandler != nil {
-			wsMux.Handle("/"+chainId, wsHandler.WebsocketHandler(wsOrigins))
+			if router != nil {
+				router.setWS(chainId, wsHandler.WebsocketHandler(wsOrigins))
+			} else {
+				wsMux.Handle("/"+chainId, wsHandler.WebsocketHandler(wsOrigins))
+			}
 			wsHandlerMapping[chainId] = wsHandler
 		}
 	}
 	return nil
 }
 
-func startHTTP(endpoint string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts) error {
+func startHTTP(endpoint string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts, maxConns int) error {
 	// Short circuit if the HTTP endpoint isn't being exposed
 	if endpoint == "" {
 		return nil
 	}
 
 	var err error
-	httpListener, httpMux, err = startNeatChainHTTPEndpoint(endpoint, cors, vhosts, timeouts)
+	httpListener, httpMux, err = startNeatChainHTTPEndpoint(endpoint, cors, vhosts, timeouts, maxConns)
 	if err != nil {
 		return err
 	}
 	httpHandlerMapping = make(map[string]*rpc.Server)
 
-	log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	log.Info("HTTP endpoint opened", "url", fmt.Sprintf("%s://%s", rpcTLS.Scheme("http"), endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","), "clientauth", rpcTLS.ClientCAFile != "")
 	return nil
 }
 
-func startNeatChainHTTPEndpoint(endpoint string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts) (net.Listener, *http.ServeMux, error) {
+func startNeatChainHTTPEndpoint(endpoint string, cors []string, vhosts []string, timeouts rpc.HTTPTimeouts, maxConns int) (net.Listener, *http.ServeMux, error) {
 	var (
 		listener net.Listener
 		err      error
 	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	if listener, err = rpcTLS.Listen(endpoint); err != nil {
 		return nil, nil, err
 	}
+	// The connections of all the chains count against the same limit
+	if maxConns > 0 {
+		listener = netutil.LimitListener(listener, maxConns)
+	}
 	mux := http.NewServeMux()
 	go rpc.NewHTTPServer(cors, vhosts, timeouts, mux).Serve(listener)
 	return listener, mux, err
@@ -147,7 +313,7 @@ func startWS(endpoint string) error {
 	}
 	wsHandlerMapping = make(map[string]*rpc.Server)
 
-	log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", wsListener.Addr()))
+	log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("%s://%s", rpcTLS.Scheme("ws"), wsListener.Addr()), "clientauth", rpcTLS.ClientCAFile != "")
 	return nil
 }
 
@@ -156,7 +322,7 @@ func startNeatChainWSEndpoint(endpoint string) (net.Listener, *http.ServeMux, er
 		listener net.Listener
 		err      error
 	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	if listener, err = rpcTLS.Listen(endpoint); err != nil {
 		return nil, nil, err
 	}
 	mux := http.NewServeMux()
@@ -164,3 +330,50 @@ func startNeatChainWSEndpoint(endpoint string) (net.Listener, *http.ServeMux, er
 	go wsServer.Serve(listener)
 	return listener, mux, err
 }
+
+// startAuth opens the endpoint serving the chains to the requests carrying a
+// bearer token signed with the JWT secret.
+func startAuth(endpoint string, secretPath string) error {
+	// Short circuit if the authenticated endpoint isn't being exposed
+	if endpoint == "" {
+		return nil
+	}
+	secret, err := rpc.LoadJWTSecret(secretPath)
+	if err != nil {
+		return err
+	}
+	listener, err := rpcTLS.Listen(endpoint)
+	if err != nil {
+		return err
+	}
+	authListener, authMux = listener, http.NewServeMux()
+	authHandlerMapping = make(map[string]*rpc.Server)
+	go (&http.Server{Handler: rpc.NewJWTHandler(secret, authMux)}).Serve(listener)
+
+	log.Info("Authenticated RPC endpoint opened", "url", fmt.Sprintf("%s://%s", rpcTLS.Scheme("http"), listener.Addr()), "jwtsecret", secretPath, "privileged", strings.Join(node.PrivilegedModules, ","))
+	return nil
+}
+
+func startRosetta(endpoint string) error {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	rosettaListener, rosettaServer = listener, rosetta.NewServer()
+	go http.Serve(listener, rosettaServer)
+
+	log.Info("Rosetta endpoint opened", "url", fmt.Sprintf("http://%s", listener.Addr()))
+	return nil
+}
+
+func startGRPC(endpoint string) error {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return err
+	}
+	grpcListener, grpcServer = listener, grpcapi.NewServer("")
+	go http.Serve(listener, grpcServer.Handler())
+
+	log.Info("gRPC endpoint opened", "addr", listener.Addr())
+	return nil
+}
@@ -0,0 +1,108 @@
+// Package neataddr converts Neatio addresses between their NEAT and hex forms.
+//
+// A Neatio address is the 32 byte ASCII string "NEAT..." made of a base58
+// alphabet. Its hex form is the 0x prefixed hex encoding of those 32 bytes,
+// optionally with mixed case checksum in the style of EIP-55.
+package neataddr
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/crypto"
+)
+
+var (
+	ErrInvalidNeatAddress = errors.New("invalid NEAT address")
+	ErrInvalidHexAddress  = errors.New("invalid hex address")
+	ErrBadChecksum        = errors.New("hex address checksum mismatch")
+)
+
+// Formats holds the representations of an address.
+type Formats struct {
+	Neat string `json:"neat"`
+	Hex  string `json:"hex"`
+}
+
+// IsNeatAddress reports whether s is a well formed NEAT address.
+func IsNeatAddress(s string) bool {
+	return crypto.ValidateNeatAddr(s)
+}
+
+// IsHexAddress reports whether s is the hex form of a NEAT address, with a
+// valid checksum if s is mixed case.
+func IsHexAddress(s string) bool {
+	_, err := ParseHex(s)
+	return err == nil
+}
+
+// ParseNeat parses a NEAT address.
+func ParseNeat(s string) (common.Address, error) {
+	if !crypto.ValidateNeatAddr(s) {
+		return common.Address{}, ErrInvalidNeatAddress
+	}
+	return common.StringToAddress(s), nil
+}
+
+// ParseHex parses the hex form of an address. All lower or all upper case input
+// is accepted as is, mixed case input must carry a valid checksum.
+func ParseHex(s string) (common.Address, error) {
+	raw := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(raw) != 2*common.NeatAddressLength {
+		return common.Address{}, ErrInvalidHexAddress
+	}
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return common.Address{}, ErrInvalidHexAddress
+	}
+	addr := common.BytesToAddress(b)
+	if !crypto.ValidateNeatAddr(addr.String()) {
+		return common.Address{}, ErrInvalidHexAddress
+	}
+	if raw != strings.ToLower(raw) && raw != strings.ToUpper(raw) && "0x"+raw != ChecksumHex(addr) {
+		return common.Address{}, ErrBadChecksum
+	}
+	return addr, nil
+}
+
+// Parse parses an address given in either form.
+func Parse(s string) (common.Address, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return ParseHex(s)
+	}
+	return ParseNeat(s)
+}
+
+// ChecksumHex returns the mixed case checksummed hex form of the address.
+func ChecksumHex(a common.Address) string {
+	unchecksummed := hex.EncodeToString(a[:])
+	hash := crypto.Keccak256([]byte(unchecksummed))
+
+	result := []byte(unchecksummed)
+	for i := 0; i < len(result); i++ {
+		hashByte := hash[i/2]
+		if i%2 == 0 {
+			hashByte = hashByte >> 4
+		} else {
+			hashByte &= 0xf
+		}
+		if result[i] > '9' && hashByte > 7 {
+			result[i] -= 32
+		}
+	}
+	return "0x" + string(result)
+}
+
+// Convert parses an address given in either form and returns both forms.
+func Convert(s string) (*Formats, error) {
+	addr, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Formats{
+		Neat: addr.String(),
+		Hex:  ChecksumHex(addr),
+	}, nil
+}
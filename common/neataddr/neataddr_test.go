@@ -0,0 +1,63 @@
+package neataddr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neatlab/neatio/crypto"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	neat := crypto.PubkeyToAddress(key.PublicKey).String()
+
+	formats, err := Convert(neat)
+	if err != nil {
+		t.Fatalf("convert %s: %v", neat, err)
+	}
+	if formats.Neat != neat {
+		t.Errorf("neat form mismatch: have %s, want %s", formats.Neat, neat)
+	}
+	for _, input := range []string{formats.Hex, strings.ToLower(formats.Hex), "0x" + strings.ToUpper(formats.Hex[2:])} {
+		back, err := Convert(input)
+		if err != nil {
+			t.Fatalf("convert %s: %v", input, err)
+		}
+		if back.Neat != neat || back.Hex != formats.Hex {
+			t.Errorf("round trip of %s mismatch: have %+v, want %+v", input, back, formats)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	checksummed := ChecksumHex(crypto.PubkeyToAddress(key.PublicKey))
+
+	// Flip the case of the first letter to break the checksum
+	broken := []byte(checksummed)
+	for i := 2; i < len(broken); i++ {
+		if broken[i] >= 'a' && broken[i] <= 'f' {
+			broken[i] -= 32
+			break
+		} else if broken[i] >= 'A' && broken[i] <= 'F' {
+			broken[i] += 32
+			break
+		}
+	}
+	tests := []struct {
+		input string
+		err   error
+	}{
+		{"", ErrInvalidNeatAddress},
+		{"NEAT0000000000000000000000000000", ErrInvalidNeatAddress},
+		{"XEAT" + crypto.PubkeyToAddress(key.PublicKey).String()[4:], ErrInvalidNeatAddress},
+		{"0x1234", ErrInvalidHexAddress},
+		{"0x" + strings.Repeat("00", 32), ErrInvalidHexAddress},
+		{string(broken), ErrBadChecksum},
+	}
+	for _, test := range tests {
+		if _, err := Parse(test.input); err != test.err {
+			t.Errorf("parse %q: have error %v, want %v", test.input, err, test.err)
+		}
+	}
+}
@@ -19,11 +19,13 @@ package consensus
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/consensus/neatpos/epoch"
 	"github.com/neatlab/neatio/core/state"
 	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/event"
 	"github.com/neatlab/neatio/params"
 	"github.com/neatlab/neatio/rpc"
 )
@@ -152,6 +154,19 @@ type EngineStartStop interface {
 	Stop() error
 }
 
+// ConflictingCommitEvent is the type of the consensus event posted when two
+// blocks committed at one height are detected.
+const ConflictingCommitEvent = "ConflictingCommit"
+
+// ConsensusEvent is posted when the consensus of a NeatPoS engine enters a
+// new round step, commits a block or detects conflicting commits.
+type ConsensusEvent struct {
+	Type   string // NewRoundStep, FinalCommitted or ConflictingCommit
+	Height uint64
+	Round  int
+	Step   string
+}
+
 // NeatPoS is a consensus engine to avoid byzantine failure
 type NeatPoS interface {
 	Engine
@@ -172,6 +187,22 @@ type NeatPoS interface {
 
 	PrivateValidator() common.Address
 
+	// WaitForProposedBlock waits until the block proposed by this node at the
+	// current height is committed, or the timeout expires
+	WaitForProposedBlock(timeout time.Duration) bool
+
+	// SkipEmptyBlocks reports whether blocks are only produced when transactions
+	// are pending, or when the heartbeat interval elapses
+	SkipEmptyBlocks() bool
+
+	// LastSignedBlock returns the highest block after the given number committed
+	// with a signature of the validator, or 0 if it signed none of them
+	LastSignedBlock(from common.Address, after uint64) (uint64, error)
+
+	// SubscribeConsensusEvent registers a subscription of the round steps and
+	// the commits of the consensus
+	SubscribeConsensusEvent(ch chan<- ConsensusEvent) event.Subscription
+
 	// VerifyHeader checks whether a header conforms to the consensus rules of a given engine.
 	VerifyHeaderBeforeConsensus(chain ChainReader, header *types.Header, seal bool) error
 }
@@ -1,19 +1,31 @@
 package neatpos
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
+	"time"
 
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/common/hexutil"
 	"github.com/neatlab/neatio/consensus"
+	ncConsensus "github.com/neatlab/neatio/consensus/neatpos/consensus"
 	"github.com/neatlab/neatio/consensus/neatpos/epoch"
 	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core/state"
 	neatCrypto "github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/params"
+	cmn "github.com/neatlib/common-go"
 	"github.com/neatlib/crypto-go"
+	"github.com/neatlib/wire-go"
 )
 
+// maxProposerSchedule is the largest number of rounds and heights the proposer
+// schedule is returned for.
+const maxProposerSchedule = 1000
+
 // API is a user facing RPC API of NeatCon
 type API struct {
 	chain   consensus.ChainReader
@@ -41,6 +53,11 @@ func (api *API) GetEpoch(num hexutil.Uint64) (*ncTypes.EpochApiForConsole, error
 		resultEpoch = epoch.LoadOneEpoch(curEpoch.GetDB(), number, nil)
 	}
 
+	state, err := api.chain.State()
+	if err != nil {
+		return nil, err
+	}
+
 	validators := make([]*ncTypes.EpochValidatorForConsole, len(resultEpoch.Validators.Validators))
 	for i, val := range resultEpoch.Validators.Validators {
 		validators[i] = &ncTypes.EpochValidatorForConsole{
@@ -48,6 +65,7 @@ func (api *API) GetEpoch(num hexutil.Uint64) (*ncTypes.EpochApiForConsole, error
 			PubKey:         val.PubKey.KeyString(),
 			Amount:         (*hexutil.Big)(val.VotingPower),
 			RemainingEpoch: hexutil.Uint64(val.RemainingEpoch),
+			Metadata:       validatorMetadata(state, common.BytesToAddress(val.Address)),
 		}
 	}
 
@@ -62,6 +80,246 @@ func (api *API) GetEpoch(num hexutil.Uint64) (*ncTypes.EpochApiForConsole, error
 	}, nil
 }
 
+// EpochRewards returns the rewards distributed in the epoch: the block rewards
+// and fees paid, the treasury share, and by validator the reward, commission
+// and rewards of the delegators. Deposits are not slashed, the validators
+// banned at the end of the epoch are listed instead.
+func (api *API) EpochRewards(num hexutil.Uint64) (*ncTypes.EpochRewardsApi, error) {
+	curEpoch := api.neatcon.core.consensusState.Epoch
+	if uint64(num) > curEpoch.Number {
+		return nil, errors.New("epoch number out of range")
+	}
+	rewards := epoch.LoadEpochRewards(curEpoch.GetDB(), uint64(num))
+	if rewards == nil {
+		return nil, fmt.Errorf("no rewards recorded for epoch %d", num)
+	}
+
+	result := &ncTypes.EpochRewardsApi{
+		Number:     hexutil.Uint64(rewards.Number),
+		FromBlock:  hexutil.Uint64(rewards.FirstBlock),
+		ToBlock:    hexutil.Uint64(rewards.LastBlock),
+		Reward:     (*hexutil.Big)(rewards.Reward),
+		GasFees:    (*hexutil.Big)(rewards.GasFees),
+		Treasury:   (*hexutil.Big)(rewards.Treasury),
+		Validators: make([]*ncTypes.ValidatorRewardApi, 0, len(rewards.Validators)),
+		Banned:     make([]string, len(rewards.Banned)),
+	}
+	for _, val := range rewards.Validators {
+		valApi := &ncTypes.ValidatorRewardApi{
+			Address:    val.Address.String(),
+			Blocks:     hexutil.Uint64(val.Blocks),
+			Reward:     (*hexutil.Big)(val.Reward),
+			Commission: (*hexutil.Big)(val.Commission),
+			Delegators: make([]*ncTypes.DelegatorRewardApi, 0, len(val.Delegators)),
+		}
+		for _, delegator := range val.Delegators {
+			valApi.Delegators = append(valApi.Delegators, &ncTypes.DelegatorRewardApi{
+				Address: delegator.Address.String(),
+				Reward:  (*hexutil.Big)(delegator.Reward),
+			})
+		}
+		sort.Slice(valApi.Delegators, func(i, j int) bool {
+			return valApi.Delegators[i].Address < valApi.Delegators[j].Address
+		})
+		result.Validators = append(result.Validators, valApi)
+	}
+	sort.Slice(result.Validators, func(i, j int) bool {
+		return result.Validators[i].Address < result.Validators[j].Address
+	})
+	for i, addr := range rewards.Banned {
+		result.Banned[i] = addr.String()
+	}
+	return result, nil
+}
+
+// ValidatorUptime returns the share of the last window blocks committed with a
+// signature of the validator, and the heights of the blocks it missed. Only the
+// blocks committed while the validator was in the validator set are counted.
+func (api *API) ValidatorUptime(from common.Address, window hexutil.Uint64) (*ncTypes.ValidatorUptimeApi, error) {
+	uptime, err := api.neatcon.ValidatorUptime(from, uint64(window))
+	if err != nil {
+		return nil, err
+	}
+	result := &ncTypes.ValidatorUptimeApi{
+		Address:   from.String(),
+		FromBlock: hexutil.Uint64(uptime.From),
+		ToBlock:   hexutil.Uint64(uptime.To),
+		Blocks:    hexutil.Uint64(uptime.Blocks),
+		Signed:    hexutil.Uint64(uptime.Signed),
+		Missed:    make([]hexutil.Uint64, len(uptime.Missed)),
+	}
+	if uptime.Blocks > 0 {
+		result.Uptime = float64(uptime.Signed) / float64(uptime.Blocks)
+	}
+	for i, number := range uptime.Missed {
+		result.Missed[i] = hexutil.Uint64(number)
+	}
+	return result, nil
+}
+
+// ChainStats sums up the blocks between from and to: the intervals between the
+// blocks, the rounds taken to commit them, their gas usage and transactions.
+// The sums of aligned chunks of blocks are stored once computed, making the
+// queries over large ranges cheap. A query computes a bounded number of them,
+// a large range on a cold node may take a few queries to cover.
+func (api *API) ChainStats(from, to hexutil.Uint64) (*ncTypes.ChainStatsApi, error) {
+	stats, first, last, err := api.neatcon.ChainStats(uint64(from), uint64(to))
+	if err != nil {
+		return nil, err
+	}
+	result := &ncTypes.ChainStatsApi{
+		FromBlock:        hexutil.Uint64(first),
+		ToBlock:          hexutil.Uint64(last),
+		Blocks:           hexutil.Uint64(stats.Blocks),
+		P50BlockInterval: hexutil.Uint64(stats.IntervalPercentile(50)),
+		P90BlockInterval: hexutil.Uint64(stats.IntervalPercentile(90)),
+		P99BlockInterval: hexutil.Uint64(stats.IntervalPercentile(99)),
+		MaxBlockInterval: hexutil.Uint64(stats.MaxInterval),
+		GasUsed:          hexutil.Uint64(stats.GasUsed),
+		GasLimit:         hexutil.Uint64(stats.GasLimit),
+		Txs:              hexutil.Uint64(stats.Txs),
+	}
+	if stats.Intervals > 0 {
+		result.AvgBlockInterval = float64(stats.IntervalSum) / float64(stats.Intervals)
+	}
+	if stats.IntervalSum > 0 {
+		result.TxsPerSecond = float64(stats.Txs) * 1000 / float64(stats.IntervalSum)
+	}
+	if stats.Blocks > 0 {
+		result.AvgRounds = float64(stats.Rounds) / float64(stats.Blocks)
+		result.TxsPerBlock = float64(stats.Txs) / float64(stats.Blocks)
+	}
+	if stats.GasLimit > 0 {
+		result.GasUtilization = float64(stats.GasUsed) / float64(stats.GasLimit)
+	}
+	return result, nil
+}
+
+// ForkReports returns the conflicting commits detected by this node: blocks
+// received with a valid commit for a height at which another block was
+// committed. The first detection halts the chain.
+func (api *API) ForkReports() []*ForkReport {
+	return LoadForkReports(api.neatcon.core.epochDB)
+}
+
+// PrivateAdminAPI is the collection of NeatPoS APIs controlling the consensus of
+// the node.
+type PrivateAdminAPI struct {
+	neatcon *backend
+}
+
+// ResumeAfterFork clears the halt of the chain by conflicting commits, once the
+// operator resolved the fork. The headers are accepted again at once, the
+// consensus starts again with the node. The fork reports are kept.
+func (api *PrivateAdminAPI) ResumeAfterFork() (*ForkReport, error) {
+	report := api.neatcon.resumeAfterFork()
+	if report == nil {
+		return nil, errors.New("chain not halted")
+	}
+	api.neatcon.logger.Warn("Chain resumed after conflicting commits", "report", report.String())
+	return report, nil
+}
+
+// GetProposerSchedule returns the proposers of the first n rounds of the next
+// height, as the proposer selection will pick them, and the number of heights
+// each validator is expected to propose out of the next n. The proposer of a
+// height is drawn from the hash of the previous block, so the heights after
+// the next one can only be estimated from the voting powers.
+func (api *API) GetProposerSchedule(n hexutil.Uint64) (*ncTypes.ProposerScheduleApi, error) {
+	if n == 0 || n > maxProposerSchedule {
+		return nil, fmt.Errorf("n must be between 1 and %d", maxProposerSchedule)
+	}
+	head := api.chain.CurrentHeader()
+	height := head.Number.Uint64() + 1
+
+	ep := api.neatcon.core.consensusState.Epoch
+	if height > ep.EndBlock {
+		if ep = ep.GetNextEpoch(); ep == nil {
+			return nil, errors.New("validators of the next epoch not elected yet")
+		}
+	}
+	validators := ep.Validators.Validators
+	if len(validators) == 0 {
+		return nil, errors.New("empty validator set")
+	}
+
+	var parentHash common.Hash
+	var headCommit *cmn.BitArray
+	if head.Number.Uint64() > 0 && head.Number.Uint64() != ep.StartBlock {
+		parentHash = head.ParentHash
+	}
+	if ncExtra, err := ncTypes.ExtractNeatconExtra(head); err == nil && ncExtra.SeenCommit != nil {
+		headCommit = ncExtra.SeenCommit.BitArray
+	}
+
+	result := &ncTypes.ProposerScheduleApi{
+		Height:   hexutil.Uint64(height),
+		Epoch:    hexutil.Uint64(ep.Number),
+		Heights:  n,
+		Rounds:   make([]*ncTypes.ProposerRoundApi, 0, n),
+		Expected: make([]*ncTypes.ExpectedProposerApi, len(validators)),
+	}
+	for round, idx := range ncConsensus.ProposerSchedule(head.Hash(), parentHash, headCommit, validators, int(n)) {
+		result.Rounds = append(result.Rounds, &ncTypes.ProposerRoundApi{
+			Round:    round,
+			Proposer: common.BytesToAddress(validators[idx].Address).String(),
+		})
+	}
+
+	totalPower := new(big.Int)
+	for _, val := range validators {
+		totalPower.Add(totalPower, val.VotingPower)
+	}
+	total := new(big.Float).SetInt(totalPower)
+	for i, val := range validators {
+		share, _ := new(big.Float).Quo(new(big.Float).SetInt(val.VotingPower), total).Float64()
+		result.Expected[i] = &ncTypes.ExpectedProposerApi{
+			Address:        common.BytesToAddress(val.Address).String(),
+			Share:          share,
+			ExpectedBlocks: share * float64(n),
+		}
+	}
+	sort.Slice(result.Expected, func(i, j int) bool {
+		return result.Expected[i].Share > result.Expected[j].Share
+	})
+	return result, nil
+}
+
+// GetConsensusState retrieves the round the consensus of this node is in.
+func (api *API) GetConsensusState() (*ncTypes.ConsensusStateApi, error) {
+	cs := api.neatcon.core.consensusState
+	rs := cs.GetRoundState()
+
+	result := &ncTypes.ConsensusStateApi{
+		Height:      hexutil.Uint64(rs.Height),
+		Round:       rs.Round,
+		Step:        rs.Step.String(),
+		StartTime:   rs.StartTime,
+		EpochNumber: hexutil.Uint64(cs.Epoch.Number),
+		IsProposer:  rs.IsProposer(),
+	}
+	if proposer := rs.ProposerAddress(); proposer != nil {
+		result.Proposer = common.BytesToAddress(proposer).String()
+	}
+	return result, nil
+}
+
+// PropagationStats returns the block propagation latencies between this node
+// and its peers, measured on the proposer and on the validators.
+func (api *API) PropagationStats() *ncConsensus.PropagationStats {
+	return api.neatcon.core.consensusState.PropagationStats()
+}
+
+// Misbehavior returns the conflicting proposals, equivocating votes and invalid
+// signature aggregations detected at or above the given height.
+func (api *API) Misbehavior(from *hexutil.Uint64) []*ncConsensus.Misbehavior {
+	var height uint64
+	if from != nil {
+		height = uint64(*from)
+	}
+	return api.neatcon.core.consensusState.Misbehavior(height)
+}
+
 // GetEpochVote
 func (api *API) GetNextEpochVote() (*ncTypes.EpochVotesApiForConsole, error) {
 
@@ -101,6 +359,89 @@ func (api *API) GetNextEpochVote() (*ncTypes.EpochVotesApiForConsole, error) {
 	return nil, errors.New("next epoch has not been proposed")
 }
 
+// GetEpochVoteProgress reports the progress of the election of the validators
+// of the next epoch: the candidates which applied, the votes received, the
+// reveals still pending, and the blocks and estimated time left before the
+// validator set is elected at the end of the current epoch.
+func (api *API) GetEpochVoteProgress() (*ncTypes.EpochVoteProgressApi, error) {
+	ep := api.neatcon.core.consensusState.Epoch
+	state, err := api.chain.State()
+	if err != nil {
+		return nil, err
+	}
+	header := api.chain.CurrentHeader()
+	current := header.Number.Uint64()
+
+	progress := &ncTypes.EpochVoteProgressApi{
+		EpochNumber:    hexutil.Uint64(ep.Number + 1),
+		Status:         "not proposed",
+		StartBlock:     hexutil.Uint64(ep.EndBlock + 1),
+		CurrentBlock:   hexutil.Uint64(current),
+		PendingReveals: make([]string, 0),
+		Candidates:     make([]*ncTypes.CandidateVoteStatusApi, 0),
+	}
+	if current < ep.EndBlock {
+		progress.BlocksRemaining = hexutil.Uint64(ep.EndBlock - current)
+	}
+	// Estimate the time left from the pace of the blocks of the current epoch
+	if current > ep.StartBlock && !ep.StartTime.IsZero() {
+		elapsed := time.Unix(header.Time.Int64(), 0).Sub(ep.StartTime)
+		perBlock := elapsed / time.Duration(current-ep.StartBlock)
+		remaining := perBlock * time.Duration(progress.BlocksRemaining)
+		progress.TimeRemaining = remaining.Seconds()
+		progress.EstimatedTime = time.Unix(header.Time.Int64(), 0).Add(remaining)
+	}
+
+	votes := make(map[common.Address]*epoch.EpochValidatorVote)
+	if nextEp := ep.GetNextEpoch(); nextEp != nil {
+		switch nextEp.Status {
+		case epoch.EPOCH_PROPOSED_NOT_VOTED:
+			progress.Status = "proposed"
+		case epoch.EPOCH_VOTED_NOT_SAVED:
+			progress.Status = "voted"
+		case epoch.EPOCH_SAVED:
+			progress.Status = "saved"
+		}
+		if voteSet := nextEp.GetEpochValidatorVoteSet(); voteSet != nil {
+			for _, v := range voteSet.Votes {
+				votes[v.Address] = v
+			}
+		}
+	}
+
+	addresses := make(map[common.Address]struct{})
+	for addr := range state.GetCandidateSet() {
+		addresses[addr] = struct{}{}
+	}
+	for addr := range votes {
+		addresses[addr] = struct{}{}
+	}
+	for addr := range addresses {
+		candidate := &ncTypes.CandidateVoteStatusApi{
+			Address:   addr.String(),
+			Validator: ep.Validators.HasAddress(addr.Bytes()),
+			Banned:    state.GetBanned(addr),
+		}
+		if v, ok := votes[addr]; ok {
+			candidate.Voted = true
+			// A vote is revealed once its content is known
+			candidate.Revealed = v.Amount != nil && v.Salt != "" && v.PubKey != nil
+			progress.Votes++
+			if candidate.Revealed {
+				progress.Revealed++
+			} else {
+				progress.PendingReveals = append(progress.PendingReveals, candidate.Address)
+			}
+		}
+		progress.Candidates = append(progress.Candidates, candidate)
+	}
+	sort.Strings(progress.PendingReveals)
+	sort.Slice(progress.Candidates, func(i, j int) bool {
+		return progress.Candidates[i].Address < progress.Candidates[j].Address
+	})
+	return progress, nil
+}
+
 func (api *API) GetNextEpochValidators() ([]*ncTypes.EpochValidatorForConsole, error) {
 
 	//height := api.chain.CurrentBlock().NumberU64()
@@ -132,6 +473,7 @@ func (api *API) GetNextEpochValidators() ([]*ncTypes.EpochValidatorForConsole, e
 				PubKey:         pkstring,
 				Amount:         (*hexutil.Big)(val.VotingPower),
 				RemainingEpoch: hexutil.Uint64(val.RemainingEpoch),
+				Metadata:       validatorMetadata(state, common.BytesToAddress(val.Address)),
 			})
 		}
 
@@ -165,22 +507,135 @@ func (api *API) DecodeExtraData(extra string) (extraApi *ncTypes.NeatconExtraApi
 		EpochNumber:     hexutil.Uint64(ncExtra.EpochNumber),
 		SeenCommitHash:  hexutil.Encode(ncExtra.SeenCommitHash),
 		ValidatorsHash:  hexutil.Encode(ncExtra.ValidatorsHash),
-		SeenCommit: &ncTypes.CommitApi{
-			BlockID: ncTypes.BlockIDApi{
-				Hash: hexutil.Encode(ncExtra.SeenCommit.BlockID.Hash),
-				PartsHeader: ncTypes.PartSetHeaderApi{
-					Total: hexutil.Uint64(ncExtra.SeenCommit.BlockID.PartsHeader.Total),
-					Hash:  hexutil.Encode(ncExtra.SeenCommit.BlockID.PartsHeader.Hash),
-				},
+		SeenCommit:      newCommitApi(ncExtra.SeenCommit),
+		EpochBytes:      ncExtra.EpochBytes,
+	}
+	return extraApi, nil
+}
+
+func newCommitApi(commit *ncTypes.Commit) *ncTypes.CommitApi {
+	return &ncTypes.CommitApi{
+		BlockID: ncTypes.BlockIDApi{
+			Hash: hexutil.Encode(commit.BlockID.Hash),
+			PartsHeader: ncTypes.PartSetHeaderApi{
+				Total: hexutil.Uint64(commit.BlockID.PartsHeader.Total),
+				Hash:  hexutil.Encode(commit.BlockID.PartsHeader.Hash),
 			},
-			Height:   hexutil.Uint64(ncExtra.SeenCommit.Height),
-			Round:    ncExtra.SeenCommit.Round,
-			SignAggr: ncExtra.SeenCommit.SignAggr,
-			BitArray: ncExtra.SeenCommit.BitArray,
 		},
-		EpochBytes: ncExtra.EpochBytes,
+		Height:   hexutil.Uint64(commit.Height),
+		Round:    commit.Round,
+		SignAggr: commit.SignAggr,
+		BitArray: commit.BitArray,
 	}
-	return extraApi, nil
+}
+
+// GetCommit returns the commit of the block at the given height: the block
+// and round the validators signed, the bit array of the signers in the
+// validator set of the epoch of the block, their addresses, and the aggregate
+// of their signatures.
+func (api *API) GetCommit(number hexutil.Uint64) (*ncTypes.CommitApi, error) {
+	header := api.chain.GetHeaderByNumber(uint64(number))
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	ncExtra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	if ncExtra.SeenCommit == nil || ncExtra.SeenCommit.BitArray == nil {
+		return nil, fmt.Errorf("block %d has no commit", number)
+	}
+	ep, err := api.loadEpoch(ncExtra.EpochNumber)
+	if err != nil {
+		return nil, err
+	}
+	commit := newCommitApi(ncExtra.SeenCommit)
+	aggr, err := ep.Validators.GetAggrPubKeyAndAddress(ncExtra.SeenCommit.BitArray)
+	if err != nil {
+		return nil, err
+	}
+	commit.Signers = make([]string, 0, ncExtra.SeenCommit.NumCommits())
+	for i, addr := range aggr.Addresses {
+		if ncExtra.SeenCommit.BitArray.GetIndex(uint64(i)) {
+			commit.Signers = append(commit.Signers, addr.String())
+		}
+	}
+	return commit, nil
+}
+
+// loadEpoch returns the epoch with the given number, which must not be after
+// the current one.
+func (api *API) loadEpoch(number uint64) (*epoch.Epoch, error) {
+	curEpoch := api.neatcon.core.consensusState.Epoch
+	if number > curEpoch.Number {
+		return nil, errors.New("epoch number out of range")
+	}
+	if number == curEpoch.Number {
+		return curEpoch, nil
+	}
+	if ep := epoch.LoadOneEpoch(curEpoch.GetDB(), number, nil); ep != nil {
+		return ep, nil
+	}
+	return nil, fmt.Errorf("epoch %d not found", number)
+}
+
+// GetValidatorSetProof returns the validator set of the epoch with a merkle
+// proof for each validator against the validators hash in the extra data of
+// the first block of the epoch. Given the header of the block, the signatures
+// of the epoch can be verified without the state of the chain: the encoding
+// of a validator hashes to its leaf, the proof leads to the validators hash,
+// which is part of the hashed header.
+func (api *API) GetValidatorSetProof(num hexutil.Uint64) (*ncTypes.ValidatorSetProofApi, error) {
+	ep, err := api.loadEpoch(uint64(num))
+	if err != nil {
+		return nil, err
+	}
+	number := ep.StartBlock
+	if number == 0 {
+		// The genesis block carries no consensus data
+		number = 1
+	}
+	header := api.chain.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	ncExtra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	if ncExtra.EpochNumber != ep.Number {
+		return nil, fmt.Errorf("block %d is in epoch %d, not %d", number, ncExtra.EpochNumber, ep.Number)
+	}
+	root, proofs := ep.Validators.Proofs()
+	if !bytes.Equal(root, ncExtra.ValidatorsHash) {
+		return nil, fmt.Errorf("validator set of epoch %d does not match the validators hash of block %d", ep.Number, number)
+	}
+
+	result := &ncTypes.ValidatorSetProofApi{
+		Epoch:          hexutil.Uint64(ep.Number),
+		Block:          hexutil.Uint64(number),
+		BlockHash:      header.Hash(),
+		ValidatorsHash: hexutil.Encode(root),
+		Validators:     make([]*ncTypes.ValidatorProofApi, len(ep.Validators.Validators)),
+	}
+	for i, val := range ep.Validators.Validators {
+		proof := make([]string, len(proofs[i].Aunts))
+		for j, aunt := range proofs[i].Aunts {
+			proof[j] = hexutil.Encode(aunt)
+		}
+		result.Validators[i] = &ncTypes.ValidatorProofApi{
+			EpochValidatorForConsole: ncTypes.EpochValidatorForConsole{
+				Address:        common.BytesToAddress(val.Address).String(),
+				PubKey:         val.PubKey.KeyString(),
+				Amount:         (*hexutil.Big)(val.VotingPower),
+				RemainingEpoch: hexutil.Uint64(val.RemainingEpoch),
+			},
+			Index:   hexutil.Uint64(i),
+			Encoded: hexutil.Encode(wire.BinaryBytes(val)),
+			Proof:   proof,
+		}
+	}
+	return result, nil
 }
 
 // get consensus publickey of the block
@@ -242,11 +697,36 @@ func (api *API) GetValidatorStatus(from common.Address) (*ncTypes.ValidatorStatu
 	}
 	status := &ncTypes.ValidatorStatus{
 		IsBanned: state.GetOrNewStateObject(from).IsBanned(),
+		Metadata: validatorMetadata(state, from),
 	}
 
 	return status, nil
 }
 
+// validatorMetadata returns the metadata the validator published, or nil if
+// it did not publish any.
+func validatorMetadata(statedb *state.StateDB, addr common.Address) *ncTypes.ValidatorMetadataApi {
+	metadata := statedb.GetValidatorMetadata(addr)
+	if metadata == nil {
+		return nil
+	}
+	return &ncTypes.ValidatorMetadataApi{
+		Moniker:         metadata.Moniker,
+		Website:         metadata.Website,
+		Identity:        metadata.Identity,
+		Details:         metadata.Details,
+		SecurityContact: metadata.SecurityContact,
+	}
+}
+
+// GetLastSignedBlock returns the highest block after the given number committed with
+// a signature of the validator, or 0 if the validator has not signed any of them.
+// It fails if the given number is more than 100000 blocks behind the head.
+func (api *API) GetLastSignedBlock(from common.Address, after hexutil.Uint64) (hexutil.Uint64, error) {
+	number, err := api.neatcon.LastSignedBlock(from, uint64(after))
+	return hexutil.Uint64(number), err
+}
+
 func (api *API) GetCandidateList() (*ncTypes.CandidateApi, error) {
 	state, err := api.chain.State()
 
@@ -288,3 +768,60 @@ func (api *API) GetBannedList() (*ncTypes.BannedApi, error) {
 
 	return bannedAddresses, nil
 }
+
+// UpgradeStatus reports, for the upgrades scheduled on chain and the ones this
+// node supports, which validators of the current epoch signaled their node is
+// ready for it.
+func (api *API) UpgradeStatus() ([]*ncTypes.UpgradeStatusApi, error) {
+	state, err := api.chain.State()
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	upgrades := state.GetUpgrades()
+	for name := range params.SupportedUpgrades {
+		if _, ok := upgrades[name]; !ok {
+			upgrades[name] = 0
+		}
+	}
+	names := make([]string, 0, len(upgrades))
+	for name := range upgrades {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	validators := api.neatcon.core.consensusState.Epoch.Validators
+	height := api.chain.CurrentHeader().Number.Uint64()
+
+	statuses := make([]*ncTypes.UpgradeStatusApi, 0, len(names))
+	for _, name := range names {
+		ready := make(map[common.Address]bool)
+		for _, addr := range state.GetUpgradeSignals(name) {
+			ready[addr] = true
+		}
+
+		status := &ncTypes.UpgradeStatusApi{
+			Name:             name,
+			ActivationHeight: hexutil.Uint64(upgrades[name]),
+			Activated:        upgrades[name] != 0 && height >= upgrades[name],
+			Supported:        params.SupportedUpgrades[name],
+			Ready:            make([]string, 0),
+			NotReady:         make([]string, 0),
+		}
+		readyPower, totalPower := new(big.Int), new(big.Int)
+		for _, val := range validators.Validators {
+			addr := common.BytesToAddress(val.Address)
+			totalPower.Add(totalPower, val.VotingPower)
+			if ready[addr] {
+				readyPower.Add(readyPower, val.VotingPower)
+				status.Ready = append(status.Ready, addr.String())
+			} else {
+				status.NotReady = append(status.NotReady, addr.String())
+			}
+		}
+		status.ReadyVotingPower = (*hexutil.Big)(readyPower)
+		status.TotalVotingPower = (*hexutil.Big)(totalPower)
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
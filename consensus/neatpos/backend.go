@@ -43,6 +43,10 @@ func New(chainConfig *params.ChainConfig, cliCtx *cli.Context,
 		//knownMessages:    knownMessages,
 	}
 	backend.core = MakeNeatconNode(backend, config, chainConfig, cch)
+	if backend.fork = loadForkHalt(backend.core.epochDB); backend.fork != nil {
+		backend.logger.Error("Chain halted by conflicting commits, resume with admin_resumeAfterFork once resolved", "report", backend.fork.String())
+	}
+	backend.feedConsensusEvents()
 	return backend
 }
 
@@ -77,6 +81,11 @@ type backend struct {
 	// event subscription for ChainHeadEvent event
 	broadcaster consensus.Broadcaster
 
+	consensusFeed event.Feed // Round steps and commits of the consensus
+
+	fork   *ForkReport // First conflicting commit detected, halting the chain
+	forkMu sync.Mutex
+
 	//recentMessages *lru.ARCCache // the cache of peer's messages
 	//knownMessages  *lru.ARCCache // the cache of self messages
 }
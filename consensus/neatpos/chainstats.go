@@ -0,0 +1,147 @@
+package neatpos
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// statsChunkSize is the number of blocks summed up by each stored chunk of the
+// chain stats. The chunks are computed once, when first covered by a query, so
+// that only the blocks at the ends of a range are walked through.
+const statsChunkSize = 1024
+
+const statsChunkKey = "ChainStats:%v"
+
+// maxStatsWalkedBlocks caps the blocks walked through by a query, the chunks
+// not stored yet included. The chunks summed up before the cap is hit are
+// stored, so a large range on a cold node is covered by repeating the query.
+const maxStatsWalkedBlocks = 16 * statsChunkSize
+
+var errStatsWalkLimit = fmt.Errorf("more than %d blocks not summed up yet, retry or query a smaller range", maxStatsWalkedBlocks)
+
+// statsWalker sums up blocks, remembering the time of the last one to measure
+// the interval of the next one from.
+type statsWalker struct {
+	sb         *backend
+	prevNumber uint64
+	prevTime   time.Time
+	walked     uint64
+}
+
+func (w *statsWalker) blockTime(number uint64) (time.Time, error) {
+	if number == w.prevNumber && !w.prevTime.IsZero() {
+		return w.prevTime, nil
+	}
+	header := w.sb.chain.GetHeaderByNumber(number)
+	if header == nil {
+		return time.Time{}, fmt.Errorf("block %d not found", number)
+	}
+	ncExtra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ncExtra.Time, nil
+}
+
+// addBlock adds the block to the stats. The genesis block, which was not
+// committed by the validators, is skipped.
+func (w *statsWalker) addBlock(stats *ncTypes.ChainStats, number uint64) error {
+	if number == 0 {
+		return nil
+	}
+	if w.walked++; w.walked > maxStatsWalkedBlocks {
+		return errStatsWalkLimit
+	}
+	block := w.sb.chain.GetBlockByNumber(number)
+	if block == nil {
+		return fmt.Errorf("block %d not found", number)
+	}
+	ncExtra, err := ncTypes.ExtractNeatconExtra(block.Header())
+	if err != nil {
+		return err
+	}
+
+	interval := int64(-1)
+	if number > 1 {
+		parentTime, err := w.blockTime(number - 1)
+		if err != nil {
+			return err
+		}
+		if interval = int64(ncExtra.Time.Sub(parentTime) / time.Millisecond); interval < 0 {
+			interval = 0
+		}
+	}
+	rounds := uint64(1)
+	if ncExtra.SeenCommit != nil {
+		rounds += uint64(ncExtra.SeenCommit.Round)
+	}
+	stats.AddBlock(interval, rounds, block.GasUsed(), block.GasLimit(), uint64(len(block.Transactions())))
+
+	w.prevNumber, w.prevTime = number, ncExtra.Time
+	return nil
+}
+
+// statsChunk returns the stats of the index-th chunk of blocks, which must be
+// committed already.
+func (w *statsWalker) statsChunk(index uint64) (*ncTypes.ChainStats, error) {
+	db := w.sb.core.consensusState.Epoch.GetDB()
+	key := []byte(fmt.Sprintf(statsChunkKey, index))
+	if blob := db.Get(key); len(blob) > 0 {
+		stats := new(ncTypes.ChainStats)
+		if err := rlp.DecodeBytes(blob, stats); err == nil {
+			return stats, nil
+		}
+	}
+
+	stats := ncTypes.NewChainStats()
+	for number := index * statsChunkSize; number < (index+1)*statsChunkSize; number++ {
+		if err := w.addBlock(stats, number); err != nil {
+			return nil, err
+		}
+	}
+	if blob, err := rlp.EncodeToBytes(stats); err == nil {
+		db.Set(key, blob)
+	}
+	return stats, nil
+}
+
+// ChainStats sums up the committed blocks between from and to, inclusive. The
+// range is capped by the head of the chain. It fails with errStatsWalkLimit if
+// too many of the blocks are not summed up in stored chunks yet.
+func (sb *backend) ChainStats(from, to uint64) (*ncTypes.ChainStats, uint64, uint64, error) {
+	if sb.chain == nil {
+		return nil, 0, 0, errors.New("consensus engine not started")
+	}
+	if head := sb.chain.CurrentHeader().Number.Uint64(); to > head {
+		to = head
+	}
+	if from == 0 {
+		from = 1
+	}
+	if from > to {
+		return nil, 0, 0, fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+
+	stats := ncTypes.NewChainStats()
+	walker := &statsWalker{sb: sb}
+	for number := from; number <= to; {
+		if number%statsChunkSize == 0 && number+statsChunkSize-1 <= to {
+			chunk, err := walker.statsChunk(number / statsChunkSize)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			stats.Merge(chunk)
+			number += statsChunkSize
+			continue
+		}
+		if err := walker.addBlock(stats, number); err != nil {
+			return nil, 0, 0, err
+		}
+		number++
+	}
+	return stats, from, to, nil
+}
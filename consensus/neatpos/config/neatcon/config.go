@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"unicode"
 
 	. "github.com/neatlib/common-go"
 	cfg "github.com/neatlib/config-go"
@@ -95,19 +97,16 @@ func GetConfig(rootDir, chainId string) cfg.Config {
 	mapConfig.SetDefault("block_part_size", 65536) // part size 64K
 	mapConfig.SetDefault("disable_data_hash", false)
 
-	// all timeouts are in ms
-	mapConfig.SetDefault("timeout_handshake", 10000)
-	mapConfig.SetDefault("timeout_wait_for_miner_block", 2000)
-	mapConfig.SetDefault("timeout_propose", 1500)
-	mapConfig.SetDefault("timeout_propose_delta", 500)
-	mapConfig.SetDefault("timeout_prevote", 2000)
-	mapConfig.SetDefault("timeout_prevote_delta", 500)
-	mapConfig.SetDefault("timeout_precommit", 2000)
-	mapConfig.SetDefault("timeout_precommit_delta", 500)
-	mapConfig.SetDefault("timeout_commit", 1000)
-
-	// make progress asap (no `timeout_commit`) on full precommit votes
-	mapConfig.SetDefault("skip_timeout_commit", false)
+	// Consensus timeouts, the values set in the node configuration file win
+	// over config.toml
+	consensus, fields := consensusConfig(chainId)
+	consensus.apply(func(key string, value interface{}) {
+		if fields[key] {
+			mapConfig.Set(key, value)
+		} else {
+			mapConfig.SetDefault(key, value)
+		}
+	})
 	mapConfig.SetDefault("mempool_recheck", true)
 	mapConfig.SetDefault("mempool_recheck_empty", true)
 	mapConfig.SetDefault("mempool_broadcast", true)
@@ -122,6 +121,132 @@ func GetConfig(rootDir, chainId string) cfg.Config {
 	return mapConfig
 }
 
+// ConsensusConfig holds the consensus settings which can be given in the
+// configuration file of the node. All timeouts are in ms.
+type ConsensusConfig struct {
+	TimeoutHandshake         int
+	TimeoutWaitForMinerBlock int
+	TimeoutPropose           int
+	TimeoutProposeDelta      int
+	TimeoutPrevote           int
+	TimeoutPrevoteDelta      int
+	TimeoutPrecommit         int
+	TimeoutPrecommitDelta    int
+	TimeoutCommit            int
+
+	// make progress asap (no `timeout_commit`) on full precommit votes
+	SkipTimeoutCommit bool
+
+	// Adaptive timeouts shrink the propose, prevote and precommit timeouts
+	// toward the observed step latencies, bounded by TimeoutMin and the
+	// timeouts above, and multiply them by TimeoutBackoff with every round
+	// of a height, up to TimeoutMax.
+	AdaptiveTimeouts bool
+	TimeoutMin       int
+	TimeoutMax       int
+	TimeoutBackoff   float64
+
+	// Skip empty blocks while the transaction pool is empty, producing one at
+	// most every HeartbeatInterval.
+	SkipEmptyBlocks   bool
+	HeartbeatInterval int
+
+	// Maximum difference between the consensus time of a proposed block and
+	// the local time, 0 for any.
+	MaxTimeDrift int
+}
+
+// DefaultConsensusConfig contains the default consensus settings.
+var DefaultConsensusConfig = ConsensusConfig{
+	TimeoutHandshake:         10000,
+	TimeoutWaitForMinerBlock: 2000,
+	TimeoutPropose:           1500,
+	TimeoutProposeDelta:      500,
+	TimeoutPrevote:           2000,
+	TimeoutPrevoteDelta:      500,
+	TimeoutPrecommit:         2000,
+	TimeoutPrecommitDelta:    500,
+	TimeoutCommit:            1000,
+	SkipTimeoutCommit:        false,
+	AdaptiveTimeouts:         false,
+	TimeoutMin:               500,
+	TimeoutMax:               30000,
+	TimeoutBackoff:           1.5,
+	SkipEmptyBlocks:          false,
+	HeartbeatInterval:        60000,
+	MaxTimeDrift:             15000,
+}
+
+func (c *ConsensusConfig) apply(set func(key string, value interface{})) {
+	set("timeout_handshake", c.TimeoutHandshake)
+	set("timeout_wait_for_miner_block", c.TimeoutWaitForMinerBlock)
+	set("timeout_propose", c.TimeoutPropose)
+	set("timeout_propose_delta", c.TimeoutProposeDelta)
+	set("timeout_prevote", c.TimeoutPrevote)
+	set("timeout_prevote_delta", c.TimeoutPrevoteDelta)
+	set("timeout_precommit", c.TimeoutPrecommit)
+	set("timeout_precommit_delta", c.TimeoutPrecommitDelta)
+	set("timeout_commit", c.TimeoutCommit)
+	set("skip_timeout_commit", c.SkipTimeoutCommit)
+	set("adaptive_timeouts", c.AdaptiveTimeouts)
+	set("timeout_min", c.TimeoutMin)
+	set("timeout_max", c.TimeoutMax)
+	set("timeout_backoff", c.TimeoutBackoff)
+	set("skip_empty_blocks", c.SkipEmptyBlocks)
+	set("heartbeat_interval", c.HeartbeatInterval)
+	set("max_time_drift", c.MaxTimeDrift)
+}
+
+// configKey returns the config.toml key of a field of ConsensusConfig, e.g.
+// timeout_propose for TimeoutPropose.
+func configKey(field string) string {
+	var key []rune
+	for i, r := range field {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				key = append(key, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		key = append(key, r)
+	}
+	return string(key)
+}
+
+type chainConsensusConfig struct {
+	config ConsensusConfig
+	keys   map[string]bool // Keys of the settings given
+}
+
+var (
+	consensusConfigsMu sync.Mutex
+	consensusConfigs   = make(map[string]chainConsensusConfig)
+)
+
+// SetConsensusConfig sets the consensus settings of a chain given in the node
+// configuration file, fields naming the ones set there. Those override the
+// ones of config.toml, the others are left to it.
+func SetConsensusConfig(chainId string, c ConsensusConfig, fields []string) {
+	keys := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		keys[configKey(field)] = true
+	}
+	consensusConfigsMu.Lock()
+	defer consensusConfigsMu.Unlock()
+	consensusConfigs[chainId] = chainConsensusConfig{c, keys}
+}
+
+// consensusConfig returns the consensus settings of a chain, and the keys of
+// the ones given in the node configuration file.
+func consensusConfig(chainId string) (ConsensusConfig, map[string]bool) {
+	consensusConfigsMu.Lock()
+	defer consensusConfigsMu.Unlock()
+	if c, ok := consensusConfigs[chainId]; ok {
+		return c.config, c.keys
+	}
+	return DefaultConsensusConfig, nil
+}
+
 var defaultConfigTmpl = `# This is a TOML config file.
 # For more information, see https://github.com/toml-lang/toml
 #proxy_app = "tcp://127.0.0.1:46658"
@@ -0,0 +1,46 @@
+package neatcon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConsensusConfigKeys(t *testing.T) {
+	root, err := ioutil.TempDir("", "neatcon-config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	config := defaultConfig("anonymous") + "timeout_propose = 5000\ntimeout_commit = 3000\n"
+	if err := ioutil.WriteFile(filepath.Join(root, defaultConfigFileName), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the timeout commit is set in the node configuration file, the other
+	// values are the defaults
+	consensus := DefaultConsensusConfig
+	consensus.TimeoutCommit = 4000
+	SetConsensusConfig("test", consensus, []string{"TimeoutCommit"})
+
+	mapConfig := GetConfig(root, "test")
+	tests := map[string]int{
+		"timeout_propose":              5000, // From config.toml
+		"timeout_commit":               4000, // From the node configuration file
+		"timeout_prevote":              DefaultConsensusConfig.TimeoutPrevote,
+		"timeout_wait_for_miner_block": DefaultConsensusConfig.TimeoutWaitForMinerBlock,
+	}
+	for key, want := range tests {
+		if have := mapConfig.GetInt(key); have != want {
+			t.Errorf("%s mismatch: have %d, want %d", key, have, want)
+		}
+	}
+
+	// Without a node configuration file, config.toml wins over the defaults
+	mapConfig = GetConfig(root, "other")
+	if have := mapConfig.GetInt("timeout_commit"); have != 3000 {
+		t.Errorf("timeout_commit mismatch: have %d, want 3000", have)
+	}
+}
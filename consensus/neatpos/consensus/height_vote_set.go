@@ -39,6 +39,7 @@ type HeightVoteSet struct {
 	round             int                  // max tracked round
 	roundVoteSets     map[int]RoundVoteSet // keys: [0...round]
 	peerCatchupRounds map[string][]int     // keys: peer.Key; values: at most 2 rounds
+	signBytesCache    *types.SignBytesCache
 
 	logger log.Logger
 }
@@ -65,6 +66,19 @@ func (hvs *HeightVoteSet) Reset(height uint64, valSet *types.ValidatorSet) {
 	hvs.round = 0
 }
 
+// SetSignBytesCache makes the vote sets verify the votes against the sign
+// bytes of the cache.
+func (hvs *HeightVoteSet) SetSignBytesCache(cache *types.SignBytesCache) {
+	hvs.mtx.Lock()
+	defer hvs.mtx.Unlock()
+
+	hvs.signBytesCache = cache
+	for _, rvs := range hvs.roundVoteSets {
+		rvs.Prevotes.SetSignBytesCache(cache)
+		rvs.Precommits.SetSignBytesCache(cache)
+	}
+}
+
 func (hvs *HeightVoteSet) Height() uint64 {
 	hvs.mtx.Lock()
 	defer hvs.mtx.Unlock()
@@ -118,6 +132,10 @@ func (hvs *HeightVoteSet) addRound(round int) {
 	hvs.logger.Debug("addRound(round)", "round", round)
 	prevotes := types.NewVoteSet(hvs.chainID, hvs.height, round, types.VoteTypePrevote, hvs.valSet)
 	precommits := types.NewVoteSet(hvs.chainID, hvs.height, round, types.VoteTypePrecommit, hvs.valSet)
+	if hvs.signBytesCache != nil {
+		prevotes.SetSignBytesCache(hvs.signBytesCache)
+		precommits.SetSignBytesCache(hvs.signBytesCache)
+	}
 	hvs.roundVoteSets[round] = RoundVoteSet{
 		Prevotes:   prevotes,
 		Precommits: precommits,
@@ -127,6 +145,16 @@ func (hvs *HeightVoteSet) addRound(round int) {
 // Duplicate votes return added=false, err=nil.
 // By convention, peerKey is "" if origin is self.
 func (hvs *HeightVoteSet) AddVote(vote *types.Vote, peerKey string) (added bool, err error) {
+	return hvs.addVote(vote, peerKey, false)
+}
+
+// AddPreverifiedVote is like AddVote for a vote whose signature was already
+// verified.
+func (hvs *HeightVoteSet) AddPreverifiedVote(vote *types.Vote, peerKey string) (added bool, err error) {
+	return hvs.addVote(vote, peerKey, true)
+}
+
+func (hvs *HeightVoteSet) addVote(vote *types.Vote, peerKey string, verified bool) (added bool, err error) {
 	hvs.mtx.Lock()
 	defer hvs.mtx.Unlock()
 	if !types.IsVoteTypeValid(vote.Type) {
@@ -146,7 +174,11 @@ func (hvs *HeightVoteSet) AddVote(vote *types.Vote, peerKey string) (added bool,
 			return false, errors.New("Deal with peer giving votes from unwanted rounds")
 		}
 	}
-	added, err = voteSet.AddVote(vote)
+	if verified {
+		added, err = voteSet.AddPreverifiedVote(vote)
+	} else {
+		added, err = voteSet.AddVote(vote)
+	}
 	return
 }
 
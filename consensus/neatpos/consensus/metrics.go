@@ -0,0 +1,156 @@
+package consensus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/metrics"
+)
+
+// consensusMetrics instruments the consensus state machine. The metric names
+// are prefixed with the chain id, as a node runs the consensus of the main
+// chain and of its side chains in a single process.
+type consensusMetrics struct {
+	prefix string
+
+	stepStart time.Time                // Time the current round step was entered
+	voteStart map[byte]time.Time       // Time the proposer started collecting votes of a type
+	timers    map[string]metrics.Timer // Lazily registered step and vote timers
+	mu        sync.Mutex
+
+	roundsPerHeight metrics.Histogram // Number of rounds needed to commit a block
+	missedProposals metrics.Counter   // Rounds left without a complete proposal
+}
+
+func newConsensusMetrics(chainId string) *consensusMetrics {
+	prefix := "consensus/" + chainId + "/"
+	return &consensusMetrics{
+		prefix:          prefix,
+		stepStart:       time.Now(),
+		voteStart:       make(map[byte]time.Time),
+		timers:          make(map[string]metrics.Timer),
+		roundsPerHeight: metrics.GetOrRegisterHistogram(prefix+"rounds", nil, metrics.NewExpDecaySample(1028, 0.015)),
+		missedProposals: metrics.GetOrRegisterCounter(prefix+"proposals/missed", nil),
+	}
+}
+
+// timer returns the timer registered under name, registering it on first use.
+func (m *consensusMetrics) timer(name string) metrics.Timer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.timers[name]
+	if !ok {
+		t = metrics.GetOrRegisterTimer(m.prefix+name, nil)
+		m.timers[name] = t
+	}
+	return t
+}
+
+// stepChanged records the time spent in the step being left.
+func (m *consensusMetrics) stepChanged(from RoundStepType) {
+	if m == nil || !metrics.Enabled {
+		return
+	}
+	// The zero step is the state before the first height is set up
+	if from != 0 {
+		m.timer("step/" + stepName(from)).UpdateSince(m.stepStart)
+	}
+	m.stepStart = time.Now()
+}
+
+// newRound forgets the vote collection times of the previous round.
+func (m *consensusMetrics) newRound() {
+	if m == nil {
+		return
+	}
+	m.voteStart = make(map[byte]time.Time)
+}
+
+// timeout counts a timeout of the step.
+func (m *consensusMetrics) timeout(step RoundStepType) {
+	if m == nil || !metrics.Enabled {
+		return
+	}
+	metrics.GetOrRegisterCounter(m.prefix+"timeouts/"+stepName(step), nil).Inc(1)
+}
+
+// proposalMissed counts a round which was left without a complete proposal.
+func (m *consensusMetrics) proposalMissed() {
+	if m == nil {
+		return
+	}
+	m.missedProposals.Inc(1)
+}
+
+// votesRequested marks the time the proposer started to wait for votes of a
+// type, the proposal for prevotes and the prevote aggregation for precommits.
+func (m *consensusMetrics) votesRequested(voteType byte) {
+	if m == nil {
+		return
+	}
+	m.voteStart[voteType] = time.Now()
+}
+
+// voteArrived records the latency of a vote of a validator. Votes are only
+// sent to the proposer, so the latencies are measured on proposing nodes.
+func (m *consensusMetrics) voteArrived(vote *types.Vote) {
+	if m == nil || !metrics.Enabled {
+		return
+	}
+	start, ok := m.voteStart[vote.Type]
+	if !ok {
+		return
+	}
+	name := voteTypeName(vote.Type)
+	latency := time.Since(start)
+	m.timer("votes/" + name).Update(latency)
+	m.timer(fmt.Sprintf("votes/%s/%x", name, common.BytesToAddress(vote.ValidatorAddress))).Update(latency)
+}
+
+// committed records the number of rounds a height took to commit.
+func (m *consensusMetrics) committed(round int) {
+	if m == nil {
+		return
+	}
+	m.roundsPerHeight.Update(int64(round + 1))
+}
+
+func stepName(step RoundStepType) string {
+	switch step {
+	case RoundStepNewHeight:
+		return "newheight"
+	case RoundStepNewRound:
+		return "newround"
+	case RoundStepWaitForMinerBlock:
+		return "waitforminerblock"
+	case RoundStepPropose:
+		return "propose"
+	case RoundStepPrevote:
+		return "prevote"
+	case RoundStepPrevoteWait:
+		return "prevotewait"
+	case RoundStepPrecommit:
+		return "precommit"
+	case RoundStepPrecommitWait:
+		return "precommitwait"
+	case RoundStepCommit:
+		return "commit"
+	default:
+		return "unknown"
+	}
+}
+
+func voteTypeName(voteType byte) string {
+	switch voteType {
+	case types.VoteTypePrevote:
+		return "prevote"
+	case types.VoteTypePrecommit:
+		return "precommit"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,166 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/metrics"
+	dbm "github.com/neatlib/db-go"
+)
+
+// Kinds of misbehavior recognised by the detector.
+const (
+	MisbehaviorConflictingProposal = "conflicting_proposal" // Proposer signed two proposals in one round
+	MisbehaviorEquivocation        = "equivocation"         // Validator signed two votes of one type in one round
+	MisbehaviorInvalidAggregate    = "invalid_aggregate"    // Peer sent a signature aggregation failing verification
+)
+
+// Misbehavior is an incident of byzantine behavior, returned by
+// neat_misbehavior. It is recorded for later slashing, but has no effect yet.
+type Misbehavior struct {
+	Type      string          `json:"type"`
+	Height    uint64          `json:"height"`
+	Round     int             `json:"round"`
+	Validator common.Address  `json:"validator"`         // Validator that signed the offending messages, zero if unknown
+	PeerKey   string          `json:"peerKey,omitempty"` // Peer the offending message was received from
+	Evidence  json.RawMessage `json:"evidence"`          // The offending messages
+	Time      time.Time       `json:"time"`
+}
+
+// misbehaviorDetector records the incidents reported by the consensus state,
+// once each, into a persistent store.
+type misbehaviorDetector struct {
+	db      dbm.DB // nil keeps the incidents in memory only
+	logger  log.Logger
+	counter metrics.Counter
+
+	incidents map[string]*Misbehavior
+	mu        sync.Mutex
+}
+
+func newMisbehaviorDetector(chainId string, logger log.Logger) *misbehaviorDetector {
+	return &misbehaviorDetector{
+		logger:    logger,
+		counter:   metrics.GetOrRegisterCounter("consensus/"+chainId+"/misbehavior", nil),
+		incidents: make(map[string]*Misbehavior),
+	}
+}
+
+// setDB loads the incidents recorded in db and persists new incidents there.
+func (d *misbehaviorDetector) setDB(db dbm.DB) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	it := db.Iterator()
+	if release, ok := it.(interface{ Release() }); ok {
+		defer release.Release()
+	}
+	for it.Next() {
+		incident := new(Misbehavior)
+		if err := json.Unmarshal(it.Value(), incident); err != nil {
+			d.logger.Warn("Skipping corrupt misbehavior record", "key", common.Bytes2Hex(it.Key()), "err", err)
+			continue
+		}
+		d.incidents[string(it.Key())] = incident
+	}
+	d.db = db
+}
+
+// conflictingProposal reports two valid proposals of a proposer for one round.
+func (d *misbehaviorDetector) conflictingProposal(proposer []byte, a, b *types.Proposal, peerKey string) {
+	d.record(MisbehaviorConflictingProposal, a.Height, a.Round, proposer, peerKey, []*types.Proposal{a, b})
+}
+
+// equivocation reports two conflicting votes of a validator.
+func (d *misbehaviorDetector) equivocation(err *types.ErrVoteConflictingVotes, peerKey string) {
+	vote := err.VoteB
+	d.record(MisbehaviorEquivocation, vote.Height, int(vote.Round), vote.ValidatorAddress, peerKey, []*types.Vote{err.VoteA, err.VoteB})
+}
+
+// invalidAggregate reports a signature aggregation which failed verification.
+// The aggregator is not known for certain, as aggregations are relayed, so
+// only the peer is recorded.
+func (d *misbehaviorDetector) invalidAggregate(signAggr *types.SignAggr, peerKey string) {
+	d.record(MisbehaviorInvalidAggregate, signAggr.Height, signAggr.Round, nil, peerKey, signAggr)
+}
+
+func (d *misbehaviorDetector) record(typ string, height uint64, round int, validator []byte, peerKey string, evidence interface{}) {
+	incident := &Misbehavior{
+		Type:      typ,
+		Height:    height,
+		Round:     round,
+		Validator: common.BytesToAddress(validator),
+		PeerKey:   peerKey,
+		Time:      time.Now(),
+	}
+	key := incident.key()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.incidents[string(key)]; ok {
+		return
+	}
+	blob, err := json.Marshal(evidence)
+	if err != nil {
+		d.logger.Error("Failed to encode misbehavior evidence", "type", typ, "err", err)
+		return
+	}
+	incident.Evidence = blob
+	d.incidents[string(key)] = incident
+	d.counter.Inc(1)
+
+	d.logger.Warn("Detected validator misbehavior", "type", typ, "height", height, "round", round, "validator", incident.Validator, "peer", peerKey)
+
+	if d.db != nil {
+		blob, err := json.Marshal(incident)
+		if err != nil {
+			d.logger.Error("Failed to encode misbehavior", "type", typ, "err", err)
+			return
+		}
+		d.db.SetSync(key, blob)
+	}
+}
+
+// list returns the incidents at or above the given height, oldest first.
+func (d *misbehaviorDetector) list(from uint64) []*Misbehavior {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	incidents := make([]*Misbehavior, 0)
+	for _, incident := range d.incidents {
+		if incident.Height >= from {
+			incidents = append(incidents, incident)
+		}
+	}
+	sort.Slice(incidents, func(i, j int) bool {
+		if incidents[i].Height != incidents[j].Height {
+			return incidents[i].Height < incidents[j].Height
+		}
+		if incidents[i].Round != incidents[j].Round {
+			return incidents[i].Round < incidents[j].Round
+		}
+		return incidents[i].Time.Before(incidents[j].Time)
+	})
+	return incidents
+}
+
+// key identifies an incident, so that relayed copies of the offending messages
+// are recorded once: height (8 bytes) || round (8 bytes) || type || validator
+// or, if the validator is unknown, peer key.
+func (m *Misbehavior) key() []byte {
+	key := make([]byte, 16, 16+len(m.Type)+len(m.PeerKey)+len(m.Validator))
+	binary.BigEndian.PutUint64(key, m.Height)
+	binary.BigEndian.PutUint64(key[8:], uint64(m.Round))
+	key = append(key, m.Type...)
+	if m.Validator != (common.Address{}) {
+		return append(key, m.Validator[:]...)
+	}
+	return append(key, m.PeerKey...)
+}
@@ -0,0 +1,58 @@
+package consensus
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/log"
+	dbm "github.com/neatlib/db-go"
+)
+
+func TestMisbehaviorDetector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "misbehavior")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := dbm.NewDB("misbehavior", dbm.GoLevelDBBackendStr, dir)
+	d := newMisbehaviorDetector("test", log.New())
+	d.setDB(db)
+
+	validator := []byte{0x01, 0x02, 0x03}
+	conflict := &types.ErrVoteConflictingVotes{
+		VoteA: &types.Vote{ValidatorAddress: validator, Height: 10, Round: 1, Type: types.VoteTypePrevote},
+		VoteB: &types.Vote{ValidatorAddress: validator, Height: 10, Round: 1, Type: types.VoteTypePrevote},
+	}
+	// Relayed copies of the same equivocation are recorded once
+	d.equivocation(conflict, "peer1")
+	d.equivocation(conflict, "peer2")
+	d.invalidAggregate(&types.SignAggr{Height: 5, Round: 0}, "peer3")
+
+	incidents := d.list(0)
+	if len(incidents) != 2 {
+		t.Fatalf("incident count mismatch: have %d, want 2", len(incidents))
+	}
+	if incidents[0].Type != MisbehaviorInvalidAggregate || incidents[0].PeerKey != "peer3" {
+		t.Errorf("incident 0 mismatch: %+v", incidents[0])
+	}
+	if incidents[1].Type != MisbehaviorEquivocation || incidents[1].Height != 10 || incidents[1].PeerKey != "peer1" {
+		t.Errorf("incident 1 mismatch: %+v", incidents[1])
+	}
+	if len(d.list(6)) != 1 {
+		t.Errorf("height filter mismatch: have %d, want 1", len(d.list(6)))
+	}
+	db.Close()
+
+	// The incidents survive a restart
+	db = dbm.NewDB("misbehavior", dbm.GoLevelDBBackendStr, dir)
+	defer db.Close()
+
+	d = newMisbehaviorDetector("test", log.New())
+	d.setDB(db)
+	if len(d.list(0)) != 2 {
+		t.Errorf("reloaded incident count mismatch: have %d, want 2", len(d.list(0)))
+	}
+}
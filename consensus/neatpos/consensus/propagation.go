@@ -0,0 +1,216 @@
+package consensus
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/metrics"
+)
+
+// latencyWindowSize is the number of recent latencies kept per peer and
+// direction for the percentiles reported by neat_propagationStats.
+const latencyWindowSize = 256
+
+// Only the proposer gossips the proposal and its block parts, so block
+// propagation is measured on both ends of the proposer to validator path:
+//
+//   - outbound, on the proposer: from the creation of the proposal until a
+//     peer reports a step past propose, i.e. it has the complete block.
+//   - inbound, on the validators: from the arrival of the proposal until the
+//     part set received from the proposer is complete.
+//
+// Neither needs synchronised clocks, as both ends are timed by the same node.
+type propagationTracker struct {
+	prefix string
+
+	height   uint64
+	round    int
+	start    time.Time       // Proposal created (proposer) or received (validator)
+	proposer bool            // Whether this node created the proposal of the round
+	reported map[string]bool // Peers whose outbound latency was recorded in this round
+
+	inbound  map[string]*latencyWindow
+	outbound map[string]*latencyWindow
+	mu       sync.Mutex
+}
+
+func newPropagationTracker(chainId string) *propagationTracker {
+	return &propagationTracker{
+		prefix:   "consensus/" + chainId + "/propagation/",
+		reported: make(map[string]bool),
+		inbound:  make(map[string]*latencyWindow),
+		outbound: make(map[string]*latencyWindow),
+	}
+}
+
+// proposalCreated starts the outbound measurement of a round.
+func (p *propagationTracker) proposalCreated(height uint64, round int) {
+	p.reset(height, round, true)
+}
+
+// proposalReceived starts the inbound measurement of a round.
+func (p *propagationTracker) proposalReceived(height uint64, round int) {
+	p.reset(height, round, false)
+}
+
+func (p *propagationTracker) reset(height uint64, round int, proposer bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.height, p.round, p.proposer = height, round, proposer
+	p.start = time.Now()
+	p.reported = make(map[string]bool)
+}
+
+// blockReceived records the inbound latency of the complete proposal block,
+// the last part of which was received from the given peer.
+func (p *propagationTracker) blockReceived(height uint64, round int, peerKey string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proposer || p.start.IsZero() || p.height != height || p.round != round {
+		return
+	}
+	p.record("inbound", p.inbound, peerKey, time.Since(p.start))
+}
+
+// peerStepped records the outbound latency to a peer the first time it reports
+// a step past propose in the round of our proposal.
+func (p *propagationTracker) peerStepped(height uint64, round int, step RoundStepType, peerKey string) {
+	if step < RoundStepPrevote {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.proposer || p.height != height || p.round != round || p.reported[peerKey] {
+		return
+	}
+	p.reported[peerKey] = true
+	p.record("outbound", p.outbound, peerKey, time.Since(p.start))
+}
+
+func (p *propagationTracker) record(direction string, windows map[string]*latencyWindow, peerKey string, latency time.Duration) {
+	window, ok := windows[peerKey]
+	if !ok {
+		window = new(latencyWindow)
+		windows[peerKey] = window
+	}
+	window.add(latency)
+
+	if metrics.Enabled {
+		metrics.GetOrRegisterTimer(p.prefix+direction, nil).Update(latency)
+		metrics.GetOrRegisterTimer(p.prefix+direction+"/"+shortPeerKey(peerKey), nil).Update(latency)
+	}
+}
+
+// stats summarises the recorded latencies per peer.
+func (p *propagationTracker) stats() *PropagationStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := &PropagationStats{Peers: make(map[string]*PeerPropagation)}
+	peer := func(key string) *PeerPropagation {
+		if _, ok := stats.Peers[key]; !ok {
+			stats.Peers[key] = new(PeerPropagation)
+		}
+		return stats.Peers[key]
+	}
+	for key, window := range p.inbound {
+		peer(key).Inbound = window.summary()
+	}
+	for key, window := range p.outbound {
+		peer(key).Outbound = window.summary()
+	}
+	return stats
+}
+
+// PropagationStats is returned by neat_propagationStats.
+type PropagationStats struct {
+	Peers map[string]*PeerPropagation `json:"peers"`
+}
+
+// PeerPropagation holds the block propagation latencies on the path between
+// this node and a peer.
+type PeerPropagation struct {
+	Inbound  *LatencySummary `json:"inbound,omitempty"`  // Proposal received to complete block, the peer being the proposer
+	Outbound *LatencySummary `json:"outbound,omitempty"` // Proposal created to the peer having the complete block
+}
+
+// LatencySummary summarises recent latencies, in milliseconds.
+type LatencySummary struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// latencyWindow is a ring buffer of the most recent latencies.
+type latencyWindow struct {
+	samples [latencyWindowSize]time.Duration
+	next    int
+	count   int
+}
+
+func (w *latencyWindow) add(latency time.Duration) {
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % latencyWindowSize
+	if w.count < latencyWindowSize {
+		w.count++
+	}
+}
+
+// sorted returns the latencies in the window in ascending order.
+func (w *latencyWindow) sorted() []time.Duration {
+	sorted := make([]time.Duration, w.count)
+	copy(sorted, w.samples[:w.count])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// percentile returns the p-th percentile of the latencies in the window.
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	if w.count == 0 {
+		return 0
+	}
+	sorted := w.sorted()
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+func (w *latencyWindow) summary() *LatencySummary {
+	if w.count == 0 {
+		return nil
+	}
+	sorted := w.sorted()
+
+	var sum time.Duration
+	for _, latency := range sorted {
+		sum += latency
+	}
+	percentile := func(p float64) float64 {
+		return millis(sorted[int(p*float64(len(sorted)-1))])
+	}
+	return &LatencySummary{
+		Count: w.count,
+		Mean:  millis(sum / time.Duration(w.count)),
+		P50:   percentile(0.5),
+		P90:   percentile(0.9),
+		P99:   percentile(0.99),
+		Max:   millis(sorted[len(sorted)-1]),
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// shortPeerKey abbreviates a peer key for use in metric names.
+func shortPeerKey(key string) string {
+	if len(key) > 16 {
+		return key[:16]
+	}
+	return key
+}
@@ -0,0 +1,56 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyWindowSummary(t *testing.T) {
+	w := new(latencyWindow)
+	if w.summary() != nil {
+		t.Fatal("empty window has a summary")
+	}
+	// Overflow the window, only the last latencyWindowSize samples count
+	for i := 1; i <= latencyWindowSize+100; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	s := w.summary()
+	if s.Count != latencyWindowSize {
+		t.Errorf("count mismatch: have %d, want %d", s.Count, latencyWindowSize)
+	}
+	if s.Max != float64(latencyWindowSize+100) {
+		t.Errorf("max mismatch: have %v, want %v", s.Max, latencyWindowSize+100)
+	}
+	if s.P50 < 101 || s.P50 > s.P90 || s.P90 > s.P99 || s.P99 > s.Max {
+		t.Errorf("percentiles out of order: %+v", s)
+	}
+}
+
+func TestPropagationTracker(t *testing.T) {
+	p := newPropagationTracker("test")
+
+	// Outbound latencies are recorded once per peer, on the proposer only
+	p.proposalCreated(10, 0)
+	p.peerStepped(10, 0, RoundStepPropose, "a")
+	p.peerStepped(10, 0, RoundStepPrevote, "a")
+	p.peerStepped(10, 0, RoundStepPrecommit, "a")
+	p.peerStepped(10, 1, RoundStepPrevote, "b")
+	p.blockReceived(10, 0, "a")
+
+	// Inbound latencies are recorded for the current round only
+	p.proposalReceived(11, 0)
+	p.blockReceived(11, 0, "b")
+	p.blockReceived(11, 1, "b")
+	p.peerStepped(11, 0, RoundStepPrevote, "a")
+
+	stats := p.stats()
+	if len(stats.Peers) != 2 {
+		t.Fatalf("peer count mismatch: have %d, want 2", len(stats.Peers))
+	}
+	if a := stats.Peers["a"]; a.Outbound == nil || a.Outbound.Count != 1 || a.Inbound != nil {
+		t.Errorf("peer a mismatch: %+v", a)
+	}
+	if b := stats.Peers["b"]; b.Inbound == nil || b.Inbound.Count != 1 || b.Outbound != nil {
+		t.Errorf("peer b mismatch: %+v", b)
+	}
+}
@@ -0,0 +1,63 @@
+package consensus
+
+import (
+	"crypto/sha256"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	cmn "github.com/neatlib/common-go"
+)
+
+// vrfProposerIndex draws the proposer of the first round of a height from the
+// hash of the previous block, each validator weighted by its voting power.
+func vrfProposerIndex(headerHash common.Hash, validators []*types.Validator) (proposer int) {
+
+	idx := -1
+
+	var roundBytes = make([]byte, 8)
+	vrfBytes := append(roundBytes, headerHash[:]...)
+	hs := sha256.New()
+	hs.Write(vrfBytes)
+	hv := hs.Sum(nil)
+	hash := new(big.Int)
+	hash.SetBytes(hv[:])
+	n := big.NewInt(0)
+	for _, validator := range validators {
+		n.Add(n, validator.VotingPower)
+	}
+	n.Mod(hash, n)
+
+	for i, validator := range validators {
+		n.Sub(n, validator.VotingPower)
+		if n.Sign() == -1 {
+			idx = i
+			break
+		}
+	}
+
+	return idx
+}
+
+// ProposerSchedule returns the indexes of the proposers of the first rounds
+// of the height after head, as proposerByRound selects them: the proposer of
+// round 0 is drawn from the hash of head, and skipped if it was also drawn for
+// head and did not sign the commit of head. The following rounds go round
+// robin through the validators. parentHash is the hash of the parent of head,
+// or the zero hash when head starts the epoch.
+func ProposerSchedule(headHash, parentHash common.Hash, headCommit *cmn.BitArray, validators []*types.Validator, rounds int) []int {
+	if len(validators) == 0 || rounds <= 0 {
+		return nil
+	}
+	idx := vrfProposerIndex(headHash, validators)
+	if parentHash != (common.Hash{}) && idx == vrfProposerIndex(parentHash, validators) &&
+		headCommit != nil && !headCommit.GetIndex(uint64(idx)) {
+		idx = (idx + 1) % len(validators)
+	}
+
+	schedule := make([]int, rounds)
+	for round := range schedule {
+		schedule[round] = (idx + round) % len(validators)
+	}
+	return schedule
+}
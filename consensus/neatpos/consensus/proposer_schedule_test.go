@@ -0,0 +1,55 @@
+package consensus
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	cmn "github.com/neatlib/common-go"
+)
+
+func TestProposerSchedule(t *testing.T) {
+	validators := []*types.Validator{
+		{Address: common.Address{1}.Bytes(), VotingPower: big.NewInt(0)},
+		{Address: common.Address{2}.Bytes(), VotingPower: big.NewInt(100)},
+		{Address: common.Address{3}.Bytes(), VotingPower: big.NewInt(0)},
+	}
+	head, parent := common.Hash{0xaa}, common.Hash{0xbb}
+
+	// All the voting power on one validator makes it the proposer of round 0
+	if have := ProposerSchedule(head, parent, nil, validators, 4); !equalSchedule(have, []int{1, 2, 0, 1}) {
+		t.Errorf("schedule mismatch: have %v", have)
+	}
+
+	// Drawn for the head too, and missing from its commit: skipped
+	commit := cmn.NewBitArray(3)
+	commit.SetIndex(0, true)
+	if have := ProposerSchedule(head, parent, commit, validators, 2); !equalSchedule(have, []int{2, 0}) {
+		t.Errorf("skipped schedule mismatch: have %v", have)
+	}
+	// Not skipped when it signed, or at the start of an epoch
+	commit.SetIndex(1, true)
+	if have := ProposerSchedule(head, parent, commit, validators, 1); !equalSchedule(have, []int{1}) {
+		t.Errorf("signer skipped: have %v", have)
+	}
+	if have := ProposerSchedule(head, common.Hash{}, cmn.NewBitArray(3), validators, 1); !equalSchedule(have, []int{1}) {
+		t.Errorf("proposer skipped at the start of the epoch: have %v", have)
+	}
+
+	if ProposerSchedule(head, parent, nil, nil, 3) != nil {
+		t.Error("schedule of an empty validator set")
+	}
+}
+
+func equalSchedule(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package consensus
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+)
+
+var (
+	errPeerFlooding = errors.New("peer flooding consensus messages")
+	errPeerInvalid  = errors.New("peer sending invalid consensus messages")
+)
+
+// Classes of consensus messages limited separately, each at a rate well above
+// what an honest peer sends.
+const (
+	msgClassState    = iota // Round steps and vote announcements
+	msgClassProposal        // Proposals, their POLs and the +2/3 aggregations
+	msgClassPart            // Proposal block parts
+	msgClassVote            // Votes and partial aggregations
+	msgClassInvalid         // Messages failing to decode
+	numMsgClasses
+)
+
+// msgClassLimits are the sustained rate, in messages per second, and burst of
+// each class. A peer relays the votes of every validator, and announces each
+// of them, twice a round.
+var msgClassLimits = [numMsgClasses]struct {
+	rate  float64
+	burst float64
+}{
+	msgClassState:    {rate: 500, burst: 4 * types.MaxValidatorSetSize},
+	msgClassProposal: {rate: 20, burst: 50},
+	msgClassPart:     {rate: 500, burst: 2 * types.MaxBlockPartsCount},
+	msgClassVote:     {rate: 500, burst: 4 * types.MaxValidatorSetSize},
+	msgClassInvalid:  {rate: 0.1, burst: 10},
+}
+
+// msgClass returns the class a message of type msgType is limited in. The
+// type is read before the message is decoded, so a peer pays for the decoding
+// of what it sends.
+func msgClass(msgType byte) int {
+	switch msgType {
+	case msgTypeProposal, msgTypeProposalPOL, msgTypeMaj23SignAggr:
+		return msgClassProposal
+	case msgTypeBlockPart:
+		return msgClassPart
+	case msgTypeVote, msgTypePartialSignAggr:
+		return msgClassVote
+	default:
+		return msgClassState
+	}
+}
+
+// tokenBucket limits the rate of a class of messages of a peer. The messages
+// over the limit are dropped, and counted until the bucket fills up again: a
+// peer dropping more than a burst in that time sends faster than the rate for
+// long, it is flooding.
+type tokenBucket struct {
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	dropped float64
+}
+
+// take takes a token for a message received at now. It reports whether the
+// message is allowed, and whether the peer is flooding.
+func (b *tokenBucket) take(now time.Time) (bool, bool) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.rate
+		if b.tokens >= b.burst {
+			b.tokens, b.dropped = b.burst, 0
+		}
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, false
+	}
+	b.dropped++
+	return false, b.dropped > b.burst
+}
+
+// peerRateLimiter holds the token buckets of a peer.
+type peerRateLimiter struct {
+	mtx     sync.Mutex
+	buckets [numMsgClasses]tokenBucket
+}
+
+func newPeerRateLimiter(now time.Time) *peerRateLimiter {
+	l := new(peerRateLimiter)
+	for class, limit := range msgClassLimits {
+		l.buckets[class] = tokenBucket{rate: limit.rate, burst: limit.burst, tokens: limit.burst, last: now}
+	}
+	return l
+}
+
+// allow reports whether a message of type msgType, received at now, is within
+// the limits of the peer. It returns errPeerFlooding if the peer should be
+// disconnected.
+func (l *peerRateLimiter) allow(msgType byte, now time.Time) (bool, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	ok, flooding := l.buckets[msgClass(msgType)].take(now)
+	if flooding {
+		return false, errPeerFlooding
+	}
+	return ok, nil
+}
+
+// invalid charges a message received at now failing to decode. A few are
+// tolerated, from a peer running another version, but a peer going on
+// sending them is disconnected with errPeerInvalid.
+func (l *peerRateLimiter) invalid(now time.Time) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if ok, _ := l.buckets[msgClassInvalid].take(now); !ok {
+		return errPeerInvalid
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeerRateLimiter(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	l := newPeerRateLimiter(now)
+	limit := msgClassLimits[msgClassProposal]
+	proposal := msgTypeProposal
+
+	// A burst is allowed, the messages after it are dropped
+	for i := 0; i < int(limit.burst); i++ {
+		if ok, err := l.allow(proposal, now); !ok || err != nil {
+			t.Fatalf("message %d of the burst not allowed: %v", i, err)
+		}
+	}
+	if ok, err := l.allow(proposal, now); ok || err != nil {
+		t.Fatalf("message over the burst: have %v, %v, want dropped", ok, err)
+	}
+	// The other classes have their own buckets
+	if ok, err := l.allow(msgTypeVote, now); !ok || err != nil {
+		t.Fatalf("vote limited by proposals: %v", err)
+	}
+	// Tokens come back at the rate of the class
+	now = now.Add(time.Second)
+	for i := 0; i < int(limit.rate); i++ {
+		if ok, _ := l.allow(proposal, now); !ok {
+			t.Fatalf("message %d after refill not allowed", i)
+		}
+	}
+	if ok, _ := l.allow(proposal, now); ok {
+		t.Fatal("message over the rate allowed")
+	}
+
+	// Once the bucket is full again, the messages dropped are forgiven
+	now = now.Add(time.Duration(limit.burst/limit.rate+1) * time.Second)
+	for i := 0; i < int(limit.burst); i++ {
+		l.allow(proposal, now)
+	}
+	for i := 0; i < int(limit.burst); i++ {
+		if _, err := l.allow(proposal, now); err != nil {
+			t.Fatalf("peer disconnected after %d dropped messages", i+1)
+		}
+	}
+	// But a peer going on sending over the rate is flooding
+	if _, err := l.allow(proposal, now); err != errPeerFlooding {
+		t.Fatalf("flooding peer: have %v, want %v", err, errPeerFlooding)
+	}
+}
+
+func TestPeerRateLimiterInvalid(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	l := newPeerRateLimiter(now)
+	limit := msgClassLimits[msgClassInvalid]
+
+	// A few messages failing to decode are tolerated
+	for i := 0; i < int(limit.burst); i++ {
+		if err := l.invalid(now); err != nil {
+			t.Fatalf("peer disconnected after %d invalid messages", i+1)
+		}
+	}
+	// But not a peer going on sending them
+	if err := l.invalid(now); err != errPeerInvalid {
+		t.Fatalf("invalid peer: have %v, want %v", err, errPeerInvalid)
+	}
+	// And they take nothing from the limits of the valid messages
+	if ok, err := l.allow(msgTypeVote, now); !ok || err != nil {
+		t.Fatalf("vote limited by invalid messages: %v", err)
+	}
+}
@@ -101,13 +101,13 @@ func (conR *ConsensusReactor) AddPeer(peer consensus.Peer) {
 	conR.logger.Debug("add peer")
 
 	if _, ok := conR.peerStates.Load(peer.GetKey()); ok {
-		conR.logger.Infof("peer %v has been added, return", peer.GetKey())
+		conR.logger.Info("Peer already added", "peer", peer.GetKey())
 		return
 	}
 
 	peerKey := peer.GetKey()
 	if peerKey == "" {
-		conR.logger.Infof("peer key is empty")
+		conR.logger.Info("Peer key is empty")
 		return
 	}
 
@@ -117,9 +117,7 @@ func (conR *ConsensusReactor) AddPeer(peer consensus.Peer) {
 
 	conR.peerStates.Store(peerKey, peerState)
 
-	conR.logger.Debugf("peer is:%+v", peer)
-	conR.logger.Debugf("peer key is:%+v", peer.GetKey())
-	conR.logger.Infof("peer %v added", peer.GetKey())
+	conR.logger.Info("Peer added", "peer", peerKey)
 
 	if conR.IsRunning() {
 		// Begin routines for this peer.
@@ -169,19 +167,13 @@ func (conR *ConsensusReactor) startPeerRoutine() {
 // Peer state updates can happen in parallel, but processing of
 // proposals, block parts, and votes are ordered by the receiveRoutine
 // NOTE: blocks on consensus state for proposals, block parts, and votes
-func (conR *ConsensusReactor) Receive(chID uint64, src consensus.Peer, msgBytes []byte) {
+// Returns an error if the peer is flooding, or sending invalid messages, it should be
+// disconnected.
+func (conR *ConsensusReactor) Receive(chID uint64, src consensus.Peer, msgBytes []byte) error {
 	if !conR.IsRunning() {
 		conR.logger.Debug("Receive", "src", src, "chId", chID, "bytes", msgBytes)
-		return
-	}
-
-	_, msg, err := DecodeMessage(msgBytes)
-	if err != nil {
-		conR.logger.Warn("Error decoding message", "src", src, "chId", chID, "msg", msg, "error", err, "bytes", msgBytes)
-		// TODO punish peer?
-		return
+		return nil
 	}
-	conR.logger.Debug("Receive", "src", src, "chId", chID, "msg", msg)
 
 	// Get peer states
 	ps, exist := src.GetPeerState().(*PeerState)
@@ -193,15 +185,46 @@ func (conR *ConsensusReactor) Receive(chID uint64, src consensus.Peer, msgBytes
 	}
 	//ps := src.Data.Get(conR.ChainId + "." + types.PeerStateKey).(*PeerState)
 
+	// Limit the messages before decoding them, by the type they claim
+	var msgType byte
+	if len(msgBytes) > 0 {
+		msgType = msgBytes[0]
+	}
+	if ok, err := ps.limiter.allow(msgType, time.Now()); err != nil {
+		conR.logger.Warn("Disconnecting flooding peer", "peer", src.GetKey(), "chId", chID, "msgType", msgType)
+		return err
+	} else if !ok {
+		conR.logger.Debug("Dropping rate limited message", "peer", src.GetKey(), "chId", chID, "msgType", msgType)
+		return nil
+	}
+
+	_, msg, err := DecodeMessage(msgBytes)
+	if err != nil {
+		conR.logger.Warn("Error decoding message", "src", src, "chId", chID, "msg", msg, "error", err, "bytes", msgBytes)
+		if err := ps.limiter.invalid(time.Now()); err != nil {
+			conR.logger.Warn("Disconnecting peer sending invalid messages", "peer", src.GetKey(), "chId", chID)
+			return err
+		}
+		return nil
+	}
+	conR.logger.Debug("Receive", "src", src, "chId", chID, "msg", msg)
+
 	switch chID {
 	case StateChannel:
 		// fmt.Println(chID, src, msg)
 		switch msg := msg.(type) {
 		case *NewRoundStepMessage:
 			ps.ApplyNewRoundStepMessage(msg)
+			conR.conS.propagation.peerStepped(msg.Height, msg.Round, msg.Step, src.GetKey())
+			conR.sendPartialSignAggrs(ps, msg)
 		case *CommitStepMessage:
 			ps.ApplyCommitStepMessage(msg)
 		case *HasVoteMessage:
+			cs := conR.conS
+			cs.mtx.Lock()
+			height, valSize := cs.Height, cs.Validators.Size()
+			cs.mtx.Unlock()
+			ps.EnsureVoteBitArrays(height, uint64(valSize))
 			ps.ApplyHasVoteMessage(msg)
 		default:
 			conR.logger.Warn(Fmt("Unknown message type %v", reflect.TypeOf(msg)))
@@ -243,6 +266,8 @@ func (conR *ConsensusReactor) Receive(chID uint64, src consensus.Peer, msgBytes
 			ps.SetHasVote(msg.Vote)
 
 			conR.conS.peerMsgQueue <- msgInfo{msg, src.GetKey()}
+		case *PartialSignAggrMessage:
+			conR.conS.peerMsgQueue <- msgInfo{msg, src.GetKey()}
 
 		default:
 			// don't punish (leave room for soft upgrades)
@@ -255,6 +280,7 @@ func (conR *ConsensusReactor) Receive(chID uint64, src consensus.Peer, msgBytes
 	if err != nil {
 		conR.logger.Warn("Error in Receive()", "error", err)
 	}
+	return nil
 }
 
 // implements events.Eventable
@@ -283,7 +309,7 @@ func (conR *ConsensusReactor) registerEventCallbacks() {
 		//if conR.conS.Step < RoundStepPropose {
 		re := data.(types.EventDataRequest)
 		block := re.Proposal
-		conR.logger.Infof("registerEventCallbacks received block height: %d, conR.conS.Height: %d, conR.conS.Step: %v", block.NumberU64(), conR.conS.Height, conR.conS.Step)
+		conR.logger.Info("Received block from miner", "number", block.NumberU64(), "height", conR.conS.Height, "step", conR.conS.Step)
 		//wait block in new height or new block has been inserted to start a new height
 		if block.NumberU64() >= conR.conS.Height {
 
@@ -294,6 +320,9 @@ func (conR *ConsensusReactor) registerEventCallbacks() {
 
 			//set block here
 			conR.conS.blockFromMiner = re.Proposal
+			if len(block.Transactions()) > 0 {
+				conR.conS.NotifyTxsAvailable(block.NumberU64())
+			}
 			conR.logger.Infof("registerEventCallbacks received Request Event conR.conS.blockFromMiner has been set with height: %v", conR.conS.blockFromMiner.NumberU64())
 		} else {
 			conR.logger.Info("registerEventCallbacks received Request Event", "conR.conS.Height", conR.conS.Height, "conR.conS.Step", conR.conS.Step)
@@ -338,9 +367,13 @@ func (conR *ConsensusReactor) broadcastSignAggr(sign *types.SignAggr) {
 		msg := &Maj23SignAggrMessage{Maj23SignAggr: sign}
 		conR.peerStates.Range(func(_, val interface{}) bool {
 			peerState := val.(*PeerState)
+			// Skip the peers which sent it to us, or got it from gossip
+			if !peerState.claimMaj23SignAggr(sign) {
+				return true
+			}
 			go func(peer consensus.Peer, peerState *PeerState) {
-				if peer.Send(DataChannel, struct{ ConsensusMessage }{msg}) == nil {
-					peerState.SetHasMaj23SignAggr(sign)
+				if peer.Send(DataChannel, struct{ ConsensusMessage }{msg}) != nil {
+					peerState.releaseMaj23SignAggr(sign)
 				}
 			}(peerState.Peer, peerState)
 			return true
@@ -352,10 +385,19 @@ func (conR *ConsensusReactor) sendVote2Proposer(vote *types.Vote, proposerKey st
 	if vote != nil {
 		peerState, ok := conR.peerStates.Load(proposerKey)
 		if ok {
+			ps := peerState.(*PeerState)
+			ps.EnsureVoteBitArrays(vote.Height, uint64(conR.conS.Validators.Size()))
+			// The proposer reports the votes it has with HasVoteMessage
+			if ps.HasVote(vote) {
+				conR.logger.Debug("Proposer has the vote already", "peer", proposerKey)
+				return
+			}
 			msg := &VoteMessage{vote}
-			peerState.(*PeerState).Peer.Send(VoteChannel, struct{ ConsensusMessage }{msg})
+			if ps.Peer.Send(VoteChannel, struct{ ConsensusMessage }{msg}) == nil {
+				ps.SetHasVote(vote)
+			}
 		} else {
-			conR.logger.Infof("proposerKey is :%+v, proposer could be offline\n", proposerKey)
+			conR.logger.Info("Proposer could be offline", "peer", proposerKey)
 		}
 	} else {
 		panic("vote is nil")
@@ -363,6 +405,14 @@ func (conR *ConsensusReactor) sendVote2Proposer(vote *types.Vote, proposerKey st
 }
 
 // Broadcasts HasVoteMessage to peers that care.
+// sendPartialSignAggrs sends a peer entering our round the votes collected so
+// far, for it to fold its own vote into.
+func (conR *ConsensusReactor) sendPartialSignAggrs(ps *PeerState, msg *NewRoundStepMessage) {
+	for _, signAggr := range conR.conS.PartialSignAggrs(msg.Height, msg.Round, msg.Step) {
+		ps.Peer.Send(VoteChannel, struct{ ConsensusMessage }{&PartialSignAggrMessage{signAggr}})
+	}
+}
+
 func (conR *ConsensusReactor) broadcastHasVoteMessage(vote *types.Vote) {
 	// only the proposer needs to broadcast HasVoteMessage
 	if conR.conS.IsProposer() {
@@ -406,24 +456,24 @@ func (conR *ConsensusReactor) sendNewRoundStepMessages(peer consensus.Peer) {
 
 func (conR *ConsensusReactor) gossipDataRoutine(peer consensus.Peer, ps *PeerState) {
 	id := peer.GetKey()
-	conR.logger.Infof("gossipDataRoutine start for peer %v", id)
+	conR.logger.Info("gossipDataRoutine start", "peer", id)
 
 OUTER_LOOP:
 	for {
 		// Manage disconnects from self or peer.
 		if peer == nil {
-			conR.logger.Infof("Peer is nil, Stopping gossipDataRoutine for peer %v", id)
+			conR.logger.Info("Peer is nil, stopping gossipDataRoutine", "peer", id)
 			return
 		}
 
 		ps := peer.GetPeerState().(*PeerState)
 		if !ps.Connected {
-			conR.logger.Infof("Peer disconnected, stopping gossipDataRoutine for peer %v", peer)
+			conR.logger.Info("Peer disconnected, stopping gossipDataRoutine", "peer", id)
 			return
 		}
 
 		if !conR.IsRunning() {
-			conR.logger.Infof("Consensus reactor is not running, stopping gossipDataRoutine for peer %v", peer)
+			conR.logger.Info("Consensus reactor is not running, stopping gossipDataRoutine", "peer", id)
 			return
 		}
 
@@ -499,18 +549,18 @@ OUTER_LOOP:
 	for {
 		// Manage disconnects from self or peer.
 		if peer == nil {
-			conR.logger.Infof("Peer is nil, Stopping gossipVotesRoutine for peer %v", id)
+			conR.logger.Info("Peer is nil, stopping gossipVotesRoutine", "peer", id)
 			return
 		}
 
 		ps1 := peer.GetPeerState().(*PeerState)
 		if !ps1.Connected {
-			conR.logger.Infof("Peer disconnected, stopping gossipVotesRoutine for peer %v", peer)
+			conR.logger.Info("Peer disconnected, stopping gossipVotesRoutine", "peer", id)
 			return
 		}
 
 		if !conR.IsRunning() {
-			conR.logger.Infof("Consensus reactor is not running, stopping gossipVotesRoutine for peer %v", peer)
+			conR.logger.Info("Consensus reactor is not running, stopping gossipVotesRoutine", "peer", id)
 			return
 			//time.Sleep(peerGossipSleepDuration)
 			//continue OUTER_LOOP
@@ -648,6 +698,8 @@ type PeerState struct {
 
 	Connected bool
 	logger    log.Logger
+
+	limiter *peerRateLimiter // Limits of the messages received from the peer
 }
 
 func NewPeerState(peer consensus.Peer, logger log.Logger) *PeerState {
@@ -659,6 +711,7 @@ func NewPeerState(peer consensus.Peer, logger log.Logger) *PeerState {
 		},
 		Connected: true,
 		logger:    logger,
+		limiter:   newPeerRateLimiter(time.Now()),
 	}
 }
 
@@ -738,14 +791,57 @@ func (ps *PeerState) SetHasMaj23SignAggr(signAggr *types.SignAggr) {
 	}
 }
 
-// PickSendSignAggr sends signature aggregation to peer.
+// claimMaj23SignAggr marks the signature aggregation as had by the peer, so
+// that it is sent once. Returns false if the peer had it already.
+func (ps *PeerState) claimMaj23SignAggr(signAggr *types.SignAggr) bool {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	if ps.Height != signAggr.Height || ps.Round != signAggr.Round {
+		return true
+	}
+	switch signAggr.Type {
+	case types.VoteTypePrevote:
+		if ps.PrevoteMaj23SignAggr {
+			return false
+		}
+		ps.PrevoteMaj23SignAggr = true
+	case types.VoteTypePrecommit:
+		if ps.PrecommitMaj23SignAggr {
+			return false
+		}
+		ps.PrecommitMaj23SignAggr = true
+	}
+	return true
+}
+
+// releaseMaj23SignAggr undoes claimMaj23SignAggr when sending failed.
+func (ps *PeerState) releaseMaj23SignAggr(signAggr *types.SignAggr) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	if ps.Height != signAggr.Height || ps.Round != signAggr.Round {
+		return
+	}
+	switch signAggr.Type {
+	case types.VoteTypePrevote:
+		ps.PrevoteMaj23SignAggr = false
+	case types.VoteTypePrecommit:
+		ps.PrecommitMaj23SignAggr = false
+	}
+}
+
+// PickSendSignAggr sends signature aggregation to peer, unless it has it.
 // Returns true if vote was sent.
 func (ps *PeerState) PickSendSignAggr(signAggr *types.SignAggr) (ok bool) {
+	if !ps.claimMaj23SignAggr(signAggr) {
+		return false
+	}
 	msg := &Maj23SignAggrMessage{signAggr}
 	if ps.Peer.Send(DataChannel, struct{ ConsensusMessage }{msg}) == nil {
-		ps.SetHasMaj23SignAggr(msg.Maj23SignAggr)
 		return true
 	}
+	ps.releaseMaj23SignAggr(signAggr)
 	return false
 }
 
@@ -834,6 +930,14 @@ func (ps *PeerState) ensureVoteBitArrays(height uint64, numValidators uint64) {
 	}
 }
 
+// HasVote reports whether the peer is known to have the vote.
+func (ps *PeerState) HasVote(vote *types.Vote) bool {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	return ps.getVoteBitArray(vote.Height, int(vote.Round), vote.Type).GetIndex(vote.ValidatorIndex)
+}
+
 func (ps *PeerState) SetHasVote(vote *types.Vote) {
 	ps.mtx.Lock()
 	defer ps.mtx.Unlock()
@@ -963,20 +1067,28 @@ func (ps *PeerState) StringIndented(indent string) string {
 // Messages
 
 const (
-	msgTypeNewRoundStep  = byte(0x01)
-	msgTypeCommitStep    = byte(0x02)
-	msgTypeProposal      = byte(0x11)
-	msgTypeProposalPOL   = byte(0x12)
-	msgTypeBlockPart     = byte(0x13) // both block & POL
-	msgTypeVote          = byte(0x14)
-	msgTypeHasVote       = byte(0x15)
-	msgTypeVoteSetMaj23  = byte(0x16)
-	msgTypeVoteSetBits   = byte(0x17)
-	msgTypeMaj23SignAggr = byte(0x18)
+	msgTypeNewRoundStep    = byte(0x01)
+	msgTypeCommitStep      = byte(0x02)
+	msgTypeProposal        = byte(0x11)
+	msgTypeProposalPOL     = byte(0x12)
+	msgTypeBlockPart       = byte(0x13) // both block & POL
+	msgTypeVote            = byte(0x14)
+	msgTypeHasVote         = byte(0x15)
+	msgTypeVoteSetMaj23    = byte(0x16)
+	msgTypeVoteSetBits     = byte(0x17)
+	msgTypeMaj23SignAggr   = byte(0x18)
+	msgTypePartialSignAggr = byte(0x19)
 )
 
 type ConsensusMessage interface{}
 
+var (
+	errEmptyMessage  = errors.New("empty message")
+	errNegativeRound = errors.New("negative round")
+	errNilMessage    = errors.New("message with missing content")
+	errVoteType      = errors.New("invalid vote type")
+)
+
 var _ = wire.RegisterInterface(
 	struct{ ConsensusMessage }{},
 	wire.ConcreteType{&NewRoundStepMessage{}, msgTypeNewRoundStep},
@@ -989,15 +1101,37 @@ var _ = wire.RegisterInterface(
 	wire.ConcreteType{&VoteSetMaj23Message{}, msgTypeVoteSetMaj23},
 	wire.ConcreteType{&VoteSetBitsMessage{}, msgTypeVoteSetBits},
 	wire.ConcreteType{&Maj23SignAggrMessage{}, msgTypeMaj23SignAggr},
+	wire.ConcreteType{&PartialSignAggrMessage{}, msgTypePartialSignAggr},
 )
 
-// TODO: check for unnecessary extra bytes at the end.
+// DecodeMessage decodes a consensus message received from a peer. The input is
+// not trusted: malformed messages, trailing bytes and fields out of bounds are
+// reported as errors.
 func DecodeMessage(bz []byte) (msgType byte, msg ConsensusMessage, err error) {
+	if len(bz) == 0 {
+		return 0, nil, errEmptyMessage
+	}
 	msgType = bz[0]
 	n := new(int)
 	r := bytes.NewReader(bz)
-	msg = wire.ReadBinary(struct{ ConsensusMessage }{}, r, maxConsensusMessageSize, n, &err).(struct{ ConsensusMessage }).ConsensusMessage
-	return
+	msg = types.ReadBinary(struct{ ConsensusMessage }{}, r, maxConsensusMessageSize, n, &err).(struct{ ConsensusMessage }).ConsensusMessage
+	if err != nil {
+		return msgType, nil, err
+	}
+	if r.Len() != 0 {
+		return msgType, nil, types.ErrTrailingBytes
+	}
+	if err := types.CheckCanonical(struct{ ConsensusMessage }{msg}, bz); err != nil {
+		return msgType, nil, err
+	}
+	validated, ok := msg.(interface{ ValidateBasic() error })
+	if !ok {
+		return msgType, nil, fmt.Errorf("unexpected message %T", msg)
+	}
+	if err := validated.ValidateBasic(); err != nil {
+		return msgType, nil, err
+	}
+	return msgType, msg, nil
 }
 
 //-------------------------------------
@@ -1016,6 +1150,14 @@ func (m *NewRoundStepMessage) String() string {
 		m.Height, m.Round, m.Step, m.LastCommitRound)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *NewRoundStepMessage) ValidateBasic() error {
+	if m.Round < 0 || m.LastCommitRound < -1 {
+		return errNegativeRound
+	}
+	return nil
+}
+
 //-------------------------------------
 
 type CommitStepMessage struct {
@@ -1028,6 +1170,14 @@ func (m *CommitStepMessage) String() string {
 	return fmt.Sprintf("[CommitStep H:%v BP:%v BA:%v]", m.Height, m.BlockPartsHeader, m.BlockParts)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *CommitStepMessage) ValidateBasic() error {
+	if err := m.BlockPartsHeader.ValidateBasic(); err != nil {
+		return err
+	}
+	return types.ValidateBitArray(m.BlockParts, m.BlockPartsHeader.Total)
+}
+
 //-------------------------------------
 
 type ProposalMessage struct {
@@ -1038,6 +1188,14 @@ func (m *ProposalMessage) String() string {
 	return fmt.Sprintf("[Proposal %v]", m.Proposal)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *ProposalMessage) ValidateBasic() error {
+	if m.Proposal == nil {
+		return errNilMessage
+	}
+	return m.Proposal.ValidateBasic()
+}
+
 //-------------------------------------
 
 type ProposalPOLMessage struct {
@@ -1050,6 +1208,14 @@ func (m *ProposalPOLMessage) String() string {
 	return fmt.Sprintf("[ProposalPOL H:%v POLR:%v POL:%v]", m.Height, m.ProposalPOLRound, m.ProposalPOL)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *ProposalPOLMessage) ValidateBasic() error {
+	if m.ProposalPOLRound < 0 {
+		return errNegativeRound
+	}
+	return types.ValidateBitArray(m.ProposalPOL, types.MaxValidatorSetSize)
+}
+
 //-------------------------------------
 
 type BlockPartMessage struct {
@@ -1062,6 +1228,17 @@ func (m *BlockPartMessage) String() string {
 	return fmt.Sprintf("[BlockPart H:%v R:%v P:%v]", m.Height, m.Round, m.Part)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *BlockPartMessage) ValidateBasic() error {
+	if m.Round < 0 {
+		return errNegativeRound
+	}
+	if m.Part == nil {
+		return errNilMessage
+	}
+	return m.Part.ValidateBasic()
+}
+
 //-------------------------------------
 
 type VoteMessage struct {
@@ -1072,6 +1249,14 @@ func (m *VoteMessage) String() string {
 	return fmt.Sprintf("[Vote %v]", m.Vote)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *VoteMessage) ValidateBasic() error {
+	if m.Vote == nil {
+		return errNilMessage
+	}
+	return m.Vote.ValidateBasic()
+}
+
 //-------------------------------------
 
 type Maj23SignAggrMessage struct {
@@ -1082,6 +1267,32 @@ func (m *Maj23SignAggrMessage) String() string {
 	return fmt.Sprintf("[SignAggr %v]", m.Maj23SignAggr)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *Maj23SignAggrMessage) ValidateBasic() error {
+	if m.Maj23SignAggr == nil {
+		return errNilMessage
+	}
+	return m.Maj23SignAggr.ValidateBasic()
+}
+
+//-------------------------------------
+
+type PartialSignAggrMessage struct {
+	PartialSignAggr *types.SignAggr
+}
+
+func (m *PartialSignAggrMessage) String() string {
+	return fmt.Sprintf("[PartialSignAggr %v]", m.PartialSignAggr)
+}
+
+// ValidateBasic checks the bounds of the message.
+func (m *PartialSignAggrMessage) ValidateBasic() error {
+	if m.PartialSignAggr == nil {
+		return errNilMessage
+	}
+	return m.PartialSignAggr.ValidateBasic()
+}
+
 //-------------------------------------
 
 type HasVoteMessage struct {
@@ -1095,6 +1306,19 @@ func (m *HasVoteMessage) String() string {
 	return fmt.Sprintf("[HasVote VI:%v V:{%v/%02d/%v} VI:%v]", m.Index, m.Height, m.Round, m.Type, m.Index)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *HasVoteMessage) ValidateBasic() error {
+	switch {
+	case m.Round < 0:
+		return errNegativeRound
+	case !types.IsVoteTypeValid(m.Type):
+		return errVoteType
+	case m.Index < 0 || m.Index >= types.MaxValidatorSetSize:
+		return types.ErrVoteInvalidValidatorIndex
+	}
+	return nil
+}
+
 //-------------------------------------
 
 type VoteSetMaj23Message struct {
@@ -1108,6 +1332,17 @@ func (m *VoteSetMaj23Message) String() string {
 	return fmt.Sprintf("[VSM23 %v/%02d/%v %v]", m.Height, m.Round, m.Type, m.BlockID)
 }
 
+// ValidateBasic checks the bounds of the message.
+func (m *VoteSetMaj23Message) ValidateBasic() error {
+	if m.Round < 0 {
+		return errNegativeRound
+	}
+	if !types.IsVoteTypeValid(m.Type) {
+		return errVoteType
+	}
+	return m.BlockID.ValidateBasic()
+}
+
 //-------------------------------------
 
 type VoteSetBitsMessage struct {
@@ -1121,3 +1356,17 @@ type VoteSetBitsMessage struct {
 func (m *VoteSetBitsMessage) String() string {
 	return fmt.Sprintf("[VSB %v/%02d/%v %v %v]", m.Height, m.Round, m.Type, m.BlockID, m.Votes)
 }
+
+// ValidateBasic checks the bounds of the message.
+func (m *VoteSetBitsMessage) ValidateBasic() error {
+	if m.Round < 0 {
+		return errNegativeRound
+	}
+	if !types.IsVoteTypeValid(m.Type) {
+		return errVoteType
+	}
+	if err := m.BlockID.ValidateBasic(); err != nil {
+		return err
+	}
+	return types.ValidateBitArray(m.Votes, types.MaxValidatorSetSize)
+}
@@ -0,0 +1,70 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	. "github.com/neatlib/common-go"
+	"github.com/neatlib/wire-go"
+)
+
+func encodeMessage(msg ConsensusMessage) []byte {
+	return wire.BinaryBytes(struct{ ConsensusMessage }{msg})
+}
+
+func TestDecodeMessage(t *testing.T) {
+	valid := &HasVoteMessage{Height: 10, Round: 1, Type: types.VoteTypePrevote, Index: 3}
+	data := encodeMessage(valid)
+	if _, msg, err := DecodeMessage(data); err != nil {
+		t.Fatalf("failed to decode valid message: %v", err)
+	} else if *msg.(*HasVoteMessage) != *valid {
+		t.Errorf("decoded message mismatch: have %v, want %v", msg, valid)
+	}
+	if _, _, err := DecodeMessage(append(data, 0)); err != types.ErrTrailingBytes {
+		t.Errorf("message with trailing bytes: have %v, want %v", err, types.ErrTrailingBytes)
+	}
+	// Pad the round varint, which decodes to the same message
+	padded := append(append(append([]byte{}, data[:9]...), 0x02, 0x00, 0x01), data[11:]...)
+	if _, _, err := DecodeMessage(padded); err != types.ErrNonCanonical {
+		t.Errorf("non-canonical message: have %v, want %v", err, types.ErrNonCanonical)
+	}
+
+	invalid := map[string][]byte{
+		"empty":            {},
+		"nil message":      {0x00},
+		"unknown type":     {0xff},
+		"nil proposal":     encodeMessage(&ProposalMessage{}),
+		"nil block part":   encodeMessage(&BlockPartMessage{Height: 1}),
+		"nil vote":         encodeMessage(&VoteMessage{}),
+		"nil sign aggr":    encodeMessage(&Maj23SignAggrMessage{}),
+		"negative round":   encodeMessage(&NewRoundStepMessage{Height: 1, Round: -1}),
+		"negative index":   encodeMessage(&HasVoteMessage{Height: 1, Type: types.VoteTypePrevote, Index: -1}),
+		"bad vote type":    encodeMessage(&HasVoteMessage{Height: 1, Type: 0x07}),
+		"part index":       encodeMessage(&BlockPartMessage{Height: 1, Part: &types.Part{Index: -1}}),
+		"bad bit array":    encodeMessage(&ProposalPOLMessage{Height: 1, ProposalPOL: &BitArray{Bits: 200}}),
+		"huge parts total": encodeMessage(&CommitStepMessage{Height: 1, BlockPartsHeader: types.PartSetHeader{Total: 1 << 40}}),
+	}
+	for name, data := range invalid {
+		if _, _, err := DecodeMessage(data); err == nil {
+			t.Errorf("%s: invalid message decoded", name)
+		}
+	}
+}
+
+func TestDecodeMessageNoPanic(t *testing.T) {
+	msg := &VoteMessage{Vote: &types.Vote{
+		ValidatorAddress: make([]byte, 20),
+		Type:             types.VoteTypePrecommit,
+		BlockID:          types.BlockID{Hash: make([]byte, 32), PartsHeader: types.PartSetHeader{Total: 3, Hash: make([]byte, 20)}},
+	}}
+	data := encodeMessage(msg)
+
+	// Corrupt every byte in turn, the decoding may fail but must not panic
+	for i := range data {
+		for _, b := range []byte{0x00, 0x01, 0x7f, 0xff} {
+			corrupted := append([]byte(nil), data...)
+			corrupted[i] = b
+			DecodeMessage(corrupted)
+		}
+	}
+}
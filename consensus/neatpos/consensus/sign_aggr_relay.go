@@ -0,0 +1,141 @@
+package consensus
+
+import (
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	. "github.com/neatlib/common-go"
+	tmdcrypto "github.com/neatlib/crypto-go"
+)
+
+// Validators joining a round late are sent the proposer's partial signature
+// aggregation of the round's votes so far. Once they vote, they fold their
+// own signature into it and, if that completes the 2/3+ majority, finish the
+// aggregation themselves rather than waiting for the proposer.
+
+// partialSignAggr aggregates the votes of the current round for the proposal
+// block. Returns nil unless this node is the proposer and has collected some,
+// but not yet 2/3+, of those votes.
+func (cs *ConsensusState) partialSignAggr(voteType byte) *types.SignAggr {
+	if !cs.IsProposer() || cs.ProposalBlock == nil || cs.ProposalBlockParts == nil {
+		return nil
+	}
+
+	var voteSet *types.VoteSet
+	switch voteType {
+	case types.VoteTypePrevote:
+		if cs.PrevoteMaj23SignAggr != nil {
+			return nil
+		}
+		voteSet = cs.Votes.Prevotes(cs.Round)
+	case types.VoteTypePrecommit:
+		if cs.PrecommitMaj23SignAggr != nil {
+			return nil
+		}
+		voteSet = cs.Votes.Precommits(cs.Round)
+	}
+	if voteSet == nil || voteSet.HasTwoThirdsMajority() {
+		return nil
+	}
+
+	blockID := types.BlockID{Hash: cs.ProposalBlock.Hash(), PartsHeader: cs.ProposalBlockParts.Header()}
+	signBitArray := NewBitArray(uint64(cs.Validators.Size()))
+	var sigs []*tmdcrypto.Signature
+	for _, vote := range voteSet.Votes() {
+		if vote != nil && blockID.Equals(vote.BlockID) {
+			signBitArray.SetIndex(vote.ValidatorIndex, true)
+			sigs = append(sigs, &(vote.Signature))
+		}
+	}
+	if len(sigs) == 0 {
+		return nil
+	}
+	signature := tmdcrypto.BLSSignatureAggregate(sigs)
+	if signature == nil {
+		return nil
+	}
+
+	signAggr := types.MakeSignAggr(cs.Height, cs.Round, voteType, cs.Validators.Size(), blockID, cs.Votes.chainID, signBitArray, signature)
+	signAggr.Maj23 = types.BlockID{}
+	return signAggr
+}
+
+// PartialSignAggrs returns the partial signature aggregations of the current
+// round for a peer at the given step.
+func (cs *ConsensusState) PartialSignAggrs(height uint64, round int, step RoundStepType) []*types.SignAggr {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	if height != cs.Height || round != cs.Round {
+		return nil
+	}
+	var signAggrs []*types.SignAggr
+	if step <= RoundStepPrevote {
+		if signAggr := cs.partialSignAggr(types.VoteTypePrevote); signAggr != nil {
+			signAggrs = append(signAggrs, signAggr)
+		}
+	}
+	if step <= RoundStepPrecommit {
+		if signAggr := cs.partialSignAggr(types.VoteTypePrecommit); signAggr != nil {
+			signAggrs = append(signAggrs, signAggr)
+		}
+	}
+	return signAggrs
+}
+
+// handlePartialSignAggr verifies a partial signature aggregation and keeps it
+// for our vote of the round to be folded in.
+func (cs *ConsensusState) handlePartialSignAggr(signAggr *types.SignAggr) error {
+	if signAggr == nil {
+		return ErrInvalidSignatureAggr
+	}
+	if signAggr.Height != cs.Height || signAggr.Round != cs.Round {
+		return nil
+	}
+	if signAggr.Type != types.VoteTypePrevote && signAggr.Type != types.VoteTypePrecommit {
+		return ErrInvalidSignatureAggr
+	}
+	if signAggr.BitArray.IsEmpty() {
+		return ErrInvalidSignatureAggr
+	}
+	if _, err := cs.blsVerifySignAggr(signAggr); err != nil {
+		cs.logger.Warn("Invalid partial signature aggregation", cs.logCtx("type", signAggr.Type, "err", err)...)
+		return ErrInvalidSignatureAggr
+	}
+
+	// Keep the aggregation covering the most votes
+	if prev := cs.partialSignAggrs[signAggr.Type]; prev != nil && prev.BitArray.NumBitsSet() >= signAggr.BitArray.NumBitsSet() {
+		return nil
+	}
+	cs.partialSignAggrs[signAggr.Type] = signAggr
+	cs.contributeSignAggr(signAggr.Type)
+	return nil
+}
+
+// contributeSignAggr folds our vote into the partial signature aggregation of
+// its type, if both are known and for the same block. If that completes the
+// 2/3+ majority, the aggregation is handled and broadcast as the proposer's
+// would be.
+func (cs *ConsensusState) contributeSignAggr(voteType byte) {
+	partial, vote := cs.partialSignAggrs[voteType], cs.ownVotes[voteType]
+	if partial == nil || vote == nil || !partial.BlockID.Equals(vote.BlockID) {
+		return
+	}
+	if (voteType == types.VoteTypePrevote && cs.PrevoteMaj23SignAggr != nil) ||
+		(voteType == types.VoteTypePrecommit && cs.PrecommitMaj23SignAggr != nil) {
+		return
+	}
+
+	signAggr := *partial
+	signAggr.BitArray = partial.BitArray.Copy()
+	if !signAggr.AddSignature(vote.ValidatorIndex, vote.Signature) {
+		return
+	}
+	cs.partialSignAggrs[voteType] = &signAggr
+	if !signAggr.HasTwoThirdsMajority(cs.Validators) {
+		return
+	}
+	signAggr.Maj23 = signAggr.BlockID
+	cs.logger.Info("Completed signature aggregation with our vote", cs.logCtx("type", voteType)...)
+
+	types.FireEventSignAggr(cs.evsw, types.EventDataSignAggr{SignAggr: &signAggr})
+	cs.sendInternalMessage(msgInfo{&Maj23SignAggrMessage{&signAggr}, ""})
+}
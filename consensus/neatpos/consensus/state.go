@@ -15,7 +15,6 @@ import (
 	"context"
 
 	"crypto/ecdsa"
-	"crypto/sha256"
 	"math/big"
 
 	consss "github.com/neatlab/neatio/consensus"
@@ -25,12 +24,14 @@ import (
 	"github.com/neatlab/neatio/core"
 	ethTypes "github.com/neatlab/neatio/core/types"
 	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/internal/tracing"
 	neatabi "github.com/neatlab/neatio/neatabi/abi"
 	"github.com/neatlab/neatio/params"
 	"github.com/neatlab/neatio/rlp"
 	. "github.com/neatlib/common-go"
 	cfg "github.com/neatlib/config-go"
 	tmdcrypto "github.com/neatlib/crypto-go"
+	dbm "github.com/neatlib/db-go"
 )
 
 const ROUND_NOT_PROPOSED int = 0
@@ -61,6 +62,12 @@ type TimeoutParams struct {
 	PrecommitDelta     int
 	Commit0            int
 	SkipTimeoutCommit  bool
+	SkipEmptyBlocks    bool
+	Heartbeat0         int
+	MinBlockInterval0  int
+	MaxTimeDrift0      int
+
+	adaptive *adaptiveTimeouts // nil unless adaptive_timeouts is enabled
 }
 
 // Wait this long for a proposal
@@ -71,6 +78,9 @@ func (tp *TimeoutParams) WaitForMinerBlock() time.Duration {
 //In NeatPoS, wait for this long for Proposer to send proposal
 //the more round, the more time to wait for proposer's proposal
 func (tp *TimeoutParams) Propose(round int) time.Duration {
+	if tp.adaptive != nil {
+		return tp.adaptive.timeout(RoundStepPropose, round, time.Duration(tp.Propose0)*time.Millisecond)
+	}
 	if round >= 5 {
 		round = 4
 	}
@@ -84,6 +94,9 @@ func (tp *TimeoutParams) Prevote(round int) time.Duration {
 	// we skip to another round to find another proposer who has better connection situation
 	//if round is equal to or great than 5, we assume some validators are after the newest round,
 	//we extends time every round to wait for them to catch up
+	if tp.adaptive != nil {
+		return tp.adaptive.timeout(RoundStepPrevote, round, time.Duration(tp.Prevote0)*time.Millisecond)
+	}
 	if round < 5 {
 		return time.Duration(tp.Prevote0+tp.PrevoteDelta*round) * time.Millisecond
 	} else {
@@ -93,6 +106,9 @@ func (tp *TimeoutParams) Prevote(round int) time.Duration {
 
 //In NeatPoS, wait for this long for Non-Proposer validator to vote precommit
 func (tp *TimeoutParams) Precommit(round int) time.Duration {
+	if tp.adaptive != nil {
+		return tp.adaptive.timeout(RoundStepPrecommit, round, time.Duration(tp.Precommit0)*time.Millisecond)
+	}
 	if round < 5 {
 		return time.Duration(tp.Precommit0+tp.PrecommitDelta*round) * time.Millisecond
 	} else {
@@ -100,14 +116,38 @@ func (tp *TimeoutParams) Precommit(round int) time.Duration {
 	}
 }
 
+// With SkipEmptyBlocks, wait at most this long after the last commit for
+// transactions before proposing an empty block
+func (tp *TimeoutParams) Heartbeat() time.Duration {
+	return time.Duration(tp.Heartbeat0) * time.Millisecond
+}
+
+// Propose blocks at least this long after their parent, as set by governance
+func (tp *TimeoutParams) MinBlockInterval() time.Duration {
+	return time.Duration(tp.MinBlockInterval0) * time.Millisecond
+}
+
+// Prevote nil for blocks whose time is further than this from the local time
+func (tp *TimeoutParams) MaxTimeDrift() time.Duration {
+	return time.Duration(tp.MaxTimeDrift0) * time.Millisecond
+}
+
 // After receiving +2/3 precommits for a single block (a commit), wait this long for stragglers in the next height's RoundStepNewHeight
 func (tp *TimeoutParams) Commit(t time.Time) time.Time {
 	return t.Add(time.Duration(tp.Commit0) * time.Millisecond)
 }
 
+// Observe records the latency of a step which completed before its timeout,
+// for the adaptive timeouts.
+func (tp *TimeoutParams) Observe(step RoundStepType, latency time.Duration) {
+	if tp.adaptive != nil {
+		tp.adaptive.observe(step, latency)
+	}
+}
+
 // InitTimeoutParamsFromConfig initializes parameters from config
 func InitTimeoutParamsFromConfig(config cfg.Config) *TimeoutParams {
-	return &TimeoutParams{
+	tp := &TimeoutParams{
 		WaitForMinerBlock0: config.GetInt("timeout_wait_for_miner_block"),
 		Propose0:           config.GetInt("timeout_propose"),
 		ProposeDelta:       config.GetInt("timeout_propose_delta"),
@@ -117,7 +157,18 @@ func InitTimeoutParamsFromConfig(config cfg.Config) *TimeoutParams {
 		PrecommitDelta:     config.GetInt("timeout_precommit_delta"),
 		Commit0:            config.GetInt("timeout_commit"),
 		SkipTimeoutCommit:  config.GetBool("skip_timeout_commit"),
+		SkipEmptyBlocks:    config.GetBool("skip_empty_blocks"),
+		Heartbeat0:         config.GetInt("heartbeat_interval"),
+		MaxTimeDrift0:      config.GetInt("max_time_drift"),
 	}
+	if config.GetBool("adaptive_timeouts") {
+		tp.adaptive = newAdaptiveTimeouts(
+			time.Duration(config.GetInt("timeout_min"))*time.Millisecond,
+			time.Duration(config.GetInt("timeout_max"))*time.Millisecond,
+			config.GetFloat64("timeout_backoff"),
+		)
+	}
+	return tp
 }
 
 //-------------------------------------
@@ -235,6 +286,20 @@ func (rs *RoundState) RoundStateEvent() types.EventDataRoundState {
 	return edrs
 }
 
+// ProposerAddress returns the address of the proposer of the round, nil if
+// it is not known yet.
+func (rs *RoundState) ProposerAddress() []byte {
+	if rs.proposer == nil || rs.proposer.Proposer == nil {
+		return nil
+	}
+	return rs.proposer.Proposer.Address
+}
+
+// IsProposer returns true if this validator is the proposer of the round.
+func (rs *RoundState) IsProposer() bool {
+	return rs.isProposer
+}
+
 func (rs *RoundState) String() string {
 	return rs.StringIndented("")
 }
@@ -316,10 +381,30 @@ type ConsensusState struct {
 
 	peerMsgQueue     chan msgInfo   // serializes msgs affecting state (proposals, block parts, votes)
 	internalMsgQueue chan msgInfo   // like peerMsgQueue but for our own proposals, parts, votes
+	txsAvailable     chan uint64    // heights for which the miner built a block with transactions
 	timeoutTicker    TimeoutTicker  // ticker for timeouts
 	timeoutParams    *TimeoutParams // parameters and functions for timeout intervals
 
-	evsw types.EventSwitch
+	evsw        types.EventSwitch
+	metrics     *consensusMetrics
+	propagation *propagationTracker
+	stepStart   map[RoundStepType]time.Time // Time each step was last entered
+	misbehavior *misbehaviorDetector
+	wal         *WAL
+
+	// Verifies the signatures of the votes of the peers off the receive routine
+	voteVerifier *voteVerifier
+
+	// The sign bytes of the votes of the round and the validators hash of the height
+	signBytesCache *types.SignBytesCache
+
+	// Our votes and the partial signature aggregations of the current round
+	ownVotes         map[byte]*types.Vote
+	partialSignAggrs map[byte]*types.SignAggr
+
+	// Spans of the current round ended by a later state transition
+	gossipSpan *tracing.Span
+	voteSpans  map[byte]*tracing.Span
 
 	nSteps int // used for testing to limit the number of transitions the state makes
 
@@ -331,6 +416,7 @@ type ConsensusState struct {
 	done chan struct{}
 
 	blockFromMiner *ethTypes.Block
+	waitingForTxs  bool // Round 0 waits for transactions or the heartbeat
 	backend        Backend
 
 	conR *ConsensusReactor
@@ -345,8 +431,18 @@ func NewConsensusState(backend Backend, config cfg.Config, chainConfig *params.C
 		Epoch:            epoch,
 		peerMsgQueue:     make(chan msgInfo, msgQueueSize),
 		internalMsgQueue: make(chan msgInfo, msgQueueSize),
+		txsAvailable:     make(chan uint64, 1),
 		timeoutTicker:    NewTimeoutTicker(backend.GetLogger()),
 		timeoutParams:    InitTimeoutParamsFromConfig(config),
+		metrics:          newConsensusMetrics(chainConfig.NeatChainId),
+		propagation:      newPropagationTracker(chainConfig.NeatChainId),
+		stepStart:        make(map[RoundStepType]time.Time),
+		misbehavior:      newMisbehaviorDetector(chainConfig.NeatChainId, backend.GetLogger()),
+		voteVerifier:     newVoteVerifier(defaultVoteVerifyWorkers(), msgQueueSize),
+		signBytesCache:   types.NewSignBytesCache(chainConfig.NeatChainId),
+		voteSpans:        make(map[byte]*tracing.Span),
+		ownVotes:         make(map[byte]*types.Vote),
+		partialSignAggrs: make(map[byte]*types.SignAggr),
 		//done:             make(chan struct{}),
 		blockFromMiner: nil,
 		backend:        backend,
@@ -384,6 +480,26 @@ func (cs *ConsensusState) GetState() *sm.State {
 	return cs.state.Copy()
 }
 
+// PropagationStats returns the block propagation latencies per peer.
+func (cs *ConsensusState) PropagationStats() *PropagationStats {
+	return cs.propagation.stats()
+}
+
+// SetMisbehaviorDB sets the store of the detected validator misbehavior.
+func (cs *ConsensusState) SetMisbehaviorDB(db dbm.DB) {
+	cs.misbehavior.setDB(db)
+}
+
+// SetWAL sets the WAL recording the messages and transitions of the state.
+func (cs *ConsensusState) SetWAL(wal *WAL) {
+	cs.wal = wal
+}
+
+// Misbehavior returns the validator misbehavior detected at or above a height.
+func (cs *ConsensusState) Misbehavior(from uint64) []*Misbehavior {
+	return cs.misbehavior.list(from)
+}
+
 func (cs *ConsensusState) GetRoundState() *RoundState {
 	cs.mtx.Lock()
 	defer cs.mtx.Unlock()
@@ -522,33 +638,7 @@ func (cs *ConsensusState) proposersByVRF() (lastProposer int, curProposer int) {
 }
 
 func (cs *ConsensusState) proposerByVRF(headerHash common.Hash, validators []*types.Validator) (proposer int) {
-
-	idx := -1
-
-	var roundBytes = make([]byte, 8)
-	vrfBytes := append(roundBytes, headerHash[:]...)
-	hs := sha256.New()
-	hs.Write(vrfBytes)
-	hv := hs.Sum(nil)
-	hash := new(big.Int)
-	hash.SetBytes(hv[:])
-	//fmt.Printf("ConsensusState proposerByVRF hash %v", hash)
-	//n := big.NewInt(int64(cs.Validators.Size()))
-	n := big.NewInt(0)
-	for _, validator := range validators {
-		n.Add(n, validator.VotingPower)
-	}
-	n.Mod(hash, n)
-
-	for i, validator := range validators {
-		n.Sub(n, validator.VotingPower)
-		if n.Sign() == -1 {
-			idx = i
-			break
-		}
-	}
-
-	return idx
+	return vrfProposerIndex(headerHash, validators)
 }
 
 // Sets our private validator account for signing votes.
@@ -569,6 +659,27 @@ func (cs *ConsensusState) IsProposer() bool {
 	return cs.isProposer
 }
 
+// WaitForProposedBlock waits, at most timeout, until the block this validator
+// is proposing at the current height got committed. It returns false if the
+// height is still in flight when the timeout expires.
+func (cs *ConsensusState) WaitForProposedBlock(timeout time.Duration) bool {
+	cs.mtx.Lock()
+	height, proposing := cs.Height, cs.isProposer && cs.ProposalBlock != nil
+	cs.mtx.Unlock()
+	if !proposing {
+		return true
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		if cs.GetRoundState().Height != height {
+			return true
+		}
+	}
+	return false
+}
+
 // Set the local timer
 func (cs *ConsensusState) SetTimeoutTicker(timeoutTicker TimeoutTicker) {
 	cs.mtx.Lock()
@@ -592,6 +703,7 @@ func (cs *ConsensusState) OnStart() error {
 	//  firing on the tockChan until the receiveRoutine is started
 	//  to deal with them (by that point, at most one will be valid)
 	cs.timeoutTicker.Start()
+	cs.voteVerifier.start()
 
 	// now start the receiveRoutine
 	go cs.receiveRoutine(0)
@@ -607,6 +719,7 @@ func (cs *ConsensusState) OnStop() {
 
 	cs.BaseService.OnStop()
 	cs.timeoutTicker.Stop()
+	cs.voteVerifier.stop()
 }
 
 // NOTE: be sure to Stop() the event switch and drain
@@ -671,9 +784,33 @@ func (cs *ConsensusState) SetProposalAndBlock(proposal *types.Proposal, block *t
 //------------------------------------------------------------
 // internal functions for managing the state
 
+// logCtx returns the structured logging context of the current height, round
+// and step, followed by ctx.
+func (cs *ConsensusState) logCtx(ctx ...interface{}) []interface{} {
+	return append([]interface{}{"height", cs.Height, "round", cs.Round, "step", cs.Step}, ctx...)
+}
+
+// startSpan starts a trace span of the current height and round.
+func (cs *ConsensusState) startSpan(name string) *tracing.Span {
+	return tracing.StartBlockSpan(cs.chainConfig.NeatChainId, cs.Height, cs.Round, name)
+}
+
 func (cs *ConsensusState) updateRoundStep(round int, step RoundStepType) {
+	if cs.Round != round || cs.Step != step {
+		cs.metrics.stepChanged(cs.Step)
+		cs.stepStart[step] = time.Now()
+	}
 	cs.Round = round
 	cs.Step = step
+	cs.signBytesCache.Reset(cs.Height, uint64(round))
+}
+
+// observeStep feeds the time spent in a step of the current round, which
+// completed before its timeout, to the adaptive timeouts.
+func (cs *ConsensusState) observeStep(step RoundStepType) {
+	if start, ok := cs.stepStart[step]; ok {
+		cs.timeoutParams.Observe(step, time.Since(start))
+	}
 }
 
 // enterNewRound(height, 0) at cs.StartTime.
@@ -716,6 +853,7 @@ func (cs *ConsensusState) newStep() {
 	rs := cs.RoundStateEvent()
 
 	cs.nSteps += 1
+	cs.logWALError(cs.wal.saveStep(cs.Height, cs.Round, cs.Step))
 	// newStep is called by updateToStep in NewConsensusState before the evsw is set!
 	if cs.evsw != nil {
 		types.FireEventNewRoundStep(cs.evsw, rs)
@@ -745,17 +883,25 @@ func (cs *ConsensusState) receiveRoutine(maxSteps int) {
 		case mi = <-cs.peerMsgQueue:
 			// handles proposals, block parts, votes
 			// may generate internal events (votes, complete proposals, 2/3 majorities)
+			cs.logWALError(cs.wal.saveMsg(mi))
 			rs := cs.RoundState
 			cs.handleMsg(mi, rs)
 		case mi = <-cs.internalMsgQueue:
 			// handles proposals, block parts, votes
+			cs.logWALError(cs.wal.saveMsg(mi))
 			rs := cs.RoundState
 			cs.handleMsg(mi, rs)
+		case result := <-cs.voteVerifier.results:
+			// a vote of a peer whose signature was verified by the pool
+			cs.handleVerifiedVote(result)
 		case ti := <-cs.timeoutTicker.Chan(): // tockChan:
 			// if the timeout is relevant to the rs
 			// go to the next step
+			cs.logWALError(cs.wal.saveTimeout(ti))
 			rs := cs.RoundState
 			cs.handleTimeout(ti, rs)
+		case height := <-cs.txsAvailable:
+			cs.handleTxsAvailable(height)
 		case <-cs.Quit:
 			close(cs.done)
 			return
@@ -763,6 +909,12 @@ func (cs *ConsensusState) receiveRoutine(maxSteps int) {
 	}
 }
 
+func (cs *ConsensusState) logWALError(err error) {
+	if err != nil {
+		cs.logger.Error("Failed to write consensus WAL", "err", err)
+	}
+}
+
 // state transitions on complete-proposal, 2/3-any, 2/3-one
 func (cs *ConsensusState) handleMsg(mi msgInfo, rs RoundState) {
 	//	cs.mtx.Lock()
@@ -776,13 +928,14 @@ func (cs *ConsensusState) handleMsg(mi msgInfo, rs RoundState) {
 		// once proposal is set, we can receive block parts
 		cs.logger.Debugf("handleMsg: Received proposal message %v", msg.Proposal)
 		cs.mtx.Lock()
+		cs.checkConflictingProposal(msg.Proposal, peerKey)
 		err = cs.setProposal(msg.Proposal)
 		cs.mtx.Unlock()
 	case *BlockPartMessage:
 		// if the proposal is complete, we'll enterPrevote or tryFinalizeCommit
 		cs.logger.Infof("handleMsg. BlockPartMessage: %v", msg)
 		cs.mtx.Lock()
-		_, err = cs.addProposalBlockPart(msg.Height, msg.Round, msg.Part, peerKey != "")
+		_, err = cs.addProposalBlockPart(msg.Height, msg.Round, msg.Part, peerKey)
 		if err != nil && msg.Round != cs.Round {
 			err = nil
 		}
@@ -792,12 +945,27 @@ func (cs *ConsensusState) handleMsg(mi msgInfo, rs RoundState) {
 		cs.mtx.Lock()
 		err = cs.handleSignAggr(msg.Maj23SignAggr)
 		cs.mtx.Unlock()
+		if err == ErrInvalidSignatureAggr && peerKey != "" {
+			cs.misbehavior.invalidAggregate(msg.Maj23SignAggr, peerKey)
+		}
+	case *PartialSignAggrMessage:
+		// Msg carrying the votes the proposer has collected so far
+		cs.mtx.Lock()
+		err = cs.handlePartialSignAggr(msg.PartialSignAggr)
+		cs.mtx.Unlock()
+		if err == ErrInvalidSignatureAggr && peerKey != "" {
+			cs.misbehavior.invalidAggregate(msg.PartialSignAggr, peerKey)
+		}
 	case *VoteMessage:
 		// attempt to add the vote and dupeout the validator if its a duplicate signature
 		// if the vote gives us a 2/3-any or 2/3-one, we transition
 		cs.logger.Infof("handleMsg. VoteMessage: %v", msg)
 		cs.mtx.Lock()
-		err := cs.tryAddVote(msg.Vote, peerKey)
+		if cs.verifyVoteAsync(msg.Vote, peerKey) {
+			cs.mtx.Unlock()
+			return
+		}
+		err := cs.tryAddVote(msg.Vote, peerKey, false)
 		cs.mtx.Unlock()
 		if err == ErrAddingVote {
 			// TODO: punish peer
@@ -806,16 +974,17 @@ func (cs *ConsensusState) handleMsg(mi msgInfo, rs RoundState) {
 		// NOTE: the vote is broadcast to peers by the reactor listening
 		// for vote events
 	default:
-		cs.logger.Warnf("handleMsg. Unknown msg type %v", reflect.TypeOf(msg))
+		cs.logger.Warn("Unknown msg type", cs.logCtx("type", reflect.TypeOf(msg), "peer", peerKey)...)
 	}
 
 	if err != nil {
-		cs.logger.Errorf("handleMsg. msg: %v, error: %v", msg, err)
+		cs.logger.Error("Failed to handle msg", cs.logCtx("msg", msg, "peer", peerKey, "err", err)...)
 	}
 }
 
 func (cs *ConsensusState) handleTimeout(ti timeoutInfo, rs RoundState) {
-	cs.logger.Infof("Received tock. timeout: %v, (%v/%v/%v), Current: (%v/%v/%v)", ti.Duration, ti.Height, ti.Round, ti.Step, rs.Height, rs.Round, rs.Step)
+	cs.logger.Info("Received tock", "height", rs.Height, "round", rs.Round, "step", rs.Step,
+		"timeout", ti.Duration, "timeoutHeight", ti.Height, "timeoutRound", ti.Round, "timeoutStep", ti.Step)
 
 	// timeouts must be for current height, round, step
 	if ti.Height != rs.Height || ti.Round < rs.Round || (ti.Round == rs.Round && ti.Step < rs.Step) {
@@ -827,12 +996,16 @@ func (cs *ConsensusState) handleTimeout(ti timeoutInfo, rs RoundState) {
 	cs.mtx.Lock()
 	defer cs.mtx.Unlock()
 
+	cs.metrics.timeout(ti.Step)
 	cs.logger.Debugf("step is :%+v", ti.Step)
 	switch ti.Step {
 	case RoundStepNewHeight:
 		// NewRound event fired from enterNewRound.
 		// XXX: should we fire timeout here (for timeout commit)?
 		cs.enterNewRound(ti.Height, 0)
+	case RoundStepNewRound:
+		// The minimum block interval or the heartbeat elapsed
+		cs.enterPropose0(ti.Height)
 	case RoundStepWaitForMinerBlock:
 		types.FireEventTimeoutPropose(cs.evsw, cs.RoundStateEvent())
 		if cs.blockFromMiner != nil {
@@ -864,7 +1037,7 @@ func (cs *ConsensusState) handleTimeout(ti timeoutInfo, rs RoundState) {
 // NOTE: cs.StartTime was already set for height.
 func (cs *ConsensusState) enterNewRound(height uint64, round int) {
 	if cs.Height != height || round < cs.Round || (cs.Round == round && cs.Step != RoundStepNewHeight) {
-		cs.logger.Warnf("enterNewRound(%v/%v): Invalid args. Current step: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+		cs.logger.Warn("enterNewRound: invalid args", cs.logCtx("targetHeight", height, "targetRound", round)...)
 		return
 	}
 
@@ -872,7 +1045,7 @@ func (cs *ConsensusState) enterNewRound(height uint64, round int) {
 		cs.logger.Warn("Need to set a buffer and log.Warn() here for sanity.", "startTime", cs.StartTime, "now", now)
 	}
 
-	cs.logger.Infof("enterNewRound(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+	cs.logger.Info("enterNewRound", cs.logCtx("targetHeight", height, "targetRound", round)...)
 	cs.logger.Infof("Validators: %v", cs.Validators)
 
 	// Setup new round
@@ -894,8 +1067,50 @@ func (cs *ConsensusState) enterNewRound(height uint64, round int) {
 	cs.VoteSignAggr.SetRound(round + 1) // also track next round (round+1) to allow round-skipping
 	cs.Votes.SetRound(round + 1)
 	cs.pastRoundStates[round] = ROUND_NOT_PROPOSED
+	cs.metrics.newRound()
+	cs.gossipSpan = nil
+	cs.voteSpans = make(map[byte]*tracing.Span)
+	cs.ownVotes = make(map[byte]*types.Vote)
+	cs.partialSignAggrs = make(map[byte]*types.SignAggr)
 	types.FireEventNewRound(cs.evsw, cs.RoundStateEvent())
 
+	if round == 0 {
+		cs.enterPropose0(height)
+		return
+	}
+	cs.enterProposeOrWaitForMinerBlock(height, round)
+}
+
+// enterPropose0 enters the propose step of round 0, unless the minimum block
+// interval has not elapsed yet or, skipping empty blocks, there is nothing to
+// propose.
+func (cs *ConsensusState) enterPropose0(height uint64) {
+	if cs.waitForBlockInterval(height) || cs.waitForTxs(height) {
+		return
+	}
+	cs.enterProposeOrWaitForMinerBlock(height, 0)
+}
+
+// waitForBlockInterval holds back round 0 of a height until the minimum block
+// interval after the parent block elapsed.
+func (cs *ConsensusState) waitForBlockInterval(height uint64) bool {
+	if cs.timeoutParams.MinBlockInterval() == 0 || cs.state.NcExtra.Time.IsZero() {
+		return false
+	}
+	wait := cs.timeoutParams.MinBlockInterval() - time.Since(cs.state.NcExtra.Time)
+	if wait <= 0 {
+		return false
+	}
+	cs.logger.Debug("Waiting for the minimum block interval", cs.logCtx("wait", wait)...)
+	cs.scheduleTimeout(wait, height, 0, RoundStepNewRound)
+	return true
+}
+
+// enterProposeOrWaitForMinerBlock enters the propose step, unless we are the
+// proposer and still wait for the block of the miner.
+func (cs *ConsensusState) enterProposeOrWaitForMinerBlock(height uint64, round int) {
+	cs.waitingForTxs = false
+
 	// Immediately go to enterPropose.
 	if cs.IsProposer() && (cs.blockFromMiner == nil || cs.Height != cs.blockFromMiner.NumberU64()) {
 
@@ -912,13 +1127,64 @@ func (cs *ConsensusState) enterNewRound(height uint64, round int) {
 	cs.enterPropose(height, round)
 }
 
+// waitForTxs holds back round 0 of a height while the miner has no transactions
+// for it, until they arrive or the heartbeat after the last block elapses.
+func (cs *ConsensusState) waitForTxs(height uint64) bool {
+	if !cs.timeoutParams.SkipEmptyBlocks || cs.minerBlockHasTxs(height) {
+		return false
+	}
+	last := cs.GetChainReader().CurrentHeader()
+	wait := cs.timeoutParams.Heartbeat() - time.Since(time.Unix(last.Time.Int64(), 0))
+	if wait <= 0 {
+		return false
+	}
+	cs.logger.Info("Waiting for transactions", cs.logCtx("heartbeat", wait)...)
+	cs.waitingForTxs = true
+	cs.scheduleTimeout(wait, height, 0, RoundStepNewRound)
+	return true
+}
+
+func (cs *ConsensusState) minerBlockHasTxs(height uint64) bool {
+	block := cs.blockFromMiner
+	return block != nil && block.NumberU64() == height && len(block.Transactions()) > 0
+}
+
+// NotifyTxsAvailable tells the consensus that the miner built a block with
+// transactions for the given height.
+func (cs *ConsensusState) NotifyTxsAvailable(height uint64) {
+	if !cs.timeoutParams.SkipEmptyBlocks {
+		return
+	}
+	select {
+	case cs.txsAvailable <- height:
+	default:
+	}
+}
+
+// SkipEmptyBlocks reports whether blocks are only produced for transactions or
+// on the heartbeat.
+func (cs *ConsensusState) SkipEmptyBlocks() bool {
+	return cs.timeoutParams.SkipEmptyBlocks
+}
+
+func (cs *ConsensusState) handleTxsAvailable(height uint64) {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	if !cs.waitingForTxs || cs.Height != height || cs.Round != 0 || cs.Step != RoundStepNewRound {
+		return
+	}
+	cs.logger.Info("Transactions available, propose", cs.logCtx()...)
+	cs.enterProposeOrWaitForMinerBlock(height, 0)
+}
+
 func (cs *ConsensusState) enterLowerRound(height uint64, round int) {
 
 	if now := time.Now(); cs.StartTime.After(now) {
 		cs.logger.Warn("Need to set a buffer and log.Warn() here for sanity.", "startTime", cs.StartTime, "now", now)
 	}
 
-	cs.logger.Infof("enterLowerRound(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+	cs.logger.Info("enterLowerRound", cs.logCtx("targetHeight", height, "targetRound", round)...)
 	cs.logger.Infof("Validators: %v", cs.Validators)
 
 	//clear all heigher round state
@@ -964,10 +1230,10 @@ func (cs *ConsensusState) enterLowerRound(height uint64, round int) {
 // Enter: from NewRound(height,round).
 func (cs *ConsensusState) enterPropose(height uint64, round int) {
 	if cs.Height != height || round < cs.Round || (cs.Round == round && RoundStepPropose <= cs.Step) {
-		cs.logger.Warnf("enterPropose(%v/%v): Invalid args. Current step: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+		cs.logger.Warn("enterPropose: invalid args", cs.logCtx("targetHeight", height, "targetRound", round)...)
 		return
 	}
-	cs.logger.Infof("enterPropose(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+	cs.logger.Info("enterPropose", cs.logCtx("targetHeight", height, "targetRound", round)...)
 
 	defer func() {
 
@@ -1034,10 +1300,16 @@ func (cs *ConsensusState) defaultDecideProposal(height uint64, round int) {
 		block, blockParts = cs.LockedBlock, cs.LockedBlockParts
 	} else {
 		// Create a new proposal block from state/txs from the mempool.
+		span := cs.startSpan("proposal.create")
 		block, blockParts = cs.createProposalBlock()
 		if block == nil { // on error
+			span.SetError(errors.New("no proposal block"))
+			span.End()
 			return
 		}
+		span.SetAttribute("txs", len(block.Block.Transactions()))
+		span.SetAttribute("parts", blockParts.Total())
+		span.End()
 	}
 
 	// Make proposal
@@ -1055,7 +1327,10 @@ func (cs *ConsensusState) defaultDecideProposal(height uint64, round int) {
 	err := cs.privValidator.SignProposal(cs.state.NcExtra.ChainID, proposal)
 	if err == nil {
 
-		cs.logger.Infof("Signed proposal block, height: %v", block.NcExtra.Height)
+		cs.logger.Info("Signed proposal block", cs.logCtx("hash", block.Hash())...)
+		cs.metrics.votesRequested(types.VoteTypePrevote)
+		cs.propagation.proposalCreated(height, round)
+		cs.voteSpans[types.VoteTypePrevote] = cs.startSpan("votes.prevote")
 		// send proposal and block parts on internal msg queue
 		cs.sendInternalMessage(msgInfo{&ProposalMessage{proposal}, ""})
 		for i := 0; i < blockParts.Total(); i++ {
@@ -1153,8 +1428,8 @@ func (cs *ConsensusState) createProposalBlock() (*types.TdmBlock, *types.PartSet
 		}
 
 		return types.MakeBlock(cs.Height, cs.state.NcExtra.ChainID, commit, neatBlock,
-			val.Hash(), cs.Epoch.Number, epochBytes,
-			tx3ProofData, 65536)
+			cs.signBytesCache.ValidatorsHash(val), cs.Epoch.Number, epochBytes,
+			tx3ProofData, types.BlockPartSizeBytes)
 	} else {
 		cs.logger.Warn("block from miner should not be nil, let's start another round")
 		return nil, nil
@@ -1168,7 +1443,7 @@ func (cs *ConsensusState) createProposalBlock() (*types.TdmBlock, *types.PartSet
 // Otherwise vote nil.
 func (cs *ConsensusState) enterPrevote(height uint64, round int) {
 	if cs.Height != height || round < cs.Round || (cs.Round == round && RoundStepPrevoteWait < cs.Step) {
-		cs.logger.Warnf("enterPrevote(%v/%v): Invalid args. Current step: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+		cs.logger.Warn("enterPrevote: invalid args", cs.logCtx("targetHeight", height, "targetRound", round)...)
 		return
 	}
 
@@ -1183,15 +1458,34 @@ func (cs *ConsensusState) enterPrevote(height uint64, round int) {
 		cs.enterPrevoteWait(height, round)
 	}()
 
-	cs.logger.Infof("enterPrevote(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+	cs.logger.Info("enterPrevote", cs.logCtx("targetHeight", height, "targetRound", round)...)
 
 	// Sign and broadcast vote as necessary
 	if cs.isProposalComplete() {
 		cs.doPrevote(height, round)
+	} else if cs.Step == RoundStepPropose {
+		cs.metrics.proposalMissed()
 	}
 
 }
 
+// validateProposalTime checks the time of the proposal block from the block
+// time fork: it must be after the parent block and, for a new proposal, close
+// to the local time. A block proposed again, with a POL round or locked on,
+// keeps the time of its first proposal; checking it against the local clock
+// would leave the validators locked on it alone prevoting for it.
+func (cs *ConsensusState) validateProposalTime(now time.Time) error {
+	block := cs.ProposalBlock
+	if !cs.chainConfig.IsBlockTime(block.Block.Number()) {
+		return nil
+	}
+	maxDrift := cs.timeoutParams.MaxTimeDrift()
+	if (cs.Proposal != nil && cs.Proposal.POLRound >= 0) || cs.LockedBlock.HashesTo(block.Hash()) {
+		maxDrift = 0
+	}
+	return block.ValidateTime(cs.state.NcExtra, now, maxDrift)
+}
+
 func (cs *ConsensusState) defaultDoPrevote(height uint64, round int) {
 	// If a block is locked, prevote that.
 	if cs.LockedBlock != nil {
@@ -1208,10 +1502,17 @@ func (cs *ConsensusState) defaultDoPrevote(height uint64, round int) {
 	}
 
 	// Validate proposal block
-	err := cs.ProposalBlock.ValidateBasic(cs.state.NcExtra)
+	err := cs.ProposalBlock.ValidateBasic(cs.state.NcExtra, cs.timeoutParams.MinBlockInterval())
 	if err != nil {
 		// ProposalBlock is invalid, prevote nil.
-		cs.logger.Warnf("enterPrevote: ProposalBlock is invalid, error: %v", err)
+		cs.logger.Warn("enterPrevote: ProposalBlock is invalid", cs.logCtx("err", err)...)
+		cs.signAddVote(types.VoteTypePrevote, nil, types.PartSetHeader{})
+		return
+	}
+	err = cs.validateProposalTime(time.Now())
+	if err != nil {
+		// ProposalBlock time is out of the window, prevote nil.
+		cs.logger.Warn("enterPrevote: ProposalBlock time is invalid", cs.logCtx("err", err)...)
 		cs.signAddVote(types.VoteTypePrevote, nil, types.PartSetHeader{})
 		return
 	}
@@ -1220,7 +1521,7 @@ func (cs *ConsensusState) defaultDoPrevote(height uint64, round int) {
 	err = cs.ValidateTX4(cs.ProposalBlock)
 	if err != nil {
 		// ProposalBlock is invalid, prevote nil.
-		cs.logger.Warnf("enterPrevote: ProposalBlock is invalid, error: %v", err)
+		cs.logger.Warn("enterPrevote: ProposalBlock is invalid", cs.logCtx("err", err)...)
 		cs.signAddVote(types.VoteTypePrevote, nil, types.PartSetHeader{})
 		return
 	}
@@ -1229,10 +1530,14 @@ func (cs *ConsensusState) defaultDoPrevote(height uint64, round int) {
 	if !cs.IsProposer() {
 		if cv, ok := cs.backend.ChainReader().(consss.ChainValidator); ok {
 			cs.logger.Info("enterPrevote: Validate/Execute Block")
+			span := cs.startSpan("block.execute")
+			span.SetAttribute("txs", len(cs.ProposalBlock.Block.Transactions()))
 			state, receipts, ops, err := cv.ValidateBlock(cs.ProposalBlock.Block)
+			span.SetError(err)
+			span.End()
 			if err != nil {
 				// ProposalBlock is invalid, prevote nil.
-				cs.logger.Warnf("enterPrevote: ValidateBlock fail, error: %v", err)
+				cs.logger.Warn("enterPrevote: ValidateBlock fail", cs.logCtx("err", err)...)
 				cs.signAddVote(types.VoteTypePrevote, nil, types.PartSetHeader{})
 				return
 			}
@@ -1254,7 +1559,7 @@ func (cs *ConsensusState) defaultDoPrevote(height uint64, round int) {
 			err = cs.Epoch.ValidateNextEpoch(proposedNextEpoch, lastHeight, lastBlockTime)
 			if err != nil {
 				// ProposalBlock is invalid, prevote nil.
-				cs.logger.Warnf("enterPrevote: Proposal Next Epoch is invalid, error: %v", err)
+				cs.logger.Warn("enterPrevote: Proposal Next Epoch is invalid", cs.logCtx("err", err)...)
 				cs.signAddVote(types.VoteTypePrevote, nil, types.PartSetHeader{})
 				return
 			}
@@ -1271,11 +1576,11 @@ func (cs *ConsensusState) defaultDoPrevote(height uint64, round int) {
 // In NeatPoS, wait for 2/3 votes for prevote
 func (cs *ConsensusState) enterPrevoteWait(height uint64, round int) {
 	if cs.Height != height || round < cs.Round || (cs.Round == round && RoundStepPrevoteWait <= cs.Step) {
-		cs.logger.Warnf("enterPrevoteWait(%v/%v): Invalid args. Current step: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+		cs.logger.Warn("enterPrevoteWait: invalid args", cs.logCtx("targetHeight", height, "targetRound", round)...)
 		return
 	}
 
-	cs.logger.Infof("enterPrevoteWait(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+	cs.logger.Info("enterPrevoteWait", cs.logCtx("targetHeight", height, "targetRound", round)...)
 
 	defer func() {
 		// Done enterPrevoteWait:
@@ -1290,11 +1595,11 @@ func (cs *ConsensusState) enterPrevoteWait(height uint64, round int) {
 // In NeatPoS, when prevote round ends, enter to vote for precommit
 func (cs *ConsensusState) enterPrecommit(height uint64, round int) {
 	if cs.Height != height || round < cs.Round || (cs.Round == round && RoundStepPrecommit <= cs.Step) {
-		cs.logger.Warnf("enterPrecommit(%v/%v): Invalid args. Current step: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+		cs.logger.Warn("enterPrecommit: invalid args", cs.logCtx("targetHeight", height, "targetRound", round)...)
 		return
 	}
 
-	cs.logger.Infof("enterPrecommit(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+	cs.logger.Info("enterPrecommit", cs.logCtx("targetHeight", height, "targetRound", round)...)
 
 	defer func() {
 		// Done enterPrecommit:
@@ -1361,8 +1666,9 @@ func (cs *ConsensusState) enterPrecommit(height uint64, round int) {
 	// If +2/3 prevoted for proposal block, stage and precommit it
 	if cs.ProposalBlock.HashesTo(blockID.Hash) {
 		cs.logger.Info("enterPrecommit: +2/3 prevoted proposal block. Locking", "hash", blockID.Hash)
-		// Validate the block.
-		if err := cs.ProposalBlock.ValidateBasic(cs.state.NcExtra); err != nil {
+		// Validate the block. The minimum block interval is enforced by the
+		// prevotes, +2/3 agreed on it already.
+		if err := cs.ProposalBlock.ValidateBasic(cs.state.NcExtra, 0); err != nil {
 			PanicConsensus(Fmt("enterPrecommit: +2/3 prevoted for an invalid block: %v", err))
 		}
 		cs.LockedRound = round
@@ -1392,7 +1698,7 @@ func (cs *ConsensusState) enterPrecommit(height uint64, round int) {
 // In NeatPoS, wait for 2/3 votes for precommit
 func (cs *ConsensusState) enterPrecommitWait(height uint64, round int) {
 	if cs.Height != height || round < cs.Round || (cs.Round == round && RoundStepPrecommitWait <= cs.Step) {
-		cs.logger.Warnf("enterPrecommitWait(%v/%v): Invalid args. Current step: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+		cs.logger.Warn("enterPrecommitWait: invalid args", cs.logCtx("targetHeight", height, "targetRound", round)...)
 		return
 	}
 	/*
@@ -1401,7 +1707,7 @@ func (cs *ConsensusState) enterPrecommitWait(height uint64, round int) {
 			PanicSanity(Fmt("enterPrecommitWait(%v/%v), but Precommits does not have any +2/3 votes", height, round))
 		}
 	*/
-	cs.logger.Infof("enterPrecommitWait(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step)
+	cs.logger.Info("enterPrecommitWait", cs.logCtx("targetHeight", height, "targetRound", round)...)
 
 	defer func() {
 		// Done enterPrecommitWait:
@@ -1417,10 +1723,10 @@ func (cs *ConsensusState) enterPrecommitWait(height uint64, round int) {
 // Enter: +2/3 precommits for block
 func (cs *ConsensusState) enterCommit(height uint64, commitRound int) {
 	if cs.Height != height || RoundStepCommit <= cs.Step {
-		cs.logger.Warnf("enterCommit(%v/%v): Invalid args. Current step: %v/%v/%v", height, commitRound, cs.Height, cs.Round, cs.Step)
+		cs.logger.Warn("enterCommit: invalid args", cs.logCtx("targetHeight", height, "targetRound", commitRound)...)
 		return
 	}
-	cs.logger.Infof("enterCommit(%v/%v). Current: %v/%v/%v", height, commitRound, cs.Height, cs.Round, cs.Step)
+	cs.logger.Info("enterCommit", cs.logCtx("targetHeight", height, "targetRound", commitRound)...)
 
 	defer func() {
 		// Done enterCommit:
@@ -1481,14 +1787,14 @@ func (cs *ConsensusState) tryFinalizeCommit(height uint64) {
 		return
 	}
 	//	go
-	cs.logger.Infof("do finalizeCommit at height: %v", height)
+	cs.logger.Info("finalizeCommit", cs.logCtx("targetHeight", height)...)
 	cs.finalizeCommit(height)
 }
 
 // Increment height and goto RoundStepNewHeight
 func (cs *ConsensusState) finalizeCommit(height uint64) {
 	if cs.Height != height || cs.Step != RoundStepCommit {
-		cs.logger.Warnf("finalizeCommit(%v): Invalid args. Current step: %v/%v/%v", height, cs.Height, cs.Round, cs.Step)
+		cs.logger.Warn("finalizeCommit: invalid args", cs.logCtx("targetHeight", height)...)
 		return
 	}
 
@@ -1505,7 +1811,7 @@ func (cs *ConsensusState) finalizeCommit(height uint64) {
 	if !block.HashesTo(blockID.Hash) {
 		PanicSanity(Fmt("Cannot finalizeCommit, ProposalBlock does not hash to commit hash"))
 	}
-	if err := block.ValidateBasic(cs.state.NcExtra); err != nil {
+	if err := block.ValidateBasic(cs.state.NcExtra, 0); err != nil {
 		PanicConsensus(Fmt("+2/3 committed an invalid block: %v", err))
 	}
 
@@ -1525,7 +1831,7 @@ func (cs *ConsensusState) finalizeCommit(height uint64) {
 			// check epoch
 			if len(block.NcExtra.EpochBytes) > 0 {
 				block.NcExtra.NeedToSave = true
-				cs.logger.Infof("NeedToSave set to true due to epoch. Chain: %s, Height: %v", block.NcExtra.ChainID, block.NcExtra.Height)
+				cs.logger.Info("NeedToSave set to true due to epoch", "height", block.NcExtra.Height)
 			}
 			// check special cross-chain tx
 			txs := block.Block.Transactions()
@@ -1539,22 +1845,28 @@ func (cs *ConsensusState) finalizeCommit(height uint64) {
 
 					if function == neatabi.WithdrawFromSideChain {
 						block.NcExtra.NeedToBroadcast = true
-						cs.logger.Infof("NeedToBroadcast set to true due to tx. Tx: %s, Chain: %s, Height: %v", function.String(), block.NcExtra.ChainID, block.NcExtra.Height)
+						cs.logger.Info("NeedToBroadcast set to true due to tx", "tx", function.String(), "height", block.NcExtra.Height)
 						break
 					}
 				}
 			}
 		}
 
+		cs.metrics.committed(cs.CommitRound)
+
 		// Fire event for new block.
 		types.FireEventNewBlock(cs.evsw, types.EventDataNewBlock{block})
 		types.FireEventNewBlockHeader(cs.evsw, types.EventDataNewBlockHeader{int(block.NcExtra.Height)})
 
 		//the second parameter as signature has been set above
+		span := cs.startSpan("block.commit")
 		err := cs.backend.Commit(block, [][]byte{}, cs.IsProposer)
+		span.SetError(err)
+		span.End()
 		if err != nil {
-			cs.logger.Errorf("Commit fail. error: %v", err)
+			cs.logger.Error("Commit fail", cs.logCtx("hash", block.Hash(), "err", err)...)
 		}
+		cs.logWALError(cs.wal.saveEndHeight(block.NcExtra.Height))
 	} else {
 		cs.logger.Warn("Calling finalizeCommit on already stored block", "height", block.NcExtra.Height)
 	}
@@ -1562,6 +1874,23 @@ func (cs *ConsensusState) finalizeCommit(height uint64) {
 	return
 }
 
+// checkConflictingProposal reports a proposal for the round of the current
+// proposal which differs from it, but is signed by the same proposer.
+func (cs *ConsensusState) checkConflictingProposal(proposal *types.Proposal, peerKey string) {
+	current := cs.Proposal
+	if current == nil || proposal.Height != current.Height || proposal.Round != current.Round || proposal.Round != cs.Round {
+		return
+	}
+	if bytes.Equal(proposal.Hash, current.Hash) && proposal.BlockPartsHeader.Equals(current.BlockPartsHeader) {
+		return
+	}
+	proposer := cs.GetProposer()
+	if !types.VerifyBLS(proposer.PubKey, types.SignBytes(cs.chainConfig.NeatChainId, proposal), proposal.Signature) {
+		return
+	}
+	cs.misbehavior.conflictingProposal(proposer.Address, current, proposal, peerKey)
+}
+
 //-----------------------------------------------------------------------------
 func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
 	// Already have one
@@ -1589,7 +1918,7 @@ func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
 	if proposal.Round == cs.Round {
 
 		// Verify signature
-		if !cs.GetProposer().PubKey.VerifyBytes(types.SignBytes(cs.chainConfig.NeatChainId, proposal), proposal.Signature) {
+		if !types.VerifyBLS(cs.GetProposer().PubKey, types.SignBytes(cs.chainConfig.NeatChainId, proposal), proposal.Signature) {
 			fmt.Printf("default set proposal1, proposal.Round %v, cs.Round %v\n", proposal.Round, cs.Round)
 			fmt.Printf("default set proposal1, proposal %v, cs %v\n", proposal, cs)
 			fmt.Printf("default set proposal1, proposal.Signature %v\n", proposal.Signature.String())
@@ -1602,7 +1931,7 @@ func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
 	} else /*proposal.Round < cs.Round*/ {
 
 		// Verify signature
-		if !cs.proposerByRound(proposal.Round).Proposer.PubKey.VerifyBytes(types.SignBytes(cs.chainConfig.NeatChainId, proposal), proposal.Signature) {
+		if !types.VerifyBLS(cs.proposerByRound(proposal.Round).Proposer.PubKey, types.SignBytes(cs.chainConfig.NeatChainId, proposal), proposal.Signature) {
 			fmt.Printf("default set proposal2, proposal.Round %v, cs.Round %v\n", proposal.Round, cs.Round)
 			fmt.Printf("default set proposal2, proposal %v, cs %v\n", proposal, cs)
 			fmt.Printf("default set proposal2, proposal.Signature %v\n", proposal.Signature.String())
@@ -1630,6 +1959,11 @@ func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
 	cs.logger.Debugf("proposal is: %X", proposal.Hash)
 	cs.ProposalBlockParts = types.NewPartSetFromHeader(proposal.BlockPartsHeader)
 	cs.ProposerPeerKey = proposal.ProposerPeerKey
+	cs.gossipSpan = cs.startSpan("proposal.gossip")
+	cs.gossipSpan.SetAttribute("parts", proposal.BlockPartsHeader.Total)
+	if !cs.isProposer {
+		cs.propagation.proposalReceived(proposal.Height, proposal.Round)
+	}
 
 	cs.pastRoundStates[cs.Round] = ROUND_PROPOSED
 
@@ -1638,7 +1972,7 @@ func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
 
 // NOTE: block is not necessarily valid.
 // Asynchronously triggers either enterPrevote (before we timeout of propose) or tryFinalizeCommit, once we have the full block.
-func (cs *ConsensusState) addProposalBlockPart(height uint64, round int, part *types.Part, verify bool) (added bool, err error) {
+func (cs *ConsensusState) addProposalBlockPart(height uint64, round int, part *types.Part, peerKey string) (added bool, err error) {
 
 	if cs.Height != height || cs.Round != round {
 		return false, nil
@@ -1649,7 +1983,7 @@ func (cs *ConsensusState) addProposalBlockPart(height uint64, round int, part *t
 		return false, nil // TODO: bad peer? Return error?
 	}
 
-	added, err = cs.ProposalBlockParts.AddPart(part, verify)
+	added, err = cs.ProposalBlockParts.AddPart(part, peerKey != "")
 	if err != nil {
 		return added, err
 	}
@@ -1657,12 +1991,30 @@ func (cs *ConsensusState) addProposalBlockPart(height uint64, round int, part *t
 		// Added and completed!
 		tdmBlock := &types.TdmBlock{}
 		cs.ProposalBlock, err = tdmBlock.FromBytes(cs.ProposalBlockParts.GetReader())
+		if err == nil && cs.ProposalBlock.Block != nil {
+			// Recover the senders while the block waits for its votes, so that
+			// validating it does not stall on ecrecover
+			block := cs.ProposalBlock.Block
+			core.SenderCacher.RecoverFromBlocks(ethTypes.MakeSigner(cs.chainConfig, block.Number()), []*ethTypes.Block{block})
+		}
+		cs.gossipSpan.SetError(err)
+		cs.gossipSpan.End()
+		cs.gossipSpan = nil
+		if peerKey != "" {
+			cs.propagation.blockReceived(height, round, peerKey)
+			if cs.Step == RoundStepPropose {
+				cs.observeStep(RoundStepPropose)
+			}
+		}
 
 		cs.logger.Infof("Received complete proposal block %v, err %v", cs.ProposalBlock, err)
 
 		// NOTE: it's possible to receive complete proposal blocks for future rounds without having the proposal
 		//log.Info("Received complete proposal block", "height", cs.ProposalBlock.Height, "hash", cs.ProposalBlock.Hash())
-		if RoundStepPropose <= cs.Step && cs.Step <= RoundStepPrevoteWait && cs.isProposalComplete() {
+		if cs.waitingForTxs && cs.Step == RoundStepNewRound {
+			// The proposer had transactions before us, stop waiting
+			cs.enterProposeOrWaitForMinerBlock(height, cs.Round)
+		} else if RoundStepPropose <= cs.Step && cs.Step <= RoundStepPrevoteWait && cs.isProposalComplete() {
 			// Move onto the next step
 			cs.enterPrevote(height, cs.Round)
 		} else if cs.Step == RoundStepCommit {
@@ -1692,7 +2044,7 @@ func (cs *ConsensusState) setMaj23SignAggr(signAggr *types.SignAggr) (error, boo
 	maj23, err := cs.blsVerifySignAggr(signAggr)
 
 	if err != nil || maj23 == false {
-		cs.logger.Warnf("verifyMaj23SignAggr: Invalid signature aggregation, error:%+v, maj23:%+v", err, maj23)
+		cs.logger.Warn("verifyMaj23SignAggr: Invalid signature aggregation", cs.logCtx("maj23", maj23, "err", err)...)
 		cs.logger.Warnf("SignAggr:%+v", signAggr)
 		return ErrInvalidSignatureAggr, false
 	}
@@ -1742,7 +2094,10 @@ func (cs *ConsensusState) setMaj23SignAggr(signAggr *types.SignAggr) (error, boo
 	}
 
 	if signAggr.Type == types.VoteTypePrevote {
-		cs.logger.Infof("setMaj23SignAggr: Received 2/3+ prevotes for block %d, enter precommit", cs.Height)
+		cs.logger.Info("setMaj23SignAggr: Received 2/3+ prevotes, enter precommit", cs.logCtx()...)
+		if cs.Step == RoundStepPrevote || cs.Step == RoundStepPrevoteWait {
+			cs.observeStep(RoundStepPrevote)
+		}
 		if cs.isProposalComplete() {
 			cs.logger.Debugf("receive block:%+v", cs.ProposalBlock)
 			cs.enterPrecommit(cs.Height, cs.Round)
@@ -1754,6 +2109,9 @@ func (cs *ConsensusState) setMaj23SignAggr(signAggr *types.SignAggr) (error, boo
 		}
 	} else if signAggr.Type == types.VoteTypePrecommit {
 		cs.logger.Info(Fmt("setMaj23SignAggr: Received 2/3+ precommits for block %d, enter commit\n", cs.Height))
+		if cs.Step == RoundStepPrecommit || cs.Step == RoundStepPrecommitWait {
+			cs.observeStep(RoundStepPrecommit)
+		}
 
 		// TODO : Shall go to this state?
 		// cs.tryFinalizeCommit(height)
@@ -1849,7 +2207,13 @@ func (cs *ConsensusState) blsVerifySignAggr(signAggr *types.SignAggr) (bool, err
 		Type:    signAggr.Type,
 	}
 
-	if !aggrPubKey.VerifyBytes(types.SignBytes(signAggr.ChainID, vote), signAggr.SignAggr()) {
+	var signBytes []byte
+	if signAggr.ChainID == cs.chainConfig.NeatChainId {
+		signBytes = cs.signBytesCache.VoteSignBytes(vote)
+	} else {
+		signBytes = types.SignBytes(signAggr.ChainID, vote)
+	}
+	if !types.VerifyBLS(aggrPubKey, signBytes, signAggr.SignAggr()) {
 		cs.logger.Info("Invalid aggregate signature")
 		return false, errors.New("Invalid aggregate signature")
 	}
@@ -1858,19 +2222,21 @@ func (cs *ConsensusState) blsVerifySignAggr(signAggr *types.SignAggr) (bool, err
 }
 
 // Attempt to add the vote. if its a duplicate signature, dupeout the validator
-func (cs *ConsensusState) tryAddVote(vote *types.Vote, peerKey string) error {
-	_, err := cs.addVote(vote, peerKey)
+// verified is set if the signature of the vote was already verified.
+func (cs *ConsensusState) tryAddVote(vote *types.Vote, peerKey string, verified bool) error {
+	_, err := cs.addVote(vote, peerKey, verified)
 	if err != nil {
 		// If the vote height is off, we'll just ignore it,
 		// But if it's a conflicting sig, broadcast evidence tx for slashing.
 		// If it's otherwise invalid, punish peer.
 		if err == ErrVoteHeightMismatch {
 			return err
-		} else if _, ok := err.(*types.ErrVoteConflictingVotes); ok {
+		} else if conflict, ok := err.(*types.ErrVoteConflictingVotes); ok {
 			if peerKey == "" {
 				cs.logger.Warn("Found conflicting vote from ourselves. Did you unsafe_reset a validator?", "height", vote.Height, "round", vote.Round, "type", vote.Type)
 				return err
 			}
+			cs.misbehavior.equivocation(conflict, peerKey)
 			return err
 		} else {
 			// Probably an invalid signature. Bad peer.
@@ -1881,9 +2247,49 @@ func (cs *ConsensusState) tryAddVote(vote *types.Vote, peerKey string) error {
 	return nil
 }
 
+// verifyVoteAsync queues the vote of a peer for the verification of its
+// signature by the pool of workers, it is then added by handleVerifiedVote.
+// It returns false if the vote must be added right away: our own votes, the
+// votes which would be ignored or rejected before their signature is
+// verified, and all the votes when the pool is saturated.
+func (cs *ConsensusState) verifyVoteAsync(vote *types.Vote, peerKey string) bool {
+	if peerKey == "" || !cs.IsProposer() || vote.Height != cs.Height || int(vote.Round) != cs.Round {
+		return false
+	}
+	if vote.ValidatorIndex >= uint64(cs.Validators.Size()) {
+		return false
+	}
+	address, val := cs.Validators.GetByIndex(int(vote.ValidatorIndex))
+	if !bytes.Equal(address, vote.ValidatorAddress) {
+		return false
+	}
+	return cs.voteVerifier.submit(voteVerifyJob{
+		vote:      vote,
+		peerKey:   peerKey,
+		pubKey:    val.PubKey,
+		signBytes: cs.signBytesCache.VoteSignBytes(vote),
+	})
+}
+
+// handleVerifiedVote adds a vote verified by the pool of workers.
+func (cs *ConsensusState) handleVerifiedVote(result verifiedVote) {
+	if !result.valid {
+		cs.logger.Warn("Error attempting to add vote", "error", types.ErrVoteInvalidSignature, "peer", result.peerKey)
+		return
+	}
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	// The validator set only changes with the height, which the vote is
+	// checked against before it is added
+	if err := cs.tryAddVote(result.vote, result.peerKey, true); err != nil {
+		cs.logger.Error("Failed to handle msg", cs.logCtx("msg", &VoteMessage{result.vote}, "peer", result.peerKey, "err", err)...)
+	}
+}
+
 //-----------------------------------------------------------------------------
 //only proposer would invoke this function
-func (cs *ConsensusState) addVote(vote *types.Vote, peerKey string) (added bool, err error) {
+func (cs *ConsensusState) addVote(vote *types.Vote, peerKey string, verified bool) (added bool, err error) {
 	cs.logger.Info("addVote", "voteHeight", vote.Height, "voteType", vote.Type, "csHeight", cs.Height)
 
 	if !cs.IsProposer() {
@@ -1907,8 +2313,13 @@ func (cs *ConsensusState) addVote(vote *types.Vote, peerKey string) (added bool,
 		}
 	}
 
-	added, err = cs.Votes.AddVote(vote, peerKey)
+	if verified {
+		added, err = cs.Votes.AddPreverifiedVote(vote, peerKey)
+	} else {
+		added, err = cs.Votes.AddVote(vote, peerKey)
+	}
 	if added {
+		cs.metrics.voteArrived(vote)
 		if vote.Type == types.VoteTypePrevote {
 			// If 2/3+ votes received, send them to other validators
 			if cs.Votes.Prevotes(cs.Round).HasTwoThirdsMajority() {
@@ -1955,6 +2366,8 @@ func (cs *ConsensusState) signAddVote(type_ byte, hash []byte, header types.Part
 			if cs.ProposerPeerKey != "" {
 				v2pMsg := types.EventDataVote2Proposer{vote, cs.ProposerPeerKey}
 				types.FireEventVote2Proposer(cs.evsw, v2pMsg)
+				cs.ownVotes[type_] = vote
+				cs.contributeSignAggr(type_)
 			} else {
 				cs.logger.Warn("sign and vote, Proposer key is nil")
 			}
@@ -2034,6 +2447,15 @@ func (cs *ConsensusState) sendMaj23SignAggr(voteType byte) {
 
 	// send sign aggregate msg on internal msg queue
 	cs.sendInternalMessage(msgInfo{&Maj23SignAggrMessage{signAggr}, ""})
+	if span := cs.voteSpans[voteType]; span != nil {
+		span.SetAttribute("votes", len(sigs))
+		span.End()
+		delete(cs.voteSpans, voteType)
+	}
+	if voteType == types.VoteTypePrevote {
+		cs.metrics.votesRequested(types.VoteTypePrecommit)
+		cs.voteSpans[types.VoteTypePrecommit] = cs.startSpan("votes.precommit")
+	}
 }
 
 //---------------------------------------------------------
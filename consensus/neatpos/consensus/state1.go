@@ -7,6 +7,7 @@ import (
 	ep "github.com/neatlab/neatio/consensus/neatpos/epoch"
 	sm "github.com/neatlab/neatio/consensus/neatpos/state"
 	"github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core/state"
 	"github.com/neatlab/neatio/log"
 	"github.com/neatlab/neatio/params"
 	cmn "github.com/neatlib/common-go"
@@ -130,16 +131,40 @@ func (cs *ConsensusState) UpdateToState(state *sm.State) {
 	_, validators, _ := state.GetValidators()
 	cs.Validators = validators
 	cs.Votes = NewHeightVoteSet(cs.chainConfig.NeatChainId, height, validators, cs.logger)
+	cs.Votes.SetSignBytesCache(cs.signBytesCache)
 	cs.VoteSignAggr = NewHeightVoteSignAggr(cs.chainConfig.NeatChainId, height, validators, cs.logger)
 
 	cs.vrfValIndex = -1
 	cs.pastRoundStates = make(map[int]int)
 
 	cs.state = state
+	cs.updateGovTimeouts()
 
 	cs.newStep()
 }
 
+// updateGovTimeouts takes the timeouts set by governance, if any, from the
+// state of the chain head. The minimum block interval is only set by
+// governance, for all the validators to check the proposals against the same
+// value.
+func (cs *ConsensusState) updateGovTimeouts() {
+	statedb, err := cs.backend.ChainReader().State()
+	if err != nil {
+		cs.logger.Warn("Failed to read the governance timeouts", "error", err)
+		return
+	}
+	if v := statedb.GetGovParam(state.GovParamTimeoutPropose); v != nil {
+		cs.timeoutParams.Propose0 = int(v.Uint64())
+	}
+	if v := statedb.GetGovParam(state.GovParamTimeoutPrevote); v != nil {
+		cs.timeoutParams.Prevote0 = int(v.Uint64())
+	}
+	if v := statedb.GetGovParam(state.GovParamTimeoutPrecommit); v != nil {
+		cs.timeoutParams.Precommit0 = int(v.Uint64())
+	}
+	cs.timeoutParams.MinBlockInterval0 = int(statedb.MinBlockInterval())
+}
+
 // The +2/3 and other Precommit-votes for block at `height`.
 // This Commit comes from block.LastCommit for `height+1`.
 func (cs *ConsensusState) LoadBlock(height uint64) *types.TdmBlock {
@@ -1,10 +1,15 @@
 package consensus
 
 import (
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/neatlab/neatio/common/hexutil"
+	sm "github.com/neatlab/neatio/consensus/neatpos/state"
 	"github.com/neatlab/neatio/consensus/neatpos/types"
+	ethTypes "github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/params"
 	"github.com/neatlib/crypto-go"
 )
 
@@ -25,3 +30,45 @@ func TestVerifyBytes(t *testing.T) {
 
 	//testProposal = types.NewProposal(uint64(675224), int(4))
 }
+
+func TestValidateProposalTime(t *testing.T) {
+	parentTime := time.Unix(1600000000, 0)
+	blockTime := parentTime.Add(time.Second)
+	block := &types.TdmBlock{
+		Block:   ethTypes.NewBlockWithHeader(&ethTypes.Header{Number: big.NewInt(11)}),
+		NcExtra: &types.NeatconExtra{ChainID: "neatio", Height: 11, Time: blockTime},
+	}
+	cs := &ConsensusState{
+		chainConfig:   &params.ChainConfig{BlockTimeBlock: big.NewInt(0)},
+		state:         &sm.State{NcExtra: &types.NeatconExtra{ChainID: "neatio", Height: 10, Time: parentTime}},
+		timeoutParams: &TimeoutParams{MaxTimeDrift0: 15000},
+	}
+	cs.ProposalBlock = block
+
+	// A new proposal must be close to the local time
+	cs.Proposal = &types.Proposal{Height: 11, Round: 0, POLRound: -1}
+	if err := cs.validateProposalTime(blockTime); err != nil {
+		t.Errorf("new proposal rejected: %v", err)
+	}
+	if err := cs.validateProposalTime(blockTime.Add(time.Minute)); err == nil {
+		t.Error("new proposal drifting from the local time accepted")
+	}
+
+	// The block locked in round 0 and proposed again in round 3 keeps its time
+	cs.Proposal = &types.Proposal{Height: 11, Round: 3, POLRound: 0}
+	if err := cs.validateProposalTime(blockTime.Add(time.Minute)); err != nil {
+		t.Errorf("locked block proposed again rejected: %v", err)
+	}
+
+	// It still has to be after its parent
+	block.NcExtra.Time = parentTime
+	if err := cs.validateProposalTime(blockTime.Add(time.Minute)); err == nil {
+		t.Error("locked block not after its parent accepted")
+	}
+
+	// Nothing is checked before the block time fork
+	cs.chainConfig = &params.ChainConfig{BlockTimeBlock: big.NewInt(12)}
+	if err := cs.validateProposalTime(blockTime.Add(time.Minute)); err != nil {
+		t.Errorf("proposal time checked before the fork: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package consensus
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	adaptiveMinSamples = 10 // Step latencies observed before the timeouts adapt
+	adaptiveMargin     = 2  // Multiple of the median step latency waited for
+)
+
+// adaptiveTimeouts derives the propose, prevote and precommit timeouts from
+// the time the steps take when they complete in time. In round 0 a step times
+// out after adaptiveMargin times its median latency, at least min and at most
+// the configured timeout, so an offline proposer costs little dead time. Every
+// further round of the height multiplies the timeout by backoff, up to max, so
+// a slow network still gets the time it needs.
+type adaptiveTimeouts struct {
+	min     time.Duration
+	max     time.Duration
+	backoff float64
+
+	latencies map[RoundStepType]*latencyWindow
+	mu        sync.Mutex
+}
+
+func newAdaptiveTimeouts(min, max time.Duration, backoff float64) *adaptiveTimeouts {
+	if backoff < 1 {
+		backoff = 1
+	}
+	return &adaptiveTimeouts{
+		min:       min,
+		max:       max,
+		backoff:   backoff,
+		latencies: make(map[RoundStepType]*latencyWindow),
+	}
+}
+
+// observe records the latency of a step which completed before its timeout.
+func (a *adaptiveTimeouts) observe(step RoundStepType, latency time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	window, ok := a.latencies[step]
+	if !ok {
+		window = new(latencyWindow)
+		a.latencies[step] = window
+	}
+	window.add(latency)
+}
+
+// timeout returns the timeout of a step in a round, given the configured
+// timeout of the step in round 0.
+func (a *adaptiveTimeouts) timeout(step RoundStepType, round int, configured time.Duration) time.Duration {
+	base := configured
+
+	a.mu.Lock()
+	if window, ok := a.latencies[step]; ok && window.count >= adaptiveMinSamples {
+		base = adaptiveMargin * window.percentile(0.5)
+	}
+	a.mu.Unlock()
+
+	if base < a.min {
+		base = a.min
+	}
+	if base > configured {
+		base = configured
+	}
+	timeout := float64(base) * math.Pow(a.backoff, float64(round))
+	if timeout > float64(a.max) {
+		return a.max
+	}
+	return time.Duration(timeout)
+}
@@ -0,0 +1,44 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeouts(t *testing.T) {
+	a := newAdaptiveTimeouts(500*time.Millisecond, 10*time.Second, 2)
+	configured := 3 * time.Second
+
+	// Too few samples, the configured timeout applies
+	for i := 0; i < adaptiveMinSamples-1; i++ {
+		a.observe(RoundStepPropose, 400*time.Millisecond)
+	}
+	if have := a.timeout(RoundStepPropose, 0, configured); have != configured {
+		t.Errorf("timeout mismatch before adapting: have %v, want %v", have, configured)
+	}
+	a.observe(RoundStepPropose, 400*time.Millisecond)
+
+	tests := []struct {
+		step  RoundStepType
+		round int
+		want  time.Duration
+	}{
+		{RoundStepPropose, 0, 800 * time.Millisecond},  // Twice the median latency
+		{RoundStepPropose, 1, 1600 * time.Millisecond}, // Backed off
+		{RoundStepPropose, 4, 10 * time.Second},        // Capped at max
+		{RoundStepPrevote, 0, configured},              // Not observed
+	}
+	for i, tt := range tests {
+		if have := a.timeout(tt.step, tt.round, configured); have != tt.want {
+			t.Errorf("test %d: timeout mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+
+	// Fast steps never push the timeout below min
+	for i := 0; i < latencyWindowSize; i++ {
+		a.observe(RoundStepPropose, time.Millisecond)
+	}
+	if have := a.timeout(RoundStepPropose, 0, configured); have != 500*time.Millisecond {
+		t.Errorf("timeout mismatch below min: have %v, want %v", have, 500*time.Millisecond)
+	}
+}
@@ -0,0 +1,101 @@
+package consensus
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+	tmdcrypto "github.com/neatlib/crypto-go"
+)
+
+// The signatures of the votes of the peers are verified by a pool of workers
+// rather than on the goroutine of the consensus state, which would otherwise
+// verify the votes of all the validators one by one while collecting them.
+// The verified votes go back to the receive routine, which adds them to the
+// vote sets without verifying them again.
+
+// voteVerifyJob is a vote to verify with the public key of its validator.
+type voteVerifyJob struct {
+	vote      *types.Vote
+	peerKey   string
+	pubKey    tmdcrypto.PubKey
+	signBytes []byte
+}
+
+// verifiedVote is the result of the verification of a vote.
+type verifiedVote struct {
+	vote    *types.Vote
+	peerKey string
+	valid   bool
+}
+
+type voteVerifier struct {
+	jobs    chan voteVerifyJob
+	results chan verifiedVote
+	workers int
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newVoteVerifier(workers, queueSize int) *voteVerifier {
+	if workers < 1 {
+		workers = 1
+	}
+	return &voteVerifier{
+		jobs:    make(chan voteVerifyJob, queueSize),
+		results: make(chan verifiedVote, queueSize),
+		workers: workers,
+	}
+}
+
+// defaultVoteVerifyWorkers is the number of workers verifying the votes, one
+// per CPU, the verification being CPU bound.
+func defaultVoteVerifyWorkers() int {
+	return runtime.NumCPU()
+}
+
+func (v *voteVerifier) start() {
+	v.quit = make(chan struct{})
+	for i := 0; i < v.workers; i++ {
+		v.wg.Add(1)
+		go v.loop()
+	}
+}
+
+func (v *voteVerifier) stop() {
+	close(v.quit)
+	v.wg.Wait()
+}
+
+// submit queues a vote for verification. It returns false if the queue is
+// full, the vote should then be verified by the caller.
+func (v *voteVerifier) submit(job voteVerifyJob) bool {
+	select {
+	case v.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+func (v *voteVerifier) loop() {
+	defer v.wg.Done()
+	for {
+		select {
+		case job := <-v.jobs:
+			result := verifiedVote{
+				vote:    job.vote,
+				peerKey: job.peerKey,
+				valid:   types.ValidateBLSPubKey(job.pubKey) == nil && types.VerifyBLS(job.pubKey, job.signBytes, job.vote.Signature),
+			}
+			select {
+			case v.results <- result:
+			case <-v.quit:
+				return
+			}
+		case <-v.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package consensus
+
+import (
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+)
+
+func TestVoteVerifier(t *testing.T) {
+	key := types.GenPrivValidatorKey(common.Address{1}).PrivKey
+	vote := &types.Vote{Height: 1, Type: types.VoteTypePrevote}
+	signBytes := types.SignBytes("neatio", vote)
+	vote.Signature = key.Sign(signBytes)
+	forged := &types.Vote{Height: 1, Type: types.VoteTypePrevote, Signature: types.GenPrivValidatorKey(common.Address{2}).PrivKey.Sign(signBytes)}
+
+	v := newVoteVerifier(2, 1)
+	v.start()
+	defer v.stop()
+
+	for _, job := range []voteVerifyJob{
+		{vote: vote, peerKey: "a", pubKey: key.PubKey(), signBytes: signBytes},
+		{vote: forged, peerKey: "b", pubKey: key.PubKey(), signBytes: signBytes},
+	} {
+		if !v.submit(job) {
+			t.Fatal("job not queued")
+		}
+		result := <-v.results
+		if result.vote != job.vote || result.peerKey != job.peerKey || result.valid != (job.vote == vote) {
+			t.Errorf("unexpected result %+v of the vote of %s", result, job.peerKey)
+		}
+	}
+
+	// The queue being full, the caller verifies the vote itself
+	full := newVoteVerifier(1, 1)
+	job := voteVerifyJob{vote: vote, pubKey: key.PubKey(), signBytes: signBytes}
+	if !full.submit(job) || full.submit(job) {
+		t.Error("jobs not queued up to the size of the queue")
+	}
+}
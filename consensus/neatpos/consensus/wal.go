@@ -0,0 +1,243 @@
+package consensus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/neatlib/wire-go"
+)
+
+// Kinds of records in the consensus WAL.
+const (
+	WALRecordMsg       = "msg"        // Proposal, block part, vote or signature aggregation handled
+	WALRecordTimeout   = "timeout"    // Timeout handled
+	WALRecordStep      = "step"       // Step entered
+	WALRecordEndHeight = "end_height" // Block of the height committed
+)
+
+const (
+	walMaxRecordSize = 2 * maxConsensusMessageSize
+	walMaxFileSize   = 64 * 1024 * 1024 // Size above which the WAL is rotated at the end of a height
+)
+
+// WALRecord is an input or transition of the consensus state, in the order
+// the state handled them.
+type WALRecord struct {
+	Time     time.Time     `json:"time"`
+	Type     string        `json:"type"`
+	Height   uint64        `json:"height"`
+	Round    int           `json:"round"`
+	Step     RoundStepType `json:"step,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"` // Of a timeout
+	PeerKey  string        `json:"peer,omitempty"`     // Sender of a message, empty if it is our own
+	Msg      []byte        `json:"msg,omitempty"`      // Wire encoded consensus message
+}
+
+// Message decodes the consensus message of a msg record.
+func (r *WALRecord) Message() (ConsensusMessage, error) {
+	if r.Type != WALRecordMsg || len(r.Msg) == 0 {
+		return nil, errors.New("not a message record")
+	}
+	_, msg, err := DecodeMessage(r.Msg)
+	return msg, err
+}
+
+// WAL is a write ahead log of the consensus state, for debugging halts after
+// the fact. Records are framed as crc32 (4 bytes) || length (4 bytes) || JSON.
+// A nil WAL records nothing.
+type WAL struct {
+	path  string
+	light bool // Leave out block parts, which make up most of the log
+
+	file *os.File
+	size int64
+	mu   sync.Mutex
+}
+
+// OpenWAL opens the WAL at path for appending, creating it if necessary.
+func OpenWAL(path string, light bool) (*WAL, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	wal := &WAL{path: path, light: light}
+	if err := wal.open(); err != nil {
+		return nil, err
+	}
+	return wal, nil
+}
+
+func (wal *WAL) open() error {
+	file, err := os.OpenFile(wal.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	wal.file, wal.size = file, info.Size()
+	return nil
+}
+
+// saveMsg records a message about to be handled. Our own messages are synced,
+// so that what we signed is known after a crash.
+func (wal *WAL) saveMsg(mi msgInfo) error {
+	if wal == nil {
+		return nil
+	}
+	record := &WALRecord{Type: WALRecordMsg, PeerKey: mi.PeerKey}
+	switch msg := mi.Msg.(type) {
+	case *ProposalMessage:
+		record.Height, record.Round = msg.Proposal.Height, msg.Proposal.Round
+	case *BlockPartMessage:
+		if wal.light {
+			return nil
+		}
+		record.Height, record.Round = msg.Height, msg.Round
+	case *VoteMessage:
+		record.Height, record.Round = msg.Vote.Height, int(msg.Vote.Round)
+	case *Maj23SignAggrMessage:
+		record.Height, record.Round = msg.Maj23SignAggr.Height, msg.Maj23SignAggr.Round
+	case *PartialSignAggrMessage:
+		record.Height, record.Round = msg.PartialSignAggr.Height, msg.PartialSignAggr.Round
+	}
+	record.Msg = wire.BinaryBytes(struct{ ConsensusMessage }{mi.Msg})
+	return wal.save(record, mi.PeerKey == "")
+}
+
+// saveTimeout records a timeout about to be handled.
+func (wal *WAL) saveTimeout(ti timeoutInfo) error {
+	if wal == nil {
+		return nil
+	}
+	return wal.save(&WALRecord{Type: WALRecordTimeout, Height: ti.Height, Round: ti.Round, Step: ti.Step, Duration: ti.Duration}, false)
+}
+
+// saveStep records the step entered.
+func (wal *WAL) saveStep(height uint64, round int, step RoundStepType) error {
+	if wal == nil {
+		return nil
+	}
+	return wal.save(&WALRecord{Type: WALRecordStep, Height: height, Round: round, Step: step}, false)
+}
+
+// saveEndHeight records the commit of the block of a height, and rotates the
+// WAL once it grew too large.
+func (wal *WAL) saveEndHeight(height uint64) error {
+	if wal == nil {
+		return nil
+	}
+	if err := wal.save(&WALRecord{Type: WALRecordEndHeight, Height: height}, true); err != nil {
+		return err
+	}
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+
+	if wal.size < walMaxFileSize {
+		return nil
+	}
+	wal.file.Close()
+	if err := os.Rename(wal.path, wal.path+".1"); err != nil {
+		return err
+	}
+	return wal.open()
+}
+
+func (wal *WAL) save(record *WALRecord, sync bool) error {
+	record.Time = time.Now()
+	blob, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 8+len(blob))
+	binary.BigEndian.PutUint32(frame, crc32.ChecksumIEEE(blob))
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(blob)))
+	copy(frame[8:], blob)
+
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+
+	n, err := wal.file.Write(frame)
+	wal.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if sync {
+		return wal.file.Sync()
+	}
+	return nil
+}
+
+// Close closes the WAL file.
+func (wal *WAL) Close() error {
+	if wal == nil {
+		return nil
+	}
+	wal.mu.Lock()
+	defer wal.mu.Unlock()
+
+	return wal.file.Close()
+}
+
+// WALCorruptionError is returned by WALDecoder for a record which can not be
+// read back, typically the last one, written when the node crashed.
+type WALCorruptionError struct {
+	Offset int64 // Offset of the record in the WAL
+	Err    error
+}
+
+func (e *WALCorruptionError) Error() string {
+	return fmt.Sprintf("corrupt WAL record at offset %d: %v", e.Offset, e.Err)
+}
+
+// WALDecoder reads the records of a WAL.
+type WALDecoder struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+func NewWALDecoder(r io.Reader) *WALDecoder {
+	return &WALDecoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next record. It returns io.EOF at the end of the WAL and a
+// *WALCorruptionError for a record failing the checks.
+func (dec *WALDecoder) Decode() (*WALRecord, error) {
+	offset := dec.offset
+
+	var header [8]byte
+	if _, err := io.ReadFull(dec.r, header[:]); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, &WALCorruptionError{offset, err}
+	}
+	checksum, length := binary.BigEndian.Uint32(header[:]), binary.BigEndian.Uint32(header[4:])
+	if length > walMaxRecordSize {
+		return nil, &WALCorruptionError{offset, fmt.Errorf("record size %d exceeds %d", length, walMaxRecordSize)}
+	}
+	blob := make([]byte, length)
+	if _, err := io.ReadFull(dec.r, blob); err != nil {
+		return nil, &WALCorruptionError{offset, err}
+	}
+	dec.offset += int64(len(header)) + int64(length)
+
+	if crc32.ChecksumIEEE(blob) != checksum {
+		return nil, &WALCorruptionError{offset, errors.New("checksum mismatch")}
+	}
+	record := new(WALRecord)
+	if err := json.Unmarshal(blob, record); err != nil {
+		return nil, &WALCorruptionError{offset, err}
+	}
+	return record, nil
+}
@@ -0,0 +1,154 @@
+package consensus
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+)
+
+// WALReplay is what the consensus state saw of one height, rebuilt from the
+// WAL. It depends on the WAL and the validator set only, so replaying a WAL
+// taken from a halted node gives the same result anywhere.
+type WALReplay struct {
+	Height    uint64         `json:"height"`
+	Rounds    []*WALRound    `json:"rounds"`
+	Committed *types.BlockID `json:"committed"`         // Block with 2/3+ precommits, nil if none
+	Ended     bool           `json:"ended"`             // Whether the block was committed by this node
+	Invalid   []string       `json:"invalid"`           // Messages failing verification
+	LastStep  *WALStepRecord `json:"lastStep"`          // Last step entered
+	Corrupt   string         `json:"corrupt,omitempty"` // Record the WAL ends with, unreadable
+}
+
+// WALRound is what the consensus state saw of one round.
+type WALRound struct {
+	Round          int             `json:"round"`
+	Proposal       *types.Proposal `json:"proposal"`
+	BlockParts     int             `json:"blockParts"`
+	Prevotes       int             `json:"prevotes"`   // Verified individual votes
+	Precommits     int             `json:"precommits"` // Verified individual votes
+	PrevoteMaj23   *types.BlockID  `json:"prevoteMaj23"`
+	PrecommitMaj23 *types.BlockID  `json:"precommitMaj23"`
+	Timeouts       []string        `json:"timeouts"`
+}
+
+// WALStepRecord is a step of the consensus state.
+type WALStepRecord struct {
+	Round int    `json:"round"`
+	Step  string `json:"step"`
+}
+
+// ReplayWAL replays the records of the given height, verifying the messages
+// against the validator set of the height.
+func ReplayWAL(r io.Reader, height uint64, chainID string, valSet *types.ValidatorSet) (*WALReplay, error) {
+	replay := &WALReplay{Height: height}
+	dec := NewWALDecoder(r)
+	for {
+		record, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if corrupt, ok := err.(*WALCorruptionError); ok {
+			// A torn last record is expected after a crash
+			replay.Corrupt = corrupt.Error()
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Height != height {
+			continue
+		}
+		switch record.Type {
+		case WALRecordStep:
+			replay.LastStep = &WALStepRecord{Round: record.Round, Step: record.Step.String()}
+		case WALRecordTimeout:
+			round := replay.round(record.Round)
+			round.Timeouts = append(round.Timeouts, record.Step.String())
+		case WALRecordEndHeight:
+			replay.Ended = true
+		case WALRecordMsg:
+			msg, err := record.Message()
+			if err != nil {
+				replay.invalid(record, "undecodable message: %v", err)
+				continue
+			}
+			replay.applyMsg(record, msg, chainID, valSet)
+		}
+	}
+	return replay, nil
+}
+
+func (replay *WALReplay) applyMsg(record *WALRecord, msg ConsensusMessage, chainID string, valSet *types.ValidatorSet) {
+	round := replay.round(record.Round)
+
+	switch msg := msg.(type) {
+	case *ProposalMessage:
+		signBytes := types.SignBytes(chainID, msg.Proposal)
+		for _, val := range valSet.Validators {
+			if types.VerifyBLS(val.PubKey, signBytes, msg.Proposal.Signature) {
+				round.Proposal = msg.Proposal
+				return
+			}
+		}
+		replay.invalid(record, "proposal not signed by a validator")
+	case *BlockPartMessage:
+		round.BlockParts++
+	case *VoteMessage:
+		vote := msg.Vote
+		if int(vote.ValidatorIndex) >= valSet.Size() {
+			replay.invalid(record, "unknown validator index %d", vote.ValidatorIndex)
+			return
+		}
+		_, val := valSet.GetByIndex(int(vote.ValidatorIndex))
+		if !types.VerifyBLS(val.PubKey, types.SignBytes(chainID, vote), vote.Signature) {
+			replay.invalid(record, "invalid signature of vote %v", vote)
+			return
+		}
+		if vote.Type == types.VoteTypePrevote {
+			round.Prevotes++
+		} else {
+			round.Precommits++
+		}
+	case *Maj23SignAggrMessage:
+		signAggr := msg.Maj23SignAggr
+		vote := &types.Vote{
+			BlockID: signAggr.BlockID,
+			Height:  signAggr.Height,
+			Round:   uint64(signAggr.Round),
+			Type:    signAggr.Type,
+		}
+		if !signAggr.SignAggrVerify(types.SignBytes(signAggr.ChainID, vote), valSet) {
+			replay.invalid(record, "invalid signature aggregation %v", signAggr)
+			return
+		}
+		blockID := signAggr.Maj23
+		if signAggr.Type == types.VoteTypePrevote {
+			round.PrevoteMaj23 = &blockID
+		} else {
+			round.PrecommitMaj23 = &blockID
+			replay.Committed = &blockID
+		}
+	case *PartialSignAggrMessage:
+		// Only informs our own vote
+	}
+}
+
+func (replay *WALReplay) round(r int) *WALRound {
+	for _, round := range replay.Rounds {
+		if round.Round == r {
+			return round
+		}
+	}
+	round := &WALRound{Round: r}
+	replay.Rounds = append(replay.Rounds, round)
+	return round
+}
+
+func (replay *WALReplay) invalid(record *WALRecord, format string, args ...interface{}) {
+	from := record.PeerKey
+	if from == "" {
+		from = "self"
+	}
+	replay.Invalid = append(replay.Invalid, fmt.Sprintf("round %d from %s: ", record.Round, from)+fmt.Sprintf(format, args...))
+}
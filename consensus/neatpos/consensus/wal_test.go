@@ -0,0 +1,101 @@
+package consensus
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus/neatpos/types"
+)
+
+func TestWALReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		privs []*types.PrivValidator
+		vals  []*types.Validator
+	)
+	for i := 0; i < 4; i++ {
+		priv := types.GenPrivValidatorKey(common.BytesToAddress([]byte{byte(i + 1)}))
+		privs = append(privs, priv)
+		vals = append(vals, types.NewValidator(priv.Address[:], priv.PubKey, big.NewInt(1)))
+	}
+	valSet := types.NewValidatorSet(vals)
+
+	path := filepath.Join(dir, "cs.wal", "wal")
+	wal, err := OpenWAL(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blockID := types.BlockID{Hash: []byte{0x01}}
+	for i, priv := range privs[:3] {
+		vote := &types.Vote{ValidatorIndex: uint64(i), Height: 10, Round: 1, Type: types.VoteTypePrevote, BlockID: blockID}
+		priv.SignVote("neatio", vote)
+		wal.saveMsg(msgInfo{&VoteMessage{vote}, "peer"})
+	}
+	// A vote signed by a validator other than the one at its index
+	forged := &types.Vote{ValidatorIndex: 3, Height: 10, Round: 1, Type: types.VoteTypePrevote, BlockID: blockID}
+	privs[0].SignVote("neatio", forged)
+	wal.saveMsg(msgInfo{&VoteMessage{forged}, "peer"})
+
+	wal.saveTimeout(timeoutInfo{Height: 10, Round: 0, Step: RoundStepPropose})
+	wal.saveStep(10, 1, RoundStepPrevote)
+	wal.saveStep(11, 0, RoundStepNewHeight)
+	wal.Close()
+
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay, err := ReplayWAL(bytes.NewReader(blob), 10, "neatio", valSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Rounds) != 2 || replay.Rounds[0].Round != 1 || replay.Rounds[1].Round != 0 {
+		t.Fatalf("rounds mismatch: %+v", replay.Rounds)
+	}
+	if have := replay.Rounds[0].Prevotes; have != 3 {
+		t.Errorf("prevote count mismatch: have %d, want 3", have)
+	}
+	if len(replay.Invalid) != 1 {
+		t.Errorf("invalid message count mismatch: have %d, want 1", len(replay.Invalid))
+	}
+	if len(replay.Rounds[1].Timeouts) != 1 {
+		t.Errorf("timeout count mismatch: have %d, want 1", len(replay.Rounds[1].Timeouts))
+	}
+	if replay.LastStep == nil || replay.LastStep.Step != RoundStepPrevote.String() {
+		t.Errorf("last step mismatch: have %+v", replay.LastStep)
+	}
+	if replay.Committed != nil || replay.Ended {
+		t.Errorf("height reported committed")
+	}
+
+	// A torn last record is reported, the records before it are read
+	dec := NewWALDecoder(bytes.NewReader(blob[:len(blob)-3]))
+	records := 0
+	for {
+		_, err := dec.Decode()
+		if err == io.EOF {
+			t.Fatalf("torn record not detected")
+		}
+		if _, ok := err.(*WALCorruptionError); ok {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records++
+	}
+	if records != 6 {
+		t.Errorf("record count mismatch: have %d, want 6", records)
+	}
+}
@@ -12,8 +12,11 @@ import (
 	"github.com/neatlab/neatio/consensus"
 	"github.com/neatlab/neatio/consensus/neatpos/epoch"
 	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core"
 	"github.com/neatlab/neatio/core/state"
 	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/event"
 	"github.com/neatlab/neatio/params"
 	"github.com/neatlab/neatio/rpc"
 	"github.com/neatlib/wire-go"
@@ -74,6 +77,12 @@ var (
 	inmemoryAddresses  = 20 // Number of recent addresses from ecrecover
 	recentAddresses, _ = lru.NewARC(inmemoryAddresses)
 
+	inmemoryVerifiedSeals = 4096 // Number of recent blocks whose commit aggregate signature was verified
+	verifiedSeals, _      = lru.NewARC(inmemoryVerifiedSeals)
+
+	inmemoryValidatorSets    = 16 // Number of recent epochs whose validator set hash was checked
+	verifiedValidatorSets, _ = lru.NewARC(inmemoryValidatorSets)
+
 	_ consensus.Engine = (*backend)(nil)
 
 	// Address for Child Chain Reward
@@ -87,6 +96,10 @@ func (sb *backend) APIs(chain consensus.ChainReader) []rpc.API {
 		Version:   "1.0",
 		Service:   &API{chain: chain, neatcon: sb},
 		Public:    true,
+	}, {
+		Namespace: "admin",
+		Version:   "1.0",
+		Service:   &PrivateAdminAPI{neatcon: sb},
 	}}
 }
 
@@ -100,6 +113,9 @@ func (sb *backend) Start(chain consensus.ChainReader, currentBlock func() *types
 	if sb.coreStarted {
 		return ErrStartedEngine
 	}
+	if sb.halted() != nil {
+		return errConflictingCommit
+	}
 
 	// clear previous data
 	sb.proposedBlockHash = common.Hash{}
@@ -146,8 +162,20 @@ func (sb *backend) Stop() error {
 	return nil
 }
 
+// WaitForProposedBlock implements consensus.NeatPoS.WaitForProposedBlock
+func (sb *backend) WaitForProposedBlock(timeout time.Duration) bool {
+	sb.coreMu.RLock()
+	defer sb.coreMu.RUnlock()
+	if !sb.coreStarted {
+		return true
+	}
+	return sb.core.consensusState.WaitForProposedBlock(timeout)
+}
+
 func (sb *backend) Close() error {
 	sb.core.epochDB.Close()
+	sb.core.misbehaviorDB.Close()
+	sb.core.wal.Close()
 	return nil
 }
 
@@ -178,6 +206,10 @@ func (sb *backend) verifyHeader(chain consensus.ChainReader, header *types.Heade
 		return errUnknownBlock
 	}
 
+	if sb.halted() != nil {
+		return errConflictingCommit
+	}
+
 	//if header.Number.Uint64() == 0 {
 	//	return nil // Ignore verify for genesis block
 	//}
@@ -253,6 +285,15 @@ func (sb *backend) verifyHeader(chain consensus.ChainReader, header *types.Heade
 			sb.logger.Infof("NeatPoS VerifyHeader, Main Chain Number mismatch, wait for %v then try again (count %d)", duration, tried)
 			time.Sleep(duration)
 		}
+
+		// No block past the end of the grace period of a retired side chain
+		_, sunset, retired, err := sb.core.cch.GetSideChainRetirement(sb.chainConfig.NeatChainId, header.MainChainNumber.Uint64())
+		if err != nil {
+			return err
+		}
+		if retired && header.MainChainNumber.Uint64() >= sunset {
+			return core.ErrSideChainRetired
+		}
 	}
 
 	return nil
@@ -279,11 +320,40 @@ func (sb *backend) verifyCascadingFields(chain consensus.ChainReader, header *ty
 	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
 		return consensus.ErrUnknownAncestor
 	}
+	if sb.chainConfig.IsBlockTime(header.Number) {
+		if err := verifyBlockTime(header, parent); err != nil {
+			return err
+		}
+	}
 
 	err := sb.verifyCommittedSeals(chain, header, parents)
 	return err
 }
 
+// verifyBlockTime checks that the block does not go back in time from its
+// parent: the header time, in seconds, may repeat the one of the parent, the
+// consensus time must be after the parent's.
+func verifyBlockTime(header, parent *types.Header) error {
+	if header.Time.Cmp(parent.Time) < 0 {
+		return errInvalidTimestamp
+	}
+	if parent.Number.Sign() == 0 {
+		return nil
+	}
+	extra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil {
+		return errInvalidExtraDataFormat
+	}
+	parentExtra, err := ncTypes.ExtractNeatconExtra(parent)
+	if err != nil {
+		return errInvalidExtraDataFormat
+	}
+	if !extra.Time.After(parentExtra.Time) {
+		return errInvalidTimestamp
+	}
+	return nil
+}
+
 func (sb *backend) VerifyHeaderBeforeConsensus(chain consensus.ChainReader, header *types.Header, seal bool) error {
 	sb.logger.Info("NeatPoS backend verify header before consensus")
 
@@ -373,7 +443,7 @@ func (sb *backend) verifyCommittedSeals(chain consensus.ChainReader, header *typ
 	}
 
 	valSet := epoch.Validators
-	if !bytes.Equal(valSet.Hash(), ncExtra.ValidatorsHash) {
+	if !validatorsHashMatches(sb.chainConfig.NeatChainId, epoch.Number, valSet, ncExtra.ValidatorsHash) {
 		sb.logger.Errorf("verifyCommittedSeals error. Our Validator Set %x, ncExtra Valdiator %x", valSet.Hash(), ncExtra.ValidatorsHash)
 		sb.logger.Errorf("verifyCommittedSeals error. epoch validator set %v, extra data %v", valSet.String(), ncExtra.String())
 		return errInconsistentValidatorSet
@@ -386,12 +456,41 @@ func (sb *backend) verifyCommittedSeals(chain consensus.ChainReader, header *typ
 		return errInvalidCommittedSeals
 	}
 
-	if err = valSet.VerifyCommit(ncExtra.ChainID, ncExtra.Height, seenCommit); err != nil {
-		sb.logger.Errorf("verifyCommittedSeals verify commit err %v", err)
-		return errInvalidSignature
+	// The aggregate signature of the commit of a block never changes, it is
+	// verified once per block
+	key := sealCacheKey(sb.chainConfig.NeatChainId, header)
+	if !verifiedSeals.Contains(key) {
+		if err = valSet.VerifyCommit(ncExtra.ChainID, ncExtra.Height, seenCommit); err != nil {
+			sb.logger.Errorf("verifyCommittedSeals verify commit err %v", err)
+			return errInvalidSignature
+		}
+		verifiedSeals.Add(key, struct{}{})
 	}
 
-	return nil
+	// The local chain may have committed another block at the height since
+	return sb.checkConflictingCommit(chain, header, ncExtra, valSet)
+}
+
+// sealCacheKey returns the key of the header in the cache of verified seals.
+// The hash of the header covers neither the chain nor the extra data holding
+// the commit, both are part of the key.
+func sealCacheKey(chainID string, header *types.Header) common.Hash {
+	return crypto.Keccak256Hash([]byte(chainID), header.Hash().Bytes(), header.Extra)
+}
+
+// validatorsHashMatches returns whether the validator set of the epoch hashes
+// to hash. The validator set of an epoch is fixed once it started, the hash
+// checked is kept for the next blocks of the epoch.
+func validatorsHashMatches(chainID string, epochNumber uint64, valSet *ncTypes.ValidatorSet, hash []byte) bool {
+	key := crypto.Keccak256Hash([]byte(chainID), new(big.Int).SetUint64(epochNumber).Bytes(), hash)
+	if verifiedValidatorSets.Contains(key) {
+		return true
+	}
+	if !bytes.Equal(valSet.Hash(), hash) {
+		return false
+	}
+	verifiedValidatorSets.Add(key, struct{}{})
+	return true
 }
 
 // VerifySeal checks whether the crypto seal on a header is valid according to
@@ -439,6 +538,10 @@ func (sb *backend) Prepare(chain consensus.ChainReader, header *types.Header) er
 	//if header.Time.Int64() < time.Now().Unix() {
 	header.Time = big.NewInt(time.Now().Unix())
 	//}
+	// The clock of the proposer may be behind the one of the previous proposer
+	if header.Time.Cmp(parent.Time) < 0 {
+		header.Time = new(big.Int).Set(parent.Time)
+	}
 
 	// Add Main Chain Height if running on Child Chain
 	if sb.chainConfig.NeatChainId != params.MainnetChainConfig.NeatChainId && sb.chainConfig.NeatChainId != params.TestnetChainConfig.NeatChainId {
@@ -462,6 +565,12 @@ func (sb *backend) Finalize(chain consensus.ChainReader, header *types.Header, s
 	if sb.chainConfig.NeatChainId == params.MainnetChainConfig.NeatChainId || sb.chainConfig.NeatChainId == params.TestnetChainConfig.NeatChainId {
 		// Check the Child Chain Start
 		readyId, updateBytes, removedId := sb.core.cch.ReadyForLaunchSideChain(header.Number, state)
+		if sb.chainConfig.IsAssetRegistry(header.Number) {
+			// The asset registry knows the side chains from the state only
+			for _, chainId := range readyId {
+				state.AddSideChain(chainId)
+			}
+		}
 		if len(readyId) > 0 || updateBytes != nil || len(removedId) > 0 {
 			if ok := ops.Append(&types.LaunchSideChainsOp{
 				SideChainIds:       readyId,
@@ -472,13 +581,16 @@ func (sb *backend) Finalize(chain consensus.ChainReader, header *types.Header, s
 				sb.logger.Error("NeatPoS Finalize, Fail to append LaunchSideChainsOp, only one LaunchSideChainsOp is allowed in each block")
 			}
 		}
+
+		// Pay out the withdrawals from the side chains at the end of their challenge window
+		totalGasFee = core.ReleaseWithdrawals(state, header, totalGasFee)
 	}
 
 	curBlockNumber := header.Number.Uint64()
 	epoch := sb.GetEpoch().GetEpochByBlockNumber(curBlockNumber)
 
 	// Calculate the rewards
-	accumulateRewards(sb.chainConfig, state, header, epoch, totalGasFee)
+	rewardsOp := accumulateRewards(sb.chainConfig, state, header, epoch, totalGasFee)
 
 	// update validator status include participating consensus block times and banned
 	if header.Number.Uint64() > 1 {
@@ -487,13 +599,19 @@ func (sb *backend) Finalize(chain consensus.ChainReader, header *types.Header, s
 		if prevHeader != nil {
 			extra, err := ncTypes.ExtractNeatconExtra(prevHeader)
 			if err == nil {
-				epoch.UpdateBannedState(header, prevHeader, extra.SeenCommit, state)
+				rewardsOp.Banned = epoch.UpdateBannedState(header, prevHeader, extra.SeenCommit, state)
 			}
 		}
 	}
+	ops.Append(rewardsOp)
+
+	// End the deposit and voting periods of the governance proposals
+	if sb.chainConfig.IsGovernance(header.Number) {
+		epoch.ProcessGovProposals(state, curBlockNumber)
+	}
 
 	// Check the Epoch switch and update their account balance accordingly (Refund the Locked Balance)
-	if ok, newValidators, _ := epoch.ShouldEnterNewEpoch(header.Number.Uint64(), state); ok {
+	if ok, newValidators, _ := epoch.ShouldEnterNewEpoch(sb.chainConfig, header.Number.Uint64(), state); ok {
 		ops.Append(&ncTypes.SwitchEpochOp{
 			ChainId:       sb.chainConfig.NeatChainId,
 			NewValidators: newValidators,
@@ -649,6 +767,7 @@ func (sb *backend) GetEpoch() *epoch.Epoch {
 
 func (sb *backend) SetEpoch(ep *epoch.Epoch) {
 	sb.core.consensusState.Epoch = ep
+	sb.core.rotatePrivValidator(ep)
 }
 
 func (sb *backend) PrivateValidator() common.Address {
@@ -658,6 +777,66 @@ func (sb *backend) PrivateValidator() common.Address {
 	return common.Address{}
 }
 
+// SkipEmptyBlocks reports whether blocks are only produced for transactions or
+// on the heartbeat interval.
+func (sb *backend) SkipEmptyBlocks() bool {
+	return sb.core.consensusState.SkipEmptyBlocks()
+}
+
+// maxLastSignedWindow is the largest number of blocks searched for the last
+// signature of a validator.
+const maxLastSignedWindow = maxUptimeWindow
+
+// LastSignedBlock returns the highest block after the given number committed
+// with a signature of the validator, or 0 if the validator has not signed any
+// of them. At most maxLastSignedWindow blocks are searched.
+func (sb *backend) LastSignedBlock(from common.Address, after uint64) (uint64, error) {
+	if sb.chain == nil {
+		return 0, errors.New("consensus engine not started")
+	}
+	head := sb.chain.CurrentHeader().Number.Uint64()
+	if head > after && head-after > maxLastSignedWindow {
+		return 0, fmt.Errorf("%d blocks after block %d, more than the %d searched", head-after, after, maxLastSignedWindow)
+	}
+	epochs := &epochCache{current: sb.core.consensusState.Epoch, epochs: make(map[uint64]*epoch.Epoch)}
+	for number := head; number > after; number-- {
+		record, err := sb.signRecord(number)
+		if err != nil {
+			return 0, err
+		}
+		if record == nil {
+			continue
+		}
+		ep, err := epochs.get(record.Epoch)
+		if err != nil {
+			return 0, err
+		}
+		if index, val := ep.Validators.GetByAddress(from.Bytes()); val != nil && record.signed(index) {
+			return number, nil
+		}
+	}
+	return 0, nil
+}
+
+// SubscribeConsensusEvent implements consensus.NeatPoS.SubscribeConsensusEvent
+func (sb *backend) SubscribeConsensusEvent(ch chan<- consensus.ConsensusEvent) event.Subscription {
+	return sb.consensusFeed.Subscribe(ch)
+}
+
+// feedConsensusEvents posts the round steps and the commits of the consensus
+// to the subscribers of the consensus events.
+func (sb *backend) feedConsensusEvents() {
+	evsw := sb.core.EventSwitch()
+	ncTypes.AddListenerForEvent(evsw, "consensusFeed", ncTypes.EventStringNewRoundStep(), func(data ncTypes.TMEventData) {
+		rs := data.(ncTypes.EventDataRoundState)
+		sb.consensusFeed.Send(consensus.ConsensusEvent{Type: ncTypes.EventStringNewRoundStep(), Height: rs.Height, Round: rs.Round, Step: rs.Step})
+	})
+	ncTypes.AddListenerForEvent(evsw, "consensusFeed", ncTypes.EventStringFinalCommitted(), func(data ncTypes.TMEventData) {
+		fc := data.(ncTypes.EventDataFinalCommitted)
+		sb.consensusFeed.Send(consensus.ConsensusEvent{Type: ncTypes.EventStringFinalCommitted(), Height: fc.BlockNumber})
+	})
+}
+
 func (sb *backend) updateBlock(parent *types.Header, block *types.Block) (*types.Block, error) {
 
 	sb.logger.Debug("NeatPoS backend update block")
@@ -690,12 +869,57 @@ func writeCommittedSeals(h *types.Header, ncExtra *ncTypes.NeatconExtra) error {
 	return nil
 }
 
-func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, ep *epoch.Epoch, totalGasFee *big.Int) {
+// govRewardPerBlock scales the block reward of the reward scheme by the
+// reward rate set by governance, in percent, from the governance fork.
+func govRewardPerBlock(config *params.ChainConfig, number *big.Int, statedb *state.StateDB, rewardPerBlock *big.Int) *big.Int {
+	if !config.IsGovernance(number) {
+		return rewardPerBlock
+	}
+	rate := statedb.GetGovParam(state.GovParamRewardRate)
+	if rate == nil || rewardPerBlock == nil {
+		return rewardPerBlock
+	}
+	return new(big.Int).Div(new(big.Int).Mul(rewardPerBlock, rate), big.NewInt(100))
+}
+
+// fundTreasury moves the share of the block reward set by governance to the
+// treasury from the governance fork, and returns the rest.
+func fundTreasury(config *params.ChainConfig, number *big.Int, statedb *state.StateDB, rewardPerBlock *big.Int) *big.Int {
+	if !config.IsGovernance(number) {
+		return rewardPerBlock
+	}
+	rate := statedb.GetGovParam(state.GovParamTreasuryRate)
+	if rate == nil || rate.Sign() == 0 {
+		return rewardPerBlock
+	}
+	share := new(big.Int).Div(new(big.Int).Mul(rewardPerBlock, rate), big.NewInt(100))
+	statedb.FundTreasury(share)
+	return new(big.Int).Sub(rewardPerBlock, share)
+}
+
+// accumulateRewards distributes the rewards of the block to its proposer and
+// the delegators of the proposer, and returns the op recording them in the
+// reward summary of the epoch.
+func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, ep *epoch.Epoch, totalGasFee *big.Int) *ncTypes.BlockRewardsOp {
+	op := &ncTypes.BlockRewardsOp{
+		Epoch:      ep.Number,
+		Block:      header.Number.Uint64(),
+		Validator:  header.Coinbase,
+		Reward:     new(big.Int),
+		GasFees:    new(big.Int).Set(totalGasFee),
+		Treasury:   new(big.Int),
+		Commission: new(big.Int),
+		Rewards:    make(map[common.Address]*big.Int),
+	}
 	var coinbaseReward *big.Int
 	if config.NeatChainId == params.MainnetChainConfig.NeatChainId || config.NeatChainId == params.TestnetChainConfig.NeatChainId {
 
-		rewardPerBlock := ep.RewardPerBlock
+		rewardPerBlock := govRewardPerBlock(config, header.Number, state, ep.RewardPerBlock)
 		if rewardPerBlock != nil && rewardPerBlock.Sign() == 1 {
+			op.Treasury.Set(rewardPerBlock)
+			rewardPerBlock = fundTreasury(config, header.Number, state, rewardPerBlock)
+			op.Treasury.Sub(op.Treasury, rewardPerBlock)
+			op.Reward.Set(rewardPerBlock)
 			coinbaseReward = big.NewInt(0)
 			coinbaseReward.Add(rewardPerBlock, totalGasFee)
 		} else {
@@ -709,6 +933,10 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 				rewardPerBlock = sideChainRewardBalance
 			}
 			state.SubBalance(sideChainRewardAddress, rewardPerBlock)
+			op.Treasury.Set(rewardPerBlock)
+			rewardPerBlock = fundTreasury(config, header.Number, state, rewardPerBlock)
+			op.Treasury.Sub(op.Treasury, rewardPerBlock)
+			op.Reward.Set(rewardPerBlock)
 
 			coinbaseReward = new(big.Int).Add(rewardPerBlock, totalGasFee)
 		} else {
@@ -738,10 +966,12 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 			selfReward.Add(selfReward, commissionReward)
 
 			delegateReward.Sub(delegateReward, commissionReward)
+			op.Commission.Set(commissionReward)
 		}
 	}
 
 	state.AddRewardBalanceByDelegateAddress(header.Coinbase, header.Coinbase, selfReward)
+	op.Rewards[header.Coinbase] = new(big.Int).Set(selfReward)
 
 	if delegateReward != nil && delegateReward.Sign() > 0 {
 		totalIndividualReward := big.NewInt(0)
@@ -750,6 +980,11 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 				individualReward := new(big.Int).Quo(new(big.Int).Mul(depositProxiedBalance, delegateReward), totalProxiedDeposit)
 				state.AddRewardBalanceByDelegateAddress(key, header.Coinbase, individualReward)
 				totalIndividualReward.Add(totalIndividualReward, individualReward)
+				if reward, ok := op.Rewards[key]; ok {
+					reward.Add(reward, individualReward)
+				} else {
+					op.Rewards[key] = new(big.Int).Set(individualReward)
+				}
 			}
 			return true
 		})
@@ -757,9 +992,12 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 		if cmp == 1 {
 			diff := new(big.Int).Sub(delegateReward, totalIndividualReward)
 			state.AddRewardBalanceByDelegateAddress(header.Coinbase, header.Coinbase, diff)
+			op.Rewards[header.Coinbase].Add(op.Rewards[header.Coinbase], diff)
 		} else if cmp == -1 {
 			diff := new(big.Int).Sub(totalIndividualReward, delegateReward)
 			state.SubRewardBalanceByDelegateAddress(header.Coinbase, header.Coinbase, diff)
+			op.Rewards[header.Coinbase].Sub(op.Rewards[header.Coinbase], diff)
 		}
 	}
+	return op
 }
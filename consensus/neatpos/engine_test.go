@@ -0,0 +1,122 @@
+package neatpos
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlib/wire-go"
+)
+
+func TestSealCacheKey(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(10), Extra: []byte{1}}
+	key := sealCacheKey("neatio", header)
+
+	// The commit in the extra data is not covered by the hash of the header
+	other := types.CopyHeader(header)
+	other.Extra = []byte{2}
+	if sealCacheKey("neatio", other) == key {
+		t.Error("headers with different commits share a key")
+	}
+	if sealCacheKey("side_0", header) == key {
+		t.Error("header of another chain shares the key")
+	}
+	if sealCacheKey("neatio", types.CopyHeader(header)) != key {
+		t.Error("same header keyed differently")
+	}
+}
+
+func TestValidatorsHashMatches(t *testing.T) {
+	priv := ncTypes.GenPrivValidatorKey(common.Address{1})
+	valSet := ncTypes.NewValidatorSet([]*ncTypes.Validator{ncTypes.NewValidator(priv.Address[:], priv.PubKey, big.NewInt(1))})
+	hash := valSet.Hash()
+
+	if validatorsHashMatches("neatio", 1, valSet, []byte{1}) {
+		t.Fatal("wrong validators hash matched")
+	}
+	if !validatorsHashMatches("neatio", 1, valSet, hash) {
+		t.Fatal("validators hash not matched")
+	}
+	// The checked hash is cached for the epoch of the chain only
+	empty := ncTypes.NewValidatorSet(nil)
+	if !validatorsHashMatches("neatio", 1, empty, hash) {
+		t.Error("checked validators hash not cached")
+	}
+	if validatorsHashMatches("neatio", 2, empty, hash) {
+		t.Error("validators hash cached for another epoch")
+	}
+	if validatorsHashMatches("side_0", 1, empty, hash) {
+		t.Error("validators hash cached for another chain")
+	}
+}
+
+func TestVerifyBlockTime(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	header := func(number int64, unix int64, consensusTime time.Time) *types.Header {
+		extra := wire.BinaryBytes(&ncTypes.NeatconExtra{Height: uint64(number), Time: consensusTime})
+		return &types.Header{Number: big.NewInt(number), Time: big.NewInt(unix), Extra: extra}
+	}
+	parent := header(1, now.Unix(), now)
+
+	tests := []struct {
+		header *types.Header
+		valid  bool
+	}{
+		{header(2, now.Unix(), now.Add(500*time.Millisecond)), true}, // Same second
+		{header(2, now.Unix()+1, now.Add(time.Second)), true},
+		{header(2, now.Unix()-1, now.Add(time.Second)), false}, // Header time back
+		{header(2, now.Unix()+1, now), false},                  // Consensus time not after the parent's
+	}
+	for i, tt := range tests {
+		if err := verifyBlockTime(tt.header, parent); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+	// The genesis has no consensus time
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(now.Unix())}
+	if err := verifyBlockTime(header(1, now.Unix(), now), genesis); err != nil {
+		t.Errorf("first block rejected: %v", err)
+	}
+}
+
+func TestGovRewardPerBlock(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetGovParam(state.GovParamRewardRate, big.NewInt(50), 1, 1)
+	statedb.SetGovEpoch(1)
+	config := &params.ChainConfig{GovernanceBlock: big.NewInt(100)}
+	reward := big.NewInt(1000)
+
+	if have := govRewardPerBlock(config, big.NewInt(99), statedb, reward); have.Cmp(reward) != 0 {
+		t.Errorf("reward scaled before the fork: have %v", have)
+	}
+	if have := govRewardPerBlock(config, big.NewInt(100), statedb, reward); have.Int64() != 500 {
+		t.Errorf("reward mismatch from the fork: have %v, want 500", have)
+	}
+}
+
+// headChain is a chain only knowing its head.
+type headChain struct {
+	consensus.ChainReader
+	head *types.Header
+}
+
+func (c *headChain) CurrentHeader() *types.Header { return c.head }
+
+func TestLastSignedBlockWindow(t *testing.T) {
+	head := uint64(2 * maxLastSignedWindow)
+	sb := &backend{chain: &headChain{head: &types.Header{Number: new(big.Int).SetUint64(head)}}}
+
+	if _, err := sb.LastSignedBlock(common.Address{}, head-maxLastSignedWindow-1); err == nil {
+		t.Error("search over the window allowed")
+	}
+	if _, err := sb.LastSignedBlock(common.Address{}, 0); err == nil {
+		t.Error("search down to the genesis allowed")
+	}
+}
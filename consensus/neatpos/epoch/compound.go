@@ -0,0 +1,34 @@
+package epoch
+
+import (
+	"math/big"
+
+	"github.com/neatlab/neatio/core/state"
+)
+
+// compoundRewards delegates again the rewards of the auto-compounding
+// delegations to their candidate. A delegation being fully undelegated, or to
+// an account no longer candidate, keeps its rewards claimable.
+func (epoch *Epoch) compoundRewards(statedb *state.StateDB) {
+	for _, compound := range statedb.GetAutoCompounds() {
+		delegator, candidate := compound.Delegator, compound.Candidate
+		if !statedb.IsCandidate(candidate) {
+			continue
+		}
+		delegated := new(big.Int).Add(statedb.GetProxiedBalanceByUser(candidate, delegator), statedb.GetDepositProxiedBalanceByUser(candidate, delegator))
+		if delegated.Cmp(statedb.GetPendingRefundBalanceByUser(candidate, delegator)) <= 0 {
+			continue
+		}
+		reward := statedb.GetRewardBalanceByDelegateAddress(delegator, candidate)
+		if reward.Sign() <= 0 {
+			continue
+		}
+		reward = new(big.Int).Set(reward)
+
+		// Same as a delegation, counted from the next epoch
+		statedb.SubRewardBalanceByDelegateAddress(delegator, candidate, reward)
+		statedb.AddDelegateBalance(delegator, reward)
+		statedb.AddProxiedBalanceByUser(candidate, delegator, reward)
+		epoch.logger.Debugf("Reward %v of %x compounded in the delegation to %x", reward, delegator, candidate)
+	}
+}
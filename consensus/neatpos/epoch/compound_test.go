@@ -0,0 +1,56 @@
+package epoch
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/log"
+)
+
+func TestCompoundRewards(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	candidate := common.BytesToAddress([]byte{0x01})
+	compounding := common.BytesToAddress([]byte{0x02})
+	claiming := common.BytesToAddress([]byte{0x03})
+	leaving := common.BytesToAddress([]byte{0x04})
+	statedb.ApplyForCandidate(candidate, "", 10)
+	for _, delegator := range []common.Address{compounding, claiming, leaving} {
+		statedb.AddDelegateBalance(delegator, big.NewInt(100))
+		statedb.AddDepositProxiedBalanceByUser(candidate, delegator, big.NewInt(100))
+		statedb.AddRewardBalanceByDelegateAddress(delegator, candidate, big.NewInt(7))
+	}
+	statedb.SetAutoCompound(compounding, candidate, true)
+	statedb.SetAutoCompound(leaving, candidate, true)
+	statedb.AddPendingRefundBalanceByUser(candidate, leaving, big.NewInt(100))
+
+	ep := &Epoch{logger: log.New()}
+	ep.compoundRewards(statedb)
+
+	for _, tt := range []struct {
+		delegator         common.Address
+		reward, delegated int64
+	}{
+		{compounding, 0, 107},
+		{claiming, 7, 100},
+		{leaving, 7, 100},
+	} {
+		if have := statedb.GetRewardBalanceByDelegateAddress(tt.delegator, candidate); have.Int64() != tt.reward {
+			t.Errorf("%x: reward mismatch: have %v, want %d", tt.delegator, have, tt.reward)
+		}
+		if have := statedb.GetDelegateBalance(tt.delegator); have.Int64() != tt.delegated {
+			t.Errorf("%x: delegate balance mismatch: have %v, want %d", tt.delegator, have, tt.delegated)
+		}
+	}
+	if have := statedb.GetProxiedBalanceByUser(candidate, compounding); have.Int64() != 7 {
+		t.Errorf("compounded reward not proxied: have %v, want 7", have)
+	}
+
+	statedb.SetAutoCompound(compounding, candidate, false)
+	if statedb.IsAutoCompound(compounding, candidate) || !statedb.IsAutoCompound(leaving, candidate) {
+		t.Errorf("auto compound opt-out mismatch")
+	}
+}
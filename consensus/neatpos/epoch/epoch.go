@@ -9,6 +9,7 @@ import (
 	"github.com/neatlab/neatio/core/state"
 	"github.com/neatlab/neatio/core/types"
 	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/params"
 	goCrypto "github.com/neatlib/crypto-go"
 	dbm "github.com/neatlib/db-go"
 	"github.com/neatlib/wire-go"
@@ -128,6 +129,12 @@ func loadOneEpoch(db dbm.DB, epochNumber uint64, logger log.Logger) *Epoch {
 	return ep
 }
 
+// LoadEpochBytes returns the stored encoding of the given epoch, nil if the
+// epoch is unknown.
+func LoadEpochBytes(db dbm.DB, epochNumber uint64) []byte {
+	return db.Get(calcEpochKeyWithHeight(epochNumber))
+}
+
 // Convert from OneEpochDoc (Json) to Epoch
 func MakeOneEpoch(db dbm.DB, oneEpoch *tmTypes.OneEpochDoc, logger log.Logger) *Epoch {
 
@@ -299,11 +306,27 @@ func (epoch *Epoch) GetPreviousEpoch() *Epoch {
 	return epoch.previousEpoch
 }
 
-func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bool, *tmTypes.ValidatorSet, error) {
+func (epoch *Epoch) ShouldEnterNewEpoch(config *params.ChainConfig, height uint64, state *state.StateDB) (bool, *tmTypes.ValidatorSet, error) {
 
 	if height == epoch.EndBlock {
 		epoch.nextEpoch = epoch.GetNextEpoch()
 		if epoch.nextEpoch != nil {
+			number := new(big.Int).SetUint64(height)
+
+			// Step 0: Apply the parameter changes passed by governance for the next epoch
+			if config.IsGovernance(number) {
+				epoch.applyGovProposals(state)
+			}
+
+			// Step 0.1: Apply the commissions the candidates set for the next epoch
+			for _, change := range state.ApplyCommissionChanges(epoch.Number + 1) {
+				epoch.logger.Infof("Commission of %x set to %d%% from epoch %d", change.Candidate, change.Commission, epoch.Number+1)
+			}
+
+			// Step 0.2: Delegate again the rewards of the auto-compounding delegations
+			if config.IsAutoCompound(number) {
+				epoch.compoundRewards(state)
+			}
 
 			// Step 1: Refund the Delegate (subtract the pending refund / deposit proxied amount)
 			for refundAddress := range state.GetDelegateAddressRefundSet() {
@@ -324,6 +347,11 @@ func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bo
 			}
 			state.ClearDelegateRefundSet()
 
+			// Step 1.1: Ban the validators whose self bond fell below their minimum
+			if config.IsMinSelfBond(number) {
+				epoch.jailBelowMinSelfBond(state)
+			}
+
 			// Step 2: Sort the Validators and potential Validators (with success vote) base on deposit amount + deposit proxied amount
 			// Step 2.1: Update deposit amount base on the vote (Add/Substract deposit amount base on vote)
 			// Step 2.2: Add candidate to next epoch vote set
@@ -466,13 +494,29 @@ func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bo
 
 			// Update Validators with vote
 			//refundsUpdate, err := updateEpochValidatorSet(newValidators, epoch.nextEpoch.validatorVoteSet)
-			refundsUpdate, err := updateEpochValidatorSet(newValidators, nextEpochVoteSet)
+			refundsUpdate, err := updateEpochValidatorSet(newValidators, nextEpochVoteSet, maxValidatorsSize(state))
 			if err != nil {
 				epoch.logger.Warn("Error changing validator set", "error", err)
 				return false, nil, err
 			}
 			refunds = append(refunds, refundsUpdate...)
 
+			// Step 2.4: Validators who rotated their consensus key during this epoch sign with the new key from now on
+			if config.IsKeyRotation(number) {
+				for addr := range state.GetKeyRotationSet() {
+					if _, v := newValidators.GetByAddress(addr.Bytes()); v != nil {
+						pubkeyBytes := common.FromHex(state.GetPubkey(addr))
+						if len(pubkeyBytes) != 128 {
+							continue
+						}
+						var blsPK goCrypto.BLSPubKey
+						copy(blsPK[:], pubkeyBytes)
+						v.PubKey = blsPK
+					}
+				}
+				state.ClearKeyRotationSet()
+			}
+
 			// Now newValidators become a real new Validators
 			// Step 3: Special Case: For the existing Validator + Candidate + no vote, Move proxied amount to deposit proxied amount  (proxied amount -> deposit proxied amount)
 			for _, v := range newValidators.Validators {
@@ -577,13 +621,13 @@ func DryRunUpdateEpochValidatorSet(state *state.StateDB, validators *tmTypes.Val
 		}
 	}
 
-	_, err := updateEpochValidatorSet(validators, voteSet)
+	_, err := updateEpochValidatorSet(validators, voteSet, maxValidatorsSize(state))
 	return err
 }
 
 // updateEpochValidatorSet Update the Current Epoch Validator by vote
 //
-func updateEpochValidatorSet(validators *tmTypes.ValidatorSet, voteSet *EpochValidatorVoteSet) ([]*tmTypes.RefundValidatorAmount, error) {
+func updateEpochValidatorSet(validators *tmTypes.ValidatorSet, voteSet *EpochValidatorVoteSet, maxValSize int) ([]*tmTypes.RefundValidatorAmount, error) {
 
 	// Refund List will be vaildators contain from Vote (exit validator or less amount than previous amount) and Knockout after sort by amount
 	var refund []*tmTypes.RefundValidatorAmount
@@ -633,8 +677,8 @@ func updateEpochValidatorSet(validators *tmTypes.ValidatorSet, voteSet *EpochVal
 	// Determine the Validator Size
 	//valSize := oldValSize + newValSize/2
 	valSize := oldValSize + newValSize
-	if valSize > MaximumValidatorsSize {
-		valSize = MaximumValidatorsSize
+	if valSize > maxValSize {
+		valSize = maxValSize
 	} else if valSize < MinimumValidatorsSize {
 		valSize = MinimumValidatorsSize
 	}
@@ -918,7 +962,9 @@ func (epoch *Epoch) GetBannedDuration() time.Duration {
 }
 
 // Update validator block time and set banned if this validator did not participate in consensus in one epoch
-func (epoch *Epoch) UpdateBannedState(header *types.Header, prevHeader *types.Header, commit *tmTypes.Commit, state *state.StateDB) {
+// UpdateBannedState counts the blocks signed by the validators, and bans the
+// ones which signed none of the epoch. Returns the validators banned.
+func (epoch *Epoch) UpdateBannedState(header *types.Header, prevHeader *types.Header, commit *tmTypes.Commit, state *state.StateDB) (banned []common.Address) {
 	validators := epoch.Validators.Validators
 	height := header.Number.Uint64()
 	//bannedTime := prevHeader.Time
@@ -926,7 +972,7 @@ func (epoch *Epoch) UpdateBannedState(header *types.Header, prevHeader *types.He
 	//epoch.logger.Infof("Update validator banned state height %v", height)
 
 	if height <= 1 || height == epoch.StartBlock {
-		return
+		return nil
 	} else if height == epoch.EndBlock {
 		epoch.logger.Debugf("Update validator banned state, epoch end block %v", height)
 		// epoch end block set all validators mined block times 0
@@ -946,12 +992,13 @@ func (epoch *Epoch) UpdateBannedState(header *types.Header, prevHeader *types.He
 				state.SetBannedTime(addr, BannedEpoch)
 
 				state.MarkAddressBanned(addr)
+				banned = append(banned, addr)
 			}
 		}
 	} else {
 		if commit == nil || commit.BitArray == nil {
 			epoch.logger.Debugf("Update validator banned state seenCommit %v", commit)
-			return
+			return nil
 		}
 
 		// update the mined block times
@@ -971,5 +1018,5 @@ func (epoch *Epoch) UpdateBannedState(header *types.Header, prevHeader *types.He
 			}
 		}
 	}
-
+	return banned
 }
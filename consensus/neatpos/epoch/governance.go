@@ -0,0 +1,124 @@
+package epoch
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/state"
+)
+
+// A governance proposal passes when the stake which voted on it is at least
+// 1/3 of the voting power of the validators (quorum), and more than 2/3 of
+// that stake approved it (threshold).
+var (
+	govQuorumNum, govQuorumDenom       = big.NewInt(1), big.NewInt(3)
+	govThresholdNum, govThresholdDenom = big.NewInt(2), big.NewInt(3)
+)
+
+// GovStake returns the stake addr votes on governance proposals with: its
+// own deposit plus what it delegated.
+func GovStake(statedb *state.StateDB, addr common.Address) *big.Int {
+	return new(big.Int).Add(statedb.GetDepositBalance(addr), statedb.GetDelegateBalance(addr))
+}
+
+// ProcessGovProposals ends the deposit and voting periods of the proposals
+// reaching their end at block number. Deposits are refunded once the voting
+// reached quorum, and burned otherwise.
+func (epoch *Epoch) ProcessGovProposals(statedb *state.StateDB, number uint64) {
+	for _, proposal := range statedb.GetEndingGovProposals(number) {
+		switch {
+		case proposal.Status == state.GovProposalDeposit && number >= proposal.DepositEnd:
+			statedb.SetGovProposalStatus(proposal.Id, state.GovProposalExpired)
+			epoch.logger.Infof("Governance proposal %d expired without minimum deposit, deposit %v burned", proposal.Id, proposal.TotalDeposit())
+
+		case proposal.Status == state.GovProposalVoting && number >= proposal.VotingEnd:
+			epoch.tallyGovProposal(statedb, proposal, number)
+		}
+	}
+}
+
+func (epoch *Epoch) tallyGovProposal(statedb *state.StateDB, proposal *state.GovProposal, number uint64) {
+	// TotalVotingPower counts the validators, the quorum is on their stake
+	quorum := new(big.Int)
+	for _, v := range epoch.Validators.Validators {
+		quorum.Add(quorum, v.VotingPower)
+	}
+	quorum.Mul(quorum, govQuorumNum)
+	quorum.Div(quorum, govQuorumDenom)
+
+	voted, approved := new(big.Int), new(big.Int)
+	for _, vote := range proposal.Votes {
+		stake := GovStake(statedb, vote.Voter)
+		voted.Add(voted, stake)
+		if vote.Approve {
+			approved.Add(approved, stake)
+		}
+	}
+	threshold := new(big.Int).Mul(voted, govThresholdNum)
+	threshold.Div(threshold, govThresholdDenom)
+
+	if voted.Sign() == 0 || voted.Cmp(quorum) < 0 {
+		statedb.SetGovProposalStatus(proposal.Id, state.GovProposalExpired)
+		epoch.logger.Infof("Governance proposal %d expired, stake voted %v below quorum %v, deposit %v burned", proposal.Id, voted, quorum, proposal.TotalDeposit())
+		return
+	}
+	for _, deposit := range proposal.Deposits {
+		statedb.AddBalance(deposit.Depositor, deposit.Amount)
+	}
+	if approved.Cmp(threshold) > 0 {
+		statedb.SetGovProposalStatus(proposal.Id, state.GovProposalPassed)
+		epoch.logger.Infof("Governance proposal %d passed, stake voted %v, approved %v", proposal.Id, voted, approved)
+
+		// Treasury spends are paid once passed
+		if proposal.IsTreasurySpend() {
+			if statedb.SpendTreasury(proposal.Id, proposal.Recipient, proposal.Value, number) {
+				statedb.SetGovProposalStatus(proposal.Id, state.GovProposalExecuted)
+				epoch.logger.Infof("Governance proposal %d executed, %v paid out of the treasury to %x", proposal.Id, proposal.Value, proposal.Recipient)
+			} else {
+				statedb.SetGovProposalStatus(proposal.Id, state.GovProposalFailed)
+				epoch.logger.Infof("Governance proposal %d failed, treasury can not afford %v", proposal.Id, proposal.Value)
+			}
+		}
+	} else {
+		statedb.SetGovProposalStatus(proposal.Id, state.GovProposalRejected)
+		epoch.logger.Infof("Governance proposal %d rejected, stake voted %v, approved %v", proposal.Id, voted, approved)
+	}
+}
+
+// applyGovProposals puts in effect the parameter changes of the next epoch,
+// and applies the ones passed for it, or for an earlier one if the voting
+// ended after it started.
+func (epoch *Epoch) applyGovProposals(statedb *state.StateDB) {
+	statedb.SetGovEpoch(epoch.Number + 1)
+	for _, proposal := range statedb.GetPassedGovProposals() {
+		if proposal.Epoch > epoch.Number+1 {
+			continue
+		}
+		// An upgrade or a side chain retirement can not be scheduled at a height
+		// already reached
+		if isScheduledParam(proposal.Param) && proposal.Value.Uint64() <= epoch.EndBlock {
+			statedb.SetGovProposalStatus(proposal.Id, state.GovProposalRejected)
+			epoch.logger.Infof("Governance proposal %d rejected, height %v of %s reached", proposal.Id, proposal.Value, proposal.Param)
+			continue
+		}
+		statedb.SetGovParam(proposal.Param, proposal.Value, proposal.Id, epoch.Number+1)
+		statedb.SetGovProposalStatus(proposal.Id, state.GovProposalExecuted)
+		epoch.logger.Infof("Governance proposal %d executed, %s = %v from epoch %d", proposal.Id, proposal.Param, proposal.Value, epoch.Number+1)
+	}
+}
+
+// isScheduledParam reports whether the value of the governance parameter is
+// the height it takes effect from.
+func isScheduledParam(param string) bool {
+	return strings.HasPrefix(param, state.GovParamUpgradePrefix) || strings.HasPrefix(param, state.GovParamRetirePrefix)
+}
+
+// maxValidatorsSize returns the maximum size of the validator set, as set by
+// governance.
+func maxValidatorsSize(statedb *state.StateDB) int {
+	if size := statedb.GetGovParam(state.GovParamMaxValidators); size != nil {
+		return int(size.Uint64())
+	}
+	return MaximumValidatorsSize
+}
@@ -0,0 +1,202 @@
+package epoch
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	tmTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/log"
+)
+
+func TestGovProposalLifecycle(t *testing.T) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+
+	validator := common.BytesToAddress([]byte{0x01})
+	delegator := common.BytesToAddress([]byte{0x02})
+	depositor := common.BytesToAddress([]byte{0x03})
+	statedb.AddDepositBalance(validator, big.NewInt(60))
+	statedb.AddDelegateBalance(delegator, big.NewInt(40))
+
+	ep := &Epoch{
+		Number:   1,
+		EndBlock: 1000,
+		Validators: tmTypes.NewValidatorSet([]*tmTypes.Validator{
+			tmTypes.NewValidator(validator[:], nil, big.NewInt(100)),
+		}),
+		logger: log.New(),
+	}
+	deposit := big.NewInt(5)
+	recipient := common.BytesToAddress([]byte{0x04})
+	submit := func(param string, value int64, approvers ...common.Address) uint64 {
+		var v *big.Int
+		if param != "" {
+			v = big.NewInt(value)
+		}
+		id := statedb.AddGovProposal(depositor, "title", "", param, v, recipient, 2, 10)
+		statedb.AddGovDeposit(id, depositor, deposit)
+		statedb.StartGovVoting(id, 10)
+		for _, voter := range approvers {
+			statedb.VoteGovProposal(id, voter, true)
+		}
+		return id
+	}
+
+	// Approved by 60 of the 60 voting
+	passed := submit(state.GovParamMaxValidators, 21, validator)
+	// Text proposal approved by 60 of the 60 voting
+	text := submit("", 0, validator)
+	// Approved by 40 of the 100 voting
+	rejected := submit(state.GovParamRewardRate, 50, delegator)
+	statedb.VoteGovProposal(rejected, validator, false)
+	// Approved by 30 of the 30 voting, below quorum
+	noQuorum := submit(state.GovParamTimeoutPropose, 5000, delegator)
+	statedb.SubDelegateBalance(delegator, big.NewInt(10))
+	// Never reaching the minimum deposit
+	noDeposit := statedb.AddGovProposal(depositor, "title", "", "", nil, common.Address{}, 0, 10)
+	statedb.AddGovDeposit(noDeposit, depositor, deposit)
+
+	// Treasury spends, the second one not affordable
+	statedb.FundTreasury(big.NewInt(100))
+	spent := submit(state.GovParamTreasurySpend, 60, validator)
+	unaffordable := submit(state.GovParamTreasurySpend, 60, validator)
+
+	votingEnd := 10 + statedb.GovVotingPeriod()
+	ep.ProcessGovProposals(statedb, votingEnd-1)
+	if have := statedb.GetGovProposal(passed).Status; have != state.GovProposalVoting {
+		t.Fatalf("proposal tallied before the end of the voting period: status %d", have)
+	}
+	ep.ProcessGovProposals(statedb, votingEnd)
+	ep.applyGovProposals(statedb)
+
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, db)
+
+	for _, tt := range []struct {
+		id     uint64
+		status uint8
+	}{
+		{passed, state.GovProposalExecuted},
+		{text, state.GovProposalPassed},
+		{rejected, state.GovProposalRejected},
+		{noQuorum, state.GovProposalExpired},
+		{noDeposit, state.GovProposalExpired},
+		{spent, state.GovProposalExecuted},
+		{unaffordable, state.GovProposalFailed},
+	} {
+		if have := statedb.GetGovProposal(tt.id).Status; have != tt.status {
+			t.Errorf("proposal %d: status mismatch: have %d, want %d", tt.id, have, tt.status)
+		}
+	}
+	// Deposits of the proposals reaching quorum are refunded, the others burned
+	if have, want := statedb.GetBalance(depositor), big.NewInt(25); have.Cmp(want) != 0 {
+		t.Errorf("refunded deposits mismatch: have %v, want %v", have, want)
+	}
+	params := statedb.GetGovParams()
+	if len(params) != 1 || params[state.GovParamMaxValidators].Uint64() != 21 {
+		t.Errorf("governance params mismatch: have %v", params)
+	}
+	if changes := statedb.GetGovParamChanges(state.GovParamMaxValidators); len(changes) != 1 || changes[0].ProposalId != passed || changes[0].Epoch != 2 {
+		t.Errorf("max validators history mismatch: have %v", changes)
+	}
+	if have := maxValidatorsSize(statedb); have != 21 {
+		t.Errorf("max validators mismatch: have %d, want 21", have)
+	}
+
+	received, spentTotal, spends := statedb.GetTreasuryAccounting()
+	if received.Int64() != 100 || spentTotal.Int64() != 60 || len(spends) != 1 || spends[0].ProposalId != spent {
+		t.Errorf("treasury accounting mismatch: received %v, spent %v, spends %d", received, spentTotal, len(spends))
+	}
+	if have := statedb.GetBalance(recipient); have.Int64() != 60 {
+		t.Errorf("recipient balance mismatch: have %v, want 60", have)
+	}
+	if have := statedb.GetBalance(state.TreasuryAddr); have.Int64() != 40 {
+		t.Errorf("treasury balance mismatch: have %v, want 40", have)
+	}
+}
+
+func TestEndingGovProposals(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	proposer := common.BytesToAddress([]byte{0x01})
+
+	late := statedb.AddGovProposal(proposer, "late", "", "", nil, common.Address{}, 0, 20)
+	early := statedb.AddGovProposal(proposer, "early", "", "", nil, common.Address{}, 0, 10)
+	voting := statedb.AddGovProposal(proposer, "voting", "", "", nil, common.Address{}, 0, 10)
+	statedb.StartGovVoting(voting, 15)
+
+	// Both periods last as long by default
+	period := statedb.GovDepositPeriod()
+	if ending := statedb.GetEndingGovProposals(10 + period - 1); len(ending) != 0 {
+		t.Fatalf("proposals ending before their period: have %d", len(ending))
+	}
+	ending := statedb.GetEndingGovProposals(20 + period)
+	if len(ending) != 3 || ending[0].Id != early || ending[1].Id != voting || ending[2].Id != late {
+		t.Fatalf("ending proposals mismatch: have %d", len(ending))
+	}
+	if open := statedb.OpenGovProposals(); open != 3 {
+		t.Errorf("open proposals mismatch: have %d, want 3", open)
+	}
+	statedb.SetGovProposalStatus(early, state.GovProposalExpired)
+	if open := statedb.OpenGovProposals(); open != 2 {
+		t.Errorf("open proposals mismatch: have %d, want 2", open)
+	}
+	if ending := statedb.GetEndingGovProposals(15 + period); len(ending) != 1 || ending[0].Id != voting {
+		t.Errorf("closed proposal still ending: have %d", len(ending))
+	}
+}
+
+func TestCommissionChanges(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	candidate := common.BytesToAddress([]byte{0x01})
+	former := common.BytesToAddress([]byte{0x02})
+	statedb.ApplyForCandidate(candidate, "", 10)
+	statedb.ApplyForCandidate(former, "", 10)
+
+	statedb.ScheduleCommission(candidate, 15, 3)
+	statedb.ScheduleCommission(candidate, 20, 3)
+	statedb.ScheduleCommission(former, 20, 3)
+	statedb.CancelCandidate(former, true)
+
+	if applied := statedb.ApplyCommissionChanges(2); len(applied) != 0 || statedb.GetCommission(candidate) != 10 {
+		t.Fatalf("commission changed before its epoch: applied %d, commission %d", len(applied), statedb.GetCommission(candidate))
+	}
+	applied := statedb.ApplyCommissionChanges(3)
+	if len(applied) != 1 || statedb.GetCommission(candidate) != 20 {
+		t.Fatalf("commission change mismatch: applied %d, commission %d", len(applied), statedb.GetCommission(candidate))
+	}
+	if statedb.GetCommissionChange(candidate) != nil || statedb.GetCommissionChange(former) != nil {
+		t.Errorf("commission changes left after being applied")
+	}
+	if have := statedb.MaxCommissionChange(); have != state.DefaultMaxCommissionChange {
+		t.Errorf("max commission change mismatch: have %d, want %d", have, state.DefaultMaxCommissionChange)
+	}
+}
+
+func TestGovParamEpoch(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	ep := &Epoch{Number: 1, EndBlock: 1000, logger: log.New()}
+
+	statedb.SetGovParam(state.GovParamMaxValidators, big.NewInt(21), 0, 2)
+	statedb.SetGovParam(state.GovParamMaxValidators, big.NewInt(30), 0, 3)
+	if have := statedb.GetGovParam(state.GovParamMaxValidators); have != nil {
+		t.Fatalf("value applying from a future epoch in effect: have %v", have)
+	}
+	ep.applyGovProposals(statedb)
+	if have := statedb.GetGovParam(state.GovParamMaxValidators); have == nil || have.Uint64() != 21 {
+		t.Errorf("max validators mismatch at epoch 2: have %v, want 21", have)
+	}
+	if params := statedb.GetGovParams(); len(params) != 1 || params[state.GovParamMaxValidators].Uint64() != 21 {
+		t.Errorf("governance params mismatch at epoch 2: have %v", params)
+	}
+	statedb.SetGovEpoch(3)
+	if have := maxValidatorsSize(statedb); have != 30 {
+		t.Errorf("max validators mismatch at epoch 3: have %d, want 30", have)
+	}
+}
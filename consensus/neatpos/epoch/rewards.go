@@ -0,0 +1,142 @@
+package epoch
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/neatlab/neatio/common"
+	tmTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/rlp"
+	dbm "github.com/neatlib/db-go"
+)
+
+const epochRewardsKey = "EpochRewards:%v"
+
+// rewardsMtx serializes the updates of the reward summaries, which are shared
+// by the epoch objects of a chain.
+var rewardsMtx sync.Mutex
+
+// EpochRewards sums up the rewards distributed by the blocks of an epoch, so
+// that they can be reported without replaying the state.
+type EpochRewards struct {
+	Number     uint64
+	FirstBlock uint64 // First block recorded, the ones before are missing
+	LastBlock  uint64 // Last block recorded
+
+	Reward     *big.Int // Block rewards, less the treasury share
+	GasFees    *big.Int
+	Treasury   *big.Int
+	Validators []*ValidatorRewards
+	Banned     []common.Address // Validators banned at the end of the epoch
+}
+
+// ValidatorRewards are the rewards of the blocks proposed by a validator in
+// an epoch.
+type ValidatorRewards struct {
+	Address    common.Address
+	Blocks     uint64
+	Reward     *big.Int // Reward of the validator, commission included
+	Commission *big.Int
+	Delegators []*DelegatorReward
+}
+
+// DelegatorReward is the reward of a delegator of a validator in an epoch.
+type DelegatorReward struct {
+	Address common.Address
+	Reward  *big.Int
+}
+
+func calcEpochRewardsKey(number uint64) []byte {
+	return []byte(fmt.Sprintf(epochRewardsKey, number))
+}
+
+// LoadEpochRewards loads the reward summary of the epoch, or returns nil if no
+// block of the epoch was recorded.
+func LoadEpochRewards(db dbm.DB, number uint64) *EpochRewards {
+	buf := db.Get(calcEpochRewardsKey(number))
+	if len(buf) == 0 {
+		return nil
+	}
+	rewards := new(EpochRewards)
+	if err := rlp.DecodeBytes(buf, rewards); err != nil {
+		log.Errorf("LoadEpochRewards Failed, epoch: %v, error: %v", number, err)
+		return nil
+	}
+	return rewards
+}
+
+// RecordBlockRewards adds the rewards of a block to the summary of its epoch.
+// The blocks are recorded in order, once each: a block written again, when
+// the chain is imported again, is skipped.
+func (epoch *Epoch) RecordBlockRewards(op *tmTypes.BlockRewardsOp) error {
+	rewardsMtx.Lock()
+	defer rewardsMtx.Unlock()
+
+	rewards := LoadEpochRewards(epoch.db, op.Epoch)
+	if rewards == nil {
+		rewards = &EpochRewards{
+			Number:     op.Epoch,
+			FirstBlock: op.Block,
+			Reward:     new(big.Int),
+			GasFees:    new(big.Int),
+			Treasury:   new(big.Int),
+		}
+	} else if op.Block <= rewards.LastBlock {
+		return nil
+	}
+	rewards.addBlock(op)
+
+	blob, err := rlp.EncodeToBytes(rewards)
+	if err != nil {
+		return err
+	}
+	epoch.db.SetSync(calcEpochRewardsKey(op.Epoch), blob)
+	return nil
+}
+
+func (rewards *EpochRewards) addBlock(op *tmTypes.BlockRewardsOp) {
+	rewards.LastBlock = op.Block
+	rewards.Reward.Add(rewards.Reward, op.Reward)
+	rewards.GasFees.Add(rewards.GasFees, op.GasFees)
+	rewards.Treasury.Add(rewards.Treasury, op.Treasury)
+	rewards.Banned = append(rewards.Banned, op.Banned...)
+
+	val := rewards.Validator(op.Validator)
+	if val == nil {
+		val = &ValidatorRewards{
+			Address:    op.Validator,
+			Reward:     new(big.Int),
+			Commission: new(big.Int),
+		}
+		rewards.Validators = append(rewards.Validators, val)
+	}
+	val.Blocks++
+	val.Commission.Add(val.Commission, op.Commission)
+
+	delegators := make(map[common.Address]*big.Int, len(val.Delegators))
+	for _, delegator := range val.Delegators {
+		delegators[delegator.Address] = delegator.Reward
+	}
+	for addr, reward := range op.Rewards {
+		if addr == op.Validator {
+			val.Reward.Add(val.Reward, reward)
+		} else if total, ok := delegators[addr]; ok {
+			total.Add(total, reward)
+		} else {
+			val.Delegators = append(val.Delegators, &DelegatorReward{Address: addr, Reward: new(big.Int).Set(reward)})
+		}
+	}
+}
+
+// Validator returns the rewards of the validator, or nil if it proposed no
+// block recorded.
+func (rewards *EpochRewards) Validator(addr common.Address) *ValidatorRewards {
+	for _, val := range rewards.Validators {
+		if val.Address == addr {
+			return val
+		}
+	}
+	return nil
+}
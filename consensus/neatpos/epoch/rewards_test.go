@@ -0,0 +1,69 @@
+package epoch
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	tmTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	dbm "github.com/neatlib/db-go"
+)
+
+func TestRecordBlockRewards(t *testing.T) {
+	ep := &Epoch{db: dbm.NewMemDB()}
+	val, other, delegator := common.Address{1}, common.Address{2}, common.Address{3}
+
+	blockOp := func(block uint64, validator common.Address, rewards map[common.Address]*big.Int) *tmTypes.BlockRewardsOp {
+		return &tmTypes.BlockRewardsOp{
+			Epoch:      2,
+			Block:      block,
+			Validator:  validator,
+			Reward:     big.NewInt(100),
+			GasFees:    big.NewInt(10),
+			Treasury:   big.NewInt(20),
+			Commission: big.NewInt(5),
+			Rewards:    rewards,
+		}
+	}
+	ops := []*tmTypes.BlockRewardsOp{
+		blockOp(10, val, map[common.Address]*big.Int{val: big.NewInt(60), delegator: big.NewInt(50)}),
+		blockOp(11, other, map[common.Address]*big.Int{other: big.NewInt(70), delegator: big.NewInt(40)}),
+		blockOp(12, val, map[common.Address]*big.Int{val: big.NewInt(80), delegator: big.NewInt(30)}),
+	}
+	ops[2].Banned = []common.Address{other}
+	for _, op := range ops {
+		if err := ep.RecordBlockRewards(op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Blocks written again are not counted twice
+	if err := ep.RecordBlockRewards(ops[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	rewards := LoadEpochRewards(ep.db, 2)
+	if rewards == nil {
+		t.Fatal("epoch rewards not recorded")
+	}
+	if rewards.FirstBlock != 10 || rewards.LastBlock != 12 {
+		t.Errorf("block range mismatch: have %d-%d, want 10-12", rewards.FirstBlock, rewards.LastBlock)
+	}
+	if rewards.Reward.Int64() != 300 || rewards.GasFees.Int64() != 30 || rewards.Treasury.Int64() != 60 {
+		t.Errorf("totals mismatch: reward %v, fees %v, treasury %v", rewards.Reward, rewards.GasFees, rewards.Treasury)
+	}
+	if len(rewards.Banned) != 1 || rewards.Banned[0] != other {
+		t.Errorf("banned validators mismatch: %v", rewards.Banned)
+	}
+	v := rewards.Validator(val)
+	if v == nil || v.Blocks != 2 || v.Reward.Int64() != 140 || v.Commission.Int64() != 10 {
+		t.Errorf("validator rewards mismatch: %+v", v)
+	} else if len(v.Delegators) != 1 || v.Delegators[0].Address != delegator || v.Delegators[0].Reward.Int64() != 80 {
+		t.Errorf("delegator rewards mismatch: %+v", v.Delegators)
+	}
+	if o := rewards.Validator(other); o == nil || o.Blocks != 1 || len(o.Delegators) != 1 || o.Delegators[0].Reward.Int64() != 40 {
+		t.Errorf("other validator rewards mismatch: %+v", o)
+	}
+	if LoadEpochRewards(ep.db, 3) != nil {
+		t.Error("rewards of an epoch without blocks")
+	}
+}
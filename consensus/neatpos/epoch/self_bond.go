@@ -0,0 +1,30 @@
+package epoch
+
+import (
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/state"
+)
+
+// jailBelowMinSelfBond bans the validators and candidates whose self bond
+// fell below the minimum they committed to, as they would for not signing.
+func (epoch *Epoch) jailBelowMinSelfBond(statedb *state.StateDB) {
+	addrs := make(map[common.Address]struct{})
+	for _, v := range epoch.Validators.Validators {
+		addrs[common.BytesToAddress(v.Address)] = struct{}{}
+	}
+	for addr := range statedb.GetCandidateSet() {
+		addrs[addr] = struct{}{}
+	}
+
+	for addr := range addrs {
+		if statedb.GetBanned(addr) || !statedb.IsBelowMinSelfBond(addr) {
+			continue
+		}
+		epoch.logger.Infof("Validator %x banned, self bond %v below its minimum %v", addr, statedb.GetSelfBond(addr), statedb.GetMinSelfBond(addr))
+		statedb.SetBanned(addr, true)
+		statedb.SetBannedTime(addr, new(big.Int).Set(BannedEpoch))
+		statedb.MarkAddressBanned(addr)
+	}
+}
@@ -0,0 +1,48 @@
+package epoch
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	tmTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/log"
+)
+
+func TestJailBelowMinSelfBond(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	bonded := common.BytesToAddress([]byte{0x01})
+	undelegated := common.BytesToAddress([]byte{0x02})
+	unset := common.BytesToAddress([]byte{0x03})
+	for _, addr := range []common.Address{bonded, undelegated, unset} {
+		statedb.ApplyForCandidate(addr, "", 10)
+		statedb.MarkAddressCandidate(addr)
+		statedb.AddDepositBalance(addr, big.NewInt(50))
+	}
+	// Self delegation counts, unless being refunded
+	statedb.AddDepositProxiedBalanceByUser(bonded, bonded, big.NewInt(50))
+	statedb.AddDepositProxiedBalanceByUser(undelegated, undelegated, big.NewInt(50))
+	statedb.AddPendingRefundBalanceByUser(undelegated, undelegated, big.NewInt(50))
+	statedb.SetMinSelfBond(bonded, big.NewInt(100))
+	statedb.SetMinSelfBond(undelegated, big.NewInt(100))
+
+	ep := &Epoch{
+		Validators: tmTypes.NewValidatorSet([]*tmTypes.Validator{
+			tmTypes.NewValidator(bonded[:], nil, big.NewInt(100)),
+		}),
+		logger: log.New(),
+	}
+	ep.jailBelowMinSelfBond(statedb)
+
+	for addr, banned := range map[common.Address]bool{bonded: false, undelegated: true, unset: false} {
+		if have := statedb.GetBanned(addr); have != banned {
+			t.Errorf("%x: banned mismatch: have %v, want %v", addr, have, banned)
+		}
+	}
+	if have := statedb.GetBannedTime(undelegated); have.Cmp(BannedEpoch) != 0 {
+		t.Errorf("banned epochs mismatch: have %v, want %v", have, BannedEpoch)
+	}
+}
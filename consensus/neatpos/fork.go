@@ -0,0 +1,212 @@
+package neatpos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/metrics"
+	"github.com/neatlab/neatio/rlp"
+	dbm "github.com/neatlib/db-go"
+)
+
+var (
+	// errConflictingCommit is returned for every header once two blocks were seen
+	// committed at one height: the chain is halted until the operator steps in.
+	errConflictingCommit = errors.New("conflicting commits detected, chain halted")
+
+	// errCommitNotForHeader is returned for a header at the height of a local
+	// block carrying a commit which is not for the header itself, as a commit
+	// of another block copied to a forged header would be.
+	errCommitNotForHeader = errors.New("commit not for the header")
+)
+
+const (
+	forkReportsKey = "ForkReports"
+	forkHaltKey    = "ForkHalt" // Fork halting the chain, until cleared by the operator
+)
+
+// ForkReport describes two blocks committed at one height, each with a valid
+// commit of more than two thirds of the validators. It takes more than one
+// third of the validators signing both commits, or a misconfigured chain, to
+// get there.
+type ForkReport struct {
+	Height        uint64           `json:"height"`
+	Epoch         uint64           `json:"epoch"`
+	Local         common.Hash      `json:"local"` // Block of the local chain
+	LocalRound    uint64           `json:"localRound"`
+	LocalSigners  []common.Address `json:"localSigners"`
+	Remote        common.Hash      `json:"remote"` // Conflicting block received
+	RemoteRound   uint64           `json:"remoteRound"`
+	RemoteSigners []common.Address `json:"remoteSigners"`
+	DoubleSigners []common.Address `json:"doubleSigners"` // Validators that signed both commits
+	Time          uint64           `json:"time"`          // Unix time of the detection
+}
+
+func (r *ForkReport) String() string {
+	doubleSigners := make([]string, len(r.DoubleSigners))
+	for i, addr := range r.DoubleSigners {
+		doubleSigners[i] = addr.String()
+	}
+	return fmt.Sprintf("ForkReport{Height:%v, Epoch:%v, Local:%x (round %v, %v signers), Remote:%x (round %v, %v signers), DoubleSigners:[%v]}",
+		r.Height, r.Epoch, r.Local, r.LocalRound, len(r.LocalSigners), r.Remote, r.RemoteRound, len(r.RemoteSigners), strings.Join(doubleSigners, ","))
+}
+
+// LoadForkReports loads the conflicting commits detected so far, in the order
+// of their detection.
+func LoadForkReports(db dbm.DB) []*ForkReport {
+	reports := make([]*ForkReport, 0)
+	if blob := db.Get([]byte(forkReportsKey)); len(blob) > 0 {
+		if err := rlp.DecodeBytes(blob, &reports); err != nil {
+			return make([]*ForkReport, 0)
+		}
+	}
+	return reports
+}
+
+// loadForkHalt loads the fork halting the chain, nil if it is not halted.
+func loadForkHalt(db dbm.DB) *ForkReport {
+	blob := db.Get([]byte(forkHaltKey))
+	if len(blob) == 0 {
+		return nil
+	}
+	report := new(ForkReport)
+	if err := rlp.DecodeBytes(blob, report); err != nil {
+		return nil
+	}
+	return report
+}
+
+// committedHash returns the hash the commit of a block is signed for: the hash
+// of its NeatconExtra as proposed, before the flags set on commit.
+func committedHash(ncExtra *ncTypes.NeatconExtra) []byte {
+	proposed := *ncExtra
+	proposed.NeedToSave, proposed.NeedToBroadcast = false, false
+	return proposed.Hash()
+}
+
+// isConflictingCommit returns whether the commits of two headers at one height
+// are for different blocks. The commit verification does not bind the commit to
+// the header, so a remote header with the commit of another block is rejected
+// with errCommitNotForHeader rather than taken for a fork.
+func isConflictingCommit(localExtra, remoteExtra *ncTypes.NeatconExtra) (bool, error) {
+	committed := remoteExtra.SeenCommit.BlockID.Hash
+	if len(committed) == 0 || !bytes.Equal(committed, committedHash(remoteExtra)) {
+		return false, errCommitNotForHeader
+	}
+	if bytes.Equal(committed, localExtra.SeenCommit.BlockID.Hash) {
+		// The local block was committed, the header differs from it elsewhere
+		return false, errCommitNotForHeader
+	}
+	return true, nil
+}
+
+// checkConflictingCommit compares a header carrying a valid commit with the
+// block of the local chain at its height. Two different blocks committed at
+// one height mean the safety of the consensus is broken: the fork is reported
+// and the chain halted.
+func (sb *backend) checkConflictingCommit(chain consensus.ChainReader, header *types.Header, ncExtra *ncTypes.NeatconExtra, valSet *ncTypes.ValidatorSet) error {
+	local := chain.GetHeaderByNumber(header.Number.Uint64())
+	if local == nil || local.Hash() == header.Hash() {
+		return nil
+	}
+	localExtra, err := ncTypes.ExtractNeatconExtra(local)
+	if err != nil || localExtra.SeenCommit == nil || localExtra.SeenCommit.BitArray == nil {
+		return nil
+	}
+	if conflicting, err := isConflictingCommit(localExtra, ncExtra); !conflicting {
+		return err
+	}
+
+	report := &ForkReport{
+		Height:      header.Number.Uint64(),
+		Epoch:       ncExtra.EpochNumber,
+		Local:       local.Hash(),
+		LocalRound:  uint64(localExtra.SeenCommit.Round),
+		Remote:      header.Hash(),
+		RemoteRound: uint64(ncExtra.SeenCommit.Round),
+		Time:        uint64(time.Now().Unix()),
+	}
+	for i, val := range valSet.Validators {
+		addr := common.BytesToAddress(val.Address)
+		signedLocal := localExtra.SeenCommit.BitArray.GetIndex(uint64(i))
+		signedRemote := ncExtra.SeenCommit.BitArray.GetIndex(uint64(i))
+		if signedLocal {
+			report.LocalSigners = append(report.LocalSigners, addr)
+		}
+		if signedRemote {
+			report.RemoteSigners = append(report.RemoteSigners, addr)
+		}
+		if signedLocal && signedRemote {
+			report.DoubleSigners = append(report.DoubleSigners, addr)
+		}
+	}
+	sb.reportFork(report)
+	return errConflictingCommit
+}
+
+// reportFork records the fork, notifies the subscribers of the consensus
+// events and halts the chain: the consensus is stopped and no header is
+// accepted anymore, after a restart as well.
+func (sb *backend) reportFork(report *ForkReport) {
+	sb.forkMu.Lock()
+	first := sb.fork == nil
+	if first {
+		sb.fork = report
+		if blob, err := rlp.EncodeToBytes(report); err == nil {
+			sb.core.epochDB.SetSync([]byte(forkHaltKey), blob)
+		}
+	}
+	reports := LoadForkReports(sb.core.epochDB)
+	for _, known := range reports {
+		if known.Remote == report.Remote {
+			sb.forkMu.Unlock()
+			return
+		}
+	}
+	if blob, err := rlp.EncodeToBytes(append(reports, report)); err == nil {
+		sb.core.epochDB.SetSync([]byte(forkReportsKey), blob)
+	}
+	sb.forkMu.Unlock()
+
+	sb.logger.Error("Conflicting commits detected, halting the chain", "report", report.String())
+	for _, addr := range report.DoubleSigners {
+		sb.logger.Error("Validator signed both conflicting commits", "height", report.Height, "validator", addr.String())
+	}
+	metrics.GetOrRegisterCounter("consensus/"+sb.chainConfig.NeatChainId+"/forks", nil).Inc(1)
+	sb.consensusFeed.Send(consensus.ConsensusEvent{Type: consensus.ConflictingCommitEvent, Height: report.Height, Round: int(report.RemoteRound)})
+
+	if first {
+		// The headers may be verified by the consensus itself, don't wait on it
+		go func() {
+			if err := sb.Stop(); err != nil && err != ErrStoppedEngine {
+				sb.logger.Error("Failed to stop the consensus after a fork", "err", err)
+			}
+		}()
+	}
+}
+
+// halted returns the first fork detected, or nil if the chain is not halted.
+func (sb *backend) halted() *ForkReport {
+	sb.forkMu.Lock()
+	defer sb.forkMu.Unlock()
+	return sb.fork
+}
+
+// resumeAfterFork clears the halt of the chain, once the operator resolved the
+// fork. It returns the fork which halted the chain, nil if it was not halted.
+func (sb *backend) resumeAfterFork() *ForkReport {
+	sb.forkMu.Lock()
+	defer sb.forkMu.Unlock()
+
+	report := sb.fork
+	sb.fork = nil
+	sb.core.epochDB.DeleteSync([]byte(forkHaltKey))
+	return report
+}
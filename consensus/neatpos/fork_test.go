@@ -0,0 +1,80 @@
+package neatpos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/rlp"
+	dbm "github.com/neatlib/db-go"
+)
+
+// committedExtra returns the NeatconExtra of a block proposed at the time,
+// with a commit for it.
+func committedExtra(t time.Time) *ncTypes.NeatconExtra {
+	extra := &ncTypes.NeatconExtra{ChainID: "neatio", Height: 10, Time: t, ValidatorsHash: []byte{1}}
+	extra.SeenCommit = &ncTypes.Commit{BlockID: ncTypes.BlockID{Hash: committedHash(extra)}}
+	return extra
+}
+
+func TestIsConflictingCommit(t *testing.T) {
+	local := committedExtra(time.Unix(1600000000, 0))
+
+	// A block committed at the same height
+	remote := committedExtra(time.Unix(1600000001, 0))
+	if conflicting, err := isConflictingCommit(local, remote); !conflicting || err != nil {
+		t.Errorf("other committed block: have %v, %v, want conflict", conflicting, err)
+	}
+	// The flags set on commit do not change the committed hash
+	remote.NeedToSave, remote.NeedToBroadcast = true, true
+	if conflicting, err := isConflictingCommit(local, remote); !conflicting || err != nil {
+		t.Errorf("other committed block with flags: have %v, %v, want conflict", conflicting, err)
+	}
+
+	// The local commit copied to a header differing outside the consensus data
+	copied := *local
+	if conflicting, err := isConflictingCommit(local, &copied); conflicting || err != errCommitNotForHeader {
+		t.Errorf("local commit copied: have %v, %v, want %v", conflicting, err, errCommitNotForHeader)
+	}
+	// The local commit copied to a header with other consensus data
+	forged := committedExtra(time.Unix(1600000002, 0))
+	forged.SeenCommit = local.SeenCommit
+	if conflicting, err := isConflictingCommit(local, forged); conflicting || err != errCommitNotForHeader {
+		t.Errorf("local commit on forged header: have %v, %v, want %v", conflicting, err, errCommitNotForHeader)
+	}
+	// A commit for no block
+	forged.SeenCommit = &ncTypes.Commit{}
+	if conflicting, err := isConflictingCommit(local, forged); conflicting || err != errCommitNotForHeader {
+		t.Errorf("empty commit: have %v, %v, want %v", conflicting, err, errCommitNotForHeader)
+	}
+}
+
+func TestForkHaltPersistence(t *testing.T) {
+	db := dbm.NewMemDB()
+	if report := loadForkHalt(db); report != nil {
+		t.Fatalf("chain halted without fork: %v", report)
+	}
+
+	report := &ForkReport{Height: 10, Local: common.Hash{1}, Remote: common.Hash{2}, DoubleSigners: []common.Address{{3}}}
+	blob, err := rlp.EncodeToBytes(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetSync([]byte(forkHaltKey), blob)
+
+	// The halt is loaded again after a restart
+	sb := &backend{core: &Node{epochDB: db}}
+	if sb.fork = loadForkHalt(db); sb.halted() == nil || sb.halted().Remote != report.Remote {
+		t.Fatalf("halt not loaded: %v", sb.halted())
+	}
+	if resumed := sb.resumeAfterFork(); resumed == nil || resumed.Height != report.Height {
+		t.Errorf("resumed fork mismatch: have %v, want %v", resumed, report)
+	}
+	if sb.halted() != nil || loadForkHalt(db) != nil {
+		t.Errorf("chain still halted after resume")
+	}
+	if resumed := sb.resumeAfterFork(); resumed != nil {
+		t.Errorf("resumed chain not halted: %v", resumed)
+	}
+}
@@ -36,8 +36,9 @@ func (sb *backend) HandleMsg(chID uint64, src consensus.Peer, msgBytes []byte) (
 	sb.coreMu.Lock()
 	defer sb.coreMu.Unlock()
 
-	sb.core.consensusReactor.Receive(chID, src, msgBytes)
-
+	if err := sb.core.consensusReactor.Receive(chID, src, msgBytes); err != nil {
+		return false, err
+	}
 	return false, nil
 }
 
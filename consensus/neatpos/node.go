@@ -18,8 +18,11 @@ import (
 
 type Node struct {
 	cmn.BaseService
-	privValidator    *types.PrivValidator
+	privValidator     *types.PrivValidator
+	privValidatorFile string
 	epochDB          dbm.DB
+	misbehaviorDB    dbm.DB
+	wal              *consensus.WAL
 	evsw             types.EventSwitch
 	consensusState   *consensus.ConsensusState
 	consensusReactor *consensus.ConsensusReactor
@@ -33,6 +36,15 @@ func NewNodeNotStart(backend *backend, config cfg.Config, chainConfig *params.Ch
 	if _, err := os.Stat(privValidatorFile); err == nil {
 		privValidator = types.LoadPrivValidator(privValidatorFile)
 	}
+	// The log stays open for the life of the process, as the consensus may be
+	// stopped and started again. Every entry is synced when recorded.
+	if privValidator != nil {
+		if auditLog, err := types.OpenSignAuditLog(types.SignAuditFile(privValidatorFile)); err != nil {
+			backend.logger.Error("Failed to open signing audit log, signing without", "err", err)
+		} else {
+			privValidator.SetSignAuditLog(auditLog)
+		}
+	}
 
 	epochDB := dbm.NewDB("epoch", config.GetString("db_backend"), config.GetString("db_dir"))
 	ep := epoch.InitEpoch(epochDB, genDoc, backend.logger)
@@ -44,6 +56,14 @@ func NewNodeNotStart(backend *backend, config cfg.Config, chainConfig *params.Ch
 	}
 
 	consensusState := consensus.NewConsensusState(backend, config, chainConfig, cch, ep)
+	misbehaviorDB := dbm.NewDB("misbehavior", config.GetString("db_backend"), config.GetString("db_dir"))
+	consensusState.SetMisbehaviorDB(misbehaviorDB)
+	wal, err := consensus.OpenWAL(config.GetString("cs_wal_file"), config.GetBool("cs_wal_light"))
+	if err != nil {
+		backend.logger.Warn("Failed to open consensus WAL, running without", "err", err)
+	} else {
+		consensusState.SetWAL(wal)
+	}
 	if privValidator != nil {
 		consensusState.SetPrivValidator(privValidator)
 	}
@@ -53,9 +73,12 @@ func NewNodeNotStart(backend *backend, config cfg.Config, chainConfig *params.Ch
 	SetEventSwitch(eventSwitch, consensusReactor)
 
 	node := &Node{
-		privValidator: privValidator,
+		privValidator:     privValidator,
+		privValidatorFile: privValidatorFile,
 
-		epochDB: epochDB,
+		epochDB:       epochDB,
+		misbehaviorDB: misbehaviorDB,
+		wal:           wal,
 
 		evsw: eventSwitch,
 
@@ -68,9 +91,41 @@ func NewNodeNotStart(backend *backend, config cfg.Config, chainConfig *params.Ch
 	}
 	node.BaseService = *cmn.NewBaseService(backend.logger, "Node", node)
 
+	// The node may have been down while the epoch switched to a rotated key
+	node.rotatePrivValidator(ep)
+
 	return node
 }
 
+// rotatePrivValidator adopts the pending consensus key once the validator set of
+// the given epoch expects it, the old key can no longer sign for this epoch
+func (n *Node) rotatePrivValidator(ep *epoch.Epoch) {
+	if n.privValidator == nil || ep == nil || ep.Validators == nil {
+		return
+	}
+	_, val := ep.Validators.GetByAddress(n.privValidator.Address[:])
+	if val == nil || val.PubKey == nil || val.PubKey.Equals(n.privValidator.PubKey) {
+		return
+	}
+
+	pendingFile := types.PendingPrivValidatorFile(n.privValidatorFile)
+	if _, err := os.Stat(pendingFile); err != nil {
+		n.logger.Warn("Validator set expects a different consensus key, but no pending key found", "file", pendingFile)
+		return
+	}
+	pending := types.LoadPrivValidator(pendingFile)
+	if pending.Address != n.privValidator.Address || !val.PubKey.Equals(pending.PubKey) {
+		n.logger.Warn("Pending consensus key does not match the validator set", "file", pendingFile)
+		return
+	}
+
+	n.privValidator.RotateKey(pending.PubKey, pending.PrivKey)
+	if err := os.Remove(pendingFile); err != nil {
+		n.logger.Warn("Failed to remove pending consensus key file", "file", pendingFile, "err", err)
+	}
+	n.logger.Info("Switched to rotated consensus key", "epoch", ep.Number, "pubkey", pending.PubKey.KeyString())
+}
+
 func (n *Node) OnStart() error {
 
 	n.logger.Info("(n *Node) OnStart()")
@@ -37,7 +37,7 @@ func (s *State) ValidateBlock(block *types.TdmBlock) error {
 //Very current block
 func (s *State) validateBlock(block *types.TdmBlock) error {
 	// Basic block validation.
-	err := block.ValidateBasic(s.NcExtra)
+	err := block.ValidateBasic(s.NcExtra, 0)
 	if err != nil {
 		return err
 	}
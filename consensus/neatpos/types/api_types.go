@@ -67,10 +67,20 @@ type EpochValidator struct {
 
 // For console
 type EpochValidatorForConsole struct {
-	Address        string         `json:"address"`
-	PubKey         string         `json:"publicKey"`
-	Amount         *hexutil.Big   `json:"votingPower"`
-	RemainingEpoch hexutil.Uint64 `json:"remainEpoch"`
+	Address        string                `json:"address"`
+	PubKey         string                `json:"publicKey"`
+	Amount         *hexutil.Big          `json:"votingPower"`
+	RemainingEpoch hexutil.Uint64        `json:"remainEpoch"`
+	Metadata       *ValidatorMetadataApi `json:"metadata,omitempty"`
+}
+
+// ValidatorMetadataApi is the identity a validator published on chain
+type ValidatorMetadataApi struct {
+	Moniker         string `json:"moniker"`
+	Website         string `json:"website"`
+	Identity        string `json:"identity"`
+	Details         string `json:"details"`
+	SecurityContact string `json:"securityContact"`
 }
 
 type NeatconExtraApi struct {
@@ -94,6 +104,7 @@ type CommitApi struct {
 	// BLS signature aggregation to be added here
 	SignAggr crypto.BLSSignature `json:"signAggr"`
 	BitArray *BitArray           `json:"bitArray"`
+	Signers  []string            `json:"signers,omitempty"` // Addresses of the validators in BitArray
 
 	//// Volatile
 	//hash []byte
@@ -114,8 +125,19 @@ type ConsensusAggr struct {
 	Addresses  []common.Address `json:"address"`
 }
 
+type ConsensusStateApi struct {
+	Height      hexutil.Uint64 `json:"height"`
+	Round       int            `json:"round"`
+	Step        string         `json:"step"`
+	StartTime   time.Time      `json:"startTime"`
+	EpochNumber hexutil.Uint64 `json:"epochNumber"`
+	Proposer    string         `json:"proposer"`
+	IsProposer  bool           `json:"isProposer"`
+}
+
 type ValidatorStatus struct {
-	IsBanned bool `json:"isBanned"`
+	IsBanned bool                  `json:"isBanned"`
+	Metadata *ValidatorMetadataApi `json:"metadata,omitempty"`
 }
 
 type CandidateApi struct {
@@ -125,3 +147,134 @@ type CandidateApi struct {
 type BannedApi struct {
 	BannedList []string `json:"bannedList"`
 }
+
+type UpgradeStatusApi struct {
+	Name             string         `json:"name"`
+	ActivationHeight hexutil.Uint64 `json:"activationHeight"` // 0 if not scheduled
+	Activated        bool           `json:"activated"`
+	Supported        bool           `json:"supported"` // by this node
+	ReadyVotingPower *hexutil.Big   `json:"readyVotingPower"`
+	TotalVotingPower *hexutil.Big   `json:"totalVotingPower"`
+	Ready            []string       `json:"ready"`
+	NotReady         []string       `json:"notReady"`
+}
+
+type EpochRewardsApi struct {
+	Number     hexutil.Uint64        `json:"number"`
+	FromBlock  hexutil.Uint64        `json:"fromBlock"` // First block summed up
+	ToBlock    hexutil.Uint64        `json:"toBlock"`   // Last block summed up
+	Reward     *hexutil.Big          `json:"reward"`    // Block rewards paid to the validators and delegators
+	GasFees    *hexutil.Big          `json:"gasFees"`
+	Treasury   *hexutil.Big          `json:"treasury"`
+	Validators []*ValidatorRewardApi `json:"validators"`
+	Banned     []string              `json:"banned"` // Validators banned for missing all the blocks of the epoch
+}
+
+type ValidatorRewardApi struct {
+	Address    string                `json:"address"`
+	Blocks     hexutil.Uint64        `json:"blocks"`
+	Reward     *hexutil.Big          `json:"reward"` // Commission included
+	Commission *hexutil.Big          `json:"commission"`
+	Delegators []*DelegatorRewardApi `json:"delegators"`
+}
+
+type DelegatorRewardApi struct {
+	Address string       `json:"address"`
+	Reward  *hexutil.Big `json:"reward"`
+}
+
+type ValidatorUptimeApi struct {
+	Address   string           `json:"address"`
+	FromBlock hexutil.Uint64   `json:"fromBlock"`
+	ToBlock   hexutil.Uint64   `json:"toBlock"`
+	Blocks    hexutil.Uint64   `json:"blocks"` // Blocks committed while the validator was in the validator set
+	Signed    hexutil.Uint64   `json:"signed"`
+	Uptime    float64          `json:"uptime"` // Share of the blocks signed, between 0 and 1
+	Missed    []hexutil.Uint64 `json:"missed"`
+}
+
+// ValidatorSetProofApi is the validator set of an epoch with the proofs linking
+// each validator to the validators hash committed in the header of a block.
+type ValidatorSetProofApi struct {
+	Epoch          hexutil.Uint64       `json:"epoch"`
+	Block          hexutil.Uint64       `json:"block"`
+	BlockHash      common.Hash          `json:"blockHash"`
+	ValidatorsHash string               `json:"validatorsHash"` // Merkle root of the validators, in the extra data of the block
+	Validators     []*ValidatorProofApi `json:"validators"`
+}
+
+type ValidatorProofApi struct {
+	EpochValidatorForConsole
+	Index   hexutil.Uint64 `json:"index"`
+	Encoded string         `json:"encoded"` // Encoding of the validator, hashed with RIPEMD-160 into the merkle leaf
+	Proof   []string       `json:"proof"`   // Hashes from the sibling of the leaf up to a child of the root
+}
+
+// ChainStatsApi sums up the blocks of a height range. The block intervals are
+// in milliseconds, their percentiles are rounded up to the bounds of the
+// buckets of BlockIntervalBuckets.
+type ChainStatsApi struct {
+	FromBlock        hexutil.Uint64 `json:"fromBlock"`
+	ToBlock          hexutil.Uint64 `json:"toBlock"`
+	Blocks           hexutil.Uint64 `json:"blocks"`
+	AvgBlockInterval float64        `json:"avgBlockInterval"`
+	P50BlockInterval hexutil.Uint64 `json:"p50BlockInterval"`
+	P90BlockInterval hexutil.Uint64 `json:"p90BlockInterval"`
+	P99BlockInterval hexutil.Uint64 `json:"p99BlockInterval"`
+	MaxBlockInterval hexutil.Uint64 `json:"maxBlockInterval"`
+	AvgRounds        float64        `json:"avgRounds"` // Rounds taken to commit a block
+	GasUsed          hexutil.Uint64 `json:"gasUsed"`
+	GasLimit         hexutil.Uint64 `json:"gasLimit"`
+	GasUtilization   float64        `json:"gasUtilization"` // Share of the gas limit used, between 0 and 1
+	Txs              hexutil.Uint64 `json:"txs"`
+	TxsPerBlock      float64        `json:"txsPerBlock"`
+	TxsPerSecond     float64        `json:"txsPerSecond"`
+}
+
+// EpochVoteProgressApi is the progress of the election of the validators of the
+// next epoch: the candidates, the votes received and revealed, and the time
+// left until the validators are elected at the end of the current epoch.
+type EpochVoteProgressApi struct {
+	EpochNumber     hexutil.Uint64            `json:"voteForEpoch"`
+	Status          string                    `json:"status"`
+	StartBlock      hexutil.Uint64            `json:"startBlock"` // First block of the next epoch
+	CurrentBlock    hexutil.Uint64            `json:"currentBlock"`
+	BlocksRemaining hexutil.Uint64            `json:"blocksRemaining"` // Blocks left before the election
+	TimeRemaining   float64                   `json:"timeRemaining"`   // Estimate of the seconds left before the election
+	EstimatedTime   time.Time                 `json:"estimatedTime"`
+	Votes           hexutil.Uint64            `json:"votes"`    // Vote hashes received
+	Revealed        hexutil.Uint64            `json:"revealed"` // Votes revealed
+	PendingReveals  []string                  `json:"pendingReveals"`
+	Candidates      []*CandidateVoteStatusApi `json:"candidates"`
+}
+
+type CandidateVoteStatusApi struct {
+	Address   string `json:"address"`
+	Validator bool   `json:"validator"` // In the validator set of the current epoch
+	Voted     bool   `json:"voted"`
+	Revealed  bool   `json:"revealed"`
+	Banned    bool   `json:"banned"`
+}
+
+// ProposerScheduleApi is the schedule of the proposers of the rounds of the
+// next height, and the share of the following heights each validator is
+// expected to propose: their proposers are drawn from block hashes not known
+// yet.
+type ProposerScheduleApi struct {
+	Height   hexutil.Uint64         `json:"height"`
+	Epoch    hexutil.Uint64         `json:"epoch"`
+	Rounds   []*ProposerRoundApi    `json:"rounds"`
+	Heights  hexutil.Uint64         `json:"heights"`
+	Expected []*ExpectedProposerApi `json:"expected"`
+}
+
+type ProposerRoundApi struct {
+	Round    int    `json:"round"`
+	Proposer string `json:"proposer"`
+}
+
+type ExpectedProposerApi struct {
+	Address        string  `json:"address"`
+	Share          float64 `json:"share"`          // Chance to propose a height, in proportion of the voting power
+	ExpectedBlocks float64 `json:"expectedBlocks"` // Heights expected to be proposed out of the following ones
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
@@ -55,8 +56,9 @@ func MakeBlock(height uint64, chainID string, commit *Commit,
 	return tdmBlock, tdmBlock.MakePartSet(partSize)
 }
 
-// Basic validation that doesn't involve state data.
-func (b *TdmBlock) ValidateBasic(ncExtra *NeatconExtra) error {
+// Basic validation that doesn't involve state data. A non-zero minInterval
+// is the minimum time since the parent block.
+func (b *TdmBlock) ValidateBasic(ncExtra *NeatconExtra, minInterval time.Duration) error {
 
 	if b.NcExtra.ChainID != ncExtra.ChainID {
 		return errors.New(Fmt("Wrong Block.Header.ChainID. Expected %v, got %v", ncExtra.ChainID, b.NcExtra.ChainID))
@@ -64,6 +66,9 @@ func (b *TdmBlock) ValidateBasic(ncExtra *NeatconExtra) error {
 	if b.NcExtra.Height != ncExtra.Height+1 {
 		return errors.New(Fmt("Wrong Block.Header.Height. Expected %v, got %v", ncExtra.Height+1, b.NcExtra.Height))
 	}
+	if minInterval > 0 && b.NcExtra.Time.Sub(ncExtra.Time) < minInterval {
+		return errors.New(Fmt("Block.Header.Time too early. Expected %v after %v, got %v", minInterval, ncExtra.Time, b.NcExtra.Time))
+	}
 
 	/*
 		if !b.NcExtra.BlockID.Equals(blockID) {
@@ -81,6 +86,20 @@ func (b *TdmBlock) ValidateBasic(ncExtra *NeatconExtra) error {
 	return nil
 }
 
+// ValidateTime checks that the block time is after the time of the parent and,
+// for a non-zero maxDrift, at most maxDrift away from now.
+func (b *TdmBlock) ValidateTime(ncExtra *NeatconExtra, now time.Time, maxDrift time.Duration) error {
+	if !b.NcExtra.Time.After(ncExtra.Time) {
+		return errors.New(Fmt("Block.Header.Time not after parent. Expected after %v, got %v", ncExtra.Time, b.NcExtra.Time))
+	}
+	if maxDrift > 0 {
+		if drift := b.NcExtra.Time.Sub(now); drift > maxDrift || drift < -maxDrift {
+			return errors.New(Fmt("Block.Header.Time drifts %v from local time, more than %v", drift, maxDrift))
+		}
+	}
+	return nil
+}
+
 func (b *TdmBlock) FillSeenCommitHash() {
 	if b.NcExtra.SeenCommitHash == nil {
 		b.NcExtra.SeenCommitHash = b.NcExtra.SeenCommit.Hash()
@@ -112,55 +131,151 @@ func (b *TdmBlock) ToBytes() []byte {
 	}
 	//fmt.Printf("TdmBlock.toBytes 0 with block: %v\n", b)
 
-	bs, err := rlp.EncodeToBytes(b.Block)
-	if err != nil {
-		log.Warnf("TdmBlock.toBytes error\n")
+	// The block is encoded in pooled buffers, only the bytes returned, which
+	// the parts are slices of, are allocated
+	bs := getEncodeBuffer()
+	defer putEncodeBuffer(bs)
+	if err := rlp.Encode(bs, b.Block); err != nil {
+		log.Warn("Failed to encode block", "number", b.Block.NumberU64(), "err", err)
+		bs.Reset()
 	}
 	bb := &TmpBlock{
-		BlockData:    bs,
+		BlockData:    bs.Bytes(),
 		NcExtra:      b.NcExtra,
 		TX3ProofData: b.TX3ProofData,
 	}
 
-	ret := wire.BinaryBytes(bb)
-	return ret
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+	var n int
+	var err error
+	wire.WriteBinary(bb, buf, &n, &err)
+	if err != nil {
+		PanicSanity(err)
+	}
+	return append([]byte(nil), buf.Bytes()...)
 }
 
+// FromBytes decodes a block encoded by ToBytes. The block data is decoded
+// straight from the reader, usually over the parts of a PartSet, rather than
+// from a copy of it. As the parts come from peers, the input is not trusted:
+// malformed or oversized fields and trailing bytes are rejected with an error.
+//
+// The fields the block hash covers must be in canonical encoding: the block
+// data length, the block data, which RLP decodes canonically only, and the
+// NcExtra. The TX3ProofData is not part of the hash and is checked field by
+// field by the main chain, the go-wire encoding of its headers does not round
+// trip byte for byte.
 func (b *TdmBlock) FromBytes(reader io.Reader) (*TdmBlock, error) {
 
-	type TmpBlock struct {
-		BlockData    []byte
-		NcExtra      *NeatconExtra
-		TX3ProofData []*types.TX3ProofData
+	// The RLP stream must not read ahead of the block data
+	br, ok := reader.(io.ByteReader)
+	if !ok {
+		buffered := bufio.NewReader(reader)
+		reader, br = buffered, buffered
 	}
 
-	//fmt.Printf("TdmBlock.FromBytes \n")
+	// Keep the encoded length and NcExtra, to check their encoding
+	raw := getEncodeBuffer()
+	defer putEncodeBuffer(raw)
+	tee := io.TeeReader(reader, raw)
 
+	// ToBytes writes a pointer to the TmpBlock, its fields follow a non-nil
+	// marker, then the block data length
 	var n int
 	var err error
-	bb := wire.ReadBinary(&TmpBlock{}, reader, MaxBlockSize, &n, &err).(*TmpBlock)
+	if wire.ReadByte(tee, &n, &err) != 0x01 && err == nil {
+		err = errors.New("Unexpected nil block")
+	}
+	length := wire.ReadVarint(tee, &n, &err)
+	if err == nil && (length < 0 || length > MaxBlockSize) {
+		err = wire.ErrBinaryReadInvalidLength
+	}
+	if err == nil {
+		err = CheckCanonical(struct {
+			NotNil byte
+			Length int
+		}{0x01, length}, raw.Bytes())
+	}
 	if err != nil {
-		log.Warnf("TdmBlock.FromBytes 0 error: %v\n", err)
+		log.Warn("Failed to decode block", "err", err)
 		return nil, err
 	}
+	data := &limitedByteReader{r: reader, br: br, remaining: length}
 
 	var block types.Block
-	err = rlp.DecodeBytes(bb.BlockData, &block)
+	err = rlp.NewStream(data, uint64(length)).Decode(&block)
+	if err == nil && data.remaining != 0 {
+		err = rlp.ErrMoreThanOneValue
+	}
 	if err != nil {
-		log.Warnf("TdmBlock.FromBytes 1 error: %v\n", err)
+		log.Warn("Failed to decode block body", "err", err)
+		return nil, err
+	}
+	n += length
+
+	// The rest of the TmpBlock of ToBytes, after the block data
+	raw.Reset()
+	ncExtra := ReadBinary((*NeatconExtra)(nil), tee, MaxBlockSize, &n, &err).(*NeatconExtra)
+	if err == nil {
+		err = CheckCanonical(ncExtra, raw.Bytes())
+	}
+	var tx3ProofData []*types.TX3ProofData
+	if err == nil {
+		tx3ProofData = ReadBinary([]*types.TX3ProofData{}, reader, MaxBlockSize, &n, &err).([]*types.TX3ProofData)
+	}
+	if err == nil {
+		err = expectEOF(reader)
+	}
+	if err != nil {
+		log.Warn("Failed to decode block", "err", err)
 		return nil, err
 	}
 
 	tdmBlock := &TdmBlock{
 		Block:        &block,
-		NcExtra:      bb.NcExtra,
-		TX3ProofData: bb.TX3ProofData,
+		NcExtra:      ncExtra,
+		TX3ProofData: tx3ProofData,
+	}
+	if err := tdmBlock.validateDecoded(); err != nil {
+		log.Warn("Invalid block", "number", block.NumberU64(), "err", err)
+		return nil, err
 	}
 
-	log.Debugf("TdmBlock.FromBytes 2 with: %v\n", tdmBlock)
+	log.Debug("Decoded block", "number", block.NumberU64(), "hash", block.Hash())
 	return tdmBlock, nil
 }
 
+// limitedByteReader reads at most remaining bytes of r, byte by byte too.
+type limitedByteReader struct {
+	r         io.Reader
+	br        io.ByteReader
+	remaining int
+}
+
+func (l *limitedByteReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if len(p) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= n
+	return n, err
+}
+
+func (l *limitedByteReader) ReadByte() (byte, error) {
+	if l.remaining <= 0 {
+		return 0, io.EOF
+	}
+	c, err := l.br.ReadByte()
+	if err == nil {
+		l.remaining--
+	}
+	return c, err
+}
+
 // Convenience.
 // A nil block never hashes to anything.
 // Nothing hashes to a nil hash.
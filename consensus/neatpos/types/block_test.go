@@ -0,0 +1,172 @@
+package types
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/types"
+	. "github.com/neatlib/common-go"
+)
+
+func TestValidateBasicMinInterval(t *testing.T) {
+	parentTime := time.Unix(1600000000, 0)
+	parent := &NeatconExtra{ChainID: "neatio", Height: 10, Time: parentTime}
+
+	tests := []struct {
+		delay       time.Duration
+		minInterval time.Duration
+		valid       bool
+	}{
+		{time.Second, 0, true},
+		{time.Second, 5 * time.Second, false},
+		{5 * time.Second, 5 * time.Second, true},
+		{10 * time.Second, 5 * time.Second, true},
+	}
+	for i, tt := range tests {
+		block := &TdmBlock{NcExtra: &NeatconExtra{ChainID: "neatio", Height: 11, Time: parentTime.Add(tt.delay)}}
+		if err := block.ValidateBasic(parent, tt.minInterval); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+func TestValidateTime(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	parent := &NeatconExtra{Time: now.Add(-5 * time.Second)}
+
+	tests := []struct {
+		time     time.Time
+		maxDrift time.Duration
+		valid    bool
+	}{
+		{now, 10 * time.Second, true},
+		{parent.Time, 10 * time.Second, false},              // Not after the parent
+		{now.Add(-6 * time.Second), 0, false},               // Before the parent
+		{now.Add(time.Minute), 10 * time.Second, false},     // Too far in the future
+		{now.Add(time.Minute), 0, true},                     // Drift unchecked
+		{now.Add(-4 * time.Second), 2 * time.Second, false}, // Too far in the past
+	}
+	for i, tt := range tests {
+		block := &TdmBlock{NcExtra: &NeatconExtra{Time: tt.time}}
+		if err := block.ValidateTime(parent, now, tt.maxDrift); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want valid %v", i, err, tt.valid)
+		}
+	}
+}
+
+func TestBlockPartSetRoundTrip(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(11), Extra: bytes.Repeat([]byte{1}, 5000)}
+	block := &TdmBlock{
+		Block:   types.NewBlockWithHeader(header),
+		NcExtra: &NeatconExtra{ChainID: "neatio", Height: 11, Time: time.Unix(1600000000, 0), SeenCommit: &Commit{}},
+	}
+	// The encodings reuse the pooled buffers
+	first := block.MakePartSet(1024)
+	second := block.MakePartSet(1024)
+	if !first.HashesTo(second.Hash()) || first.Total() != 6 {
+		t.Fatalf("part sets mismatch: %v, %v", first.Header(), second.Header())
+	}
+	// The block is decoded over the parts, or over any reader
+	data := block.ToBytes()
+	for _, reader := range []io.Reader{first.GetReader(), io.MultiReader(bytes.NewReader(data[:100]), bytes.NewReader(data[100:]))} {
+		decoded, err := new(TdmBlock).FromBytes(reader)
+		if err != nil {
+			t.Fatalf("failed to decode the block: %v", err)
+		}
+		if decoded.Block.Hash() != block.Block.Hash() || decoded.NcExtra.Height != 11 {
+			t.Errorf("decoded block mismatch: %v", decoded.Block.Header())
+		}
+	}
+	if _, err := new(TdmBlock).FromBytes(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Errorf("truncated block decoded")
+	}
+}
+
+func TestBlockFromBytesMalformed(t *testing.T) {
+	valid := func() *TdmBlock {
+		return &TdmBlock{
+			Block:   types.NewBlockWithHeader(&types.Header{Number: big.NewInt(11)}),
+			NcExtra: &NeatconExtra{ChainID: "neatio", Height: 11, Time: time.Unix(1600000000, 0), SeenCommit: &Commit{}},
+		}
+	}
+	data := valid().ToBytes()
+	if _, err := new(TdmBlock).FromBytes(bytes.NewReader(data)); err != nil {
+		t.Fatalf("failed to decode valid block: %v", err)
+	}
+	if _, err := new(TdmBlock).FromBytes(bytes.NewReader(append(data, 0))); err != ErrTrailingBytes {
+		t.Errorf("block with trailing bytes: have %v, want %v", err, ErrTrailingBytes)
+	}
+	// The block data length is a varint, which decodes the same when padded
+	if size := int(data[1]); size > 0 && size < 8 {
+		padded := append([]byte{data[0], byte(size + 1), 0}, data[2:]...)
+		if _, err := new(TdmBlock).FromBytes(bytes.NewReader(padded)); err != ErrNonCanonical {
+			t.Errorf("block with padded length: have %v, want %v", err, ErrNonCanonical)
+		}
+	} else {
+		t.Fatalf("unexpected block data length encoding %x", data[:2])
+	}
+
+	tests := map[string]func(b *TdmBlock){
+		"nil extra":       func(b *TdmBlock) { b.NcExtra = nil },
+		"nil commit":      func(b *TdmBlock) { b.NcExtra.SeenCommit = nil },
+		"long chain ID":   func(b *TdmBlock) { b.NcExtra.ChainID = string(make([]byte, maxChainIDSize+1)) },
+		"long hash":       func(b *TdmBlock) { b.NcExtra.ValidatorsHash = make([]byte, maxHashSize+1) },
+		"bad bit array":   func(b *TdmBlock) { b.NcExtra.SeenCommit.BitArray = &BitArray{Bits: 100} },
+		"huge bit array":  func(b *TdmBlock) { b.NcExtra.SeenCommit.BitArray = NewBitArray(MaxValidatorSetSize + 1) },
+		"excess proofs":   func(b *TdmBlock) { b.TX3ProofData = []*types.TX3ProofData{{Header: &types.Header{}}} },
+		"proof no header": func(b *TdmBlock) { b.TX3ProofData = []*types.TX3ProofData{nil} },
+	}
+	for name, corrupt := range tests {
+		block := valid()
+		corrupt(block)
+		if _, err := new(TdmBlock).FromBytes(bytes.NewReader(block.ToBytes())); err == nil {
+			t.Errorf("%s: invalid block decoded", name)
+		}
+	}
+}
+
+func TestBlockFromBytesTX3ProofData(t *testing.T) {
+	tx := types.NewTransaction(0, common.Address{1}, big.NewInt(1), 0, big.NewInt(1), nil)
+	proof := &types.TX3ProofData{Header: &types.Header{
+		ParentHash: common.Hash{1},
+		Number:     big.NewInt(7),
+		GasLimit:   8000000,
+		GasUsed:    21000,
+		Time:       big.NewInt(1600000000),
+		Difficulty: big.NewInt(1),
+		Extra:      []byte("side chain header"),
+	}}
+	block := &TdmBlock{
+		Block:        types.NewBlockWithHeader(&types.Header{Number: big.NewInt(11)}).WithBody([]*types.Transaction{tx}, nil),
+		NcExtra:      &NeatconExtra{ChainID: "neatio", Height: 11, Time: time.Unix(1600000000, 0), SeenCommit: &Commit{}},
+		TX3ProofData: []*types.TX3ProofData{proof},
+	}
+	decoded, err := new(TdmBlock).FromBytes(bytes.NewReader(block.ToBytes()))
+	if err != nil {
+		t.Fatalf("failed to decode block with TX3 proof: %v", err)
+	}
+	if len(decoded.TX3ProofData) != 1 || decoded.TX3ProofData[0].Header.Hash() != proof.Header.Hash() {
+		t.Errorf("decoded TX3 proof mismatch: %v", decoded.TX3ProofData)
+	}
+}
+
+func TestBlockFromBytesNoPanic(t *testing.T) {
+	block := &TdmBlock{
+		Block:   types.NewBlockWithHeader(&types.Header{Number: big.NewInt(11)}),
+		NcExtra: &NeatconExtra{ChainID: "neatio", Height: 11, Time: time.Unix(1600000000, 0), SeenCommit: &Commit{BitArray: NewBitArray(4)}},
+	}
+	data := block.ToBytes()
+
+	// Corrupt every byte in turn, the decoding may fail but must not panic
+	for i := range data {
+		for _, b := range []byte{0x00, 0x01, 0x7f, 0xff} {
+			corrupted := append([]byte(nil), data...)
+			corrupted[i] = b
+			new(TdmBlock).FromBytes(bytes.NewReader(corrupted))
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/neatlib/bls-go/bn256"
+	"github.com/neatlib/crypto-go"
+)
+
+var (
+	ErrInvalidBLSSignature = errors.New("invalid BLS signature")
+	ErrInvalidBLSPubKey    = errors.New("invalid BLS public key")
+	ErrDuplicateBLSPubKey  = errors.New("duplicate BLS public key")
+)
+
+// checkedPubKeys caches the public keys which passed the subgroup check, the
+// scalar multiplication it takes is too slow to be done for every vote.
+var checkedPubKeys, _ = lru.New(4096)
+
+// ValidateBLSSignature checks that sig is a point of G1 in its canonical
+// encoding, and not the identity. The decoding of the BLS library accepts
+// coordinates above the field modulus, which gives every signature other
+// encodings, and the identity would verify against the identity public key.
+func ValidateBLSSignature(sig crypto.Signature) error {
+	blsSig, ok := sig.(crypto.BLSSignature)
+	if !ok {
+		return ErrInvalidBLSSignature
+	}
+	var p bn256.G1
+	if err := p.Unmarshal(blsSig); err != nil {
+		return ErrInvalidBLSSignature
+	}
+	// G1 has a cofactor of 1, every point of the curve is in the group
+	if p.IsZero() || !bytes.Equal(p.Marshal(), blsSig) {
+		return ErrInvalidBLSSignature
+	}
+	return nil
+}
+
+// checkBLSPubKey checks that pk is a point of the twist in its canonical
+// encoding, and not the identity.
+func checkBLSPubKey(pk crypto.PubKey) (*bn256.G2, error) {
+	blsPK, ok := toBLSPubKey(pk)
+	if !ok {
+		return nil, ErrInvalidBLSPubKey
+	}
+	p := new(bn256.G2)
+	if err := p.Unmarshal(blsPK[:]); err != nil {
+		return nil, ErrInvalidBLSPubKey
+	}
+	if p.IsZero() || !bytes.Equal(p.Marshal(), blsPK[:]) {
+		return nil, ErrInvalidBLSPubKey
+	}
+	return p, nil
+}
+
+// ValidateBLSPubKey checks that pk is an element of G2 other than the
+// identity, in its canonical encoding. Unlike G1, the twist has points of
+// small order outside of G2, which the decoding of the BLS library accepts.
+func ValidateBLSPubKey(pk crypto.PubKey) error {
+	blsPK, ok := toBLSPubKey(pk)
+	if !ok {
+		return ErrInvalidBLSPubKey
+	}
+	if checkedPubKeys.Contains(blsPK) {
+		return nil
+	}
+	p, err := checkBLSPubKey(blsPK)
+	if err != nil {
+		return err
+	}
+	if !new(bn256.G2).ScalarMult(p, bn256.Order).IsZero() {
+		return ErrInvalidBLSPubKey
+	}
+	checkedPubKeys.Add(blsPK, struct{}{})
+	return nil
+}
+
+// VerifyBLS verifies the signature of msg by pubKey, after checking both are
+// valid. pubKey may be an aggregate of validated keys, it isn't checked for
+// the subgroup again.
+func VerifyBLS(pubKey crypto.PubKey, msg []byte, sig crypto.Signature) bool {
+	if ValidateBLSSignature(sig) != nil {
+		return false
+	}
+	if _, err := checkBLSPubKey(pubKey); err != nil {
+		return false
+	}
+	return pubKey.VerifyBytes(msg, sig)
+}
+
+// AggregateBLSPubKeys aggregates the public keys after validating them. The
+// same key can't be aggregated twice, it would count the signature of its
+// owner more than once.
+func AggregateBLSPubKeys(pks []*crypto.PubKey) (crypto.PubKey, error) {
+	seen := make(map[crypto.BLSPubKey]struct{}, len(pks))
+	for _, pk := range pks {
+		if pk == nil {
+			return nil, ErrInvalidBLSPubKey
+		}
+		if err := ValidateBLSPubKey(*pk); err != nil {
+			return nil, err
+		}
+		blsPK, _ := toBLSPubKey(*pk)
+		if _, ok := seen[blsPK]; ok {
+			return nil, ErrDuplicateBLSPubKey
+		}
+		seen[blsPK] = struct{}{}
+	}
+	return *crypto.BLSPubKeyAggregate(pks), nil
+}
+
+// toBLSPubKey returns the BLS key of pk, aggregated keys are held by pointer.
+func toBLSPubKey(pk crypto.PubKey) (crypto.BLSPubKey, bool) {
+	switch pk := pk.(type) {
+	case crypto.BLSPubKey:
+		return pk, true
+	case *crypto.BLSPubKey:
+		if pk != nil {
+			return *pk, true
+		}
+	}
+	return crypto.BLSPubKey{}, false
+}
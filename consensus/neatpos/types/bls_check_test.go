@@ -0,0 +1,112 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	. "github.com/neatlib/common-go"
+	"github.com/neatlib/crypto-go"
+)
+
+// fieldModulus is the modulus of the base field of the BLS curve.
+var fieldModulus, _ = new(big.Int).SetString("65000549695646603732796438742359905742825358107623003571877145026864184071783", 10)
+
+// addModulus adds the field modulus to the coordinate at the start of enc,
+// which encodes the same point if the library doesn't reduce it. Reports
+// false if the sum overflows the coordinate.
+func addModulus(enc []byte) bool {
+	x := new(big.Int).SetBytes(enc[:32])
+	x.Add(x, fieldModulus)
+	if x.BitLen() > 256 {
+		return false
+	}
+	copy(enc[:32], common.LeftPadBytes(x.Bytes(), 32))
+	return true
+}
+
+func TestValidateBLSSignature(t *testing.T) {
+	priv := GenPrivValidatorKey(common.Address{1})
+	msg := []byte("neatio")
+	sig := priv.PrivKey.Sign(msg)
+	if err := ValidateBLSSignature(sig); err != nil {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	if !VerifyBLS(priv.PubKey, msg, sig) {
+		t.Fatal("valid signature not verified")
+	}
+	if err := ValidateBLSSignature(crypto.BLSSignature(make([]byte, 64))); err != ErrInvalidBLSSignature {
+		t.Errorf("identity signature: have %v, want %v", err, ErrInvalidBLSSignature)
+	}
+	if err := ValidateBLSSignature(crypto.BLSSignature(sig.Bytes()[:32])); err != ErrInvalidBLSSignature {
+		t.Errorf("short signature: have %v, want %v", err, ErrInvalidBLSSignature)
+	}
+	for i := byte(0); ; i++ {
+		sig := priv.PrivKey.Sign(append(msg, i))
+		malleated := append(crypto.BLSSignature{}, sig.(crypto.BLSSignature)...)
+		if !addModulus(malleated) {
+			continue
+		}
+		if err := ValidateBLSSignature(malleated); err != ErrInvalidBLSSignature {
+			t.Errorf("non-canonical signature: have %v, want %v", err, ErrInvalidBLSSignature)
+		}
+		if VerifyBLS(priv.PubKey, append(msg, i), malleated) {
+			t.Error("non-canonical signature verified")
+		}
+		break
+	}
+}
+
+func TestValidateBLSPubKey(t *testing.T) {
+	pubKey := GenPrivValidatorKey(common.Address{1}).PubKey
+	if err := ValidateBLSPubKey(pubKey); err != nil {
+		t.Fatalf("valid public key rejected: %v", err)
+	}
+	if err := ValidateBLSPubKey(crypto.BLSPubKey{}); err != ErrInvalidBLSPubKey {
+		t.Errorf("identity public key: have %v, want %v", err, ErrInvalidBLSPubKey)
+	}
+	if VerifyBLS(crypto.BLSPubKey{}, []byte("neatio"), crypto.BLSSignature(make([]byte, 64))) {
+		t.Error("identity signature verified by the identity public key")
+	}
+	if err := ValidateBLSPubKey(crypto.PubKeyEd25519{}); err != ErrInvalidBLSPubKey {
+		t.Errorf("Ed25519 public key: have %v, want %v", err, ErrInvalidBLSPubKey)
+	}
+	for i := byte(1); ; i++ {
+		malleated := GenPrivValidatorKey(common.Address{i}).PubKey.(crypto.BLSPubKey)
+		if !addModulus(malleated[:]) {
+			continue
+		}
+		if err := ValidateBLSPubKey(malleated); err != ErrInvalidBLSPubKey {
+			t.Errorf("non-canonical public key: have %v, want %v", err, ErrInvalidBLSPubKey)
+		}
+		break
+	}
+}
+
+func TestAggregateBLSPubKeys(t *testing.T) {
+	a := GenPrivValidatorKey(common.Address{1}).PubKey
+	b := GenPrivValidatorKey(common.Address{2}).PubKey
+	if _, err := AggregateBLSPubKeys([]*crypto.PubKey{&a, &b}); err != nil {
+		t.Fatalf("failed to aggregate distinct keys: %v", err)
+	}
+	if _, err := AggregateBLSPubKeys([]*crypto.PubKey{&a, &b, &a}); err != ErrDuplicateBLSPubKey {
+		t.Errorf("duplicate keys: have %v, want %v", err, ErrDuplicateBLSPubKey)
+	}
+	var identity crypto.PubKey = crypto.BLSPubKey{}
+	if _, err := AggregateBLSPubKeys([]*crypto.PubKey{&a, &identity}); err != ErrInvalidBLSPubKey {
+		t.Errorf("identity key: have %v, want %v", err, ErrInvalidBLSPubKey)
+	}
+
+	// A validator set holding the same key twice can't verify aggregated
+	// signatures
+	valSet := NewValidatorSet([]*Validator{
+		NewValidator(common.Address{1}.Bytes(), a, big.NewInt(1)),
+		NewValidator(common.Address{2}.Bytes(), a, big.NewInt(1)),
+	})
+	bitArray := NewBitArray(2)
+	bitArray.SetIndex(0, true)
+	bitArray.SetIndex(1, true)
+	if valSet.AggrPubKey(bitArray) != nil {
+		t.Error("duplicate validator keys aggregated")
+	}
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity beyond which a buffer is dropped rather
+// than pooled, not to hold on to the memory of an oversized encoding.
+const maxPooledBufferSize = MaxBlockSize
+
+// encodeBufferPool holds the buffers the blocks are encoded in before being
+// split into parts. Encoding a large block in a fresh buffer grows and drops
+// several buffers of its size, which shows as GC spikes on every proposal.
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+func getEncodeBuffer() *bytes.Buffer {
+	buf := encodeBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putEncodeBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBufferSize {
+		encodeBufferPool.Put(buf)
+	}
+}
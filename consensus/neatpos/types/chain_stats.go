@@ -0,0 +1,104 @@
+package types
+
+import (
+	"math"
+)
+
+// BlockIntervalBuckets are the upper bounds, in milliseconds, of the buckets
+// of the block interval histogram. The intervals above the last bound are
+// counted in an extra bucket.
+var BlockIntervalBuckets = []uint64{
+	250, 500, 750, 1000, 1500, 2000, 2500, 3000, 4000, 5000,
+	7500, 10000, 15000, 20000, 30000, 60000, 120000, 300000,
+}
+
+// ChainStats sums up the blocks of a height range. The sums of adjacent ranges
+// merge into the sum of their union, so that the stats of a large range are
+// computed from the stats of its parts.
+type ChainStats struct {
+	Blocks      uint64
+	Intervals   uint64   // Blocks whose interval from their parent is counted
+	IntervalSum uint64   // Milliseconds
+	MaxInterval uint64   // Milliseconds
+	Histogram   []uint64 // Block intervals by bucket of BlockIntervalBuckets
+	Rounds      uint64   // Rounds the blocks took to be committed
+	GasUsed     uint64
+	GasLimit    uint64
+	Txs         uint64
+}
+
+func NewChainStats() *ChainStats {
+	return &ChainStats{Histogram: make([]uint64, len(BlockIntervalBuckets)+1)}
+}
+
+// AddBlock counts a block committed in the given number of rounds. A block
+// without a parent to measure the interval from passes a negative interval.
+func (s *ChainStats) AddBlock(interval int64, rounds, gasUsed, gasLimit, txs uint64) {
+	s.Blocks++
+	s.Rounds += rounds
+	s.GasUsed += gasUsed
+	s.GasLimit += gasLimit
+	s.Txs += txs
+	if interval < 0 {
+		return
+	}
+	ms := uint64(interval)
+	s.Intervals++
+	s.IntervalSum += ms
+	if ms > s.MaxInterval {
+		s.MaxInterval = ms
+	}
+	bucket := len(BlockIntervalBuckets)
+	for i, bound := range BlockIntervalBuckets {
+		if ms <= bound {
+			bucket = i
+			break
+		}
+	}
+	s.Histogram[bucket]++
+}
+
+// Merge adds the stats of another range.
+func (s *ChainStats) Merge(other *ChainStats) {
+	s.Blocks += other.Blocks
+	s.Intervals += other.Intervals
+	s.IntervalSum += other.IntervalSum
+	if other.MaxInterval > s.MaxInterval {
+		s.MaxInterval = other.MaxInterval
+	}
+	for i := range s.Histogram {
+		if i < len(other.Histogram) {
+			s.Histogram[i] += other.Histogram[i]
+		}
+	}
+	s.Rounds += other.Rounds
+	s.GasUsed += other.GasUsed
+	s.GasLimit += other.GasLimit
+	s.Txs += other.Txs
+}
+
+// IntervalPercentile returns the upper bound of the bucket holding the block
+// interval at the given percentile, between 0 and 100, capped by the largest
+// interval seen.
+func (s *ChainStats) IntervalPercentile(percentile float64) uint64 {
+	if s.Intervals == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(percentile / 100 * float64(s.Intervals)))
+	if rank == 0 {
+		rank = 1
+	} else if rank > s.Intervals {
+		rank = s.Intervals
+	}
+	var seen uint64
+	for i, count := range s.Histogram {
+		seen += count
+		if seen >= rank {
+			if i < len(BlockIntervalBuckets) && BlockIntervalBuckets[i] < s.MaxInterval {
+				return BlockIntervalBuckets[i]
+			}
+			break
+		}
+	}
+	return s.MaxInterval
+}
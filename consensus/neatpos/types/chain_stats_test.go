@@ -0,0 +1,48 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestChainStats(t *testing.T) {
+	first, second := NewChainStats(), NewChainStats()
+	first.AddBlock(-1, 1, 100, 1000, 2)
+	for i := 0; i < 8; i++ {
+		first.AddBlock(900, 1, 200, 1000, 1)
+	}
+	second.AddBlock(1800, 2, 500, 1000, 4)
+	second.AddBlock(45000, 3, 0, 1000, 0)
+
+	stats := NewChainStats()
+	stats.Merge(first)
+	stats.Merge(second)
+	if stats.Blocks != 11 || stats.Intervals != 10 {
+		t.Fatalf("block count mismatch: have %d blocks, %d intervals", stats.Blocks, stats.Intervals)
+	}
+	if stats.IntervalSum != 8*900+1800+45000 || stats.MaxInterval != 45000 {
+		t.Errorf("interval mismatch: sum %d, max %d", stats.IntervalSum, stats.MaxInterval)
+	}
+	if stats.Rounds != 14 || stats.GasUsed != 2200 || stats.GasLimit != 11000 || stats.Txs != 14 {
+		t.Errorf("totals mismatch: %+v", stats)
+	}
+
+	tests := []struct {
+		percentile float64
+		want       uint64
+	}{
+		{0, 1000},
+		{50, 1000},
+		{80, 1000},
+		{90, 2000},
+		{99, 45000},
+		{100, 45000},
+	}
+	for _, tt := range tests {
+		if have := stats.IntervalPercentile(tt.percentile); have != tt.want {
+			t.Errorf("percentile %v: have %d, want %d", tt.percentile, have, tt.want)
+		}
+	}
+	if NewChainStats().IntervalPercentile(50) != 0 {
+		t.Error("percentile of no interval")
+	}
+}
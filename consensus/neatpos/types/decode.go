@@ -0,0 +1,283 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/neatlab/neatio/core/types"
+	. "github.com/neatlib/common-go"
+	"github.com/neatlib/wire-go"
+)
+
+// Bounds of the blocks and consensus messages decoded from the network, well
+// above what honest peers send.
+const (
+	// BlockPartSizeBytes is the size of the parts proposal blocks are split in.
+	BlockPartSizeBytes = 65536
+
+	// MaxBlockPartsCount is the number of parts of the largest block.
+	MaxBlockPartsCount = (MaxBlockSize + BlockPartSizeBytes - 1) / BlockPartSizeBytes
+
+	// MaxValidatorSetSize is the upper bound of the max_validators governance
+	// parameter, which bounds the bit arrays of the votes.
+	MaxValidatorSetSize = 1000
+
+	maxChainIDSize    = 256
+	maxStringSize     = 1024
+	maxHashSize       = 64
+	maxEpochBytesSize = 1 << 20
+	maxProofAunts     = 64
+	maxSignBytesSize  = 4096
+)
+
+var (
+	ErrTrailingBytes = errors.New("unexpected bytes after the encoded value")
+	ErrNonCanonical  = errors.New("non-canonical encoding")
+	errNilField      = errors.New("missing field")
+)
+
+// ReadBinary is wire.ReadBinary, but reports the malformed input it panics on
+// as an error. On error, o is returned as is.
+func ReadBinary(o interface{}, r io.Reader, lmt int, n *int, err *error) (res interface{}) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			*err = fmt.Errorf("malformed input: %v", rec)
+			res = o
+		}
+	}()
+	return wire.ReadBinary(o, r, lmt, n, err)
+}
+
+// CheckCanonical returns ErrNonCanonical unless the decoded value o encodes
+// back to exactly data. Decoding accepts some values, such as varints, in more
+// than one form, which would let the same block or vote go by different
+// hashes.
+func CheckCanonical(o interface{}, data []byte) error {
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+
+	var (
+		n   int
+		err error
+	)
+	wire.WriteBinary(o, buf, &n, &err)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		return ErrNonCanonical
+	}
+	return nil
+}
+
+// expectEOF returns ErrTrailingBytes if r is not exhausted.
+func expectEOF(r io.Reader) error {
+	var b [1]byte
+	if n, err := r.Read(b[:]); n > 0 {
+		return ErrTrailingBytes
+	} else if err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// ValidateBitArray checks that a decoded bit array, if any, holds at most
+// maxBits bits, and as many words as it has bits.
+func ValidateBitArray(ba *BitArray, maxBits uint64) error {
+	if ba == nil {
+		return nil
+	}
+	if ba.Bits > maxBits {
+		return fmt.Errorf("bit array of %d bits, more than %d", ba.Bits, maxBits)
+	}
+	if uint64(len(ba.Elems)) != (ba.Bits+63)/64 {
+		return fmt.Errorf("bit array of %d bits with %d words", ba.Bits, len(ba.Elems))
+	}
+	return nil
+}
+
+func validateBytes(name string, b []byte, max int) error {
+	if len(b) > max {
+		return fmt.Errorf("%s of %d bytes, more than %d", name, len(b), max)
+	}
+	return nil
+}
+
+// ValidateBasic checks the bounds of a part set header.
+func (psh PartSetHeader) ValidateBasic() error {
+	if psh.Total > MaxBlockPartsCount {
+		return fmt.Errorf("part set of %d parts, more than %d", psh.Total, MaxBlockPartsCount)
+	}
+	return validateBytes("part set hash", psh.Hash, maxHashSize)
+}
+
+// ValidateBasic checks the bounds of a block ID.
+func (blockID BlockID) ValidateBasic() error {
+	if err := validateBytes("block hash", blockID.Hash, maxHashSize); err != nil {
+		return err
+	}
+	return blockID.PartsHeader.ValidateBasic()
+}
+
+// ValidateBasic checks the bounds of a block part received from a peer.
+func (part *Part) ValidateBasic() error {
+	if part.Index < 0 || part.Index >= MaxBlockPartsCount {
+		return fmt.Errorf("part index %d out of range", part.Index)
+	}
+	if err := validateBytes("part", part.Bytes, BlockPartSizeBytes); err != nil {
+		return err
+	}
+	if len(part.Proof.Aunts) > maxProofAunts {
+		return fmt.Errorf("part proof of %d aunts, more than %d", len(part.Proof.Aunts), maxProofAunts)
+	}
+	for _, aunt := range part.Proof.Aunts {
+		if err := validateBytes("part proof aunt", aunt, maxHashSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateBasic checks the fields of a proposal received from a peer, before
+// its signature is.
+func (p *Proposal) ValidateBasic() error {
+	switch {
+	case p.Round < 0:
+		return fmt.Errorf("negative proposal round %d", p.Round)
+	case p.POLRound < -1:
+		return fmt.Errorf("invalid proposal POL round %d", p.POLRound)
+	case p.BlockPartsHeader.Total == 0:
+		return errors.New("proposal of an empty part set")
+	case p.Signature == nil:
+		return errNilField
+	case len(p.NodeID) > maxStringSize || len(p.ProposerNetAddr) > maxStringSize || len(p.ProposerPeerKey) > maxStringSize:
+		return errors.New("proposal proposer field too long")
+	}
+	if err := ValidateBLSSignature(p.Signature); err != nil {
+		return err
+	}
+	if err := validateBytes("proposal hash", p.Hash, maxHashSize); err != nil {
+		return err
+	}
+	if err := p.BlockPartsHeader.ValidateBasic(); err != nil {
+		return err
+	}
+	return p.POLBlockID.ValidateBasic()
+}
+
+// ValidateBasic checks the fields of a vote received from a peer, before its
+// signature is.
+func (vote *Vote) ValidateBasic() error {
+	switch {
+	case !IsVoteTypeValid(vote.Type):
+		return ErrVoteUnexpectedStep
+	case vote.ValidatorIndex >= MaxValidatorSetSize:
+		return ErrVoteInvalidValidatorIndex
+	case vote.Signature == nil:
+		return ErrVoteInvalidSignature
+	}
+	if err := ValidateBLSSignature(vote.Signature); err != nil {
+		return err
+	}
+	if err := validateBytes("validator address", vote.ValidatorAddress, maxHashSize); err != nil {
+		return err
+	}
+	if err := validateBytes("vote sign bytes", vote.SignBytes, maxSignBytesSize); err != nil {
+		return err
+	}
+	return vote.BlockID.ValidateBasic()
+}
+
+// ValidateBasic checks the fields of a signature aggregation received from a
+// peer, before the signature is.
+func (sa *SignAggr) ValidateBasic() error {
+	switch {
+	case !IsVoteTypeValid(sa.Type):
+		return ErrVoteUnexpectedStep
+	case sa.Round < 0:
+		return fmt.Errorf("negative signature aggregation round %d", sa.Round)
+	case sa.NumValidators < 0 || sa.NumValidators > MaxValidatorSetSize:
+		return fmt.Errorf("signature aggregation of %d validators", sa.NumValidators)
+	case sa.BitArray == nil:
+		return errNilField
+	case len(sa.ChainID) > maxChainIDSize:
+		return errors.New("signature aggregation chain ID too long")
+	}
+	if err := ValidateBitArray(sa.BitArray, MaxValidatorSetSize); err != nil {
+		return err
+	}
+	if err := ValidateBLSSignature(sa.SignatureAggr); err != nil {
+		return err
+	}
+	if err := validateBytes("signature aggregation sign bytes", sa.SignBytes, maxSignBytesSize); err != nil {
+		return err
+	}
+	if err := sa.BlockID.ValidateBasic(); err != nil {
+		return err
+	}
+	return sa.Maj23.ValidateBasic()
+}
+
+// validateDecoded checks the bounds of the fields of a block decoded from the
+// network, before anything is done with it.
+func (b *TdmBlock) validateDecoded() error {
+	extra := b.NcExtra
+	if extra == nil || extra.SeenCommit == nil {
+		return errNilField
+	}
+	if len(extra.ChainID) > maxChainIDSize {
+		return errors.New("block chain ID too long")
+	}
+	if err := validateBytes("seen commit hash", extra.SeenCommitHash, maxHashSize); err != nil {
+		return err
+	}
+	if err := validateBytes("validators hash", extra.ValidatorsHash, maxHashSize); err != nil {
+		return err
+	}
+	if err := validateBytes("epoch", extra.EpochBytes, maxEpochBytesSize); err != nil {
+		return err
+	}
+	commit := extra.SeenCommit
+	if commit.Round < 0 {
+		return fmt.Errorf("negative commit round %d", commit.Round)
+	}
+	if err := commit.BlockID.ValidateBasic(); err != nil {
+		return err
+	}
+	if err := ValidateBitArray(commit.BitArray, MaxValidatorSetSize); err != nil {
+		return err
+	}
+	if len(commit.SignAggr) > 0 {
+		if err := ValidateBLSSignature(commit.SignAggr); err != nil {
+			return err
+		}
+	}
+
+	// Every proof backs a withdrawal transaction of the block
+	if len(b.TX3ProofData) > len(b.Block.Transactions()) {
+		return fmt.Errorf("%d TX3 proofs for %d transactions", len(b.TX3ProofData), len(b.Block.Transactions()))
+	}
+	for _, proof := range b.TX3ProofData {
+		if err := validateTX3ProofData(proof); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTX3ProofData(proof *types.TX3ProofData) error {
+	if proof == nil || proof.Header == nil {
+		return errNilField
+	}
+	if len(proof.TxIndexs) != len(proof.TxProofs) {
+		return fmt.Errorf("TX3 proof of %d transactions with %d proofs", len(proof.TxIndexs), len(proof.TxProofs))
+	}
+	for _, txProof := range proof.TxProofs {
+		if txProof == nil {
+			return errNilField
+		}
+	}
+	return nil
+}
@@ -264,6 +264,19 @@ func (psr *PartSetReader) Read(p []byte) (n int, err error) {
 	return psr.Read(p)
 }
 
+// ReadByte implements io.ByteReader, for the decoders to read over the parts
+// without buffering them.
+func (psr *PartSetReader) ReadByte() (byte, error) {
+	for psr.reader.Len() == 0 {
+		psr.i += 1
+		if psr.i >= len(psr.parts) {
+			return 0, io.EOF
+		}
+		psr.reader = bytes.NewReader(psr.parts[psr.i].Bytes)
+	}
+	return psr.reader.ReadByte()
+}
+
 func (ps *PartSet) StringShort() string {
 	if ps == nil {
 		return "nil-PartSet"
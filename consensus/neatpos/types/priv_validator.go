@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/log"
 	"github.com/neatlib/bls-go"
 	. "github.com/neatlib/common-go"
 	"github.com/neatlib/crypto-go"
@@ -28,6 +29,9 @@ type PrivValidator struct {
 	// Overloaded for testing.
 	filePath string
 	mtx      sync.Mutex
+
+	// Records every signature, if set
+	auditLog *SignAuditLog
 }
 
 // The address type of the modified privalidator is string
@@ -120,6 +124,25 @@ func LoadPrivValidator(filePath string) *PrivValidator {
 	return privV
 }
 
+// PendingPrivValidatorFile returns the path where a rotated consensus key is kept
+// until the validator set switches to it
+func PendingPrivValidatorFile(filePath string) string {
+	return filePath + ".next"
+}
+
+// RotateKey replaces the consensus key pair and persists it if the validator is file backed
+func (pv *PrivValidator) RotateKey(pubKey crypto.PubKey, privKey crypto.PrivKey) {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	pv.PubKey = pubKey
+	pv.PrivKey = privKey
+	pv.Signer = NewDefaultSigner(privKey)
+	if pv.filePath != "" {
+		pv.save()
+	}
+}
+
 func (pv *PrivValidator) SetFile(filePath string) {
 	pv.mtx.Lock()
 	defer pv.mtx.Unlock()
@@ -127,6 +150,14 @@ func (pv *PrivValidator) SetFile(filePath string) {
 	pv.filePath = filePath
 }
 
+// SetSignAuditLog makes the validator record all its signatures in the log.
+func (pv *PrivValidator) SetSignAuditLog(auditLog *SignAuditLog) {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	pv.auditLog = auditLog
+}
+
 func (pv *PrivValidator) Save() {
 	pv.mtx.Lock()
 	defer pv.mtx.Unlock()
@@ -179,6 +210,12 @@ func (pv *PrivValidator) SignVote(chainID string, vote *Vote) error {
 
 	signature := pv.Sign(SignBytes(chainID, vote))
 	vote.Signature = signature
+
+	typ := SignAuditPrevote
+	if vote.Type == VoteTypePrecommit {
+		typ = SignAuditPrecommit
+	}
+	pv.audit(chainID, vote.Height, vote.Round, typ, vote.BlockID.Hash, signature)
 	return nil
 }
 
@@ -188,9 +225,22 @@ func (pv *PrivValidator) SignProposal(chainID string, proposal *Proposal) error
 
 	signature := pv.Sign(SignBytes(chainID, proposal))
 	proposal.Signature = signature
+
+	pv.audit(chainID, proposal.Height, uint64(proposal.Round), SignAuditProposal, proposal.Hash, signature)
 	return nil
 }
 
+// audit records a signature in the audit log, if any. A failure to record does
+// not stop the validator from signing, but is reported loudly.
+func (pv *PrivValidator) audit(chainID string, height, round uint64, typ string, blockHash []byte, signature crypto.Signature) {
+	if pv.auditLog == nil {
+		return
+	}
+	if err := pv.auditLog.Record(chainID, height, round, typ, blockHash, signature.Bytes()); err != nil {
+		log.Error("Failed to record signature in the audit log", "height", height, "round", round, "type", typ, "err", err)
+	}
+}
+
 func (pv *PrivValidator) String() string {
 	return fmt.Sprintf("PrivValidator{%X}", pv.Address)
 }
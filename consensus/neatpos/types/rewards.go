@@ -0,0 +1,35 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	ethTypes "github.com/neatlab/neatio/core/types"
+)
+
+// BlockRewardsOp records the rewards distributed by a block into the reward
+// summary of its epoch, once the block is written.
+type BlockRewardsOp struct {
+	Epoch      uint64
+	Block      uint64
+	Validator  common.Address              // Proposer of the block
+	Reward     *big.Int                    // Block reward, less the treasury share
+	GasFees    *big.Int                    // Fees of the transactions of the block
+	Treasury   *big.Int                    // Share of the block reward funding the treasury
+	Commission *big.Int                    // Part of the delegators rewards taken by the validator
+	Rewards    map[common.Address]*big.Int // Rewards by address, the validator included
+	Banned     []common.Address            // Validators banned by the block
+}
+
+func (op *BlockRewardsOp) Conflict(op1 ethTypes.PendingOp) bool {
+	if _, ok := op1.(*BlockRewardsOp); ok {
+		// Only one BlockRewardsOp is allowed in each block
+		return true
+	}
+	return false
+}
+
+func (op *BlockRewardsOp) String() string {
+	return fmt.Sprintf("BlockRewardsOp - Epoch:%v, Block:%v, Validator:%x, Reward:%v, GasFees:%v", op.Epoch, op.Block, op.Validator, op.Reward, op.GasFees)
+}
@@ -64,6 +64,26 @@ func MakeSignAggr(height uint64, round int, mtype byte, numValidators int, block
 	}
 }
 
+// AddSignature folds the signature of the validator at index into the
+// aggregation. Returns false if the validator is included already, or the
+// signature can not be aggregated.
+func (sa *SignAggr) AddSignature(index uint64, sig crypto.Signature) bool {
+	if index >= sa.BitArray.Size() || sa.BitArray.GetIndex(index) {
+		return false
+	}
+	if ValidateBLSSignature(sig) != nil {
+		return false
+	}
+	aggr := crypto.Signature(sa.SignatureAggr)
+	signature := crypto.BLSSignatureAggregate([]*crypto.Signature{&aggr, &sig})
+	if signature == nil {
+		return false
+	}
+	sa.SignatureAggr = signature
+	sa.BitArray.SetIndex(index, true)
+	return true
+}
+
 func (sa *SignAggr) SignAggr() crypto.BLSSignature {
 	if sa != nil {
 		return sa.SignatureAggr
@@ -158,7 +178,10 @@ func (sa *SignAggr) SignAggrVerify(msg []byte, valSet *ValidatorSet) bool {
 		return false
 	}
 	pubKey := valSet.AggrPubKey(sa.BitArray)
-	return pubKey.VerifyBytes(msg, sa.SignatureAggr) && sa.HasTwoThirdsMajority(valSet)
+	if pubKey == nil {
+		return false
+	}
+	return VerifyBLS(pubKey, msg, sa.SignatureAggr) && sa.HasTwoThirdsMajority(valSet)
 }
 
 //func (sa *SignAggr) HasTwoThirdsAny(valSet *ValidatorSet) bool {
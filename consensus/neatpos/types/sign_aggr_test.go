@@ -0,0 +1,55 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	. "github.com/neatlib/common-go"
+	"github.com/neatlib/crypto-go"
+)
+
+func TestSignAggrAddSignature(t *testing.T) {
+	var (
+		privs []*PrivValidator
+		vals  []*Validator
+	)
+	for i := 0; i < 4; i++ {
+		priv := GenPrivValidatorKey(common.BytesToAddress([]byte{byte(i + 1)}))
+		privs = append(privs, priv)
+		vals = append(vals, NewValidator(priv.Address[:], priv.PubKey, big.NewInt(1)))
+	}
+	valSet := NewValidatorSet(vals)
+
+	blockID := BlockID{Hash: []byte{0x01}}
+	sigs := make([]crypto.Signature, len(privs))
+	for i, priv := range privs {
+		vote := &Vote{Height: 10, Round: 0, Type: VoteTypePrevote, BlockID: blockID}
+		priv.SignVote("neatio", vote)
+		sigs[i] = vote.Signature
+	}
+	msg := SignBytes("neatio", &Vote{Height: 10, Round: 0, Type: VoteTypePrevote, BlockID: blockID})
+
+	// Aggregate the first two votes, then fold in the third
+	bitArray := NewBitArray(4)
+	bitArray.SetIndex(0, true)
+	bitArray.SetIndex(1, true)
+	signature := crypto.BLSSignatureAggregate([]*crypto.Signature{&sigs[0], &sigs[1]})
+	signAggr := MakeSignAggr(10, 0, VoteTypePrevote, 4, blockID, "neatio", bitArray, signature)
+
+	if signAggr.HasTwoThirdsMajority(valSet) {
+		t.Fatalf("two votes reached a majority of four")
+	}
+	if signAggr.AddSignature(1, sigs[1]) {
+		t.Errorf("included signature added twice")
+	}
+	if !signAggr.AddSignature(2, sigs[2]) {
+		t.Fatalf("failed to add signature")
+	}
+	if !valSet.AggrPubKey(signAggr.BitArray).VerifyBytes(msg, signAggr.SignatureAggr) {
+		t.Errorf("extended aggregation failed verification")
+	}
+	if !signAggr.HasTwoThirdsMajority(valSet) {
+		t.Errorf("three votes did not reach a majority of four")
+	}
+}
@@ -0,0 +1,204 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/log"
+)
+
+// Kinds of messages recorded in the signing audit log.
+const (
+	SignAuditProposal  = "proposal"
+	SignAuditPrevote   = "prevote"
+	SignAuditPrecommit = "precommit"
+)
+
+var errSignAuditClosed = errors.New("signing audit log closed")
+
+// SignAuditFile returns the path of the signing audit log of the validator
+// whose consensus key is kept in the given file.
+func SignAuditFile(privValidatorFile string) string {
+	return filepath.Join(filepath.Dir(privValidatorFile), "sign_audit.log")
+}
+
+// SignAuditEntry records one signature produced by the validator. Each entry
+// commits to the previous one, so that entries can't be removed, reordered or
+// altered without breaking the chain of hashes.
+type SignAuditEntry struct {
+	Seq       uint64        `json:"seq"`
+	Time      time.Time     `json:"time"`
+	ChainID   string        `json:"chain_id"`
+	Height    uint64        `json:"height"`
+	Round     uint64        `json:"round"`
+	Type      string        `json:"type"`
+	BlockHash hexutil.Bytes `json:"block_hash"`
+	Signature hexutil.Bytes `json:"signature"`
+	PrevHash  hexutil.Bytes `json:"prev_hash"`
+	Hash      hexutil.Bytes `json:"hash,omitempty"`
+}
+
+// computeHash returns the hash of the entry: the SHA-256 of its encoding
+// without the hash itself.
+func (e *SignAuditEntry) computeHash() ([]byte, error) {
+	unhashed := *e
+	unhashed.Hash = nil
+	data, err := json.Marshal(&unhashed)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	return hash[:], nil
+}
+
+// SignAuditLog is the append-only, hash-chained log of all the signatures of
+// a validator. Every entry is synced to disk before the signature is used.
+type SignAuditLog struct {
+	mtx  sync.Mutex
+	file *os.File
+	seq  uint64
+	prev []byte
+}
+
+// OpenSignAuditLog opens the audit log at path for appending, creating it if
+// it doesn't exist. New entries are chained to the last valid entry.
+func OpenSignAuditLog(path string) (*SignAuditLog, error) {
+	entries, err := ReadSignAuditLog(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("signing audit log %s is corrupted after %d entries: %v", path, len(entries), err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// An entry torn by a crash is left in place, but the next ones start on
+	// their own line
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			file.Write([]byte{'\n'})
+		}
+	}
+	l := &SignAuditLog{file: file, prev: make([]byte, sha256.Size)}
+	if n := len(entries); n > 0 {
+		l.seq, l.prev = entries[n-1].Seq+1, entries[n-1].Hash
+	}
+	return l, nil
+}
+
+// Record appends a signature to the log.
+func (l *SignAuditLog) Record(chainID string, height, round uint64, typ string, blockHash, signature []byte) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.file == nil {
+		return errSignAuditClosed
+	}
+	entry := &SignAuditEntry{
+		Seq:       l.seq,
+		Time:      time.Now().UTC(),
+		ChainID:   chainID,
+		Height:    height,
+		Round:     round,
+		Type:      typ,
+		BlockHash: blockHash,
+		Signature: signature,
+		PrevHash:  l.prev,
+	}
+	hash, err := entry.computeHash()
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.seq, l.prev = l.seq+1, hash
+	return nil
+}
+
+// Close closes the log, later records fail.
+func (l *SignAuditLog) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// ReadSignAuditLog reads the audit log at path and verifies its chain of
+// hashes. If the chain is broken, the entries before the break are returned
+// along with an error locating it. Lines which are not entries at all, as
+// written by a crash in the middle of a record, are skipped.
+func ReadSignAuditLog(path string) ([]*SignAuditEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readSignAudit(file)
+}
+
+func readSignAudit(r io.Reader) ([]*SignAuditEntry, error) {
+	var (
+		entries []*SignAuditEntry
+		prev    = make([]byte, sha256.Size)
+		reader  = bufio.NewReader(r)
+	)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(data) == 0 {
+			return entries, nil
+		} else if err != nil && err != io.EOF {
+			return entries, err
+		}
+		var entry SignAuditEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			// Entries torn by a crash are skipped, the chain shows if any is
+			// missing
+			log.Warn("Skipping unreadable signing audit entry", "line", line, "err", err)
+			continue
+		}
+		if entry.Seq != uint64(len(entries)) {
+			return entries, fmt.Errorf("line %d: sequence number %d, want %d", line, entry.Seq, len(entries))
+		}
+		if !bytes.Equal(entry.PrevHash, prev) {
+			return entries, fmt.Errorf("line %d: not chained to the previous entry", line)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return entries, fmt.Errorf("line %d: %v", line, err)
+		}
+		if !bytes.Equal(entry.Hash, hash) {
+			return entries, fmt.Errorf("line %d: hash mismatch, the entry was altered", line)
+		}
+		entries = append(entries, &entry)
+		prev = hash
+	}
+}
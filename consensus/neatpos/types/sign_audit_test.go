@@ -0,0 +1,105 @@
+package types
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sign-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := SignAuditFile(filepath.Join(dir, "priv_validator.json"))
+
+	l, err := OpenSignAuditLog(path)
+	if err != nil {
+		t.Fatalf("failed to create log: %v", err)
+	}
+	if err := l.Record("neatio", 10, 0, SignAuditProposal, []byte{1}, []byte{2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Record("neatio", 10, 0, SignAuditPrevote, []byte{1}, []byte{3}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	// Simulate a crash in the middle of a record, then reopen
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"seq":2,"ti`)
+	file.Close()
+
+	if l, err = OpenSignAuditLog(path); err != nil {
+		t.Fatalf("failed to reopen log: %v", err)
+	}
+	if err := l.Record("neatio", 10, 0, SignAuditPrecommit, []byte{1}, []byte{4}); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	entries, err := ReadSignAuditLog(path)
+	if err != nil {
+		t.Fatalf("failed to verify log: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("entry count mismatch: have %d, want 3", len(entries))
+	}
+	for i, typ := range []string{SignAuditProposal, SignAuditPrevote, SignAuditPrecommit} {
+		if entries[i].Seq != uint64(i) || entries[i].Type != typ || entries[i].Height != 10 {
+			t.Errorf("entry %d mismatch: %+v", i, entries[i])
+		}
+	}
+}
+
+func TestSignAuditLogTampering(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sign-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sign_audit.log")
+
+	l, err := OpenSignAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for height := uint64(1); height <= 3; height++ {
+		if err := l.Record("neatio", height, 0, SignAuditPrecommit, []byte{1}, []byte{2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+
+	tests := map[string][]byte{
+		"altered":   bytes.Join([][]byte{lines[0], bytes.Replace(lines[1], []byte(`"height":2`), []byte(`"height":5`), 1), lines[2]}, nil),
+		"removed":   bytes.Join([][]byte{lines[0], lines[2]}, nil),
+		"reordered": bytes.Join([][]byte{lines[0], lines[2], lines[1]}, nil),
+	}
+	for name, data := range tests {
+		entries, err := readSignAudit(bytes.NewReader(data))
+		if err == nil {
+			t.Errorf("%s: tampering not detected", name)
+		}
+		if len(entries) != 1 {
+			t.Errorf("%s: valid prefix mismatch: have %d entries, want 1", name, len(entries))
+		}
+	}
+	if err := ioutil.WriteFile(path, tests["removed"], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenSignAuditLog(path); err == nil {
+		t.Error("tampered log reopened")
+	}
+}
@@ -0,0 +1,88 @@
+package types
+
+import (
+	"sync"
+)
+
+// maxSignBytesCacheEntries bounds the sign bytes cached for a round. The
+// votes of a round are for a few blocks at most, the others are dropped not to
+// let peers grow the cache with votes for made up blocks.
+const maxSignBytesCacheEntries = 64
+
+// voteSignKey identifies the sign bytes of the votes of a round.
+type voteSignKey struct {
+	type_   byte
+	blockID string
+}
+
+// SignBytesCache caches the sign bytes of the votes of the current round and
+// the hash of the validators of the current height.
+//
+// The canonical vote does not include the validator, so all the votes for a
+// block at a height, round and step sign the same bytes, which would otherwise
+// be encoded again for every vote received, by every peer. The sign bytes of
+// the other rounds are not cached, and they are dropped on round change.
+type SignBytesCache struct {
+	chainID string
+
+	mtx     sync.Mutex
+	height  uint64
+	round   uint64
+	votes   map[voteSignKey][]byte
+	valSet  *ValidatorSet
+	valHash []byte
+}
+
+func NewSignBytesCache(chainID string) *SignBytesCache {
+	return &SignBytesCache{
+		chainID: chainID,
+		votes:   make(map[voteSignKey][]byte),
+	}
+}
+
+// Reset moves the cache to height and round, dropping the sign bytes of the
+// previous round, and the validators hash of the previous height.
+func (c *SignBytesCache) Reset(height, round uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if height != c.height {
+		c.valSet, c.valHash = nil, nil
+	}
+	if height != c.height || round != c.round {
+		c.votes = make(map[voteSignKey][]byte)
+	}
+	c.height, c.round = height, round
+}
+
+// VoteSignBytes returns the sign bytes of vote. The slice returned is shared
+// and must not be modified.
+func (c *SignBytesCache) VoteSignBytes(vote *Vote) []byte {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if vote.Height != c.height || vote.Round != c.round {
+		return SignBytes(c.chainID, vote)
+	}
+	key := voteSignKey{type_: vote.Type, blockID: vote.BlockID.Key()}
+	if signBytes, ok := c.votes[key]; ok {
+		return signBytes
+	}
+	signBytes := SignBytes(c.chainID, vote)
+	if len(c.votes) < maxSignBytesCacheEntries {
+		c.votes[key] = signBytes
+	}
+	return signBytes
+}
+
+// ValidatorsHash returns the hash of valSet, the validators of the current
+// height.
+func (c *SignBytesCache) ValidatorsHash(valSet *ValidatorSet) []byte {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.valSet != valSet {
+		c.valSet, c.valHash = valSet, valSet.Hash()
+	}
+	return c.valHash
+}
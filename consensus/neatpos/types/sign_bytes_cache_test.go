@@ -0,0 +1,51 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestSignBytesCache(t *testing.T) {
+	cache := NewSignBytesCache("neatio")
+	cache.Reset(10, 1)
+
+	blockID := BlockID{Hash: []byte{1}, PartsHeader: PartSetHeader{Total: 1, Hash: []byte{2}}}
+	vote := &Vote{ValidatorAddress: []byte{3}, Height: 10, Round: 1, Type: VoteTypePrevote, BlockID: blockID}
+	other := &Vote{ValidatorAddress: []byte{4}, ValidatorIndex: 1, Height: 10, Round: 1, Type: VoteTypePrevote, BlockID: blockID}
+
+	signBytes := cache.VoteSignBytes(vote)
+	if !bytes.Equal(signBytes, SignBytes("neatio", vote)) {
+		t.Fatalf("sign bytes mismatch: %s", signBytes)
+	}
+	// The votes of the other validators sign the same bytes
+	if otherBytes := cache.VoteSignBytes(other); &otherBytes[0] != &signBytes[0] {
+		t.Errorf("sign bytes of the same vote not cached")
+	}
+	// The types and blocks do not
+	precommit := &Vote{Height: 10, Round: 1, Type: VoteTypePrecommit, BlockID: blockID}
+	if !bytes.Equal(cache.VoteSignBytes(precommit), SignBytes("neatio", precommit)) {
+		t.Errorf("sign bytes of a precommit mismatch")
+	}
+	nilVote := &Vote{Height: 10, Round: 1, Type: VoteTypePrevote}
+	if !bytes.Equal(cache.VoteSignBytes(nilVote), SignBytes("neatio", nilVote)) {
+		t.Errorf("sign bytes of a nil vote mismatch")
+	}
+	// The votes of the other rounds are not cached
+	late := &Vote{Height: 10, Round: 0, Type: VoteTypePrevote, BlockID: blockID}
+	if !bytes.Equal(cache.VoteSignBytes(late), SignBytes("neatio", late)) || len(cache.votes) != 3 {
+		t.Errorf("vote of another round cached")
+	}
+	// A round change drops the cache
+	cache.Reset(10, 2)
+	if len(cache.votes) != 0 {
+		t.Errorf("%d sign bytes cached after a round change", len(cache.votes))
+	}
+	// The cache is bounded
+	for i := 0; i < 2*maxSignBytesCacheEntries; i++ {
+		cache.VoteSignBytes(&Vote{Height: 10, Round: 2, BlockID: BlockID{Hash: []byte(fmt.Sprint(i))}})
+	}
+	if len(cache.votes) != maxSignBytesCacheEntries {
+		t.Errorf("%d sign bytes cached, want %d", len(cache.votes), maxSignBytesCacheEntries)
+	}
+}
@@ -71,7 +71,12 @@ func (valSet *ValidatorSet) AggrPubKey(bitMap *cmn.BitArray) crypto.PubKey {
 			pks = append(pks, &(validators[i].PubKey))
 		}
 	}
-	return crypto.BLSPubKeyAggregate(pks)
+	pubKey, err := AggregateBLSPubKeys(pks)
+	if err != nil {
+		log.Warn("Failed to aggregate validator keys", "err", err)
+		return nil
+	}
+	return pubKey
 }
 
 func (valSet *ValidatorSet) GetAggrPubKeyAndAddress(bitMap *cmn.BitArray) (*ConsensusAggr, error) {
@@ -205,6 +210,28 @@ func (valSet *ValidatorSet) Hash() []byte {
 	return merkle.SimpleHashFromHashables(hashables)
 }
 
+// Proofs returns the hash of the validator set and the merkle proof of each
+// validator, in the order of the set, linking the validator to the hash.
+func (valSet *ValidatorSet) Proofs() ([]byte, []*merkle.SimpleProof) {
+	if len(valSet.Validators) == 0 {
+		return nil, nil
+	}
+	hashables := make([]merkle.Hashable, len(valSet.Validators))
+	for i, val := range valSet.Validators {
+		hashables[i] = val
+	}
+	return merkle.SimpleProofsFromHashables(hashables)
+}
+
+// VerifyValidatorProof checks that the validator is the one at index in a
+// validator set of total validators whose hash is root.
+func VerifyValidatorProof(val *Validator, index, total int, proof *merkle.SimpleProof, root []byte) bool {
+	if val == nil || proof == nil || index < 0 || total <= 0 {
+		return false
+	}
+	return proof.Verify(index, total, val.Hash(), root)
+}
+
 func (valSet *ValidatorSet) Add(val *Validator) (added bool) {
 	val = val.Copy()
 
@@ -305,6 +332,9 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, height uint64, commit *
 	}
 
 	pubKey := valSet.AggrPubKey(commit.BitArray)
+	if pubKey == nil {
+		return fmt.Errorf("invalid commit -- can not aggregate the keys of BitArray:%v", commit.BitArray)
+	}
 	vote := &Vote{
 
 		BlockID: commit.BlockID,
@@ -312,7 +342,7 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, height uint64, commit *
 		Round:   (uint64)(commit.Round),
 		Type:    commit.Type(),
 	}
-	if !pubKey.VerifyBytes(SignBytes(chainID, vote), commit.SignAggr) {
+	if !VerifyBLS(pubKey, SignBytes(chainID, vote), commit.SignAggr) {
 		return fmt.Errorf("invalid commit -- wrong Signature:%v or BitArray:%v", commit.SignAggr, commit.BitArray)
 	}
 
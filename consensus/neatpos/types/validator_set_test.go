@@ -1,6 +1,7 @@
 package types
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
 	"math/big"
@@ -92,6 +93,33 @@ func TestValidatorSet_Hash(t *testing.T) {
 
 }
 
+func TestValidatorSetProofs(t *testing.T) {
+	validators := make([]*Validator, len(valList))
+	for i, v := range valList {
+		vp, _ := hexutil.DecodeBig(v.VotingPower)
+		validators[i], _ = makeValidator(v.Address, v.PublicKey, vp)
+	}
+	valSet := &ValidatorSet{Validators: validators, totalVotingPower: big.NewInt(int64(len(validators)))}
+
+	root, proofs := valSet.Proofs()
+	if !bytes.Equal(root, valSet.Hash()) {
+		t.Fatalf("proofs root mismatch: have %x, want %x", root, valSet.Hash())
+	}
+	for i, val := range validators {
+		if !VerifyValidatorProof(val, i, len(validators), proofs[i], root) {
+			t.Errorf("proof of validator %d rejected", i)
+		}
+	}
+	if len(validators) > 1 && VerifyValidatorProof(validators[0], 1, len(validators), proofs[0], root) {
+		t.Error("proof accepted at another index")
+	}
+	forged := validators[0].Copy()
+	forged.VotingPower = new(big.Int).Add(forged.VotingPower, big.NewInt(1))
+	if VerifyValidatorProof(forged, 0, len(validators), proofs[0], root) {
+		t.Error("proof of a forged validator accepted")
+	}
+}
+
 func makeValidator(address, blsPubKey string, vp *big.Int) (validator *Validator, err error) {
 	var blsPK crypto.BLSPubKey
 	blsPubKeyByte, err := hexutil.Decode(blsPubKey)
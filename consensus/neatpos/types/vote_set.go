@@ -91,6 +91,8 @@ type VoteSet struct {
 	maj23         *BlockID               // First 2/3 majority seen
 	votesByBlock  map[string]*blockVotes // string(blockHash|blockParts) -> blockVotes
 	peerMaj23s    map[string]BlockID     // Maj23 for each peer
+
+	signBytesCache *SignBytesCache // Sign bytes shared by the votes, if set
 }
 
 // Constructs a new VoteSet struct used to accumulate votes for given height/round.
@@ -113,6 +115,21 @@ func NewVoteSet(chainID string, height uint64, round int, type_ byte, valSet *Va
 	}
 }
 
+// SetSignBytesCache makes the vote set verify the votes against the sign bytes
+// of the cache.
+func (voteSet *VoteSet) SetSignBytesCache(cache *SignBytesCache) {
+	voteSet.mtx.Lock()
+	defer voteSet.mtx.Unlock()
+	voteSet.signBytesCache = cache
+}
+
+func (voteSet *VoteSet) voteSignBytes(vote *Vote) []byte {
+	if voteSet.signBytesCache != nil {
+		return voteSet.signBytesCache.VoteSignBytes(vote)
+	}
+	return SignBytes(voteSet.chainID, vote)
+}
+
 func (voteSet *VoteSet) ChainID() string {
 	return voteSet.chainID
 }
@@ -168,11 +185,23 @@ func (voteSet *VoteSet) AddVote(vote *Vote) (added bool, err error) {
 	voteSet.mtx.Lock()
 	defer voteSet.mtx.Unlock()
 
-	return voteSet.addVote(vote)
+	return voteSet.addVote(vote, false)
+}
+
+// AddPreverifiedVote is like AddVote for a vote whose signature was already
+// verified against the public key of its validator in the set.
+func (voteSet *VoteSet) AddPreverifiedVote(vote *Vote) (added bool, err error) {
+	if voteSet == nil {
+		PanicSanity("AddPreverifiedVote() on nil VoteSet")
+	}
+	voteSet.mtx.Lock()
+	defer voteSet.mtx.Unlock()
+
+	return voteSet.addVote(vote, true)
 }
 
 // NOTE: Validates as much as possible before attempting to verify the signature.
-func (voteSet *VoteSet) addVote(vote *Vote) (added bool, err error) {
+func (voteSet *VoteSet) addVote(vote *Vote, verified bool) (added bool, err error) {
 	valIndex := vote.ValidatorIndex
 	valAddr := vote.ValidatorAddress
 	blockKey := vote.BlockID.Key()
@@ -210,7 +239,7 @@ func (voteSet *VoteSet) addVote(vote *Vote) (added bool, err error) {
 	}
 
 	// Check signature.
-	if !val.PubKey.VerifyBytes(SignBytes(voteSet.chainID, vote), vote.Signature) {
+	if !verified && (ValidateBLSPubKey(val.PubKey) != nil || !VerifyBLS(val.PubKey, voteSet.voteSignBytes(vote), vote.Signature)) {
 		// Bad signature.
 		return false, ErrVoteInvalidSignature
 	}
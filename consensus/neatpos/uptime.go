@@ -0,0 +1,140 @@
+package neatpos
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus/neatpos/epoch"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/rlp"
+	cmn "github.com/neatlib/common-go"
+)
+
+// maxUptimeWindow is the largest number of blocks the uptime of a validator
+// is computed over.
+const maxUptimeWindow = 100000
+
+const signIndexKey = "SignIndex:%v"
+
+// signRecord indexes the signers of the commit of a block: the bit array of
+// the commit in the validator set of the epoch of the block. It spares the
+// decoding of the header when looking for the blocks signed by a validator.
+type signRecord struct {
+	Epoch uint64
+	Bits  uint64
+	Elems []uint64
+}
+
+func (r *signRecord) signed(index int) bool {
+	return (&cmn.BitArray{Bits: r.Bits, Elems: r.Elems}).GetIndex(uint64(index))
+}
+
+// signRecord returns the signers of the commit of the block at number, or nil
+// if the block has no commit. The records are indexed on first use, commits
+// never change once the block is written.
+func (sb *backend) signRecord(number uint64) (*signRecord, error) {
+	db := sb.core.consensusState.Epoch.GetDB()
+	key := []byte(fmt.Sprintf(signIndexKey, number))
+	if blob := db.Get(key); len(blob) > 0 {
+		record := new(signRecord)
+		if err := rlp.DecodeBytes(blob, record); err == nil {
+			return record, nil
+		}
+	}
+
+	header := sb.chain.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	ncExtra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	if ncExtra.SeenCommit == nil || ncExtra.SeenCommit.BitArray == nil {
+		return nil, nil
+	}
+	record := &signRecord{
+		Epoch: ncExtra.EpochNumber,
+		Bits:  ncExtra.SeenCommit.BitArray.Bits,
+		Elems: ncExtra.SeenCommit.BitArray.Elems,
+	}
+	if blob, err := rlp.EncodeToBytes(record); err == nil {
+		db.Set(key, blob)
+	}
+	return record, nil
+}
+
+// epochCache loads the epochs of the blocks walked through, once each.
+type epochCache struct {
+	current *epoch.Epoch
+	epochs  map[uint64]*epoch.Epoch
+}
+
+func (c *epochCache) get(number uint64) (*epoch.Epoch, error) {
+	if number == c.current.Number {
+		return c.current, nil
+	}
+	if ep, ok := c.epochs[number]; ok {
+		return ep, nil
+	}
+	ep := epoch.LoadOneEpoch(c.current.GetDB(), number, nil)
+	if ep == nil {
+		return nil, fmt.Errorf("epoch %d not found", number)
+	}
+	c.epochs[number] = ep
+	return ep, nil
+}
+
+// ValidatorUptime is the share of the blocks committed while the validator was
+// in the validator set which it signed.
+type ValidatorUptime struct {
+	From   uint64
+	To     uint64
+	Blocks uint64 // Blocks committed while the validator was in the set
+	Signed uint64
+	Missed []uint64 // Heights of the blocks the validator didn't sign
+}
+
+// ValidatorUptime walks the commits of the last window blocks for the
+// signatures of the validator.
+func (sb *backend) ValidatorUptime(from common.Address, window uint64) (*ValidatorUptime, error) {
+	if sb.chain == nil {
+		return nil, errors.New("consensus engine not started")
+	}
+	if window == 0 || window > maxUptimeWindow {
+		return nil, fmt.Errorf("window must be between 1 and %d blocks", maxUptimeWindow)
+	}
+	uptime := &ValidatorUptime{To: sb.chain.CurrentHeader().Number.Uint64()}
+	if uptime.To < window {
+		uptime.From = 1
+	} else {
+		uptime.From = uptime.To - window + 1
+	}
+
+	epochs := &epochCache{current: sb.core.consensusState.Epoch, epochs: make(map[uint64]*epoch.Epoch)}
+	for number := uptime.From; number <= uptime.To; number++ {
+		record, err := sb.signRecord(number)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			continue
+		}
+		ep, err := epochs.get(record.Epoch)
+		if err != nil {
+			return nil, err
+		}
+		index, val := ep.Validators.GetByAddress(from.Bytes())
+		if val == nil {
+			continue
+		}
+		uptime.Blocks++
+		if record.signed(index) {
+			uptime.Signed++
+		} else {
+			uptime.Missed = append(uptime.Missed, number)
+		}
+	}
+	return uptime, nil
+}
@@ -31,6 +31,7 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
 	"github.com/neatlab/neatio/common/mclock"
 	"github.com/neatlab/neatio/common/prque"
 	"github.com/neatlab/neatio/consensus"
@@ -77,6 +78,8 @@ type CacheConfig struct {
 	TrieDirtyLimit    int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
 	TrieDirtyDisabled bool          // Whether to disable trie write caching and GC altogether (archive node)
 	TrieTimeLimit     time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	PipelinedCommit bool // Whether to persist the state and receipts of the mined blocks in the background
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -99,7 +102,9 @@ type BlockChain struct {
 
 	db     neatdb.Database // Low level persistent database to store final content in
 	triegc *prque.Prque    // Priority queue mapping block numbers to tries to gc
-	gcproc time.Duration   // Accumulates canonical block processing for trie dumping
+	gcproc int64           // Accumulates canonical block processing for trie dumping, in nanoseconds (atomic)
+
+	trieFlushDone chan struct{} // Closed when the flush of the trie cache running in the background completes
 
 	hc                  *HeaderChain
 	rmLogsFeed          event.Feed
@@ -128,10 +133,17 @@ type BlockChain struct {
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
+	// upgradeHalted must be atomically called
+	upgradeHalted int32 // set once halted at an upgrade this release does not implement
 	// procInterrupt must be atomically called
 	procInterrupt int32          // interrupt signaler for block processing
 	wg            sync.WaitGroup // chain processing wait group for shutting down
 
+	persistCh  chan *pendingPersist // Blocks whose state and receipts are persisted in the background
+	persisting sync.WaitGroup       // Blocks queued and not persisted yet
+	persistMu  sync.Mutex           // Protects persistErr
+	persistErr error                // First failure to persist a block, no block is written after it
+
 	engine    consensus.Engine
 	validator Validator // Block and state validator interface
 	processor Processor // Block transaction processor interface
@@ -181,6 +193,10 @@ func NewBlockChain(db neatdb.Database, cacheConfig *CacheConfig, chainConfig *pa
 	}
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.processor = NewStateProcessor(chainConfig, bc, engine, cch)
+	if cacheConfig.PipelinedCommit {
+		bc.persistCh = make(chan *pendingPersist, persistQueueSize)
+		go bc.persistLoop(bc.persist)
+	}
 
 	var err error
 	bc.hc, err = NewHeaderChain(db, chainConfig, engine, bc.getProcInterrupt)
@@ -284,6 +300,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 
 	bc.chainmu.Lock()
 	defer bc.chainmu.Unlock()
+	bc.waitPersisted()
 
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(db neatdb.Writer, hash common.Hash, num uint64) {
@@ -480,19 +497,24 @@ func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 //
 // Note, this function assumes that the `mu` mutex is held!
 func (bc *BlockChain) insert(block *types.Block) {
+	bc.writeHeadMarkers(block, bc.insertHead(block))
+}
+
+// insertHead makes the block the head of the canonical chain without writing
+// the head markers to the database, and reports whether the other heads were
+// forced onto it.
+func (bc *BlockChain) insertHead(block *types.Block) bool {
 	// If the block is on a side chain or an unknown one, force other heads onto it too
 	updateHeads := rawdb.ReadCanonicalHash(bc.db, block.NumberU64()) != block.Hash()
 
 	// Add the block to the canonical chain number scheme and mark as the head
 	rawdb.WriteCanonicalHash(bc.db, block.Hash(), block.NumberU64())
-	rawdb.WriteHeadBlockHash(bc.db, block.Hash())
 
 	bc.currentBlock.Store(block)
 
 	// If the block is better than our head or is on a different chain, force update heads
 	if updateHeads {
 		bc.hc.SetCurrentHeader(block.Header())
-		rawdb.WriteHeadFastBlockHash(bc.db, block.Hash())
 
 		bc.currentFastBlock.Store(block)
 	}
@@ -502,6 +524,16 @@ func (bc *BlockChain) insert(block *types.Block) {
 	for _, cb := range ibCbMap {
 		cb(bc, block)
 	}
+	return updateHeads
+}
+
+// writeHeadMarkers writes the head block markers to the database, and the
+// fast block one if the heads were forced onto the block.
+func (bc *BlockChain) writeHeadMarkers(block *types.Block, updateHeads bool) {
+	rawdb.WriteHeadBlockHash(bc.db, block.Hash())
+	if updateHeads {
+		rawdb.WriteHeadFastBlockHash(bc.db, block.Hash())
+	}
 }
 
 // Genesis retrieves the chain's genesis block.
@@ -682,6 +714,10 @@ func (bc *BlockChain) ValidateBlock(block *types.Block) (*state.StateDB, types.R
 		log.Debugf("ValidateBlock-state.New return with error: %v", err)
 		return nil, nil, nil, err
 	}
+	if err := CheckUpgrades(state, block.NumberU64()); err != nil {
+		bc.haltForUpgrade(err.(*UnsupportedUpgradeError))
+		return nil, nil, nil, err
+	}
 
 	// Process block using the parent state as reference point.
 	receipts, _, usedGas, ops, err := bc.processor.Process(block, state, bc.vmConfig)
@@ -718,6 +754,11 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+	if bc.persistCh != nil {
+		bc.waitPersisted()
+		close(bc.persistCh)
+	}
+	bc.waitTrieFlush()
 
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
@@ -778,6 +819,7 @@ const (
 func (bc *BlockChain) Rollback(chain []common.Hash) {
 	bc.chainmu.Lock()
 	defer bc.chainmu.Unlock()
+	bc.waitPersisted()
 
 	for i := len(chain) - 1; i >= 0; i-- {
 		hash := chain[i]
@@ -953,8 +995,13 @@ func (bc *BlockChain) MuUnLock() {
 }
 
 // WriteBlockWithState writes the block and all associated state to the database.
+// With the pipelined commit, the block becomes the head once its state is
+// committed in memory, and the state trie and the receipts are persisted in
+// the background, while the next block is executed.
 func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) (status WriteStatus, err error) {
-
+	if bc.persistCh != nil {
+		return bc.writeBlockWithStatePipelined(block, receipts, state)
+	}
 	return bc.writeBlockWithState(block, receipts, state)
 }
 
@@ -964,6 +1011,11 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
+	// The blocks written before must be persisted first
+	if err := bc.waitPersisted(); err != nil {
+		return NonStatTy, err
+	}
+
 	//to avoid rewrite the block, just refresh the head
 	// Set new head.
 	if bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
@@ -992,6 +1044,59 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	if err != nil {
 		return NonStatTy, err
 	}
+	if err := bc.commitTrie(block, root); err != nil {
+		return NonStatTy, err
+	}
+
+	// Write other block data using a batch.
+	batch := bc.db.NewBatch()
+	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+
+	var reorg bool
+	if _, ok := bc.engine.(consensus.NeatPoS); ok {
+		// NeatPoS Engine always Canon State, insert the block to the chain,
+		reorg = true
+	} else {
+		// If the total difficulty is higher than our known, add it to the canonical chain
+		// Second clause in the if statement reduces the vulnerability to selfish mining.
+		// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
+		reorg := externTd.Cmp(localTd) > 0
+		currentBlock = bc.CurrentBlock()
+		if !reorg && externTd.Cmp(localTd) == 0 {
+			// Split same-difficulty blocks by number, then at random
+			reorg = block.NumberU64() < currentBlock.NumberU64() || (block.NumberU64() == currentBlock.NumberU64() && mrand.Float64() < 0.5)
+		}
+	}
+	if reorg {
+		// Reorganise the chain if the parent is not the head block
+		if block.ParentHash() != currentBlock.Hash() {
+			if err := bc.reorg(currentBlock, block); err != nil {
+				return NonStatTy, err
+			}
+		}
+		// Write the positional metadata for transaction/receipt lookups and preimages
+		rawdb.WriteTxLookupEntries(batch, block)
+		rawdb.WritePreimages(batch, state.Preimages())
+
+		status = CanonStatTy
+	} else {
+		status = SideStatTy
+	}
+	if err := batch.Write(); err != nil {
+		return NonStatTy, err
+	}
+
+	// Set new head.
+	if status == CanonStatTy {
+		bc.insert(block)
+	}
+	bc.futureBlocks.Remove(block.Hash())
+	return status, nil
+}
+
+// commitTrie flushes the state trie of the block to disk or keeps it in memory
+// for the garbage collection, depending on the caching of the tries.
+func (bc *BlockChain) commitTrie(block *types.Block, root common.Hash) error {
 	triedb := bc.stateCache.TrieDB()
 
 	//we flush db within 5 blocks before/after epoch-switch to avoid rollback issues
@@ -1006,8 +1111,9 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 
 	// If we're running an archive node, always flush
 	if withinEpochSwitchWindow || bc.cacheConfig.TrieDirtyDisabled || meetFlushBlockInterval {
+		bc.waitTrieFlush()
 		if err := triedb.Commit(root, false); err != nil {
-			return NonStatTy, err
+			return err
 		}
 	} else {
 		// Full but not archive node, do proper garbage collection
@@ -1020,14 +1126,16 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 				nodes, imgs = triedb.Size()
 				limit       = common.StorageSize(bc.cacheConfig.TrieDirtyLimit) * 1024 * 1024
 			)
+			updateTrieFlushBacklog(nodes, limit)
 			if nodes > limit || imgs > 4*1024*1024 {
-				triedb.Cap(limit - neatdb.IdealBatchSize)
+				bc.flushTrie(nodes, limit-neatdb.IdealBatchSize)
 			}
 			// Find the next state trie we need to commit
 			chosen := current - triesInMemory
 
 			// If we exceeded out time allowance, flush an entire trie to disk
-			if bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+			gcproc := time.Duration(atomic.LoadInt64(&bc.gcproc))
+			if gcproc > bc.cacheConfig.TrieTimeLimit {
 				// If the header is missing (canonical chain behind), we're reorging a low
 				// diff sidechain. Suspend committing until this operation is completed.
 				header := bc.GetHeaderByNumber(chosen)
@@ -1036,13 +1144,14 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 				} else {
 					// If we're exceeding limits but haven't reached a large enough memory gap,
 					// warn the user that the system is becoming unstable.
-					if chosen < lastWrite+triesInMemory && bc.gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
-						log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/triesInMemory)
+					if chosen < lastWrite+triesInMemory && gcproc >= 2*bc.cacheConfig.TrieTimeLimit {
+						log.Info("State in memory for too long, committing", "time", gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/triesInMemory)
 					}
 					// Flush an entire trie and restart the counters
+					bc.waitTrieFlush()
 					triedb.Commit(header.Root, true)
 					lastWrite = chosen
-					bc.gcproc = 0
+					atomic.AddInt64(&bc.gcproc, -int64(gcproc))
 				}
 			}
 			// Garbage collect anything below our required write retention
@@ -1056,51 +1165,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 			}
 		}
 	}
-
-	// Write other block data using a batch.
-	batch := bc.db.NewBatch()
-	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
-
-	var reorg bool
-	if _, ok := bc.engine.(consensus.NeatPoS); ok {
-		// NeatPoS Engine always Canon State, insert the block to the chain,
-		reorg = true
-	} else {
-		// If the total difficulty is higher than our known, add it to the canonical chain
-		// Second clause in the if statement reduces the vulnerability to selfish mining.
-		// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
-		reorg := externTd.Cmp(localTd) > 0
-		currentBlock = bc.CurrentBlock()
-		if !reorg && externTd.Cmp(localTd) == 0 {
-			// Split same-difficulty blocks by number, then at random
-			reorg = block.NumberU64() < currentBlock.NumberU64() || (block.NumberU64() == currentBlock.NumberU64() && mrand.Float64() < 0.5)
-		}
-	}
-	if reorg {
-		// Reorganise the chain if the parent is not the head block
-		if block.ParentHash() != currentBlock.Hash() {
-			if err := bc.reorg(currentBlock, block); err != nil {
-				return NonStatTy, err
-			}
-		}
-		// Write the positional metadata for transaction/receipt lookups and preimages
-		rawdb.WriteTxLookupEntries(batch, block)
-		rawdb.WritePreimages(batch, state.Preimages())
-
-		status = CanonStatTy
-	} else {
-		status = SideStatTy
-	}
-	if err := batch.Write(); err != nil {
-		return NonStatTy, err
-	}
-
-	// Set new head.
-	if status == CanonStatTy {
-		bc.insert(block)
-	}
-	bc.futureBlocks.Remove(block.Hash())
-	return status, nil
+	return nil
 }
 
 // addFutureBlock checks if the block is within the max allowed window to get
@@ -1179,6 +1244,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		lastCanon     *types.Block
 		coalescedLogs []*types.Log
 	)
+	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
+	SenderCacher.RecoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number()), chain)
+
 	// Start the parallel header verifier
 	headers := make([]*types.Header, len(chain))
 	seals := make([]bool, len(chain))
@@ -1241,7 +1309,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 	// Some other error occurred, abort
 	case err != nil:
 		stats.ignored += len(it.chain)
-		bc.reportBlock(block, nil, err)
+		bc.reportBlock(block, nil, common.Hash{}, err)
 		return it.index, events, coalescedLogs, err
 	}
 
@@ -1254,7 +1322,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		}
 		// If the header is a banned one, straight out abort
 		if BadHashes[block.Hash()] {
-			bc.reportBlock(block, nil, ErrBlacklistedHash)
+			bc.reportBlock(block, nil, common.Hash{}, ErrBlacklistedHash)
 			return it.index, events, coalescedLogs, ErrBlacklistedHash
 		}
 
@@ -1269,17 +1337,22 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		if err != nil {
 			return it.index, events, coalescedLogs, err
 		}
+		// Halt at an upgrade this release does not implement
+		if err := CheckUpgrades(statedb, block.NumberU64()); err != nil {
+			bc.haltForUpgrade(err.(*UnsupportedUpgradeError))
+			return it.index, events, coalescedLogs, err
+		}
 		// Process block using the parent state as reference point.
 		receipts, logs, usedGas, ops, err := bc.processor.Process(block, statedb, bc.vmConfig)
 		if err != nil {
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, common.Hash{}, err)
 			return it.index, events, coalescedLogs, err
 		}
 
 		// Validate the state using the default validator
 		err = bc.Validator().ValidateState(block, statedb, receipts, usedGas)
 		if err != nil {
-			bc.reportBlock(block, receipts, err)
+			bc.reportBlock(block, receipts, statedb.IntermediateRoot(bc.chainConfig.IsEIP158(block.Number())), err)
 			return it.index, events, coalescedLogs, err
 		}
 		proctime := time.Since(start)
@@ -1315,7 +1388,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 			lastCanon = block
 
 			// Only count canonical blocks for GC processing time
-			bc.gcproc += proctime
+			atomic.AddInt64(&bc.gcproc, int64(proctime))
 
 		case SideStatTy:
 			bc.logger.Debug("Inserted forked block", "number", block.Number(), "hash", block.Hash(),
@@ -1634,20 +1707,42 @@ func (bc *BlockChain) update() {
 
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
-	Hash   common.Hash   `json:"hash"`
-	Header *types.Header `json:"header"`
-}
-
-// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
+	Hash         common.Hash    `json:"hash"`
+	Header       *types.Header  `json:"header"`
+	RLP          hexutil.Bytes  `json:"rlp"`
+	ExpectedRoot common.Hash    `json:"expectedRoot"`
+	ActualRoot   common.Hash    `json:"actualRoot"`
+	Receipts     types.Receipts `json:"receipts"`
+	Reason       string         `json:"reason"`
+	Time         hexutil.Uint64 `json:"time"`
+}
+
+// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network,
+// kept in the database across restarts, the highest first
 func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
-	headers := make([]BadBlockArgs, 0, bc.badBlocks.Len())
-	for _, hash := range bc.badBlocks.Keys() {
-		if hdr, exist := bc.badBlocks.Peek(hash); exist {
-			header := hdr.(*types.Header)
-			headers = append(headers, BadBlockArgs{header.Hash(), header})
+	bads := rawdb.ReadAllBadBlocks(bc.db)
+	blocks := make([]BadBlockArgs, 0, len(bads))
+	for _, bad := range bads {
+		enc, err := rlp.EncodeToBytes(bad.Block())
+		if err != nil {
+			return nil, err
 		}
+		receipts := make(types.Receipts, len(bad.Receipts))
+		for i, receipt := range bad.Receipts {
+			receipts[i] = (*types.Receipt)(receipt)
+		}
+		blocks = append(blocks, BadBlockArgs{
+			Hash:         bad.Header.Hash(),
+			Header:       bad.Header,
+			RLP:          enc,
+			ExpectedRoot: bad.ExpectedRoot,
+			ActualRoot:   bad.ActualRoot,
+			Receipts:     receipts,
+			Reason:       bad.Reason,
+			Time:         hexutil.Uint64(bad.Time),
+		})
 	}
-	return headers, nil
+	return blocks, nil
 }
 
 // HasBadBlock returns whether the block with the hash is a bad block
@@ -1660,10 +1755,26 @@ func (bc *BlockChain) addBadBlock(block *types.Block) {
 	bc.badBlocks.Add(block.Header().Hash(), block.Header())
 }
 
-// reportBlock logs a bad block error.
-func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
+// reportBlock logs a bad block error and keeps the block in the database, along
+// with the receipts and the state root of its execution. The root is zero if the
+// execution did not complete.
+func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, root common.Hash, err error) {
 	bc.addBadBlock(block)
 
+	storageReceipts := make([]*types.ReceiptForStorage, len(receipts))
+	for i, receipt := range receipts {
+		storageReceipts[i] = (*types.ReceiptForStorage)(receipt)
+	}
+	rawdb.WriteBadBlock(bc.db, &rawdb.BadBlock{
+		Header:       block.Header(),
+		Body:         block.Body(),
+		Receipts:     storageReceipts,
+		ExpectedRoot: block.Root(),
+		ActualRoot:   root,
+		Reason:       err.Error(),
+		Time:         uint64(time.Now().Unix()),
+	})
+
 	var receiptString string
 	for _, receipt := range receipts {
 		receiptString += fmt.Sprintf("\t%v\n", receipt)
@@ -1674,11 +1785,13 @@ Chain config: %v
 
 Number: %v
 Hash: 0x%x
+Expected root: 0x%x
+Actual root: 0x%x
 %v
 
 Error: %v
 ##############################
-`, bc.chainConfig, block.Number(), block.Hash(), receiptString, err))
+`, bc.chainConfig, block.Number(), block.Hash(), block.Root(), root, receiptString, err))
 }
 
 // InsertHeaderChain attempts to insert the given header chain in to the local
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/metrics"
+)
+
+var (
+	trieFlushBacklogGauge = metrics.NewRegisteredGauge("chain/trie/flush/backlog", nil)
+	trieFlushTimer        = metrics.NewRegisteredTimer("chain/trie/flush/time", nil)
+	trieFlushStallTimer   = metrics.NewRegisteredTimer("chain/trie/flush/stall", nil)
+)
+
+// trieDirtyBound is the bound of the dirty trie cache, in multiples of its
+// limit. The cache keeps growing while a flush runs in the background, beyond
+// the bound the block writes wait for the flush to complete.
+const trieDirtyBound = 2
+
+// flushTrie flushes the oldest dirty trie nodes in the background until the
+// cache goes below limit. If a flush is already running, it is waited for only
+// if the cache, of size, outgrew its bound.
+//
+// It is called by the block writes only, which never run concurrently.
+func (bc *BlockChain) flushTrie(size, limit common.StorageSize) {
+	if bc.trieFlushDone != nil {
+		select {
+		case <-bc.trieFlushDone:
+		default:
+			if size <= trieDirtyBound*limit {
+				return
+			}
+			start := time.Now()
+			<-bc.trieFlushDone
+			trieFlushStallTimer.UpdateSince(start)
+		}
+	}
+	done := make(chan struct{})
+	bc.trieFlushDone = done
+
+	go func() {
+		defer close(done)
+
+		start := time.Now()
+		if err := bc.stateCache.TrieDB().Cap(limit); err != nil {
+			bc.logger.Error("Failed to flush the trie cache", "err", err)
+		}
+		trieFlushTimer.UpdateSince(start)
+	}()
+}
+
+// waitTrieFlush waits for the flush running in the background, if any. The
+// tries must not be committed while it runs.
+func (bc *BlockChain) waitTrieFlush() {
+	if bc.trieFlushDone != nil {
+		<-bc.trieFlushDone
+	}
+}
+
+// updateTrieFlushBacklog reports the size of the dirty trie cache beyond its
+// limit.
+func updateTrieFlushBacklog(size, limit common.StorageSize) {
+	backlog := int64(0)
+	if size > limit {
+		backlog = int64(size - limit)
+	}
+	trieFlushBacklogGauge.Update(backlog)
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+)
+
+// persistQueueSize is the number of blocks which can wait to be persisted,
+// the writes of the next blocks block until one is persisted.
+const persistQueueSize = 16
+
+// pendingPersist is the part of the write of a block deferred to the
+// background: its receipts, transaction lookups and preimages, then the head
+// markers.
+type pendingPersist struct {
+	block       *types.Block
+	receipts    types.Receipts
+	preimages   map[common.Hash][]byte
+	updateHeads bool
+}
+
+// writeBlockWithStatePipelined writes a block extending the head, which
+// becomes the new head as soon as its state trie is committed. The rest of the
+// write is queued to the persister, the receipts being served from the cache
+// until they are written.
+//
+// The blocks not extending the head are written at once. It expects the chain
+// mutex to be held: the garbage collection and the flushes of the tries never
+// run concurrently with the processing of the blocks.
+func (bc *BlockChain) writeBlockWithStatePipelined(block *types.Block, receipts []*types.Receipt, state *state.StateDB) (status WriteStatus, err error) {
+	bc.wg.Add(1)
+	defer bc.wg.Done()
+
+	if err := bc.persistError(); err != nil {
+		return NonStatTy, err
+	}
+	// The persister is closed once the chain is stopping
+	if atomic.LoadInt32(&bc.running) == 1 || block.ParentHash() != bc.CurrentBlock().Hash() || bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
+		return bc.writeBlockWithState(block, receipts, state)
+	}
+	ptd := bc.GetTd(block.ParentHash(), block.NumberU64()-1)
+	if ptd == nil {
+		return NonStatTy, consensus.ErrUnknownAncestor
+	}
+	externTd := new(big.Int).Add(block.Difficulty(), ptd)
+	if err := bc.hc.WriteTd(block.Hash(), block.NumberU64(), externTd); err != nil {
+		return NonStatTy, err
+	}
+	rawdb.WriteBlock(bc.db, block)
+
+	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
+	if err != nil {
+		return NonStatTy, err
+	}
+	if err := bc.commitTrie(block, root); err != nil {
+		return NonStatTy, err
+	}
+	bc.receiptsCache.Add(block.Hash(), types.Receipts(receipts))
+	updateHeads := bc.insertHead(block)
+	bc.futureBlocks.Remove(block.Hash())
+
+	bc.persisting.Add(1)
+	bc.persistCh <- &pendingPersist{
+		block:       block,
+		receipts:    receipts,
+		preimages:   state.Preimages(),
+		updateHeads: updateHeads,
+	}
+	return CanonStatTy, nil
+}
+
+// persistLoop persists the queued blocks, in order. Once a block fails to be
+// persisted, the blocks queued after it are dropped, and the chain stops
+// processing blocks: the head on disk stays at the last block persisted.
+func (bc *BlockChain) persistLoop(persist func(*pendingPersist) error) {
+	for p := range bc.persistCh {
+		if bc.persistError() == nil {
+			if err := persist(p); err != nil {
+				bc.logger.Error("Failed to persist block, stopping the chain", "number", p.block.NumberU64(), "hash", p.block.Hash(), "err", err)
+				bc.persistMu.Lock()
+				bc.persistErr = fmt.Errorf("failed to persist block %d: %v", p.block.NumberU64(), err)
+				bc.persistMu.Unlock()
+				atomic.StoreInt32(&bc.procInterrupt, 1)
+			}
+		}
+		bc.persisting.Done()
+	}
+}
+
+// persistError returns the failure to persist a block, if any.
+func (bc *BlockChain) persistError() error {
+	bc.persistMu.Lock()
+	defer bc.persistMu.Unlock()
+	return bc.persistErr
+}
+
+func (bc *BlockChain) persist(p *pendingPersist) error {
+	batch := bc.db.NewBatch()
+	rawdb.WriteReceipts(batch, p.block.Hash(), p.block.NumberU64(), p.receipts)
+	rawdb.WriteTxLookupEntries(batch, p.block)
+	rawdb.WritePreimages(batch, p.preimages)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// The head markers go last, a node stopped before restarts from the
+	// previous block
+	bc.writeHeadMarkers(p.block, p.updateHeads)
+	return nil
+}
+
+// waitPersisted waits until the blocks queued are persisted, and returns the
+// failure to persist one of them.
+func (bc *BlockChain) waitPersisted() error {
+	bc.persisting.Wait()
+	return bc.persistError()
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	"github.com/neatlab/neatio/consensus/neatpos/epoch"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/core/vm"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/neatdb"
+	"github.com/neatlab/neatio/params"
+)
+
+// epochEngine is a NeatPoS engine only knowing its epoch.
+type epochEngine struct {
+	consensus.NeatPoS
+	epoch *epoch.Epoch
+}
+
+func (e *epochEngine) GetEpoch() *epoch.Epoch { return e.epoch }
+
+// newPipelinedTestChain returns a chain whose blocks are all in the first
+// blocks of their epoch, their state trie being flushed at once.
+func newPipelinedTestChain(t *testing.T, db neatdb.Database, config *params.ChainConfig) *BlockChain {
+	engine := &epochEngine{epoch: &epoch.Epoch{StartBlock: 0, EndBlock: 100}}
+	bc, err := NewBlockChain(db, nil, config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bc
+}
+
+// pipelinedTestBlock returns a child of parent changing the state.
+func pipelinedTestBlock(parent *types.Block, statedb *state.StateDB) *types.Block {
+	statedb.AddBalance(common.BytesToAddress([]byte{0x01}), common.Big1)
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		Difficulty: big.NewInt(1),
+		Time:       new(big.Int).SetUint64(parent.Time() + 1),
+		GasLimit:   parent.GasLimit(),
+		Root:       statedb.IntermediateRoot(true),
+	}
+	return types.NewBlock(header, nil, nil, nil)
+}
+
+func TestPipelinedPersistFailure(t *testing.T) {
+	config := *params.TestChainConfig
+	config.ChainLogger = log.New()
+	db := rawdb.NewMemoryDatabase()
+	genesis := (&Genesis{Config: &config}).MustCommit(db)
+
+	bc := newPipelinedTestChain(t, db, &config)
+	errPersist := errors.New("disk full")
+	persisted := make(chan uint64, persistQueueSize)
+	bc.persistCh = make(chan *pendingPersist, persistQueueSize)
+	go bc.persistLoop(func(p *pendingPersist) error {
+		persisted <- p.block.NumberU64()
+		return errPersist
+	})
+
+	statedb, _ := state.New(genesis.Root(), bc.stateCache)
+	first := pipelinedTestBlock(genesis, statedb)
+	if status, err := bc.WriteBlockWithState(first, nil, statedb); err != nil || status != CanonStatTy {
+		t.Fatalf("pipelined write failed: status %v, err %v", status, err)
+	}
+	if head := bc.CurrentBlock().Hash(); head != first.Hash() {
+		t.Fatalf("head not moved to the block queued: have %x, want %x", head, first.Hash())
+	}
+	if number := <-persisted; number != 1 {
+		t.Fatalf("persisted block mismatch: have %d, want 1", number)
+	}
+
+	// The failure stops the chain, and no block is written after it
+	if err := bc.waitPersisted(); err == nil {
+		t.Fatal("persist failure not reported")
+	}
+	if !bc.getProcInterrupt() {
+		t.Error("chain processing not stopped")
+	}
+	statedb, _ = state.New(first.Root(), bc.stateCache)
+	if _, err := bc.WriteBlockWithState(pipelinedTestBlock(first, statedb), nil, statedb); err == nil {
+		t.Error("block written after a persist failure")
+	}
+	if rawdb.ReadHeadBlockHash(db) != genesis.Hash() {
+		t.Error("head marker moved past the last persisted block")
+	}
+}
+
+func TestPipelinedPersistCrash(t *testing.T) {
+	config := *params.TestChainConfig
+	config.ChainLogger = log.New()
+	db := rawdb.NewMemoryDatabase()
+	genesis := (&Genesis{Config: &config}).MustCommit(db)
+
+	bc := newPipelinedTestChain(t, db, &config)
+	persisted := make(chan uint64, persistQueueSize)
+	crash := make(chan struct{})
+	defer close(crash)
+	bc.persistCh = make(chan *pendingPersist, persistQueueSize)
+	go bc.persistLoop(func(p *pendingPersist) error {
+		// The node stops while the second block is persisted
+		if p.block.NumberU64() == 2 {
+			<-crash
+			return errors.New("stopped")
+		}
+		err := bc.persist(p)
+		persisted <- p.block.NumberU64()
+		return err
+	})
+
+	statedb, _ := state.New(genesis.Root(), bc.stateCache)
+	first := pipelinedTestBlock(genesis, statedb)
+	if _, err := bc.WriteBlockWithState(first, nil, statedb); err != nil {
+		t.Fatalf("failed to write block 1: %v", err)
+	}
+	statedb, _ = state.New(first.Root(), bc.stateCache)
+	second := pipelinedTestBlock(first, statedb)
+	if _, err := bc.WriteBlockWithState(second, nil, statedb); err != nil {
+		t.Fatalf("failed to write block 2: %v", err)
+	}
+	if number := <-persisted; number != 1 {
+		t.Fatalf("persisted block mismatch: have %d, want 1", number)
+	}
+
+	// The reopened chain restarts from the last block persisted, with its state
+	reopened := newPipelinedTestChain(t, db, &config)
+	if head := reopened.CurrentBlock(); head.Hash() != first.Hash() {
+		t.Fatalf("head mismatch after reopen: have %d [%x], want 1 [%x]", head.NumberU64(), head.Hash(), first.Hash())
+	}
+	if _, err := state.New(first.Root(), reopened.stateCache); err != nil {
+		t.Errorf("state of the head missing after reopen: %v", err)
+	}
+	if receipts := rawdb.ReadReceipts(db, first.Hash(), first.NumberU64()); receipts == nil {
+		t.Errorf("receipts of the head missing after reopen")
+	}
+}
@@ -0,0 +1,108 @@
+// Package bridge hosts the light clients of external chains: neatio verifies
+// their headers, and the packets they committed to, to transfer tokens
+// between those chains and neatio.
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/rlp"
+)
+
+var (
+	ErrUnknownClientType  = errors.New("unknown bridge client type")
+	ErrUnknownClient      = errors.New("unknown bridge client")
+	ErrClientFrozen       = errors.New("bridge client frozen")
+	ErrNoConsensusState   = errors.New("no consensus state of the bridge client at the height")
+	ErrInvalidChannel     = errors.New("invalid bridge channel")
+	ErrInvalidPacket      = errors.New("invalid bridge packet")
+	ErrPacketReceived     = errors.New("bridge packet already received")
+	ErrPacketTimeout      = errors.New("bridge packet timed out")
+	ErrInsufficientEscrow = errors.New("insufficient NEAT escrowed by the bridge client")
+)
+
+// ConsensusState is what a light client trusts of the external chain at a
+// height: the root its state is proven against, and the validators
+// expected to sign the next header.
+type ConsensusState struct {
+	Height             uint64
+	Timestamp          uint64 // unix nanoseconds
+	Root               []byte
+	NextValidatorsHash []byte
+}
+
+// EncodeConsensusState returns the encoding of cs stored in the state.
+func EncodeConsensusState(cs *ConsensusState) ([]byte, error) {
+	return rlp.EncodeToBytes(cs)
+}
+
+// DecodeConsensusState decodes a consensus state encoded by
+// EncodeConsensusState.
+func DecodeConsensusState(data []byte) (*ConsensusState, error) {
+	cs := new(ConsensusState)
+	if err := rlp.DecodeBytes(data, cs); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// Client is the light client of an external chain.
+type Client interface {
+	// ClientType returns the type the client is registered with.
+	ClientType() string
+
+	// ChainID returns the id of the external chain.
+	ChainID() string
+
+	// LatestHeight returns the highest header the client verified.
+	LatestHeight() uint64
+
+	// TrustedHeight returns the height of the consensus state header is
+	// verified against.
+	TrustedHeight(header []byte) (uint64, error)
+
+	// Update verifies header against the trusted consensus state, and
+	// returns the client and the consensus state at the header height.
+	Update(header []byte, trusted *ConsensusState, now time.Time) (Client, *ConsensusState, error)
+
+	// VerifyMembership verifies proof that value is stored at path in the
+	// state committed by root.
+	VerifyMembership(root []byte, proof []byte, path [][]byte, value []byte) error
+
+	// Encode returns the encoding of the client stored in the state.
+	Encode() ([]byte, error)
+}
+
+// ClientDecoder decodes a client encoded by its Encode method.
+type ClientDecoder func(data []byte) (Client, error)
+
+var (
+	clientTypesMu sync.RWMutex
+	clientTypes   = make(map[string]ClientDecoder)
+)
+
+// RegisterClientType makes the clients of type typ available to the bridge.
+func RegisterClientType(typ string, decode ClientDecoder) {
+	clientTypesMu.Lock()
+	defer clientTypesMu.Unlock()
+
+	if _, exist := clientTypes[typ]; exist {
+		panic(fmt.Sprintf("bridge client type %s registered twice", typ))
+	}
+	clientTypes[typ] = decode
+}
+
+// DecodeClient decodes a client of type typ.
+func DecodeClient(typ string, data []byte) (Client, error) {
+	clientTypesMu.RLock()
+	decode, exist := clientTypes[typ]
+	clientTypesMu.RUnlock()
+
+	if !exist {
+		return nil, ErrUnknownClientType
+	}
+	return decode(data)
+}
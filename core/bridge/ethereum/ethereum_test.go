@@ -0,0 +1,164 @@
+package ethereum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/neatdb/memorydb"
+	"github.com/neatlab/neatio/rlp"
+	"github.com/neatlab/neatio/trie"
+)
+
+// mainnetGenesis returns the fields of the genesis header of Ethereum.
+func mainnetGenesis() []interface{} {
+	return []interface{}{
+		common.Hash{},
+		emptyUncleHash,
+		[20]byte{},
+		common.HexToHash("0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544"),
+		common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"),
+		common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"),
+		[256]byte{},
+		big.NewInt(0x400000000),
+		uint64(0),
+		uint64(5000),
+		uint64(0),
+		uint64(0),
+		hexutil.MustDecode("0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa"),
+		common.Hash{},
+		[8]byte{0, 0, 0, 0, 0, 0, 0, 0x42},
+	}
+}
+
+func encodeHeader(t *testing.T, fields []interface{}) []byte {
+	data, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeHeader(t *testing.T) {
+	h, err := DecodeHeader(encodeHeader(t, mainnetGenesis()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3")
+	if h.Hash() != want || h.IsPoS() || h.GasLimit != 5000 {
+		t.Fatalf("genesis decoded to hash %x, gas limit %d", h.Hash(), h.GasLimit)
+	}
+
+	// After the merge the proof of work fields are empty.
+	fields := append(mainnetGenesis(), big.NewInt(7))
+	fields[7] = big.NewInt(0)
+	if _, err := DecodeHeader(encodeHeader(t, fields)); err == nil {
+		t.Error("proof of stake header with a nonce accepted")
+	}
+	fields[14] = [8]byte{}
+	if _, err := DecodeHeader(encodeHeader(t, fields)); err != nil {
+		t.Errorf("proof of stake header rejected: %v", err)
+	}
+
+	fields = mainnetGenesis()
+	fields[10] = uint64(5001)
+	if _, err := DecodeHeader(encodeHeader(t, fields)); err == nil {
+		t.Error("header using more gas than its limit accepted")
+	}
+	if _, err := DecodeHeader(encodeHeader(t, mainnetGenesis()[:14])); err == nil {
+		t.Error("header missing fields accepted")
+	}
+}
+
+func TestVerifyHeaderChain(t *testing.T) {
+	genesis := encodeHeader(t, mainnetGenesis())
+	child := func(parent []byte, time uint64) []byte {
+		h, err := DecodeHeader(parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields := mainnetGenesis()
+		fields[0], fields[8], fields[11] = h.Hash(), h.Number+1, time
+		return encodeHeader(t, fields)
+	}
+	first := child(genesis, 10)
+	second := child(first, 20)
+
+	headers, err := VerifyHeaderChain([][]byte{genesis, first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers[2].Number != 2 || headers[2].ParentHash != headers[1].Hash() {
+		t.Fatalf("chain decoded to block %d", headers[2].Number)
+	}
+	if _, err := VerifyHeaderChain([][]byte{genesis, second}); err == nil {
+		t.Error("unlinked headers accepted")
+	}
+	if _, err := VerifyHeaderChain([][]byte{first, child(first, 10)}); err == nil {
+		t.Error("header not after its parent accepted")
+	}
+}
+
+func TestVerifyLog(t *testing.T) {
+	tr, err := trie.New(common.Hash{}, trie.NewDatabase(memorydb.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	emitter := [20]byte{0xee}
+	receipts := []interface{}{
+		// A legacy receipt of a failed transaction
+		[]interface{}{[]byte{}, uint64(21000), [256]byte{}, []*Log{{Address: emitter}}},
+		// A dynamic fee receipt, with two logs
+		[]interface{}{[]byte{1}, uint64(60000), [256]byte{}, []*Log{
+			{Address: emitter, Topics: []common.Hash{{1}}, Data: []byte("first")},
+			{Address: emitter, Topics: []common.Hash{{2}, {3}}, Data: []byte("second")},
+		}},
+	}
+	for i, receipt := range receipts {
+		data, err := rlp.EncodeToBytes(receipt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 1 {
+			data = append([]byte{2}, data...)
+		}
+		key, _ := rlp.EncodeToBytes(uint64(i))
+		tr.Update(key, data)
+	}
+	root := tr.Hash()
+	proof := func(index uint64) [][]byte {
+		db := memorydb.New()
+		key, _ := rlp.EncodeToBytes(index)
+		if err := tr.Prove(key, 0, db); err != nil {
+			t.Fatal(err)
+		}
+		var nodes [][]byte
+		it := db.NewIterator()
+		for it.Next() {
+			nodes = append(nodes, common.CopyBytes(it.Value()))
+		}
+		it.Release()
+		return nodes
+	}
+
+	log, err := VerifyLog(root, 1, proof(1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if log.Address != emitter || len(log.Topics) != 2 || log.Topics[1] != (common.Hash{3}) || string(log.Data) != "second" {
+		t.Fatalf("log mismatch: %+v", log)
+	}
+	if _, err := VerifyLog(root, 0, proof(0), 0); err != ErrFailedReceipt {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrFailedReceipt)
+	}
+	if _, err := VerifyLog(root, 1, proof(1), 2); err == nil {
+		t.Error("missing log returned")
+	}
+	if _, err := VerifyLog(root, 1, proof(0), 0); err == nil {
+		t.Error("proof of another receipt accepted")
+	}
+	if _, err := VerifyLog(common.Hash{1}, 1, proof(1), 0); err == nil {
+		t.Error("proof against another root accepted")
+	}
+}
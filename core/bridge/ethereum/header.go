@@ -0,0 +1,184 @@
+// Package ethereum verifies the headers of Ethereum, and the receipts proven
+// against them, for the contracts bridging tokens with Ethereum.
+//
+// Headers are checked to be well formed and linked: the consensus of Ethereum
+// (the Ethash seals before the merge, the beacon chain finality after) is not
+// verified, a header chain is trusted when it ends at a finalized checkpoint
+// submitted by the validators.
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/rlp"
+)
+
+const (
+	// maxExtraDataSize is the maximum size of the extra data of a header.
+	maxExtraDataSize = 32
+
+	// The number of fields of the headers, from the launch of Ethereum to
+	// the requests hash of Prague.
+	minHeaderFields = 15
+	maxHeaderFields = 21
+)
+
+var ErrInvalidHeader = errors.New("invalid ethereum header")
+
+// emptyUncleHash is the hash of an empty list of uncles.
+var emptyUncleHash = crypto.Keccak256Hash([]byte{0xc0})
+
+// Header is a header of an Ethereum block, the fields added by the forks nil
+// before them.
+type Header struct {
+	ParentHash  common.Hash
+	UncleHash   common.Hash
+	Coinbase    [20]byte
+	Root        common.Hash
+	TxHash      common.Hash
+	ReceiptHash common.Hash
+	Bloom       [256]byte
+	Difficulty  *big.Int
+	Number      uint64
+	GasLimit    uint64
+	GasUsed     uint64
+	Time        uint64
+	Extra       []byte
+	MixDigest   common.Hash
+	Nonce       [8]byte
+
+	BaseFee          *big.Int     // London
+	WithdrawalsHash  *common.Hash // Shanghai
+	BlobGasUsed      *uint64      // Cancun
+	ExcessBlobGas    *uint64      // Cancun
+	ParentBeaconRoot *common.Hash // Cancun
+	RequestsHash     *common.Hash // Prague
+
+	hash common.Hash
+}
+
+// Hash returns the hash of the header.
+func (h *Header) Hash() common.Hash {
+	return h.hash
+}
+
+// IsPoS returns whether the header is of a block after the merge.
+func (h *Header) IsPoS() bool {
+	return h.Difficulty.Sign() == 0
+}
+
+// DecodeHeader decodes and checks an RLP encoded header.
+func DecodeHeader(data []byte) (*Header, error) {
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(data, &fields); err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidHeader, err)
+	}
+	if len(fields) < minHeaderFields || len(fields) > maxHeaderFields {
+		return nil, fmt.Errorf("%v: %d fields", ErrInvalidHeader, len(fields))
+	}
+
+	h := &Header{hash: crypto.Keccak256Hash(data)}
+	targets := []interface{}{
+		&h.ParentHash, &h.UncleHash, &h.Coinbase, &h.Root, &h.TxHash, &h.ReceiptHash, &h.Bloom,
+		&h.Difficulty, &h.Number, &h.GasLimit, &h.GasUsed, &h.Time, &h.Extra, &h.MixDigest, &h.Nonce,
+	}
+	if len(fields) > 15 {
+		h.BaseFee = new(big.Int)
+		targets = append(targets, h.BaseFee)
+	}
+	if len(fields) > 16 {
+		h.WithdrawalsHash = new(common.Hash)
+		targets = append(targets, h.WithdrawalsHash)
+	}
+	if len(fields) > 17 {
+		h.BlobGasUsed = new(uint64)
+		targets = append(targets, h.BlobGasUsed)
+	}
+	if len(fields) > 18 {
+		h.ExcessBlobGas = new(uint64)
+		targets = append(targets, h.ExcessBlobGas)
+	}
+	if len(fields) > 19 {
+		h.ParentBeaconRoot = new(common.Hash)
+		targets = append(targets, h.ParentBeaconRoot)
+	}
+	if len(fields) > 20 {
+		h.RequestsHash = new(common.Hash)
+		targets = append(targets, h.RequestsHash)
+	}
+	for i, field := range fields {
+		if err := rlp.DecodeBytes(field, targets[i]); err != nil {
+			return nil, fmt.Errorf("%v: field %d: %v", ErrInvalidHeader, i, err)
+		}
+	}
+
+	if err := h.check(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// check checks the fields of the header which do not depend on its parent.
+func (h *Header) check() error {
+	if len(h.Extra) > maxExtraDataSize {
+		return fmt.Errorf("%v: extra data of %d bytes", ErrInvalidHeader, len(h.Extra))
+	}
+	if h.GasUsed > h.GasLimit {
+		return fmt.Errorf("%v: gas used %d above the gas limit %d", ErrInvalidHeader, h.GasUsed, h.GasLimit)
+	}
+	if h.IsPoS() {
+		// The merge emptied the fields of the proof of work
+		if h.UncleHash != emptyUncleHash || h.Nonce != [8]byte{} {
+			return fmt.Errorf("%v: proof of work fields set after the merge", ErrInvalidHeader)
+		}
+		if h.BaseFee == nil {
+			return fmt.Errorf("%v: no base fee after the merge", ErrInvalidHeader)
+		}
+	}
+	return nil
+}
+
+// VerifyHeaderChain decodes the headers and checks each is the child of the
+// previous one.
+func VerifyHeaderChain(encoded [][]byte) ([]*Header, error) {
+	if len(encoded) == 0 {
+		return nil, fmt.Errorf("%v: no headers", ErrInvalidHeader)
+	}
+	headers := make([]*Header, len(encoded))
+	for i, data := range encoded {
+		h, err := DecodeHeader(data)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			if err := verifyChild(headers[i-1], h); err != nil {
+				return nil, err
+			}
+		}
+		headers[i] = h
+	}
+	return headers, nil
+}
+
+func verifyChild(parent, h *Header) error {
+	if h.ParentHash != parent.hash {
+		return fmt.Errorf("%v: block %d not the child of %x", ErrInvalidHeader, h.Number, parent.hash)
+	}
+	if h.Number != parent.Number+1 {
+		return fmt.Errorf("%v: block %d after block %d", ErrInvalidHeader, h.Number, parent.Number)
+	}
+	if h.Time <= parent.Time {
+		return fmt.Errorf("%v: block %d not after its parent", ErrInvalidHeader, h.Number)
+	}
+	if parent.IsPoS() && !h.IsPoS() {
+		return fmt.Errorf("%v: block %d with proof of work after the merge", ErrInvalidHeader, h.Number)
+	}
+	if parent.BaseFee != nil && h.BaseFee == nil {
+		return fmt.Errorf("%v: block %d without base fee after London", ErrInvalidHeader, h.Number)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package ethereum
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/neatdb/memorydb"
+	"github.com/neatlab/neatio/rlp"
+	"github.com/neatlab/neatio/trie"
+)
+
+var (
+	ErrInvalidReceipt = errors.New("invalid ethereum receipt")
+	ErrFailedReceipt  = errors.New("ethereum transaction failed")
+)
+
+// Log is an event logged by an Ethereum transaction.
+type Log struct {
+	Address [20]byte
+	Topics  []common.Hash
+	Data    []byte
+}
+
+// Receipt is the receipt of an Ethereum transaction.
+type Receipt struct {
+	Type              uint8
+	Status            []byte // 1 for a successful transaction
+	CumulativeGasUsed uint64
+	Bloom             [256]byte
+	Logs              []*Log
+}
+
+// Succeeded returns whether the transaction of the receipt succeeded.
+func (r *Receipt) Succeeded() bool {
+	return len(r.Status) == 1 && r.Status[0] == 1
+}
+
+// DecodeReceipt decodes a receipt, in its legacy or typed encoding.
+func DecodeReceipt(data []byte) (*Receipt, error) {
+	r := new(Receipt)
+	if len(data) > 0 && data[0] < 0x80 {
+		r.Type, data = data[0], data[1:]
+	}
+	var dec struct {
+		Status            []byte
+		CumulativeGasUsed uint64
+		Bloom             [256]byte
+		Logs              []*Log
+	}
+	if err := rlp.DecodeBytes(data, &dec); err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidReceipt, err)
+	}
+	r.Status, r.CumulativeGasUsed, r.Bloom, r.Logs = dec.Status, dec.CumulativeGasUsed, dec.Bloom, dec.Logs
+	return r, nil
+}
+
+// VerifyReceiptProof verifies the proof, the trie nodes from the root to the
+// receipt, of the receipt of transaction index in the block of receiptsRoot.
+func VerifyReceiptProof(receiptsRoot common.Hash, index uint64, proof [][]byte) (*Receipt, error) {
+	db := memorydb.New()
+	for _, node := range proof {
+		if err := db.Put(crypto.Keccak256(node), node); err != nil {
+			return nil, err
+		}
+	}
+	key, err := rlp.EncodeToBytes(index)
+	if err != nil {
+		return nil, err
+	}
+	value, _, err := trie.VerifyProof(receiptsRoot, key, db)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidReceipt, err)
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%v: no receipt of transaction %d", ErrInvalidReceipt, index)
+	}
+	return DecodeReceipt(value)
+}
+
+// VerifyLog verifies the proof of the receipt of transaction index, and
+// returns its log at logIndex. The transaction must have succeeded.
+func VerifyLog(receiptsRoot common.Hash, index uint64, proof [][]byte, logIndex uint64) (*Log, error) {
+	receipt, err := VerifyReceiptProof(receiptsRoot, index, proof)
+	if err != nil {
+		return nil, err
+	}
+	if !receipt.Succeeded() {
+		return nil, ErrFailedReceipt
+	}
+	if logIndex >= uint64(len(receipt.Logs)) {
+		return nil, fmt.Errorf("%v: no log %d in %d logs", ErrInvalidReceipt, logIndex, len(receipt.Logs))
+	}
+	return receipt.Logs[logIndex], nil
+}
@@ -0,0 +1,143 @@
+package bridge
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/neatlab/neatio/rlp"
+)
+
+// NativeDenom is the denomination of NEAT in the transfers.
+const NativeDenom = "neat"
+
+// CounterpartyStoreKey is the store of the external chains their packet
+// commitments are proven in.
+const CounterpartyStoreKey = "ibc"
+
+var ErrInvalidTransfer = errors.New("invalid bridge transfer")
+
+// Packet carries data between a port and channel of neatio and of an external
+// chain, following ICS-04.
+type Packet struct {
+	Sequence           uint64
+	SourcePort         string
+	SourceChannel      string
+	DestinationPort    string
+	DestinationChannel string
+	Data               []byte
+	TimeoutHeight      uint64 // height on the destination chain, 0 for none
+	TimeoutTimestamp   uint64 // unix nanoseconds on the destination chain, 0 for none
+}
+
+// Commitment returns the commitment to the packet stored by the source
+// chain: sha256(timeoutTimestamp || revision || timeoutHeight || sha256(data)).
+func (p *Packet) Commitment() []byte {
+	buf := make([]byte, 24, 24+sha256.Size)
+	binary.BigEndian.PutUint64(buf, p.TimeoutTimestamp)
+	binary.BigEndian.PutUint64(buf[16:], p.TimeoutHeight)
+	dataHash := sha256.Sum256(p.Data)
+	hash := sha256.Sum256(append(buf, dataHash[:]...))
+	return hash[:]
+}
+
+// CommitmentPath returns the key of the packet commitment in the store of
+// the source chain.
+func (p *Packet) CommitmentPath() [][]byte {
+	key := fmt.Sprintf("commitments/ports/%s/channels/%s/sequences/%d", p.SourcePort, p.SourceChannel, p.Sequence)
+	return [][]byte{[]byte(CounterpartyStoreKey), []byte(key)}
+}
+
+// AcknowledgementPath returns the key of the commitment to the
+// acknowledgement of the packet in the store of the destination chain.
+func (p *Packet) AcknowledgementPath() [][]byte {
+	key := fmt.Sprintf("acks/ports/%s/channels/%s/sequences/%d", p.DestinationPort, p.DestinationChannel, p.Sequence)
+	return [][]byte{[]byte(CounterpartyStoreKey), []byte(key)}
+}
+
+// EncodePacket returns the encoding of the packets neatio sends and receives.
+func EncodePacket(p *Packet) ([]byte, error) {
+	return rlp.EncodeToBytes(p)
+}
+
+// DecodePacket decodes a packet encoded by EncodePacket.
+func DecodePacket(data []byte) (*Packet, error) {
+	p := new(Packet)
+	if err := rlp.DecodeBytes(data, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// AcknowledgementCommitment returns the commitment to an acknowledgement
+// stored by the destination chain of the packet.
+func AcknowledgementCommitment(ack []byte) []byte {
+	hash := sha256.Sum256(ack)
+	return hash[:]
+}
+
+// IsErrorAcknowledgement returns whether the ICS-20 acknowledgement reports
+// the transfer failed, and its tokens are to be refunded.
+func IsErrorAcknowledgement(ack []byte) (bool, error) {
+	var res struct {
+		Result []byte `json:"result"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(ack, &res); err != nil {
+		return false, fmt.Errorf("%v: acknowledgement: %v", ErrInvalidTransfer, err)
+	}
+	if res.Error == "" && len(res.Result) == 0 {
+		return false, fmt.Errorf("%v: empty acknowledgement", ErrInvalidTransfer)
+	}
+	return res.Error != "", nil
+}
+
+// TransferData is the data of a token transfer packet, following ICS-20.
+type TransferData struct {
+	Amount   string `json:"amount"`
+	Denom    string `json:"denom"`
+	Receiver string `json:"receiver"`
+	Sender   string `json:"sender"`
+}
+
+// Encode returns the packet data of the transfer, as JSON with sorted keys.
+func (t *TransferData) Encode() []byte {
+	data, _ := json.Marshal(t)
+	return data
+}
+
+// DecodeTransferData decodes and validates the data of a transfer packet.
+func DecodeTransferData(data []byte) (*TransferData, *big.Int, error) {
+	t := new(TransferData)
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, nil, fmt.Errorf("%v: %v", ErrInvalidTransfer, err)
+	}
+	amount, ok := new(big.Int).SetString(t.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("%v: amount %q", ErrInvalidTransfer, t.Amount)
+	}
+	if t.Denom == "" || t.Receiver == "" || t.Sender == "" {
+		return nil, nil, fmt.Errorf("%v: missing denom, receiver or sender", ErrInvalidTransfer)
+	}
+	return t, amount, nil
+}
+
+// DenomPrefix returns the prefix of the denominations of the tokens received
+// on port and channel.
+func DenomPrefix(port, channel string) string {
+	return port + "/" + channel + "/"
+}
+
+// ReturningDenom returns the denomination a token had on the chain it is
+// sent back to, which it left from port and channel, and whether it did.
+func ReturningDenom(port, channel, denom string) (string, bool) {
+	prefix := DenomPrefix(port, channel)
+	if !strings.HasPrefix(denom, prefix) {
+		return "", false
+	}
+	return denom[len(prefix):], true
+}
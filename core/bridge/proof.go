@@ -0,0 +1,202 @@
+package bridge
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// Hash and length operations of the proofs, numbered as in ICS-23.
+const (
+	HashOpNoHash = 0
+	HashOpSha256 = 1
+	HashOpKeccak = 3
+
+	LengthOpNoPrefix = 0
+	LengthOpVarProto = 1
+)
+
+var errInvalidProof = errors.New("invalid membership proof")
+
+// LeafOp hashes a key and its value into a leaf of the tree.
+type LeafOp struct {
+	Hash         uint8
+	PrehashKey   uint8
+	PrehashValue uint8
+	Length       uint8
+	Prefix       []byte
+}
+
+// InnerOp hashes a child with its siblings, given in Prefix and Suffix.
+type InnerOp struct {
+	Hash   uint8
+	Prefix []byte
+	Suffix []byte
+}
+
+// ExistenceProof proves a key is set to a value in a tree, from the leaf up
+// to the root.
+type ExistenceProof struct {
+	Key   []byte
+	Value []byte
+	Leaf  LeafOp
+	Path  []InnerOp
+}
+
+// ProofSpec are the operations a tree is built with. Checking a proof
+// follows them prevents passing an inner node as a leaf.
+type ProofSpec struct {
+	Leaf            LeafOp // Prefix is the prefix every leaf starts with
+	InnerHash       uint8
+	MinPrefixLength int
+	MaxPrefixLength int
+}
+
+var (
+	// IavlSpec is the spec of the IAVL trees of the Cosmos SDK stores.
+	IavlSpec = &ProofSpec{
+		Leaf:            LeafOp{Hash: HashOpSha256, PrehashKey: HashOpNoHash, PrehashValue: HashOpSha256, Length: LengthOpVarProto, Prefix: []byte{0}},
+		InnerHash:       HashOpSha256,
+		MinPrefixLength: 4,
+		MaxPrefixLength: 12 + 33,
+	}
+
+	// TendermintSpec is the spec of the simple merkle tree of the Cosmos SDK
+	// multistore, committing to the root of each store.
+	TendermintSpec = &ProofSpec{
+		Leaf:            LeafOp{Hash: HashOpSha256, PrehashKey: HashOpNoHash, PrehashValue: HashOpSha256, Length: LengthOpVarProto, Prefix: []byte{0}},
+		InnerHash:       HashOpSha256,
+		MinPrefixLength: 1,
+		MaxPrefixLength: 1 + 32,
+	}
+)
+
+// EncodeProof returns the encoding of the chained proofs a client verifies.
+func EncodeProof(proofs []*ExistenceProof) ([]byte, error) {
+	return rlp.EncodeToBytes(proofs)
+}
+
+// DecodeProof decodes chained proofs encoded by EncodeProof.
+func DecodeProof(data []byte) ([]*ExistenceProof, error) {
+	var proofs []*ExistenceProof
+	if err := rlp.DecodeBytes(data, &proofs); err != nil {
+		return nil, err
+	}
+	return proofs, nil
+}
+
+// VerifyMembership verifies value is stored at path under root. The proofs
+// are chained from the innermost tree: each proves the root of the previous
+// one is stored at the next key up the path, the last one against root.
+func VerifyMembership(specs []*ProofSpec, root []byte, proofs []*ExistenceProof, path [][]byte, value []byte) error {
+	if len(proofs) != len(specs) || len(path) != len(proofs) {
+		return fmt.Errorf("%v: %d proofs for a path of %d keys", errInvalidProof, len(proofs), len(path))
+	}
+	for i, proof := range proofs {
+		if !bytes.Equal(proof.Key, path[len(path)-1-i]) {
+			return fmt.Errorf("%v: key %q, want %q", errInvalidProof, proof.Key, path[len(path)-1-i])
+		}
+		if !bytes.Equal(proof.Value, value) {
+			return fmt.Errorf("%v: value mismatch for key %q", errInvalidProof, proof.Key)
+		}
+		if err := proof.checkSpec(specs[i]); err != nil {
+			return err
+		}
+		var err error
+		if value, err = proof.Root(); err != nil {
+			return err
+		}
+	}
+	if !bytes.Equal(value, root) {
+		return fmt.Errorf("%v: root %x, want %x", errInvalidProof, value, root)
+	}
+	return nil
+}
+
+// Root returns the root of the tree the proof is for.
+func (p *ExistenceProof) Root() ([]byte, error) {
+	if len(p.Key) == 0 || len(p.Value) == 0 {
+		return nil, fmt.Errorf("%v: empty key or value", errInvalidProof)
+	}
+	key, err := prepareLeafData(p.Leaf.PrehashKey, p.Leaf.Length, p.Key)
+	if err != nil {
+		return nil, err
+	}
+	value, err := prepareLeafData(p.Leaf.PrehashValue, p.Leaf.Length, p.Value)
+	if err != nil {
+		return nil, err
+	}
+	data := append(append(append([]byte(nil), p.Leaf.Prefix...), key...), value...)
+	res, err := doHash(p.Leaf.Hash, data)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range p.Path {
+		data := append(append(append([]byte(nil), op.Prefix...), res...), op.Suffix...)
+		if res, err = doHash(op.Hash, data); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (p *ExistenceProof) checkSpec(spec *ProofSpec) error {
+	leaf := p.Leaf
+	if leaf.Hash != spec.Leaf.Hash || leaf.PrehashKey != spec.Leaf.PrehashKey ||
+		leaf.PrehashValue != spec.Leaf.PrehashValue || leaf.Length != spec.Leaf.Length {
+		return fmt.Errorf("%v: leaf operations not following the spec", errInvalidProof)
+	}
+	if !bytes.HasPrefix(leaf.Prefix, spec.Leaf.Prefix) {
+		return fmt.Errorf("%v: leaf prefix %x, want %x", errInvalidProof, leaf.Prefix, spec.Leaf.Prefix)
+	}
+	for _, op := range p.Path {
+		if op.Hash != spec.InnerHash {
+			return fmt.Errorf("%v: inner hash operation not following the spec", errInvalidProof)
+		}
+		if bytes.HasPrefix(op.Prefix, spec.Leaf.Prefix) {
+			return fmt.Errorf("%v: inner prefix starts with the leaf prefix", errInvalidProof)
+		}
+		if len(op.Prefix) < spec.MinPrefixLength || len(op.Prefix) > spec.MaxPrefixLength {
+			return fmt.Errorf("%v: inner prefix of %d bytes", errInvalidProof, len(op.Prefix))
+		}
+	}
+	return nil
+}
+
+func prepareLeafData(hashOp, lengthOp uint8, data []byte) ([]byte, error) {
+	if hashOp != HashOpNoHash {
+		var err error
+		if data, err = doHash(hashOp, data); err != nil {
+			return nil, err
+		}
+	}
+	switch lengthOp {
+	case LengthOpNoPrefix:
+		return data, nil
+	case LengthOpVarProto:
+		buf := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(buf, uint64(len(data)))
+		return append(buf[:n], data...), nil
+	default:
+		return nil, fmt.Errorf("%v: unsupported length operation %d", errInvalidProof, lengthOp)
+	}
+}
+
+func doHash(hashOp uint8, data []byte) ([]byte, error) {
+	switch hashOp {
+	case HashOpNoHash:
+		return data, nil
+	case HashOpSha256:
+		hash := sha256.Sum256(data)
+		return hash[:], nil
+	case HashOpKeccak:
+		return crypto.Keccak256(data), nil
+	default:
+		return nil, fmt.Errorf("%v: unsupported hash operation %d", errInvalidProof, hashOp)
+	}
+}
@@ -0,0 +1,81 @@
+package bridge
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+// testProofs returns the chained proofs of value at path, and their root.
+func testProofs(t *testing.T, path [][]byte, value []byte) ([]*ExistenceProof, []byte) {
+	store := &ExistenceProof{
+		Key:   path[1],
+		Value: value,
+		Leaf:  IavlSpec.Leaf,
+		Path: []InnerOp{
+			{Hash: HashOpSha256, Prefix: []byte{2, 4, 2, 0x20}, Suffix: bytes.Repeat([]byte{1}, 32)},
+			{Hash: HashOpSha256, Prefix: append([]byte{4, 8, 4, 0x20}, bytes.Repeat([]byte{2}, 32)...)},
+		},
+	}
+	storeRoot, err := store.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	multistore := &ExistenceProof{
+		Key:   path[0],
+		Value: storeRoot,
+		Leaf:  TendermintSpec.Leaf,
+		Path: []InnerOp{
+			{Hash: HashOpSha256, Prefix: []byte{1}, Suffix: bytes.Repeat([]byte{3}, 32)},
+		},
+	}
+	root, err := multistore.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*ExistenceProof{store, multistore}, root
+}
+
+func TestVerifyMembership(t *testing.T) {
+	packet := &Packet{Sequence: 1, SourcePort: "transfer", SourceChannel: "channel-0", Data: []byte("data"), TimeoutHeight: 100}
+	path, value := packet.CommitmentPath(), packet.Commitment()
+	specs := []*ProofSpec{IavlSpec, TendermintSpec}
+
+	proofs, root := testProofs(t, path, value)
+	data, err := EncodeProof(proofs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeProof(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyMembership(specs, root, decoded, path, value); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+
+	forged := sha256.Sum256([]byte("forged"))
+	if err := VerifyMembership(specs, root, proofs, path, forged[:]); err == nil {
+		t.Error("proof of another value accepted")
+	}
+	other := &Packet{Sequence: 2, SourcePort: "transfer", SourceChannel: "channel-0", Data: []byte("data"), TimeoutHeight: 100}
+	if err := VerifyMembership(specs, root, proofs, other.CommitmentPath(), value); err == nil {
+		t.Error("proof of another key accepted")
+	}
+	if err := VerifyMembership(specs, forged[:], proofs, path, value); err == nil {
+		t.Error("proof against another root accepted")
+	}
+
+	// An inner node passed as a leaf breaks the spec.
+	proofs, root = testProofs(t, path, value)
+	proofs[0].Path[0].Prefix = []byte{0, 1, 2, 3}
+	if proofs[1].Value, err = proofs[0].Root(); err != nil {
+		t.Fatal(err)
+	}
+	if root, err = proofs[1].Root(); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyMembership(specs, root, proofs, path, value); err == nil {
+		t.Error("proof not following the spec accepted")
+	}
+}
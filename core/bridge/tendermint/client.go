@@ -0,0 +1,255 @@
+// Package tendermint implements the bridge light client of Tendermint chains,
+// such as the Cosmos SDK chains, following the light client verification of
+// Tendermint and ICS-07.
+package tendermint
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/neatlab/neatio/core/bridge"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ClientType is the type the Tendermint client is registered with.
+const ClientType = "07-tendermint"
+
+var (
+	ErrInvalidHeader      = errors.New("invalid tendermint header")
+	ErrTrustingPeriod     = errors.New("trusted consensus state outside the trusting period")
+	ErrInsufficientPower  = errors.New("insufficient voting power signed the header")
+	ErrInvalidClientState = errors.New("invalid tendermint client state")
+)
+
+// ClientState is the state of the light client of a Tendermint chain.
+type ClientState struct {
+	ChainId         string
+	TrustLevelNum   uint64 // fraction of the trusted power which must sign
+	TrustLevelDenom uint64 // a header skipping validator set changes
+	TrustingPeriod  uint64 // seconds a consensus state is trusted for
+	MaxClockDrift   uint64 // seconds headers may be ahead of neatio
+	Height          uint64 // latest height verified
+}
+
+// NewClientState returns the state of a client of chainId, with the default
+// trust level of 1/3.
+func NewClientState(chainId string, trustingPeriod, maxClockDrift, height uint64) *ClientState {
+	return &ClientState{
+		ChainId:         chainId,
+		TrustLevelNum:   1,
+		TrustLevelDenom: 3,
+		TrustingPeriod:  trustingPeriod,
+		MaxClockDrift:   maxClockDrift,
+		Height:          height,
+	}
+}
+
+// DecodeClient decodes a client state encoded by its Encode method.
+func DecodeClient(data []byte) (bridge.Client, error) {
+	cs := new(ClientState)
+	if err := rlp.DecodeBytes(data, cs); err != nil {
+		return nil, err
+	}
+	if err := cs.Validate(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// Validate checks the parameters of the client.
+func (cs *ClientState) Validate() error {
+	switch {
+	case cs.ChainId == "":
+		return fmt.Errorf("%v: empty chain id", ErrInvalidClientState)
+	case cs.TrustLevelDenom == 0 || cs.TrustLevelNum*3 < cs.TrustLevelDenom || cs.TrustLevelNum > cs.TrustLevelDenom:
+		return fmt.Errorf("%v: trust level %d/%d not within [1/3, 1]", ErrInvalidClientState, cs.TrustLevelNum, cs.TrustLevelDenom)
+	case cs.TrustingPeriod == 0:
+		return fmt.Errorf("%v: zero trusting period", ErrInvalidClientState)
+	case cs.Height == 0:
+		return fmt.Errorf("%v: zero height", ErrInvalidClientState)
+	}
+	return nil
+}
+
+func (cs *ClientState) ClientType() string { return ClientType }
+
+func (cs *ClientState) ChainID() string { return cs.ChainId }
+
+func (cs *ClientState) LatestHeight() uint64 { return cs.Height }
+
+func (cs *ClientState) Encode() ([]byte, error) { return rlp.EncodeToBytes(cs) }
+
+// EncodeHeader returns the encoding of a header submitted to the client.
+func EncodeHeader(h *Header) ([]byte, error) {
+	return rlp.EncodeToBytes(h)
+}
+
+func decodeHeader(data []byte) (*Header, error) {
+	h := new(Header)
+	if err := rlp.DecodeBytes(data, h); err != nil {
+		return nil, fmt.Errorf("%v: %v", ErrInvalidHeader, err)
+	}
+	return h, nil
+}
+
+func (cs *ClientState) TrustedHeight(header []byte) (uint64, error) {
+	h, err := decodeHeader(header)
+	if err != nil {
+		return 0, err
+	}
+	return h.TrustedHeight, nil
+}
+
+// Update verifies the header is signed by the validators trusted at its
+// trusted height, directly for the next height, or by enough of them
+// otherwise, and by more than 2/3 of its own validators.
+func (cs *ClientState) Update(header []byte, trusted *bridge.ConsensusState, now time.Time) (bridge.Client, *bridge.ConsensusState, error) {
+	h, err := decodeHeader(header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cs.verifyHeader(h, trusted, now); err != nil {
+		return nil, nil, err
+	}
+	updated := *cs
+	if h.Header.Height > updated.Height {
+		updated.Height = h.Header.Height
+	}
+	consensus := &bridge.ConsensusState{
+		Height:             h.Header.Height,
+		Timestamp:          uint64(h.Header.Time().UnixNano()),
+		Root:               h.Header.AppHash,
+		NextValidatorsHash: h.Header.NextValidatorsHash,
+	}
+	return &updated, consensus, nil
+}
+
+func (cs *ClientState) verifyHeader(h *Header, trusted *bridge.ConsensusState, now time.Time) error {
+	bh := &h.Header
+	if bh.ChainID != cs.ChainId {
+		return fmt.Errorf("%v: chain id %s, want %s", ErrInvalidHeader, bh.ChainID, cs.ChainId)
+	}
+	if h.TrustedHeight != trusted.Height || bh.Height <= trusted.Height {
+		return fmt.Errorf("%v: height %d not above the trusted height %d", ErrInvalidHeader, bh.Height, trusted.Height)
+	}
+	trustedTime := time.Unix(0, int64(trusted.Timestamp))
+	if !trustedTime.Add(time.Duration(cs.TrustingPeriod) * time.Second).After(now) {
+		return ErrTrustingPeriod
+	}
+	if !bh.Time().After(trustedTime) {
+		return fmt.Errorf("%v: time %v not after the trusted time %v", ErrInvalidHeader, bh.Time(), trustedTime)
+	}
+	if bh.Time().After(now.Add(time.Duration(cs.MaxClockDrift) * time.Second)) {
+		return fmt.Errorf("%v: time %v in the future", ErrInvalidHeader, bh.Time())
+	}
+	if !bytes.Equal(validatorSetHash(h.Validators), bh.ValidatorsHash) {
+		return fmt.Errorf("%v: validators not matching the validators hash", ErrInvalidHeader)
+	}
+	if h.Commit.Height != bh.Height || !bytes.Equal(h.Commit.BlockID.Hash, bh.Hash()) {
+		return fmt.Errorf("%v: commit not for the header", ErrInvalidHeader)
+	}
+
+	if bh.Height == trusted.Height+1 {
+		if !bytes.Equal(bh.ValidatorsHash, trusted.NextValidatorsHash) {
+			return fmt.Errorf("%v: validators not the next validators of the trusted header", ErrInvalidHeader)
+		}
+	} else {
+		if !bytes.Equal(validatorSetHash(h.TrustedValidators), trusted.NextValidatorsHash) {
+			return fmt.Errorf("%v: trusted validators not matching the trusted consensus state", ErrInvalidHeader)
+		}
+		if err := verifyCommitTrusting(bh.ChainID, &h.Commit, h.TrustedValidators, cs.TrustLevelNum, cs.TrustLevelDenom); err != nil {
+			return err
+		}
+	}
+	return verifyCommit(bh.ChainID, &h.Commit, h.Validators)
+}
+
+// verifyCommit checks more than 2/3 of the power of validators signed the
+// commit, the signatures in the order of the validators.
+func verifyCommit(chainID string, commit *Commit, validators []*Validator) error {
+	if len(commit.Signatures) != len(validators) {
+		return fmt.Errorf("%v: %d signatures for %d validators", ErrInvalidHeader, len(commit.Signatures), len(validators))
+	}
+	total, signed := new(big.Int), new(big.Int)
+	for i, sig := range commit.Signatures {
+		val := validators[i]
+		total.Add(total, new(big.Int).SetUint64(val.VotingPower))
+		if sig.Flag != BlockIDFlagCommit {
+			continue
+		}
+		if !bytes.Equal(sig.ValidatorAddress, val.Address()) {
+			return fmt.Errorf("%v: signature %d not from validator %x", ErrInvalidHeader, i, val.Address())
+		}
+		if err := verifySignature(chainID, commit, i, val); err != nil {
+			return err
+		}
+		signed.Add(signed, new(big.Int).SetUint64(val.VotingPower))
+	}
+	return checkPower(signed, total, 2, 3)
+}
+
+// verifyCommitTrusting checks more than num/denom of the power of the
+// trusted validators signed the commit.
+func verifyCommitTrusting(chainID string, commit *Commit, trusted []*Validator, num, denom uint64) error {
+	byAddress := make(map[string]*Validator, len(trusted))
+	total := new(big.Int)
+	for _, val := range trusted {
+		byAddress[string(val.Address())] = val
+		total.Add(total, new(big.Int).SetUint64(val.VotingPower))
+	}
+	signed := new(big.Int)
+	for i, sig := range commit.Signatures {
+		if sig.Flag != BlockIDFlagCommit {
+			continue
+		}
+		val, exist := byAddress[string(sig.ValidatorAddress)]
+		if !exist {
+			continue
+		}
+		// Count each trusted validator once.
+		delete(byAddress, string(sig.ValidatorAddress))
+		if err := verifySignature(chainID, commit, i, val); err != nil {
+			return err
+		}
+		signed.Add(signed, new(big.Int).SetUint64(val.VotingPower))
+	}
+	return checkPower(signed, total, num, denom)
+}
+
+func verifySignature(chainID string, commit *Commit, idx int, val *Validator) error {
+	if len(val.PubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("%v: validator key of %d bytes", ErrInvalidHeader, len(val.PubKey))
+	}
+	if !ed25519.Verify(val.PubKey, commit.voteSignBytes(chainID, idx), commit.Signatures[idx].Signature) {
+		return fmt.Errorf("%v: wrong signature of validator %x", ErrInvalidHeader, val.Address())
+	}
+	return nil
+}
+
+// checkPower checks signed is more than num/denom of total.
+func checkPower(signed, total *big.Int, num, denom uint64) error {
+	lhs := new(big.Int).Mul(signed, new(big.Int).SetUint64(denom))
+	rhs := new(big.Int).Mul(total, new(big.Int).SetUint64(num))
+	if total.Sign() == 0 || lhs.Cmp(rhs) <= 0 {
+		return fmt.Errorf("%v: %v of %v", ErrInsufficientPower, signed, total)
+	}
+	return nil
+}
+
+// VerifyMembership verifies a Cosmos SDK proof: the IAVL proof of the store
+// chained with the proof of the store root in the multistore.
+func (cs *ClientState) VerifyMembership(root []byte, proof []byte, path [][]byte, value []byte) error {
+	proofs, err := bridge.DecodeProof(proof)
+	if err != nil {
+		return err
+	}
+	return bridge.VerifyMembership([]*bridge.ProofSpec{bridge.IavlSpec, bridge.TendermintSpec}, root, proofs, path, value)
+}
+
+func init() {
+	bridge.RegisterClientType(ClientType, DecodeClient)
+}
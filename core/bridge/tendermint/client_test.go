@@ -0,0 +1,154 @@
+package tendermint
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neatlab/neatio/core/bridge"
+)
+
+func sum(s string) []byte {
+	hash := sha256.Sum256([]byte(s))
+	return hash[:]
+}
+
+func TestHeaderHash(t *testing.T) {
+	// The header hash test vector of Tendermint.
+	tm := time.Date(2019, 10, 13, 16, 14, 44, 0, time.UTC)
+	h := &BlockHeader{
+		VersionBlock:       1,
+		VersionApp:         2,
+		ChainID:            "chainId",
+		Height:             3,
+		TimeSeconds:        uint64(tm.Unix()),
+		LastBlockID:        BlockID{Hash: make([]byte, 32), PartSetTotal: 6, PartSetHash: make([]byte, 32)},
+		LastCommitHash:     sum("last_commit_hash"),
+		DataHash:           sum("data_hash"),
+		ValidatorsHash:     sum("validators_hash"),
+		NextValidatorsHash: sum("next_validators_hash"),
+		ConsensusHash:      sum("consensus_hash"),
+		AppHash:            sum("app_hash"),
+		LastResultsHash:    sum("last_results_hash"),
+		EvidenceHash:       sum("evidence_hash"),
+		ProposerAddress:    sum("proposer_address")[:20],
+	}
+	want := "f740121f553b5418c3efbd343c2dbfe9e007bb67b0d020a0741374bab65242a4"
+	if got := hex.EncodeToString(h.Hash()); got != want {
+		t.Fatalf("hash mismatch: have %s, want %s", got, want)
+	}
+}
+
+type testChain struct {
+	keys       []ed25519.PrivateKey
+	validators []*Validator
+}
+
+func newTestChain(n int) *testChain {
+	c := new(testChain)
+	for i := 0; i < n; i++ {
+		seed := sum(string(rune('a' + i)))
+		key := ed25519.NewKeyFromSeed(seed)
+		c.keys = append(c.keys, key)
+		c.validators = append(c.validators, &Validator{PubKey: key.Public().(ed25519.PublicKey), VotingPower: 10})
+	}
+	return c
+}
+
+// header returns the header at height signed by the validators in signers.
+func (c *testChain) header(height uint64, tm time.Time, signers ...int) *Header {
+	valHash := validatorSetHash(c.validators)
+	h := &Header{
+		Header: BlockHeader{
+			VersionBlock:       11,
+			ChainID:            "cosmoshub-4",
+			Height:             height,
+			TimeSeconds:        uint64(tm.Unix()),
+			TimeNanos:          uint32(tm.Nanosecond()),
+			AppHash:            sum("app"),
+			ValidatorsHash:     valHash,
+			NextValidatorsHash: valHash,
+		},
+		Validators:        c.validators,
+		TrustedValidators: c.validators,
+	}
+	h.Commit = Commit{
+		Height:     height,
+		BlockID:    BlockID{Hash: h.Header.Hash(), PartSetTotal: 1, PartSetHash: sum("parts")},
+		Signatures: make([]*CommitSig, len(c.validators)),
+	}
+	for i := range c.validators {
+		h.Commit.Signatures[i] = &CommitSig{Flag: BlockIDFlagAbsent}
+	}
+	for _, i := range signers {
+		sig := &CommitSig{
+			Flag:             BlockIDFlagCommit,
+			ValidatorAddress: c.validators[i].Address(),
+			TimeSeconds:      uint64(tm.Unix()),
+		}
+		h.Commit.Signatures[i] = sig
+		sig.Signature = ed25519.Sign(c.keys[i], h.Commit.voteSignBytes("cosmoshub-4", i))
+	}
+	return h
+}
+
+func TestUpdate(t *testing.T) {
+	chain := newTestChain(4)
+	start := time.Unix(1600000000, 0)
+	trusted := &bridge.ConsensusState{
+		Height:             10,
+		Timestamp:          uint64(start.UnixNano()),
+		Root:               sum("app"),
+		NextValidatorsHash: validatorSetHash(chain.validators),
+	}
+	client := NewClientState("cosmoshub-4", 3600, 10, 10)
+	now := start.Add(time.Minute)
+
+	tests := []struct {
+		height  uint64
+		signers []int
+		modify  func(h *Header)
+		err     error
+	}{
+		{11, []int{0, 1, 2}, nil, nil},
+		{20, []int{0, 1, 2, 3}, nil, nil},
+		{11, []int{0, 1}, nil, ErrInsufficientPower},
+		{11, []int{0, 1, 2}, func(h *Header) { h.Header.ChainID = "other" }, ErrInvalidHeader},
+		{11, []int{0, 1, 2}, func(h *Header) { h.Commit.Signatures[0].Signature[0] ^= 1 }, ErrInvalidHeader},
+		{11, []int{0, 1, 2}, func(h *Header) { h.Header.AppHash = sum("forged") }, ErrInvalidHeader},
+		{10, []int{0, 1, 2}, nil, ErrInvalidHeader},
+	}
+	for i, tt := range tests {
+		h := chain.header(tt.height, start.Add(time.Duration(tt.height)*time.Second), tt.signers...)
+		h.TrustedHeight = 10
+		if tt.modify != nil {
+			tt.modify(h)
+		}
+		data, err := EncodeHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		updated, cs, err := client.Update(data, trusted, now)
+		if (err == nil) != (tt.err == nil) || (err != nil && !strings.HasPrefix(err.Error(), tt.err.Error())) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if updated.LatestHeight() != tt.height || cs.Height != tt.height || hex.EncodeToString(cs.Root) != hex.EncodeToString(sum("app")) {
+			t.Errorf("test %d: updated to height %d, consensus state %+v", i, updated.LatestHeight(), cs)
+		}
+	}
+
+	// A consensus state past the trusting period is no longer trusted.
+	h := chain.header(11, start.Add(time.Second), 0, 1, 2)
+	h.TrustedHeight = 10
+	data, _ := EncodeHeader(h)
+	if _, _, err := client.Update(data, trusted, start.Add(2*time.Hour)); err != ErrTrustingPeriod {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrTrustingPeriod)
+	}
+}
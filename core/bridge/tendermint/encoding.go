@@ -0,0 +1,96 @@
+package tendermint
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// The signed and hashed Tendermint structures are protobuf messages, encoded
+// here field by field. Fields at their default value are omitted as in
+// proto3, except the embedded messages Tendermint declares non-nullable.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func appendVarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	return append(buf, b[:n]...)
+}
+
+func appendTag(buf []byte, field int, wireType int) []byte {
+	return appendVarint(buf, uint64(field)<<3|uint64(wireType))
+}
+
+func appendVarintField(buf []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	return appendVarint(appendTag(buf, field, wireVarint), v)
+}
+
+func appendSfixed64Field(buf []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(appendTag(buf, field, wireFixed64), b[:]...)
+}
+
+func appendBytesField(buf []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return buf
+	}
+	return appendMessageField(buf, field, v)
+}
+
+// appendMessageField appends an embedded message, even empty.
+func appendMessageField(buf []byte, field int, msg []byte) []byte {
+	buf = appendVarint(appendTag(buf, field, wireBytes), uint64(len(msg)))
+	return append(buf, msg...)
+}
+
+func encodeTimestamp(seconds uint64, nanos uint32) []byte {
+	return appendVarintField(appendVarintField(nil, 1, seconds), 2, uint64(nanos))
+}
+
+// leafHash and innerHash are the hashes of the RFC 6962 merkle tree
+// Tendermint commits to lists with.
+func leafHash(leaf []byte) []byte {
+	hash := sha256.Sum256(append([]byte{0}, leaf...))
+	return hash[:]
+}
+
+func innerHash(left, right []byte) []byte {
+	data := make([]byte, 0, 1+len(left)+len(right))
+	data = append(append(append(data, 1), left...), right...)
+	hash := sha256.Sum256(data)
+	return hash[:]
+}
+
+// merkleRoot returns the root of the merkle tree of items.
+func merkleRoot(items [][]byte) []byte {
+	switch len(items) {
+	case 0:
+		hash := sha256.Sum256(nil)
+		return hash[:]
+	case 1:
+		return leafHash(items[0])
+	default:
+		k := splitPoint(len(items))
+		return innerHash(merkleRoot(items[:k]), merkleRoot(items[k:]))
+	}
+}
+
+// splitPoint returns the largest power of 2 less than n.
+func splitPoint(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+	return k
+}
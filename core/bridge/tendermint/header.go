@@ -0,0 +1,142 @@
+package tendermint
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// BlockIDFlag tells whether a validator signed the block of a commit.
+const (
+	BlockIDFlagAbsent = 1
+	BlockIDFlagCommit = 2
+	BlockIDFlagNil    = 3
+)
+
+const precommitType = 2
+
+// BlockID identifies a Tendermint block.
+type BlockID struct {
+	Hash         []byte
+	PartSetTotal uint32
+	PartSetHash  []byte
+}
+
+func (id *BlockID) isZero() bool {
+	return len(id.Hash) == 0 && id.PartSetTotal == 0 && len(id.PartSetHash) == 0
+}
+
+func (id *BlockID) encode() []byte {
+	partSetHeader := appendBytesField(appendVarintField(nil, 1, uint64(id.PartSetTotal)), 2, id.PartSetHash)
+	return appendMessageField(appendBytesField(nil, 1, id.Hash), 2, partSetHeader)
+}
+
+// BlockHeader is the header of a Tendermint block.
+type BlockHeader struct {
+	VersionBlock       uint64
+	VersionApp         uint64
+	ChainID            string
+	Height             uint64
+	TimeSeconds        uint64
+	TimeNanos          uint32
+	LastBlockID        BlockID
+	LastCommitHash     []byte
+	DataHash           []byte
+	ValidatorsHash     []byte
+	NextValidatorsHash []byte
+	ConsensusHash      []byte
+	AppHash            []byte
+	LastResultsHash    []byte
+	EvidenceHash       []byte
+	ProposerAddress    []byte
+}
+
+// Time returns the time of the block.
+func (h *BlockHeader) Time() time.Time {
+	return time.Unix(int64(h.TimeSeconds), int64(h.TimeNanos)).UTC()
+}
+
+// Hash returns the hash of the header the validators sign.
+func (h *BlockHeader) Hash() []byte {
+	version := appendVarintField(appendVarintField(nil, 1, h.VersionBlock), 2, h.VersionApp)
+	return merkleRoot([][]byte{
+		version,
+		appendBytesField(nil, 1, []byte(h.ChainID)),
+		appendVarintField(nil, 1, h.Height),
+		encodeTimestamp(h.TimeSeconds, h.TimeNanos),
+		h.LastBlockID.encode(),
+		appendBytesField(nil, 1, h.LastCommitHash),
+		appendBytesField(nil, 1, h.DataHash),
+		appendBytesField(nil, 1, h.ValidatorsHash),
+		appendBytesField(nil, 1, h.NextValidatorsHash),
+		appendBytesField(nil, 1, h.ConsensusHash),
+		appendBytesField(nil, 1, h.AppHash),
+		appendBytesField(nil, 1, h.LastResultsHash),
+		appendBytesField(nil, 1, h.EvidenceHash),
+		appendBytesField(nil, 1, h.ProposerAddress),
+	})
+}
+
+// CommitSig is the signature of a validator in a commit.
+type CommitSig struct {
+	Flag             uint8
+	ValidatorAddress []byte
+	TimeSeconds      uint64
+	TimeNanos        uint32
+	Signature        []byte
+}
+
+// Commit is the precommits of the validators for a block.
+type Commit struct {
+	Height     uint64
+	Round      uint32
+	BlockID    BlockID
+	Signatures []*CommitSig
+}
+
+// voteSignBytes returns the bytes validator idx signed for the commit: its
+// length-prefixed canonical precommit.
+func (c *Commit) voteSignBytes(chainID string, idx int) []byte {
+	sig := c.Signatures[idx]
+	vote := appendVarintField(nil, 1, precommitType)
+	vote = appendSfixed64Field(vote, 2, c.Height)
+	vote = appendSfixed64Field(vote, 3, uint64(c.Round))
+	if !c.BlockID.isZero() {
+		vote = appendMessageField(vote, 4, c.BlockID.encode())
+	}
+	vote = appendMessageField(vote, 5, encodeTimestamp(sig.TimeSeconds, sig.TimeNanos))
+	vote = appendBytesField(vote, 6, []byte(chainID))
+	return append(appendVarint(nil, uint64(len(vote))), vote...)
+}
+
+// Validator is a Tendermint validator, with an ed25519 key.
+type Validator struct {
+	PubKey      []byte
+	VotingPower uint64
+}
+
+// Address returns the address of the validator in the commits.
+func (v *Validator) Address() []byte {
+	hash := sha256.Sum256(v.PubKey)
+	return hash[:20]
+}
+
+// validatorSetHash returns the hash of the validator set headers commit to.
+func validatorSetHash(validators []*Validator) []byte {
+	items := make([][]byte, len(validators))
+	for i, v := range validators {
+		pubKey := appendBytesField(nil, 1, v.PubKey)
+		items[i] = appendVarintField(appendMessageField(nil, 1, pubKey), 2, v.VotingPower)
+	}
+	return merkleRoot(items)
+}
+
+// Header is a header of the external chain submitted to the client, with
+// the validators which signed it, and the validators trusted at the height
+// it is verified against.
+type Header struct {
+	Header            BlockHeader
+	Commit            Commit
+	Validators        []*Validator
+	TrustedHeight     uint64
+	TrustedValidators []*Validator
+}
@@ -345,6 +345,24 @@ func GetPendingSideChainData(db dbm.DB, chainId string) *CoreChainInfo {
 	return nil
 }
 
+// GetPendingSideChainIds get the ids of the side chains waiting for launch
+func GetPendingSideChainIds(db dbm.DB) []string {
+	pendingChainMtx.Lock()
+	defer pendingChainMtx.Unlock()
+
+	var idx []pendingIdxData
+	pendingIdxByteSlice := db.Get(pendingChainIndexKey)
+	if pendingIdxByteSlice != nil {
+		wire.ReadBinaryBytes(pendingIdxByteSlice, &idx)
+	}
+
+	ids := make([]string, 0, len(idx))
+	for _, v := range idx {
+		ids = append(ids, v.ChainID)
+	}
+	return ids
+}
+
 // CreatePendingSideChainData create the pending side chain data with index
 func CreatePendingSideChainData(db dbm.DB, cci *CoreChainInfo) {
 	storePendingSideChainData(db, cci, true)
@@ -0,0 +1,78 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+)
+
+// checkCrossChainTransfer checks the amount of a TX3 against the minimum set by
+// the governance of the side chain, and that the sender affords the cross
+// chain fee on top of it.
+func checkCrossChainTransfer(statedb *state.StateDB, from common.Address, function neatabi.FunctionType, tx *types.Transaction) error {
+	if function != neatabi.WithdrawFromSideChain {
+		return nil
+	}
+	if tx.Value().Sign() <= 0 || tx.Value().Cmp(statedb.CrossChainMinAmount()) < 0 {
+		return ErrCrossChainAmount
+	}
+	fee, _ := statedb.CrossChainFee(tx.Value())
+	if cost := new(big.Int).Add(tx.Value(), fee); statedb.GetBalance(from).Cmp(cost) < 0 {
+		return fmt.Errorf("insufficient NEAT for cross chain transfer (%x). Req %v, has %v", from.Bytes()[:4], cost, statedb.GetBalance(from))
+	}
+	return nil
+}
+
+// applyCrossChainTransfer moves the value of the cross chain transfers. A TX3
+// burns the amount withdrawn from the side chain, the sender paying the fee of
+// the side chain on top of it; a TX4 releases the amount on the main chain,
+// minus the fee of the main chain, or holds it for the challenge window set by
+// governance, see ReleaseWithdrawals.
+//
+// The relayer part of a fee goes to the proposer of the block, which relays
+// the transfer between the chains. The rest is added to the gas fees of the
+// block, shared by the validator with its delegators.
+//
+// The transfers move their amounts from the cross chain fee fork block of the
+// chain config.
+func applyCrossChainTransfer(statedb *state.StateDB, header *types.Header, from common.Address, function neatabi.FunctionType, tx *types.Transaction, totalUsedMoney *big.Int) error {
+	var fee, relayerFee *big.Int
+	switch function {
+	case neatabi.WithdrawFromSideChain:
+		if err := checkCrossChainTransfer(statedb, from, function, tx); err != nil {
+			return err
+		}
+		fee, relayerFee = statedb.CrossChainFee(tx.Value())
+		statedb.SubBalance(from, new(big.Int).Add(tx.Value(), fee))
+	case neatabi.WithdrawFromMainChain:
+		var args neatabi.WithdrawFromMainChainArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.WithdrawFromMainChain.String(), tx.Data()[4:]); err != nil {
+			return err
+		}
+		if window := statedb.ChallengeWindow(); window > 0 {
+			statedb.AddPendingWithdrawal(args.TxHash, from, args.ChainId, args.Amount, header.Number.Uint64()+window)
+			return nil
+		}
+		payWithdrawal(statedb, header.Coinbase, from, args.Amount, totalUsedMoney)
+		return nil
+	default:
+		return nil
+	}
+
+	statedb.AddBalance(header.Coinbase, relayerFee)
+	totalUsedMoney.Add(totalUsedMoney, new(big.Int).Sub(fee, relayerFee))
+	return nil
+}
+
+// payWithdrawal credits the amount withdrawn from a side chain on the main
+// chain, minus the cross chain fee.
+func payWithdrawal(statedb *state.StateDB, coinbase, to common.Address, amount, totalUsedMoney *big.Int) {
+	fee, relayerFee := statedb.CrossChainFee(amount)
+	statedb.AddBalance(to, new(big.Int).Sub(amount, fee))
+	statedb.AddBalance(coinbase, relayerFee)
+	totalUsedMoney.Add(totalUsedMoney, new(big.Int).Sub(fee, relayerFee))
+}
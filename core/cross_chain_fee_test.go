@@ -0,0 +1,63 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+)
+
+func TestApplyCrossChainTransfer(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetGovParam(state.GovParamCrossChainFeeRate, big.NewInt(100), 0, 0)   // 1%
+	statedb.SetGovParam(state.GovParamCrossChainRelayerFee, big.NewInt(25), 0, 0) // A quarter of the fee
+	statedb.SetGovParam(state.GovParamCrossChainMinAmount, big.NewInt(1), 0, 0)
+
+	from := common.BytesToAddress([]byte{0x01})
+	header := &types.Header{Coinbase: common.BytesToAddress([]byte{0x02})}
+	neat := big.NewInt(1e18)
+	statedb.AddBalance(from, new(big.Int).Mul(big.NewInt(200), neat))
+
+	// TX3 below the minimum
+	small := types.NewTransaction(0, neatabi.ChainContractMagicAddr, big.NewInt(1000), 0, big.NewInt(1), nil)
+	if err := applyCrossChainTransfer(statedb, header, from, neatabi.WithdrawFromSideChain, small, new(big.Int)); err != ErrCrossChainAmount {
+		t.Fatalf("small TX3: error mismatch: have %v, want %v", err, ErrCrossChainAmount)
+	}
+
+	// TX3 burning 100 NEAT, paying 1 NEAT of fee
+	amount := new(big.Int).Mul(big.NewInt(100), neat)
+	tx3 := types.NewTransaction(0, neatabi.ChainContractMagicAddr, amount, 0, big.NewInt(1), nil)
+	gasFees := new(big.Int)
+	if err := applyCrossChainTransfer(statedb, header, from, neatabi.WithdrawFromSideChain, tx3, gasFees); err != nil {
+		t.Fatalf("TX3: unexpected error: %v", err)
+	}
+	if have, want := statedb.GetBalance(from), new(big.Int).Mul(big.NewInt(99), neat); have.Cmp(want) != 0 {
+		t.Errorf("TX3 sender balance mismatch: have %v, want %v", have, want)
+	}
+	if have, want := statedb.GetBalance(header.Coinbase), big.NewInt(25e16); have.Cmp(want) != 0 {
+		t.Errorf("TX3 relayer fee mismatch: have %v, want %v", have, want)
+	}
+	if want := big.NewInt(75e16); gasFees.Cmp(want) != 0 {
+		t.Errorf("TX3 validator fee mismatch: have %v, want %v", gasFees, want)
+	}
+
+	// TX4 releasing the 100 NEAT minus 1 NEAT of fee
+	data, err := neatabi.ChainABI.Pack(neatabi.WithdrawFromMainChain.String(), "side", amount, common.Hash{0x03})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx4 := types.NewTransaction(1, neatabi.ChainContractMagicAddr, new(big.Int), 0, big.NewInt(1), data)
+	if err := applyCrossChainTransfer(statedb, header, from, neatabi.WithdrawFromMainChain, tx4, gasFees); err != nil {
+		t.Fatalf("TX4: unexpected error: %v", err)
+	}
+	if have, want := statedb.GetBalance(from), new(big.Int).Mul(big.NewInt(198), neat); have.Cmp(want) != 0 {
+		t.Errorf("TX4 sender balance mismatch: have %v, want %v", have, want)
+	}
+	if want := big.NewInt(150e16); gasFees.Cmp(want) != 0 {
+		t.Errorf("TX4 validator fee mismatch: have %v, want %v", gasFees, want)
+	}
+}
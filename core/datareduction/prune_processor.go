@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"github.com/neatlab/neatio/common"
@@ -17,15 +17,23 @@ import (
 	"github.com/neatlab/neatio/trie"
 )
 
+const (
+	// DefaultRetention is the number of most recent blocks whose data is kept
+	// when the chain configures none.
+	DefaultRetention uint64 = 1000
+	// MinRetention is the least number of most recent blocks whose data is
+	// kept, for the reorgs and the consensus to find their states.
+	MinRetention uint64 = 128
+)
+
 var (
 	// max scan trie height
 	max_count_trie uint64 = 1000
-	// max retain trie height
-	max_remain_trie uint64 = 1000
 	// emptyRoot is the known root hash of an empty trie.
 	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
 
-	pruning int32 // indicate pruning is running or not
+	pruningLock sync.Mutex
+	pruning     = make(map[neatdb.Database]bool) // Chains being pruned, by prune database
 )
 
 type NodeCount map[common.Hash]uint64
@@ -38,6 +46,7 @@ type PruneProcessor struct {
 	chainDb neatdb.Database // database instance to delete the state/block data
 
 	pruneBodyData bool
+	retention     uint64 // Most recent blocks whose data is kept
 
 	nodeCount NodeCount
 }
@@ -51,21 +60,51 @@ type PruneStatus struct {
 
 type processLeafTrie func(addr common.Address, account state.Account)
 
-func StartPruning() bool {
-	return atomic.CompareAndSwapInt32(&pruning, 0, 1)
+// StartPruning marks the chain of the prune database as being pruned. It
+// returns false if it is already, the chains of a node are pruned separately.
+func StartPruning(prunedb neatdb.Database) bool {
+	pruningLock.Lock()
+	defer pruningLock.Unlock()
+
+	if pruning[prunedb] {
+		return false
+	}
+	pruning[prunedb] = true
+	return true
+}
+
+// StopPruning marks the chain of the prune database as not being pruned.
+func StopPruning(prunedb neatdb.Database) bool {
+	pruningLock.Lock()
+	defer pruningLock.Unlock()
+
+	if !pruning[prunedb] {
+		return false
+	}
+	delete(pruning, prunedb)
+	return true
 }
 
-func StopPruning() bool {
-	return atomic.CompareAndSwapInt32(&pruning, 1, 0)
+func isPruning(prunedb neatdb.Database) bool {
+	pruningLock.Lock()
+	defer pruningLock.Unlock()
+
+	return pruning[prunedb]
 }
 
-func NewPruneProcessor(chaindb, prunedb neatdb.Database, bc *core.BlockChain, pruneBodyData bool) *PruneProcessor {
+// NewPruneProcessor creates the processor pruning the data of the chain older
+// than the retention, the number of most recent blocks whose data is kept.
+func NewPruneProcessor(chaindb, prunedb neatdb.Database, bc *core.BlockChain, pruneBodyData bool, retention uint64) *PruneProcessor {
+	if retention < MinRetention {
+		retention = MinRetention
+	}
 	return &PruneProcessor{
 		db:            prunedb,
 		prunedb:       NewDatabase(prunedb),
 		bc:            bc,
 		chainDb:       chaindb,
 		pruneBodyData: pruneBodyData,
+		retention:     retention,
 		nodeCount:     make(NodeCount),
 	}
 }
@@ -76,7 +115,7 @@ func (p *PruneProcessor) Process(blockNumber, scanNumber, pruneNumber uint64) (u
 	var scanStart, scanEnd uint64
 	for {
 		// Step 1. determine the scan height
-		needScan, scanStart, scanEnd = calculateScan(scanNumber, blockNumber)
+		needScan, scanStart, scanEnd = calculateScan(scanNumber, blockNumber, p.retention)
 
 		log.Infof("Data Reduction - scan ? %v , %d - %d", needScan, scanStart, scanEnd)
 
@@ -119,7 +158,7 @@ func (p *PruneProcessor) Process(blockNumber, scanNumber, pruneNumber uint64) (u
 
 					if p.pruneBodyData {
 						for j := pruneBodyStart; j <= i; j++ {
-							rawdb.DeleteBody(p.chainDb, rawdb.ReadCanonicalHash(p.chainDb, i), i)
+							rawdb.DeleteBody(p.chainDb, rawdb.ReadCanonicalHash(p.chainDb, j), j)
 						}
 						log.Infof("deleted block from %v to %v", pruneBodyStart, i-1)
 						pruneBodyStart = i
@@ -141,14 +180,14 @@ func (p *PruneProcessor) Process(blockNumber, scanNumber, pruneNumber uint64) (u
 	return scanEnd + 1, scanEnd
 }
 
-func calculateScan(scan, latestBlockHeight uint64) (scanOrNot bool, from, to uint64) {
+func calculateScan(scan, latestBlockHeight, retention uint64) (scanOrNot bool, from, to uint64) {
 
 	from = scan
 	to = 0
 
 	unscanHeight := latestBlockHeight - scan
-	if unscanHeight > max_remain_trie {
-		to = latestBlockHeight - max_remain_trie
+	if unscanHeight > retention {
+		to = latestBlockHeight - retention
 	}
 
 	if to != 0 {
@@ -354,7 +393,7 @@ func GetLatestStatus(prunedb neatdb.Database) *PruneStatus {
 	}
 
 	return &PruneStatus{
-		Running:           atomic.LoadInt32(&pruning) == 1,
+		Running:           isPruning(prunedb),
 		LatestScanNumber:  scanNo,
 		LatestPruneNumber: pruneNo,
 	}
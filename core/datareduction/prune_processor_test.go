@@ -0,0 +1,68 @@
+package datareduction
+
+import (
+	"testing"
+
+	"github.com/neatlab/neatio/core/rawdb"
+)
+
+func TestCalculateScanRetention(t *testing.T) {
+	tests := []struct {
+		scan, latest, retention uint64
+		want                    bool
+		to                      uint64
+	}{
+		{0, 1000, 1000, false, 0},
+		{0, 1001, 1000, true, 1},
+		{0, 1000, 128, true, 872},
+		{500, 1000, 128, true, 872},
+		{900, 1000, 128, false, 0},
+		{0, 100000, DefaultRetention, true, 99000},
+	}
+	for i, tt := range tests {
+		scan, from, to := calculateScan(tt.scan, tt.latest, tt.retention)
+		if scan != tt.want || (scan && (from != tt.scan || to != tt.to)) {
+			t.Errorf("test %d: have %v %d-%d, want %v %d-%d", i, scan, from, to, tt.want, tt.scan, tt.to)
+		}
+	}
+}
+
+func TestNewPruneProcessorRetention(t *testing.T) {
+	if p := NewPruneProcessor(nil, rawdb.NewMemoryDatabase(), nil, false, 10); p.retention != MinRetention {
+		t.Errorf("retention below the minimum: have %d, want %d", p.retention, MinRetention)
+	}
+	if p := NewPruneProcessor(nil, rawdb.NewMemoryDatabase(), nil, false, 5000); p.retention != 5000 {
+		t.Errorf("retention: have %d, want %d", p.retention, 5000)
+	}
+}
+
+func TestPruningPerChain(t *testing.T) {
+	mainDb, sideDb := rawdb.NewMemoryDatabase(), rawdb.NewMemoryDatabase()
+
+	if !StartPruning(sideDb) {
+		t.Fatal("failed to start the pruning of the side chain")
+	}
+	if StartPruning(sideDb) {
+		t.Error("pruning of the side chain started twice")
+	}
+	if !StartPruning(mainDb) {
+		t.Fatal("pruning of the side chain blocks the other chain")
+	}
+	if !GetLatestStatus(sideDb).Running || !GetLatestStatus(mainDb).Running {
+		t.Error("pruning not reported running")
+	}
+
+	if !StopPruning(sideDb) {
+		t.Fatal("failed to stop the pruning of the side chain")
+	}
+	if GetLatestStatus(sideDb).Running {
+		t.Error("pruning of the side chain still reported running")
+	}
+	if !GetLatestStatus(mainDb).Running {
+		t.Error("stopping the side chain stopped the other chain")
+	}
+	if StopPruning(sideDb) {
+		t.Error("pruning of the side chain stopped twice")
+	}
+	StopPruning(mainDb)
+}
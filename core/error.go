@@ -36,6 +36,9 @@ var (
 	// ErrInvalidTx4 is returned if the tx4 has been checked during execution
 	ErrInvalidTx4 = errors.New("invalid Tx4")
 
+	// ErrTX3Consumed is returned if the tx4 redeems a tx3 which was already withdrawn
+	ErrTX3Consumed = errors.New("tx3 already withdrawn")
+
 	// Delegation Error
 	// ErrCancelSelfDelegate is returned if the cancel delegate apply to the self address
 	ErrCancelSelfDelegate = errors.New("can not cancel self delegation")
@@ -67,8 +70,41 @@ var (
 	// ErrNotCandidate is returned if the request address is not a candidate
 	ErrNotCandidate = errors.New("address not candidate")
 
+	// ErrNoDelegation is returned if the request address did not delegate to the candidate
+	ErrNoDelegation = errors.New("address has no delegation to the candidate")
+
+	// ErrBelowMinSelfBond is returned if the candidate bonded itself less than its minimum self bond
+	ErrBelowMinSelfBond = errors.New("self bond below the minimum self bond")
+
+	// ErrMinSelfBondDecrease is returned if the request lowers the minimum self bond of the candidate
+	ErrMinSelfBondDecrease = errors.New("minimum self bond can not be lowered")
+
 	ErrBannedUnRegister = errors.New("banned candidate can not unregister")
 
+	// ErrSameConsensusKey is returned if the rotated consensus key equals the current one
+	ErrSameConsensusKey = errors.New("new consensus key is the same as the current one")
+
+	// ErrGovNoStake is returned if the request address has no deposit nor delegation to vote with
+	ErrGovNoStake = errors.New("governance requires a deposit or delegation")
+
+	// ErrGovNoDeposit is returned if a governance proposal is submitted or deposited on without a deposit
+	ErrGovNoDeposit = errors.New("governance proposal requires a deposit")
+
+	// ErrGovProposalNotFound is returned if the voted governance proposal does not exist
+	ErrGovProposalNotFound = errors.New("governance proposal not found")
+
+	// ErrGovProposalClosed is returned if the voting on the governance proposal is over
+	ErrGovProposalClosed = errors.New("governance proposal voting closed")
+
+	// ErrGovProposalEpoch is returned if the governance proposal does not take effect at a future epoch, or too far ahead
+	ErrGovProposalEpoch = errors.New("governance proposal must take effect at a future epoch, not too far ahead")
+
+	// ErrGovProposalLimit is returned if too many governance proposals are in their deposit or voting period
+	ErrGovProposalLimit = errors.New("too many open governance proposals")
+
+	// ErrNotValidator is returned if the request address is neither a validator nor a candidate
+	ErrNotValidator = errors.New("address not validator nor candidate")
+
 	//ErrExceedDelegationAddressLimit is returned if delegated address number exceed the limit
 	ErrExceedDelegationAddressLimit = errors.New("exceed the delegation address limit")
 
@@ -78,6 +114,12 @@ var (
 	// ErrCommission is returned if the request Commission value not between 0 and 100
 	ErrCommission = errors.New("commission percentage (between 0 and 100) out of range")
 
+	// ErrCommissionTooHigh is returned if the request Commission value is above the maximum set by governance
+	ErrCommissionTooHigh = errors.New("commission percentage above the maximum commission")
+
+	// ErrCommissionChange is returned if the request Commission value changes the commission more than allowed per epoch
+	ErrCommissionChange = errors.New("commission percentage changed more than the maximum change per epoch")
+
 	// Vote Error
 	// ErrVoteAmountTooLow is returned if the vote amount less than proxied delegation amount
 	ErrVoteAmountTooLow = errors.New("vote amount too low")
@@ -93,4 +135,34 @@ var (
 
 	// ErrNotAllowedInSideChain is returned if the transaction with side flag = false be sent to side chain
 	ErrNotAllowedInSideChain = errors.New("transaction not allowed in side chain")
+
+	// ErrFunctionNotActive is returned if the transaction calls a chain function added by a fork not reached yet
+	ErrFunctionNotActive = errors.New("chain function not active yet")
+
+	// ErrSideChainFrozen is returned if the transaction is sent to a side chain retired by governance, past its freeze height
+	ErrSideChainFrozen = errors.New("side chain frozen, only withdrawals to the main chain are allowed")
+
+	// ErrSideChainRetired is returned if the side chain reached the end of the grace period of its retirement
+	ErrSideChainRetired = errors.New("side chain retired")
+
+	// ErrSideChainCapacity is returned if the validator already secures the most side chains governance allows
+	ErrSideChainCapacity = errors.New("validator secures the maximum number of side chains")
+
+	// ErrCrossChainAmount is returned if the amount of a cross chain transfer is below the minimum set by governance
+	ErrCrossChainAmount = errors.New("cross chain transfer amount below the minimum")
+
+	// ErrNoPendingWithdrawal is returned if a withdrawal is challenged which is not held in its challenge window
+	ErrNoPendingWithdrawal = errors.New("no pending withdrawal for the transaction")
+
+	// ErrUnknownAsset is returned if a transaction names an asset which is not in the asset registry
+	ErrUnknownAsset = errors.New("unknown asset")
+
+	// ErrAssetExists is returned if an asset is registered twice
+	ErrAssetExists = errors.New("asset already registered")
+
+	// ErrAssetContractTaken is returned if a contract already represents another asset on its chain
+	ErrAssetContractTaken = errors.New("contract already represents another asset")
+
+	// ErrNotAssetOwner is returned if an asset is changed by another account than the one which registered it
+	ErrNotAssetOwner = errors.New("account not owner of the asset")
 )
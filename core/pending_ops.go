@@ -59,6 +59,8 @@ func ApplyOp(op types.PendingOp, bc *BlockChain, cch CrossChainHelper) error {
 			cch.ChangeValidators(op.ChainId) //must after eng.SetEpoch(nextEp), it uses epoch just set
 		}
 		return err
+	case *tmTypes.BlockRewardsOp:
+		return bc.engine.(consensus.NeatPoS).GetEpoch().RecordBlockRewards(op)
 	default:
 		return fmt.Errorf("unknown op: %v", op)
 	}
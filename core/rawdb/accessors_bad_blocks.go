@@ -0,0 +1,89 @@
+package rawdb
+
+import (
+	"sort"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/neatdb"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// badBlockToKeep is the maximum number of bad blocks kept in the database.
+const badBlockToKeep = 10
+
+// BadBlock is a block which failed validation, kept along with what its
+// execution produced for the diagnosis of the failure.
+type BadBlock struct {
+	Header       *types.Header
+	Body         *types.Body
+	Receipts     []*types.ReceiptForStorage // Receipts of the execution, empty if it did not complete
+	ExpectedRoot common.Hash                // State root of the header
+	ActualRoot   common.Hash                // State root of the execution, zero if it did not complete
+	Reason       string                     // Validation error
+	Time         uint64                     // Unix time the block was rejected at
+}
+
+// Block returns the bad block.
+func (b *BadBlock) Block() *types.Block {
+	return types.NewBlockWithHeader(b.Header).WithBody(b.Body.Transactions, b.Body.Uncles)
+}
+
+// ReadBadBlock retrieves the bad block with the hash.
+func ReadBadBlock(db neatdb.Reader, hash common.Hash) *BadBlock {
+	for _, bad := range ReadAllBadBlocks(db) {
+		if bad.Header.Hash() == hash {
+			return bad
+		}
+	}
+	return nil
+}
+
+// ReadAllBadBlocks retrieves all the bad blocks in the database, the highest
+// first.
+func ReadAllBadBlocks(db neatdb.Reader) []*BadBlock {
+	blob, _ := db.Get(badBlockKey)
+	if len(blob) == 0 {
+		return nil
+	}
+	var bads []*BadBlock
+	if err := rlp.DecodeBytes(blob, &bads); err != nil {
+		log.Error("Invalid bad block list RLP", "err", err)
+		return nil
+	}
+	return bads
+}
+
+// WriteBadBlock stores a bad block in the database. Only the highest
+// badBlockToKeep bad blocks are kept.
+func WriteBadBlock(db neatdb.Database, bad *BadBlock) {
+	bads := ReadAllBadBlocks(db)
+	hash := bad.Header.Hash()
+	for _, b := range bads {
+		if b.Header.Hash() == hash {
+			return
+		}
+	}
+	bads = append(bads, bad)
+	sort.SliceStable(bads, func(i, j int) bool {
+		return bads[i].Header.Number.Cmp(bads[j].Header.Number) > 0
+	})
+	if len(bads) > badBlockToKeep {
+		bads = bads[:badBlockToKeep]
+	}
+	data, err := rlp.EncodeToBytes(bads)
+	if err != nil {
+		log.Crit("Failed to encode bad blocks", "err", err)
+	}
+	if err := db.Put(badBlockKey, data); err != nil {
+		log.Crit("Failed to store bad blocks", "err", err)
+	}
+}
+
+// DeleteBadBlocks deletes all the bad blocks from the database.
+func DeleteBadBlocks(db neatdb.Writer) {
+	if err := db.Delete(badBlockKey); err != nil {
+		log.Crit("Failed to delete bad blocks", "err", err)
+	}
+}
@@ -169,7 +169,9 @@ func WriteHeader(db neatdb.Writer, header *types.Header) {
 		log.Crit("Failed to store hash to number mapping", "err", err)
 	}
 	// Write the encoded header
-	data, err := rlp.EncodeToBytes(header)
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+	data, err := buf.encode(header)
 	if err != nil {
 		log.Crit("Failed to RLP encode header", "err", err)
 	}
@@ -232,7 +234,9 @@ func ReadBody(db neatdb.Reader, hash common.Hash, number uint64) *types.Body {
 
 // WriteBody storea a block body into the database.
 func WriteBody(db neatdb.Writer, hash common.Hash, number uint64, body *types.Body) {
-	data, err := rlp.EncodeToBytes(body)
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+	data, err := buf.encode(body)
 	if err != nil {
 		log.Crit("Failed to RLP encode body", "err", err)
 	}
@@ -268,7 +272,9 @@ func ReadTd(db neatdb.Reader, hash common.Hash, number uint64) *big.Int {
 
 // WriteTd stores the total difficulty of a block into the database.
 func WriteTd(db neatdb.Writer, hash common.Hash, number uint64, td *big.Int) {
-	data, err := rlp.EncodeToBytes(td)
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+	data, err := buf.encode(td)
 	if err != nil {
 		log.Crit("Failed to RLP encode block total difficulty", "err", err)
 	}
@@ -339,7 +345,9 @@ func WriteReceipts(db neatdb.Writer, hash common.Hash, number uint64, receipts t
 	for i, receipt := range receipts {
 		storageReceipts[i] = (*types.ReceiptForStorage)(receipt)
 	}
-	bytes, err := rlp.EncodeToBytes(storageReceipts)
+	buf := getEncodeBuffer()
+	defer putEncodeBuffer(buf)
+	bytes, err := buf.encode(storageReceipts)
 	if err != nil {
 		log.Crit("Failed to encode block receipts", "err", err)
 	}
@@ -318,3 +318,58 @@ func TestBlockReceiptStorage(t *testing.T) {
 		t.Fatalf("deleted receipts returned: %v", rs)
 	}
 }
+
+// Tests bad block storage and retrieval operations.
+func TestBadBlockStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	if bads := ReadAllBadBlocks(db); len(bads) != 0 {
+		t.Fatalf("Non existent bad blocks returned: %v", bads)
+	}
+	newBad := func(number int64) *BadBlock {
+		receipt := &types.Receipt{Status: types.ReceiptStatusFailed, CumulativeGasUsed: 1, GasUsed: 1, TxHash: common.Hash{byte(number)}, Logs: []*types.Log{}}
+		return &BadBlock{
+			Header:       &types.Header{Number: big.NewInt(number), Extra: []byte("bad block"), Root: common.Hash{1}},
+			Body:         &types.Body{},
+			Receipts:     []*types.ReceiptForStorage{(*types.ReceiptForStorage)(receipt)},
+			ExpectedRoot: common.Hash{1},
+			ActualRoot:   common.Hash{2},
+			Reason:       "invalid merkle root",
+			Time:         uint64(number),
+		}
+	}
+	bad := newBad(1)
+	WriteBadBlock(db, bad)
+	WriteBadBlock(db, bad)
+	entry := ReadBadBlock(db, bad.Header.Hash())
+	if entry == nil {
+		t.Fatalf("Stored bad block not found")
+	}
+	if entry.Block().Hash() != bad.Header.Hash() || entry.ExpectedRoot != bad.ExpectedRoot || entry.ActualRoot != bad.ActualRoot || entry.Reason != bad.Reason {
+		t.Fatalf("Retrieved bad block mismatch: have %+v, want %+v", entry, bad)
+	}
+	if len(entry.Receipts) != 1 || entry.Receipts[0].TxHash != bad.Receipts[0].TxHash {
+		t.Fatalf("Retrieved bad block receipts mismatch: have %v, want %v", entry.Receipts, bad.Receipts)
+	}
+	if bads := ReadAllBadBlocks(db); len(bads) != 1 {
+		t.Fatalf("Duplicate bad block stored: have %d, want 1", len(bads))
+	}
+
+	// Only the highest bad blocks are kept
+	for number := int64(2); number <= 2*badBlockToKeep; number++ {
+		WriteBadBlock(db, newBad(number))
+	}
+	bads := ReadAllBadBlocks(db)
+	if len(bads) != badBlockToKeep {
+		t.Fatalf("Bad block count mismatch: have %d, want %d", len(bads), badBlockToKeep)
+	}
+	for i, entry := range bads {
+		if want := uint64(2*badBlockToKeep - i); entry.Header.Number.Uint64() != want {
+			t.Fatalf("Bad block %d number mismatch: have %d, want %d", i, entry.Header.Number, want)
+		}
+	}
+	DeleteBadBlocks(db)
+	if bads := ReadAllBadBlocks(db); len(bads) != 0 {
+		t.Fatalf("Deleted bad blocks returned: %v", bads)
+	}
+}
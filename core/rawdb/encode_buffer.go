@@ -0,0 +1,45 @@
+package rawdb
+
+import (
+	"sync"
+
+	"github.com/neatlab/neatio/rlp"
+)
+
+// maxPooledEncodeBufferSize caps the buffers kept for reuse, so that a single
+// oversized block does not pin its encoding in memory for good.
+const maxPooledEncodeBufferSize = 4 * 1024 * 1024
+
+// encodeBuffer is a reusable scratch space for RLP encoding database values.
+type encodeBuffer struct {
+	data []byte
+}
+
+// encode RLP encodes val into the buffer. The returned slice is only valid
+// until the buffer is encoded into again or returned to the pool; all the
+// database writers copy the value on Put, so it may be handed to them as is.
+func (b *encodeBuffer) encode(val interface{}) ([]byte, error) {
+	data, err := rlp.AppendToBytes(b.data[:0], val)
+	if err != nil {
+		return nil, err
+	}
+	b.data = data
+	return data, nil
+}
+
+var encodeBufferPool = sync.Pool{
+	New: func() interface{} {
+		return &encodeBuffer{data: make([]byte, 0, 4096)}
+	},
+}
+
+func getEncodeBuffer() *encodeBuffer {
+	return encodeBufferPool.Get().(*encodeBuffer)
+}
+
+func putEncodeBuffer(b *encodeBuffer) {
+	if cap(b.data) > maxPooledEncodeBufferSize {
+		return
+	}
+	encodeBufferPool.Put(b)
+}
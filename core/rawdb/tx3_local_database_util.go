@@ -109,7 +109,7 @@ func GetAllTX3ProofData(db neatdb.Database) []*types.TX3ProofData {
 			break
 		}
 
-		var proofData *types.TX3ProofData
+		proofData := new(types.TX3ProofData)
 		err := rlp.DecodeBytes(value, proofData)
 		if err != nil {
 			continue
@@ -0,0 +1,36 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+)
+
+func TestRecordRewardClaimRevert(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	addr := common.BytesToAddress([]byte{0x01})
+
+	statedb.RecordRewardClaim(addr, big.NewInt(50), 10)
+	snapshot := statedb.Snapshot()
+	statedb.RecordRewardClaim(addr, big.NewInt(30), 20)
+	if claim := statedb.GetRewardClaim(addr); claim.Claimed.Int64() != 80 || claim.Count != 2 || claim.LastBlock != 20 {
+		t.Errorf("claim mismatch: have %v/%d/%d, want 80/2/20", claim.Claimed, claim.Count, claim.LastBlock)
+	}
+	statedb.RevertToSnapshot(snapshot)
+	if claim := statedb.GetRewardClaim(addr); claim.Claimed.Int64() != 50 || claim.Count != 1 || claim.LastBlock != 10 {
+		t.Errorf("claim mismatch after revert: have %v/%d/%d, want 50/1/10", claim.Claimed, claim.Count, claim.LastBlock)
+	}
+
+	// A claim reverted before any other leaves the state root untouched
+	root := statedb.IntermediateRoot(false)
+	other := common.BytesToAddress([]byte{0x02})
+	snapshot = statedb.Snapshot()
+	statedb.RecordRewardClaim(other, big.NewInt(1), 30)
+	statedb.RevertToSnapshot(snapshot)
+	if have := statedb.IntermediateRoot(false); have != root {
+		t.Errorf("root mismatch after reverted claim: have %x, want %x", have, root)
+	}
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+)
+
+func TestSenderCacherRecoverAndWait(t *testing.T) {
+	cacher := newTxSenderCacher(3)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+
+	keys := make([]common.Address, 25)
+	txs := make([]*types.Transaction, len(keys))
+	for i := range txs {
+		key, _ := crypto.GenerateKey()
+		keys[i] = crypto.PubkeyToAddress(key.PublicKey)
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txs[i] = tx
+	}
+	cacher.RecoverAndWait(signer, txs)
+	for i, tx := range txs {
+		if from, err := types.Sender(signer, tx); err != nil || from != keys[i] {
+			t.Fatalf("tx %d: sender mismatch: have %x, %v, want %x", i, from, err, keys[i])
+		}
+	}
+	// Recovering nothing must not block
+	cacher.RecoverAndWait(signer, nil)
+}
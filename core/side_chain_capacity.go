@@ -0,0 +1,21 @@
+package core
+
+// CheckSideChainCapacity returns ErrSideChainCapacity if a validator securing
+// the given side chains can't secure chainId as well, limit being the most
+// side chains it may secure, 0 if not limited.
+func CheckSideChainCapacity(chains []string, limit uint64, chainId string) error {
+	if limit == 0 {
+		return nil
+	}
+	count := uint64(0)
+	for _, id := range chains {
+		if id == chainId {
+			return nil
+		}
+		count++
+	}
+	if count >= limit {
+		return ErrSideChainCapacity
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestCheckSideChainCapacity(t *testing.T) {
+	tests := []struct {
+		chains  []string
+		limit   uint64
+		chainId string
+		want    error
+	}{
+		{[]string{"a", "b", "c"}, 0, "d", nil},
+		{[]string{"a", "b"}, 3, "c", nil},
+		{[]string{"a", "b", "c"}, 3, "d", ErrSideChainCapacity},
+		{[]string{"a", "b", "c"}, 3, "b", nil},
+		{[]string{"a", "b", "c", "d"}, 2, "e", ErrSideChainCapacity},
+		{nil, 1, "a", nil},
+	}
+	for i, tt := range tests {
+		if err := CheckSideChainCapacity(tt.chains, tt.limit, tt.chainId); err != tt.want {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+)
+
+// allowedInFrozenSideChain reports whether the transaction can still be sent
+// to a side chain past its freeze height: the withdrawals to the main chain,
+// and the deposits made on the main chain before the freeze.
+func allowedInFrozenSideChain(tx *types.Transaction) bool {
+	if !neatabi.IsNeatChainContractAddr(tx.To()) || len(tx.Data()) < 4 {
+		return false
+	}
+	function, err := neatabi.FunctionTypeFromId(tx.Data()[:4])
+	if err != nil {
+		return false
+	}
+	return function == neatabi.WithdrawFromSideChain || function == neatabi.DepositInSideChain
+}
+
+// checkSideChainRetirement rejects the transactions a side chain retired by
+// governance doesn't accept anymore. On the main chain, the deposits to the
+// side chain stop at its freeze height, looked up in the state of the block at
+// number. On the side chain, only the withdrawals are accepted from the freeze
+// height and nothing from the end of the grace period, both compared to the
+// main chain height mainNumber.
+func checkSideChainRetirement(config *params.ChainConfig, cch CrossChainHelper, statedb *state.StateDB, number, mainNumber *big.Int, tx *types.Transaction) error {
+	if config.IsMainChain() {
+		if !neatabi.IsNeatChainContractAddr(tx.To()) || len(tx.Data()) < 4 {
+			return nil
+		}
+		function, err := neatabi.FunctionTypeFromId(tx.Data()[:4])
+		if err != nil || function != neatabi.DepositInMainChain {
+			return nil
+		}
+		var args neatabi.DepositInMainChainArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.DepositInMainChain.String(), tx.Data()[4:]); err != nil {
+			return err
+		}
+		if freeze, _, retired := statedb.SideChainRetirement(args.ChainId); retired && number.Uint64() >= freeze {
+			return ErrSideChainFrozen
+		}
+		return nil
+	}
+
+	if cch == nil || mainNumber == nil {
+		return nil
+	}
+	freeze, sunset, retired, err := cch.GetSideChainRetirement(config.NeatChainId, mainNumber.Uint64())
+	switch {
+	case err != nil:
+		return err
+	case !retired || mainNumber.Uint64() < freeze:
+		return nil
+	case mainNumber.Uint64() >= sunset:
+		return ErrSideChainRetired
+	case !allowedInFrozenSideChain(tx):
+		return ErrSideChainFrozen
+	}
+	return nil
+}
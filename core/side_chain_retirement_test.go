@@ -0,0 +1,80 @@
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+)
+
+// retirementHelper serves the retirement of the side chain out of the main
+// chain state it wraps.
+type retirementHelper struct {
+	CrossChainHelper
+	statedb *state.StateDB
+}
+
+func (h *retirementHelper) GetSideChainRetirement(chainId string, number uint64) (uint64, uint64, bool, error) {
+	if h.statedb == nil {
+		return 0, 0, false, errors.New("main chain state not available")
+	}
+	freeze, sunset, retired := h.statedb.SideChainRetirement(chainId)
+	return freeze, sunset, retired, nil
+}
+
+func chainTx(t *testing.T, function neatabi.FunctionType, args ...interface{}) *types.Transaction {
+	data, err := neatabi.ChainABI.Pack(function.String(), args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.NewTransaction(0, neatabi.ChainContractMagicAddr, big.NewInt(1), function.RequiredGas(), big.NewInt(1), data)
+}
+
+func TestCheckSideChainRetirement(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetGovParam(state.RetireParam("side"), big.NewInt(100), 0, 0)
+	statedb.SetGovParam(state.GovParamSideChainGracePeriod, big.NewInt(1000), 0, 0)
+
+	deposit := chainTx(t, neatabi.DepositInMainChain, "side")
+	otherDeposit := chainTx(t, neatabi.DepositInMainChain, "other")
+	withdraw := chainTx(t, neatabi.WithdrawFromSideChain, "side")
+	transfer := types.NewTransaction(0, common.BytesToAddress([]byte{0x01}), big.NewInt(1), 21000, big.NewInt(1), nil)
+
+	mainConfig := params.MainnetChainConfig
+	sideConfig := &params.ChainConfig{NeatChainId: "side"}
+	cch := &retirementHelper{statedb: statedb}
+
+	tests := []struct {
+		config *params.ChainConfig
+		number int64
+		tx     *types.Transaction
+		want   error
+	}{
+		{mainConfig, 99, deposit, nil},
+		{mainConfig, 100, deposit, ErrSideChainFrozen},
+		{mainConfig, 100, otherDeposit, nil},
+		{mainConfig, 100, transfer, nil},
+		{sideConfig, 99, transfer, nil},
+		{sideConfig, 100, transfer, ErrSideChainFrozen},
+		{sideConfig, 100, withdraw, nil},
+		{sideConfig, 1099, withdraw, nil},
+		{sideConfig, 1100, withdraw, ErrSideChainRetired},
+	}
+	for i, tt := range tests {
+		number := big.NewInt(tt.number)
+		if err := checkSideChainRetirement(tt.config, cch, statedb, number, number, tt.tx); err != tt.want {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// The retirement is not checked against another state than the one of the
+	// main chain block
+	if err := checkSideChainRetirement(sideConfig, &retirementHelper{}, statedb, big.NewInt(1), big.NewInt(1), transfer); err == nil {
+		t.Error("transaction accepted without the main chain state")
+	}
+}
@@ -162,6 +162,72 @@ type (
 		prev      bool
 		prevDirty bool
 	}
+	bridgeEntryChange struct {
+		key  common.Hash
+		prev []byte
+	}
+	pendingWithdrawalsChange struct {
+		prev      PendingWithdrawals
+		prevDirty bool
+	}
+	rewardClaimChange struct {
+		account   *common.Address
+		prev      *RewardClaim
+		prevDirty bool
+	}
+	govProposalChange struct {
+		id                uint64
+		prev              *GovProposal // nil if the proposal was added
+		prevOpen          []uint64
+		prevPassed        []uint64
+		prevDirty         bool
+		prevProposalDirty bool
+	}
+	govEpochChange struct {
+		prev      uint64
+		prevDirty bool
+	}
+	govParamChange struct {
+		name      string
+		prevDirty bool
+	}
+	treasuryChange struct {
+		prevReceived *big.Int
+		prevSpent    *big.Int
+		prevSpends   int
+		prevDirty    bool
+	}
+	upgradeSignalsChange struct {
+		prev      UpgradeSignals
+		prevDirty bool
+	}
+	validatorMetadataChange struct {
+		account   *common.Address
+		prev      *ValidatorMetadata
+		prevDirty bool
+	}
+	minSelfBondChange struct {
+		account   *common.Address
+		prev      *big.Int
+		prevDirty bool
+	}
+	commissionChangesChange struct {
+		prev      CommissionChanges
+		prevDirty bool
+	}
+	autoCompoundsChange struct {
+		prev      AutoCompounds
+		prevDirty bool
+	}
+	keyRotationChange struct {
+		prev      KeyRotationSet
+		prevDirty bool
+	}
+	assetsChange struct {
+		id        string
+		prev      *Asset // nil if the asset was registered
+		prevDirty bool
+	}
 )
 
 func (ch createObjectChange) undo(s *StateDB) {
@@ -318,3 +384,113 @@ func (ch addLogChange) undo(s *StateDB) {
 func (ch addPreimageChange) undo(s *StateDB) {
 	delete(s.preimages, ch.hash)
 }
+
+func (ch bridgeEntryChange) undo(s *StateDB) {
+	s.bridgeEntries[ch.key] = ch.prev
+}
+
+func (ch pendingWithdrawalsChange) undo(s *StateDB) {
+	s.pendingWithdrawals = ch.prev
+	s.pendingWithdrawalsDirty = ch.prevDirty
+}
+
+func (ch rewardClaimChange) undo(s *StateDB) {
+	s.rewardClaims[*ch.account] = ch.prev
+	if !ch.prevDirty {
+		delete(s.rewardClaimsDirty, *ch.account)
+	}
+}
+
+func (ch govProposalChange) undo(s *StateDB) {
+	gov := s.getGovernance()
+	if ch.prev == nil {
+		delete(s.govProposals, ch.id)
+		gov.Count = ch.id - 1
+	} else {
+		s.govProposals[ch.id] = ch.prev
+	}
+	if !ch.prevProposalDirty {
+		delete(s.govProposalsDirty, ch.id)
+	}
+	gov.Open = ch.prevOpen
+	gov.Passed = ch.prevPassed
+	s.govDirty = ch.prevDirty
+}
+
+func (ch govEpochChange) undo(s *StateDB) {
+	s.getGovernance().Epoch = ch.prev
+	s.govDirty = ch.prevDirty
+}
+
+func (ch govParamChange) undo(s *StateDB) {
+	gov := s.getGovernance()
+	for i, param := range gov.Params {
+		if param.Name != ch.name {
+			continue
+		}
+		if len(param.Changes) == 1 {
+			gov.Params = append(gov.Params[:i:i], gov.Params[i+1:]...)
+		} else {
+			param.Changes = param.Changes[:len(param.Changes)-1]
+		}
+		break
+	}
+	s.govDirty = ch.prevDirty
+}
+
+func (ch treasuryChange) undo(s *StateDB) {
+	t := s.getTreasury()
+	t.Received = ch.prevReceived
+	t.Spent = ch.prevSpent
+	t.Spends = t.Spends[:ch.prevSpends]
+	s.govDirty = ch.prevDirty
+}
+
+func (ch upgradeSignalsChange) undo(s *StateDB) {
+	s.upgradeSignals = ch.prev
+	s.upgradeSignalsDirty = ch.prevDirty
+}
+
+func (ch validatorMetadataChange) undo(s *StateDB) {
+	s.validatorMetadata[*ch.account] = ch.prev
+	if !ch.prevDirty {
+		delete(s.validatorMetadataDirty, *ch.account)
+	}
+}
+
+func (ch minSelfBondChange) undo(s *StateDB) {
+	s.minSelfBonds[*ch.account] = ch.prev
+	if !ch.prevDirty {
+		delete(s.minSelfBondsDirty, *ch.account)
+	}
+}
+
+func (ch commissionChangesChange) undo(s *StateDB) {
+	s.commissionChanges = ch.prev
+	s.commissionChangesDirty = ch.prevDirty
+}
+
+func (ch autoCompoundsChange) undo(s *StateDB) {
+	s.autoCompounds = ch.prev
+	s.autoCompoundsDirty = ch.prevDirty
+}
+
+func (ch keyRotationChange) undo(s *StateDB) {
+	s.keyRotationSet = ch.prev
+	s.keyRotationSetDirty = ch.prevDirty
+}
+
+func (ch assetsChange) undo(s *StateDB) {
+	for i, asset := range s.assets {
+		if asset.Id != ch.id {
+			continue
+		}
+		if ch.prev == nil {
+			s.assets = append(s.assets[:i:i], s.assets[i+1:]...)
+		} else {
+			s.assets[i] = ch.prev
+		}
+		break
+	}
+	s.assetsDirty = ch.prevDirty
+}
@@ -0,0 +1,136 @@
+package state
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/neatdb/memorydb"
+)
+
+func TestRevertValidatorSettings(t *testing.T) {
+	statedb, _ := New(common.Hash{}, NewDatabase(memorydb.New()))
+	validator := common.BytesToAddress([]byte{0x01})
+	delegator := common.BytesToAddress([]byte{0x02})
+
+	statedb.SetValidatorMetadata(validator, &ValidatorMetadata{Moniker: "before"})
+	statedb.SetMinSelfBond(validator, big.NewInt(100))
+	statedb.ScheduleCommission(validator, 10, 1)
+	statedb.SetAutoCompound(delegator, validator, true)
+	statedb.Finalise(false)
+
+	snapshot := statedb.Snapshot()
+	statedb.SetValidatorMetadata(validator, &ValidatorMetadata{Moniker: "after"})
+	statedb.SetMinSelfBond(validator, big.NewInt(200))
+	statedb.ScheduleCommission(validator, 20, 2)
+	statedb.ScheduleCommission(delegator, 30, 2)
+	statedb.SetAutoCompound(delegator, validator, false)
+	statedb.SetAutoCompound(validator, validator, true)
+	statedb.RevertToSnapshot(snapshot)
+
+	if metadata := statedb.GetValidatorMetadata(validator); metadata == nil || metadata.Moniker != "before" {
+		t.Errorf("metadata mismatch: have %+v, want moniker before", metadata)
+	}
+	if bond := statedb.GetMinSelfBond(validator); bond.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("min self bond mismatch: have %v, want 100", bond)
+	}
+	want := &CommissionChange{Candidate: validator, Commission: 10, Epoch: 1}
+	if change := statedb.GetCommissionChange(validator); !reflect.DeepEqual(change, want) {
+		t.Errorf("commission change mismatch: have %+v, want %+v", change, want)
+	}
+	if change := statedb.GetCommissionChange(delegator); change != nil {
+		t.Errorf("commission change of %x not reverted: %+v", delegator, change)
+	}
+	if !statedb.IsAutoCompound(delegator, validator) {
+		t.Errorf("auto compound of %x to %x not restored", delegator, validator)
+	}
+	if statedb.IsAutoCompound(validator, validator) {
+		t.Errorf("auto compound of %x to %x not reverted", validator, validator)
+	}
+}
+
+func TestRevertKeyRotation(t *testing.T) {
+	db := NewDatabase(memorydb.New())
+	statedb, _ := New(common.Hash{}, db)
+	rotated := common.BytesToAddress([]byte{0x01})
+	other := common.BytesToAddress([]byte{0x02})
+
+	statedb.MarkAddressKeyRotation(rotated)
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = New(root, db)
+
+	snapshot := statedb.Snapshot()
+	statedb.MarkAddressKeyRotation(other)
+	statedb.RevertToSnapshot(snapshot)
+	if set := statedb.GetKeyRotationSet(); len(set) != 1 {
+		t.Errorf("key rotation mark not reverted: %v", set)
+	}
+
+	snapshot = statedb.Snapshot()
+	statedb.ClearKeyRotationSet()
+	if set := statedb.GetKeyRotationSet(); len(set) != 0 {
+		t.Errorf("key rotation set not cleared: %v", set)
+	}
+	statedb.RevertToSnapshot(snapshot)
+	if _, ok := statedb.GetKeyRotationSet()[rotated]; !ok {
+		t.Errorf("clear of the key rotation set not reverted")
+	}
+	if have, _ := statedb.Commit(false); have != root {
+		t.Errorf("reverted state root mismatch: have %x, want %x", have, root)
+	}
+
+	// A cleared set is deleted from the trie
+	statedb.ClearKeyRotationSet()
+	cleared, _ := statedb.Commit(false)
+	empty, _ := New(common.Hash{}, db)
+	if want, _ := empty.Commit(false); cleared != want {
+		t.Errorf("cleared set left in the trie: root %x, want %x", cleared, want)
+	}
+}
+
+func TestRevertGovProposals(t *testing.T) {
+	db := NewDatabase(memorydb.New())
+	statedb, _ := New(common.Hash{}, db)
+	proposer := common.BytesToAddress([]byte{0x01})
+
+	first := statedb.AddGovProposal(proposer, "first", "", GovParamMaxValidators, big.NewInt(21), common.Address{}, 1, 10)
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Proposals are loaded from their own keys
+	statedb, _ = New(root, db)
+	if proposal := statedb.GetGovProposal(first); proposal == nil || proposal.Title != "first" {
+		t.Fatalf("proposal %d not loaded: %+v", first, proposal)
+	}
+
+	snapshot := statedb.Snapshot()
+	second := statedb.AddGovProposal(proposer, "second", "", "", nil, common.Address{}, 1, 10)
+	statedb.AddGovDeposit(first, proposer, big.NewInt(5))
+	statedb.SetGovProposalStatus(first, GovProposalPassed)
+	statedb.SetGovEpoch(1)
+	if passed := statedb.GetPassedGovProposals(); len(passed) != 1 || passed[0].Id != first {
+		t.Errorf("passed proposals mismatch: have %d", len(passed))
+	}
+	statedb.RevertToSnapshot(snapshot)
+
+	if proposal := statedb.GetGovProposal(second); proposal != nil {
+		t.Errorf("added proposal %d not reverted", second)
+	}
+	if proposal := statedb.GetGovProposal(first); proposal.Status != GovProposalDeposit || len(proposal.Deposits) != 0 {
+		t.Errorf("proposal %d not reverted: status %d, deposits %d", first, proposal.Status, len(proposal.Deposits))
+	}
+	if passed := statedb.GetPassedGovProposals(); len(passed) != 0 {
+		t.Errorf("passed proposals not reverted: have %d", len(passed))
+	}
+	if have := statedb.GovEpoch(); have != 0 {
+		t.Errorf("governance epoch not reverted: have %d", have)
+	}
+	if have, _ := statedb.Commit(false); have != root {
+		t.Errorf("reverted state root mismatch: have %x, want %x", have, root)
+	}
+}
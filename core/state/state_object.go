@@ -270,7 +270,9 @@ func (self *stateObject) HasTX1(db Database, txHash common.Hash) bool {
 			self.setError(err)
 			return false
 		}
-		if !bytes.Equal(content, txHash[:]) {
+		// Entries are stored without the leading zero bytes of the hash. The
+		// TX1s are not added by any block, the trie holds no entry on chain.
+		if !bytes.Equal(content, bytes.TrimLeft(txHash[:], "\x00")) {
 			self.setError(fmt.Errorf("content mismatch the tx hash"))
 			return false
 		}
@@ -363,7 +365,10 @@ func (self *stateObject) HasTX3(db Database, txHash common.Hash) bool {
 			self.setError(err)
 			return false
 		}
-		if !bytes.Equal(content, txHash[:]) {
+		// Entries are stored without the leading zero bytes of the hash. The
+		// TX3s are added from the TX3 replay fork block only, there is no
+		// entry of an older block which the comparison could tell otherwise.
+		if !bytes.Equal(content, bytes.TrimLeft(txHash[:], "\x00")) {
 			self.setError(fmt.Errorf("content mismatch the tx hash"))
 			return false
 		}
@@ -72,6 +72,58 @@ type StateDB struct {
 	bannedSet      BannedSet
 	bannedSetDirty bool
 
+	// key rotation set
+	keyRotationSet      KeyRotationSet
+	keyRotationSetDirty bool
+
+	// governance parameters and indexes of the proposals, nil until loaded
+	gov      *governance
+	govDirty bool
+
+	// governance proposals loaded, with the ones changed
+	govProposals      map[uint64]*GovProposal
+	govProposalsDirty map[uint64]struct{}
+
+	// validators ready for the scheduled upgrades, nil until loaded
+	upgradeSignals      UpgradeSignals
+	upgradeSignalsDirty bool
+
+	// commissions the candidates set for the next epoch, nil until loaded
+	commissionChanges      CommissionChanges
+	commissionChangesDirty bool
+
+	// delegations re-delegating their rewards, nil until loaded
+	autoCompounds      AutoCompounds
+	autoCompoundsDirty bool
+
+	// withdrawals to the main chain in their challenge window, nil until loaded
+	pendingWithdrawals      PendingWithdrawals
+	pendingWithdrawalsDirty bool
+
+	// assets registered for the cross chain transfers, nil until loaded
+	assets      Assets
+	assetsDirty bool
+
+	// minimum self bonds of the candidates loaded, with the ones changed
+	minSelfBonds      map[common.Address]*big.Int
+	minSelfBondsDirty map[common.Address]struct{}
+
+	// metadata of the validators loaded, with the ones changed
+	validatorMetadata      map[common.Address]*ValidatorMetadata
+	validatorMetadataDirty map[common.Address]struct{}
+
+	// reward withdrawals of the accounts loaded, with the ones changed
+	rewardClaims      map[common.Address]*RewardClaim
+	rewardClaimsDirty map[common.Address]struct{}
+
+	// light clients of the bridge, nil until loaded
+	bridgeClients      BridgeClients
+	bridgeClientsDirty bool
+
+	// packets and vouchers of the bridge loaded, with the ones changed
+	bridgeEntries      map[common.Hash][]byte
+	bridgeEntriesDirty map[common.Hash]struct{}
+
 	// Cache of Child Chain Reward Per Block
 	sideChainRewardPerBlock      *big.Int
 	sideChainRewardPerBlockDirty bool
@@ -120,6 +172,8 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 		candidateSetDirty:            false,
 		bannedSet:                    make(BannedSet),
 		bannedSetDirty:               false,
+		keyRotationSet:               make(KeyRotationSet),
+		keyRotationSetDirty:          false,
 		sideChainRewardPerBlock:      nil,
 		sideChainRewardPerBlockDirty: false,
 		logs:                         make(map[common.Hash][]*types.Log),
@@ -152,6 +206,24 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.rewardSet = make(RewardSet)
 	self.candidateSet = make(CandidateSet)
 	self.bannedSet = make(BannedSet)
+	self.keyRotationSet = make(KeyRotationSet)
+	self.gov = nil
+	self.govProposals = nil
+	self.govProposalsDirty = nil
+	self.upgradeSignals = nil
+	self.commissionChanges = nil
+	self.autoCompounds = nil
+	self.pendingWithdrawals = nil
+	self.assets = nil
+	self.minSelfBonds = nil
+	self.minSelfBondsDirty = nil
+	self.validatorMetadata = nil
+	self.validatorMetadataDirty = nil
+	self.rewardClaims = nil
+	self.rewardClaimsDirty = nil
+	self.bridgeClients = nil
+	self.bridgeEntries = nil
+	self.bridgeEntriesDirty = nil
 	self.sideChainRewardPerBlock = nil
 	self.thash = common.Hash{}
 	self.bhash = common.Hash{}
@@ -644,6 +716,27 @@ func (self *StateDB) Copy() *StateDB {
 		candidateSetDirty:            self.candidateSetDirty,
 		bannedSet:                    make(BannedSet, len(self.bannedSet)),
 		bannedSetDirty:               self.bannedSetDirty,
+		keyRotationSet:               make(KeyRotationSet, len(self.keyRotationSet)),
+		keyRotationSetDirty:          self.keyRotationSetDirty,
+		gov:                          self.gov.copy(),
+		govDirty:                     self.govDirty,
+		govProposals:                 copyGovProposals(self.govProposals),
+		upgradeSignals:               self.upgradeSignals.copy(),
+		upgradeSignalsDirty:          self.upgradeSignalsDirty,
+		commissionChanges:            self.commissionChanges.copy(),
+		commissionChangesDirty:       self.commissionChangesDirty,
+		autoCompounds:                self.autoCompounds.copy(),
+		autoCompoundsDirty:           self.autoCompoundsDirty,
+		pendingWithdrawals:           self.pendingWithdrawals.copy(),
+		pendingWithdrawalsDirty:      self.pendingWithdrawalsDirty,
+		assets:                       self.assets.copy(),
+		assetsDirty:                  self.assetsDirty,
+		minSelfBonds:                 copyMinSelfBonds(self.minSelfBonds),
+		validatorMetadata:            copyValidatorMetadata(self.validatorMetadata),
+		rewardClaims:                 copyRewardClaims(self.rewardClaims),
+		bridgeClients:                self.bridgeClients.copy(),
+		bridgeClientsDirty:           self.bridgeClientsDirty,
+		bridgeEntries:                copyBridgeEntries(self.bridgeEntries),
 		sideChainRewardPerBlockDirty: self.sideChainRewardPerBlockDirty,
 		refund:                       self.refund,
 		logs:                         make(map[common.Hash][]*types.Log, len(self.logs)),
@@ -670,6 +763,45 @@ func (self *StateDB) Copy() *StateDB {
 		state.bannedSet[addr] = struct{}{}
 	}
 
+	for addr := range self.keyRotationSet {
+		state.keyRotationSet[addr] = struct{}{}
+	}
+
+	if len(self.govProposalsDirty) > 0 {
+		state.govProposalsDirty = make(map[uint64]struct{}, len(self.govProposalsDirty))
+		for id := range self.govProposalsDirty {
+			state.govProposalsDirty[id] = struct{}{}
+		}
+	}
+
+	if len(self.minSelfBondsDirty) > 0 {
+		state.minSelfBondsDirty = make(map[common.Address]struct{}, len(self.minSelfBondsDirty))
+		for addr := range self.minSelfBondsDirty {
+			state.minSelfBondsDirty[addr] = struct{}{}
+		}
+	}
+
+	if len(self.validatorMetadataDirty) > 0 {
+		state.validatorMetadataDirty = make(map[common.Address]struct{}, len(self.validatorMetadataDirty))
+		for addr := range self.validatorMetadataDirty {
+			state.validatorMetadataDirty[addr] = struct{}{}
+		}
+	}
+
+	if len(self.rewardClaimsDirty) > 0 {
+		state.rewardClaimsDirty = make(map[common.Address]struct{}, len(self.rewardClaimsDirty))
+		for addr := range self.rewardClaimsDirty {
+			state.rewardClaimsDirty[addr] = struct{}{}
+		}
+	}
+
+	if len(self.bridgeEntriesDirty) > 0 {
+		state.bridgeEntriesDirty = make(map[common.Hash]struct{}, len(self.bridgeEntriesDirty))
+		for key := range self.bridgeEntriesDirty {
+			state.bridgeEntriesDirty[key] = struct{}{}
+		}
+	}
+
 	if self.sideChainRewardPerBlock != nil {
 		state.sideChainRewardPerBlock = new(big.Int).Set(self.sideChainRewardPerBlock)
 	}
@@ -754,6 +886,58 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 		s.commitBannedSet()
 	}
 
+	if s.keyRotationSetDirty {
+		s.commitKeyRotationSet()
+	}
+
+	if s.govDirty {
+		s.commitGovernance()
+	}
+
+	if len(s.govProposalsDirty) > 0 {
+		s.commitGovProposals()
+	}
+
+	if s.upgradeSignalsDirty {
+		s.commitUpgradeSignals()
+	}
+
+	if s.commissionChangesDirty {
+		s.commitCommissionChanges()
+	}
+
+	if s.autoCompoundsDirty {
+		s.commitAutoCompounds()
+	}
+
+	if s.pendingWithdrawalsDirty {
+		s.commitPendingWithdrawals()
+	}
+
+	if s.assetsDirty {
+		s.commitAssets()
+	}
+
+	if len(s.minSelfBondsDirty) > 0 {
+		s.commitMinSelfBonds()
+	}
+
+	if len(s.validatorMetadataDirty) > 0 {
+		s.commitValidatorMetadata()
+	}
+
+	if len(s.rewardClaimsDirty) > 0 {
+		s.commitRewardClaims()
+	}
+
+	if s.bridgeClientsDirty {
+		s.commitBridgeClients()
+	}
+
+	if len(s.bridgeEntriesDirty) > 0 {
+		s.commitBridgeEntries()
+	}
+
 	// Update Child Chain Reward per Block if something changed
 	if s.sideChainRewardPerBlockDirty {
 		s.commitSideChainRewardPerBlock()
@@ -873,6 +1057,71 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		s.bannedSetDirty = false
 	}
 
+	if s.keyRotationSetDirty {
+		s.commitKeyRotationSet()
+		s.keyRotationSetDirty = false
+	}
+
+	if s.govDirty {
+		s.commitGovernance()
+		s.govDirty = false
+	}
+
+	if len(s.govProposalsDirty) > 0 {
+		s.commitGovProposals()
+		s.govProposalsDirty = nil
+	}
+
+	if s.upgradeSignalsDirty {
+		s.commitUpgradeSignals()
+		s.upgradeSignalsDirty = false
+	}
+
+	if s.commissionChangesDirty {
+		s.commitCommissionChanges()
+		s.commissionChangesDirty = false
+	}
+
+	if s.autoCompoundsDirty {
+		s.commitAutoCompounds()
+		s.autoCompoundsDirty = false
+	}
+
+	if s.pendingWithdrawalsDirty {
+		s.commitPendingWithdrawals()
+		s.pendingWithdrawalsDirty = false
+	}
+
+	if s.assetsDirty {
+		s.commitAssets()
+		s.assetsDirty = false
+	}
+
+	if len(s.minSelfBondsDirty) > 0 {
+		s.commitMinSelfBonds()
+		s.minSelfBondsDirty = nil
+	}
+
+	if len(s.validatorMetadataDirty) > 0 {
+		s.commitValidatorMetadata()
+		s.validatorMetadataDirty = nil
+	}
+
+	if len(s.rewardClaimsDirty) > 0 {
+		s.commitRewardClaims()
+		s.rewardClaimsDirty = nil
+	}
+
+	if s.bridgeClientsDirty {
+		s.commitBridgeClients()
+		s.bridgeClientsDirty = false
+	}
+
+	if len(s.bridgeEntriesDirty) > 0 {
+		s.commitBridgeEntries()
+		s.bridgeEntriesDirty = nil
+	}
+
 	// Commit Reward Per Block to the trie
 	if s.sideChainRewardPerBlockDirty {
 		s.commitSideChainRewardPerBlock()
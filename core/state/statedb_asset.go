@@ -0,0 +1,184 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Asset Registry
+
+// Asset is a token transferred between chains. It is identified by the chain
+// it originates from and its symbol there, so that the tokens of the same
+// symbol bridged from several side chains remain distinct assets.
+type Asset struct {
+	Id          string // <origin chain>:<symbol>
+	Symbol      string
+	OriginChain string
+	Owner       common.Address   // Account which registered the asset, and maps its contracts
+	Contracts   []*AssetContract // by chain
+}
+
+// AssetContract is the contract representing an asset on a chain.
+type AssetContract struct {
+	Chain    string
+	Contract common.Address
+}
+
+const maxAssetSymbolLength = 16
+
+// AssetId returns the identifier of the asset of symbol originating from the
+// chain.
+func AssetId(originChain, symbol string) string {
+	return originChain + ":" + symbol
+}
+
+// ValidateAssetSymbol checks that the symbol can identify an asset.
+func ValidateAssetSymbol(symbol string) error {
+	if symbol == "" || len(symbol) > maxAssetSymbolLength {
+		return fmt.Errorf("asset symbol must have 1 to %d characters", maxAssetSymbolLength)
+	}
+	for _, c := range symbol {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+			return fmt.Errorf("invalid character %q in asset symbol", c)
+		}
+	}
+	return nil
+}
+
+// Contract returns the contract representing the asset on the chain, and
+// whether it has one.
+func (a *Asset) Contract(chain string) (common.Address, bool) {
+	for _, c := range a.Contracts {
+		if c.Chain == chain {
+			return c.Contract, true
+		}
+	}
+	return common.Address{}, false
+}
+
+func (a *Asset) copy() *Asset {
+	cpy := *a
+	cpy.Contracts = make([]*AssetContract, len(a.Contracts))
+	for i, c := range a.Contracts {
+		contract := *c
+		cpy.Contracts[i] = &contract
+	}
+	return &cpy
+}
+
+type Assets []*Asset
+
+var assetsKey = []byte("AssetRegistry")
+
+func (self *StateDB) getAssets() Assets {
+	if self.assets != nil {
+		return self.assets
+	}
+	self.assets = Assets{}
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(assetsKey)
+	if err != nil {
+		self.setError(err)
+		return self.assets
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.assets); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.assets
+}
+
+func (self *StateDB) commitAssets() {
+	data, err := rlp.EncodeToBytes(self.assets)
+	if err != nil {
+		panic(fmt.Errorf("can't encode assets : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(assetsKey, data))
+}
+
+// GetAsset returns the registered asset id, or nil if there is none.
+func (self *StateDB) GetAsset(id string) *Asset {
+	for _, asset := range self.getAssets() {
+		if asset.Id == id {
+			return asset.copy()
+		}
+	}
+	return nil
+}
+
+// GetAssets returns the registered assets, ordered by id.
+func (self *StateDB) GetAssets() []*Asset {
+	return self.getAssets().copy()
+}
+
+// GetAssetByContract returns the asset the contract represents on the chain,
+// or nil if it represents none.
+func (self *StateDB) GetAssetByContract(chain string, contract common.Address) *Asset {
+	for _, asset := range self.getAssets() {
+		if c, ok := asset.Contract(chain); ok && c == contract {
+			return asset.copy()
+		}
+	}
+	return nil
+}
+
+// RegisterAsset adds the asset of symbol originating from the chain, owned by
+// owner and represented there by contract. The caller checks the asset and
+// the contract are not registered already.
+func (self *StateDB) RegisterAsset(symbol, originChain string, owner, contract common.Address) *Asset {
+	asset := &Asset{
+		Id:          AssetId(originChain, symbol),
+		Symbol:      symbol,
+		OriginChain: originChain,
+		Owner:       owner,
+		Contracts:   []*AssetContract{{Chain: originChain, Contract: contract}},
+	}
+	assets := self.getAssets()
+	self.journal = append(self.journal, assetsChange{id: asset.Id, prevDirty: self.assetsDirty})
+	assets = append(assets, asset)
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].Id < assets[j].Id
+	})
+	self.assets = assets
+	self.assetsDirty = true
+	return asset.copy()
+}
+
+// SetAssetContract sets the contract representing the asset id on the chain,
+// replacing the one it had if any.
+func (self *StateDB) SetAssetContract(id, chain string, contract common.Address) {
+	for _, asset := range self.getAssets() {
+		if asset.Id != id {
+			continue
+		}
+		self.journal = append(self.journal, assetsChange{id: id, prev: asset.copy(), prevDirty: self.assetsDirty})
+		self.assetsDirty = true
+		for _, c := range asset.Contracts {
+			if c.Chain == chain {
+				c.Contract = contract
+				return
+			}
+		}
+		asset.Contracts = append(asset.Contracts, &AssetContract{Chain: chain, Contract: contract})
+		sort.Slice(asset.Contracts, func(i, j int) bool {
+			return asset.Contracts[i].Chain < asset.Contracts[j].Chain
+		})
+		return
+	}
+}
+
+func (assets Assets) copy() Assets {
+	if assets == nil {
+		return nil
+	}
+	cpy := make(Assets, len(assets))
+	for i, asset := range assets {
+		cpy[i] = asset.copy()
+	}
+	return cpy
+}
@@ -0,0 +1,117 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Auto Compound
+
+// AutoCompound is a delegation whose rewards are delegated again to the
+// candidate at each epoch boundary, instead of accruing as claimable.
+type AutoCompound struct {
+	Delegator common.Address
+	Candidate common.Address
+}
+
+type AutoCompounds []*AutoCompound
+
+var autoCompoundsKey = []byte("AutoCompounds")
+
+func (self *StateDB) getAutoCompounds() AutoCompounds {
+	if self.autoCompounds != nil {
+		return self.autoCompounds
+	}
+	self.autoCompounds = AutoCompounds{}
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(autoCompoundsKey)
+	if err != nil {
+		self.setError(err)
+		return self.autoCompounds
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.autoCompounds); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.autoCompounds
+}
+
+func (self *StateDB) commitAutoCompounds() {
+	data, err := rlp.EncodeToBytes(self.autoCompounds)
+	if err != nil {
+		panic(fmt.Errorf("can't encode auto compounds : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(autoCompoundsKey, data))
+}
+
+// SetAutoCompound opts the delegation of delegator to candidate in or out of
+// auto-compounding.
+func (self *StateDB) SetAutoCompound(delegator, candidate common.Address, enabled bool) {
+	compounds := self.getAutoCompounds()
+	for i, compound := range compounds {
+		if compound.Delegator == delegator && compound.Candidate == candidate {
+			if !enabled {
+				self.journalAutoCompounds()
+				self.autoCompounds = append(compounds[:i:i], compounds[i+1:]...)
+			}
+			return
+		}
+	}
+	if !enabled {
+		return
+	}
+	self.journalAutoCompounds()
+	compounds = append(compounds, &AutoCompound{Delegator: delegator, Candidate: candidate})
+	sort.Slice(compounds, func(i, j int) bool {
+		if c := bytes.Compare(compounds[i].Delegator[:], compounds[j].Delegator[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(compounds[i].Candidate[:], compounds[j].Candidate[:]) < 0
+	})
+	self.autoCompounds = compounds
+}
+
+// journalAutoCompounds journals a copy of the auto-compounding delegations
+// before they change in place.
+func (self *StateDB) journalAutoCompounds() {
+	self.journal = append(self.journal, autoCompoundsChange{
+		prev:      self.getAutoCompounds().copy(),
+		prevDirty: self.autoCompoundsDirty,
+	})
+	self.autoCompoundsDirty = true
+}
+
+// IsAutoCompound returns whether the delegation of delegator to candidate is
+// auto-compounding.
+func (self *StateDB) IsAutoCompound(delegator, candidate common.Address) bool {
+	for _, compound := range self.getAutoCompounds() {
+		if compound.Delegator == delegator && compound.Candidate == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAutoCompounds returns the auto-compounding delegations, sorted by
+// delegator and candidate.
+func (self *StateDB) GetAutoCompounds() AutoCompounds {
+	return self.getAutoCompounds().copy()
+}
+
+func (compounds AutoCompounds) copy() AutoCompounds {
+	if compounds == nil {
+		return nil
+	}
+	cpy := make(AutoCompounds, len(compounds))
+	for i, compound := range compounds {
+		c := *compound
+		cpy[i] = &c
+	}
+	return cpy
+}
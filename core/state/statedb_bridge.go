@@ -0,0 +1,261 @@
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Bridge Clients
+
+// BridgeClient is a light client of an external chain hosted by the bridge,
+// with the channel its packets are transferred on.
+type BridgeClient struct {
+	Id     string
+	Type   string
+	State  []byte // encoded client, decoded by the client type
+	Frozen bool   // after evidence of a fork of the external chain
+
+	Port                string
+	Channel             string
+	CounterpartyPort    string
+	CounterpartyChannel string
+
+	ConsensusStates []*BridgeConsensusState // by increasing height
+	Escrowed        *big.Int                // NEAT sent through the client and not returned
+	NextSequence    uint64                  // of the next packet sent
+}
+
+// BridgeConsensusState is the encoded consensus state of a client at Height.
+type BridgeConsensusState struct {
+	Height uint64
+	State  []byte
+}
+
+// ConsensusState returns the encoded consensus state of the client at
+// height, or nil if it has none.
+func (c *BridgeClient) ConsensusState(height uint64) []byte {
+	i := sort.Search(len(c.ConsensusStates), func(i int) bool {
+		return c.ConsensusStates[i].Height >= height
+	})
+	if i < len(c.ConsensusStates) && c.ConsensusStates[i].Height == height {
+		return c.ConsensusStates[i].State
+	}
+	return nil
+}
+
+// AddConsensusState stores the consensus state at height, dropping the
+// lowest ones beyond max.
+func (c *BridgeClient) AddConsensusState(height uint64, state []byte, max int) {
+	i := sort.Search(len(c.ConsensusStates), func(i int) bool {
+		return c.ConsensusStates[i].Height >= height
+	})
+	cs := &BridgeConsensusState{Height: height, State: state}
+	if i < len(c.ConsensusStates) && c.ConsensusStates[i].Height == height {
+		c.ConsensusStates[i] = cs
+	} else {
+		c.ConsensusStates = append(c.ConsensusStates, nil)
+		copy(c.ConsensusStates[i+1:], c.ConsensusStates[i:])
+		c.ConsensusStates[i] = cs
+	}
+	if len(c.ConsensusStates) > max {
+		c.ConsensusStates = c.ConsensusStates[len(c.ConsensusStates)-max:]
+	}
+}
+
+func (c *BridgeClient) copy() *BridgeClient {
+	cpy := *c
+	cpy.ConsensusStates = make([]*BridgeConsensusState, len(c.ConsensusStates))
+	for i, cs := range c.ConsensusStates {
+		s := *cs
+		cpy.ConsensusStates[i] = &s
+	}
+	cpy.Escrowed = new(big.Int).Set(c.Escrowed)
+	return &cpy
+}
+
+type BridgeClients []*BridgeClient
+
+var bridgeClientsKey = []byte("BridgeClients")
+
+func (self *StateDB) getBridgeClients() BridgeClients {
+	if self.bridgeClients != nil {
+		return self.bridgeClients
+	}
+	self.bridgeClients = BridgeClients{}
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(bridgeClientsKey)
+	if err != nil {
+		self.setError(err)
+		return self.bridgeClients
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.bridgeClients); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.bridgeClients
+}
+
+func (self *StateDB) commitBridgeClients() {
+	data, err := rlp.EncodeToBytes(self.bridgeClients)
+	if err != nil {
+		panic(fmt.Errorf("can't encode bridge clients : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(bridgeClientsKey, data))
+}
+
+// GetBridgeClient returns the bridge client id, or nil if there is none.
+func (self *StateDB) GetBridgeClient(id string) *BridgeClient {
+	for _, client := range self.getBridgeClients() {
+		if client.Id == id {
+			return client.copy()
+		}
+	}
+	return nil
+}
+
+// GetBridgeClients returns the bridge clients, ordered by id.
+func (self *StateDB) GetBridgeClients() []*BridgeClient {
+	return self.getBridgeClients().copy()
+}
+
+// SetBridgeClient stores the bridge client, replacing the client of the same
+// id if any.
+func (self *StateDB) SetBridgeClient(client *BridgeClient) {
+	clients := self.getBridgeClients()
+	client = client.copy()
+	self.bridgeClientsDirty = true
+	for i, c := range clients {
+		if c.Id == client.Id {
+			clients[i] = client
+			return
+		}
+	}
+	clients = append(clients, client)
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Id < clients[j].Id })
+	self.bridgeClients = clients
+}
+
+func (clients BridgeClients) copy() BridgeClients {
+	if clients == nil {
+		return nil
+	}
+	cpy := make(BridgeClients, len(clients))
+	for i, client := range clients {
+		cpy[i] = client.copy()
+	}
+	return cpy
+}
+
+// ----- Bridge Packets and Vouchers
+
+var bridgeEntryPrefix = []byte("Bridge")
+
+// bridgeEntryKey returns the key of a bridge entry of kind, named by parts.
+func bridgeEntryKey(kind string, parts ...[]byte) common.Hash {
+	data := [][]byte{[]byte(kind)}
+	for _, part := range parts {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(part)))
+		data = append(data, length[:], part)
+	}
+	return crypto.Keccak256Hash(data...)
+}
+
+func sequenceBytes(sequence uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], sequence)
+	return b[:]
+}
+
+func (self *StateDB) getBridgeEntry(key common.Hash) []byte {
+	if value, exist := self.bridgeEntries[key]; exist {
+		return value
+	}
+	if self.bridgeEntries == nil {
+		self.bridgeEntries = make(map[common.Hash][]byte)
+	}
+	value, err := self.trie.TryGet(append(append([]byte(nil), bridgeEntryPrefix...), key[:]...))
+	if err != nil {
+		self.setError(err)
+	}
+	self.bridgeEntries[key] = value
+	return value
+}
+
+func (self *StateDB) setBridgeEntry(key common.Hash, value []byte) {
+	prev := self.getBridgeEntry(key)
+	self.journal = append(self.journal, bridgeEntryChange{key: key, prev: prev})
+	self.bridgeEntries[key] = value
+	if self.bridgeEntriesDirty == nil {
+		self.bridgeEntriesDirty = make(map[common.Hash]struct{})
+	}
+	self.bridgeEntriesDirty[key] = struct{}{}
+}
+
+func (self *StateDB) commitBridgeEntries() {
+	for key := range self.bridgeEntriesDirty {
+		// An empty value deletes the entry.
+		self.setError(self.trie.TryUpdate(append(append([]byte(nil), bridgeEntryPrefix...), key[:]...), self.bridgeEntries[key]))
+	}
+}
+
+// HasBridgeReceipt returns whether the packet sequence sent to the bridge
+// client was received.
+func (self *StateDB) HasBridgeReceipt(clientId string, sequence uint64) bool {
+	return len(self.getBridgeEntry(bridgeEntryKey("receipt", []byte(clientId), sequenceBytes(sequence)))) > 0
+}
+
+// SetBridgeReceipt records the packet sequence sent to the bridge client was
+// received, so it is not received again.
+func (self *StateDB) SetBridgeReceipt(clientId string, sequence uint64) {
+	self.setBridgeEntry(bridgeEntryKey("receipt", []byte(clientId), sequenceBytes(sequence)), []byte{1})
+}
+
+// GetBridgeCommitment returns the commitment to the packet sequence sent
+// through the bridge client, or nil once it is acknowledged.
+func (self *StateDB) GetBridgeCommitment(clientId string, sequence uint64) []byte {
+	return common.CopyBytes(self.getBridgeEntry(bridgeEntryKey("commitment", []byte(clientId), sequenceBytes(sequence))))
+}
+
+// SetBridgeCommitment stores the commitment to the packet sequence sent
+// through the bridge client, nil deleting it.
+func (self *StateDB) SetBridgeCommitment(clientId string, sequence uint64, commitment []byte) {
+	self.setBridgeEntry(bridgeEntryKey("commitment", []byte(clientId), sequenceBytes(sequence)), common.CopyBytes(commitment))
+}
+
+// GetBridgeVoucher returns the vouchers of denom, tokens received from an
+// external chain, held by addr.
+func (self *StateDB) GetBridgeVoucher(denom string, addr common.Address) *big.Int {
+	return new(big.Int).SetBytes(self.getBridgeEntry(bridgeEntryKey("voucher", []byte(denom), addr[:])))
+}
+
+// AddBridgeVoucher mints amount vouchers of denom to addr.
+func (self *StateDB) AddBridgeVoucher(denom string, addr common.Address, amount *big.Int) {
+	balance := self.GetBridgeVoucher(denom, addr)
+	self.setBridgeEntry(bridgeEntryKey("voucher", []byte(denom), addr[:]), balance.Add(balance, amount).Bytes())
+}
+
+// SubBridgeVoucher burns amount vouchers of denom held by addr.
+func (self *StateDB) SubBridgeVoucher(denom string, addr common.Address, amount *big.Int) {
+	balance := self.GetBridgeVoucher(denom, addr)
+	self.setBridgeEntry(bridgeEntryKey("voucher", []byte(denom), addr[:]), balance.Sub(balance, amount).Bytes())
+}
+
+func copyBridgeEntries(entries map[common.Hash][]byte) map[common.Hash][]byte {
+	if entries == nil {
+		return nil
+	}
+	cpy := make(map[common.Hash][]byte, len(entries))
+	for key, value := range entries {
+		cpy[key] = common.CopyBytes(value)
+	}
+	return cpy
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"bytes"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/crypto"
+)
+
+// ----- Chain Data
+
+// chainDataKeys are the keys of the chain data held in the account trie next
+// to the accounts.
+var chainDataKeys = [][]byte{
+	candidateSetKey,
+	bannedSetKey,
+	refundSetKey,
+	rewardSetKey,
+	sideChainRewardPerBlockKey,
+	keyRotationSetKey,
+	commissionChangesKey,
+	autoCompoundsKey,
+	pendingWithdrawalsKey,
+	governanceKey,
+	upgradeSignalsKey,
+	assetsKey,
+	bridgeClientsKey,
+}
+
+// chainDataPrefixes prefix the keys of the chain data held per account or per
+// bridge entry, followed by the address or the hash of the entry.
+var chainDataPrefixes = []struct {
+	prefix []byte
+	length int
+}{
+	{rewardClaimPrefix, common.NeatAddressLength},
+	{minSelfBondPrefix, common.NeatAddressLength},
+	{validatorMetadataPrefix, common.NeatAddressLength},
+	{bridgeEntryPrefix, common.HashLength},
+}
+
+var chainDataKeyHashes = func() map[common.Hash]struct{} {
+	hashes := make(map[common.Hash]struct{}, len(chainDataKeys))
+	for _, key := range chainDataKeys {
+		hashes[crypto.Keccak256Hash(key)] = struct{}{}
+	}
+	return hashes
+}()
+
+// IsChainDataKey returns whether key holds chain data rather than an account
+// in the account trie.
+func IsChainDataKey(key []byte) bool {
+	for _, key2 := range chainDataKeys {
+		if bytes.Equal(key, key2) {
+			return true
+		}
+	}
+	for _, p := range chainDataPrefixes {
+		if len(key) == len(p.prefix)+p.length && bytes.HasPrefix(key, p.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsChainDataKeyHash returns whether hash is the hashed key of chain data not
+// held per account or per bridge entry, the ones told apart without the
+// preimage of the key.
+func IsChainDataKeyHash(hash common.Hash) bool {
+	_, ok := chainDataKeyHashes[hash]
+	return ok
+}
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Commission Changes
+
+// CommissionChange is a commission set by a candidate, effective from the
+// start of Epoch.
+type CommissionChange struct {
+	Candidate  common.Address
+	Commission uint8
+	Epoch      uint64
+}
+
+type CommissionChanges []*CommissionChange
+
+var commissionChangesKey = []byte("CommissionChanges")
+
+func (self *StateDB) getCommissionChanges() CommissionChanges {
+	if self.commissionChanges != nil {
+		return self.commissionChanges
+	}
+	self.commissionChanges = CommissionChanges{}
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(commissionChangesKey)
+	if err != nil {
+		self.setError(err)
+		return self.commissionChanges
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.commissionChanges); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.commissionChanges
+}
+
+func (self *StateDB) commitCommissionChanges() {
+	data, err := rlp.EncodeToBytes(self.commissionChanges)
+	if err != nil {
+		panic(fmt.Errorf("can't encode commission changes : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(commissionChangesKey, data))
+}
+
+// ScheduleCommission sets the commission of candidate from the start of
+// epoch, replacing the change it scheduled before if any.
+func (self *StateDB) ScheduleCommission(candidate common.Address, commission uint8, epoch uint64) {
+	changes := self.getCommissionChanges()
+	for _, change := range changes {
+		if change.Candidate == candidate {
+			self.journalCommissionChanges()
+			change.Commission = commission
+			change.Epoch = epoch
+			return
+		}
+	}
+	self.journalCommissionChanges()
+	changes = append(changes, &CommissionChange{Candidate: candidate, Commission: commission, Epoch: epoch})
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Candidate[:], changes[j].Candidate[:]) < 0
+	})
+	self.commissionChanges = changes
+}
+
+// journalCommissionChanges journals a copy of the scheduled commission changes
+// before they change in place.
+func (self *StateDB) journalCommissionChanges() {
+	self.journal = append(self.journal, commissionChangesChange{
+		prev:      self.getCommissionChanges().copy(),
+		prevDirty: self.commissionChangesDirty,
+	})
+	self.commissionChangesDirty = true
+}
+
+// GetCommissionChange returns the commission change candidate scheduled, or
+// nil if it has none.
+func (self *StateDB) GetCommissionChange(candidate common.Address) *CommissionChange {
+	for _, change := range self.getCommissionChanges() {
+		if change.Candidate == candidate {
+			cpy := *change
+			return &cpy
+		}
+	}
+	return nil
+}
+
+// ApplyCommissionChanges sets the commissions scheduled up to epoch, and
+// returns them. The changes of the accounts no longer candidate are dropped.
+func (self *StateDB) ApplyCommissionChanges(epoch uint64) []*CommissionChange {
+	var applied []*CommissionChange
+	pending := CommissionChanges{}
+	for _, change := range self.getCommissionChanges() {
+		if change.Epoch > epoch {
+			pending = append(pending, change)
+			continue
+		}
+		if self.IsCandidate(change.Candidate) {
+			self.SetCommission(change.Candidate, change.Commission)
+			applied = append(applied, change)
+		}
+	}
+	if len(pending) != len(self.commissionChanges) {
+		self.journalCommissionChanges()
+		self.commissionChanges = pending
+	}
+	return applied
+}
+
+func (changes CommissionChanges) copy() CommissionChanges {
+	if changes == nil {
+		return nil
+	}
+	cpy := make(CommissionChanges, len(changes))
+	for i, change := range changes {
+		c := *change
+		cpy[i] = &c
+	}
+	return cpy
+}
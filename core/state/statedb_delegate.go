@@ -276,6 +276,14 @@ func (self *StateDB) GetPubkey(addr common.Address) string {
 	return ""
 }
 
+// SetPubkey replaces the consensus public key of the given candidate
+func (self *StateDB) SetPubkey(addr common.Address, pubkey string) {
+	stateObject := self.GetOrNewStateObject(addr)
+	if stateObject != nil {
+		stateObject.SetPubkey(pubkey)
+	}
+}
+
 // GetCommission Retrieve the commission percentage of the given address or 0 if object not found
 func (self *StateDB) GetCommission(addr common.Address) uint8 {
 	stateObject := self.getStateObject(addr)
@@ -312,6 +320,9 @@ func (self *StateDB) CancelCandidate(addr common.Address, allRefund bool) {
 
 		if allRefund {
 			stateObject.SetCommission(0)
+			if self.getMinSelfBond(addr).Sign() > 0 {
+				self.SetMinSelfBond(addr, common.Big0)
+			}
 		}
 	}
 }
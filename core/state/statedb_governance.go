@@ -0,0 +1,736 @@
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Governance
+
+// Parameters which can be changed by governance proposals.
+const (
+	GovParamBlockGasLimit        = "block_gas_limit"             // Gas limit the block producers aim for
+	GovParamMaxValidators        = "max_validators"              // Maximum size of the validator set
+	GovParamRewardRate           = "reward_rate"                 // Block reward, in percent of the epoch reward per block
+	GovParamTimeoutPropose       = "timeout_propose"             // Consensus propose timeout, in milliseconds
+	GovParamTimeoutPrevote       = "timeout_prevote"             // Consensus prevote wait timeout, in milliseconds
+	GovParamTimeoutPrecommit     = "timeout_precommit"           // Consensus precommit wait timeout, in milliseconds
+	GovParamMinBlockInterval     = "min_block_interval"          // Minimum time between the consensus times of consecutive blocks, in milliseconds
+	GovParamMinDeposit           = "gov_min_deposit"             // Deposit opening the voting on a proposal, in NEAT
+	GovParamDepositPeriod        = "gov_deposit_period"          // Blocks a proposal has to reach the minimum deposit
+	GovParamVotingPeriod         = "gov_voting_period"           // Blocks a proposal is voted on
+	GovParamTreasuryRate         = "treasury_rate"               // Share of the block reward funding the treasury, in percent
+	GovParamTreasurySpend        = "treasury_spend"              // Not a parameter: the value is paid out of the treasury
+	GovParamMaxCommission        = "max_commission"              // Highest commission of the candidates, in percent
+	GovParamMaxCommissionChange  = "max_commission_change"       // Largest commission change per epoch, in percent
+	GovParamSideChainGracePeriod = "side_chain_grace_period"     // Main chain blocks a retired side chain accepts withdrawals
+	GovParamMaxSideChains        = "max_side_chains"             // Most side chains a validator may secure at once, unlimited if not set
+	GovParamCrossChainFeeRate    = "cross_chain_fee_rate"        // Fee of the cross chain transfers, in basis points of the amount
+	GovParamCrossChainRelayerFee = "cross_chain_relayer_fee"     // Share of the cross chain fees paid to the relayer, in percent
+	GovParamCrossChainMinAmount  = "cross_chain_min_amount"      // Smallest cross chain transfer, in NEAT
+	GovParamChallengeWindow      = "withdrawal_challenge_window" // Main chain blocks a withdrawal from a side chain can be challenged before its release
+	GovParamMinGasPrice          = "min_gas_price"               // Lowest gas price of the transactions the pools accept from the network, in wei
+	GovParamTxPoolAccountSlots   = "txpool_account_slots"        // Executable transactions the pools guarantee per account
+	GovParamTxPoolGlobalSlots    = "txpool_global_slots"         // Executable transactions the pools hold for all accounts
+	GovParamTxPoolAccountQueue   = "txpool_account_queue"        // Non-executable transactions the pools hold per account
+	GovParamTxPoolGlobalQueue    = "txpool_global_queue"         // Non-executable transactions the pools hold for all accounts
+)
+
+// Defaults of the governance parameters of the proposal lifecycle.
+const (
+	DefaultGovMinDeposit    = 1000
+	DefaultGovDepositPeriod = 86400
+	DefaultGovVotingPeriod  = 86400
+)
+
+// Defaults of the governance parameters of the cross chain transfers.
+const (
+	DefaultCrossChainFeeRate    = 0
+	DefaultCrossChainRelayerFee = 50
+	DefaultCrossChainMinAmount  = 0
+)
+
+// Defaults of the governance parameters bounding the commission.
+const (
+	DefaultMaxCommission       = 100
+	DefaultMaxCommissionChange = 10
+)
+
+// govParamRanges are the values allowed for each governed parameter.
+var govParamRanges = map[string][2]uint64{
+	GovParamBlockGasLimit:        {5000, 1 << 40},
+	GovParamMaxValidators:        {1, 1000},
+	GovParamRewardRate:           {0, 1000},
+	GovParamTimeoutPropose:       {100, 600000},
+	GovParamTimeoutPrevote:       {100, 600000},
+	GovParamTimeoutPrecommit:     {100, 600000},
+	GovParamMinBlockInterval:     {0, 600000},
+	GovParamMinDeposit:           {1, 1000000000},
+	GovParamDepositPeriod:        {100, 10000000},
+	GovParamVotingPeriod:         {100, 10000000},
+	GovParamTreasuryRate:         {0, 100},
+	GovParamMaxCommission:        {0, 100},
+	GovParamMaxCommissionChange:  {1, 100},
+	GovParamSideChainGracePeriod: {1000, 100000000},
+	GovParamMaxSideChains:        {1, 1000},
+	GovParamCrossChainFeeRate:    {0, 1000},
+	GovParamCrossChainRelayerFee: {0, 100},
+	GovParamCrossChainMinAmount:  {0, 1000000000},
+	GovParamChallengeWindow:      {0, 10000000},
+	GovParamMinGasPrice:          {1, 1000000000000000000},
+	GovParamTxPoolAccountSlots:   {1, 100000},
+	GovParamTxPoolGlobalSlots:    {1, 1000000},
+	GovParamTxPoolAccountQueue:   {1, 100000},
+	GovParamTxPoolGlobalQueue:    {1, 1000000},
+}
+
+// GovParamNames returns the names of the governed parameters, sorted.
+func GovParamNames() []string {
+	names := make([]string, 0, len(govParamRanges))
+	for name := range govParamRanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateGovParam checks that value is allowed for the governed parameter.
+func ValidateGovParam(name string, value *big.Int) error {
+	if strings.HasPrefix(name, GovParamUpgradePrefix) {
+		if err := validateUpgradeName(strings.TrimPrefix(name, GovParamUpgradePrefix)); err != nil {
+			return err
+		}
+		if value == nil || !value.IsUint64() || value.Sign() == 0 {
+			return fmt.Errorf("activation height of %s out of range", name)
+		}
+		return nil
+	}
+	if strings.HasPrefix(name, GovParamRetirePrefix) {
+		if err := validateSideChainId(strings.TrimPrefix(name, GovParamRetirePrefix)); err != nil {
+			return err
+		}
+		if value == nil || !value.IsUint64() || value.Sign() == 0 {
+			return fmt.Errorf("freeze height of %s out of range", name)
+		}
+		return nil
+	}
+	if name == GovParamTreasurySpend {
+		if value == nil || value.Sign() <= 0 {
+			return fmt.Errorf("amount of %s must be positive", name)
+		}
+		return nil
+	}
+	bounds, ok := govParamRanges[name]
+	if !ok {
+		return fmt.Errorf("unknown governance parameter %q", name)
+	}
+	if value == nil || !value.IsUint64() || value.Uint64() < bounds[0] || value.Uint64() > bounds[1] {
+		return fmt.Errorf("value of %s out of range [%d, %d]", name, bounds[0], bounds[1])
+	}
+	return nil
+}
+
+// Status of a governance proposal.
+const (
+	GovProposalDeposit  uint8 = iota // Collecting the minimum deposit
+	GovProposalVoting                // Voting until the end of the voting period
+	GovProposalPassed                // Passed, parameter change applied from the epoch of the proposal
+	GovProposalRejected              // Quorum reached, threshold not reached
+	GovProposalExecuted              // Parameter change applied
+	GovProposalExpired               // Minimum deposit or quorum not reached, deposits burned
+	GovProposalFailed                // Passed, treasury spend not affordable
+)
+
+// GovProposal is a proposal voted on by bonded stake. A proposal without
+// parameter is a text proposal, recording a decision made off chain; the value
+// of a parameter change proposal is applied from the start of an epoch.
+type GovProposal struct {
+	Id          uint64
+	Proposer    common.Address
+	Title       string
+	Description string
+	Param       string
+	Value       *big.Int
+	Recipient   common.Address // Of a treasury spend
+	Epoch       uint64
+	Status      uint8
+	DepositEnd  uint64 // Last block of the deposit period
+	VotingEnd   uint64 // Last block of the voting period, once started
+	Deposits    []*GovDeposit
+	Votes       []*GovVote
+}
+
+// InDepositPeriod reports whether the proposal is still collecting deposits.
+func (p *GovProposal) InDepositPeriod() bool {
+	return p.Status == GovProposalDeposit
+}
+
+// Voting reports whether the proposal is still open for votes.
+func (p *GovProposal) Voting() bool {
+	return p.Status == GovProposalVoting
+}
+
+// IsText reports whether the proposal changes no parameter.
+func (p *GovProposal) IsText() bool {
+	return p.Param == ""
+}
+
+// IsTreasurySpend reports whether the proposal pays out of the treasury.
+func (p *GovProposal) IsTreasurySpend() bool {
+	return p.Param == GovParamTreasurySpend
+}
+
+// TotalDeposit returns the sum of the deposits on the proposal.
+func (p *GovProposal) TotalDeposit() *big.Int {
+	total := new(big.Int)
+	for _, deposit := range p.Deposits {
+		total.Add(total, deposit.Amount)
+	}
+	return total
+}
+
+// StatusString returns the status of the proposal in words.
+func (p *GovProposal) StatusString() string {
+	switch p.Status {
+	case GovProposalDeposit:
+		return "deposit"
+	case GovProposalVoting:
+		return "voting"
+	case GovProposalPassed:
+		return "passed"
+	case GovProposalRejected:
+		return "rejected"
+	case GovProposalExecuted:
+		return "executed"
+	case GovProposalExpired:
+		return "expired"
+	case GovProposalFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// GovDeposit is the deposit of an address on a proposal.
+type GovDeposit struct {
+	Depositor common.Address
+	Amount    *big.Int
+}
+
+// GovVote is the vote of an address on a proposal; its weight is the stake of
+// the address when the proposal is tallied.
+type GovVote struct {
+	Voter   common.Address
+	Approve bool
+}
+
+// GovParam is a governed parameter set by proposals, with the history of its
+// values; the last change applying from the current epoch is in effect.
+type GovParam struct {
+	Name    string
+	Changes []*GovParamChange
+}
+
+// GovParamChange is a value set for a governed parameter.
+type GovParamChange struct {
+	Value      *big.Int
+	ProposalId uint64 // Proposal which set the value, 0 if none
+	Epoch      uint64 // Epoch the value applies from
+}
+
+// change returns the change in effect at epoch, nil if none.
+func (param *GovParam) change(epoch uint64) *GovParamChange {
+	for i := len(param.Changes) - 1; i >= 0; i-- {
+		if param.Changes[i].Epoch <= epoch {
+			return param.Changes[i]
+		}
+	}
+	return nil
+}
+
+// value returns the value in effect at epoch, nil if none.
+func (param *GovParam) value(epoch uint64) *big.Int {
+	if change := param.change(epoch); change != nil {
+		return new(big.Int).Set(change.Value)
+	}
+	return nil
+}
+
+// governance is the governance data stored in the state. The proposals are
+// stored apart, each under its own key.
+type governance struct {
+	Count    uint64 // Proposals submitted, the id of the last one
+	Params   []*GovParam
+	Treasury treasury
+	Open     []uint64 // Proposals in their deposit or voting period, by end of the period
+	Passed   []uint64 // Parameter change proposals passed, not yet applied
+	Epoch    uint64   // Epoch the parameter changes are in effect for
+}
+
+var (
+	governanceKey        = []byte("Governance")
+	govProposalKeyPrefix = []byte("GovProposal")
+)
+
+func govProposalKey(id uint64) []byte {
+	key := make([]byte, len(govProposalKeyPrefix)+8)
+	copy(key, govProposalKeyPrefix)
+	binary.BigEndian.PutUint64(key[len(govProposalKeyPrefix):], id)
+	return key
+}
+
+func (self *StateDB) getGovernance() *governance {
+	if self.gov != nil {
+		return self.gov
+	}
+	self.gov = new(governance)
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(governanceKey)
+	if err != nil {
+		self.setError(err)
+		return self.gov
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, self.gov); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.gov
+}
+
+func (self *StateDB) commitGovernance() {
+	data, err := rlp.EncodeToBytes(self.gov)
+	if err != nil {
+		panic(fmt.Errorf("can't encode governance : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(governanceKey, data))
+}
+
+func (self *StateDB) getGovProposal(id uint64) *GovProposal {
+	if id == 0 || id > self.getGovernance().Count {
+		return nil
+	}
+	if proposal, exist := self.govProposals[id]; exist {
+		return proposal
+	}
+	if self.govProposals == nil {
+		self.govProposals = make(map[uint64]*GovProposal)
+	}
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(govProposalKey(id))
+	if err != nil {
+		self.setError(err)
+		return nil
+	}
+	var proposal *GovProposal
+	if len(enc) > 0 {
+		proposal = new(GovProposal)
+		if err := rlp.DecodeBytes(enc, proposal); err != nil {
+			self.setError(err)
+		}
+	}
+	self.govProposals[id] = proposal
+	return proposal
+}
+
+func (self *StateDB) commitGovProposals() {
+	for id := range self.govProposalsDirty {
+		data, err := rlp.EncodeToBytes(self.govProposals[id])
+		if err != nil {
+			panic(fmt.Errorf("can't encode governance proposal %d: %v", id, err))
+		}
+		self.setError(self.trie.TryUpdate(govProposalKey(id), data))
+	}
+}
+
+// AddGovProposal stores a new proposal in its deposit period, starting at
+// block number, and returns its id.
+func (self *StateDB) AddGovProposal(proposer common.Address, title, description, param string, value *big.Int, recipient common.Address, epoch, number uint64) uint64 {
+	gov := self.getGovernance()
+	proposal := &GovProposal{
+		Id:          gov.Count + 1,
+		Proposer:    proposer,
+		Title:       title,
+		Description: description,
+		Param:       param,
+		Value:       new(big.Int),
+		Recipient:   recipient,
+		Epoch:       epoch,
+		Status:      GovProposalDeposit,
+		DepositEnd:  number + self.GovDepositPeriod(),
+	}
+	if value != nil {
+		proposal.Value.Set(value)
+	}
+	self.journalGovProposal(proposal.Id)
+	gov.Count = proposal.Id
+	if self.govProposals == nil {
+		self.govProposals = make(map[uint64]*GovProposal)
+	}
+	self.govProposals[proposal.Id] = proposal
+	self.openGovProposal(proposal)
+	return proposal.Id
+}
+
+// journalGovProposal journals the proposal, not yet added if id is the next
+// one, and the indexes of the proposals before they change.
+func (self *StateDB) journalGovProposal(id uint64) {
+	gov := self.getGovernance()
+	_, prevProposalDirty := self.govProposalsDirty[id]
+	change := govProposalChange{
+		id:                id,
+		prevOpen:          append([]uint64(nil), gov.Open...),
+		prevPassed:        append([]uint64(nil), gov.Passed...),
+		prevDirty:         self.govDirty,
+		prevProposalDirty: prevProposalDirty,
+	}
+	if proposal := self.getGovProposal(id); proposal != nil {
+		change.prev = proposal.copy()
+	}
+	self.journal = append(self.journal, change)
+	self.govDirty = true
+	if self.govProposalsDirty == nil {
+		self.govProposalsDirty = make(map[uint64]struct{})
+	}
+	self.govProposalsDirty[id] = struct{}{}
+}
+
+// periodEnd returns the last block of the current period of the proposal.
+func (p *GovProposal) periodEnd() uint64 {
+	if p.Status == GovProposalVoting {
+		return p.VotingEnd
+	}
+	return p.DepositEnd
+}
+
+// openGovProposal indexes the proposal by the end of its current period,
+// after the proposals ending at the same block.
+func (self *StateDB) openGovProposal(proposal *GovProposal) {
+	gov := self.getGovernance()
+	gov.Open = removeGovProposalId(gov.Open, proposal.Id)
+	end := proposal.periodEnd()
+	i := sort.Search(len(gov.Open), func(i int) bool {
+		return self.getGovProposal(gov.Open[i]).periodEnd() > end
+	})
+	gov.Open = append(gov.Open, 0)
+	copy(gov.Open[i+1:], gov.Open[i:])
+	gov.Open[i] = proposal.Id
+}
+
+func removeGovProposalId(ids []uint64, id uint64) []uint64 {
+	for i, other := range ids {
+		if other == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+// GetGovProposal returns the proposal with the given id, nil if not found.
+func (self *StateDB) GetGovProposal(id uint64) *GovProposal {
+	return self.getGovProposal(id)
+}
+
+// GetGovProposals returns all the proposals, by id.
+func (self *StateDB) GetGovProposals() []*GovProposal {
+	count := self.getGovernance().Count
+	proposals := make([]*GovProposal, 0, count)
+	for id := uint64(1); id <= count; id++ {
+		if proposal := self.getGovProposal(id); proposal != nil {
+			proposals = append(proposals, proposal)
+		}
+	}
+	return proposals
+}
+
+// GetPassedGovProposals returns the parameter change proposals passed and not
+// yet applied, by id.
+func (self *StateDB) GetPassedGovProposals() []*GovProposal {
+	var passed []*GovProposal
+	for _, id := range self.getGovernance().Passed {
+		passed = append(passed, self.getGovProposal(id))
+	}
+	return passed
+}
+
+// OpenGovProposals returns the number of proposals in their deposit or
+// voting period.
+func (self *StateDB) OpenGovProposals() int {
+	return len(self.getGovernance().Open)
+}
+
+// GetEndingGovProposals returns the proposals whose deposit or voting period
+// ended by block number, by end of the period.
+func (self *StateDB) GetEndingGovProposals(number uint64) []*GovProposal {
+	gov := self.getGovernance()
+	var ending []*GovProposal
+	for _, id := range gov.Open {
+		proposal := self.getGovProposal(id)
+		if proposal.periodEnd() > number {
+			break
+		}
+		ending = append(ending, proposal)
+	}
+	return ending
+}
+
+// AddGovDeposit adds to the deposit of depositor on a proposal. The amount
+// must have been taken from the balance of the depositor.
+func (self *StateDB) AddGovDeposit(id uint64, depositor common.Address, amount *big.Int) {
+	proposal := self.GetGovProposal(id)
+	if proposal == nil {
+		return
+	}
+	self.journalGovProposal(id)
+	for _, deposit := range proposal.Deposits {
+		if deposit.Depositor == depositor {
+			deposit.Amount = new(big.Int).Add(deposit.Amount, amount)
+			return
+		}
+	}
+	proposal.Deposits = append(proposal.Deposits, &GovDeposit{Depositor: depositor, Amount: new(big.Int).Set(amount)})
+}
+
+// StartGovVoting opens the voting period on a proposal at block number.
+func (self *StateDB) StartGovVoting(id, number uint64) {
+	if proposal := self.GetGovProposal(id); proposal != nil {
+		self.journalGovProposal(id)
+		proposal.Status = GovProposalVoting
+		proposal.VotingEnd = number + self.GovVotingPeriod()
+		self.openGovProposal(proposal)
+	}
+}
+
+// VoteGovProposal records the vote of voter, replacing an earlier one.
+func (self *StateDB) VoteGovProposal(id uint64, voter common.Address, approve bool) {
+	proposal := self.GetGovProposal(id)
+	if proposal == nil {
+		return
+	}
+	self.journalGovProposal(id)
+	for _, vote := range proposal.Votes {
+		if vote.Voter == voter {
+			vote.Approve = approve
+			return
+		}
+	}
+	proposal.Votes = append(proposal.Votes, &GovVote{Voter: voter, Approve: approve})
+}
+
+// SetGovProposalStatus moves a proposal along its lifecycle. The parameter
+// changes passed are indexed until applied.
+func (self *StateDB) SetGovProposalStatus(id uint64, status uint8) {
+	if proposal := self.GetGovProposal(id); proposal != nil {
+		self.journalGovProposal(id)
+		proposal.Status = status
+		gov := self.getGovernance()
+		if status != GovProposalDeposit && status != GovProposalVoting {
+			gov.Open = removeGovProposalId(gov.Open, id)
+		}
+		gov.Passed = removeGovProposalId(gov.Passed, id)
+		if status == GovProposalPassed && !proposal.IsText() {
+			gov.Passed = append(gov.Passed, id)
+		}
+	}
+}
+
+// GetGovParam returns the value governance set for the parameter in effect
+// at the current epoch, nil if none.
+func (self *StateDB) GetGovParam(name string) *big.Int {
+	gov := self.getGovernance()
+	for _, param := range gov.Params {
+		if param.Name == name {
+			return param.value(gov.Epoch)
+		}
+	}
+	return nil
+}
+
+// GetGovParams returns the values governance set in effect at the current
+// epoch, by parameter name.
+func (self *StateDB) GetGovParams() map[string]*big.Int {
+	gov := self.getGovernance()
+	params := make(map[string]*big.Int)
+	for _, param := range gov.Params {
+		if value := param.value(gov.Epoch); value != nil {
+			params[param.Name] = value
+		}
+	}
+	return params
+}
+
+// GetGovParamChanges returns the values governance set for the parameter,
+// oldest first.
+func (self *StateDB) GetGovParamChanges(name string) []*GovParamChange {
+	for _, param := range self.getGovernance().Params {
+		if param.Name == name {
+			return param.Changes
+		}
+	}
+	return nil
+}
+
+// GovEpoch returns the epoch the governance parameter changes are in effect
+// for.
+func (self *StateDB) GovEpoch() uint64 {
+	return self.getGovernance().Epoch
+}
+
+// SetGovEpoch puts in effect the governance parameter changes applying from
+// the epoch.
+func (self *StateDB) SetGovEpoch(epoch uint64) {
+	gov := self.getGovernance()
+	if gov.Epoch == epoch {
+		return
+	}
+	self.journal = append(self.journal, govEpochChange{prev: gov.Epoch, prevDirty: self.govDirty})
+	self.govDirty = true
+	gov.Epoch = epoch
+}
+
+// SetGovParam sets the value of a governed parameter from the given epoch, as
+// decided by the proposal.
+func (self *StateDB) SetGovParam(name string, value *big.Int, proposalId, epoch uint64) {
+	gov := self.getGovernance()
+	self.journal = append(self.journal, govParamChange{name: name, prevDirty: self.govDirty})
+	self.govDirty = true
+	change := &GovParamChange{Value: new(big.Int).Set(value), ProposalId: proposalId, Epoch: epoch}
+	for _, param := range gov.Params {
+		if param.Name == name {
+			param.Changes = append(param.Changes, change)
+			return
+		}
+	}
+	gov.Params = append(gov.Params, &GovParam{Name: name, Changes: []*GovParamChange{change}})
+	sort.Slice(gov.Params, func(i, j int) bool {
+		return gov.Params[i].Name < gov.Params[j].Name
+	})
+}
+
+func (self *StateDB) govParamOrDefault(name string, def uint64) uint64 {
+	if value := self.GetGovParam(name); value != nil {
+		return value.Uint64()
+	}
+	return def
+}
+
+// GovMinDeposit returns the deposit opening the voting on a proposal, in wei.
+func (self *StateDB) GovMinDeposit() *big.Int {
+	neat := self.govParamOrDefault(GovParamMinDeposit, DefaultGovMinDeposit)
+	return new(big.Int).Mul(new(big.Int).SetUint64(neat), big.NewInt(1e18))
+}
+
+// GovDepositPeriod returns the blocks a proposal has to reach the minimum deposit.
+func (self *StateDB) GovDepositPeriod() uint64 {
+	return self.govParamOrDefault(GovParamDepositPeriod, DefaultGovDepositPeriod)
+}
+
+// GovVotingPeriod returns the blocks a proposal is voted on.
+func (self *StateDB) GovVotingPeriod() uint64 {
+	return self.govParamOrDefault(GovParamVotingPeriod, DefaultGovVotingPeriod)
+}
+
+// MinBlockInterval returns the minimum time between the consensus times of
+// consecutive blocks, in milliseconds, 0 if not limited.
+func (self *StateDB) MinBlockInterval() uint64 {
+	return self.govParamOrDefault(GovParamMinBlockInterval, 0)
+}
+
+// MaxCommission returns the highest commission a candidate can set.
+func (self *StateDB) MaxCommission() uint8 {
+	return uint8(self.govParamOrDefault(GovParamMaxCommission, DefaultMaxCommission))
+}
+
+// MaxCommissionChange returns how much a candidate can change its commission
+// from an epoch to the next.
+func (self *StateDB) MaxCommissionChange() uint8 {
+	return uint8(self.govParamOrDefault(GovParamMaxCommissionChange, DefaultMaxCommissionChange))
+}
+
+// CrossChainFee returns the fee of a cross chain transfer of amount, and the
+// part of it paid to the relayer.
+func (self *StateDB) CrossChainFee(amount *big.Int) (*big.Int, *big.Int) {
+	rate := self.govParamOrDefault(GovParamCrossChainFeeRate, DefaultCrossChainFeeRate)
+	fee := new(big.Int).Mul(amount, new(big.Int).SetUint64(rate))
+	fee.Div(fee, big.NewInt(10000))
+
+	share := self.govParamOrDefault(GovParamCrossChainRelayerFee, DefaultCrossChainRelayerFee)
+	relayerFee := new(big.Int).Mul(fee, new(big.Int).SetUint64(share))
+	relayerFee.Div(relayerFee, big.NewInt(100))
+	return fee, relayerFee
+}
+
+// CrossChainMinAmount returns the smallest cross chain transfer, in wei.
+func (self *StateDB) CrossChainMinAmount() *big.Int {
+	neat := self.govParamOrDefault(GovParamCrossChainMinAmount, DefaultCrossChainMinAmount)
+	return new(big.Int).Mul(new(big.Int).SetUint64(neat), big.NewInt(1e18))
+}
+
+// MaxSideChains returns the most side chains a validator may secure at once,
+// 0 if not limited.
+func (self *StateDB) MaxSideChains() uint64 {
+	return self.govParamOrDefault(GovParamMaxSideChains, 0)
+}
+
+func (gov *governance) copy() *governance {
+	if gov == nil {
+		return nil
+	}
+	cpy := &governance{
+		Count:  gov.Count,
+		Params: make([]*GovParam, len(gov.Params)),
+		Open:   append([]uint64(nil), gov.Open...),
+		Passed: append([]uint64(nil), gov.Passed...),
+		Epoch:  gov.Epoch,
+	}
+	if gov.Treasury.Received != nil {
+		cpy.Treasury = *gov.Treasury.copy()
+	}
+	for i, param := range gov.Params {
+		changes := make([]*GovParamChange, len(param.Changes))
+		for j, change := range param.Changes {
+			c := *change
+			c.Value = new(big.Int).Set(change.Value)
+			changes[j] = &c
+		}
+		cpy.Params[i] = &GovParam{Name: param.Name, Changes: changes}
+	}
+	return cpy
+}
+
+func copyGovProposals(proposals map[uint64]*GovProposal) map[uint64]*GovProposal {
+	if proposals == nil {
+		return nil
+	}
+	cpy := make(map[uint64]*GovProposal, len(proposals))
+	for id, proposal := range proposals {
+		if proposal == nil {
+			cpy[id] = nil
+			continue
+		}
+		cpy[id] = proposal.copy()
+	}
+	return cpy
+}
+
+func (p *GovProposal) copy() *GovProposal {
+	cpy := *p
+	cpy.Value = new(big.Int).Set(p.Value)
+	cpy.Deposits = make([]*GovDeposit, len(p.Deposits))
+	for i, deposit := range p.Deposits {
+		cpy.Deposits[i] = &GovDeposit{Depositor: deposit.Depositor, Amount: new(big.Int).Set(deposit.Amount)}
+	}
+	cpy.Votes = make([]*GovVote, len(p.Votes))
+	for i, vote := range p.Votes {
+		v := *vote
+		cpy.Votes[i] = &v
+	}
+	return &cpy
+}
@@ -0,0 +1,108 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- key rotation Set
+
+// MarkAddressKeyRotation records that the candidate has rotated its consensus key
+// and the new key should be picked up at the next epoch switch
+func (self *StateDB) MarkAddressKeyRotation(addr common.Address) {
+	if _, exist := self.GetKeyRotationSet()[addr]; !exist {
+		self.journalKeyRotationSet()
+		self.keyRotationSet[addr] = struct{}{}
+	}
+}
+
+// journalKeyRotationSet journals a copy of the key rotation set before it
+// changes in place.
+func (self *StateDB) journalKeyRotationSet() {
+	prev := make(KeyRotationSet, len(self.keyRotationSet))
+	for addr := range self.keyRotationSet {
+		prev[addr] = struct{}{}
+	}
+	self.journal = append(self.journal, keyRotationChange{
+		prev:      prev,
+		prevDirty: self.keyRotationSetDirty,
+	})
+	self.keyRotationSetDirty = true
+}
+
+func (self *StateDB) GetKeyRotationSet() KeyRotationSet {
+	// A set emptied by ClearKeyRotationSet is dirty until committed
+	if len(self.keyRotationSet) != 0 || self.keyRotationSetDirty {
+		return self.keyRotationSet
+	}
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(keyRotationSetKey)
+	if err != nil {
+		self.setError(err)
+		return nil
+	}
+	var value KeyRotationSet
+	if len(enc) > 0 {
+		err := rlp.DecodeBytes(enc, &value)
+		if err != nil {
+			self.setError(err)
+		}
+		self.keyRotationSet = value
+	}
+	return value
+}
+
+func (self *StateDB) commitKeyRotationSet() {
+	if len(self.keyRotationSet) == 0 {
+		self.setError(self.trie.TryDelete(keyRotationSetKey))
+		return
+	}
+	data, err := rlp.EncodeToBytes(self.keyRotationSet)
+	if err != nil {
+		panic(fmt.Errorf("can't encode key rotation set : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(keyRotationSetKey, data))
+}
+
+// ClearKeyRotationSet empties the set once the rotated keys were switched, the
+// set being deleted from the trie at the commit.
+func (self *StateDB) ClearKeyRotationSet() {
+	self.GetKeyRotationSet()
+	self.journalKeyRotationSet()
+	self.keyRotationSet = make(KeyRotationSet)
+}
+
+// Store the Key Rotation Address Set
+
+var keyRotationSetKey = []byte("KeyRotationSet")
+
+type KeyRotationSet map[common.Address]struct{}
+
+func (set KeyRotationSet) EncodeRLP(w io.Writer) error {
+	var list []common.Address
+	for addr := range set {
+		list = append(list, addr)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Bytes(), list[j].Bytes()) == 1
+	})
+	return rlp.Encode(w, list)
+}
+
+func (set *KeyRotationSet) DecodeRLP(s *rlp.Stream) error {
+	var list []common.Address
+	if err := s.Decode(&list); err != nil {
+		return err
+	}
+	keyRotationSet := make(KeyRotationSet, len(list))
+	for _, addr := range list {
+		keyRotationSet[addr] = struct{}{}
+	}
+	*set = keyRotationSet
+	return nil
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ----- Side chain retirement
+
+// GovParamRetirePrefix prefixes the governance parameters retiring a side
+// chain; the value of retire_side_chain:<chainId> is the main chain height from
+// which the side chain is frozen.
+const GovParamRetirePrefix = "retire_side_chain:"
+
+// DefaultSideChainGracePeriod is the number of main chain blocks a frozen side
+// chain keeps running for its users to withdraw to the main chain.
+const DefaultSideChainGracePeriod = 604800
+
+const maxSideChainIdLength = 30
+
+// RetireParam returns the governance parameter retiring the side chain.
+func RetireParam(chainId string) string {
+	return GovParamRetirePrefix + chainId
+}
+
+func validateSideChainId(chainId string) error {
+	if chainId == "" || len(chainId) > maxSideChainIdLength {
+		return fmt.Errorf("side chain id must have 1 to %d characters", maxSideChainIdLength)
+	}
+	for _, c := range chainId {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_') {
+			return fmt.Errorf("invalid character %q in side chain id", c)
+		}
+	}
+	return nil
+}
+
+// SideChainGracePeriod returns the number of main chain blocks between the
+// freeze and the end of a retired side chain.
+func (self *StateDB) SideChainGracePeriod() uint64 {
+	return self.govParamOrDefault(GovParamSideChainGracePeriod, DefaultSideChainGracePeriod)
+}
+
+// SideChainRetirement returns the main chain heights from which the side chain
+// is frozen, accepting only the withdrawals to the main chain, and retired,
+// producing no block anymore. It reports false if the side chain is not
+// retired by governance.
+func (self *StateDB) SideChainRetirement(chainId string) (uint64, uint64, bool) {
+	freeze := self.GetGovParam(RetireParam(chainId))
+	if freeze == nil {
+		return 0, 0, false
+	}
+	return freeze.Uint64(), freeze.Uint64() + self.SideChainGracePeriod(), true
+}
+
+// GetSideChainRetirements returns the freeze heights of the side chains retired
+// by governance, by side chain id.
+func (self *StateDB) GetSideChainRetirements() map[string]uint64 {
+	retirements := make(map[string]uint64)
+	gov := self.getGovernance()
+	for _, param := range gov.Params {
+		if strings.HasPrefix(param.Name, GovParamRetirePrefix) {
+			if value := param.value(gov.Epoch); value != nil {
+				retirements[strings.TrimPrefix(param.Name, GovParamRetirePrefix)] = value.Uint64()
+			}
+		}
+	}
+	return retirements
+}
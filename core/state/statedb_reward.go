@@ -156,10 +156,12 @@ func (db *StateDB) ForEachReward(addr common.Address, cb func(key common.Address
 	if so == nil {
 		return
 	}
+	seen := make(map[common.Address]struct{})
 	it := trie.NewIterator(so.getRewardTrie(db.db).NodeIterator(nil))
 	for it.Next() {
 		var key common.Address
 		rlp.DecodeBytes(db.trie.GetKey(it.Key), &key)
+		seen[key] = struct{}{}
 		if value, dirty := so.dirtyReward[key]; dirty {
 			cb(key, value)
 			continue
@@ -168,6 +170,12 @@ func (db *StateDB) ForEachReward(addr common.Address, cb func(key common.Address
 		rlp.DecodeBytes(it.Value, &value)
 		cb(key, &value)
 	}
+	// Rewards not committed to the trie yet
+	for key, value := range so.dirtyReward {
+		if _, exist := seen[key]; !exist {
+			cb(key, value)
+		}
+	}
 }
 
 // ----- Reward Set
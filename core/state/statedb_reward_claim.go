@@ -0,0 +1,112 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Reward Claims
+
+// RewardClaim records the rewards an account withdrew from its claimable
+// reward balances to its spendable balance.
+type RewardClaim struct {
+	Claimed   *big.Int // total amount withdrawn
+	Count     uint64   // number of withdrawals
+	LastBlock uint64   // block of the last withdrawal
+}
+
+var rewardClaimPrefix = []byte("RewardClaim")
+
+func rewardClaimKey(addr common.Address) []byte {
+	return append(append([]byte(nil), rewardClaimPrefix...), addr[:]...)
+}
+
+func (self *StateDB) getRewardClaim(addr common.Address) *RewardClaim {
+	if claim, exist := self.rewardClaims[addr]; exist {
+		return claim
+	}
+	if self.rewardClaims == nil {
+		self.rewardClaims = make(map[common.Address]*RewardClaim)
+	}
+	claim := &RewardClaim{Claimed: new(big.Int)}
+	self.rewardClaims[addr] = claim
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(rewardClaimKey(addr))
+	if err != nil {
+		self.setError(err)
+		return claim
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, claim); err != nil {
+			self.setError(err)
+		}
+	}
+	return claim
+}
+
+func (self *StateDB) commitRewardClaims() {
+	for addr := range self.rewardClaimsDirty {
+		data, err := rlp.EncodeToBytes(self.rewardClaims[addr])
+		if err != nil {
+			panic(fmt.Errorf("can't encode reward claim at %x: %v", addr[:], err))
+		}
+		self.setError(self.trie.TryUpdate(rewardClaimKey(addr), data))
+	}
+}
+
+// WithdrawRewardBalance moves amount from the reward addr earned with
+// delegateAddress to its balance.
+func (self *StateDB) WithdrawRewardBalance(addr, delegateAddress common.Address, amount *big.Int) {
+	self.SubRewardBalanceByDelegateAddress(addr, delegateAddress, amount)
+	self.AddBalance(addr, amount)
+}
+
+// RecordRewardClaim adds the withdrawal of amount by addr at block number to
+// the rewards it withdrew, journaling the change.
+func (self *StateDB) RecordRewardClaim(addr common.Address, amount *big.Int, number uint64) {
+	prev := self.getRewardClaim(addr)
+	_, prevDirty := self.rewardClaimsDirty[addr]
+	self.journal = append(self.journal, rewardClaimChange{
+		account:   &addr,
+		prev:      prev,
+		prevDirty: prevDirty,
+	})
+	self.rewardClaims[addr] = &RewardClaim{
+		Claimed:   new(big.Int).Add(prev.Claimed, amount),
+		Count:     prev.Count + 1,
+		LastBlock: number,
+	}
+	if self.rewardClaimsDirty == nil {
+		self.rewardClaimsDirty = make(map[common.Address]struct{})
+	}
+	self.rewardClaimsDirty[addr] = struct{}{}
+}
+
+// GetRewardClaim returns the rewards addr withdrew so far.
+func (self *StateDB) GetRewardClaim(addr common.Address) RewardClaim {
+	claim := self.getRewardClaim(addr)
+	return RewardClaim{
+		Claimed:   new(big.Int).Set(claim.Claimed),
+		Count:     claim.Count,
+		LastBlock: claim.LastBlock,
+	}
+}
+
+func copyRewardClaims(claims map[common.Address]*RewardClaim) map[common.Address]*RewardClaim {
+	if claims == nil {
+		return nil
+	}
+	cpy := make(map[common.Address]*RewardClaim, len(claims))
+	for addr, claim := range claims {
+		cpy[addr] = &RewardClaim{
+			Claimed:   new(big.Int).Set(claim.Claimed),
+			Count:     claim.Count,
+			LastBlock: claim.LastBlock,
+		}
+	}
+	return cpy
+}
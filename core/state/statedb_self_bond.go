@@ -0,0 +1,100 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Self Bond
+
+var minSelfBondPrefix = []byte("MinSelfBond")
+
+func minSelfBondKey(addr common.Address) []byte {
+	return append(append([]byte(nil), minSelfBondPrefix...), addr[:]...)
+}
+
+func (self *StateDB) getMinSelfBond(addr common.Address) *big.Int {
+	if amount, exist := self.minSelfBonds[addr]; exist {
+		return amount
+	}
+	if self.minSelfBonds == nil {
+		self.minSelfBonds = make(map[common.Address]*big.Int)
+	}
+	amount := new(big.Int)
+	self.minSelfBonds[addr] = amount
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(minSelfBondKey(addr))
+	if err != nil {
+		self.setError(err)
+		return amount
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, amount); err != nil {
+			self.setError(err)
+		}
+	}
+	return amount
+}
+
+func (self *StateDB) commitMinSelfBonds() {
+	for addr := range self.minSelfBondsDirty {
+		data, err := rlp.EncodeToBytes(self.minSelfBonds[addr])
+		if err != nil {
+			panic(fmt.Errorf("can't encode min self bond at %x: %v", addr[:], err))
+		}
+		self.setError(self.trie.TryUpdate(minSelfBondKey(addr), data))
+	}
+}
+
+// GetMinSelfBond returns the stake the candidate committed to keep bonded
+// itself, or 0 if it did not set any.
+func (self *StateDB) GetMinSelfBond(addr common.Address) *big.Int {
+	return new(big.Int).Set(self.getMinSelfBond(addr))
+}
+
+// SetMinSelfBond sets the stake the candidate commits to keep bonded itself,
+// journaling the change.
+func (self *StateDB) SetMinSelfBond(addr common.Address, amount *big.Int) {
+	prev := self.getMinSelfBond(addr)
+	_, prevDirty := self.minSelfBondsDirty[addr]
+	self.journal = append(self.journal, minSelfBondChange{
+		account:   &addr,
+		prev:      prev,
+		prevDirty: prevDirty,
+	})
+	self.minSelfBonds[addr] = new(big.Int).Set(amount)
+	if self.minSelfBondsDirty == nil {
+		self.minSelfBondsDirty = make(map[common.Address]struct{})
+	}
+	self.minSelfBondsDirty[addr] = struct{}{}
+}
+
+// GetSelfBond returns the stake the candidate bonded itself: its deposit plus
+// what it delegated to itself and is not being refunded.
+func (self *StateDB) GetSelfBond(addr common.Address) *big.Int {
+	bond := new(big.Int).Add(self.GetDepositBalance(addr), self.GetProxiedBalanceByUser(addr, addr))
+	bond.Add(bond, self.GetDepositProxiedBalanceByUser(addr, addr))
+	return bond.Sub(bond, self.GetPendingRefundBalanceByUser(addr, addr))
+}
+
+// IsBelowMinSelfBond returns whether the candidate bonded itself less than it
+// committed to.
+func (self *StateDB) IsBelowMinSelfBond(addr common.Address) bool {
+	min := self.getMinSelfBond(addr)
+	return min.Sign() > 0 && self.GetSelfBond(addr).Cmp(min) < 0
+}
+
+func copyMinSelfBonds(bonds map[common.Address]*big.Int) map[common.Address]*big.Int {
+	if bonds == nil {
+		return nil
+	}
+	cpy := make(map[common.Address]*big.Int, len(bonds))
+	for addr, amount := range bonds {
+		cpy[addr] = new(big.Int).Set(amount)
+	}
+	return cpy
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Side Chain Validators
+
+// The side chains joined by a validator are kept with the bridge entries, so
+// they are journaled and reverted with the transaction joining them.
+func validatorSideChainsKey(addr common.Address) common.Hash {
+	return bridgeEntryKey("validator side chains", addr[:])
+}
+
+func (self *StateDB) getJoinedSideChains(addr common.Address) []string {
+	enc := self.getBridgeEntry(validatorSideChainsKey(addr))
+	if len(enc) == 0 {
+		return nil
+	}
+	var chains []string
+	if err := rlp.DecodeBytes(enc, &chains); err != nil {
+		self.setError(err)
+		return nil
+	}
+	return chains
+}
+
+// GetValidatorSideChains returns the side chains the validator joined on the
+// main chain, the ones retired by governance excepted.
+func (self *StateDB) GetValidatorSideChains(addr common.Address) []string {
+	chains := make([]string, 0)
+	for _, chainId := range self.getJoinedSideChains(addr) {
+		if _, _, retired := self.SideChainRetirement(chainId); !retired {
+			chains = append(chains, chainId)
+		}
+	}
+	return chains
+}
+
+// AddValidatorSideChain records the validator joined the side chain.
+func (self *StateDB) AddValidatorSideChain(addr common.Address, chainId string) {
+	chains := self.getJoinedSideChains(addr)
+	for _, id := range chains {
+		if id == chainId {
+			return
+		}
+	}
+	data, err := rlp.EncodeToBytes(append(chains, chainId))
+	if err != nil {
+		panic(fmt.Errorf("can't encode side chains of %x: %v", addr[:], err))
+	}
+	self.setBridgeEntry(validatorSideChainsKey(addr), data)
+}
+
+func sideChainKey(chainId string) common.Hash {
+	return bridgeEntryKey("side chain", []byte(chainId))
+}
+
+// IsSideChain returns whether the side chain was launched by the main chain
+// since the asset registry fork.
+func (self *StateDB) IsSideChain(chainId string) bool {
+	return len(self.getBridgeEntry(sideChainKey(chainId))) > 0
+}
+
+// AddSideChain records the launch of the side chain by the main chain.
+func (self *StateDB) AddSideChain(chainId string) {
+	self.setBridgeEntry(sideChainKey(chainId), []byte{1})
+}
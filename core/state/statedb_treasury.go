@@ -0,0 +1,105 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+)
+
+// ----- Treasury
+
+// TreasuryAddr holds the community treasury, funded by a share of the block
+// rewards and spent by governance proposals only.
+var TreasuryAddr = common.StringToAddress("NEATDDDDDDDDDDDDDDDDDDDDDDDDDDDD")
+
+// TreasurySpend is a payment out of the treasury.
+type TreasurySpend struct {
+	ProposalId uint64
+	Recipient  common.Address
+	Amount     *big.Int
+	Number     uint64 // Block of the payment
+}
+
+// treasury is the accounting of the treasury, which may also receive plain
+// transfers: its balance is not Received - Spent.
+type treasury struct {
+	Received *big.Int // Block reward shares
+	Spent    *big.Int
+	Spends   []*TreasurySpend
+}
+
+func (t *treasury) copy() *treasury {
+	cpy := &treasury{
+		Received: new(big.Int).Set(t.Received),
+		Spent:    new(big.Int).Set(t.Spent),
+		Spends:   make([]*TreasurySpend, len(t.Spends)),
+	}
+	for i, spend := range t.Spends {
+		s := *spend
+		s.Amount = new(big.Int).Set(spend.Amount)
+		cpy.Spends[i] = &s
+	}
+	return cpy
+}
+
+func (self *StateDB) getTreasury() *treasury {
+	gov := self.getGovernance()
+	if gov.Treasury.Received == nil {
+		gov.Treasury.Received = new(big.Int)
+	}
+	if gov.Treasury.Spent == nil {
+		gov.Treasury.Spent = new(big.Int)
+	}
+	return &gov.Treasury
+}
+
+// journalTreasury journals the accounting of the treasury before it changes.
+// The amounts are replaced, not set, and the payments only appended to.
+func (self *StateDB) journalTreasury() {
+	t := self.getTreasury()
+	self.journal = append(self.journal, treasuryChange{
+		prevReceived: t.Received,
+		prevSpent:    t.Spent,
+		prevSpends:   len(t.Spends),
+		prevDirty:    self.govDirty,
+	})
+	self.govDirty = true
+}
+
+// FundTreasury moves a share of the block reward to the treasury.
+func (self *StateDB) FundTreasury(amount *big.Int) {
+	if amount.Sign() <= 0 {
+		return
+	}
+	t := self.getTreasury()
+	self.AddBalance(TreasuryAddr, amount)
+	self.journalTreasury()
+	t.Received = new(big.Int).Add(t.Received, amount)
+}
+
+// SpendTreasury pays amount out of the treasury to recipient, as decided by
+// the proposal. It reports false if the treasury can not afford it.
+func (self *StateDB) SpendTreasury(proposalId uint64, recipient common.Address, amount *big.Int, number uint64) bool {
+	if self.GetBalance(TreasuryAddr).Cmp(amount) < 0 {
+		return false
+	}
+	t := self.getTreasury()
+	self.SubBalance(TreasuryAddr, amount)
+	self.AddBalance(recipient, amount)
+	self.journalTreasury()
+	t.Spent = new(big.Int).Add(t.Spent, amount)
+	t.Spends = append(t.Spends, &TreasurySpend{
+		ProposalId: proposalId,
+		Recipient:  recipient,
+		Amount:     new(big.Int).Set(amount),
+		Number:     number,
+	})
+	return true
+}
+
+// GetTreasuryAccounting returns the block reward shares received by the
+// treasury, what it spent, and its payments.
+func (self *StateDB) GetTreasuryAccounting() (received, spent *big.Int, spends []*TreasurySpend) {
+	t := self.getTreasury()
+	return new(big.Int).Set(t.Received), new(big.Int).Set(t.Spent), t.Spends
+}
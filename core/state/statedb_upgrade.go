@@ -0,0 +1,147 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Upgrades
+
+// GovParamUpgradePrefix prefixes the governance parameters scheduling an
+// upgrade; the value of upgrade:<name> is the activation height.
+const GovParamUpgradePrefix = "upgrade:"
+
+const maxUpgradeNameLength = 32
+
+// UpgradeParam returns the governance parameter scheduling the named upgrade.
+func UpgradeParam(name string) string {
+	return GovParamUpgradePrefix + name
+}
+
+func validateUpgradeName(name string) error {
+	if name == "" || len(name) > maxUpgradeNameLength {
+		return fmt.Errorf("upgrade name must have 1 to %d characters", maxUpgradeNameLength)
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return fmt.Errorf("invalid character %q in upgrade name", c)
+		}
+	}
+	return nil
+}
+
+// GetUpgrades returns the activation heights of the upgrades scheduled by
+// governance, by upgrade name.
+func (self *StateDB) GetUpgrades() map[string]uint64 {
+	upgrades := make(map[string]uint64)
+	gov := self.getGovernance()
+	for _, param := range gov.Params {
+		if strings.HasPrefix(param.Name, GovParamUpgradePrefix) {
+			if value := param.value(gov.Epoch); value != nil {
+				upgrades[strings.TrimPrefix(param.Name, GovParamUpgradePrefix)] = value.Uint64()
+			}
+		}
+	}
+	return upgrades
+}
+
+// UpgradeSignal is the set of validators whose node runs a release
+// supporting an upgrade.
+type UpgradeSignal struct {
+	Name       string
+	Validators []common.Address
+}
+
+type UpgradeSignals []*UpgradeSignal
+
+var upgradeSignalsKey = []byte("UpgradeSignals")
+
+func (self *StateDB) getUpgradeSignals() UpgradeSignals {
+	if self.upgradeSignals != nil {
+		return self.upgradeSignals
+	}
+	self.upgradeSignals = UpgradeSignals{}
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(upgradeSignalsKey)
+	if err != nil {
+		self.setError(err)
+		return self.upgradeSignals
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.upgradeSignals); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.upgradeSignals
+}
+
+func (self *StateDB) commitUpgradeSignals() {
+	data, err := rlp.EncodeToBytes(self.upgradeSignals)
+	if err != nil {
+		panic(fmt.Errorf("can't encode upgrade signals : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(upgradeSignalsKey, data))
+}
+
+// SignalUpgrade records that the node of validator is ready for the upgrade.
+func (self *StateDB) SignalUpgrade(name string, validator common.Address) {
+	signals := self.getUpgradeSignals()
+	for _, signal := range signals {
+		if signal.Name != name {
+			continue
+		}
+		for _, addr := range signal.Validators {
+			if addr == validator {
+				return
+			}
+		}
+		self.journalUpgradeSignals()
+		signal.Validators = append(signal.Validators, validator)
+		return
+	}
+	self.journalUpgradeSignals()
+	signals = append(signals, &UpgradeSignal{Name: name, Validators: []common.Address{validator}})
+	sort.Slice(signals, func(i, j int) bool {
+		return signals[i].Name < signals[j].Name
+	})
+	self.upgradeSignals = signals
+}
+
+// journalUpgradeSignals journals a copy of the upgrade signals before they
+// change in place.
+func (self *StateDB) journalUpgradeSignals() {
+	self.journal = append(self.journal, upgradeSignalsChange{
+		prev:      self.getUpgradeSignals().copy(),
+		prevDirty: self.upgradeSignalsDirty,
+	})
+	self.upgradeSignalsDirty = true
+}
+
+// GetUpgradeSignals returns the validators ready for the upgrade.
+func (self *StateDB) GetUpgradeSignals(name string) []common.Address {
+	for _, signal := range self.getUpgradeSignals() {
+		if signal.Name == name {
+			return signal.Validators
+		}
+	}
+	return nil
+}
+
+func (signals UpgradeSignals) copy() UpgradeSignals {
+	if signals == nil {
+		return nil
+	}
+	cpy := make(UpgradeSignals, len(signals))
+	for i, signal := range signals {
+		cpy[i] = &UpgradeSignal{
+			Name:       signal.Name,
+			Validators: append([]common.Address(nil), signal.Validators...),
+		}
+	}
+	return cpy
+}
@@ -0,0 +1,106 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Validator Metadata
+
+// ValidatorMetadata is the human-readable identity a validator publishes
+// for explorers and staking interfaces.
+type ValidatorMetadata struct {
+	Moniker         string
+	Website         string
+	Identity        string
+	Details         string
+	SecurityContact string
+}
+
+var validatorMetadataPrefix = []byte("ValidatorMetadata")
+
+func validatorMetadataKey(addr common.Address) []byte {
+	return append(append([]byte(nil), validatorMetadataPrefix...), addr[:]...)
+}
+
+func (self *StateDB) getValidatorMetadata(addr common.Address) *ValidatorMetadata {
+	if metadata, exist := self.validatorMetadata[addr]; exist {
+		return metadata
+	}
+	if self.validatorMetadata == nil {
+		self.validatorMetadata = make(map[common.Address]*ValidatorMetadata)
+	}
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(validatorMetadataKey(addr))
+	if err != nil {
+		self.setError(err)
+		return nil
+	}
+	var metadata *ValidatorMetadata
+	if len(enc) > 0 {
+		metadata = new(ValidatorMetadata)
+		if err := rlp.DecodeBytes(enc, metadata); err != nil {
+			self.setError(err)
+		}
+	}
+	self.validatorMetadata[addr] = metadata
+	return metadata
+}
+
+func (self *StateDB) commitValidatorMetadata() {
+	for addr := range self.validatorMetadataDirty {
+		data, err := rlp.EncodeToBytes(self.validatorMetadata[addr])
+		if err != nil {
+			panic(fmt.Errorf("can't encode validator metadata at %x: %v", addr[:], err))
+		}
+		self.setError(self.trie.TryUpdate(validatorMetadataKey(addr), data))
+	}
+}
+
+// GetValidatorMetadata returns the metadata the validator published, or nil
+// if it did not publish any.
+func (self *StateDB) GetValidatorMetadata(addr common.Address) *ValidatorMetadata {
+	metadata := self.getValidatorMetadata(addr)
+	if metadata == nil {
+		return nil
+	}
+	cpy := *metadata
+	return &cpy
+}
+
+// SetValidatorMetadata replaces the metadata of the validator, journaling the
+// change.
+func (self *StateDB) SetValidatorMetadata(addr common.Address, metadata *ValidatorMetadata) {
+	prev := self.getValidatorMetadata(addr)
+	_, prevDirty := self.validatorMetadataDirty[addr]
+	self.journal = append(self.journal, validatorMetadataChange{
+		account:   &addr,
+		prev:      prev,
+		prevDirty: prevDirty,
+	})
+	cpy := *metadata
+	self.validatorMetadata[addr] = &cpy
+	if self.validatorMetadataDirty == nil {
+		self.validatorMetadataDirty = make(map[common.Address]struct{})
+	}
+	self.validatorMetadataDirty[addr] = struct{}{}
+}
+
+func copyValidatorMetadata(metadata map[common.Address]*ValidatorMetadata) map[common.Address]*ValidatorMetadata {
+	if metadata == nil {
+		return nil
+	}
+	cpy := make(map[common.Address]*ValidatorMetadata, len(metadata))
+	for addr, m := range metadata {
+		if m == nil {
+			cpy[addr] = nil
+			continue
+		}
+		c := *m
+		cpy[addr] = &c
+	}
+	return cpy
+}
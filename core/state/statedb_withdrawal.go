@@ -0,0 +1,156 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// ----- Pending Withdrawals
+
+// PendingWithdrawal is a withdrawal from a side chain redeemed on the main
+// chain by a TX4, held until the end of its challenge window. Anyone proving
+// the side chain block of its TX3 was contradicted by another commit at its
+// height cancels it before Release.
+type PendingWithdrawal struct {
+	TxHash  common.Hash // Hash of the TX3 on the side chain
+	From    common.Address
+	ChainId string
+	Amount  *big.Int
+	Release uint64 // Main chain block releasing the amount
+}
+
+type PendingWithdrawals []*PendingWithdrawal
+
+var pendingWithdrawalsKey = []byte("PendingWithdrawals")
+
+func (self *StateDB) getPendingWithdrawals() PendingWithdrawals {
+	if self.pendingWithdrawals != nil {
+		return self.pendingWithdrawals
+	}
+	self.pendingWithdrawals = PendingWithdrawals{}
+
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(pendingWithdrawalsKey)
+	if err != nil {
+		self.setError(err)
+		return self.pendingWithdrawals
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.pendingWithdrawals); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.pendingWithdrawals
+}
+
+func (self *StateDB) commitPendingWithdrawals() {
+	data, err := rlp.EncodeToBytes(self.pendingWithdrawals)
+	if err != nil {
+		panic(fmt.Errorf("can't encode pending withdrawals : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(pendingWithdrawalsKey, data))
+}
+
+// ChallengeWindow returns the number of main chain blocks a withdrawal from a
+// side chain is held for, 0 if released right away.
+func (self *StateDB) ChallengeWindow() uint64 {
+	return self.govParamOrDefault(GovParamChallengeWindow, 0)
+}
+
+// setPendingWithdrawals replaces the withdrawals held, journaling the change.
+// The slice of the withdrawals replaced must be left untouched.
+func (self *StateDB) setPendingWithdrawals(withdrawals PendingWithdrawals) {
+	self.journal = append(self.journal, pendingWithdrawalsChange{
+		prev:      self.getPendingWithdrawals(),
+		prevDirty: self.pendingWithdrawalsDirty,
+	})
+	self.pendingWithdrawals = withdrawals
+	self.pendingWithdrawalsDirty = true
+}
+
+// AddPendingWithdrawal holds the withdrawal until its release block. The
+// withdrawals are kept in the order of their release, the ones released at
+// the same block in the order they were added.
+func (self *StateDB) AddPendingWithdrawal(txHash common.Hash, from common.Address, chainId string, amount *big.Int, release uint64) {
+	withdrawals := self.getPendingWithdrawals()
+	i := sort.Search(len(withdrawals), func(i int) bool {
+		return withdrawals[i].Release > release
+	})
+	updated := make(PendingWithdrawals, 0, len(withdrawals)+1)
+	updated = append(updated, withdrawals[:i]...)
+	updated = append(updated, &PendingWithdrawal{
+		TxHash:  txHash,
+		From:    from,
+		ChainId: chainId,
+		Amount:  new(big.Int).Set(amount),
+		Release: release,
+	})
+	self.setPendingWithdrawals(append(updated, withdrawals[i:]...))
+}
+
+// GetPendingWithdrawal returns the withdrawal held for the TX3, or nil if it
+// has none.
+func (self *StateDB) GetPendingWithdrawal(txHash common.Hash) *PendingWithdrawal {
+	for _, withdrawal := range self.getPendingWithdrawals() {
+		if withdrawal.TxHash == txHash {
+			return withdrawal.copy()
+		}
+	}
+	return nil
+}
+
+// GetPendingWithdrawals returns the withdrawals held, in the order of their
+// release.
+func (self *StateDB) GetPendingWithdrawals() PendingWithdrawals {
+	return self.getPendingWithdrawals().copy()
+}
+
+// CancelPendingWithdrawal drops the withdrawal held for the TX3, and returns
+// it, or nil if it has none.
+func (self *StateDB) CancelPendingWithdrawal(txHash common.Hash) *PendingWithdrawal {
+	withdrawals := self.getPendingWithdrawals()
+	for i, withdrawal := range withdrawals {
+		if withdrawal.TxHash == txHash {
+			self.setPendingWithdrawals(append(withdrawals[:i:i], withdrawals[i+1:]...))
+			return withdrawal
+		}
+	}
+	return nil
+}
+
+// ReleasePendingWithdrawals drops the withdrawals due by block number, and
+// returns them. Paying them out is left to the caller.
+func (self *StateDB) ReleasePendingWithdrawals(number uint64) []*PendingWithdrawal {
+	withdrawals := self.getPendingWithdrawals()
+	due := 0
+	for due < len(withdrawals) && withdrawals[due].Release <= number {
+		due++
+	}
+	if due == 0 {
+		return nil
+	}
+	released := withdrawals[:due:due]
+	self.setPendingWithdrawals(append(PendingWithdrawals{}, withdrawals[due:]...))
+	return released
+}
+
+func (withdrawal *PendingWithdrawal) copy() *PendingWithdrawal {
+	cpy := *withdrawal
+	cpy.Amount = new(big.Int).Set(withdrawal.Amount)
+	return &cpy
+}
+
+func (withdrawals PendingWithdrawals) copy() PendingWithdrawals {
+	if withdrawals == nil {
+		return nil
+	}
+	cpy := make(PendingWithdrawals, len(withdrawals))
+	for i, withdrawal := range withdrawals {
+		cpy[i] = withdrawal.copy()
+	}
+	return cpy
+}
@@ -26,6 +26,13 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 	if err != nil {
 		return nil, 0, err
 	}
+	if err := checkReplayProtection(config, header.Number, tx); err != nil {
+		return nil, 0, err
+	}
+
+	if err := checkSideChainRetirement(config, cch, statedb, header.Number, header.MainChainNumber, tx); err != nil {
+		return nil, 0, err
+	}
 
 	if !neatabi.IsNeatChainContractAddr(tx.To()) {
 
@@ -97,6 +104,9 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 		} else if !config.IsMainChain() && !function.AllowInSideChain() {
 			return nil, 0, ErrNotAllowedInSideChain
 		}
+		if err := checkFunctionFork(config, function, header.Number); err != nil {
+			return nil, 0, err
+		}
 
 		from := msg.From()
 		// Make sure this transaction's nonce is correct
@@ -134,11 +144,11 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 			return nil, 0, fmt.Errorf("insufficient NEAT for tx amount (%x). Req %v, has %v", from.Bytes()[:4], tx.Value(), statedb.GetBalance(from))
 		}
 
-		if applyCb := GetApplyCb(function); applyCb != nil {
+		if applyCb := GetApplyCb(function); applyCb != nil && applyCbActive(config, function, header.Number) {
 			if function.IsCrossChainType() {
 				if fn, ok := applyCb.(CrossChainApplyCb); ok {
 					cch.GetMutex().Lock()
-					err := fn(tx, statedb, ops, cch, mining)
+					err := fn(tx, statedb, ops, cch, header, mining)
 					cch.GetMutex().Unlock()
 
 					if err != nil {
@@ -149,7 +159,7 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 				}
 			} else {
 				if fn, ok := applyCb.(NonCrossChainApplyCb); ok {
-					if err := fn(tx, statedb, bc, ops); err != nil {
+					if err := fn(tx, statedb, bc, header, ops); err != nil {
 						return nil, 0, err
 					}
 				} else {
@@ -158,6 +168,12 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 			}
 		}
 
+		if config.IsCrossChainFee(header.Number) {
+			if err := applyCrossChainTransfer(statedb, header, from, function, tx, totalUsedMoney); err != nil {
+				return nil, 0, err
+			}
+		}
+
 		// refund gas
 		remainingGas := gasLimit - gas
 		remaining := new(big.Int).Mul(new(big.Int).SetUint64(remainingGas), tx.GasPrice())
@@ -192,3 +208,13 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 		return receipt, 0, nil
 	}
 }
+
+// checkReplayProtection returns ErrUnprotectedTx for a transaction without
+// EIP-155 replay protection in a block past the side chain id fork. The
+// signer already rejects the transactions signed for another chain.
+func checkReplayProtection(config *params.ChainConfig, number *big.Int, tx *types.Transaction) error {
+	if config.IsSideChainId(number) && !tx.Protected() {
+		return ErrUnprotectedTx
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/params"
+)
+
+func TestCheckReplayProtection(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	config := &params.ChainConfig{ChainId: big.NewInt(1000), SideChainIdBlock: big.NewInt(100)}
+
+	tx := types.NewTransaction(0, common.BytesToAddress([]byte{0x01}), big.NewInt(1), 21000, big.NewInt(1), nil)
+	unprotected, err := types.SignTx(tx, types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	protected, err := types.SignTx(tx, types.NewEIP155Signer(config.ChainId), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		number int64
+		tx     *types.Transaction
+		want   error
+	}{
+		{99, unprotected, nil},
+		{99, protected, nil},
+		{100, unprotected, ErrUnprotectedTx},
+		{100, protected, nil},
+	}
+	for i, tt := range tests {
+		if err := checkReplayProtection(config, big.NewInt(tt.number), tt.tx); err != tt.want {
+			t.Errorf("test %d: have %v, want %v", i, err, tt.want)
+		}
+	}
+	if err := checkReplayProtection(&params.ChainConfig{}, big.NewInt(100), unprotected); err != nil {
+		t.Errorf("unprotected transaction rejected without the fork: %v", err)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/neatlab/neatio/core/types"
 	neatabi "github.com/neatlab/neatio/neatabi/abi"
 	"github.com/neatlab/neatio/neatcli"
+	"github.com/neatlab/neatio/params"
 	"github.com/neatlib/crypto-go"
 	dbm "github.com/neatlib/db-go"
 )
@@ -45,6 +46,14 @@ type CrossChainHelper interface {
 	GetHeightFromMainChain() *big.Int
 	GetEpochFromMainChain() (string, *epoch.Epoch)
 	GetTxFromMainChain(txHash common.Hash) *types.Transaction
+	// GetSideChainRetirement returns the main chain heights from which the side
+	// chain is frozen and retired, as scheduled in the state of the main chain
+	// block at number, which must be available.
+	GetSideChainRetirement(chainId string, number uint64) (uint64, uint64, bool, error)
+	// GetValidatorSideChains returns the side chains joined by the validator,
+	// and the most it may secure as set by the governance of the main chain, 0
+	// if not limited, both from the state of the main chain.
+	GetValidatorSideChains(addr common.Address) ([]string, uint64)
 
 	ChangeValidators(chainId string)
 
@@ -55,6 +64,10 @@ type CrossChainHelper interface {
 	TX3LocalCache
 	ValidateTX3ProofData(proofData *types.TX3ProofData) error
 	ValidateTX4WithInMemTX3ProofData(tx4 *types.Transaction, tx3ProofData *types.TX3ProofData) error
+	// ValidateWithdrawalChallenge checks the fraud proof against the withdrawal
+	// of a TX3: the block of the side chain including the TX3, and another
+	// block committed at its height.
+	ValidateWithdrawalChallenge(chainId string, txHash common.Hash, proofData *types.TX3ProofData, header *types.Header) error
 
 	////SaveDataToMainV1 acceps both epoch and tx3
 	//VerifySideChainProofDataV1(proofData *types.SideChainProofDataV1) error
@@ -63,11 +76,11 @@ type CrossChainHelper interface {
 
 // CrossChain Callback
 type CrossChainValidateCb = func(tx *types.Transaction, state *state.StateDB, cch CrossChainHelper) error
-type CrossChainApplyCb = func(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch CrossChainHelper, mining bool) error
+type CrossChainApplyCb = func(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch CrossChainHelper, header *types.Header, mining bool) error
 
 // Non-CrossChain Callback
 type NonCrossChainValidateCb = func(tx *types.Transaction, state *state.StateDB, bc *BlockChain) error
-type NonCrossChainApplyCb = func(tx *types.Transaction, state *state.StateDB, bc *BlockChain, ops *types.PendingOps) error
+type NonCrossChainApplyCb = func(tx *types.Transaction, state *state.StateDB, bc *BlockChain, header *types.Header, ops *types.PendingOps) error
 
 type EtdInsertBlockCb func(bc *BlockChain, block *types.Block)
 
@@ -118,6 +131,60 @@ func GetApplyCb(function neatabi.FunctionType) interface{} {
 	return nil
 }
 
+// checkFunctionFork rejects the chain functions added by a fork before the
+// block number reaches it.
+func checkFunctionFork(config *params.ChainConfig, function neatabi.FunctionType, number *big.Int) error {
+	switch function {
+	case neatabi.SubmitEthCheckpoint:
+		if !config.IsEthBridge(number) {
+			return ErrFunctionNotActive
+		}
+	case neatabi.RegisterAsset, neatabi.SetAssetContract:
+		if !config.IsAssetRegistry(number) {
+			return ErrFunctionNotActive
+		}
+	case neatabi.SubmitGovProposal, neatabi.DepositGovProposal, neatabi.VoteGovProposal, neatabi.SignalUpgrade:
+		if !config.IsGovernance(number) {
+			return ErrFunctionNotActive
+		}
+	case neatabi.SetSecurityContact:
+		if !config.IsValidatorMetadata(number) {
+			return ErrFunctionNotActive
+		}
+	case neatabi.SetAutoCompound:
+		if !config.IsAutoCompound(number) {
+			return ErrFunctionNotActive
+		}
+	case neatabi.SetMinSelfBond:
+		if !config.IsMinSelfBond(number) {
+			return ErrFunctionNotActive
+		}
+	case neatabi.CreateBridgeClient, neatabi.UpdateBridgeClient, neatabi.RecvBridgePacket, neatabi.SendBridgePacket, neatabi.AcknowledgeBridgePacket:
+		if !config.IsBridge(number) {
+			return ErrFunctionNotActive
+		}
+	case neatabi.RotateConsensusKey:
+		if !config.IsKeyRotation(number) {
+			return ErrFunctionNotActive
+		}
+	}
+	return nil
+}
+
+// applyCbActive returns whether the apply callback of the function runs in
+// the block at number, the callbacks added by a fork being skipped before it.
+func applyCbActive(config *params.ChainConfig, function neatabi.FunctionType, number *big.Int) bool {
+	switch function {
+	case neatabi.WithdrawFromMainChain:
+		return config.IsTX3Replay(number)
+	case neatabi.JoinSideChain:
+		return config.IsSideChainCapacity(number)
+	case neatabi.EditValidator:
+		return config.IsValidatorMetadata(number)
+	}
+	return true
+}
+
 func RegisterInsertBlockCb(name string, insertBlockCb EtdInsertBlockCb) error {
 
 	_, ok := insertBlockCbMap[name]
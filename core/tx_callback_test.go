@@ -0,0 +1,58 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+)
+
+func TestApplyCbActive(t *testing.T) {
+	config := &params.ChainConfig{TX3ReplayBlock: big.NewInt(100)}
+
+	if applyCbActive(config, neatabi.WithdrawFromMainChain, big.NewInt(99)) {
+		t.Error("TX3 recorded before the fork")
+	}
+	if !applyCbActive(config, neatabi.WithdrawFromMainChain, big.NewInt(100)) {
+		t.Error("TX3 not recorded from the fork")
+	}
+	config.SideChainCapacityBlock = big.NewInt(200)
+	if applyCbActive(config, neatabi.JoinSideChain, big.NewInt(199)) || !applyCbActive(config, neatabi.JoinSideChain, big.NewInt(200)) {
+		t.Error("side chain joins not applied from the fork")
+	}
+	config.ValidatorMetadataBlock = big.NewInt(300)
+	if applyCbActive(config, neatabi.EditValidator, big.NewInt(299)) || !applyCbActive(config, neatabi.EditValidator, big.NewInt(300)) {
+		t.Error("validator metadata not recorded from the fork")
+	}
+	if !applyCbActive(config, neatabi.Delegate, big.NewInt(0)) {
+		t.Error("callback without fork skipped")
+	}
+}
+
+func TestCheckFunctionFork(t *testing.T) {
+	tests := []struct {
+		config    *params.ChainConfig
+		functions []neatabi.FunctionType
+	}{
+		{&params.ChainConfig{GovernanceBlock: big.NewInt(100)}, []neatabi.FunctionType{neatabi.SubmitGovProposal, neatabi.DepositGovProposal, neatabi.VoteGovProposal, neatabi.SignalUpgrade}},
+		{&params.ChainConfig{ValidatorMetadataBlock: big.NewInt(100)}, []neatabi.FunctionType{neatabi.SetSecurityContact}},
+		{&params.ChainConfig{AutoCompoundBlock: big.NewInt(100)}, []neatabi.FunctionType{neatabi.SetAutoCompound}},
+		{&params.ChainConfig{MinSelfBondBlock: big.NewInt(100)}, []neatabi.FunctionType{neatabi.SetMinSelfBond}},
+		{&params.ChainConfig{BridgeBlock: big.NewInt(100)}, []neatabi.FunctionType{neatabi.CreateBridgeClient, neatabi.UpdateBridgeClient, neatabi.RecvBridgePacket, neatabi.SendBridgePacket, neatabi.AcknowledgeBridgePacket}},
+		{&params.ChainConfig{KeyRotationBlock: big.NewInt(100)}, []neatabi.FunctionType{neatabi.RotateConsensusKey}},
+	}
+	for _, tt := range tests {
+		for _, function := range tt.functions {
+			if err := checkFunctionFork(tt.config, function, big.NewInt(99)); err != ErrFunctionNotActive {
+				t.Errorf("%v: error mismatch before the fork: have %v, want %v", function, err, ErrFunctionNotActive)
+			}
+			if err := checkFunctionFork(tt.config, function, big.NewInt(100)); err != nil {
+				t.Errorf("%v: rejected from the fork: %v", function, err)
+			}
+		}
+	}
+	if err := checkFunctionFork(&params.ChainConfig{}, neatabi.Delegate, big.NewInt(0)); err != nil {
+		t.Errorf("function without fork rejected: %v", err)
+	}
+}
@@ -47,6 +47,10 @@ var (
 	// ErrInvalidSender is returned if the transaction contains an invalid signature.
 	ErrInvalidSender = errors.New("invalid sender")
 
+	// ErrUnprotectedTx is returned if the transaction is not replay protected
+	// by EIP-155, and so could be replayed on the other chains.
+	ErrUnprotectedTx = errors.New("only replay-protected (EIP-155) transactions allowed")
+
 	ErrInvalidAddress = errors.New("invalid address")
 
 	// ErrNonceTooLow is returned if the nonce of a transaction is lower than the
@@ -192,9 +196,11 @@ func (config *TxPoolConfig) sanitize() TxPoolConfig {
 // two states over time as they are received and processed.
 type TxPool struct {
 	config       TxPoolConfig
+	localConfig  TxPoolConfig // Configuration of the node, the limits governance sets override it
 	chainconfig  *params.ChainConfig
 	chain        blockChain
 	gasPrice     *big.Int
+	govGasPrice  *big.Int // Minimum gas price set by the governance of the chain, nil if not set
 	txFeed       event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
@@ -231,6 +237,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	// Create the transaction pool with its initial settings
 	pool := &TxPool{
 		config:      config,
+		localConfig: config,
 		chainconfig: chainconfig,
 		chain:       chain,
 		signer:      types.NewEIP155Signer(chainconfig.ChainId),
@@ -420,6 +427,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.applyGovParams(statedb)
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -441,6 +449,43 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.promoteExecutables(nil)
 }
 
+// applyGovParams sets the limits and the minimum gas price of the pool to the
+// ones the governance of the chain set in the state, leaving the others to the
+// configuration of the node.
+func (pool *TxPool) applyGovParams(statedb *state.StateDB) {
+	config := pool.localConfig
+	for name, limit := range map[string]*uint64{
+		state.GovParamTxPoolAccountSlots: &config.AccountSlots,
+		state.GovParamTxPoolGlobalSlots:  &config.GlobalSlots,
+		state.GovParamTxPoolAccountQueue: &config.AccountQueue,
+		state.GovParamTxPoolGlobalQueue:  &config.GlobalQueue,
+	} {
+		if value := statedb.GetGovParam(name); value != nil {
+			*limit = value.Uint64()
+		}
+	}
+	pool.config = config
+
+	price := statedb.GetGovParam(state.GovParamMinGasPrice)
+	changed := price != nil && (pool.govGasPrice == nil || price.Cmp(pool.govGasPrice) != 0)
+	pool.govGasPrice = price
+	if changed {
+		for _, tx := range pool.priced.Cap(pool.minGasPrice(), pool.locals) {
+			pool.removeTx(tx.Hash())
+		}
+		log.Info("Transaction pool governed price threshold updated", "price", price)
+	}
+}
+
+// minGasPrice returns the lowest gas price of the transactions accepted from
+// the network, the higher of the ones of the node and of the governance.
+func (pool *TxPool) minGasPrice() *big.Int {
+	if pool.govGasPrice != nil && pool.govGasPrice.Cmp(pool.gasPrice) > 0 {
+		return pool.govGasPrice
+	}
+	return pool.gasPrice
+}
+
 // Stop terminates the transaction pool.
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
@@ -467,7 +512,7 @@ func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
 	defer pool.mu.RUnlock()
 
-	return new(big.Int).Set(pool.gasPrice)
+	return new(big.Int).Set(pool.minGasPrice())
 }
 
 // SetGasPrice updates the minimum price required by the transaction pool for a
@@ -477,7 +522,7 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 	defer pool.mu.Unlock()
 
 	pool.gasPrice = price
-	for _, tx := range pool.priced.Cap(price, pool.locals) {
+	for _, tx := range pool.priced.Cap(pool.minGasPrice(), pool.locals) {
 		pool.removeTx(tx.Hash())
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
@@ -579,6 +624,13 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrGasLimit
 	}
 
+	// Make sure the transaction is signed for this chain only
+	if !tx.Protected() {
+		return ErrUnprotectedTx
+	}
+	if tx.ChainId().Cmp(pool.chainconfig.ChainId) != 0 {
+		return types.ErrInvalidChainId
+	}
 	// Make sure the transaction is signed properly
 	from, err := types.Sender(pool.signer, tx)
 	if err != nil {
@@ -586,7 +638,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	}
 	// Drop non-local transactions under our own minimal accepted gas price
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
-	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
+	if !local && pool.minGasPrice().Cmp(tx.GasPrice()) > 0 {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
@@ -605,6 +657,16 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrInvalidAddress
 	}
 
+	// Stop the transactions to a side chain being retired
+	var mainNumber *big.Int
+	if !pool.chainconfig.IsMainChain() && pool.cch != nil {
+		mainNumber = pool.cch.GetHeightFromMainChain()
+	}
+	number := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+	if err := checkSideChainRetirement(pool.chainconfig, pool.cch, pool.currentState, number, mainNumber, tx); err != nil {
+		return err
+	}
+
 	if !neatabi.IsNeatChainContractAddr(tx.To()) {
 		intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, true)
 		if err != nil {
@@ -627,6 +689,16 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		} else if !pool.chainconfig.IsMainChain() && !function.AllowInSideChain() {
 			return ErrNotAllowedInSideChain
 		}
+		next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+		if err := checkFunctionFork(pool.chainconfig, function, next); err != nil {
+			return err
+		}
+
+		if pool.chainconfig.IsCrossChainFee(next) {
+			if err := checkCrossChainTransfer(pool.currentState, from, function, tx); err != nil {
+				return err
+			}
+		}
 
 		log.Infof("validateTx Chain Function %v", function.String())
 		if validateCb := GetValidateCb(function); validateCb != nil {
@@ -862,6 +934,10 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) []error {
+	// Recover the senders on all the cores before taking the lock, the signer
+	// of the pool never changes
+	SenderCacher.RecoverAndWait(pool.signer, txs)
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 	return pool.addTxsLocked(txs, local)
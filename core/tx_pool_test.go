@@ -1271,6 +1271,56 @@ func TestTransactionPoolRepricing(t *testing.T) {
 
 // Tests that setting the transaction pool gas price to a higher value does not
 // remove local transactions.
+// Tests that the limits and the minimum gas price set by the governance of the
+// chain override the configuration of the pool, and that the pool falls back
+// to its configuration for the ones governance did not set.
+func TestTransactionPoolGovParams(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	// The pool only accepts replay protected transactions
+	pricedTransaction := func(nonce uint64, gaslimit uint64, gasprice *big.Int, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(100), gaslimit, gasprice, nil), pool.signer, key)
+		return tx
+	}
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+
+	// Raise the minimum gas price and lower the pending limit through governance
+	pool.currentState.SetGovParam(state.GovParamMinGasPrice, big.NewInt(3), 0, 0)
+	pool.currentState.SetGovParam(state.GovParamTxPoolGlobalSlots, big.NewInt(8), 0, 0)
+	pool.lockedReset(nil, nil)
+
+	if price := pool.GasPrice(); price.Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("gas price mismatch: have %v, want %v", price, 3)
+	}
+	if pending, queued := pool.Stats(); pending+queued != 0 {
+		t.Errorf("underpriced transaction kept: pending %d, queued %d", pending, queued)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(2), key)); err != ErrUnderpriced {
+		t.Errorf("underpriced transaction error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(3), key)); err != nil {
+		t.Errorf("failed to add transaction at the governed price: %v", err)
+	}
+	if pool.config.GlobalSlots != 8 {
+		t.Errorf("global slots mismatch: have %d, want %d", pool.config.GlobalSlots, 8)
+	}
+	if pool.config.AccountSlots != testTxPoolConfig.AccountSlots {
+		t.Errorf("account slots mismatch: have %d, want %d", pool.config.AccountSlots, testTxPoolConfig.AccountSlots)
+	}
+
+	// A higher price of the node takes precedence
+	pool.SetGasPrice(big.NewInt(5))
+	if price := pool.GasPrice(); price.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("gas price mismatch: have %v, want %v", price, 5)
+	}
+}
+
 func TestTransactionPoolRepricingKeepsLocals(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,230 @@
+// Package txindex indexes the transactions of a chain by tag, to search them
+// with queries like "sender = 'NEAT...' AND tx.height > 1000".
+//
+// Every transaction is tagged with its hash, its height and its sender. The
+// special transactions are also tagged with the chain they send NEAT to and
+// with the validator they concern.
+package txindex
+
+import (
+	"encoding/binary"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/log"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/neatdb"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// The tags of the transactions.
+const (
+	TagHash           = "tx.hash"
+	TagHeight         = "tx.height"
+	TagSender         = "sender"
+	TagCrossChainDest = "cross_chain.dest"
+	TagValidator      = "validator.address"
+)
+
+var (
+	tagPrefix   = []byte("T") // tagPrefix + tag + 0 + value + 0 + height (uint64 big endian) + index (uint32 big endian) -> tx hash
+	blockPrefix = []byte("K") // blockPrefix + height (uint64 big endian) -> RLP list of the tag keys of the block
+)
+
+// positionLength is the length of the position of a transaction in the chain,
+// its height and index.
+const positionLength = 12
+
+// Index is the tag index of the transactions of a chain, it is updated by a
+// chain indexer processing every block.
+type Index struct {
+	chainDb   neatdb.Database
+	db        neatdb.Database
+	config    *params.ChainConfig
+	mainChain string
+	indexer   *core.ChainIndexer
+
+	height uint64       // Height of the block being processed
+	batch  neatdb.Batch // Tags of the block being processed
+	keys   [][]byte     // Keys of the tags of the block being processed
+}
+
+// New creates the tag index of the chain, mainChain is the destination of the
+// withdrawals of a side chain.
+func New(chainDb neatdb.Database, config *params.ChainConfig, mainChain string) *Index {
+	idx := &Index{
+		chainDb:   chainDb,
+		db:        rawdb.NewTable(chainDb, string(rawdb.TxTagIndexPrefix)),
+		config:    config,
+		mainChain: mainChain,
+	}
+	idx.indexer = core.NewChainIndexer(chainDb, idx.db, idx, 1, 0, 0, "txindex")
+	return idx
+}
+
+// Start indexes the blocks of the chain, from the last block indexed.
+func (idx *Index) Start(chain core.ChainIndexerChain) {
+	idx.indexer.Start(chain)
+}
+
+// Close stops indexing the blocks.
+func (idx *Index) Close() error {
+	return idx.indexer.Close()
+}
+
+// Reset implements core.ChainIndexerBackend, starting the indexing of a block.
+// The block of a height is indexed again after a reorg, the tags of the
+// previous one are removed.
+func (idx *Index) Reset(section uint64, prevHead common.Hash) error {
+	idx.height, idx.batch, idx.keys = section, idx.db.NewBatch(), nil
+
+	data, _ := idx.db.Get(blockKey(section))
+	if len(data) == 0 {
+		return nil
+	}
+	var keys [][]byte
+	if err := rlp.DecodeBytes(data, &keys); err != nil {
+		log.Warn("Invalid transaction tags of block", "number", section, "err", err)
+		return nil
+	}
+	for _, key := range keys {
+		idx.batch.Delete(key)
+	}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, tagging the transactions of a
+// block.
+func (idx *Index) Process(header *types.Header) {
+	number := header.Number.Uint64()
+	body := rawdb.ReadBody(idx.chainDb, header.Hash(), number)
+	if body == nil {
+		return
+	}
+	signer := types.MakeSigner(idx.config, header.Number)
+	for i, tx := range body.Transactions {
+		for _, tag := range idx.tags(signer, tx, number) {
+			key := tagKey(tag.name, tag.value, number, uint32(i))
+			idx.batch.Put(key, tx.Hash().Bytes())
+			idx.keys = append(idx.keys, key)
+		}
+	}
+}
+
+// Commit implements core.ChainIndexerBackend, writing the tags of the block.
+func (idx *Index) Commit() error {
+	if len(idx.keys) == 0 {
+		idx.batch.Delete(blockKey(idx.height))
+	} else {
+		data, err := rlp.EncodeToBytes(idx.keys)
+		if err != nil {
+			return err
+		}
+		idx.batch.Put(blockKey(idx.height), data)
+	}
+	return idx.batch.Write()
+}
+
+type tag struct {
+	name  string
+	value []byte
+}
+
+// tags returns the tags of a transaction.
+func (idx *Index) tags(signer types.Signer, tx *types.Transaction, number uint64) []tag {
+	tags := []tag{
+		{TagHash, []byte(tx.Hash().Hex())},
+		{TagHeight, heightValue(number)},
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return tags
+	}
+	tags = append(tags, tag{TagSender, []byte(from.String())})
+
+	data := tx.Data()
+	if !neatabi.IsNeatChainContractAddr(tx.To()) || len(data) < 4 {
+		return tags
+	}
+	function, err := neatabi.FunctionTypeFromId(data[:4])
+	if err != nil {
+		return tags
+	}
+	if dest := idx.crossChainDest(function, data[4:]); dest != "" {
+		tags = append(tags, tag{TagCrossChainDest, []byte(dest)})
+	}
+	if validator := validatorAddress(function, from, data[4:]); validator != (common.Address{}) {
+		tags = append(tags, tag{TagValidator, []byte(validator.String())})
+	}
+	return tags
+}
+
+// crossChainDest returns the chain a special transaction sends NEAT to.
+func (idx *Index) crossChainDest(function neatabi.FunctionType, input []byte) string {
+	switch function {
+	case neatabi.DepositInMainChain:
+		var args neatabi.DepositInMainChainArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), input); err == nil {
+			return args.ChainId
+		}
+	case neatabi.WithdrawFromSideChain:
+		return idx.mainChain
+	}
+	return ""
+}
+
+// validatorAddress returns the validator a special transaction concerns, the
+// sender of the transactions of the validators themselves.
+func validatorAddress(function neatabi.FunctionType, from common.Address, input []byte) common.Address {
+	switch function {
+	case neatabi.Delegate:
+		var args neatabi.DelegateArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), input); err == nil {
+			return args.Candidate
+		}
+	case neatabi.UnDelegate:
+		var args neatabi.UnDelegateArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), input); err == nil {
+			return args.Candidate
+		}
+	case neatabi.SetAutoCompound:
+		var args neatabi.SetAutoCompoundArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), input); err == nil {
+			return args.Candidate
+		}
+	case neatabi.Register, neatabi.UnRegister, neatabi.EditValidator, neatabi.UnBanned, neatabi.SetCommission,
+		neatabi.RotateConsensusKey, neatabi.SetMinSelfBond, neatabi.SetSecurityContact, neatabi.VoteNextEpoch, neatabi.RevealVote:
+		return from
+	}
+	return common.Address{}
+}
+
+// heightValue returns the value of the height tag, sorted like the heights.
+func heightValue(number uint64) []byte {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, number)
+	return value
+}
+
+// tagPrefixKey returns the prefix of the keys of the tags of a name, and of a
+// value if it is not nil.
+func tagPrefixKey(name string, value []byte) []byte {
+	key := append(append(append([]byte{}, tagPrefix...), name...), 0)
+	if value != nil {
+		key = append(append(key, value...), 0)
+	}
+	return key
+}
+
+func tagKey(name string, value []byte, number uint64, index uint32) []byte {
+	key := tagPrefixKey(name, value)
+	key = append(key, heightValue(number)...)
+	return append(key, byte(index>>24), byte(index>>16), byte(index>>8), byte(index))
+}
+
+func blockKey(number uint64) []byte {
+	return append(append([]byte{}, blockPrefix...), heightValue(number)...)
+}
@@ -0,0 +1,164 @@
+package txindex
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+)
+
+type testChain struct {
+	t      *testing.T
+	idx    *Index
+	signer types.Signer
+	parent common.Hash
+}
+
+func newTestChain(t *testing.T) *testChain {
+	idx := New(rawdb.NewMemoryDatabase(), params.TestChainConfig, "neatio")
+	return &testChain{t: t, idx: idx, signer: types.NewEIP155Signer(params.TestChainConfig.ChainId)}
+}
+
+func (c *testChain) tx(key *ecdsa.PrivateKey, nonce uint64, to common.Address, data []byte) *types.Transaction {
+	tx, err := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(1), 100000, big.NewInt(1), data), c.signer, key)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return tx
+}
+
+func (c *testChain) special(key *ecdsa.PrivateKey, nonce uint64, function neatabi.FunctionType, args ...interface{}) *types.Transaction {
+	data, err := neatabi.ChainABI.Pack(function.String(), args...)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return c.tx(key, nonce, neatabi.ChainContractMagicAddr, data)
+}
+
+// index writes and indexes a block of the transactions.
+func (c *testChain) index(number uint64, txs ...*types.Transaction) {
+	header := &types.Header{Number: new(big.Int).SetUint64(number), ParentHash: c.parent, Time: big.NewInt(int64(number))}
+	block := types.NewBlock(header, txs, nil, nil)
+	rawdb.WriteBlock(c.idx.chainDb, block)
+	rawdb.WriteCanonicalHash(c.idx.chainDb, block.Hash(), number)
+
+	if err := c.idx.Reset(number, c.parent); err != nil {
+		c.t.Fatal(err)
+	}
+	c.idx.Process(block.Header())
+	if err := c.idx.Commit(); err != nil {
+		c.t.Fatal(err)
+	}
+	c.parent = block.Hash()
+}
+
+func (c *testChain) search(query string, offset, limit int) ([]Result, int) {
+	results, total, err := c.idx.Search(query, offset, limit)
+	if err != nil {
+		c.t.Fatalf("%s: %v", query, err)
+	}
+	return results, total
+}
+
+func TestSearch(t *testing.T) {
+	var (
+		key1, _   = crypto.GenerateKey()
+		key2, _   = crypto.GenerateKey()
+		key3, _   = crypto.GenerateKey()
+		addr1     = crypto.PubkeyToAddress(key1.PublicKey)
+		validator = crypto.PubkeyToAddress(key3.PublicKey)
+	)
+	c := newTestChain(t)
+	defer c.idx.Close()
+
+	transfer := c.tx(key1, 0, common.Address{1}, nil)
+	delegate := c.special(key2, 0, neatabi.Delegate, validator)
+	deposit := c.special(key1, 1, neatabi.DepositInMainChain, "side_0")
+	register := c.special(key3, 0, neatabi.Register, []byte{1}, []byte{2}, uint8(10))
+	c.index(1, transfer, delegate)
+	c.index(2, deposit)
+	c.index(3, register)
+
+	tests := []struct {
+		query  string
+		hashes []common.Hash
+	}{
+		{"sender = '" + addr1.String() + "'", []common.Hash{transfer.Hash(), deposit.Hash()}},
+		{"sender = '" + addr1.String() + "' AND tx.height > 1", []common.Hash{deposit.Hash()}},
+		{"tx.hash = '" + strings.ToUpper(delegate.Hash().Hex()) + "'", []common.Hash{delegate.Hash()}},
+		{"validator.address = '" + validator.String() + "'", []common.Hash{delegate.Hash(), register.Hash()}},
+		{"validator.address = \"" + validator.String() + "\" and sender = '" + validator.String() + "'", []common.Hash{register.Hash()}},
+		{"cross_chain.dest = 'side_0'", []common.Hash{deposit.Hash()}},
+		{"tx.height >= 1 AND tx.height <= 2", []common.Hash{transfer.Hash(), delegate.Hash(), deposit.Hash()}},
+		{"tx.height = 3", []common.Hash{register.Hash()}},
+		{"tx.height < 1", nil},
+		{"tx.height > 1 AND tx.height < 2", nil},
+	}
+	for _, tt := range tests {
+		results, total := c.search(tt.query, 0, 10)
+		if total != len(tt.hashes) || len(results) != len(tt.hashes) {
+			t.Errorf("%s: %d results of %d, want %d", tt.query, len(results), total, len(tt.hashes))
+			continue
+		}
+		for i, result := range results {
+			if result.Hash != tt.hashes[i] {
+				t.Errorf("%s: result %d is %x, want %x", tt.query, i, result.Hash, tt.hashes[i])
+			}
+		}
+	}
+
+	results, total := c.search("tx.height <= 3", 1, 2)
+	if total != 4 || len(results) != 2 || results[0].Hash != delegate.Hash() || results[0].Height != 1 || results[0].Index != 1 || results[1].Hash != deposit.Hash() {
+		t.Errorf("unexpected page %v of %d results", results, total)
+	}
+}
+
+func TestReorg(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	query := "sender = '" + crypto.PubkeyToAddress(key.PublicKey).String() + "'"
+
+	c := newTestChain(t)
+	defer c.idx.Close()
+	c.index(1, c.tx(key, 0, common.Address{1}, nil))
+	parent := c.parent
+	c.index(2, c.tx(key, 1, common.Address{1}, nil))
+	if _, total := c.search(query, 0, 10); total != 2 {
+		t.Fatalf("%d results, want 2", total)
+	}
+
+	// The block replacing the second one has no transactions of the sender
+	c.parent = parent
+	c.index(2)
+	if results, total := c.search(query, 0, 10); total != 1 || results[0].Height != 1 {
+		t.Fatalf("%v of %d results, want the one of block 1", results, total)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"", "empty query"},
+		{"sender > 'NEAT'", "operator > not supported on sender"},
+		{"sender = NEAT", "string expected in the condition on sender"},
+		{"tx.height = '1'", "number expected in the condition on tx.height"},
+		{"tx.height => 1", "operator expected after tx.height"},
+		{"block.hash = 'x'", "unknown tag block.hash"},
+		{"tx.height = 1 OR tx.height = 2", "AND expected after the condition on tx.height"},
+		{"tx.height = 1 AND", "AND expected after the condition on tx.height"},
+		{"sender = 'NEAT", "unterminated string in the condition on sender"},
+	}
+	for _, tt := range tests {
+		if _, err := parseQuery(tt.query); err == nil || err.Error() != tt.err {
+			t.Errorf("%q: error %v, want %q", tt.query, err, tt.err)
+		}
+	}
+}
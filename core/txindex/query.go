@@ -0,0 +1,253 @@
+package txindex
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/neatlab/neatio/common"
+)
+
+var errEmptyQuery = errors.New("empty query")
+
+// condition is a condition of a query on a tag, its value encoded like the
+// values of the tag.
+type condition struct {
+	tag   string
+	op    string
+	value []byte
+}
+
+// parseQuery parses a query, conditions on tags joined by AND. The strings are
+// quoted, the heights compared with =, <, <=, > and >=, like in
+//
+//	sender = 'NEAT...' AND tx.height >= 100 AND tx.height < 200
+func parseQuery(query string) ([]condition, error) {
+	var (
+		conds []condition
+		s     = strings.TrimSpace(query)
+	)
+	if s == "" {
+		return nil, errEmptyQuery
+	}
+	for {
+		var cond condition
+		cond.tag, s = token(s, func(c byte) bool {
+			return c == '.' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+		})
+		if cond.tag == "" {
+			return nil, fmt.Errorf("tag expected at %q", s)
+		}
+		cond.op, s = token(s, func(c byte) bool { return c == '=' || c == '<' || c == '>' })
+		switch cond.op {
+		case "=", "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("operator expected after %s", cond.tag)
+		}
+		var (
+			value  string
+			quoted bool
+		)
+		if len(s) > 0 && (s[0] == '\'' || s[0] == '"') {
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in the condition on %s", cond.tag)
+			}
+			value, quoted, s = s[1:end+1], true, strings.TrimSpace(s[end+2:])
+		} else {
+			value, s = token(s, func(c byte) bool { return c >= '0' && c <= '9' })
+		}
+		var err error
+		if cond.value, err = conditionValue(cond.tag, cond.op, value, quoted); err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+
+		if s == "" {
+			return conds, nil
+		}
+		var and string
+		and, s = token(s, func(c byte) bool { return c != ' ' && c != '\t' && c != '\n' })
+		if !strings.EqualFold(and, "AND") || s == "" {
+			return nil, fmt.Errorf("AND expected after the condition on %s", cond.tag)
+		}
+	}
+}
+
+// token splits the leading characters of s accepted by valid, skipping the
+// spaces after them.
+func token(s string, valid func(c byte) bool) (string, string) {
+	i := 0
+	for i < len(s) && valid(s[i]) {
+		i++
+	}
+	return s[:i], strings.TrimSpace(s[i:])
+}
+
+// conditionValue checks a condition and encodes its value.
+func conditionValue(tag, op, value string, quoted bool) ([]byte, error) {
+	switch tag {
+	case TagHeight:
+		if quoted || value == "" {
+			return nil, fmt.Errorf("number expected in the condition on %s", tag)
+		}
+		number, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number in the condition on %s: %v", tag, err)
+		}
+		return heightValue(number), nil
+
+	case TagHash, TagSender, TagCrossChainDest, TagValidator:
+		if op != "=" {
+			return nil, fmt.Errorf("operator %s not supported on %s", op, tag)
+		}
+		if !quoted {
+			return nil, fmt.Errorf("string expected in the condition on %s", tag)
+		}
+		if tag == TagHash {
+			value = strings.ToLower(value)
+		}
+		return []byte(value), nil
+	}
+	return nil, fmt.Errorf("unknown tag %s", tag)
+}
+
+// Result is a transaction matching a query.
+type Result struct {
+	Hash   common.Hash
+	Height uint64
+	Index  uint32
+}
+
+// Search returns the transactions matching a query in the order of the chain,
+// skipping offset of them and returning at most limit, with the number of
+// transactions matching it.
+func (idx *Index) Search(query string, offset, limit int) ([]Result, int, error) {
+	conds, err := parseQuery(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	// The heights restrict the positions of the transactions, the other tags
+	// are matched in their index
+	var (
+		min, max uint64 = 0, math.MaxUint64
+		equals   []condition
+	)
+	for _, cond := range conds {
+		if cond.tag != TagHeight {
+			equals = append(equals, cond)
+			continue
+		}
+		number := binary.BigEndian.Uint64(cond.value)
+		switch cond.op {
+		case "=":
+			min, max = maxUint64(min, number), minUint64(max, number)
+		case "<":
+			if number == 0 {
+				return nil, 0, nil
+			}
+			max = minUint64(max, number-1)
+		case "<=":
+			max = minUint64(max, number)
+		case ">":
+			if number == math.MaxUint64 {
+				return nil, 0, nil
+			}
+			min = maxUint64(min, number+1)
+		case ">=":
+			min = maxUint64(min, number)
+		}
+	}
+	if min > max {
+		return nil, 0, nil
+	}
+
+	var results []Result
+	if len(equals) == 0 {
+		// Only the common leading bytes of the bounds are a prefix of the
+		// heights of the range
+		lower, upper := heightValue(min), heightValue(max)
+		shared := 0
+		for shared < len(lower) && lower[shared] == upper[shared] {
+			shared++
+		}
+		results = idx.match(TagHeight, nil, lower[:shared], min, max)
+	} else {
+		results = idx.match(equals[0].tag, equals[0].value, nil, min, max)
+		for _, cond := range equals[1:] {
+			if len(results) == 0 {
+				break
+			}
+			matches := make(map[Result]bool)
+			for _, result := range idx.match(cond.tag, cond.value, nil, min, max) {
+				matches[result] = true
+			}
+			filtered := results[:0]
+			for _, result := range results {
+				if matches[result] {
+					filtered = append(filtered, result)
+				}
+			}
+			results = filtered
+		}
+	}
+
+	total := len(results)
+	if offset >= total {
+		return nil, total, nil
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, total, nil
+}
+
+// match returns the transactions of the heights between min and max tagged
+// with value. The value of the height tag is nil, partial is then the prefix
+// of the heights.
+func (idx *Index) match(name string, value, partial []byte, min, max uint64) []Result {
+	prefix := tagPrefixKey(name, value)
+	it := idx.db.NewIteratorWithPrefix(append(prefix, partial...))
+	defer it.Release()
+
+	var results []Result
+	for it.Next() {
+		key := it.Key()
+		if len(key) < len(prefix)+positionLength || len(it.Value()) != common.HashLength {
+			continue
+		}
+		// The keys of a value, and of the heights, are sorted by position
+		position := key[len(key)-positionLength:]
+		number := binary.BigEndian.Uint64(position)
+		if number < min {
+			continue
+		}
+		if number > max {
+			break
+		}
+		results = append(results, Result{
+			Hash:   common.BytesToHash(it.Value()),
+			Height: number,
+			Index:  binary.BigEndian.Uint32(position[8:]),
+		})
+	}
+	return results
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -20,9 +20,11 @@ package types
 import (
 	"encoding/binary"
 	"fmt"
+	"hash"
 	"io"
 	"math/big"
 	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -143,8 +145,16 @@ func (h *Header) Size() common.StorageSize {
 	return common.StorageSize(unsafe.Sizeof(*h)) + common.StorageSize(len(h.Extra)+(h.Difficulty.BitLen()+h.Number.BitLen()+h.Time.BitLen())/8)
 }
 
+// hasherPool holds the Keccak hashers of rlpHash, which hashes every header,
+// transaction and receipt decoded.
+var hasherPool = sync.Pool{
+	New: func() interface{} { return sha3.NewLegacyKeccak256() },
+}
+
 func rlpHash(x interface{}) (h common.Hash) {
-	hw := sha3.NewLegacyKeccak256()
+	hw := hasherPool.Get().(hash.Hash)
+	defer hasherPool.Put(hw)
+	hw.Reset()
 	rlp.Encode(hw, x)
 	hw.Sum(h[:0])
 	return h
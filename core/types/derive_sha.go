@@ -17,8 +17,6 @@
 package types
 
 import (
-	"bytes"
-
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/rlp"
 	"github.com/neatlab/neatio/trie"
@@ -30,12 +28,11 @@ type DerivableList interface {
 }
 
 func DeriveSha(list DerivableList) common.Hash {
-	keybuf := new(bytes.Buffer)
+	var keybuf []byte
 	trie := new(trie.Trie)
 	for i := 0; i < list.Len(); i++ {
-		keybuf.Reset()
-		rlp.Encode(keybuf, uint(i))
-		trie.Update(keybuf.Bytes(), list.GetRlp(i))
+		keybuf = rlp.AppendUint64(keybuf[:0], uint64(i))
+		trie.Update(keybuf, list.GetRlp(i))
 	}
 	return trie.Hash()
 }
@@ -22,11 +22,15 @@ import (
 	"fmt"
 	"math/big"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/crypto"
 	"github.com/neatlab/neatio/params"
 )
 
+// senderCacheLimit is the number of recovered senders kept by transaction hash.
+const senderCacheLimit = 65536
+
 var (
 	ErrInvalidChainId = errors.New("invalid chain id for signer")
 	ErrInvalidSigner  = errors.New("invalid signer, should be eip155")
@@ -39,6 +43,11 @@ type sigCache struct {
 	from   common.Address
 }
 
+// senderCache keeps the senders recovered lately by transaction hash, so that
+// a transaction seen again as a different object, e.g. first in the pool and
+// then decoded from a block, does not go through ecrecover twice.
+var senderCache, _ = lru.New(senderCacheLimit)
+
 // MakeSigner returns a Signer based on the given chain config and block number.
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 	var signer Signer
@@ -99,12 +108,21 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 			return sigCache.from, nil
 		}
 	}
+	hash := tx.Hash()
+	if sc, ok := senderCache.Get(hash); ok {
+		if sigCache := sc.(sigCache); sigCache.signer.Equal(signer) {
+			tx.from.Store(sigCache)
+			return sigCache.from, nil
+		}
+	}
 
 	addr, err := signer.Sender(tx)
 	if err != nil {
 		return common.Address{}, err
 	}
-	tx.from.Store(sigCache{signer: signer, from: addr})
+	sc := sigCache{signer: signer, from: addr}
+	tx.from.Store(sc)
+	senderCache.Add(hash, sc)
 	return addr, nil
 }
 
@@ -136,3 +136,36 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestSenderCache(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer := NewEIP155Signer(big.NewInt(18))
+	tx, err := SignTx(NewTransaction(7, addr, new(big.Int), 0, new(big.Int), nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sender(signer, tx); err != nil {
+		t.Fatal(err)
+	}
+	if !senderCache.Contains(tx.Hash()) {
+		t.Fatal("recovered sender not cached by hash")
+	}
+	// A copy of the transaction decoded afresh is served from the cache
+	decoded := new(Transaction)
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(signer, decoded); err != nil || from != addr {
+		t.Fatalf("cached sender mismatch: have %x, %v, want %x", from, err, addr)
+	}
+	// but not for another signer
+	if _, err := Sender(NewEIP155Signer(big.NewInt(19)), decoded); err != ErrInvalidChainId {
+		t.Fatalf("expected %v for another chain, got %v", ErrInvalidChainId, err)
+	}
+}
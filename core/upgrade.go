@@ -0,0 +1,67 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/neatlab/neatio/consensus"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/params"
+)
+
+// UnsupportedUpgradeError is returned for a block at or above the activation
+// height of an upgrade this release does not implement.
+type UnsupportedUpgradeError struct {
+	Name   string
+	Height uint64
+}
+
+func (e *UnsupportedUpgradeError) Error() string {
+	return fmt.Sprintf("upgrade %q activated at block %d is not supported by this release (%s), install a release supporting it to continue", e.Name, e.Height, params.VersionWithMeta)
+}
+
+// CheckUpgrades returns an *UnsupportedUpgradeError if the block number
+// reached the activation height of an upgrade, scheduled in statedb, which
+// this release does not support.
+func CheckUpgrades(statedb *state.StateDB, number uint64) error {
+	upgrades := statedb.GetUpgrades()
+	names := make([]string, 0, len(upgrades))
+	for name := range upgrades {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if height := upgrades[name]; number >= height && !params.SupportedUpgrades[name] {
+			return &UnsupportedUpgradeError{Name: name, Height: height}
+		}
+	}
+	return nil
+}
+
+// haltForUpgrade halts the chain at an upgrade this release does not support.
+// The block is not reported bad, no block past the activation height is
+// imported and the consensus engine is stopped, the node has to be upgraded
+// to continue.
+func (bc *BlockChain) haltForUpgrade(err *UnsupportedUpgradeError) {
+	if !atomic.CompareAndSwapInt32(&bc.upgradeHalted, 0, 1) {
+		return
+	}
+	bc.logger.Error("Chain halted for upgrade, install a release supporting it and restart", "upgrade", err.Name, "height", err.Height, "version", params.VersionWithMeta)
+
+	if engine, ok := bc.engine.(consensus.NeatPoS); ok {
+		// The consensus may be validating the block itself, don't wait on it
+		go func() {
+			if err := engine.Stop(); err != nil {
+				bc.logger.Debug("Consensus not stopped for upgrade", "err", err)
+			}
+		}()
+	}
+}
+
+// HaltedForUpgrade reports whether the chain halted at an upgrade this release
+// does not support.
+func (bc *BlockChain) HaltedForUpgrade() bool {
+	return atomic.LoadInt32(&bc.upgradeHalted) == 1
+}
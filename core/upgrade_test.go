@@ -0,0 +1,60 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/params"
+)
+
+func TestCheckUpgrades(t *testing.T) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+
+	statedb.SetGovParam(state.UpgradeParam("known"), big.NewInt(100), 0, 0)
+	statedb.SetGovParam(state.UpgradeParam("unknown"), big.NewInt(200), 0, 0)
+	statedb.SignalUpgrade("unknown", common.BytesToAddress([]byte{0x01}))
+	statedb.SignalUpgrade("unknown", common.BytesToAddress([]byte{0x01}))
+
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, db)
+
+	params.SupportedUpgrades["known"] = true
+	defer delete(params.SupportedUpgrades, "known")
+
+	for _, number := range []uint64{99, 100, 199} {
+		if err := CheckUpgrades(statedb, number); err != nil {
+			t.Errorf("block %d: unexpected error: %v", number, err)
+		}
+	}
+	err = CheckUpgrades(statedb, 200)
+	if e, ok := err.(*UnsupportedUpgradeError); !ok || e.Name != "unknown" || e.Height != 200 {
+		t.Errorf("block 200: error mismatch: have %v", err)
+	}
+	if have := len(statedb.GetUpgradeSignals("unknown")); have != 1 {
+		t.Errorf("signal count mismatch: have %d, want 1", have)
+	}
+}
+
+func TestHaltForUpgrade(t *testing.T) {
+	bc := &BlockChain{logger: log.New()}
+	if bc.HaltedForUpgrade() {
+		t.Fatal("chain halted before any upgrade")
+	}
+	bc.haltForUpgrade(&UnsupportedUpgradeError{Name: "unknown", Height: 200})
+	if !bc.HaltedForUpgrade() {
+		t.Fatal("chain not halted for the upgrade")
+	}
+	// Halting again is a no-op
+	bc.haltForUpgrade(&UnsupportedUpgradeError{Name: "unknown", Height: 200})
+	if !bc.HaltedForUpgrade() {
+		t.Fatal("chain resumed by a second halt")
+	}
+}
@@ -0,0 +1,194 @@
+package vm
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/neatlab/neatio/accounts/abi"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/bridge/ethereum"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/params"
+)
+
+// EthBridgeAddress is the address of the precompiled contract verifying the
+// Ethereum headers and receipts relayed to the bridge contracts.
+var EthBridgeAddress = common.BytesToAddress([]byte{1, 0})
+
+// ethBridgeABI is the interface of the Ethereum bridge precompile. Ethereum
+// addresses are returned as bytes20, and reverted calls return no error data.
+const ethBridgeABI = `
+[
+	{
+		"type": "function",
+		"name": "decodeHeader",
+		"constant": true,
+		"inputs": [{"name": "header", "type": "bytes"}],
+		"outputs": [
+			{"name": "hash", "type": "bytes32"},
+			{"name": "parentHash", "type": "bytes32"},
+			{"name": "number", "type": "uint64"},
+			{"name": "timestamp", "type": "uint64"},
+			{"name": "receiptsRoot", "type": "bytes32"},
+			{"name": "pos", "type": "bool"}
+		]
+	},
+	{
+		"type": "function",
+		"name": "verifyHeaderChain",
+		"constant": true,
+		"inputs": [{"name": "headers", "type": "bytes[]"}],
+		"outputs": [
+			{"name": "parentHash", "type": "bytes32"},
+			{"name": "hashes", "type": "bytes32[]"},
+			{"name": "receiptsRoots", "type": "bytes32[]"}
+		]
+	},
+	{
+		"type": "function",
+		"name": "verifyReceiptLog",
+		"constant": true,
+		"inputs": [
+			{"name": "receiptsRoot", "type": "bytes32"},
+			{"name": "txIndex", "type": "uint64"},
+			{"name": "proof", "type": "bytes[]"},
+			{"name": "logIndex", "type": "uint64"}
+		],
+		"outputs": [
+			{"name": "emitter", "type": "bytes20"},
+			{"name": "topics", "type": "bytes32[]"},
+			{"name": "data", "type": "bytes"}
+		]
+	}
+]`
+
+var parsedEthBridgeABI abi.ABI
+
+func init() {
+	var err error
+	parsedEthBridgeABI, err = abi.JSON(strings.NewReader(ethBridgeABI))
+	if err != nil {
+		panic("fail to create the ethereum bridge ABI: " + err.Error())
+	}
+}
+
+var (
+	errEthBridgeInput     = errors.New("invalid ethereum bridge call")
+	errUntrustedEthHeader = errors.New("ethereum header chain not ending at a trusted checkpoint")
+)
+
+// ethBridge verifies Ethereum headers and receipt proofs for the contracts
+// of a bridge with Ethereum. The consensus of Ethereum is not verified, a
+// header chain is trusted only if it ends at a finalized checkpoint confirmed
+// by the validators, kept in the storage of the precompile.
+type ethBridge struct {
+	state StateDB
+}
+
+// withState returns the precompile reading the checkpoints from state.
+func (c *ethBridge) withState(state StateDB) PrecompiledContract {
+	return &ethBridge{state: state}
+}
+
+// ethCheckpointVoteKey is the storage slot recording the vote of validator
+// for the checkpoint.
+func ethCheckpointVoteKey(hash common.Hash, number uint64, validator common.Address) common.Hash {
+	var num [8]byte
+	binary.BigEndian.PutUint64(num[:], number)
+	return crypto.Keccak256Hash(hash.Bytes(), num[:], validator.Bytes())
+}
+
+// EthCheckpoint returns the number of the Ethereum block of hash, if the
+// validators confirmed it as finalized.
+func EthCheckpoint(state StateDB, hash common.Hash) (uint64, bool) {
+	value := state.GetState(EthBridgeAddress, hash).Big()
+	if value.Sign() == 0 {
+		return 0, false
+	}
+	return value.Uint64() - 1, true
+}
+
+// SetEthCheckpoint records the Ethereum block of hash as finalized.
+func SetEthCheckpoint(state StateDB, hash common.Hash, number uint64) {
+	state.SetState(EthBridgeAddress, hash, common.BigToHash(new(big.Int).SetUint64(number+1)))
+}
+
+// HasVotedEthCheckpoint returns whether the validator submitted the
+// checkpoint.
+func HasVotedEthCheckpoint(state StateDB, hash common.Hash, number uint64, validator common.Address) bool {
+	return state.GetState(EthBridgeAddress, ethCheckpointVoteKey(hash, number, validator)) != (common.Hash{})
+}
+
+// VoteEthCheckpoint records the submission of the checkpoint by the
+// validator.
+func VoteEthCheckpoint(state StateDB, hash common.Hash, number uint64, validator common.Address) {
+	state.SetState(EthBridgeAddress, ethCheckpointVoteKey(hash, number, validator), common.BytesToHash([]byte{1}))
+}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract,
+// the decoding and hashing costs growing with the input.
+func (c *ethBridge) RequiredGas(input []byte) uint64 {
+	return uint64(len(input)+31)/32*params.EthBridgePerWordGas + params.EthBridgeBaseGas
+}
+
+func (c *ethBridge) Run(input []byte) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, errEthBridgeInput
+	}
+	method, err := parsedEthBridgeABI.MethodById(input[:4])
+	if err != nil {
+		return nil, errEthBridgeInput
+	}
+	args, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil {
+		return nil, errEthBridgeInput
+	}
+
+	switch method.Name {
+	case "decodeHeader":
+		h, err := ethereum.DecodeHeader(args[0].([]byte))
+		if err != nil {
+			return nil, err
+		}
+		return method.Outputs.Pack(h.Hash(), h.ParentHash, h.Number, h.Time, h.ReceiptHash, h.IsPoS())
+
+	case "verifyHeaderChain":
+		headers, err := ethereum.VerifyHeaderChain(args[0].([][]byte))
+		if err != nil {
+			return nil, err
+		}
+		// The parent links authenticate the headers from the checkpoint
+		last := headers[len(headers)-1]
+		if c.state == nil {
+			return nil, errUntrustedEthHeader
+		}
+		if number, ok := EthCheckpoint(c.state, last.Hash()); !ok || number != last.Number {
+			return nil, errUntrustedEthHeader
+		}
+		hashes := make([][32]byte, len(headers))
+		roots := make([][32]byte, len(headers))
+		for i, h := range headers {
+			hashes[i], roots[i] = h.Hash(), h.ReceiptHash
+		}
+		return method.Outputs.Pack(headers[0].ParentHash, hashes, roots)
+
+	case "verifyReceiptLog":
+		root := common.Hash(args[0].([32]byte))
+		log, err := ethereum.VerifyLog(root, args[1].(uint64), args[2].([][]byte), args[3].(uint64))
+		if err != nil {
+			return nil, err
+		}
+		topics := make([][32]byte, len(log.Topics))
+		for i, topic := range log.Topics {
+			topics[i] = topic
+		}
+		data := log.Data
+		if data == nil {
+			data = []byte{}
+		}
+		return method.Outputs.Pack(log.Address, topics, data)
+	}
+	return nil, errEthBridgeInput
+}
@@ -22,6 +22,11 @@ import (
 	"testing"
 
 	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/rlp"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -481,3 +486,92 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// ethGenesisHeader returns the RLP encoded genesis header of Ethereum, with
+// the given parent hash, number and timestamp.
+func ethGenesisHeader(t *testing.T, parent common.Hash, number, time uint64) []byte {
+	root := common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	data, err := rlp.EncodeToBytes([]interface{}{
+		parent,
+		common.HexToHash("0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347"),
+		[20]byte{},
+		common.HexToHash("0xd7f8974fb5ac78d9ac099b9ad5018bedc2ce0a72dad1827a1709da30580f0544"),
+		root, root,
+		[256]byte{},
+		big.NewInt(0x400000000),
+		number, uint64(5000), uint64(0), time,
+		hexutil.MustDecode("0x11bbe8db4e347b4e8c937c1c8370e4b5ed33adb3db69cbdb7a38e1e50b1b82fa"),
+		common.Hash{},
+		[8]byte{0, 0, 0, 0, 0, 0, 0, 0x42},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestPrecompiledEthBridge(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	p := PrecompiledContractsEthBridge[EthBridgeAddress].(statefulPrecompiledContract).withState(statedb)
+	run := func(method string, args ...interface{}) ([]byte, error) {
+		input, err := parsedEthBridgeABI.Pack(method, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contract := NewContract(AccountRef(common.HexToAddress("1337")), nil, new(big.Int), p.RequiredGas(input))
+		return RunPrecompiledContract(p, input, contract)
+	}
+
+	genesis := ethGenesisHeader(t, common.Hash{}, 0, 0)
+	out, err := run("decodeHeader", genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header struct {
+		Hash         [32]byte
+		ParentHash   [32]byte
+		Number       uint64
+		Timestamp    uint64
+		ReceiptsRoot [32]byte
+		Pos          bool
+	}
+	if err := parsedEthBridgeABI.Unpack(&header, "decodeHeader", out); err != nil {
+		t.Fatal(err)
+	}
+	genesisHash := common.HexToHash("0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3")
+	if header.Hash != genesisHash || header.Pos {
+		t.Fatalf("genesis decoded to hash %x", header.Hash)
+	}
+
+	child := ethGenesisHeader(t, genesisHash, 1, 10)
+	if _, err := run("verifyHeaderChain", [][]byte{genesis, child}); err != errUntrustedEthHeader {
+		t.Fatalf("chain without checkpoint: have %v, want %v", err, errUntrustedEthHeader)
+	}
+	childHash := crypto.Keccak256Hash(child)
+	SetEthCheckpoint(statedb, childHash, 2)
+	if _, err := run("verifyHeaderChain", [][]byte{genesis, child}); err != errUntrustedEthHeader {
+		t.Fatalf("checkpoint at another number: have %v, want %v", err, errUntrustedEthHeader)
+	}
+	SetEthCheckpoint(statedb, childHash, 1)
+	out, err = run("verifyHeaderChain", [][]byte{genesis, child})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chain struct {
+		ParentHash    [32]byte
+		Hashes        [][32]byte
+		ReceiptsRoots [][32]byte
+	}
+	if err := parsedEthBridgeABI.Unpack(&chain, "verifyHeaderChain", out); err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.Hashes) != 2 || chain.Hashes[0] != genesisHash || len(chain.ReceiptsRoots) != 2 {
+		t.Fatalf("chain decoded to hashes %x", chain.Hashes)
+	}
+	if _, err := run("verifyHeaderChain", [][]byte{child, genesis}); err == nil {
+		t.Error("unlinked headers accepted")
+	}
+	if _, err := RunPrecompiledContract(p, []byte{1, 2, 3, 4}, NewContract(AccountRef(common.HexToAddress("1337")), nil, new(big.Int), 10000)); err == nil {
+		t.Error("unknown method accepted")
+	}
+}
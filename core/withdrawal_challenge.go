@@ -0,0 +1,26 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/log"
+)
+
+// ReleaseWithdrawals pays out the withdrawals from the side chains held on the
+// main chain until the block, their challenge window over, the same way as the
+// TX4s released right away. It returns the gas fees of the block with the part
+// of the cross chain fees going to the validators added.
+func ReleaseWithdrawals(statedb *state.StateDB, header *types.Header, totalGasFee *big.Int) *big.Int {
+	released := statedb.ReleasePendingWithdrawals(header.Number.Uint64())
+	if len(released) == 0 {
+		return totalGasFee
+	}
+	gasFees := new(big.Int).Set(totalGasFee)
+	for _, withdrawal := range released {
+		payWithdrawal(statedb, header.Coinbase, withdrawal.From, withdrawal.Amount, gasFees)
+		log.Debug("Released withdrawal from side chain", "chain", withdrawal.ChainId, "tx3", withdrawal.TxHash, "to", withdrawal.From, "amount", withdrawal.Amount)
+	}
+	return gasFees
+}
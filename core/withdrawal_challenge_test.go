@@ -0,0 +1,112 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+)
+
+func TestReleaseWithdrawals(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetGovParam(state.GovParamCrossChainFeeRate, big.NewInt(100), 0, 0)   // 1%
+	statedb.SetGovParam(state.GovParamCrossChainRelayerFee, big.NewInt(25), 0, 0) // A quarter of the fee
+	statedb.SetGovParam(state.GovParamChallengeWindow, big.NewInt(100), 0, 0)
+
+	from := common.BytesToAddress([]byte{0x01})
+	neat := big.NewInt(1e18)
+	amount := new(big.Int).Mul(big.NewInt(100), neat)
+
+	// Two TX4s held for the challenge window
+	tx4 := func(nonce uint64, tx3 common.Hash) *types.Transaction {
+		data, err := neatabi.ChainABI.Pack(neatabi.WithdrawFromMainChain.String(), "side", amount, tx3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return types.NewTransaction(nonce, neatabi.ChainContractMagicAddr, new(big.Int), 0, big.NewInt(1), data)
+	}
+	for i, tx3 := range []common.Hash{{0x03}, {0x04}} {
+		header := &types.Header{Number: big.NewInt(int64(10 + i)), Coinbase: common.BytesToAddress([]byte{0x02})}
+		gasFees := new(big.Int)
+		if err := applyCrossChainTransfer(statedb, header, from, neatabi.WithdrawFromMainChain, tx4(uint64(i), tx3), gasFees); err != nil {
+			t.Fatalf("TX4 %d: unexpected error: %v", i, err)
+		}
+		if gasFees.Sign() != 0 || statedb.GetBalance(from).Sign() != 0 {
+			t.Fatalf("TX4 %d: paid out during the challenge window", i)
+		}
+	}
+	if withdrawal := statedb.GetPendingWithdrawal(common.Hash{0x03}); withdrawal == nil || withdrawal.Release != 110 || withdrawal.Amount.Cmp(amount) != 0 {
+		t.Fatalf("pending withdrawal mismatch: have %+v", withdrawal)
+	}
+
+	// The second withdrawal is proven fraudulent
+	if withdrawal := statedb.CancelPendingWithdrawal(common.Hash{0x04}); withdrawal == nil {
+		t.Fatal("fraudulent withdrawal not found")
+	}
+	if withdrawal := statedb.CancelPendingWithdrawal(common.Hash{0x04}); withdrawal != nil {
+		t.Fatal("fraudulent withdrawal cancelled twice")
+	}
+
+	// Nothing due before the end of the window
+	coinbase := common.BytesToAddress([]byte{0x05})
+	gasFees := big.NewInt(1)
+	if have := ReleaseWithdrawals(statedb, &types.Header{Number: big.NewInt(109), Coinbase: coinbase}, gasFees); have.Cmp(gasFees) != 0 {
+		t.Fatalf("gas fees mismatch before release: have %v, want %v", have, gasFees)
+	}
+
+	// The first withdrawal is released, minus the fee
+	have := ReleaseWithdrawals(statedb, &types.Header{Number: big.NewInt(110), Coinbase: coinbase}, gasFees)
+	if want := big.NewInt(75e16 + 1); have.Cmp(want) != 0 {
+		t.Errorf("gas fees mismatch: have %v, want %v", have, want)
+	}
+	if gasFees.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("gas fees of the block modified: have %v", gasFees)
+	}
+	if have, want := statedb.GetBalance(from), new(big.Int).Mul(big.NewInt(99), neat); have.Cmp(want) != 0 {
+		t.Errorf("withdrawn balance mismatch: have %v, want %v", have, want)
+	}
+	if have, want := statedb.GetBalance(coinbase), big.NewInt(25e16); have.Cmp(want) != 0 {
+		t.Errorf("relayer fee mismatch: have %v, want %v", have, want)
+	}
+	if withdrawals := statedb.GetPendingWithdrawals(); len(withdrawals) != 0 {
+		t.Errorf("withdrawals left after release: have %d", len(withdrawals))
+	}
+}
+
+func TestPendingWithdrawalsOrder(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	from := common.BytesToAddress([]byte{0x01})
+
+	// The challenge window may shrink between withdrawals, so they are not
+	// added in the order of their release
+	for i, release := range []uint64{120, 110, 120, 100} {
+		statedb.AddPendingWithdrawal(common.Hash{byte(i)}, from, "side", big.NewInt(1), release)
+	}
+	snapshot := statedb.Snapshot()
+	statedb.AddPendingWithdrawal(common.Hash{0x04}, from, "side", big.NewInt(1), 90)
+	statedb.CancelPendingWithdrawal(common.Hash{0x01})
+	if released := statedb.ReleasePendingWithdrawals(90); len(released) != 1 {
+		t.Fatalf("released withdrawals mismatch: have %d, want 1", len(released))
+	}
+	statedb.RevertToSnapshot(snapshot)
+
+	for _, want := range [][]common.Hash{{{0x03}}, {{0x01}}, {{0x00}, {0x02}}} {
+		release := statedb.GetPendingWithdrawals()[0].Release
+		released := statedb.ReleasePendingWithdrawals(release)
+		if len(released) != len(want) {
+			t.Fatalf("released at %d mismatch: have %d, want %d", release, len(released), len(want))
+		}
+		for i, withdrawal := range released {
+			if withdrawal.TxHash != want[i] {
+				t.Errorf("released at %d, withdrawal %d mismatch: have %x, want %x", release, i, withdrawal.TxHash, want[i])
+			}
+		}
+	}
+	if withdrawals := statedb.GetPendingWithdrawals(); len(withdrawals) != 0 {
+		t.Errorf("withdrawals left after release: have %d", len(withdrawals))
+	}
+}
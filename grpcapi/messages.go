@@ -0,0 +1,128 @@
+package grpcapi
+
+import "github.com/golang/protobuf/proto"
+
+// The messages of neatio.proto, encoded by the struct tags.
+
+type BlockRequest struct {
+	Hash             []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Number           uint64 `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	Latest           bool   `protobuf:"varint,3,opt,name=latest,proto3" json:"latest,omitempty"`
+	FullTransactions bool   `protobuf:"varint,4,opt,name=full_transactions,proto3" json:"fullTransactions,omitempty"`
+}
+
+func (m *BlockRequest) Reset()         { *m = BlockRequest{} }
+func (m *BlockRequest) String() string { return proto.CompactTextString(m) }
+func (*BlockRequest) ProtoMessage()    {}
+
+type Block struct {
+	Number            uint64         `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	Hash              []byte         `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	ParentHash        []byte         `protobuf:"bytes,3,opt,name=parent_hash,proto3" json:"parentHash,omitempty"`
+	Timestamp         uint64         `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Coinbase          string         `protobuf:"bytes,5,opt,name=coinbase,proto3" json:"coinbase,omitempty"`
+	GasLimit          uint64         `protobuf:"varint,6,opt,name=gas_limit,proto3" json:"gasLimit,omitempty"`
+	GasUsed           uint64         `protobuf:"varint,7,opt,name=gas_used,proto3" json:"gasUsed,omitempty"`
+	StateRoot         []byte         `protobuf:"bytes,8,opt,name=state_root,proto3" json:"stateRoot,omitempty"`
+	TransactionHashes [][]byte       `protobuf:"bytes,9,rep,name=transaction_hashes,proto3" json:"transactionHashes,omitempty"`
+	Transactions      []*Transaction `protobuf:"bytes,10,rep,name=transactions,proto3" json:"transactions,omitempty"`
+}
+
+func (m *Block) Reset()         { *m = Block{} }
+func (m *Block) String() string { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()    {}
+
+type TransactionRequest struct {
+	Hash []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (m *TransactionRequest) Reset()         { *m = TransactionRequest{} }
+func (m *TransactionRequest) String() string { return proto.CompactTextString(m) }
+func (*TransactionRequest) ProtoMessage()    {}
+
+type Transaction struct {
+	Hash        []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	From        string `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To          string `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Value       string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	Nonce       uint64 `protobuf:"varint,5,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Gas         uint64 `protobuf:"varint,6,opt,name=gas,proto3" json:"gas,omitempty"`
+	GasPrice    string `protobuf:"bytes,7,opt,name=gas_price,proto3" json:"gasPrice,omitempty"`
+	Input       []byte `protobuf:"bytes,8,opt,name=input,proto3" json:"input,omitempty"`
+	BlockHash   []byte `protobuf:"bytes,9,opt,name=block_hash,proto3" json:"blockHash,omitempty"`
+	BlockNumber uint64 `protobuf:"varint,10,opt,name=block_number,proto3" json:"blockNumber,omitempty"`
+	Index       uint64 `protobuf:"varint,11,opt,name=index,proto3" json:"index,omitempty"`
+}
+
+func (m *Transaction) Reset()         { *m = Transaction{} }
+func (m *Transaction) String() string { return proto.CompactTextString(m) }
+func (*Transaction) ProtoMessage()    {}
+
+type EpochRequest struct {
+	Number  uint64 `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	Current bool   `protobuf:"varint,2,opt,name=current,proto3" json:"current,omitempty"`
+}
+
+func (m *EpochRequest) Reset()         { *m = EpochRequest{} }
+func (m *EpochRequest) String() string { return proto.CompactTextString(m) }
+func (*EpochRequest) ProtoMessage()    {}
+
+type Validator struct {
+	Address        string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	PubKey         string `protobuf:"bytes,2,opt,name=pub_key,proto3" json:"pubKey,omitempty"`
+	VotingPower    string `protobuf:"bytes,3,opt,name=voting_power,proto3" json:"votingPower,omitempty"`
+	RemainingEpoch uint64 `protobuf:"varint,4,opt,name=remaining_epoch,proto3" json:"remainingEpoch,omitempty"`
+}
+
+func (m *Validator) Reset()         { *m = Validator{} }
+func (m *Validator) String() string { return proto.CompactTextString(m) }
+func (*Validator) ProtoMessage()    {}
+
+type ValidatorSet struct {
+	Epoch      uint64       `protobuf:"varint,1,opt,name=epoch,proto3" json:"epoch,omitempty"`
+	Validators []*Validator `protobuf:"bytes,2,rep,name=validators,proto3" json:"validators,omitempty"`
+}
+
+func (m *ValidatorSet) Reset()         { *m = ValidatorSet{} }
+func (m *ValidatorSet) String() string { return proto.CompactTextString(m) }
+func (*ValidatorSet) ProtoMessage()    {}
+
+type Epoch struct {
+	Number         uint64       `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	StartBlock     uint64       `protobuf:"varint,2,opt,name=start_block,proto3" json:"startBlock,omitempty"`
+	EndBlock       uint64       `protobuf:"varint,3,opt,name=end_block,proto3" json:"endBlock,omitempty"`
+	StartTime      int64        `protobuf:"varint,4,opt,name=start_time,proto3" json:"startTime,omitempty"`
+	EndTime        int64        `protobuf:"varint,5,opt,name=end_time,proto3" json:"endTime,omitempty"`
+	RewardPerBlock string       `protobuf:"bytes,6,opt,name=reward_per_block,proto3" json:"rewardPerBlock,omitempty"`
+	Validators     []*Validator `protobuf:"bytes,7,rep,name=validators,proto3" json:"validators,omitempty"`
+}
+
+func (m *Epoch) Reset()         { *m = Epoch{} }
+func (m *Epoch) String() string { return proto.CompactTextString(m) }
+func (*Epoch) ProtoMessage()    {}
+
+type StreamBlocksRequest struct {
+	FullTransactions bool `protobuf:"varint,1,opt,name=full_transactions,proto3" json:"fullTransactions,omitempty"`
+}
+
+func (m *StreamBlocksRequest) Reset()         { *m = StreamBlocksRequest{} }
+func (m *StreamBlocksRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamBlocksRequest) ProtoMessage()    {}
+
+type StreamConsensusEventsRequest struct {
+}
+
+func (m *StreamConsensusEventsRequest) Reset()         { *m = StreamConsensusEventsRequest{} }
+func (m *StreamConsensusEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*StreamConsensusEventsRequest) ProtoMessage()    {}
+
+type ConsensusEvent struct {
+	Type   string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Height uint64 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Round  int32  `protobuf:"varint,3,opt,name=round,proto3" json:"round,omitempty"`
+	Step   string `protobuf:"bytes,4,opt,name=step,proto3" json:"step,omitempty"`
+}
+
+func (m *ConsensusEvent) Reset()         { *m = ConsensusEvent{} }
+func (m *ConsensusEvent) String() string { return proto.CompactTextString(m) }
+func (*ConsensusEvent) ProtoMessage()    {}
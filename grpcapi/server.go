@@ -0,0 +1,234 @@
+// Package grpcapi serves the block, transaction, validator set and epoch
+// queries of the chains run by a node over gRPC, with streams of the new
+// blocks and of the consensus events. The service is described by
+// neatio.proto.
+//
+// The server implements the gRPC protocol over HTTP/2 without TLS (h2c), with
+// the unary and the server streaming calls of the service and without
+// compression.
+package grpcapi
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/neatlab/neatio/log"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const (
+	// ServiceName is the full name of the service of neatio.proto.
+	ServiceName = "neatio.Neatio"
+
+	// ChainIdHeader is the metadata naming the chain of a call.
+	ChainIdHeader = "chain-id"
+
+	// maxMessageSize is the maximum size of a request message.
+	maxMessageSize = 1024 * 1024
+)
+
+// The gRPC status codes returned by the server.
+const (
+	codeOK              = 0
+	codeCanceled        = 1
+	codeInvalidArgument = 3
+	codeNotFound        = 5
+	codeUnimplemented   = 12
+	codeInternal        = 13
+	codeUnavailable     = 14
+)
+
+// statusError is an error returned with a gRPC status code.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
+func statusErrorf(code int, format string, args ...interface{}) error {
+	return &statusError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+// method is a method of the service, with unary or stream set.
+type method struct {
+	request func() proto.Message
+	unary   func(ctx context.Context, b Backend, req proto.Message) (proto.Message, error)
+	stream  func(ctx context.Context, b Backend, req proto.Message, send func(proto.Message) error) error
+}
+
+// Server serves the gRPC service of the chains added to it.
+type Server struct {
+	lock      sync.RWMutex
+	chains    map[string]Backend
+	mainChain string
+	methods   map[string]*method
+}
+
+// NewServer creates a server of the chains, the calls without chain id go to
+// mainChain.
+func NewServer(mainChain string) *Server {
+	return &Server{
+		chains:    make(map[string]Backend),
+		mainChain: mainChain,
+		methods:   serviceMethods(),
+	}
+}
+
+// AddChain serves the chain chainId.
+func (s *Server) AddChain(chainId string, b Backend) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.chains[chainId] = b
+}
+
+// SetMainChain makes the calls without chain id go to chainId.
+func (s *Server) SetMainChain(chainId string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.mainChain = chainId
+}
+
+// RemoveChain stops serving the chain chainId.
+func (s *Server) RemoveChain(chainId string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.chains, chainId)
+}
+
+// Handler returns the HTTP/2 handler of the server, accepting the
+// connections without TLS.
+func (s *Server) Handler() http.Handler {
+	return h2c.NewHandler(s, &http2.Server{})
+}
+
+// ServeHTTP implements http.Handler for the HTTP/2 requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	err := s.serve(w, r)
+	if err != nil {
+		log.Debug("gRPC call failed", "method", r.URL.Path, "err", err)
+	}
+	writeStatus(w, err)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) error {
+	name := strings.TrimPrefix(r.URL.Path, "/"+ServiceName+"/")
+	m, ok := s.methods[name]
+	if !ok || name == r.URL.Path {
+		return statusErrorf(codeUnimplemented, "unknown method %s", r.URL.Path)
+	}
+	chainId := r.Header.Get(ChainIdHeader)
+	s.lock.RLock()
+	if chainId == "" {
+		chainId = s.mainChain
+	}
+	b, ok := s.chains[chainId]
+	s.lock.RUnlock()
+	if !ok {
+		return statusErrorf(codeNotFound, "unknown chain %s", chainId)
+	}
+
+	req := m.request()
+	if err := readMessage(r.Body, req); err != nil {
+		return err
+	}
+	if m.unary != nil {
+		resp, err := m.unary(r.Context(), b, req)
+		if err != nil {
+			return err
+		}
+		return writeMessage(w, resp)
+	}
+	return m.stream(r.Context(), b, req, func(resp proto.Message) error {
+		return writeMessage(w, resp)
+	})
+}
+
+// readMessage reads a length prefixed message.
+func readMessage(r io.Reader, msg proto.Message) error {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return statusErrorf(codeInvalidArgument, "no request message: %v", err)
+	}
+	if prefix[0] != 0 {
+		return statusErrorf(codeUnimplemented, "compressed messages not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageSize {
+		return statusErrorf(codeInvalidArgument, "request message of %d bytes too large", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return statusErrorf(codeInvalidArgument, "truncated request message: %v", err)
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return statusErrorf(codeInvalidArgument, "invalid request message: %v", err)
+	}
+	return nil
+}
+
+// writeMessage writes a length prefixed message and flushes it to the client.
+func writeMessage(w http.ResponseWriter, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return statusErrorf(codeInternal, "%v", err)
+	}
+	frame := make([]byte, 5+len(data))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(data)))
+	copy(frame[5:], data)
+	if _, err := w.Write(frame); err != nil {
+		return statusErrorf(codeCanceled, "%v", err)
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// writeStatus writes the status of the call in the trailers.
+func writeStatus(w http.ResponseWriter, err error) {
+	code, msg := codeOK, ""
+	if err != nil {
+		code, msg = codeInternal, err.Error()
+		if serr, ok := err.(*statusError); ok {
+			code = serr.code
+		}
+	}
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeGrpcMessage(msg))
+	}
+}
+
+// encodeGrpcMessage percent encodes a status message as gRPC requires.
+func encodeGrpcMessage(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c >= ' ' && c <= '~' && c != '%' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,191 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/event"
+	"github.com/neatlab/neatio/neatdb"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rpc"
+	"golang.org/x/net/http2"
+)
+
+// testBackend is a chain of a single block, without consensus engine.
+type testBackend struct {
+	block    *types.Block
+	headFeed event.Feed
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	key, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(params.TestChainConfig.ChainId)
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(100), params.TxGas, big.NewInt(1), nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &types.Header{Number: big.NewInt(1), Time: big.NewInt(1600000000), GasLimit: 8000000, GasUsed: params.TxGas}
+	return &testBackend{block: types.NewBlock(header, []*types.Transaction{tx}, nil, nil)}
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+func (b *testBackend) ChainDb() neatdb.Database         { return rawdb.NewMemoryDatabase() }
+func (b *testBackend) Engine() consensus.Engine         { return nil }
+func (b *testBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == 1 {
+		return b.block, nil
+	}
+	return nil, nil
+}
+func (b *testBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	if blockHash == b.block.Hash() {
+		return b.block, nil
+	}
+	return nil, nil
+}
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.headFeed.Subscribe(ch)
+}
+
+// h2cClient returns a client of HTTP/2 without TLS.
+func h2cClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+}
+
+func frame(t *testing.T, msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := make([]byte, 5)
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(data)))
+	return append(prefix, data...)
+}
+
+// call makes a call and returns its response body and status.
+func call(t *testing.T, url, method string, req proto.Message) (*http.Response, string) {
+	httpReq, _ := http.NewRequest("POST", url+"/"+ServiceName+"/"+method, bytes.NewReader(frame(t, req)))
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	res, err := h2cClient().Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, res.Trailer.Get("Grpc-Status")
+}
+
+// readResponse reads a response message of a call.
+func readResponse(t *testing.T, r io.Reader, msg proto.Message) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	if _, err := io.ReadFull(r, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := proto.Unmarshal(data, msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestServer(b Backend) *httptest.Server {
+	s := NewServer("neatio")
+	s.AddChain("neatio", b)
+	return httptest.NewServer(s.Handler())
+}
+
+func TestGetBlock(t *testing.T) {
+	b := newTestBackend(t)
+	srv := newTestServer(b)
+	defer srv.Close()
+
+	for _, req := range []*BlockRequest{{Latest: true, FullTransactions: true}, {Number: 1, FullTransactions: true}, {Hash: b.block.Hash().Bytes(), FullTransactions: true}} {
+		res, _ := call(t, srv.URL, "GetBlock", req)
+		var block Block
+		readResponse(t, res.Body, &block)
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if status := res.Trailer.Get("Grpc-Status"); status != "0" {
+			t.Fatalf("%v: status %s: %s", req, status, res.Trailer.Get("Grpc-Message"))
+		}
+		if block.Number != 1 || !bytes.Equal(block.Hash, b.block.Hash().Bytes()) || block.Timestamp != 1600000000 {
+			t.Fatalf("%v: unexpected block %v", req, &block)
+		}
+		if len(block.Transactions) != 1 || block.Transactions[0].Value != "100" || block.Transactions[0].To != (common.Address{1}).String() {
+			t.Fatalf("%v: unexpected transactions %v", req, block.Transactions)
+		}
+	}
+
+	res, _ := call(t, srv.URL, "GetBlock", &BlockRequest{Number: 2})
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if status := res.Trailer.Get("Grpc-Status"); status != "5" {
+		t.Fatalf("missing block: status %s, want 5", status)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := newTestServer(newTestBackend(t))
+	defer srv.Close()
+
+	tests := []struct {
+		method string
+		req    proto.Message
+		status string
+	}{
+		{"Unknown", &BlockRequest{}, "12"},
+		{"GetEpoch", &EpochRequest{Current: true}, "12"},
+		{"GetTransaction", &TransactionRequest{Hash: []byte{1}}, "5"},
+	}
+	for _, tt := range tests {
+		res, _ := call(t, srv.URL, tt.method, tt.req)
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		if status := res.Trailer.Get("Grpc-Status"); status != tt.status {
+			t.Errorf("%s: status %s, want %s", tt.method, status, tt.status)
+		}
+	}
+}
+
+func TestStreamBlocks(t *testing.T) {
+	b := newTestBackend(t)
+	srv := newTestServer(b)
+	defer srv.Close()
+
+	res, _ := call(t, srv.URL, "StreamBlocks", &StreamBlocksRequest{})
+	defer res.Body.Close()
+
+	// Wait for the stream to subscribe before posting the heads
+	for b.headFeed.Send(core.ChainHeadEvent{Block: b.block}) == 0 {
+	}
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			b.headFeed.Send(core.ChainHeadEvent{Block: b.block})
+		}
+		var block Block
+		readResponse(t, res.Body, &block)
+		if block.Number != 1 || len(block.TransactionHashes) != 1 || len(block.Transactions) != 0 {
+			t.Fatalf("unexpected block %v", &block)
+		}
+	}
+}
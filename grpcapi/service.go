@@ -0,0 +1,259 @@
+package grpcapi
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	"github.com/neatlab/neatio/consensus/neatpos/epoch"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/event"
+	"github.com/neatlab/neatio/neatdb"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rpc"
+)
+
+// streamBuffer is the number of events buffered for a slow stream, the next
+// ones are dropped.
+const streamBuffer = 128
+
+// Backend gives access to a chain, it is implemented by the API backend of
+// the chains.
+type Backend interface {
+	ChainConfig() *params.ChainConfig
+	ChainDb() neatdb.Database
+	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
+	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
+	Engine() consensus.Engine
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+func serviceMethods() map[string]*method {
+	return map[string]*method{
+		"GetBlock": {
+			request: func() proto.Message { return new(BlockRequest) },
+			unary: func(ctx context.Context, b Backend, req proto.Message) (proto.Message, error) {
+				return getBlock(ctx, b, req.(*BlockRequest))
+			},
+		},
+		"GetTransaction": {
+			request: func() proto.Message { return new(TransactionRequest) },
+			unary: func(ctx context.Context, b Backend, req proto.Message) (proto.Message, error) {
+				return getTransaction(b, req.(*TransactionRequest))
+			},
+		},
+		"GetValidatorSet": {
+			request: func() proto.Message { return new(EpochRequest) },
+			unary: func(ctx context.Context, b Backend, req proto.Message) (proto.Message, error) {
+				ep, err := getEpoch(b, req.(*EpochRequest))
+				if err != nil {
+					return nil, err
+				}
+				return &ValidatorSet{Epoch: ep.Number, Validators: ep.Validators}, nil
+			},
+		},
+		"GetEpoch": {
+			request: func() proto.Message { return new(EpochRequest) },
+			unary: func(ctx context.Context, b Backend, req proto.Message) (proto.Message, error) {
+				return getEpoch(b, req.(*EpochRequest))
+			},
+		},
+		"StreamBlocks": {
+			request: func() proto.Message { return new(StreamBlocksRequest) },
+			stream: func(ctx context.Context, b Backend, req proto.Message, send func(proto.Message) error) error {
+				return streamBlocks(ctx, b, req.(*StreamBlocksRequest), send)
+			},
+		},
+		"StreamConsensusEvents": {
+			request: func() proto.Message { return new(StreamConsensusEventsRequest) },
+			stream: func(ctx context.Context, b Backend, req proto.Message, send func(proto.Message) error) error {
+				return streamConsensusEvents(ctx, b, send)
+			},
+		},
+	}
+}
+
+func getBlock(ctx context.Context, b Backend, req *BlockRequest) (*Block, error) {
+	var (
+		block *types.Block
+		err   error
+	)
+	switch {
+	case len(req.Hash) > 0:
+		block, err = b.GetBlock(ctx, common.BytesToHash(req.Hash))
+	case req.Latest:
+		block, err = b.BlockByNumber(ctx, rpc.LatestBlockNumber)
+	default:
+		block, err = b.BlockByNumber(ctx, rpc.BlockNumber(req.Number))
+	}
+	if err != nil {
+		return nil, statusErrorf(codeUnavailable, "%v", err)
+	}
+	if block == nil {
+		return nil, statusErrorf(codeNotFound, "block not found")
+	}
+	return newBlock(b.ChainConfig(), block, req.FullTransactions), nil
+}
+
+func getTransaction(b Backend, req *TransactionRequest) (*Transaction, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.ChainDb(), common.BytesToHash(req.Hash))
+	if tx == nil {
+		return nil, statusErrorf(codeNotFound, "transaction not found")
+	}
+	signer := types.MakeSigner(b.ChainConfig(), new(big.Int).SetUint64(blockNumber))
+	return newTransaction(signer, tx, blockHash, blockNumber, index), nil
+}
+
+// getEpoch returns the epoch of the request, the epochs after the current one
+// are not known.
+func getEpoch(b Backend, req *EpochRequest) (*Epoch, error) {
+	engine, ok := b.Engine().(consensus.NeatPoS)
+	if !ok {
+		return nil, statusErrorf(codeUnimplemented, "chain without epochs")
+	}
+	ep := engine.GetEpoch()
+	if ep == nil {
+		return nil, statusErrorf(codeUnavailable, "epoch not loaded")
+	}
+	if !req.Current && req.Number != ep.Number {
+		if req.Number > ep.Number {
+			return nil, statusErrorf(codeNotFound, "epoch %d not started", req.Number)
+		}
+		db := ep.GetDB()
+		if epoch.LoadEpochBytes(db, req.Number) == nil {
+			return nil, statusErrorf(codeNotFound, "epoch %d not found", req.Number)
+		}
+		ep = epoch.LoadOneEpoch(db, req.Number, nil)
+	}
+
+	result := &Epoch{
+		Number:     ep.Number,
+		StartBlock: ep.StartBlock,
+		EndBlock:   ep.EndBlock,
+		StartTime:  ep.StartTime.Unix(),
+		EndTime:    ep.EndTime.Unix(),
+	}
+	if ep.RewardPerBlock != nil {
+		result.RewardPerBlock = ep.RewardPerBlock.String()
+	}
+	if ep.Validators != nil {
+		for _, val := range ep.Validators.Validators {
+			result.Validators = append(result.Validators, &Validator{
+				Address:        common.BytesToAddress(val.Address).String(),
+				PubKey:         val.PubKey.KeyString(),
+				VotingPower:    val.VotingPower.String(),
+				RemainingEpoch: val.RemainingEpoch,
+			})
+		}
+	}
+	return result, nil
+}
+
+func streamBlocks(ctx context.Context, b Backend, req *StreamBlocksRequest, send func(proto.Message) error) error {
+	heads := make(chan core.ChainHeadEvent, streamBuffer)
+	sub := b.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	config := b.ChainConfig()
+	for {
+		select {
+		case head := <-heads:
+			if err := send(newBlock(config, head.Block, req.FullTransactions)); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			return statusErrorf(codeUnavailable, "chain stopped: %v", err)
+		case <-ctx.Done():
+			return statusErrorf(codeCanceled, "%v", ctx.Err())
+		}
+	}
+}
+
+func streamConsensusEvents(ctx context.Context, b Backend, send func(proto.Message) error) error {
+	engine, ok := b.Engine().(consensus.NeatPoS)
+	if !ok {
+		return statusErrorf(codeUnimplemented, "chain without consensus events")
+	}
+	// The consensus waits for the subscribers to receive the events, they are
+	// relayed to a buffer dropping them when the stream is too slow
+	events := make(chan consensus.ConsensusEvent)
+	sub := engine.SubscribeConsensusEvent(events)
+	defer sub.Unsubscribe()
+
+	buffered := make(chan consensus.ConsensusEvent, streamBuffer)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case ev := <-events:
+				select {
+				case buffered <- ev:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case ev := <-buffered:
+			if err := send(&ConsensusEvent{Type: ev.Type, Height: ev.Height, Round: int32(ev.Round), Step: ev.Step}); err != nil {
+				return err
+			}
+		case err := <-sub.Err():
+			return statusErrorf(codeUnavailable, "consensus stopped: %v", err)
+		case <-ctx.Done():
+			return statusErrorf(codeCanceled, "%v", ctx.Err())
+		}
+	}
+}
+
+func newBlock(config *params.ChainConfig, block *types.Block, fullTx bool) *Block {
+	header := block.Header()
+	result := &Block{
+		Number:     block.NumberU64(),
+		Hash:       block.Hash().Bytes(),
+		ParentHash: header.ParentHash.Bytes(),
+		Timestamp:  block.Time(),
+		Coinbase:   header.Coinbase.String(),
+		GasLimit:   header.GasLimit,
+		GasUsed:    header.GasUsed,
+		StateRoot:  header.Root.Bytes(),
+	}
+	signer := types.MakeSigner(config, block.Number())
+	for i, tx := range block.Transactions() {
+		if fullTx {
+			result.Transactions = append(result.Transactions, newTransaction(signer, tx, block.Hash(), block.NumberU64(), uint64(i)))
+		} else {
+			result.TransactionHashes = append(result.TransactionHashes, tx.Hash().Bytes())
+		}
+	}
+	return result
+}
+
+func newTransaction(signer types.Signer, tx *types.Transaction, blockHash common.Hash, blockNumber, index uint64) *Transaction {
+	from, _ := types.Sender(signer, tx)
+	result := &Transaction{
+		Hash:        tx.Hash().Bytes(),
+		From:        from.String(),
+		Value:       tx.Value().String(),
+		Nonce:       tx.Nonce(),
+		Gas:         tx.Gas(),
+		GasPrice:    tx.GasPrice().String(),
+		Input:       tx.Data(),
+		BlockHash:   blockHash.Bytes(),
+		BlockNumber: blockNumber,
+		Index:       index,
+	}
+	if tx.To() != nil {
+		result.To = tx.To().String()
+	}
+	return result
+}
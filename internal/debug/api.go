@@ -22,7 +22,10 @@ package debug
 
 import (
 	"errors"
+	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -34,6 +37,8 @@ import (
 	"time"
 
 	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/metrics"
+	"github.com/neatlab/neatio/metrics/exp"
 )
 
 // Handler is the global debugging handler.
@@ -48,6 +53,7 @@ type HandlerT struct {
 	cpuFile   string
 	traceW    io.WriteCloser
 	traceFile string
+	pprof     *http.Server
 }
 
 // Verbosity sets the log verbosity ceiling. The verbosity of individual packages
@@ -68,6 +74,95 @@ func (*HandlerT) Vmodule(pattern string) error {
 	return glogger.Vmodule(pattern)
 }
 
+// moduleAliases are the short names SetVmoduleLevel accepts for the
+// modules whose logs are raised most often.
+var moduleAliases = map[string]string{
+	"consensus":  "consensus/neatpos/*",
+	"reactor":    "consensus/neatpos/consensus/reactor.go",
+	"txpool":     "core/tx_pool.go",
+	"downloader": "neatptc/downloader/*",
+	"p2p":        "p2p/*",
+	"rpc":        "rpc/*",
+	"miner":      "miner/*",
+}
+
+// logHandlers returns the log handlers of the given chain, or of the root and
+// all chain loggers if chain is nil, keyed by chain id ("root" for the root
+// logger).
+func logHandlers(chain *string) (map[string]*log.GlogHandler, error) {
+	handlers := make(map[string]*log.GlogHandler)
+	if chain != nil {
+		logger := log.GetLogger(*chain)
+		if logger == nil {
+			return nil, fmt.Errorf("unknown chain %q", *chain)
+		}
+		if h, ok := logger.GetHandler().(*log.GlogHandler); ok {
+			handlers[*chain] = h
+		}
+		return handlers, nil
+	}
+	if h, ok := log.Root().GetHandler().(*log.GlogHandler); ok {
+		handlers["root"] = h
+	}
+	log.RangeLogger(func(key, value interface{}) bool {
+		if logger, ok := value.(log.Logger); ok {
+			if h, ok := logger.GetHandler().(*log.GlogHandler); ok {
+				handlers[key.(string)] = h
+			}
+		}
+		return true
+	})
+	return handlers, nil
+}
+
+// SetVerbosity sets the log verbosity ceiling of a chain, or of all chains if
+// no chain is given.
+func (h *HandlerT) SetVerbosity(level int, chain *string) error {
+	if level < int(log.LvlCrit) || level > int(log.LvlTrace) {
+		return fmt.Errorf("invalid verbosity %d, want %d-%d", level, log.LvlCrit, log.LvlTrace)
+	}
+	handlers, err := logHandlers(chain)
+	if err != nil {
+		return err
+	}
+	for _, handler := range handlers {
+		handler.Verbosity(log.Lvl(level))
+	}
+	log.Info("Log verbosity changed", "level", level, "chains", len(handlers))
+	return nil
+}
+
+// SetVmoduleLevel sets the log verbosity of a single module of a chain, or of
+// all chains if no chain is given, keeping the levels of other modules. The
+// module is a vmodule pattern, e.g. "core/tx_pool.go" or "p2p/*", or one of
+// the aliases consensus, reactor, txpool, downloader, p2p, rpc and miner. A
+// level of 0 resets the module to the global verbosity. The resulting vmodule
+// patterns are returned per chain.
+func (h *HandlerT) SetVmoduleLevel(module string, level int, chain *string) (map[string]string, error) {
+	if level < 0 || level > int(log.LvlTrace) {
+		return nil, fmt.Errorf("invalid verbosity %d, want 0-%d", level, log.LvlTrace)
+	}
+	if pattern, ok := moduleAliases[module]; ok {
+		module = pattern
+	}
+	handlers, err := logHandlers(chain)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	rules := make(map[string]string)
+	for id, handler := range handlers {
+		if err := handler.SetVmoduleLevel(module, log.Lvl(level)); err != nil {
+			return nil, err
+		}
+		rules[id] = handler.VmoduleRules()
+	}
+	log.Info("Log module verbosity changed", "module", module, "level", level, "chains", len(handlers))
+	return rules, nil
+}
+
 // BacktraceAt sets the log backtrace location. See package log for details on
 // the pattern syntax.
 func (*HandlerT) BacktraceAt(location string) error {
@@ -187,6 +282,68 @@ func (*HandlerT) WriteMutexProfile(file string) error {
 	return writeProfile("mutex", file)
 }
 
+// StartPProf starts the pprof HTTP server on the given address, serving the
+// profiles on /debug/pprof and the metrics on /debug/metrics.
+func (h *HandlerT) StartPProf(address string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pprof != nil {
+		return errors.New("pprof server already running")
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	// The pprof handlers register themselves on the default mux
+	mux := http.NewServeMux()
+	mux.Handle("/debug/", http.DefaultServeMux)
+	mux.Handle("/debug/metrics", exp.ExpHandler(metrics.DefaultRegistry))
+	h.pprof = &http.Server{Handler: mux}
+
+	log.Info("Starting pprof server", "addr", fmt.Sprintf("http://%s/debug/pprof", listener.Addr()))
+	go func(srv *http.Server) {
+		if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("Failure in running pprof server", "err", err)
+		}
+	}(h.pprof)
+	return nil
+}
+
+// StopPProf stops the pprof HTTP server.
+func (h *HandlerT) StopPProf() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.pprof == nil {
+		return errors.New("pprof server not running")
+	}
+	err := h.pprof.Close()
+	h.pprof = nil
+	log.Info("pprof server stopped")
+	return err
+}
+
+// CaptureProfiles writes a CPU profile sampled for nsec seconds, a heap profile
+// and the goroutine stacks into dir. The CPU profile is skipped if CPU
+// profiling is already in progress.
+func (h *HandlerT) CaptureProfiles(dir string, nsec uint) error {
+	if err := os.MkdirAll(expandHome(dir), 0700); err != nil {
+		return err
+	}
+	prefix := filepath.Join(expandHome(dir), time.Now().Format("20060102-150405"))
+	if err := writeProfile("goroutine", prefix+"-goroutine.pprof"); err != nil {
+		return err
+	}
+	if err := writeProfile("heap", prefix+"-heap.pprof"); err != nil {
+		return err
+	}
+	if err := h.StartCPUProfile(prefix + "-cpu.pprof"); err != nil {
+		log.Warn("Skipping CPU profile", "err", err)
+		return nil
+	}
+	time.Sleep(time.Duration(nsec) * time.Second)
+	return h.StopCPUProfile()
+}
+
 // WriteMemProfile writes an allocation profile to the given file.
 // Note that the profiling rate cannot be set through the API,
 // it must be set on the command line.
@@ -19,7 +19,6 @@ package debug
 import (
 	"fmt"
 	"io"
-	"net/http"
 	_ "net/http/pprof"
 	"os"
 	"runtime"
@@ -27,8 +26,6 @@ import (
 	colorable "github.com/mattn/go-colorable"
 	"github.com/neatlab/neatio/log"
 	"github.com/neatlab/neatio/log/term"
-	"github.com/neatlab/neatio/metrics"
-	"github.com/neatlab/neatio/metrics/exp"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -48,6 +45,11 @@ var (
 		Usage: "Request a stack trace at a specific logging statement (e.g. \"block.go:271\")",
 		Value: "",
 	}
+	logFormatFlag = cli.StringFlag{
+		Name:  "log.format",
+		Usage: "Log format to use (terminal, logfmt, json)",
+		Value: "terminal",
+	}
 	debugFlag = cli.BoolFlag{
 		Name:  "debug",
 		Usage: "Prepends log messages with call-site location (file and line number)",
@@ -87,7 +89,7 @@ var (
 
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
-	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
+	verbosityFlag, vmoduleFlag, backtraceAtFlag, logFormatFlag, debugFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
 }
@@ -145,17 +147,10 @@ func Setup(ctx *cli.Context) error {
 
 	// pprof server
 	if ctx.GlobalBool(pprofFlag.Name) {
-		// Hook go-metrics into expvar on any /debug/metrics request, load all vars
-		// from the registry into expvar, and execute regular expvar handler.
-		exp.Exp(metrics.DefaultRegistry)
-
 		address := fmt.Sprintf("%s:%d", ctx.GlobalString(pprofAddrFlag.Name), ctx.GlobalInt(pprofPortFlag.Name))
-		go func() {
-			log.Info("Starting pprof server", "addr", fmt.Sprintf("http://%s/debug/pprof", address))
-			if err := http.ListenAndServe(address, nil); err != nil {
-				log.Error("Failure in running pprof server", "err", err)
-			}
-		}()
+		if err := Handler.StartPProf(address); err != nil {
+			return err
+		}
 	}
 	return nil
 }
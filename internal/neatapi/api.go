@@ -22,18 +22,22 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/neatlab/neatio/consensus"
 	"github.com/neatlab/neatio/consensus/neatpos/epoch"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
 	"github.com/neatlab/neatio/core/state"
 
 	"github.com/neatlab/neatio/accounts"
 	"github.com/neatlab/neatio/accounts/keystore"
+	"github.com/neatlab/neatio/accounts/typeddata"
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/common/hexutil"
 	"github.com/neatlab/neatio/common/math"
+	"github.com/neatlab/neatio/common/neataddr"
 	"github.com/neatlab/neatio/core"
 	"github.com/neatlab/neatio/core/rawdb"
 	"github.com/neatlab/neatio/core/types"
@@ -84,10 +88,12 @@ func (s *PublicNeatioAPI) ProtocolVersion() hexutil.Uint {
 // - pulledStates:  number of state entries processed until now
 // - knownStates:   number of known state entries that still need to be pulled
 func (s *PublicNeatioAPI) Syncing() (interface{}, error) {
-	progress := s.b.Downloader().Progress()
+	downloader := s.b.Downloader()
+	progress := downloader.Progress()
 
-	// Return not syncing if the synchronisation already completed
-	if progress.CurrentBlock >= progress.HighestBlock {
+	// Return not syncing if no synchronisation is running or it already completed,
+	// the consensus keeps importing the blocks once the node caught up
+	if !downloader.Synchronising() || progress.CurrentBlock >= progress.HighestBlock {
 		return false, nil
 	}
 	// Otherwise gather the block sync stats
@@ -453,6 +459,28 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 	return signature, nil
 }
 
+// SignTypedData calculates an EIP-712 signature over the typed data, unlocking
+// the account with the given passphrase for this signature only.
+func (s *PrivateAccountAPI) SignTypedData(ctx context.Context, data typeddata.TypedData, addr common.Address, passwd string) (hexutil.Bytes, error) {
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wallet.SignHashWithPassphrase(account, passwd, hash)
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // EcRecover returns the address for the account that was used to create the signature.
 // Note, this function is compatible with eth_sign and personal_sign. As such it recovers
 // the address of:
@@ -700,17 +728,13 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
-
-	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
-	if state == nil || err != nil {
-		return nil, 0, false, err
-	}
+// ToMessage converts the call arguments to the message executed, defaulting the
+// sender to the first account of the node and the gas and gas price when unset.
+func (args *CallArgs) ToMessage(am *accounts.Manager) types.Message {
 	// Set sender address or use a default if none specified
 	addr := args.From
-	if addr == (common.Address{}) {
-		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+	if addr == (common.Address{}) && am != nil {
+		if wallets := am.Wallets(); len(wallets) > 0 {
 			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
 				addr = accounts[0].Address
 			}
@@ -724,9 +748,18 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
+	return types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+}
 
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *BlockOverrides, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, 0, false, err
+	}
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	msg := args.ToMessage(s.b.AccountManager())
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -741,7 +774,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	defer cancel()
 
 	// Get a new instance of the EVM.
-	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, vmCfg)
+	evm, vmError, err := s.b.GetEVM(ctx, msg, state, header, overrides, vmCfg)
 	if err != nil {
 		return nil, 0, false, err
 	}
@@ -764,8 +797,9 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
+// The optional overrides replace the number, time, gas limit and coinbase of the block.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *BlockOverrides) (hexutil.Bytes, error) {
+	result, _, _, err := s.doCall(ctx, args, blockNr, overrides, vm.Config{}, 5*time.Second)
 	return (hexutil.Bytes)(result), err
 }
 
@@ -795,7 +829,7 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	executable := func(gas uint64) bool {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
+		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, nil, vm.Config{}, 0)
 		if err != nil || failed {
 			return false
 		}
@@ -1034,11 +1068,12 @@ func newRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransa
 type PublicTransactionPoolAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
+	receipts  *receiptLoader
 }
 
 // NewPublicTransactionPoolAPI creates a new RPC service with methods specific for the transaction pool.
 func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTransactionPoolAPI {
-	return &PublicTransactionPoolAPI{b, nonceLock}
+	return &PublicTransactionPoolAPI{b, nonceLock, newReceiptLoader(b)}
 }
 
 // GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
@@ -1104,8 +1139,8 @@ func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, addr
 // GetTransactionByHash returns the transaction for the given hash
 func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) *RPCTransaction {
 	// Try to return an already finalized transaction
-	if tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash); tx != nil {
-		return newRPCTransaction(tx, blockHash, blockNumber, index)
+	if tx, _, block, index, _ := s.receipts.transaction(ctx, hash); tx != nil {
+		return newRPCTransaction(tx, block.Hash(), block.NumberU64(), index)
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
@@ -1159,19 +1194,37 @@ type Log struct {
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
 func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
-	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(s.b.ChainDb(), hash)
-	if tx == nil {
-		return nil, nil
+	tx, receipt, block, index, err := s.receipts.transaction(ctx, hash)
+	if receipt == nil || err != nil {
+		return nil, err
+	}
+	return rpcOutputReceipt(tx, receipt, block.Hash(), block.NumberU64(), index), nil
+}
+
+// GetBlockReceipts returns the receipts of all the transactions of the given block.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNr rpc.BlockNumber) ([]map[string]interface{}, error) {
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if block == nil || err != nil {
+		return nil, err
 	}
-	receipts, err := s.b.GetReceipts(ctx, blockHash)
+	loaded, err := s.receipts.loadBlock(ctx, block)
 	if err != nil {
 		return nil, err
 	}
-	if len(receipts) <= int(index) {
-		return nil, nil
+	txs, receipts := block.Transactions(), loaded.receipts
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts of block %d not found", block.NumberU64())
 	}
-	receipt := receipts[index]
+	fields := make([]map[string]interface{}, len(txs))
+	for i, tx := range txs {
+		fields[i] = rpcOutputReceipt(tx, receipts[i], block.Hash(), block.NumberU64(), uint64(i))
+	}
+	return fields, nil
+}
 
+// rpcOutputReceipt converts the receipt of the transaction at the given index
+// of a block into the RPC representation.
+func rpcOutputReceipt(tx *types.Transaction, receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, index uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
 		signer = types.NewEIP155Signer(tx.ChainId())
@@ -1187,12 +1240,14 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from.String(),
 		"to":                to,
 		"gasUsed":           hexutil.Uint64(receipt.GasUsed),
 		"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+		"effectiveGasPrice": (*hexutil.Big)(tx.GasPrice()),
+		"type":              hexutil.Uint(0),
 		"contractAddress":   nil,
 		"logs":              receipt.Logs,
 		"logsBloom":         receipt.Bloom,
@@ -1229,7 +1284,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress.String()
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1474,6 +1529,27 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData_v4 calculates an EIP-712 signature over the typed data with
+// the given, unlocked account, compatible with eth_signTypedData_v4.
+func (s *PublicTransactionPoolAPI) SignTypedData_v4(addr common.Address, data typeddata.TypedData) (hexutil.Bytes, error) {
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := data.Hash()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wallet.SignHash(account, hash)
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -1685,6 +1761,11 @@ var (
 	maxDelegationAddresses = 1000
 
 	maxEditValidatorLength = 100
+
+	maxGovTitleLength       = 140
+	maxGovDescriptionLength = 5000
+	maxOpenGovProposals     = 100 // Proposals in their deposit or voting period at once
+	maxGovProposalEpochs    = 100 // Epochs ahead a parameter change can be proposed for
 )
 
 type PublicNeatApi struct {
@@ -1698,6 +1779,21 @@ func NewPublicNeatApi(b Backend, nonceLock *AddrLocker) *PublicNeatApi {
 	return &PublicNeatApi{b.AccountManager(), b, nonceLock}
 }
 
+// PrivateNeatApi sends the transactions of the chain contract signed with the
+// accounts of the node: running a validator, delegating, governance, bridging
+// and moving funds across the chains. It is served in the privileged operator
+// namespace, not to let anyone reaching the node act with its accounts.
+type PrivateNeatApi struct {
+	am        *accounts.Manager
+	b         Backend
+	nonceLock *AddrLocker
+}
+
+// NewPrivateNeatApi creates a new NEAT operator API instance.
+func NewPrivateNeatApi(b Backend, nonceLock *AddrLocker) *PrivateNeatApi {
+	return &PrivateNeatApi{b.AccountManager(), b, nonceLock}
+}
+
 func (s *PublicNeatApi) SignAddress(from common.Address, consensusPrivateKey hexutil.Bytes) (goCrypto.Signature, error) {
 	if len(consensusPrivateKey) != 32 {
 		return nil, errors.New("invalid consensus private key")
@@ -1711,7 +1807,12 @@ func (s *PublicNeatApi) SignAddress(from common.Address, consensusPrivateKey hex
 	return blsSign, nil
 }
 
-func (api *PublicNeatApi) WithdrawReward(ctx context.Context, from common.Address, delegateAddress common.Address, gasPrice *hexutil.Big) (common.Hash, error) {
+// ConvertAddress returns both the NEAT and the checksummed hex form of an address given in either form.
+func (s *PublicNeatApi) ConvertAddress(address string) (*neataddr.Formats, error) {
+	return neataddr.Convert(address)
+}
+
+func (api *PrivateNeatApi) WithdrawReward(ctx context.Context, from common.Address, delegateAddress common.Address, gasPrice *hexutil.Big) (common.Hash, error) {
 	input, err := neatabi.ChainABI.Pack(neatabi.WithdrawReward.String(), delegateAddress)
 	if err != nil {
 		return common.Hash{}, err
@@ -1732,7 +1833,79 @@ func (api *PublicNeatApi) WithdrawReward(ctx context.Context, from common.Addres
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func (api *PublicNeatApi) Delegate(ctx context.Context, from, candidate common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+// SetAutoCompound opts the delegation of from to candidate in or out of
+// auto-compounding: its rewards are then delegated again at each epoch
+// boundary instead of accruing as claimable.
+func (api *PrivateNeatApi) SetAutoCompound(ctx context.Context, from common.Address, candidate common.Address, enabled bool, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.SetAutoCompound.String(), candidate, enabled)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := neatabi.SetAutoCompound.RequiredGas()
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+type ClaimableRewardResult struct {
+	Validator    common.Address `json:"validator"`
+	Amount       *hexutil.Big   `json:"amount"`
+	AutoCompound bool           `json:"autoCompound"`
+}
+
+type RewardsResult struct {
+	Address     common.Address           `json:"address"`
+	Claimable   *hexutil.Big             `json:"claimable"`
+	Validators  []*ClaimableRewardResult `json:"validators"`
+	Claimed     *hexutil.Big             `json:"claimed"`
+	Withdrawals hexutil.Uint64           `json:"withdrawals"`
+	LastBlock   hexutil.Uint64           `json:"lastWithdrawBlock"`
+}
+
+// GetRewards returns the rewards address can withdraw, by validator it earned
+// them with, and the rewards it withdrew so far.
+func (api *PublicNeatApi) GetRewards(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*RewardsResult, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	claim := statedb.GetRewardClaim(address)
+	result := &RewardsResult{
+		Address:     address,
+		Claimable:   (*hexutil.Big)(statedb.GetTotalRewardBalance(address)),
+		Validators:  make([]*ClaimableRewardResult, 0),
+		Claimed:     (*hexutil.Big)(claim.Claimed),
+		Withdrawals: hexutil.Uint64(claim.Count),
+		LastBlock:   hexutil.Uint64(claim.LastBlock),
+	}
+	statedb.ForEachReward(address, func(key common.Address, rewardBalance *big.Int) bool {
+		if rewardBalance.Sign() > 0 {
+			result.Validators = append(result.Validators, &ClaimableRewardResult{
+				Validator:    key,
+				Amount:       (*hexutil.Big)(new(big.Int).Set(rewardBalance)),
+				AutoCompound: statedb.IsAutoCompound(address, key),
+			})
+		}
+		return true
+	})
+	sort.Slice(result.Validators, func(i, j int) bool {
+		return bytes.Compare(result.Validators[i].Validator[:], result.Validators[j].Validator[:]) < 0
+	})
+	return result, statedb.Error()
+}
+
+func (api *PrivateNeatApi) Delegate(ctx context.Context, from, candidate common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
 
 	input, err := neatabi.ChainABI.Pack(neatabi.Delegate.String(), candidate)
 	if err != nil {
@@ -1753,7 +1926,7 @@ func (api *PublicNeatApi) Delegate(ctx context.Context, from, candidate common.A
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func (api *PublicNeatApi) UnDelegate(ctx context.Context, from, candidate common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+func (api *PrivateNeatApi) UnDelegate(ctx context.Context, from, candidate common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
 
 	input, err := neatabi.ChainABI.Pack(neatabi.UnDelegate.String(), candidate, (*big.Int)(amount))
 	if err != nil {
@@ -1775,7 +1948,7 @@ func (api *PublicNeatApi) UnDelegate(ctx context.Context, from, candidate common
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func (api *PublicNeatApi) Register(ctx context.Context, from common.Address, registerAmount *hexutil.Big, pubkey goCrypto.BLSPubKey, signature hexutil.Bytes, commission uint8, gasPrice *hexutil.Big) (common.Hash, error) {
+func (api *PrivateNeatApi) Register(ctx context.Context, from common.Address, registerAmount *hexutil.Big, pubkey goCrypto.BLSPubKey, signature hexutil.Bytes, commission uint8, gasPrice *hexutil.Big) (common.Hash, error) {
 
 	input, err := neatabi.ChainABI.Pack(neatabi.Register.String(), pubkey.Bytes(), signature, commission)
 	if err != nil {
@@ -1796,7 +1969,7 @@ func (api *PublicNeatApi) Register(ctx context.Context, from common.Address, reg
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func (api *PublicNeatApi) UnRegister(ctx context.Context, from common.Address, gasPrice *hexutil.Big) (common.Hash, error) {
+func (api *PrivateNeatApi) UnRegister(ctx context.Context, from common.Address, gasPrice *hexutil.Big) (common.Hash, error) {
 
 	input, err := neatabi.ChainABI.Pack(neatabi.UnRegister.String())
 	if err != nil {
@@ -1827,6 +2000,23 @@ func (api *PublicNeatApi) CheckCandidate(ctx context.Context, address common.Add
 		"candidate":  state.IsCandidate(address),
 		"commission": state.GetCommission(address),
 	}
+	if change := state.GetCommissionChange(address); change != nil {
+		fields["pendingCommission"] = change.Commission
+		fields["pendingCommissionEpoch"] = change.Epoch
+	}
+	if minSelfBond := state.GetMinSelfBond(address); minSelfBond.Sign() > 0 {
+		fields["selfBond"] = (*hexutil.Big)(state.GetSelfBond(address))
+		fields["minSelfBond"] = (*hexutil.Big)(minSelfBond)
+	}
+	if metadata := state.GetValidatorMetadata(address); metadata != nil {
+		fields["metadata"] = map[string]string{
+			"moniker":         metadata.Moniker,
+			"website":         metadata.Website,
+			"identity":        metadata.Identity,
+			"details":         metadata.Details,
+			"securityContact": metadata.SecurityContact,
+		}
+	}
 	return fields, state.Error()
 }
 
@@ -1844,7 +2034,7 @@ func (api *PublicNeatApi) GetBannedStatus(ctx context.Context, address common.Ad
 	return fields, state.Error()
 }
 
-func (api *PublicNeatApi) SetCommission(ctx context.Context, from common.Address, commission uint8, gasPrice *hexutil.Big) (common.Hash, error) {
+func (api *PrivateNeatApi) SetCommission(ctx context.Context, from common.Address, commission uint8, gasPrice *hexutil.Big) (common.Hash, error) {
 	input, err := neatabi.ChainABI.Pack(neatabi.SetCommission.String(), commission)
 	if err != nil {
 		return common.Hash{}, err
@@ -1865,13 +2055,15 @@ func (api *PublicNeatApi) SetCommission(ctx context.Context, from common.Address
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func (api *PublicNeatApi) EditValidator(ctx context.Context, from common.Address, moniker, website string, identity string, details string, gasPrice *hexutil.Big) (common.Hash, error) {
-	input, err := neatabi.ChainABI.Pack(neatabi.EditValidator.String(), moniker, website, identity, details)
+// SetMinSelfBond sets the stake from commits to keep bonded itself. It can
+// only be raised; falling below it bans the validator at the next epoch.
+func (api *PrivateNeatApi) SetMinSelfBond(ctx context.Context, from common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.SetMinSelfBond.String(), (*big.Int)(amount))
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatabi.EditValidator.RequiredGas()
+	defaultGas := neatabi.SetMinSelfBond.RequiredGas()
 
 	args := SendTxArgs{
 		From:     from,
@@ -1882,17 +2074,16 @@ func (api *PublicNeatApi) EditValidator(ctx context.Context, from common.Address
 		Input:    (*hexutil.Bytes)(&input),
 		Nonce:    nil,
 	}
-
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func (api *PublicNeatApi) UnBanned(ctx context.Context, from common.Address, gasPrice *hexutil.Big) (common.Hash, error) {
-	input, err := neatabi.ChainABI.Pack(neatabi.UnBanned.String())
+func (api *PrivateNeatApi) RotateConsensusKey(ctx context.Context, from common.Address, pubkey goCrypto.BLSPubKey, signature hexutil.Bytes, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.RotateConsensusKey.String(), pubkey.Bytes(), signature)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatabi.UnBanned.RequiredGas()
+	defaultGas := neatabi.RotateConsensusKey.RequiredGas()
 
 	args := SendTxArgs{
 		From:     from,
@@ -1907,130 +2098,900 @@ func (api *PublicNeatApi) UnBanned(ctx context.Context, from common.Address, gas
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func init() {
-	// Withdraw reward
-	core.RegisterValidateCb(neatabi.WithdrawReward, withdrawRewardValidateCb)
-	core.RegisterApplyCb(neatabi.WithdrawReward, withdrawRewardApplyCb)
+// SubmitGovProposal submits a proposal with a deposit. A text proposal has no
+// parameter, value nor epoch.
+func (api *PrivateNeatApi) SubmitGovProposal(ctx context.Context, from common.Address, title, description, param string, value *hexutil.Big, epoch hexutil.Uint64, deposit *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	if value == nil {
+		value = new(hexutil.Big)
+	}
+	input, err := neatabi.ChainABI.Pack(neatabi.SubmitGovProposal.String(), title, description, param, (*big.Int)(value), common.Address{}, uint64(epoch))
+	if err != nil {
+		return common.Hash{}, err
+	}
 
-	// Delegate
-	core.RegisterValidateCb(neatabi.Delegate, delegateValidateCb)
-	core.RegisterApplyCb(neatabi.Delegate, delegateApplyCb)
+	defaultGas := neatabi.SubmitGovProposal.RequiredGas()
 
-	// Cancel Delegate
-	core.RegisterValidateCb(neatabi.UnDelegate, unDelegateValidateCb)
-	core.RegisterApplyCb(neatabi.UnDelegate, unDelegateApplyCb)
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    deposit,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
 
-	// Register
-	core.RegisterValidateCb(neatabi.Register, registerValidateCb)
-	core.RegisterApplyCb(neatabi.Register, registerApplyCb)
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
 
-	// Cancel Register
-	core.RegisterValidateCb(neatabi.UnRegister, unRegisterValidateCb)
-	core.RegisterApplyCb(neatabi.UnRegister, unRegisterApplyCb)
+// SubmitTreasurySpend submits a proposal, with a deposit, to pay amount out of
+// the treasury to recipient.
+func (api *PrivateNeatApi) SubmitTreasurySpend(ctx context.Context, from common.Address, title, description string, recipient common.Address, amount *hexutil.Big, deposit *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.SubmitGovProposal.String(), title, description, state.GovParamTreasurySpend, (*big.Int)(amount), recipient, uint64(0))
+	if err != nil {
+		return common.Hash{}, err
+	}
 
-	// Set Commission
-	core.RegisterValidateCb(neatabi.SetCommission, setCommisstionValidateCb)
-	core.RegisterApplyCb(neatabi.SetCommission, setCommisstionApplyCb)
+	defaultGas := neatabi.SubmitGovProposal.RequiredGas()
 
-	// Edit Validator
-	core.RegisterValidateCb(neatabi.EditValidator, editValidatorValidateCb)
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    deposit,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
 
-	// UnBanned
-	core.RegisterValidateCb(neatabi.UnBanned, unBannedValidateCb)
-	core.RegisterApplyCb(neatabi.UnBanned, unBannedApplyCb)
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func withdrawRewardValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
-	from := derivedAddressFromTx(tx)
-	_, err := withDrawRewardValidation(from, tx, state, bc)
+// DepositGovProposal adds to the deposit on a proposal in its deposit period.
+func (api *PrivateNeatApi) DepositGovProposal(ctx context.Context, from common.Address, id hexutil.Uint64, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.DepositGovProposal.String(), uint64(id))
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
-	return nil
-}
-
-func withdrawRewardApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
-	from := derivedAddressFromTx(tx)
+	defaultGas := neatabi.DepositGovProposal.RequiredGas()
 
-	args, err := withDrawRewardValidation(from, tx, state, bc)
-	if err != nil {
-		return err
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    amount,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
 	}
 
-	reward := state.GetRewardBalanceByDelegateAddress(from, args.DelegateAddress)
-	state.SubRewardBalanceByDelegateAddress(from, args.DelegateAddress, reward)
-	state.AddBalance(from, reward)
-
-	return nil
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-func withDrawRewardValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.WithdrawRewardArgs, error) {
-
-	var args neatabi.WithdrawRewardArgs
-	data := tx.Data()
-	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.WithdrawReward.String(), data[4:]); err != nil {
-		return nil, err
+func (api *PrivateNeatApi) VoteGovProposal(ctx context.Context, from common.Address, id hexutil.Uint64, approve bool, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.VoteGovProposal.String(), uint64(id), approve)
+	if err != nil {
+		return common.Hash{}, err
 	}
 
-	reward := state.GetRewardBalanceByDelegateAddress(from, args.DelegateAddress)
+	defaultGas := neatabi.VoteGovProposal.RequiredGas()
 
-	if reward.Sign() < 1 {
-		return nil, fmt.Errorf("have no reward to withdraw")
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
 	}
 
-	//if args.Amount.Cmp(reward) == 1 {
-	//	return nil, fmt.Errorf("reward balance not enough, withdraw amount %v, but balance %v, delegate address %v", args.Amount, reward, args.DelegateAddress)
-	//}
-	return &args, nil
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
-// register and unregister
-func registerValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
-	from := derivedAddressFromTx(tx)
-	_, verror := registerValidation(from, tx, state, bc)
-	if verror != nil {
-		return verror
+type GovVoteResult struct {
+	Voter   common.Address `json:"voter"`
+	Approve bool           `json:"approve"`
+	Stake   *hexutil.Big   `json:"stake"`
+}
+
+type GovDepositResult struct {
+	Depositor common.Address `json:"depositor"`
+	Amount    *hexutil.Big   `json:"amount"`
+}
+
+type GovProposalResult struct {
+	Id            hexutil.Uint64      `json:"id"`
+	Proposer      common.Address      `json:"proposer"`
+	Title         string              `json:"title"`
+	Description   string              `json:"description"`
+	Param         string              `json:"param,omitempty"`
+	Value         *hexutil.Big        `json:"value,omitempty"`
+	Recipient     *common.Address     `json:"recipient,omitempty"`
+	Epoch         hexutil.Uint64      `json:"epoch,omitempty"`
+	Status        string              `json:"status"`
+	DepositEnd    hexutil.Uint64      `json:"depositEnd"`
+	VotingEnd     hexutil.Uint64      `json:"votingEnd,omitempty"`
+	TotalDeposit  *hexutil.Big        `json:"totalDeposit"`
+	Deposits      []*GovDepositResult `json:"deposits"`
+	Votes         []*GovVoteResult    `json:"votes"`
+	StakeVoted    *hexutil.Big        `json:"stakeVoted"`
+	StakeApproved *hexutil.Big        `json:"stakeApproved"`
+}
+
+func newGovProposalResult(state *state.StateDB, p *state.GovProposal) *GovProposalResult {
+	result := &GovProposalResult{
+		Id:           hexutil.Uint64(p.Id),
+		Proposer:     p.Proposer,
+		Title:        p.Title,
+		Description:  p.Description,
+		Status:       p.StatusString(),
+		DepositEnd:   hexutil.Uint64(p.DepositEnd),
+		VotingEnd:    hexutil.Uint64(p.VotingEnd),
+		TotalDeposit: (*hexutil.Big)(p.TotalDeposit()),
+		Deposits:     []*GovDepositResult{},
+		Votes:        []*GovVoteResult{},
+	}
+	if !p.IsText() {
+		result.Param = p.Param
+		result.Value = (*hexutil.Big)(p.Value)
+		result.Epoch = hexutil.Uint64(p.Epoch)
+	}
+	if p.IsTreasurySpend() {
+		recipient := p.Recipient
+		result.Recipient = &recipient
+	}
+	for _, d := range p.Deposits {
+		result.Deposits = append(result.Deposits, &GovDepositResult{
+			Depositor: d.Depositor,
+			Amount:    (*hexutil.Big)(d.Amount),
+		})
 	}
-	return nil
+	voted, approved := new(big.Int), new(big.Int)
+	for _, v := range p.Votes {
+		stake := epoch.GovStake(state, v.Voter)
+		voted.Add(voted, stake)
+		if v.Approve {
+			approved.Add(approved, stake)
+		}
+		result.Votes = append(result.Votes, &GovVoteResult{
+			Voter:   v.Voter,
+			Approve: v.Approve,
+			Stake:   (*hexutil.Big)(stake),
+		})
+	}
+	result.StakeVoted = (*hexutil.Big)(voted)
+	result.StakeApproved = (*hexutil.Big)(approved)
+	return result
 }
 
-func registerApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
-	// Validate first
-	from := derivedAddressFromTx(tx)
-	args, verror := registerValidation(from, tx, state, bc)
-	if verror != nil {
-		return verror
+// SignalUpgrade signals that the node of the validator runs a release
+// supporting the named upgrade. It is only sent from a node supporting it.
+func (api *PrivateNeatApi) SignalUpgrade(ctx context.Context, from common.Address, name string, gasPrice *hexutil.Big) (common.Hash, error) {
+	if !params.SupportedUpgrades[name] {
+		return common.Hash{}, fmt.Errorf("upgrade %q is not supported by this release", name)
 	}
 
-	// block height validation
-	verror = updateValidation(bc)
-	if verror != nil {
-		return verror
+	input, err := neatabi.ChainABI.Pack(neatabi.SignalUpgrade.String(), name)
+	if err != nil {
+		return common.Hash{}, err
 	}
 
-	amount := tx.Value()
-	// Add minimum register amount to self
-	state.SubBalance(from, amount)
-	state.AddDelegateBalance(from, amount)
-	state.AddProxiedBalanceByUser(from, from, amount)
-	// Become a Candidate
+	defaultGas := neatabi.SignalUpgrade.RequiredGas()
 
-	var blsPK goCrypto.BLSPubKey
-	copy(blsPK[:], args.Pubkey)
-	fmt.Printf("register pubkey unmarshal json start\n")
-	if verror != nil {
-		return verror
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
 	}
-	fmt.Printf("register pubkey %v\n", blsPK)
-	state.ApplyForCandidate(from, blsPK.KeyString(), args.Commission)
 
-	// mark address candidate
-	state.MarkAddressCandidate(from)
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
 
-	verror = updateNextEpochValidatorVoteSet(tx, state, bc, from, ops)
-	if verror != nil {
-		return verror
+// GetGovProposals returns the governance proposals, with the current stake of
+// the voters.
+func (api *PublicNeatApi) GetGovProposals(ctx context.Context, blockNr rpc.BlockNumber) ([]*GovProposalResult, error) {
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	results := make([]*GovProposalResult, 0)
+	for _, p := range state.GetGovProposals() {
+		results = append(results, newGovProposalResult(state, p))
+	}
+	return results, nil
+}
+
+// GetGovProposal returns a governance proposal, with the current stake of the
+// voters.
+func (api *PublicNeatApi) GetGovProposal(ctx context.Context, id hexutil.Uint64, blockNr rpc.BlockNumber) (*GovProposalResult, error) {
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	p := state.GetGovProposal(uint64(id))
+	if p == nil {
+		return nil, core.ErrGovProposalNotFound
+	}
+	return newGovProposalResult(state, p), nil
+}
+
+type TreasurySpendResult struct {
+	ProposalId hexutil.Uint64 `json:"proposalId"`
+	Recipient  common.Address `json:"recipient"`
+	Amount     *hexutil.Big   `json:"amount"`
+	Number     hexutil.Uint64 `json:"blockNumber"`
+}
+
+type TreasuryResult struct {
+	Address  common.Address         `json:"address"`
+	Balance  *hexutil.Big           `json:"balance"`
+	Rate     hexutil.Uint64         `json:"rate"`     // Percent of the block reward
+	Received *hexutil.Big           `json:"received"` // Block reward shares, plain transfers excluded
+	Spent    *hexutil.Big           `json:"spent"`
+	Spends   []*TreasurySpendResult `json:"spends"`
+}
+
+// GetTreasury returns the balance and the accounting of the treasury.
+func (api *PublicNeatApi) GetTreasury(ctx context.Context, blockNr rpc.BlockNumber) (*TreasuryResult, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	received, spent, spends := statedb.GetTreasuryAccounting()
+	result := &TreasuryResult{
+		Address:  state.TreasuryAddr,
+		Balance:  (*hexutil.Big)(statedb.GetBalance(state.TreasuryAddr)),
+		Received: (*hexutil.Big)(received),
+		Spent:    (*hexutil.Big)(spent),
+		Spends:   make([]*TreasurySpendResult, 0, len(spends)),
+	}
+	if rate := statedb.GetGovParam(state.GovParamTreasuryRate); rate != nil {
+		result.Rate = hexutil.Uint64(rate.Uint64())
+	}
+	for _, spend := range spends {
+		result.Spends = append(result.Spends, &TreasurySpendResult{
+			ProposalId: hexutil.Uint64(spend.ProposalId),
+			Recipient:  spend.Recipient,
+			Amount:     (*hexutil.Big)(spend.Amount),
+			Number:     hexutil.Uint64(spend.Number),
+		})
+	}
+	return result, nil
+}
+
+type ChainParamChangeResult struct {
+	Value      *hexutil.Big   `json:"value"`
+	ProposalId hexutil.Uint64 `json:"proposalId"`
+	Epoch      hexutil.Uint64 `json:"epoch"`
+}
+
+type ChainParamResult struct {
+	Name       string                    `json:"name"`
+	Value      *hexutil.Big              `json:"value"` // nil if left to the node configuration
+	Default    bool                      `json:"default"`
+	ProposalId *hexutil.Uint64           `json:"proposalId,omitempty"` // Proposal which set the value
+	Epoch      *hexutil.Uint64           `json:"epoch,omitempty"`      // Epoch the value applies from
+	History    []*ChainParamChangeResult `json:"history"`
+}
+
+type ChainParamsResult struct {
+	Number   hexutil.Uint64      `json:"number"`
+	GasLimit hexutil.Uint64      `json:"gasLimit"` // Of the block
+	Params   []*ChainParamResult `json:"params"`
+}
+
+// chainParamDefaults are the values of the governed parameters governance
+// never set; the ones missing are left to the node configuration.
+var chainParamDefaults = map[string]*big.Int{
+	state.GovParamMaxValidators:        big.NewInt(epoch.MaximumValidatorsSize),
+	state.GovParamRewardRate:           big.NewInt(100),
+	state.GovParamTreasuryRate:         big.NewInt(0),
+	state.GovParamMinDeposit:           big.NewInt(state.DefaultGovMinDeposit),
+	state.GovParamDepositPeriod:        big.NewInt(state.DefaultGovDepositPeriod),
+	state.GovParamVotingPeriod:         big.NewInt(state.DefaultGovVotingPeriod),
+	state.GovParamMaxCommission:        big.NewInt(state.DefaultMaxCommission),
+	state.GovParamMaxCommissionChange:  big.NewInt(state.DefaultMaxCommissionChange),
+	state.GovParamSideChainGracePeriod: big.NewInt(state.DefaultSideChainGracePeriod),
+	state.GovParamCrossChainFeeRate:    big.NewInt(state.DefaultCrossChainFeeRate),
+	state.GovParamCrossChainRelayerFee: big.NewInt(state.DefaultCrossChainRelayerFee),
+	state.GovParamCrossChainMinAmount:  big.NewInt(state.DefaultCrossChainMinAmount),
+	state.GovParamChallengeWindow:      big.NewInt(0),
+}
+
+// GetChainParams returns the consensus and economic parameters in effect at
+// the given block, with the proposals which set them.
+func (api *PublicNeatApi) GetChainParams(ctx context.Context, blockNr rpc.BlockNumber) (*ChainParamsResult, error) {
+	statedb, header, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	names := state.GovParamNames()
+	upgrades := make([]string, 0)
+	for name := range statedb.GetUpgrades() {
+		upgrades = append(upgrades, state.UpgradeParam(name))
+	}
+	for chainId := range statedb.GetSideChainRetirements() {
+		upgrades = append(upgrades, state.RetireParam(chainId))
+	}
+	sort.Strings(upgrades)
+	names = append(names, upgrades...)
+
+	result := &ChainParamsResult{
+		Number:   hexutil.Uint64(header.Number.Uint64()),
+		GasLimit: hexutil.Uint64(header.GasLimit),
+		Params:   make([]*ChainParamResult, 0, len(names)),
+	}
+	for _, name := range names {
+		param := &ChainParamResult{
+			Name:    name,
+			History: make([]*ChainParamChangeResult, 0),
+		}
+		changes := statedb.GetGovParamChanges(name)
+		for _, change := range changes {
+			param.History = append(param.History, &ChainParamChangeResult{
+				Value:      (*hexutil.Big)(change.Value),
+				ProposalId: hexutil.Uint64(change.ProposalId),
+				Epoch:      hexutil.Uint64(change.Epoch),
+			})
+		}
+		// The value in effect is the last one applying from the current epoch
+		param.Value = (*hexutil.Big)(chainParamDefaults[name])
+		param.Default = true
+		for i := len(changes) - 1; i >= 0; i-- {
+			if changes[i].Epoch <= statedb.GovEpoch() {
+				current := param.History[i]
+				param.Value, param.ProposalId, param.Epoch, param.Default = current.Value, &current.ProposalId, &current.Epoch, false
+				break
+			}
+		}
+		result.Params = append(result.Params, param)
+	}
+	return result, nil
+}
+
+// GetGovParams returns the parameters set by governance.
+func (api *PublicNeatApi) GetGovParams(ctx context.Context, blockNr rpc.BlockNumber) (map[string]*hexutil.Big, error) {
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]*hexutil.Big)
+	for name, value := range state.GetGovParams() {
+		params[name] = (*hexutil.Big)(value)
+	}
+	return params, nil
+}
+
+func (api *PrivateNeatApi) EditValidator(ctx context.Context, from common.Address, moniker, website string, identity string, details string, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.EditValidator.String(), moniker, website, identity, details)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := neatabi.EditValidator.RequiredGas()
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+// SetSecurityContact publishes how to reach the operator of validator from
+// about security issues, next to the metadata set by EditValidator.
+func (api *PrivateNeatApi) SetSecurityContact(ctx context.Context, from common.Address, securityContact string, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.SetSecurityContact.String(), securityContact)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := neatabi.SetSecurityContact.RequiredGas()
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+func (api *PrivateNeatApi) UnBanned(ctx context.Context, from common.Address, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(neatabi.UnBanned.String())
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := neatabi.UnBanned.RequiredGas()
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+// SideChainInfo describes a launched side chain.
+type SideChainInfo struct {
+	ChainId               string         `json:"chainId"`
+	Owner                 common.Address `json:"owner"`
+	MinValidators         uint16         `json:"minValidators"`
+	MinDepositAmount      *hexutil.Big   `json:"minDepositAmount"`
+	StartBlock            *hexutil.Big   `json:"startBlock"`
+	EndBlock              *hexutil.Big   `json:"endBlock"`
+	EpochNumber           hexutil.Uint64 `json:"epochNumber"`
+	Validators            int            `json:"validators"`
+	DepositInMainChain    *hexutil.Big   `json:"depositInMainChain"`
+	WithdrawFromSideChain *hexutil.Big   `json:"withdrawFromSideChain"`
+	Status                string         `json:"status"`
+	FreezeBlock           *hexutil.Big   `json:"freezeBlock,omitempty"` // Main chain block from which only withdrawals are accepted
+	SunsetBlock           *hexutil.Big   `json:"sunsetBlock,omitempty"` // Main chain block from which the side chain is stopped
+}
+
+// Status of a side chain.
+const (
+	SideChainRunning = "running"
+	SideChainFrozen  = "frozen"  // Retired by governance, accepting withdrawals
+	SideChainRetired = "retired" // Past the grace period of its retirement
+)
+
+// GetSideChains returns the side chains launched from the main chain.
+func (api *PublicNeatApi) GetSideChains() ([]*SideChainInfo, error) {
+	cch := api.b.GetCrossChainHelper()
+	db := cch.GetChainInfoDB()
+	mainHeight := cch.GetHeightFromMainChain().Uint64()
+
+	result := make([]*SideChainInfo, 0)
+	for _, chainId := range core.GetSideChainIds(db) {
+		ci := core.GetChainInfo(db, chainId)
+		if ci == nil {
+			continue
+		}
+		info := &SideChainInfo{
+			ChainId:               ci.ChainId,
+			Owner:                 ci.Owner,
+			MinValidators:         ci.MinValidators,
+			MinDepositAmount:      (*hexutil.Big)(ci.MinDepositAmount),
+			StartBlock:            (*hexutil.Big)(ci.StartBlock),
+			EndBlock:              (*hexutil.Big)(ci.EndBlock),
+			EpochNumber:           hexutil.Uint64(ci.EpochNumber),
+			DepositInMainChain:    (*hexutil.Big)(ci.DepositInMainChain),
+			WithdrawFromSideChain: (*hexutil.Big)(ci.WithdrawFromSideChain),
+			Status:                SideChainRunning,
+		}
+		freeze, sunset, retired, err := cch.GetSideChainRetirement(ci.ChainId, mainHeight)
+		if err != nil {
+			return nil, err
+		}
+		if retired {
+			info.FreezeBlock = (*hexutil.Big)(new(big.Int).SetUint64(freeze))
+			info.SunsetBlock = (*hexutil.Big)(new(big.Int).SetUint64(sunset))
+			if mainHeight >= sunset {
+				info.Status = SideChainRetired
+			} else if mainHeight >= freeze {
+				info.Status = SideChainFrozen
+			}
+		}
+		if ci.Epoch != nil && ci.Epoch.Validators != nil {
+			info.Validators = ci.Epoch.Validators.Size()
+		} else {
+			info.Validators = len(ci.JoinedValidators)
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// ValidatorSideChainsResult lists the side chains secured by a validator.
+type ValidatorSideChainsResult struct {
+	Address   common.Address  `json:"address"`
+	Chains    []string        `json:"chains"`
+	Limit     hexutil.Uint64  `json:"limit"`               // 0 if not limited
+	Remaining *hexutil.Uint64 `json:"remaining,omitempty"` // Side chains the validator may still secure
+}
+
+// GetValidatorSideChains returns the side chains the validator secures or
+// joined, and how many more it may secure.
+func (api *PublicNeatApi) GetValidatorSideChains(address common.Address) *ValidatorSideChainsResult {
+	chains, limit := api.b.GetCrossChainHelper().GetValidatorSideChains(address)
+	if chains == nil {
+		chains = make([]string, 0)
+	}
+	result := &ValidatorSideChainsResult{
+		Address: address,
+		Chains:  chains,
+		Limit:   hexutil.Uint64(limit),
+	}
+	if limit > 0 {
+		remaining := hexutil.Uint64(0)
+		if uint64(len(chains)) < limit {
+			remaining = hexutil.Uint64(limit - uint64(len(chains)))
+		}
+		result.Remaining = &remaining
+	}
+	return result
+}
+
+// DepositInMainChain locks amount on the main chain, the validators of the side
+// chain then credit it to from on the side chain.
+func (api *PrivateNeatApi) DepositInMainChain(ctx context.Context, from common.Address, chainId string, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	if !api.b.ChainConfig().IsMainChain() {
+		return common.Hash{}, errors.New("deposit must be sent to the main chain")
+	}
+	cch := api.b.GetCrossChainHelper()
+	if chainId == "" || !core.CheckSideChainRunning(cch.GetChainInfoDB(), chainId) {
+		return common.Hash{}, fmt.Errorf("side chain %q is not running", chainId)
+	}
+	mainHeight := cch.GetHeightFromMainChain().Uint64()
+	freeze, _, retired, err := cch.GetSideChainRetirement(chainId, mainHeight)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if retired && mainHeight+1 >= freeze {
+		return common.Hash{}, core.ErrSideChainFrozen
+	}
+
+	input, err := neatabi.ChainABI.Pack(neatabi.DepositInMainChain.String(), chainId)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := neatabi.DepositInMainChain.RequiredGas()
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    amount,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+// WithdrawFromSideChain burns amount on the side chain, it is refunded to from
+// on the main chain once the proof of the transaction has been saved there.
+// The cross chain fees set by the governance of both chains are charged on the
+// way: on top of amount on the side chain, out of it on the main chain.
+func (api *PrivateNeatApi) WithdrawFromSideChain(ctx context.Context, from common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	chainId := api.b.ChainConfig().NeatChainId
+	if api.b.ChainConfig().IsMainChain() {
+		return common.Hash{}, errors.New("withdraw must be sent to a side chain")
+	}
+
+	input, err := neatabi.ChainABI.Pack(neatabi.WithdrawFromSideChain.String(), chainId)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := neatabi.WithdrawFromSideChain.RequiredGas()
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    amount,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+// PendingWithdrawalResult is a withdrawal from a side chain held on the main
+// chain during its challenge window.
+type PendingWithdrawalResult struct {
+	TxHash  common.Hash    `json:"txHash"` // Hash of the TX3 on the side chain
+	From    common.Address `json:"from"`
+	ChainId string         `json:"chainId"`
+	Amount  *hexutil.Big   `json:"amount"`
+	Release hexutil.Uint64 `json:"release"`         // Main chain block releasing the amount
+	Proof   hexutil.Bytes  `json:"proof,omitempty"` // RLP encoded proof of the TX3, if known to the node
+}
+
+// GetPendingWithdrawals returns the withdrawals from the side chains still in
+// their challenge window at the given block, in the order of their release.
+func (api *PublicNeatApi) GetPendingWithdrawals(ctx context.Context, blockNr rpc.BlockNumber) ([]*PendingWithdrawalResult, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	cch := api.b.GetCrossChainHelper()
+	withdrawals := statedb.GetPendingWithdrawals()
+	result := make([]*PendingWithdrawalResult, 0, len(withdrawals))
+	for _, withdrawal := range withdrawals {
+		entry := &PendingWithdrawalResult{
+			TxHash:  withdrawal.TxHash,
+			From:    withdrawal.From,
+			ChainId: withdrawal.ChainId,
+			Amount:  (*hexutil.Big)(withdrawal.Amount),
+			Release: hexutil.Uint64(withdrawal.Release),
+		}
+		if proofData := cch.GetTX3ProofData(withdrawal.ChainId, withdrawal.TxHash); proofData != nil {
+			if proof, err := rlp.EncodeToBytes(proofData); err == nil {
+				entry.Proof = proof
+			}
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// ChallengeWithdrawal cancels the withdrawal of a TX3 still in its challenge
+// window, on proof that the side chain block of the TX3 is contradicted by
+// another block committed at its height. The proof is the RLP encoded proof of
+// the TX3, and header the RLP encoded header of the conflicting block.
+func (api *PrivateNeatApi) ChallengeWithdrawal(ctx context.Context, from common.Address, txHash common.Hash, proof hexutil.Bytes, header hexutil.Bytes, gasPrice *hexutil.Big) (common.Hash, error) {
+	if !api.b.ChainConfig().IsMainChain() {
+		return common.Hash{}, errors.New("challenge must be sent to the main chain")
+	}
+
+	input, err := neatabi.ChainABI.Pack(neatabi.ChallengeWithdrawal.String(), txHash, []byte(proof), []byte(header))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := neatabi.ChallengeWithdrawal.RequiredGas()
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+func init() {
+	// Withdraw reward
+	core.RegisterValidateCb(neatabi.WithdrawReward, withdrawRewardValidateCb)
+	core.RegisterApplyCb(neatabi.WithdrawReward, withdrawRewardApplyCb)
+
+	// Auto Compound
+	core.RegisterValidateCb(neatabi.SetAutoCompound, setAutoCompoundValidateCb)
+	core.RegisterApplyCb(neatabi.SetAutoCompound, setAutoCompoundApplyCb)
+
+	// Delegate
+	core.RegisterValidateCb(neatabi.Delegate, delegateValidateCb)
+	core.RegisterApplyCb(neatabi.Delegate, delegateApplyCb)
+
+	// Cancel Delegate
+	core.RegisterValidateCb(neatabi.UnDelegate, unDelegateValidateCb)
+	core.RegisterApplyCb(neatabi.UnDelegate, unDelegateApplyCb)
+
+	// Register
+	core.RegisterValidateCb(neatabi.Register, registerValidateCb)
+	core.RegisterApplyCb(neatabi.Register, registerApplyCb)
+
+	// Cancel Register
+	core.RegisterValidateCb(neatabi.UnRegister, unRegisterValidateCb)
+	core.RegisterApplyCb(neatabi.UnRegister, unRegisterApplyCb)
+
+	// Set Commission
+	core.RegisterValidateCb(neatabi.SetCommission, setCommisstionValidateCb)
+	core.RegisterApplyCb(neatabi.SetCommission, setCommisstionApplyCb)
+
+	// Set Min Self Bond
+	core.RegisterValidateCb(neatabi.SetMinSelfBond, setMinSelfBondValidateCb)
+	core.RegisterApplyCb(neatabi.SetMinSelfBond, setMinSelfBondApplyCb)
+
+	// Edit Validator
+	core.RegisterValidateCb(neatabi.EditValidator, editValidatorValidateCb)
+	core.RegisterApplyCb(neatabi.EditValidator, editValidatorApplyCb)
+
+	// Set Security Contact
+	core.RegisterValidateCb(neatabi.SetSecurityContact, setSecurityContactValidateCb)
+	core.RegisterApplyCb(neatabi.SetSecurityContact, setSecurityContactApplyCb)
+
+	// UnBanned
+	core.RegisterValidateCb(neatabi.UnBanned, unBannedValidateCb)
+	core.RegisterApplyCb(neatabi.UnBanned, unBannedApplyCb)
+
+	// Rotate Consensus Key
+	core.RegisterValidateCb(neatabi.RotateConsensusKey, rotateConsensusKeyValidateCb)
+	core.RegisterApplyCb(neatabi.RotateConsensusKey, rotateConsensusKeyApplyCb)
+
+	// Governance
+	core.RegisterValidateCb(neatabi.SubmitGovProposal, submitGovProposalValidateCb)
+	core.RegisterApplyCb(neatabi.SubmitGovProposal, submitGovProposalApplyCb)
+	core.RegisterValidateCb(neatabi.VoteGovProposal, voteGovProposalValidateCb)
+	core.RegisterApplyCb(neatabi.VoteGovProposal, voteGovProposalApplyCb)
+	core.RegisterValidateCb(neatabi.DepositGovProposal, depositGovProposalValidateCb)
+	core.RegisterApplyCb(neatabi.DepositGovProposal, depositGovProposalApplyCb)
+
+	// Upgrade Signal
+	core.RegisterValidateCb(neatabi.SignalUpgrade, signalUpgradeValidateCb)
+	core.RegisterApplyCb(neatabi.SignalUpgrade, signalUpgradeApplyCb)
+}
+
+func withdrawRewardValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := withDrawRewardValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func withdrawRewardApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+
+	args, err := withDrawRewardValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	reward := state.GetRewardBalanceByDelegateAddress(from, args.DelegateAddress)
+	state.WithdrawRewardBalance(from, args.DelegateAddress, reward)
+	if bc.Config().IsRewardClaim(header.Number) {
+		state.RecordRewardClaim(from, reward, header.Number.Uint64())
+	}
+
+	return nil
+}
+
+func withDrawRewardValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.WithdrawRewardArgs, error) {
+
+	var args neatabi.WithdrawRewardArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.WithdrawReward.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	reward := state.GetRewardBalanceByDelegateAddress(from, args.DelegateAddress)
+
+	if reward.Sign() < 1 {
+		return nil, fmt.Errorf("have no reward to withdraw")
+	}
+
+	//if args.Amount.Cmp(reward) == 1 {
+	//	return nil, fmt.Errorf("reward balance not enough, withdraw amount %v, but balance %v, delegate address %v", args.Amount, reward, args.DelegateAddress)
+	//}
+	return &args, nil
+}
+
+// auto compound
+func setAutoCompoundValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := setAutoCompoundValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func setAutoCompoundApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := setAutoCompoundValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	state.SetAutoCompound(from, args.Candidate, args.Enabled)
+
+	return nil
+}
+
+func setAutoCompoundValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.SetAutoCompoundArgs, error) {
+	var args neatabi.SetAutoCompoundArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SetAutoCompound.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	// Opting out is always allowed
+	if !args.Enabled {
+		return &args, nil
+	}
+
+	if !state.IsCandidate(args.Candidate) {
+		return nil, core.ErrNotCandidate
+	}
+
+	delegated := new(big.Int).Add(state.GetProxiedBalanceByUser(args.Candidate, from), state.GetDepositProxiedBalanceByUser(args.Candidate, from))
+	if delegated.Sign() == 0 {
+		return nil, core.ErrNoDelegation
+	}
+
+	return &args, nil
+}
+
+// register and unregister
+func registerValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, verror := registerValidation(from, tx, state, bc)
+	if verror != nil {
+		return verror
+	}
+
+	// The side chains joined by a validator are recorded in the state of the
+	// main chain, which the blocks of a side chain can't read: the candidates
+	// over the limit are kept out of the pool of the side chains, the limit is
+	// enforced on the main chain when joining.
+	if !bc.Config().IsMainChain() {
+		if cch := bc.GetCrossChainHelper(); cch != nil {
+			chains, limit := cch.GetValidatorSideChains(from)
+			if err := core.CheckSideChainCapacity(chains, limit, bc.Config().NeatChainId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func registerApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	// Validate first
+	from := derivedAddressFromTx(tx)
+	args, verror := registerValidation(from, tx, state, bc)
+	if verror != nil {
+		return verror
+	}
+
+	// block height validation
+	verror = updateValidation(bc)
+	if verror != nil {
+		return verror
+	}
+
+	amount := tx.Value()
+	// Add minimum register amount to self
+	state.SubBalance(from, amount)
+	state.AddDelegateBalance(from, amount)
+	state.AddProxiedBalanceByUser(from, from, amount)
+	// Become a Candidate
+
+	var blsPK goCrypto.BLSPubKey
+	copy(blsPK[:], args.Pubkey)
+	fmt.Printf("register pubkey unmarshal json start\n")
+	if verror != nil {
+		return verror
+	}
+	fmt.Printf("register pubkey %v\n", blsPK)
+	state.ApplyForCandidate(from, blsPK.KeyString(), args.Commission)
+
+	// mark address candidate
+	state.MarkAddressCandidate(from)
+
+	verror = updateNextEpochValidatorVoteSet(tx, state, bc, from, ops)
+	if verror != nil {
+		return verror
 	}
 
 	return nil
@@ -2052,299 +3013,682 @@ func registerValidation(from common.Address, tx *types.Transaction, state *state
 		return nil, core.ErrMinimumRegisterAmount
 	}
 
-	var args neatabi.RegisterArgs
+	var args neatabi.RegisterArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.Register.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if err := goCrypto.CheckConsensusPubKey(from, args.Pubkey, args.Signature); err != nil {
+		return nil, err
+	}
+	var blsPK goCrypto.BLSPubKey
+	copy(blsPK[:], args.Pubkey)
+	if err := ncTypes.ValidateBLSPubKey(blsPK); err != nil {
+		return nil, err
+	}
+
+	// Check Commission Range
+	if args.Commission > 100 {
+		return nil, core.ErrCommission
+	}
+
+	if args.Commission > state.MaxCommission() {
+		return nil, core.ErrCommissionTooHigh
+	}
+
+	// Annual/SemiAnnual supernode can not become candidate
+	var ep *epoch.Epoch
+	if tdm, ok := bc.Engine().(consensus.NeatPoS); ok {
+		ep = tdm.GetEpoch().GetEpochByBlockNumber(bc.CurrentBlock().NumberU64())
+	}
+	if _, supernode := ep.Validators.GetByAddress(from.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
+		return nil, core.ErrCannotCandidate
+	}
+
+	return &args, nil
+}
+
+func unRegisterValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	verror := unRegisterValidation(from, tx, state, bc)
+	if verror != nil {
+		return verror
+	}
+	return nil
+}
+
+func unRegisterApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	// Validate first
+	from := derivedAddressFromTx(tx)
+	verror := unRegisterValidation(from, tx, state, bc)
+	if verror != nil {
+		return verror
+	}
+
+	// Do job
+	allRefund := true
+	// Refund all the amount back to users
+	state.ForEachProxied(from, func(key common.Address, proxiedBalance, depositProxiedBalance, pendingRefundBalance *big.Int) bool {
+		// Refund Proxied Amount
+		state.SubProxiedBalanceByUser(from, key, proxiedBalance)
+		state.SubDelegateBalance(key, proxiedBalance)
+		state.AddBalance(key, proxiedBalance)
+
+		if depositProxiedBalance.Sign() > 0 {
+			allRefund = false
+			// Refund Deposit to PendingRefund if deposit > 0
+			state.AddPendingRefundBalanceByUser(from, key, depositProxiedBalance)
+			// TODO Add Pending Refund Set, Commit the Refund Set
+			state.MarkDelegateAddressRefund(from)
+		}
+		return true
+	})
+
+	state.CancelCandidate(from, allRefund)
+
+	fmt.Printf("candidate set bug, unregiser clear candidate before\n")
+	fmt.Printf("candidate set bug, unregiser clear candidate before %v\n", state.GetCandidateSet())
+	// remove address form candidate set
+	state.ClearCandidateSetByAddress(from)
+	fmt.Printf("candidate set bug, unregiser clear candidate after\n")
+	fmt.Printf("candidate set bug, unregiser clear candidate after %v\n", state.GetCandidateSet())
+
+	return nil
+}
+
+func unRegisterValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	// Check already Candidate
+	if !state.IsCandidate(from) {
+		return core.ErrNotCandidate
+	}
+
+	// Banned candidate can't unregister
+	if state.GetBanned(from) {
+		return core.ErrBannedUnRegister
+	}
+
+	// Super node can't unregister
+	var ep *epoch.Epoch
+	if tdm, ok := bc.Engine().(consensus.NeatPoS); ok {
+		ep = tdm.GetEpoch().GetEpochByBlockNumber(bc.CurrentBlock().NumberU64())
+	}
+	if _, supernode := ep.Validators.GetByAddress(from.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
+		return core.ErrCannotUnRegister
+	}
+
+	// Check Epoch Height
+	if _, err := getEpoch(bc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// delegate and unDelegate
+func delegateValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, verror := delegateValidation(from, tx, state, bc)
+	if verror != nil {
+		return verror
+	}
+	return nil
+}
+
+func delegateApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	// Validate first
+	from := derivedAddressFromTx(tx)
+	args, verror := delegateValidation(from, tx, state, bc)
+	if verror != nil {
+		return verror
+	}
+
+	// block height validation
+	verror = updateValidation(bc)
+	if verror != nil {
+		return verror
+	}
+
+	// Do job
+	amount := tx.Value()
+	// Move Balance to delegate balance
+	state.SubBalance(from, amount)
+	state.AddDelegateBalance(from, amount)
+	// Add Balance to Candidate's Proxied Balance
+	state.AddProxiedBalanceByUser(args.Candidate, from, amount)
+
+	// if banned, don't add to next epoch validator vote set
+	if !state.GetBanned(from) {
+		verror = updateNextEpochValidatorVoteSet(tx, state, bc, args.Candidate, ops)
+		if verror != nil {
+			return verror
+		}
+	}
+
+	return nil
+}
+
+func delegateValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.DelegateArgs, error) {
+	// Check minimum delegate amount
+	if tx.Value().Sign() == -1 {
+		return nil, core.ErrDelegateAmount
+	}
+
+	var args neatabi.DelegateArgs
 	data := tx.Data()
-	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.Register.String(), data[4:]); err != nil {
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.Delegate.String(), data[4:]); err != nil {
 		return nil, err
 	}
 
-	if err := goCrypto.CheckConsensusPubKey(from, args.Pubkey, args.Signature); err != nil {
-		return nil, err
+	// Check Candidate
+	if !state.IsCandidate(args.Candidate) {
+		return nil, core.ErrNotCandidate
 	}
 
-	// Check Commission Range
-	if args.Commission > 100 {
-		return nil, core.ErrCommission
+	depositBalance := state.GetDepositProxiedBalanceByUser(args.Candidate, from)
+	if depositBalance.Sign() == 0 {
+		// Check if exceed the limit of delegated addresses
+		// if exceed the limit of delegation address number, return error
+		delegatedAddressNumber := state.GetProxiedAddressNumber(args.Candidate)
+		if delegatedAddressNumber >= maxDelegationAddresses {
+			return nil, core.ErrExceedDelegationAddressLimit
+		}
 	}
 
-	// Annual/SemiAnnual supernode can not become candidate
+	// If Candidate is supernode, only allow to increase the stack(whitelist proxied list), not allow to create the new stack
 	var ep *epoch.Epoch
 	if tdm, ok := bc.Engine().(consensus.NeatPoS); ok {
 		ep = tdm.GetEpoch().GetEpochByBlockNumber(bc.CurrentBlock().NumberU64())
 	}
-	if _, supernode := ep.Validators.GetByAddress(from.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
-		return nil, core.ErrCannotCandidate
+	if _, supernode := ep.Validators.GetByAddress(args.Candidate.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
+		if depositBalance.Sign() == 0 {
+			return nil, core.ErrCannotDelegate
+		}
 	}
 
+	// Check Epoch Height
+	//if _, err := getEpoch(bc); err != nil {
+	//	return nil, err
+	//}
 	return &args, nil
 }
 
-func unRegisterValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+func unDelegateValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
-	verror := unRegisterValidation(from, tx, state, bc)
+	_, verror := unDelegateValidation(from, tx, state, bc)
 	if verror != nil {
 		return verror
 	}
 	return nil
 }
 
-func unRegisterApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+func unDelegateApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
 	// Validate first
 	from := derivedAddressFromTx(tx)
-	verror := unRegisterValidation(from, tx, state, bc)
+	args, verror := unDelegateValidation(from, tx, state, bc)
 	if verror != nil {
 		return verror
 	}
 
-	// Do job
-	allRefund := true
-	// Refund all the amount back to users
-	state.ForEachProxied(from, func(key common.Address, proxiedBalance, depositProxiedBalance, pendingRefundBalance *big.Int) bool {
-		// Refund Proxied Amount
-		state.SubProxiedBalanceByUser(from, key, proxiedBalance)
-		state.SubDelegateBalance(key, proxiedBalance)
-		state.AddBalance(key, proxiedBalance)
+	// block height validation
+	verror = updateValidation(bc)
+	if verror != nil {
+		return verror
+	}
 
-		if depositProxiedBalance.Sign() > 0 {
-			allRefund = false
-			// Refund Deposit to PendingRefund if deposit > 0
-			state.AddPendingRefundBalanceByUser(from, key, depositProxiedBalance)
-			// TODO Add Pending Refund Set, Commit the Refund Set
-			state.MarkDelegateAddressRefund(from)
-		}
-		return true
-	})
+	// Apply Logic
+	// if request amount < proxied amount, refund it immediately
+	// otherwise, refund the proxied amount, and put the rest to pending refund balance
+	proxiedBalance := state.GetProxiedBalanceByUser(args.Candidate, from)
+	var immediatelyRefund *big.Int
+	if args.Amount.Cmp(proxiedBalance) <= 0 {
+		immediatelyRefund = args.Amount
+	} else {
+		immediatelyRefund = proxiedBalance
+		restRefund := new(big.Int).Sub(args.Amount, proxiedBalance)
+		state.AddPendingRefundBalanceByUser(args.Candidate, from, restRefund)
+		// TODO Add Pending Refund Set, Commit the Refund Set
+		state.MarkDelegateAddressRefund(args.Candidate)
+	}
 
-	state.CancelCandidate(from, allRefund)
+	state.SubProxiedBalanceByUser(args.Candidate, from, immediatelyRefund)
+	state.SubDelegateBalance(from, immediatelyRefund)
+	state.AddBalance(from, immediatelyRefund)
 
-	fmt.Printf("candidate set bug, unregiser clear candidate before\n")
-	fmt.Printf("candidate set bug, unregiser clear candidate before %v\n", state.GetCandidateSet())
-	// remove address form candidate set
-	state.ClearCandidateSetByAddress(from)
-	fmt.Printf("candidate set bug, unregiser clear candidate after\n")
-	fmt.Printf("candidate set bug, unregiser clear candidate after %v\n", state.GetCandidateSet())
+	//verror = updateNextEpochValidatorVoteSet(tx, state, bc, args.Candidate)
+	//if verror != nil {
+	//	return verror
+	//}
 
 	return nil
 }
 
-func unRegisterValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
-	// Check already Candidate
-	if !state.IsCandidate(from) {
-		return core.ErrNotCandidate
+func unDelegateValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.UnDelegateArgs, error) {
+
+	var args neatabi.UnDelegateArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.UnDelegate.String(), data[4:]); err != nil {
+		return nil, err
 	}
 
-	// Banned candidate can't unregister
-	if state.GetBanned(from) {
-		return core.ErrBannedUnRegister
+	// Check Self Address
+	if from == args.Candidate {
+		return nil, core.ErrCancelSelfDelegate
 	}
 
-	// Super node can't unregister
+	// Super node Candidate can't decrease balance
 	var ep *epoch.Epoch
 	if tdm, ok := bc.Engine().(consensus.NeatPoS); ok {
 		ep = tdm.GetEpoch().GetEpochByBlockNumber(bc.CurrentBlock().NumberU64())
 	}
-	if _, supernode := ep.Validators.GetByAddress(from.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
-		return core.ErrCannotUnRegister
+	if _, supernode := ep.Validators.GetByAddress(args.Candidate.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
+		return nil, core.ErrCannotUnBond
+	}
+
+	// Check Proxied Amount in Candidate Balance
+	proxiedBalance := state.GetProxiedBalanceByUser(args.Candidate, from)
+	depositProxiedBalance := state.GetDepositProxiedBalanceByUser(args.Candidate, from)
+	pendingRefundBalance := state.GetPendingRefundBalanceByUser(args.Candidate, from)
+	// net = deposit - pending refund
+	netDeposit := new(big.Int).Sub(depositProxiedBalance, pendingRefundBalance)
+	// available = proxied + net
+	availableRefundBalance := new(big.Int).Add(proxiedBalance, netDeposit)
+	if args.Amount.Cmp(availableRefundBalance) == 1 {
+		return nil, core.ErrInsufficientProxiedBalance
+	}
+
+	// if left, the left must be greater than the min delegate amount
+	//remainingBalance := new(big.Int).Sub(availableRefundBalance, args.Amount)
+	//if remainingBalance.Sign() == 1 && remainingBalance.Cmp(minimumDelegationAmount) == -1 {
+	//	return nil, core.ErrDelegateAmount
+	//}
+
+	// Check Epoch Height
+	if _, err := getEpoch(bc); err != nil {
+		return nil, err
+	}
+
+	return &args, nil
+}
+
+// set commission
+func setCommisstionValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	next := new(big.Int).Add(bc.CurrentBlock().Number(), common.Big1)
+	_, err := setCommissionValidation(from, tx, state, bc.Config(), next)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func setCommisstionApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := setCommissionValidation(from, tx, state, bc.Config(), header.Number)
+	if err != nil {
+		return err
+	}
+
+	return setCommission(bc.Config(), bc, state, from, args.Commission, header)
+}
+
+// setCommission sets the commission of the candidate from, at once before the
+// commission schedule fork and from the next epoch after it.
+func setCommission(config *params.ChainConfig, bc *core.BlockChain, state *state.StateDB, from common.Address, commission uint8, header *types.Header) error {
+	if !config.IsCommissionSchedule(header.Number) {
+		state.SetCommission(from, commission)
+		return nil
+	}
+
+	ep, err := getEpoch(bc)
+	if err != nil {
+		return err
+	}
+
+	// The commission changes from the next epoch, delegators can react until then
+	event := neatabi.ChainABI.Events[neatabi.CommissionChangeEvent]
+	data, err := event.Inputs.NonIndexed().Pack(state.GetCommission(from), commission, ep.Number+1)
+	if err != nil {
+		return err
+	}
+	state.ScheduleCommission(from, commission, ep.Number+1)
+	state.AddLog(&types.Log{
+		Address:     neatabi.ChainContractMagicAddr,
+		Topics:      []common.Hash{event.ID(), common.BytesToHash(from[:])},
+		Data:        data,
+		BlockNumber: header.Number.Uint64(),
+	})
+
+	return nil
+}
+
+func setCommissionValidation(from common.Address, tx *types.Transaction, state *state.StateDB, config *params.ChainConfig, number *big.Int) (*neatabi.SetCommissionArgs, error) {
+	if !state.IsCandidate(from) {
+		return nil, core.ErrNotCandidate
+	}
+
+	var args neatabi.SetCommissionArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SetCommission.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if args.Commission > 100 {
+		return nil, core.ErrCommission
+	}
+
+	// The governed limits apply from the commission schedule fork only
+	if !config.IsCommissionSchedule(number) {
+		return &args, nil
+	}
+
+	if args.Commission > state.MaxCommission() {
+		return nil, core.ErrCommissionTooHigh
+	}
+
+	// Cuts are limited too, a candidate could otherwise draw delegations away
+	// from the others with a cut it raises back in a few epochs
+	change := int(args.Commission) - int(state.GetCommission(from))
+	if change < 0 {
+		change = -change
+	}
+	if change > int(state.MaxCommissionChange()) {
+		return nil, core.ErrCommissionChange
+	}
+
+	return &args, nil
+}
+
+// set min self bond
+func setMinSelfBondValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := setMinSelfBondValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func setMinSelfBondApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := setMinSelfBondValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	state.SetMinSelfBond(from, args.Amount)
+
+	return nil
+}
+
+func setMinSelfBondValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.SetMinSelfBondArgs, error) {
+	if !state.IsCandidate(from) {
+		return nil, core.ErrNotCandidate
+	}
+
+	var args neatabi.SetMinSelfBondArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SetMinSelfBond.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	// Delegators rely on the minimum, it can only be raised
+	if args.Amount.Cmp(state.GetMinSelfBond(from)) < 0 {
+		return nil, core.ErrMinSelfBondDecrease
+	}
+
+	if args.Amount.Cmp(state.GetSelfBond(from)) > 0 {
+		return nil, core.ErrBelowMinSelfBond
+	}
+
+	return &args, nil
+}
+
+// rotate consensus key
+func rotateConsensusKeyValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := rotateConsensusKeyValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func rotateConsensusKeyApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := rotateConsensusKeyValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	var blsPK goCrypto.BLSPubKey
+	copy(blsPK[:], args.Pubkey)
+	state.SetPubkey(from, blsPK.KeyString())
+
+	// the validator set picks up the new key when entering the next epoch
+	state.MarkAddressKeyRotation(from)
+
+	return nil
+}
+
+func rotateConsensusKeyValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.RotateConsensusKeyArgs, error) {
+	if !state.IsCandidate(from) {
+		return nil, core.ErrNotCandidate
+	}
+
+	var args neatabi.RotateConsensusKeyArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.RotateConsensusKey.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if err := goCrypto.CheckConsensusPubKey(from, args.Pubkey, args.Signature); err != nil {
+		return nil, err
 	}
 
-	// Check Epoch Height
-	if _, err := getEpoch(bc); err != nil {
-		return err
+	var blsPK goCrypto.BLSPubKey
+	copy(blsPK[:], args.Pubkey)
+	if err := ncTypes.ValidateBLSPubKey(blsPK); err != nil {
+		return nil, err
+	}
+	if blsPK.KeyString() == state.GetPubkey(from) {
+		return nil, core.ErrSameConsensusKey
 	}
 
-	return nil
+	return &args, nil
 }
 
-// delegate and unDelegate
-func delegateValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+// governance
+func submitGovProposalValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
-	_, verror := delegateValidation(from, tx, state, bc)
-	if verror != nil {
-		return verror
+	_, err := submitGovProposalValidation(from, tx, state, bc)
+	if err != nil {
+		return err
 	}
+
 	return nil
 }
 
-func delegateApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
-	// Validate first
+func submitGovProposalApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
 	from := derivedAddressFromTx(tx)
-	args, verror := delegateValidation(from, tx, state, bc)
-	if verror != nil {
-		return verror
-	}
-
-	// block height validation
-	verror = updateValidation(bc)
-	if verror != nil {
-		return verror
+	args, err := submitGovProposalValidation(from, tx, state, bc)
+	if err != nil {
+		return err
 	}
 
-	// Do job
-	amount := tx.Value()
-	// Move Balance to delegate balance
-	state.SubBalance(from, amount)
-	state.AddDelegateBalance(from, amount)
-	// Add Balance to Candidate's Proxied Balance
-	state.AddProxiedBalanceByUser(args.Candidate, from, amount)
-
-	// if banned, don't add to next epoch validator vote set
-	if !state.GetBanned(from) {
-		verror = updateNextEpochValidatorVoteSet(tx, state, bc, args.Candidate, ops)
-		if verror != nil {
-			return verror
-		}
-	}
+	number := header.Number.Uint64()
+	id := state.AddGovProposal(from, args.Title, args.Description, args.Param, args.Value, args.Recipient, args.Epoch, number)
+	addGovDeposit(state, id, from, tx.Value(), number)
 
 	return nil
 }
 
-func delegateValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.DelegateArgs, error) {
-	// Check minimum delegate amount
-	if tx.Value().Sign() == -1 {
-		return nil, core.ErrDelegateAmount
-	}
-
-	var args neatabi.DelegateArgs
+func submitGovProposalValidation(from common.Address, tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) (*neatabi.SubmitGovProposalArgs, error) {
+	var args neatabi.SubmitGovProposalArgs
 	data := tx.Data()
-	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.Delegate.String(), data[4:]); err != nil {
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SubmitGovProposal.String(), data[4:]); err != nil {
 		return nil, err
 	}
 
-	// Check Candidate
-	if !state.IsCandidate(args.Candidate) {
-		return nil, core.ErrNotCandidate
+	if args.Title == "" || len(args.Title) > maxGovTitleLength || len(args.Description) > maxGovDescriptionLength {
+		return nil, fmt.Errorf("title must have 1 to %v bytes and description at most %v bytes", maxGovTitleLength, maxGovDescriptionLength)
+	}
+	if statedb.OpenGovProposals() >= maxOpenGovProposals {
+		return nil, core.ErrGovProposalLimit
 	}
 
-	depositBalance := state.GetDepositProxiedBalanceByUser(args.Candidate, from)
-	if depositBalance.Sign() == 0 {
-		// Check if exceed the limit of delegated addresses
-		// if exceed the limit of delegation address number, return error
-		delegatedAddressNumber := state.GetProxiedAddressNumber(args.Candidate)
-		if delegatedAddressNumber >= maxDelegationAddresses {
-			return nil, core.ErrExceedDelegationAddressLimit
+	if args.Param == state.GovParamTreasurySpend {
+		if err := state.ValidateGovParam(args.Param, args.Value); err != nil {
+			return nil, err
+		}
+		if args.Recipient == (common.Address{}) {
+			return nil, errors.New("treasury spend without recipient")
+		}
+	} else if args.Param != "" {
+		if err := state.ValidateGovParam(args.Param, args.Value); err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(args.Param, state.GovParamRetirePrefix) {
+			chainId := strings.TrimPrefix(args.Param, state.GovParamRetirePrefix)
+			if !bc.Config().IsMainChain() {
+				return nil, errors.New("side chains are retired by the governance of the main chain")
+			}
+			if core.GetChainInfo(bc.GetCrossChainHelper().GetChainInfoDB(), chainId) == nil {
+				return nil, fmt.Errorf("side chain %q does not exist", chainId)
+			}
+		}
+		ep, err := getEpoch(bc)
+		if err != nil {
+			return nil, err
+		}
+		if args.Epoch <= ep.Number || args.Epoch > ep.Number+uint64(maxGovProposalEpochs) {
+			return nil, core.ErrGovProposalEpoch
 		}
 	}
 
-	// If Candidate is supernode, only allow to increase the stack(whitelist proxied list), not allow to create the new stack
-	var ep *epoch.Epoch
-	if tdm, ok := bc.Engine().(consensus.NeatPoS); ok {
-		ep = tdm.GetEpoch().GetEpochByBlockNumber(bc.CurrentBlock().NumberU64())
-	}
-	if _, supernode := ep.Validators.GetByAddress(args.Candidate.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
-		if depositBalance.Sign() == 0 {
-			return nil, core.ErrCannotDelegate
-		}
+	if err := govDepositValidation(from, tx, statedb); err != nil {
+		return nil, err
 	}
 
-	// Check Epoch Height
-	//if _, err := getEpoch(bc); err != nil {
-	//	return nil, err
-	//}
 	return &args, nil
 }
 
-func unDelegateValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+func depositGovProposalValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
-	_, verror := unDelegateValidation(from, tx, state, bc)
-	if verror != nil {
-		return verror
+	_, err := depositGovProposalValidation(from, tx, state, bc)
+	if err != nil {
+		return err
 	}
+
 	return nil
 }
 
-func unDelegateApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
-	// Validate first
+func depositGovProposalApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
 	from := derivedAddressFromTx(tx)
-	args, verror := unDelegateValidation(from, tx, state, bc)
-	if verror != nil {
-		return verror
+	args, err := depositGovProposalValidation(from, tx, state, bc)
+	if err != nil {
+		return err
 	}
 
-	// block height validation
-	verror = updateValidation(bc)
-	if verror != nil {
-		return verror
+	addGovDeposit(state, args.Id, from, tx.Value(), header.Number.Uint64())
+
+	return nil
+}
+
+func depositGovProposalValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.DepositGovProposalArgs, error) {
+	var args neatabi.DepositGovProposalArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.DepositGovProposal.String(), data[4:]); err != nil {
+		return nil, err
 	}
 
-	// Apply Logic
-	// if request amount < proxied amount, refund it immediately
-	// otherwise, refund the proxied amount, and put the rest to pending refund balance
-	proxiedBalance := state.GetProxiedBalanceByUser(args.Candidate, from)
-	var immediatelyRefund *big.Int
-	if args.Amount.Cmp(proxiedBalance) <= 0 {
-		immediatelyRefund = args.Amount
-	} else {
-		immediatelyRefund = proxiedBalance
-		restRefund := new(big.Int).Sub(args.Amount, proxiedBalance)
-		state.AddPendingRefundBalanceByUser(args.Candidate, from, restRefund)
-		// TODO Add Pending Refund Set, Commit the Refund Set
-		state.MarkDelegateAddressRefund(args.Candidate)
+	proposal := state.GetGovProposal(args.Id)
+	if proposal == nil {
+		return nil, core.ErrGovProposalNotFound
+	}
+	if !proposal.InDepositPeriod() {
+		return nil, core.ErrGovProposalClosed
 	}
 
-	state.SubProxiedBalanceByUser(args.Candidate, from, immediatelyRefund)
-	state.SubDelegateBalance(from, immediatelyRefund)
-	state.AddBalance(from, immediatelyRefund)
+	if err := govDepositValidation(from, tx, state); err != nil {
+		return nil, err
+	}
 
-	//verror = updateNextEpochValidatorVoteSet(tx, state, bc, args.Candidate)
-	//if verror != nil {
-	//	return verror
-	//}
+	return &args, nil
+}
 
+func govDepositValidation(from common.Address, tx *types.Transaction, state *state.StateDB) error {
+	if tx.Value().Sign() <= 0 {
+		return core.ErrGovNoDeposit
+	}
+	if state.GetBalance(from).Cmp(tx.Value()) < 0 {
+		return core.ErrInsufficientFunds
+	}
 	return nil
 }
 
-func unDelegateValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.UnDelegateArgs, error) {
+// addGovDeposit moves the deposit from the balance of the depositor to the
+// proposal, opening the voting once the minimum deposit is reached.
+func addGovDeposit(state *state.StateDB, id uint64, depositor common.Address, amount *big.Int, number uint64) {
+	state.SubBalance(depositor, amount)
+	state.AddGovDeposit(id, depositor, amount)
 
-	var args neatabi.UnDelegateArgs
-	data := tx.Data()
-	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.UnDelegate.String(), data[4:]); err != nil {
-		return nil, err
+	if state.GetGovProposal(id).TotalDeposit().Cmp(state.GovMinDeposit()) >= 0 {
+		state.StartGovVoting(id, number)
 	}
+}
 
-	// Check Self Address
-	if from == args.Candidate {
-		return nil, core.ErrCancelSelfDelegate
+func voteGovProposalValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := voteGovProposalValidation(from, tx, state, bc)
+	if err != nil {
+		return err
 	}
 
-	// Super node Candidate can't decrease balance
-	var ep *epoch.Epoch
-	if tdm, ok := bc.Engine().(consensus.NeatPoS); ok {
-		ep = tdm.GetEpoch().GetEpochByBlockNumber(bc.CurrentBlock().NumberU64())
-	}
-	if _, supernode := ep.Validators.GetByAddress(args.Candidate.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
-		return nil, core.ErrCannotUnBond
-	}
+	return nil
+}
 
-	// Check Proxied Amount in Candidate Balance
-	proxiedBalance := state.GetProxiedBalanceByUser(args.Candidate, from)
-	depositProxiedBalance := state.GetDepositProxiedBalanceByUser(args.Candidate, from)
-	pendingRefundBalance := state.GetPendingRefundBalanceByUser(args.Candidate, from)
-	// net = deposit - pending refund
-	netDeposit := new(big.Int).Sub(depositProxiedBalance, pendingRefundBalance)
-	// available = proxied + net
-	availableRefundBalance := new(big.Int).Add(proxiedBalance, netDeposit)
-	if args.Amount.Cmp(availableRefundBalance) == 1 {
-		return nil, core.ErrInsufficientProxiedBalance
+func voteGovProposalApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := voteGovProposalValidation(from, tx, state, bc)
+	if err != nil {
+		return err
 	}
 
-	// if left, the left must be greater than the min delegate amount
-	//remainingBalance := new(big.Int).Sub(availableRefundBalance, args.Amount)
-	//if remainingBalance.Sign() == 1 && remainingBalance.Cmp(minimumDelegationAmount) == -1 {
-	//	return nil, core.ErrDelegateAmount
-	//}
+	state.VoteGovProposal(args.Id, from, args.Approve)
 
-	// Check Epoch Height
-	if _, err := getEpoch(bc); err != nil {
+	return nil
+}
+
+func voteGovProposalValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.VoteGovProposalArgs, error) {
+	if epoch.GovStake(state, from).Sign() == 0 {
+		return nil, core.ErrGovNoStake
+	}
+
+	var args neatabi.VoteGovProposalArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.VoteGovProposal.String(), data[4:]); err != nil {
 		return nil, err
 	}
 
+	proposal := state.GetGovProposal(args.Id)
+	if proposal == nil {
+		return nil, core.ErrGovProposalNotFound
+	}
+	if !proposal.Voting() {
+		return nil, core.ErrGovProposalClosed
+	}
+
 	return &args, nil
 }
 
-// set commission
-func setCommisstionValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+// upgrade signal
+func signalUpgradeValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
-	_, err := setCommissionValidation(from, tx, state, bc)
+	_, err := signalUpgradeValidation(from, tx, state, bc)
 	if err != nil {
 		return err
 	}
@@ -2352,31 +3696,38 @@ func setCommisstionValidateCb(tx *types.Transaction, state *state.StateDB, bc *c
 	return nil
 }
 
-func setCommisstionApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+func signalUpgradeApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
 	from := derivedAddressFromTx(tx)
-	args, err := setCommissionValidation(from, tx, state, bc)
+	args, err := signalUpgradeValidation(from, tx, state, bc)
 	if err != nil {
 		return err
 	}
 
-	state.SetCommission(from, args.Commission)
+	state.SignalUpgrade(args.Name, from)
 
 	return nil
 }
 
-func setCommissionValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.SetCommissionArgs, error) {
-	if !state.IsCandidate(from) {
-		return nil, core.ErrNotCandidate
+func signalUpgradeValidation(from common.Address, tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) (*neatabi.SignalUpgradeArgs, error) {
+	var args neatabi.SignalUpgradeArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SignalUpgrade.String(), data[4:]); err != nil {
+		return nil, err
 	}
 
-	var args neatabi.SetCommissionArgs
-	data := tx.Data()
-	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SetCommission.String(), data[4:]); err != nil {
+	// whether this node supports the upgrade must not matter here
+	if err := state.ValidateGovParam(state.UpgradeParam(args.Name), common.Big1); err != nil {
 		return nil, err
 	}
 
-	if args.Commission > 100 {
-		return nil, core.ErrCommission
+	if !statedb.IsCandidate(from) {
+		ep, err := getEpoch(bc)
+		if err != nil {
+			return nil, err
+		}
+		if !ep.Validators.HasAddress(from.Bytes()) {
+			return nil, core.ErrNotValidator
+		}
 	}
 
 	return &args, nil
@@ -2384,14 +3735,43 @@ func setCommissionValidation(from common.Address, tx *types.Transaction, state *
 
 func editValidatorValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
+	_, err := editValidatorValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func editValidatorApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := editValidatorValidation(from, tx, statedb, bc)
+	if err != nil {
+		return err
+	}
+
+	metadata := &state.ValidatorMetadata{}
+	if current := statedb.GetValidatorMetadata(from); current != nil {
+		metadata = current
+	}
+	metadata.Moniker = args.Moniker
+	metadata.Website = args.Website
+	metadata.Identity = args.Identity
+	metadata.Details = args.Details
+	statedb.SetValidatorMetadata(from, metadata)
+
+	return nil
+}
+
+func editValidatorValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.EditValidatorArgs, error) {
 	if !state.IsCandidate(from) {
-		return errors.New("you are not a validator or candidate")
+		return nil, errors.New("you are not a validator or candidate")
 	}
 
 	var args neatabi.EditValidatorArgs
 	data := tx.Data()
 	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.EditValidator.String(), data[4:]); err != nil {
-		return err
+		return nil, err
 	}
 
 	if len([]byte(args.Details)) > maxEditValidatorLength ||
@@ -2399,12 +3779,58 @@ func editValidatorValidateCb(tx *types.Transaction, state *state.StateDB, bc *co
 		len([]byte(args.Moniker)) > maxEditValidatorLength ||
 		len([]byte(args.Website)) > maxEditValidatorLength {
 		//fmt.Printf("args details length %v, identity length %v, moniker lenth %v, website length %v\n", len([]byte(args.Details)),len([]byte(args.Identity)),len([]byte(args.Moniker)),len([]byte(args.Website)))
-		return fmt.Errorf("args length too long, more than %v", maxEditValidatorLength)
+		return nil, fmt.Errorf("args length too long, more than %v", maxEditValidatorLength)
 	}
 
+	return &args, nil
+}
+
+// set security contact
+func setSecurityContactValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := setSecurityContactValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func setSecurityContactApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := setSecurityContactValidation(from, tx, statedb, bc)
+	if err != nil {
+		return err
+	}
+
+	metadata := &state.ValidatorMetadata{}
+	if current := statedb.GetValidatorMetadata(from); current != nil {
+		metadata = current
+	}
+	metadata.SecurityContact = args.SecurityContact
+	statedb.SetValidatorMetadata(from, metadata)
+
 	return nil
 }
 
+func setSecurityContactValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.SetSecurityContactArgs, error) {
+	if !state.IsCandidate(from) {
+		return nil, errors.New("you are not a validator or candidate")
+	}
+
+	var args neatabi.SetSecurityContactArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SetSecurityContact.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if len([]byte(args.SecurityContact)) > maxEditValidatorLength {
+		return nil, fmt.Errorf("args length too long, more than %v", maxEditValidatorLength)
+	}
+
+	return &args, nil
+}
+
 func unBannedValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
 
@@ -2416,7 +3842,7 @@ func unBannedValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.Bl
 	return nil
 }
 
-func unBannedApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+func unBannedApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
 	from := derivedAddressFromTx(tx)
 	err := unBannedValidation(from, state, bc)
 	if err != nil {
@@ -2458,6 +3884,10 @@ func unBannedValidation(from common.Address, state *state.StateDB, bc *core.Bloc
 		return fmt.Errorf("please unbanned %v epoch later", bannedEpoch)
 	}
 
+	if state.IsBelowMinSelfBond(from) {
+		return core.ErrBelowMinSelfBond
+	}
+
 	return nil
 }
 
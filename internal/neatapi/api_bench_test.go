@@ -0,0 +1,56 @@
+package neatapi
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rlp"
+)
+
+// BenchmarkGetBlockByNumber serves eth_getBlockByNumber with full transactions
+// from concurrent callers. The block is decoded afresh for every call, as it
+// is when read from the database, so none of its hashes are cached yet.
+func BenchmarkGetBlockByNumber(b *testing.B) {
+	key, _ := crypto.GenerateKey()
+	backend := &compatBackend{config: params.TestChainConfig}
+	signer := types.NewEIP155Signer(backend.config.ChainId)
+	txs := make([]*types.Transaction, 200)
+	for i := range txs {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{1}, big.NewInt(1), 21000, big.NewInt(params.GWei), nil), signer, key)
+		if err != nil {
+			b.Fatal(err)
+		}
+		txs[i] = tx
+	}
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 8000000, GasUsed: 21000 * uint64(len(txs))}
+	block := types.NewBlock(header, txs, nil, nil)
+	backend.blocks = []*types.Block{block}
+	enc, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		b.Fatal(err)
+	}
+	api := NewPublicBlockChainAPI(backend)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			decoded := new(types.Block)
+			if err := rlp.DecodeBytes(enc, decoded); err != nil {
+				b.Fatal(err)
+			}
+			fields, err := api.rpcOutputBlock(decoded, true, true)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := json.Marshal(fields); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -3,14 +3,20 @@ package neatapi
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"testing"
 	"time"
 
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/common/hexutil"
 	"github.com/neatlab/neatio/common/math"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
 	"github.com/neatlab/neatio/crypto"
 	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
 )
 
 type MethoadParams struct {
@@ -148,3 +154,42 @@ func TestGoTime(t *testing.T) {
 	fmt.Printf("duration string %v\n", d.String())
 	fmt.Printf("duration seconds %v\n", d.Seconds())
 }
+
+func TestSetCommissionFork(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	candidate := crypto.PubkeyToAddress(key.PublicKey)
+	input, err := neatabi.ChainABI.Pack(neatabi.SetCommission.String(), uint8(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(types.NewTransaction(0, neatabi.ChainContractMagicAddr, nil, 0, big.NewInt(0), input), types.NewEIP155Signer(big.NewInt(1)), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &params.ChainConfig{CommissionScheduleBlock: big.NewInt(100)}
+
+	// A change of more than the governed limit before the fork replays as it
+	// was applied, at once
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.ApplyForCandidate(candidate, "", 10)
+	header := &types.Header{Number: big.NewInt(99)}
+	args, err := setCommissionValidation(candidate, tx, statedb, config, header.Number)
+	if err != nil {
+		t.Fatalf("pre-fork change rejected: %v", err)
+	}
+	if err := setCommission(config, nil, statedb, candidate, args.Commission, header); err != nil {
+		t.Fatalf("pre-fork change failed: %v", err)
+	}
+	if have := statedb.GetCommission(candidate); have != 30 {
+		t.Errorf("commission mismatch: have %d, want 30", have)
+	}
+	if statedb.GetCommissionChange(candidate) != nil {
+		t.Error("pre-fork change scheduled")
+	}
+
+	// From the fork the change is bounded by the governed limit
+	statedb.SetCommission(candidate, 10)
+	if _, err := setCommissionValidation(candidate, tx, statedb, config, big.NewInt(100)); err != core.ErrCommissionChange {
+		t.Errorf("error mismatch from the fork: have %v, want %v", err, core.ErrCommissionChange)
+	}
+}
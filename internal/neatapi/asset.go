@@ -0,0 +1,216 @@
+package neatapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/rpc"
+)
+
+type AssetContractResult struct {
+	Chain    string         `json:"chain"`
+	Contract common.Address `json:"contract"`
+}
+
+type AssetResult struct {
+	Id          string                 `json:"id"`
+	Symbol      string                 `json:"symbol"`
+	OriginChain string                 `json:"originChain"`
+	Owner       common.Address         `json:"owner"`
+	Contracts   []*AssetContractResult `json:"contracts"`
+}
+
+func newAssetResult(asset *state.Asset) *AssetResult {
+	result := &AssetResult{
+		Id:          asset.Id,
+		Symbol:      asset.Symbol,
+		OriginChain: asset.OriginChain,
+		Owner:       asset.Owner,
+		Contracts:   make([]*AssetContractResult, len(asset.Contracts)),
+	}
+	for i, c := range asset.Contracts {
+		result.Contracts[i] = &AssetContractResult{Chain: c.Chain, Contract: c.Contract}
+	}
+	return result
+}
+
+// GetAssets returns the assets of the registry, ordered by id.
+func (api *PublicNeatApi) GetAssets(ctx context.Context, blockNr rpc.BlockNumber) ([]*AssetResult, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	results := make([]*AssetResult, 0)
+	for _, asset := range statedb.GetAssets() {
+		results = append(results, newAssetResult(asset))
+	}
+	return results, nil
+}
+
+// GetAsset returns the asset id, <origin chain>:<symbol>, of the registry.
+func (api *PublicNeatApi) GetAsset(ctx context.Context, id string, blockNr rpc.BlockNumber) (*AssetResult, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	asset := statedb.GetAsset(id)
+	if asset == nil {
+		return nil, core.ErrUnknownAsset
+	}
+	return newAssetResult(asset), nil
+}
+
+// GetAssetByContract returns the asset the contract represents on the chain.
+func (api *PublicNeatApi) GetAssetByContract(ctx context.Context, chain string, contract common.Address, blockNr rpc.BlockNumber) (*AssetResult, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	asset := statedb.GetAssetByContract(chain, contract)
+	if asset == nil {
+		return nil, core.ErrUnknownAsset
+	}
+	return newAssetResult(asset), nil
+}
+
+// RegisterAsset adds the token of symbol originating from the chain to the
+// registry of the main chain, represented there by contract. The asset is
+// identified by <originChain>:<symbol>.
+func (api *PrivateNeatApi) RegisterAsset(ctx context.Context, from common.Address, symbol, originChain string, contract common.Address, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendBridgeTx(ctx, from, neatabi.RegisterAsset, nil, gasPrice, symbol, originChain, contract)
+}
+
+// SetAssetContract sets the contract representing the asset on the chain.
+// Only the account which registered the asset can map its contracts.
+func (api *PrivateNeatApi) SetAssetContract(ctx context.Context, from common.Address, assetId, chain string, contract common.Address, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendBridgeTx(ctx, from, neatabi.SetAssetContract, nil, gasPrice, assetId, chain, contract)
+}
+
+func init() {
+	// Asset Registry
+	core.RegisterValidateCb(neatabi.RegisterAsset, registerAssetValidateCb)
+	core.RegisterApplyCb(neatabi.RegisterAsset, registerAssetApplyCb)
+	core.RegisterValidateCb(neatabi.SetAssetContract, setAssetContractValidateCb)
+	core.RegisterApplyCb(neatabi.SetAssetContract, setAssetContractApplyCb)
+}
+
+// assetChains returns whether a chain can hold the contracts of assets: the
+// main chain and the side chains it launched, as recorded in statedb.
+func assetChains(bc *core.BlockChain, statedb *state.StateDB) func(string) bool {
+	return func(chain string) bool {
+		return chain == bc.Config().NeatChainId || statedb.IsSideChain(chain)
+	}
+}
+
+// checkBridgeAsset rejects the bridge transfers of a denomination naming an
+// asset, <origin chain>:<symbol> possibly behind the path of the channels it
+// went through, which is not in the registry.
+func checkBridgeAsset(statedb *state.StateDB, denom string) error {
+	base := denom[strings.LastIndex(denom, "/")+1:]
+	if strings.Contains(base, ":") && statedb.GetAsset(base) == nil {
+		return fmt.Errorf("%v: %s", core.ErrUnknownAsset, base)
+	}
+	return nil
+}
+
+// register asset
+func registerAssetValidateCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) error {
+	_, err := registerAssetValidation(tx, statedb, assetChains(bc, statedb))
+	return err
+}
+
+func registerAssetApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	args, err := registerAssetValidation(tx, statedb, assetChains(bc, statedb))
+	if err != nil {
+		return err
+	}
+	statedb.RegisterAsset(args.Symbol, args.OriginChain, derivedAddressFromTx(tx), args.Contract)
+	return nil
+}
+
+func registerAssetValidation(tx *types.Transaction, statedb *state.StateDB, knownChain func(string) bool) (*neatabi.RegisterAssetArgs, error) {
+	var args neatabi.RegisterAssetArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.RegisterAsset.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if err := state.ValidateAssetSymbol(args.Symbol); err != nil {
+		return nil, err
+	}
+	if !knownChain(args.OriginChain) {
+		return nil, fmt.Errorf("unknown chain %q", args.OriginChain)
+	}
+	if statedb.GetAsset(state.AssetId(args.OriginChain, args.Symbol)) != nil {
+		return nil, core.ErrAssetExists
+	}
+	if err := checkAssetContract(statedb, args.OriginChain, args.Contract); err != nil {
+		return nil, err
+	}
+	return &args, nil
+}
+
+// set asset contract
+func setAssetContractValidateCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := setAssetContractValidation(from, tx, statedb, assetChains(bc, statedb))
+	return err
+}
+
+func setAssetContractApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := setAssetContractValidation(from, tx, statedb, assetChains(bc, statedb))
+	if err != nil {
+		return err
+	}
+	statedb.SetAssetContract(args.AssetId, args.Chain, args.Contract)
+	return nil
+}
+
+func setAssetContractValidation(from common.Address, tx *types.Transaction, statedb *state.StateDB, knownChain func(string) bool) (*neatabi.SetAssetContractArgs, error) {
+	var args neatabi.SetAssetContractArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SetAssetContract.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	asset := statedb.GetAsset(args.AssetId)
+	if asset == nil {
+		return nil, core.ErrUnknownAsset
+	}
+	if asset.Owner != from {
+		return nil, core.ErrNotAssetOwner
+	}
+	if !knownChain(args.Chain) {
+		return nil, fmt.Errorf("unknown chain %q", args.Chain)
+	}
+	// The origin chain holds the token itself, it is not remapped
+	if args.Chain == asset.OriginChain {
+		return nil, fmt.Errorf("asset %s originates from chain %s", asset.Id, args.Chain)
+	}
+	if err := checkAssetContract(statedb, args.Chain, args.Contract); err != nil {
+		return nil, err
+	}
+	return &args, nil
+}
+
+// checkAssetContract checks the contract represents no asset on the chain.
+func checkAssetContract(statedb *state.StateDB, chain string, contract common.Address) error {
+	if contract == (common.Address{}) {
+		return fmt.Errorf("invalid contract address")
+	}
+	if statedb.GetAssetByContract(chain, contract) != nil {
+		return core.ErrAssetContractTaken
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package neatapi
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+)
+
+func TestAssetRegistry(t *testing.T) {
+	owner, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	sign := func(key *ecdsa.PrivateKey, function neatabi.FunctionType, args ...interface{}) *types.Transaction {
+		input, err := neatabi.ChainABI.Pack(function.String(), args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := types.SignTx(types.NewTransaction(0, neatabi.ChainContractMagicAddr, nil, 0, big.NewInt(0), input), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	// The side chains launched by the main chain are known from its state
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.AddSideChain("side_0")
+	statedb.AddSideChain("side_1")
+	knownChain := func(chain string) bool {
+		return chain == "neatio" || statedb.IsSideChain(chain)
+	}
+	register := func(key *ecdsa.PrivateKey, symbol, chain string, contract common.Address) error {
+		tx := sign(key, neatabi.RegisterAsset, symbol, chain, contract)
+		args, err := registerAssetValidation(tx, statedb, knownChain)
+		if err == nil {
+			statedb.RegisterAsset(args.Symbol, args.OriginChain, derivedAddressFromTx(tx), args.Contract)
+		}
+		return err
+	}
+
+	// The same symbol bridged from two side chains makes two assets
+	if err := register(owner, "USDT", "side_0", common.Address{0x01}); err != nil {
+		t.Fatalf("failed to register side_0:USDT: %v", err)
+	}
+	if err := register(other, "USDT", "side_1", common.Address{0x01}); err != nil {
+		t.Fatalf("failed to register side_1:USDT: %v", err)
+	}
+	if err := register(other, "USDT", "side_0", common.Address{0x02}); err != core.ErrAssetExists {
+		t.Errorf("asset registered twice: %v", err)
+	}
+	if err := register(other, "USDC", "side_0", common.Address{0x01}); err != core.ErrAssetContractTaken {
+		t.Errorf("contract registered for two assets: %v", err)
+	}
+	if err := register(other, "USDC", "side_9", common.Address{0x03}); err == nil {
+		t.Error("asset registered on an unknown chain")
+	}
+	if err := register(other, "US-DC", "side_0", common.Address{0x03}); err == nil {
+		t.Error("asset registered with an invalid symbol")
+	}
+
+	// Only the owner maps the contracts of the asset
+	setContract := func(key *ecdsa.PrivateKey, id, chain string, contract common.Address) error {
+		tx := sign(key, neatabi.SetAssetContract, id, chain, contract)
+		args, err := setAssetContractValidation(derivedAddressFromTx(tx), tx, statedb, knownChain)
+		if err == nil {
+			statedb.SetAssetContract(args.AssetId, args.Chain, args.Contract)
+		}
+		return err
+	}
+	if err := setContract(other, "side_0:USDT", "neatio", common.Address{0x04}); err != core.ErrNotAssetOwner {
+		t.Errorf("contract mapped by another account: %v", err)
+	}
+	if err := setContract(owner, "side_0:USDT", "neatio", common.Address{0x04}); err != nil {
+		t.Fatalf("failed to map the contract: %v", err)
+	}
+	if err := setContract(owner, "side_0:USDT", "side_0", common.Address{0x05}); err == nil {
+		t.Error("contract of the origin chain remapped")
+	}
+	if asset := statedb.GetAssetByContract("neatio", common.Address{0x04}); asset == nil || asset.Id != "side_0:USDT" {
+		t.Errorf("asset by contract mismatch: have %+v", asset)
+	}
+	if contract, ok := statedb.GetAsset("side_0:USDT").Contract("neatio"); !ok || contract != (common.Address{0x04}) {
+		t.Errorf("asset contract mismatch: have %x", contract)
+	}
+
+	// The bridge only transfers the registered assets
+	for denom, known := range map[string]bool{
+		"side_0:USDT":                    true,
+		"transfer/channel-0/side_1:USDT": true,
+		"side_2:USDT":                    false,
+		"transfer/channel-0/uatom":       true,
+	} {
+		if err := checkBridgeAsset(statedb, denom); (err == nil) != known {
+			t.Errorf("denom %s: have error %v, want known %v", denom, err, known)
+		}
+	}
+}
+
+func TestAssetRegistryRevert(t *testing.T) {
+	owner := common.Address{0x0a}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.RegisterAsset("USDT", "side_0", owner, common.Address{0x01})
+	statedb.Finalise(false)
+
+	snapshot := statedb.Snapshot()
+	statedb.RegisterAsset("USDC", "side_0", owner, common.Address{0x02})
+	statedb.SetAssetContract("side_0:USDT", "neatio", common.Address{0x03})
+	statedb.SetAssetContract("side_0:USDT", "side_0", common.Address{0x04})
+	statedb.RevertToSnapshot(snapshot)
+
+	if asset := statedb.GetAsset("side_0:USDC"); asset != nil {
+		t.Errorf("reverted asset registered: %+v", asset)
+	}
+	asset := statedb.GetAsset("side_0:USDT")
+	if asset == nil {
+		t.Fatal("asset registered before the snapshot missing")
+	}
+	if len(asset.Contracts) != 1 || asset.Contracts[0].Contract != (common.Address{0x01}) {
+		t.Errorf("reverted contracts kept: %+v", asset.Contracts)
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/neatlab/neatio/accounts"
 	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
 	"github.com/neatlab/neatio/core"
 	"github.com/neatlab/neatio/core/state"
 	"github.com/neatlab/neatio/core/types"
@@ -53,7 +54,7 @@ type Backend interface {
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
 	GetTd(blockHash common.Hash) *big.Int
-	GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error)
+	GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, overrides *BlockOverrides, vmCfg vm.Config) (*vm.EVM, func() error, error)
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 	SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription
@@ -69,6 +70,7 @@ type Backend interface {
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
+	Engine() consensus.Engine
 
 	//SetInnerAPIBridge(inBridge InnerAPIBridge)
 	//GetInnerAPIBridge() InnerAPIBridge
@@ -148,6 +150,11 @@ func GetAPIs(apiBackend Backend, solcPath string) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicNeatApi(apiBackend, nonceLock),
 			Public:    true,
+		}, {
+			Namespace: "operator",
+			Version:   "1.0",
+			Service:   NewPrivateNeatApi(apiBackend, nonceLock),
+			Public:    false,
 		},
 	}
 	return append(compiler, all...)
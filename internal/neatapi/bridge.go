@@ -0,0 +1,634 @@
+package neatapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/common/neataddr"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/bridge"
+	_ "github.com/neatlab/neatio/core/bridge/tendermint"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/rpc"
+)
+
+// maxBridgeConsensusStates is the number of consensus states kept by each
+// bridge client, packets are proven against one of them.
+const maxBridgeConsensusStates = 100
+
+type BridgeClientResult struct {
+	Id                  string         `json:"id"`
+	Type                string         `json:"type"`
+	ChainId             string         `json:"chainId"`
+	LatestHeight        hexutil.Uint64 `json:"latestHeight"`
+	Frozen              bool           `json:"frozen"`
+	Port                string         `json:"port"`
+	Channel             string         `json:"channel"`
+	CounterpartyPort    string         `json:"counterpartyPort"`
+	CounterpartyChannel string         `json:"counterpartyChannel"`
+	Escrowed            *hexutil.Big   `json:"escrowed"`
+	NextSequence        hexutil.Uint64 `json:"nextSequence"`
+}
+
+func newBridgeClientResult(record *state.BridgeClient) (*BridgeClientResult, error) {
+	client, err := bridge.DecodeClient(record.Type, record.State)
+	if err != nil {
+		return nil, err
+	}
+	return &BridgeClientResult{
+		Id:                  record.Id,
+		Type:                record.Type,
+		ChainId:             client.ChainID(),
+		LatestHeight:        hexutil.Uint64(client.LatestHeight()),
+		Frozen:              record.Frozen,
+		Port:                record.Port,
+		Channel:             record.Channel,
+		CounterpartyPort:    record.CounterpartyPort,
+		CounterpartyChannel: record.CounterpartyChannel,
+		Escrowed:            (*hexutil.Big)(record.Escrowed),
+		NextSequence:        hexutil.Uint64(record.NextSequence),
+	}, nil
+}
+
+// GetBridgeClients returns the light clients of external chains hosted by
+// the bridge.
+func (api *PublicNeatApi) GetBridgeClients(ctx context.Context, blockNr rpc.BlockNumber) ([]*BridgeClientResult, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	results := make([]*BridgeClientResult, 0)
+	for _, record := range statedb.GetBridgeClients() {
+		result, err := newBridgeClientResult(record)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// GetBridgeClient returns the bridge client clientId.
+func (api *PublicNeatApi) GetBridgeClient(ctx context.Context, clientId string, blockNr rpc.BlockNumber) (*BridgeClientResult, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+
+	record := statedb.GetBridgeClient(clientId)
+	if record == nil {
+		return nil, bridge.ErrUnknownClient
+	}
+	return newBridgeClientResult(record)
+}
+
+// GetBridgeVoucherBalance returns the tokens of denom received from external
+// chains held by address.
+func (api *PublicNeatApi) GetBridgeVoucherBalance(ctx context.Context, address common.Address, denom string, blockNr rpc.BlockNumber) (*hexutil.Big, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(statedb.GetBridgeVoucher(denom, address)), nil
+}
+
+func (api *PrivateNeatApi) sendBridgeTx(ctx context.Context, from common.Address, function neatabi.FunctionType, value *hexutil.Big, gasPrice *hexutil.Big, args ...interface{}) (common.Hash, error) {
+	input, err := neatabi.ChainABI.Pack(function.String(), args...)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := function.RequiredGas()
+
+	txArgs := SendTxArgs{
+		From:     from,
+		To:       &neatabi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    value,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, txArgs, api.am, api.b, api.nonceLock)
+}
+
+// CreateBridgeClient hosts a light client of an external chain, trusting the
+// consensus state at its latest height, to transfer tokens on a channel.
+func (api *PrivateNeatApi) CreateBridgeClient(ctx context.Context, from common.Address, clientType string, clientState, consensusState hexutil.Bytes,
+	port, channel, counterpartyPort, counterpartyChannel string, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendBridgeTx(ctx, from, neatabi.CreateBridgeClient, nil, gasPrice,
+		clientType, []byte(clientState), []byte(consensusState), port, channel, counterpartyPort, counterpartyChannel)
+}
+
+// UpdateBridgeClient submits a header of the external chain to its client.
+func (api *PrivateNeatApi) UpdateBridgeClient(ctx context.Context, from common.Address, clientId string, header hexutil.Bytes, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendBridgeTx(ctx, from, neatabi.UpdateBridgeClient, nil, gasPrice, clientId, []byte(header))
+}
+
+// RecvBridgePacket delivers a packet sent by the external chain, with the
+// proof of its commitment at a height the client verified.
+func (api *PrivateNeatApi) RecvBridgePacket(ctx context.Context, from common.Address, clientId string, packet hexutil.Bytes, proofHeight hexutil.Uint64, proof hexutil.Bytes, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendBridgeTx(ctx, from, neatabi.RecvBridgePacket, nil, gasPrice, clientId, []byte(packet), uint64(proofHeight), []byte(proof))
+}
+
+// SendBridgePacket transfers amount of denom to receiver on the external
+// chain. NEAT is escrowed until it returns, vouchers are burned.
+func (api *PrivateNeatApi) SendBridgePacket(ctx context.Context, from common.Address, clientId, denom string, amount *hexutil.Big, receiver string,
+	timeoutHeight, timeoutTimestamp hexutil.Uint64, gasPrice *hexutil.Big) (common.Hash, error) {
+	var value *hexutil.Big
+	if denom == bridge.NativeDenom {
+		value = amount
+	}
+	return api.sendBridgeTx(ctx, from, neatabi.SendBridgePacket, value, gasPrice,
+		clientId, denom, (*big.Int)(amount), receiver, uint64(timeoutHeight), uint64(timeoutTimestamp))
+}
+
+// AcknowledgeBridgePacket delivers the acknowledgement of a packet sent to
+// the external chain, refunding the transfer if it failed there.
+func (api *PrivateNeatApi) AcknowledgeBridgePacket(ctx context.Context, from common.Address, clientId string, packet, acknowledgement hexutil.Bytes,
+	proofHeight hexutil.Uint64, proof hexutil.Bytes, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendBridgeTx(ctx, from, neatabi.AcknowledgeBridgePacket, nil, gasPrice,
+		clientId, []byte(packet), []byte(acknowledgement), uint64(proofHeight), []byte(proof))
+}
+
+func init() {
+	// Bridge
+	core.RegisterValidateCb(neatabi.CreateBridgeClient, createBridgeClientValidateCb)
+	core.RegisterApplyCb(neatabi.CreateBridgeClient, createBridgeClientApplyCb)
+	core.RegisterValidateCb(neatabi.UpdateBridgeClient, updateBridgeClientValidateCb)
+	core.RegisterApplyCb(neatabi.UpdateBridgeClient, updateBridgeClientApplyCb)
+	core.RegisterValidateCb(neatabi.RecvBridgePacket, recvBridgePacketValidateCb)
+	core.RegisterApplyCb(neatabi.RecvBridgePacket, recvBridgePacketApplyCb)
+	core.RegisterValidateCb(neatabi.SendBridgePacket, sendBridgePacketValidateCb)
+	core.RegisterApplyCb(neatabi.SendBridgePacket, sendBridgePacketApplyCb)
+	core.RegisterValidateCb(neatabi.AcknowledgeBridgePacket, ackBridgePacketValidateCb)
+	core.RegisterApplyCb(neatabi.AcknowledgeBridgePacket, ackBridgePacketApplyCb)
+}
+
+// bridgeNow returns the time the bridge checks trusting periods and packet
+// timeouts against, the one of the block applying the transaction.
+func bridgeNow(header *types.Header) time.Time {
+	return time.Unix(header.Time.Int64(), 0)
+}
+
+// pendingBridgeHeader returns the header the transactions entering the pool
+// are validated against: the next block, at the time of the head.
+func pendingBridgeHeader(bc *core.BlockChain) *types.Header {
+	head := bc.CurrentBlock().Header()
+	return &types.Header{Number: new(big.Int).Add(head.Number, common.Big1), Time: new(big.Int).Set(head.Time)}
+}
+
+// loadBridgeClient returns the client clientId and its record, refusing a
+// frozen client.
+func loadBridgeClient(statedb *state.StateDB, clientId string) (*state.BridgeClient, bridge.Client, error) {
+	record := statedb.GetBridgeClient(clientId)
+	if record == nil {
+		return nil, nil, bridge.ErrUnknownClient
+	}
+	if record.Frozen {
+		return nil, nil, bridge.ErrClientFrozen
+	}
+	client, err := bridge.DecodeClient(record.Type, record.State)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, client, nil
+}
+
+// bridgeConsensusState returns the consensus state of the client at height.
+func bridgeConsensusState(record *state.BridgeClient, height uint64) (*bridge.ConsensusState, error) {
+	data := record.ConsensusState(height)
+	if data == nil {
+		return nil, bridge.ErrNoConsensusState
+	}
+	return bridge.DecodeConsensusState(data)
+}
+
+func validBridgeIdentifier(id string) bool {
+	return id != "" && len(id) <= 64 && !strings.Contains(id, "/")
+}
+
+// create bridge client
+func createBridgeClientValidateCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) error {
+	_, _, _, err := createBridgeClientValidation(tx, statedb)
+	return err
+}
+
+func createBridgeClientApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	args, client, consensus, err := createBridgeClientValidation(tx, statedb)
+	if err != nil {
+		return err
+	}
+
+	clientState, err := client.Encode()
+	if err != nil {
+		return err
+	}
+	consensusState, err := bridge.EncodeConsensusState(consensus)
+	if err != nil {
+		return err
+	}
+	// Clients are never deleted, their count numbers the next one
+	record := &state.BridgeClient{
+		Id:                  fmt.Sprintf("%s-%d", args.ClientType, len(statedb.GetBridgeClients())),
+		Type:                args.ClientType,
+		State:               clientState,
+		Port:                args.Port,
+		Channel:             args.Channel,
+		CounterpartyPort:    args.CounterpartyPort,
+		CounterpartyChannel: args.CounterpartyChannel,
+		Escrowed:            new(big.Int),
+		NextSequence:        1,
+	}
+	record.AddConsensusState(consensus.Height, consensusState, maxBridgeConsensusStates)
+	statedb.SetBridgeClient(record)
+
+	return nil
+}
+
+func createBridgeClientValidation(tx *types.Transaction, statedb *state.StateDB) (*neatabi.CreateBridgeClientArgs, bridge.Client, *bridge.ConsensusState, error) {
+	var args neatabi.CreateBridgeClientArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.CreateBridgeClient.String(), data[4:]); err != nil {
+		return nil, nil, nil, err
+	}
+
+	for _, id := range []string{args.Port, args.Channel, args.CounterpartyPort, args.CounterpartyChannel} {
+		if !validBridgeIdentifier(id) {
+			return nil, nil, nil, fmt.Errorf("%v: identifier %q", bridge.ErrInvalidChannel, id)
+		}
+	}
+	for _, record := range statedb.GetBridgeClients() {
+		if record.Port == args.Port && record.Channel == args.Channel {
+			return nil, nil, nil, fmt.Errorf("%v: channel %s/%s used by client %s", bridge.ErrInvalidChannel, args.Port, args.Channel, record.Id)
+		}
+	}
+
+	client, err := bridge.DecodeClient(args.ClientType, args.ClientState)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	consensus, err := bridge.DecodeConsensusState(args.ConsensusState)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if consensus.Height != client.LatestHeight() || len(consensus.Root) == 0 {
+		return nil, nil, nil, fmt.Errorf("%v at the latest height %d", bridge.ErrNoConsensusState, client.LatestHeight())
+	}
+
+	return &args, client, consensus, nil
+}
+
+// update bridge client
+func updateBridgeClientValidateCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) error {
+	_, _, _, err := updateBridgeClientValidation(tx, statedb, pendingBridgeHeader(bc))
+	return err
+}
+
+func updateBridgeClientApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	record, client, consensus, err := updateBridgeClientValidation(tx, statedb, header)
+	if err != nil {
+		return err
+	}
+
+	consensusState, err := bridge.EncodeConsensusState(consensus)
+	if err != nil {
+		return err
+	}
+	// Two valid headers at the same height prove the external chain forked,
+	// its client is no longer trusted
+	if existing := record.ConsensusState(consensus.Height); existing != nil {
+		if !bytes.Equal(existing, consensusState) {
+			record.Frozen = true
+			statedb.SetBridgeClient(record)
+		}
+		return nil
+	}
+
+	clientState, err := client.Encode()
+	if err != nil {
+		return err
+	}
+	record.State = clientState
+	record.AddConsensusState(consensus.Height, consensusState, maxBridgeConsensusStates)
+	statedb.SetBridgeClient(record)
+
+	return nil
+}
+
+func updateBridgeClientValidation(tx *types.Transaction, statedb *state.StateDB, header *types.Header) (*state.BridgeClient, bridge.Client, *bridge.ConsensusState, error) {
+	var args neatabi.UpdateBridgeClientArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.UpdateBridgeClient.String(), data[4:]); err != nil {
+		return nil, nil, nil, err
+	}
+
+	record, client, err := loadBridgeClient(statedb, args.ClientId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	trustedHeight, err := client.TrustedHeight(args.Header)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	trusted, err := bridgeConsensusState(record, trustedHeight)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	updated, consensus, err := client.Update(args.Header, trusted, bridgeNow(header))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return record, updated, consensus, nil
+}
+
+// bridgeTransfer is a transfer of a packet to an account of neatio, or a
+// refund to its sender.
+type bridgeTransfer struct {
+	account common.Address
+	denom   string // NativeDenom for NEAT
+	amount  *big.Int
+}
+
+// recv bridge packet
+func recvBridgePacketValidateCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) error {
+	_, _, _, err := recvBridgePacketValidation(tx, statedb, pendingBridgeHeader(bc))
+	return err
+}
+
+func recvBridgePacketApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	record, packet, transfer, err := recvBridgePacketValidation(tx, statedb, header)
+	if err != nil {
+		return err
+	}
+
+	if transfer.denom == bridge.NativeDenom {
+		record.Escrowed.Sub(record.Escrowed, transfer.amount)
+		statedb.SetBridgeClient(record)
+		statedb.AddBalance(transfer.account, transfer.amount)
+	} else {
+		statedb.AddBridgeVoucher(transfer.denom, transfer.account, transfer.amount)
+	}
+	statedb.SetBridgeReceipt(record.Id, packet.Sequence)
+
+	return nil
+}
+
+func recvBridgePacketValidation(tx *types.Transaction, statedb *state.StateDB, header *types.Header) (*state.BridgeClient, *bridge.Packet, *bridgeTransfer, error) {
+	var args neatabi.RecvBridgePacketArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.RecvBridgePacket.String(), data[4:]); err != nil {
+		return nil, nil, nil, err
+	}
+
+	record, client, err := loadBridgeClient(statedb, args.ClientId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	packet, err := bridge.DecodePacket(args.Packet)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%v: %v", bridge.ErrInvalidPacket, err)
+	}
+	if packet.SourcePort != record.CounterpartyPort || packet.SourceChannel != record.CounterpartyChannel ||
+		packet.DestinationPort != record.Port || packet.DestinationChannel != record.Channel {
+		return nil, nil, nil, fmt.Errorf("%v: not on the channel of client %s", bridge.ErrInvalidPacket, record.Id)
+	}
+	if statedb.HasBridgeReceipt(record.Id, packet.Sequence) {
+		return nil, nil, nil, bridge.ErrPacketReceived
+	}
+	if packet.TimeoutHeight != 0 && header.Number.Uint64() >= packet.TimeoutHeight {
+		return nil, nil, nil, bridge.ErrPacketTimeout
+	}
+	if packet.TimeoutTimestamp != 0 && uint64(bridgeNow(header).UnixNano()) >= packet.TimeoutTimestamp {
+		return nil, nil, nil, bridge.ErrPacketTimeout
+	}
+
+	consensus, err := bridgeConsensusState(record, args.ProofHeight)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err := client.VerifyMembership(consensus.Root, args.Proof, packet.CommitmentPath(), packet.Commitment()); err != nil {
+		return nil, nil, nil, err
+	}
+
+	transferData, amount, err := bridge.DecodeTransferData(packet.Data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	receiver, err := neataddr.Parse(transferData.Receiver)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%v: receiver: %v", bridge.ErrInvalidTransfer, err)
+	}
+
+	transfer := &bridgeTransfer{account: receiver, amount: amount}
+	if denom, returning := bridge.ReturningDenom(packet.SourcePort, packet.SourceChannel, transferData.Denom); returning {
+		// Only NEAT leaves neatio escrowed, vouchers sent out are burned
+		if denom != bridge.NativeDenom {
+			return nil, nil, nil, fmt.Errorf("%v: returning denom %s", bridge.ErrInvalidTransfer, denom)
+		}
+		if record.Escrowed.Cmp(amount) < 0 {
+			return nil, nil, nil, bridge.ErrInsufficientEscrow
+		}
+		transfer.denom = bridge.NativeDenom
+	} else {
+		if err := checkBridgeAsset(statedb, transferData.Denom); err != nil {
+			return nil, nil, nil, err
+		}
+		transfer.denom = bridge.DenomPrefix(packet.DestinationPort, packet.DestinationChannel) + transferData.Denom
+	}
+
+	return record, packet, transfer, nil
+}
+
+// send bridge packet
+func sendBridgePacketValidateCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, _, err := sendBridgePacketValidation(from, tx, statedb)
+	return err
+}
+
+func sendBridgePacketApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, record, err := sendBridgePacketValidation(from, tx, statedb)
+	if err != nil {
+		return err
+	}
+
+	transferData := &bridge.TransferData{
+		Amount:   args.Amount.String(),
+		Denom:    args.Denom,
+		Receiver: args.Receiver,
+		Sender:   from.String(),
+	}
+	packet := &bridge.Packet{
+		Sequence:           record.NextSequence,
+		SourcePort:         record.Port,
+		SourceChannel:      record.Channel,
+		DestinationPort:    record.CounterpartyPort,
+		DestinationChannel: record.CounterpartyChannel,
+		Data:               transferData.Encode(),
+		TimeoutHeight:      args.TimeoutHeight,
+		TimeoutTimestamp:   args.TimeoutTimestamp,
+	}
+	encoded, err := bridge.EncodePacket(packet)
+	if err != nil {
+		return err
+	}
+	event := neatabi.ChainABI.Events[neatabi.BridgePacketSentEvent]
+	logData, err := event.Inputs.NonIndexed().Pack(record.Id, packet.Sequence, encoded)
+	if err != nil {
+		return err
+	}
+
+	if args.Denom == bridge.NativeDenom {
+		statedb.SubBalance(from, args.Amount)
+		record.Escrowed.Add(record.Escrowed, args.Amount)
+	} else {
+		statedb.SubBridgeVoucher(args.Denom, from, args.Amount)
+	}
+	statedb.SetBridgeCommitment(record.Id, packet.Sequence, packet.Commitment())
+	record.NextSequence++
+	statedb.SetBridgeClient(record)
+	statedb.AddLog(&types.Log{
+		Address:     neatabi.ChainContractMagicAddr,
+		Topics:      []common.Hash{event.ID(), common.BytesToHash(from[:])},
+		Data:        logData,
+		BlockNumber: header.Number.Uint64(),
+	})
+
+	return nil
+}
+
+func sendBridgePacketValidation(from common.Address, tx *types.Transaction, statedb *state.StateDB) (*neatabi.SendBridgePacketArgs, *state.BridgeClient, error) {
+	var args neatabi.SendBridgePacketArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SendBridgePacket.String(), data[4:]); err != nil {
+		return nil, nil, err
+	}
+
+	record, _, err := loadBridgeClient(statedb, args.ClientId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if args.Amount.Sign() <= 0 || args.Receiver == "" {
+		return nil, nil, fmt.Errorf("%v: amount %v to %q", bridge.ErrInvalidTransfer, args.Amount, args.Receiver)
+	}
+	if args.TimeoutHeight == 0 && args.TimeoutTimestamp == 0 {
+		return nil, nil, fmt.Errorf("%v: no timeout", bridge.ErrInvalidPacket)
+	}
+
+	if args.Denom == bridge.NativeDenom {
+		if tx.Value().Cmp(args.Amount) != 0 {
+			return nil, nil, fmt.Errorf("%v: value %v, want the amount %v", bridge.ErrInvalidTransfer, tx.Value(), args.Amount)
+		}
+		if statedb.GetBalance(from).Cmp(args.Amount) < 0 {
+			return nil, nil, core.ErrInsufficientFunds
+		}
+	} else {
+		// Only the vouchers of the channel return through it
+		if _, returning := bridge.ReturningDenom(record.Port, record.Channel, args.Denom); !returning {
+			return nil, nil, fmt.Errorf("%v: denom %s not from channel %s/%s", bridge.ErrInvalidTransfer, args.Denom, record.Port, record.Channel)
+		}
+		if tx.Value().Sign() != 0 {
+			return nil, nil, fmt.Errorf("%v: value sent with vouchers", bridge.ErrInvalidTransfer)
+		}
+		if err := checkBridgeAsset(statedb, args.Denom); err != nil {
+			return nil, nil, err
+		}
+		if statedb.GetBridgeVoucher(args.Denom, from).Cmp(args.Amount) < 0 {
+			return nil, nil, core.ErrInsufficientFunds
+		}
+	}
+
+	return &args, record, nil
+}
+
+// acknowledge bridge packet
+func ackBridgePacketValidateCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain) error {
+	_, _, _, err := ackBridgePacketValidation(tx, statedb)
+	return err
+}
+
+func ackBridgePacketApplyCb(tx *types.Transaction, statedb *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	record, packet, refund, err := ackBridgePacketValidation(tx, statedb)
+	if err != nil {
+		return err
+	}
+
+	if refund != nil {
+		if refund.denom == bridge.NativeDenom {
+			record.Escrowed.Sub(record.Escrowed, refund.amount)
+			statedb.SetBridgeClient(record)
+			statedb.AddBalance(refund.account, refund.amount)
+		} else {
+			statedb.AddBridgeVoucher(refund.denom, refund.account, refund.amount)
+		}
+	}
+	statedb.SetBridgeCommitment(record.Id, packet.Sequence, nil)
+
+	return nil
+}
+
+func ackBridgePacketValidation(tx *types.Transaction, statedb *state.StateDB) (*state.BridgeClient, *bridge.Packet, *bridgeTransfer, error) {
+	var args neatabi.AcknowledgeBridgePacketArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.AcknowledgeBridgePacket.String(), data[4:]); err != nil {
+		return nil, nil, nil, err
+	}
+
+	record, client, err := loadBridgeClient(statedb, args.ClientId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	packet, err := bridge.DecodePacket(args.Packet)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%v: %v", bridge.ErrInvalidPacket, err)
+	}
+	commitment := statedb.GetBridgeCommitment(record.Id, packet.Sequence)
+	if len(commitment) == 0 || packet.SourcePort != record.Port || packet.SourceChannel != record.Channel ||
+		!bytes.Equal(commitment, packet.Commitment()) {
+		return nil, nil, nil, fmt.Errorf("%v: no commitment to the packet", bridge.ErrInvalidPacket)
+	}
+
+	consensus, err := bridgeConsensusState(record, args.ProofHeight)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ackCommitment := bridge.AcknowledgementCommitment(args.Acknowledgement)
+	if err := client.VerifyMembership(consensus.Root, args.Proof, packet.AcknowledgementPath(), ackCommitment); err != nil {
+		return nil, nil, nil, err
+	}
+	failed, err := bridge.IsErrorAcknowledgement(args.Acknowledgement)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !failed {
+		return record, packet, nil, nil
+	}
+
+	// The transfer failed on the external chain, refund the sender
+	transferData, amount, err := bridge.DecodeTransferData(packet.Data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	sender, err := neataddr.Parse(transferData.Sender)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%v: sender: %v", bridge.ErrInvalidTransfer, err)
+	}
+	if transferData.Denom == bridge.NativeDenom && record.Escrowed.Cmp(amount) < 0 {
+		return nil, nil, nil, bridge.ErrInsufficientEscrow
+	}
+
+	return record, packet, &bridgeTransfer{account: sender, denom: transferData.Denom, amount: amount}, nil
+}
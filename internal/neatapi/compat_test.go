@@ -0,0 +1,399 @@
+package neatapi
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/neatlab/neatio/accounts"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/core/vm"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/neatdb"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rpc"
+)
+
+// walletMethods are the eth methods MetaMask, ethers.js and web3.js call
+// which are served by this package.
+var walletMethods = []string{
+	"eth_chainId", "eth_blockNumber", "eth_syncing", "eth_protocolVersion",
+	"eth_gasPrice", "eth_maxPriorityFeePerGas", "eth_feeHistory",
+	"eth_getBalance", "eth_getCode", "eth_getStorageAt", "eth_getTransactionCount",
+	"eth_call", "eth_estimateGas", "eth_sendRawTransaction", "eth_sendTransaction",
+	"eth_getBlockByNumber", "eth_getBlockByHash", "eth_getBlockReceipts",
+	"eth_getBlockTransactionCountByNumber", "eth_getBlockTransactionCountByHash",
+	"eth_getTransactionByHash", "eth_getTransactionReceipt",
+	"eth_accounts", "eth_sign", "eth_signTypedData_v4", "eth_signTransaction",
+}
+
+// compatBackend serves a chain of blocks, each with a transaction.
+type compatBackend struct {
+	Backend
+	config   *params.ChainConfig
+	db       neatdb.Database
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+}
+
+func newCompatBackend(t *testing.T, n int) *compatBackend {
+	key, _ := crypto.GenerateKey()
+	b := &compatBackend{config: params.TestChainConfig, db: rawdb.NewMemoryDatabase(), receipts: make(map[common.Hash]types.Receipts)}
+	signer := types.NewEIP155Signer(b.config.ChainId)
+	for i := 0; i < n; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{1}, big.NewInt(1), 21000, big.NewInt(params.GWei), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipt := types.NewReceipt(nil, false, 21000)
+		receipt.GasUsed = 21000
+		header := &types.Header{Number: big.NewInt(int64(i)), GasLimit: 84000, GasUsed: 21000}
+		block := types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt})
+		b.blocks = append(b.blocks, block)
+		rawdb.WriteTxLookupEntries(b.db, block)
+		b.receipts[block.Hash()] = types.Receipts{receipt}
+	}
+	return b
+}
+
+func (b *compatBackend) ChainConfig() *params.ChainConfig { return b.config }
+
+func (b *compatBackend) CurrentBlock() *types.Block { return b.blocks[len(b.blocks)-1] }
+
+func (b *compatBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(params.GWei), nil
+}
+
+func (b *compatBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	if blockNr < 0 {
+		return b.CurrentBlock(), nil
+	}
+	if int(blockNr) >= len(b.blocks) {
+		return nil, nil
+	}
+	return b.blocks[blockNr], nil
+}
+
+func (b *compatBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	block, _ := b.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, nil
+	}
+	return block.Header(), nil
+}
+
+func (b *compatBackend) ChainDb() neatdb.Database { return b.db }
+
+func (b *compatBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	for _, block := range b.blocks {
+		if block.Hash() == hash {
+			return block, nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *compatBackend) GetTd(blockHash common.Hash) *big.Int {
+	return big.NewInt(int64(len(b.blocks)))
+}
+
+func (b *compatBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	return b.receipts[blockHash], nil
+}
+
+func (b *compatBackend) AccountManager() *accounts.Manager { return nil }
+
+func newCompatClient(t *testing.T, b Backend) *rpc.Client {
+	server := rpc.NewServer()
+	for _, api := range GetAPIs(b, "") {
+		if api.Namespace == "eth" {
+			if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return rpc.DialInProc(server)
+}
+
+func TestWalletMethods(t *testing.T) {
+	client := newCompatClient(t, newCompatBackend(t, 1))
+	defer client.Close()
+
+	// Invalid arguments are rejected before the method runs, unknown
+	// methods are not found.
+	invalid := make([]interface{}, 8)
+	for _, method := range walletMethods {
+		var result interface{}
+		err := client.Call(&result, method, invalid...)
+		if err == nil || strings.Contains(err.Error(), "does not exist") {
+			t.Errorf("%s: have error %v, want invalid arguments", method, err)
+		}
+	}
+}
+
+func TestWalletFees(t *testing.T) {
+	b := newCompatBackend(t, 3)
+	client := newCompatClient(t, b)
+	defer client.Close()
+
+	var chainId hexutil.Big
+	if err := client.Call(&chainId, "eth_chainId"); err != nil {
+		t.Fatal(err)
+	}
+	if chainId.ToInt().Cmp(b.config.ChainId) != 0 {
+		t.Errorf("chain id mismatch: have %v, want %v", chainId.ToInt(), b.config.ChainId)
+	}
+
+	var tip hexutil.Big
+	if err := client.Call(&tip, "eth_maxPriorityFeePerGas"); err != nil {
+		t.Fatal(err)
+	}
+	if tip.ToInt().Int64() != params.GWei {
+		t.Errorf("priority fee mismatch: have %v, want %v", tip.ToInt(), params.GWei)
+	}
+
+	var history FeeHistoryResult
+	if err := client.Call(&history, "eth_feeHistory", "0x5", "latest", []float64{10, 90}); err != nil {
+		t.Fatal(err)
+	}
+	if history.OldestBlock.ToInt().Sign() != 0 || len(history.GasUsedRatio) != 3 || len(history.BaseFee) != 4 || len(history.Reward) != 3 {
+		t.Fatalf("fee history mismatch: %+v", history)
+	}
+	if history.GasUsedRatio[2] != 0.25 || history.Reward[2][1].ToInt().Int64() != params.GWei {
+		t.Errorf("fee history of the last block mismatch: ratio %v, reward %v", history.GasUsedRatio[2], history.Reward[2][1])
+	}
+	if err := client.Call(&history, "eth_feeHistory", "0x1", "latest", []float64{90, 10}); err == nil {
+		t.Error("decreasing reward percentiles accepted")
+	}
+}
+
+func TestWalletFeeHistoryRewards(t *testing.T) {
+	b := newCompatBackend(t, 1)
+	key, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(b.config.ChainId)
+
+	// Three transactions paying 1, 2 and 3 GWei and using 21000, 21000 and
+	// 42000 gas, added out of price order
+	var (
+		txs      []*types.Transaction
+		receipts []*types.Receipt
+	)
+	for i, tt := range []struct {
+		price   int64
+		gasUsed uint64
+	}{{3, 42000}, {1, 21000}, {2, 21000}} {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{1}, big.NewInt(1), tt.gasUsed, big.NewInt(tt.price*params.GWei), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		receipt := types.NewReceipt(nil, false, tt.gasUsed)
+		receipt.GasUsed = tt.gasUsed
+		txs, receipts = append(txs, tx), append(receipts, receipt)
+	}
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 84000, GasUsed: 84000}
+	block := types.NewBlock(header, txs, nil, receipts)
+	b.blocks = append(b.blocks, block)
+	b.receipts[block.Hash()] = receipts
+
+	client := newCompatClient(t, b)
+	defer client.Close()
+
+	var history FeeHistoryResult
+	if err := client.Call(&history, "eth_feeHistory", "0x1", "latest", []float64{0, 25, 30, 50, 75, 100}); err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{1, 1, 2, 2, 3, 3}
+	if len(history.Reward) != 1 || len(history.Reward[0]) != len(want) {
+		t.Fatalf("reward mismatch: %+v", history.Reward)
+	}
+	for i, price := range want {
+		if have := history.Reward[0][i].ToInt(); have.Int64() != price*params.GWei {
+			t.Errorf("reward %d mismatch: have %v, want %v", i, have, price*params.GWei)
+		}
+	}
+	if history.GasUsedRatio[0] != 1 {
+		t.Errorf("gas used ratio mismatch: have %v, want 1", history.GasUsedRatio[0])
+	}
+}
+
+// callBackend executes the calls on a state holding contracts.
+type callBackend struct {
+	*compatBackend
+	code map[common.Address][]byte
+}
+
+func (b *callBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(b.db))
+	if err != nil {
+		return nil, nil, err
+	}
+	for addr, code := range b.code {
+		statedb.SetCode(addr, code)
+	}
+	block, _ := b.BlockByNumber(ctx, blockNr)
+	return statedb, block.Header(), nil
+}
+
+func (b *callBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, overrides *BlockOverrides, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		Origin:      msg.From(),
+		Coinbase:    header.Coinbase,
+		BlockNumber: new(big.Int).Set(header.Number),
+		Time:        new(big.Int).SetUint64(1),
+		Difficulty:  new(big.Int),
+		GasLimit:    header.GasLimit,
+		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+	}
+	overrides.Apply(&context)
+	state.SetBalance(msg.From(), new(big.Int).Lsh(big.NewInt(1), 128))
+	return vm.NewEVM(context, state, b.config, vmCfg), func() error { return nil }, nil
+}
+
+func TestWalletCallBlockOverrides(t *testing.T) {
+	// Contracts returning the block number, time, gas limit and coinbase
+	ops := map[string]byte{"number": 0x43, "time": 0x42, "gasLimit": 0x45, "coinbase": 0x41}
+	b := &callBackend{compatBackend: newCompatBackend(t, 2), code: make(map[common.Address][]byte)}
+	contracts := make(map[string]common.Address)
+	for field, op := range ops {
+		addr := common.Address{0xc0, op}
+		b.code[addr] = []byte{op, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+		contracts[field] = addr
+	}
+	client := newCompatClient(t, b)
+	defer client.Close()
+
+	call := func(field string, overrides interface{}) *big.Int {
+		var result hexutil.Bytes
+		args := map[string]interface{}{"from": common.Address{1}, "to": contracts[field]}
+		var err error
+		if overrides == nil {
+			err = client.Call(&result, "eth_call", args, "latest")
+		} else {
+			err = client.Call(&result, "eth_call", args, "latest", overrides)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", field, err)
+		}
+		return new(big.Int).SetBytes(result)
+	}
+	coinbase := common.Address{0xcb}
+	overrides := map[string]interface{}{
+		"number":   "0x64",
+		"time":     "0x5f5e100",
+		"gasLimit": "0x1312d00",
+		"coinbase": coinbase,
+		"baseFee":  "0x3b9aca00",
+	}
+	want := map[string]*big.Int{
+		"number":   big.NewInt(100),
+		"time":     big.NewInt(100000000),
+		"gasLimit": big.NewInt(20000000),
+		"coinbase": new(big.Int).SetBytes(coinbase.Bytes()),
+	}
+	latest := map[string]*big.Int{
+		"number":   big.NewInt(1),
+		"time":     big.NewInt(1),
+		"gasLimit": big.NewInt(84000),
+		"coinbase": new(big.Int),
+	}
+	for field := range ops {
+		if have := call(field, nil); have.Cmp(latest[field]) != 0 {
+			t.Errorf("%s without overrides mismatch: have %v, want %v", field, have, latest[field])
+		}
+		if have := call(field, overrides); have.Cmp(want[field]) != 0 {
+			t.Errorf("%s overridden mismatch: have %v, want %v", field, have, want[field])
+		}
+	}
+	// Only the fields set are overridden
+	if have := call("time", map[string]interface{}{"number": "0x64"}); have.Cmp(latest["time"]) != 0 {
+		t.Errorf("time not overridden mismatch: have %v, want %v", have, latest["time"])
+	}
+}
+
+// The overridden number selects the rules of the EVM: a call past the fork
+// block runs the opcodes of the fork.
+func TestWalletCallBlockOverridesFork(t *testing.T) {
+	b := &callBackend{compatBackend: newCompatBackend(t, 2), code: make(map[common.Address][]byte)}
+	config := *params.TestChainConfig
+	config.ConstantinopleBlock = big.NewInt(100)
+	b.config = &config
+
+	// Contract returning 1 << 4, shifts being added by Constantinople
+	shl := common.Address{0xc0, 0x1b}
+	b.code[shl] = []byte{0x60, 0x01, 0x60, 0x04, 0x1b, 0x60, 0x00, 0x52, 0x60, 0x20, 0x60, 0x00, 0xf3}
+	client := newCompatClient(t, b)
+	defer client.Close()
+
+	for _, tt := range []struct {
+		number string
+		want   int64
+	}{{"0x63", 0}, {"0x64", 16}} {
+		var result hexutil.Bytes
+		args := map[string]interface{}{"from": common.Address{1}, "to": shl}
+		if err := client.Call(&result, "eth_call", args, "latest", map[string]interface{}{"number": tt.number}); err != nil {
+			t.Fatalf("number %s: %v", tt.number, err)
+		}
+		if have := new(big.Int).SetBytes(result); have.Int64() != tt.want {
+			t.Errorf("number %s: result mismatch: have %v, want %v", tt.number, have, tt.want)
+		}
+	}
+}
+
+func TestWalletBlockReceipts(t *testing.T) {
+	b := newCompatBackend(t, 2)
+	client := newCompatClient(t, b)
+	defer client.Close()
+
+	var receipts []map[string]interface{}
+	if err := client.Call(&receipts, "eth_getBlockReceipts", "0x1"); err != nil {
+		t.Fatal(err)
+	}
+	tx := b.blocks[1].Transactions()[0]
+	if len(receipts) != 1 {
+		t.Fatalf("receipt count mismatch: have %d, want 1", len(receipts))
+	}
+	receipt := receipts[0]
+	if receipt["transactionHash"] != tx.Hash().Hex() || receipt["blockNumber"] != "0x1" || receipt["status"] != "0x1" {
+		t.Errorf("receipt mismatch: %v", receipt)
+	}
+	if receipt["effectiveGasPrice"] != hexutil.EncodeBig(tx.GasPrice()) {
+		t.Errorf("effective gas price mismatch: have %v, want %v", receipt["effectiveGasPrice"], hexutil.EncodeBig(tx.GasPrice()))
+	}
+}
+
+func TestWalletTransactionReceipt(t *testing.T) {
+	b := newCompatBackend(t, 3)
+	client := newCompatClient(t, b)
+	defer client.Close()
+
+	for number, block := range b.blocks {
+		tx := block.Transactions()[0]
+		var receipt map[string]interface{}
+		if err := client.Call(&receipt, "eth_getTransactionReceipt", tx.Hash()); err != nil {
+			t.Fatal(err)
+		}
+		if receipt["transactionHash"] != tx.Hash().Hex() || receipt["blockHash"] != block.Hash().Hex() || receipt["blockNumber"] != hexutil.EncodeUint64(uint64(number)) {
+			t.Errorf("block %d: receipt mismatch: %v", number, receipt)
+		}
+		var rpcTx map[string]interface{}
+		if err := client.Call(&rpcTx, "eth_getTransactionByHash", tx.Hash()); err != nil {
+			t.Fatal(err)
+		}
+		if rpcTx["hash"] != tx.Hash().Hex() || rpcTx["blockHash"] != block.Hash().Hex() || rpcTx["from"] != receipt["from"] {
+			t.Errorf("block %d: transaction mismatch: %v", number, rpcTx)
+		}
+	}
+	var receipt map[string]interface{}
+	if err := client.Call(&receipt, "eth_getTransactionReceipt", common.Hash{1}); err != nil || receipt != nil {
+		t.Errorf("unknown transaction: have %v, %v, want no receipt", receipt, err)
+	}
+}
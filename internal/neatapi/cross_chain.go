@@ -0,0 +1,162 @@
+package neatapi
+
+import (
+	"fmt"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/log"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/rlp"
+	goCrypto "github.com/neatlib/crypto-go"
+)
+
+func init() {
+	// Join side chain
+	core.RegisterValidateCb(neatabi.JoinSideChain, joinSideChainValidateCb)
+	core.RegisterApplyCb(neatabi.JoinSideChain, joinSideChainApplyCb)
+
+	// Withdraw from main chain
+	core.RegisterValidateCb(neatabi.WithdrawFromMainChain, withdrawFromMainChainValidateCb)
+	core.RegisterApplyCb(neatabi.WithdrawFromMainChain, withdrawFromMainChainApplyCb)
+
+	// Challenge a withdrawal from side chain
+	core.RegisterValidateCb(neatabi.ChallengeWithdrawal, challengeWithdrawalValidateCb)
+	core.RegisterApplyCb(neatabi.ChallengeWithdrawal, challengeWithdrawalApplyCb)
+}
+
+func joinSideChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, err := joinSideChainValidation(from, tx, state, cch)
+	return err
+}
+
+// joinSideChainApplyCb locks the deposit of a validator joining a side chain
+// waiting for launch. The side chains joined are recorded in the state, which
+// limits the side chains a validator secures as set by governance. It runs
+// from the side chain capacity fork block of the chain config.
+func joinSideChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, header *types.Header, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	args, err := joinSideChainValidation(from, tx, state, cch)
+	if err != nil {
+		return err
+	}
+
+	var pubkey goCrypto.BLSPubKey
+	copy(pubkey[:], args.PubKey)
+	op := types.JoinSideChainOp{
+		From:          from,
+		PubKey:        pubkey,
+		ChainId:       args.ChainId,
+		DepositAmount: tx.Value(),
+	}
+	if ok := ops.Append(&op); !ok {
+		return fmt.Errorf("pending ops conflict: %v", op)
+	}
+
+	state.SubBalance(from, tx.Value())
+	state.AddSideChainDepositBalance(from, args.ChainId, tx.Value())
+	state.AddValidatorSideChain(from, args.ChainId)
+	return nil
+}
+
+func joinSideChainValidation(from common.Address, tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) (*neatabi.JoinSideChainArgs, error) {
+	var args neatabi.JoinSideChainArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.JoinSideChain.String(), data[4:]); err != nil {
+		return nil, err
+	}
+	if err := core.CheckSideChainCapacity(state.GetValidatorSideChains(from), state.MaxSideChains(), args.ChainId); err != nil {
+		return nil, err
+	}
+	if err := cch.ValidateJoinSideChain(from, args.PubKey, args.ChainId, tx.Value(), args.Signature); err != nil {
+		return nil, err
+	}
+	return &args, nil
+}
+
+// withdrawFromMainChainValidateCb rejects the TX4s redeeming a TX3 which was
+// already withdrawn. The proof of the TX3 itself is checked with the block
+// carrying the TX4, it may not be available to the pool yet.
+func withdrawFromMainChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, err := withdrawFromMainChainValidation(from, tx, state)
+	return err
+}
+
+// withdrawFromMainChainApplyCb records the TX3 redeemed by a TX4 in the TX3
+// trie of the sender. The trie is part of the state, so a TX3 stays consumed
+// across restarts and when the chain is imported again. It runs from the TX3
+// replay fork block of the chain config.
+func withdrawFromMainChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, header *types.Header, mining bool) error {
+	from := derivedAddressFromTx(tx)
+
+	args, err := withdrawFromMainChainValidation(from, tx, state)
+	if err != nil {
+		if mining && err == core.ErrTX3Consumed {
+			// Drop the TX4 from the pool, it can never be included
+			log.Debug("Dropping TX4 of a withdrawn TX3", "hash", tx.Hash(), "tx3", args.TxHash)
+			return core.ErrInvalidTx4
+		}
+		return err
+	}
+	state.AddTX3(from, args.TxHash)
+	return nil
+}
+
+func withdrawFromMainChainValidation(from common.Address, tx *types.Transaction, state *state.StateDB) (*neatabi.WithdrawFromMainChainArgs, error) {
+	var args neatabi.WithdrawFromMainChainArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.WithdrawFromMainChain.String(), data[4:]); err != nil {
+		return nil, err
+	}
+	if state.HasTX3(from, args.TxHash) {
+		return &args, core.ErrTX3Consumed
+	}
+	return &args, nil
+}
+
+func challengeWithdrawalValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	_, err := challengeWithdrawalValidation(tx, state, cch)
+	return err
+}
+
+// challengeWithdrawalApplyCb cancels the withdrawal proven fraudulent. The TX3
+// stays consumed, it can't be redeemed again.
+func challengeWithdrawalApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, header *types.Header, mining bool) error {
+	args, err := challengeWithdrawalValidation(tx, state, cch)
+	if err != nil {
+		return err
+	}
+	withdrawal := state.CancelPendingWithdrawal(args.TxHash)
+	log.Info("Cancelled withdrawal from side chain on fraud proof", "chain", withdrawal.ChainId, "tx3", withdrawal.TxHash, "amount", withdrawal.Amount, "challenger", derivedAddressFromTx(tx))
+	return nil
+}
+
+func challengeWithdrawalValidation(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) (*neatabi.ChallengeWithdrawalArgs, error) {
+	var args neatabi.ChallengeWithdrawalArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.ChallengeWithdrawal.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	withdrawal := state.GetPendingWithdrawal(args.TxHash)
+	if withdrawal == nil {
+		return nil, core.ErrNoPendingWithdrawal
+	}
+
+	var proofData types.TX3ProofData
+	if err := rlp.DecodeBytes(args.Proof, &proofData); err != nil {
+		return nil, err
+	}
+	var header types.Header
+	if err := rlp.DecodeBytes(args.Header, &header); err != nil {
+		return nil, err
+	}
+	if err := cch.ValidateWithdrawalChallenge(withdrawal.ChainId, args.TxHash, &proofData, &header); err != nil {
+		return nil, err
+	}
+	return &args, nil
+}
@@ -0,0 +1,177 @@
+package neatapi
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/core/vm"
+	"github.com/neatlab/neatio/crypto"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+)
+
+func TestWithdrawFromMainChainReplay(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx3Hash := common.HexToHash("0x33") // leading zero bytes are not stored
+	input, err := neatabi.ChainABI.Pack(neatabi.WithdrawFromMainChain.String(), "side", big.NewInt(100), tx3Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	sign := func(nonce uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, neatabi.ChainContractMagicAddr, nil, 0, big.NewInt(0), input), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+
+	diskdb := rawdb.NewMemoryDatabase()
+	db := state.NewDatabase(diskdb)
+	statedb, _ := state.New(common.Hash{}, db)
+	if err := withdrawFromMainChainValidateCb(sign(0), statedb, nil); err != nil {
+		t.Fatalf("TX4 of an unused TX3 rejected: %v", err)
+	}
+	if err := withdrawFromMainChainApplyCb(sign(0), statedb, nil, nil, nil, false); err != nil {
+		t.Fatalf("failed to apply TX4: %v", err)
+	}
+	statedb.SetNonce(crypto.PubkeyToAddress(key.PublicKey), 1)
+
+	// The same TX3 can't be redeemed again, in the same block or later ones
+	if err := withdrawFromMainChainApplyCb(sign(1), statedb, nil, nil, nil, false); err != core.ErrTX3Consumed {
+		t.Errorf("TX3 redeemed twice in a block: %v", err)
+	}
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, state.NewDatabase(diskdb))
+	if err := withdrawFromMainChainValidateCb(sign(1), statedb, nil); err != core.ErrTX3Consumed {
+		t.Errorf("TX3 redeemed again after reload: %v", err)
+	}
+	if err := withdrawFromMainChainApplyCb(sign(1), statedb, nil, nil, nil, true); err != core.ErrInvalidTx4 {
+		t.Errorf("miner kept the TX4 of a withdrawn TX3: %v", err)
+	}
+}
+
+// mutexCrossChainHelper serves the lock the cross chain callbacks run under.
+type mutexCrossChainHelper struct {
+	core.CrossChainHelper
+	mu sync.Mutex
+}
+
+func (h *mutexCrossChainHelper) GetMutex() *sync.Mutex {
+	return &h.mu
+}
+
+// The TX3 trie is only written by the TX4s of the blocks from the TX3 replay
+// fork, so the blocks before it find no entry in it, whatever the hash.
+func TestWithdrawFromMainChainBeforeTX3Replay(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	tx3Hash := common.HexToHash("0x33")
+	input, err := neatabi.ChainABI.Pack(neatabi.WithdrawFromMainChain.String(), "side", big.NewInt(100), tx3Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &params.ChainConfig{
+		NeatChainId:    params.MainnetChainConfig.NeatChainId,
+		ChainId:        big.NewInt(1),
+		EIP155Block:    big.NewInt(0),
+		TX3ReplayBlock: big.NewInt(100),
+	}
+	signer := types.NewEIP155Signer(config.ChainId)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	cch := new(mutexCrossChainHelper)
+	for nonce, number := range []int64{98, 99, 100} {
+		tx, err := types.SignTx(types.NewTransaction(uint64(nonce), neatabi.ChainContractMagicAddr, nil, 0, big.NewInt(0), input), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		header := &types.Header{Number: big.NewInt(number)}
+		if _, _, err := core.ApplyTransactionEx(config, nil, nil, new(core.GasPool).AddGas(1000000), statedb, new(types.PendingOps), header, tx, new(uint64), new(big.Int), vm.Config{}, cch, false); err != nil {
+			t.Fatalf("block %d: failed to apply TX4: %v", number, err)
+		}
+		if have, want := statedb.HasTX3(from, tx3Hash), number >= 100; have != want {
+			t.Errorf("block %d: TX3 recorded mismatch: have %v, want %v", number, have, want)
+		}
+	}
+	if err := statedb.Error(); err != nil {
+		t.Errorf("state error: %v", err)
+	}
+}
+
+// joinCrossChainHelper accepts every join of a side chain, leaving the limit
+// of side chains to the state.
+type joinCrossChainHelper struct {
+	core.CrossChainHelper
+}
+
+func (joinCrossChainHelper) ValidateJoinSideChain(from common.Address, pubkey []byte, chainId string, depositAmount *big.Int, signature []byte) error {
+	return nil
+}
+
+func TestJoinSideChainCapacity(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	join := func(chainId string) *types.Transaction {
+		input, err := neatabi.ChainABI.Pack(neatabi.JoinSideChain.String(), make([]byte, 128), chainId, []byte{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx, err := types.SignTx(types.NewTransaction(0, neatabi.ChainContractMagicAddr, big.NewInt(10), 0, big.NewInt(0), input), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.AddBalance(from, big.NewInt(100))
+	statedb.SetGovParam(state.GovParamMaxSideChains, big.NewInt(1), 0, 0)
+	cch := joinCrossChainHelper{}
+
+	if err := joinSideChainApplyCb(join("side_0"), statedb, new(types.PendingOps), cch, nil, false); err != nil {
+		t.Fatalf("failed to join a side chain: %v", err)
+	}
+	if chains := statedb.GetValidatorSideChains(from); len(chains) != 1 || chains[0] != "side_0" {
+		t.Fatalf("side chains mismatch: have %v, want [side_0]", chains)
+	}
+	if deposit := statedb.GetSideChainDepositBalance("side_0", from); deposit.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("deposit mismatch: have %v, want 10", deposit)
+	}
+
+	// The limit is enforced when applying the join, and the join reverted
+	snapshot := statedb.Snapshot()
+	if err := joinSideChainValidateCb(join("side_1"), statedb, cch); err != core.ErrSideChainCapacity {
+		t.Errorf("join over the limit accepted in the pool: %v", err)
+	}
+	if err := joinSideChainApplyCb(join("side_1"), statedb, new(types.PendingOps), cch, nil, false); err != core.ErrSideChainCapacity {
+		t.Errorf("join over the limit applied: %v", err)
+	}
+	statedb.SetGovParam(state.GovParamMaxSideChains, big.NewInt(2), 0, 0)
+	if err := joinSideChainApplyCb(join("side_1"), statedb, new(types.PendingOps), cch, nil, false); err != nil {
+		t.Fatalf("failed to join a second side chain: %v", err)
+	}
+	statedb.RevertToSnapshot(snapshot)
+	if chains := statedb.GetValidatorSideChains(from); len(chains) != 1 {
+		t.Errorf("reverted join kept: %v", chains)
+	}
+
+	// A retired side chain frees its place
+	statedb.SetGovParam(state.RetireParam("side_0"), big.NewInt(1000), 0, 0)
+	if err := joinSideChainApplyCb(join("side_1"), statedb, new(types.PendingOps), cch, nil, false); err != nil {
+		t.Errorf("side chain of a retired one not joined: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package neatapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/core/vm"
+	"github.com/neatlab/neatio/log"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/rpc"
+	cmn "github.com/neatlib/common-go"
+)
+
+var (
+	errEthCheckpointTrusted = errors.New("ethereum checkpoint already trusted")
+	errEthCheckpointVoted   = errors.New("ethereum checkpoint already submitted by the validator")
+)
+
+// SubmitEthCheckpoint submits the finalized Ethereum block of hash at number
+// as a checkpoint of the Ethereum bridge. The checkpoint is trusted once the
+// validators with more than 2/3 of the voting power submitted it.
+func (api *PrivateNeatApi) SubmitEthCheckpoint(ctx context.Context, from common.Address, hash common.Hash, number hexutil.Uint64, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendBridgeTx(ctx, from, neatabi.SubmitEthCheckpoint, nil, gasPrice, hash, uint64(number))
+}
+
+// GetEthCheckpoint returns the number of the Ethereum block of hash if it is a
+// trusted checkpoint of the Ethereum bridge, nil otherwise.
+func (api *PublicNeatApi) GetEthCheckpoint(ctx context.Context, hash common.Hash, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
+	statedb, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	number, ok := vm.EthCheckpoint(statedb, hash)
+	if !ok {
+		return nil, nil
+	}
+	return (*hexutil.Uint64)(&number), nil
+}
+
+func init() {
+	core.RegisterValidateCb(neatabi.SubmitEthCheckpoint, submitEthCheckpointValidateCb)
+	core.RegisterApplyCb(neatabi.SubmitEthCheckpoint, submitEthCheckpointApplyCb)
+}
+
+func submitEthCheckpointValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := submitEthCheckpointValidation(from, tx, state, bc)
+	return err
+}
+
+// submitEthCheckpointApplyCb records the submission, and trusts the checkpoint
+// when the voting power of its submitters reaches 2/3.
+func submitEthCheckpointApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, header *types.Header, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := submitEthCheckpointValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+	vm.VoteEthCheckpoint(state, args.Hash, args.Number, from)
+
+	ep, err := getEpoch(bc)
+	if err != nil {
+		return err
+	}
+	validators := ep.Validators.Validators
+	voted := cmn.NewBitArray(uint64(len(validators)))
+	for i, v := range validators {
+		voted.SetIndex(uint64(i), vm.HasVotedEthCheckpoint(state, args.Hash, args.Number, common.BytesToAddress(v.Address)))
+	}
+	_, votes, total, err := ep.Validators.TalliedVotingPower(voted)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).Mul(votes, big.NewInt(3)).Cmp(new(big.Int).Mul(total, big.NewInt(2))) > 0 {
+		vm.SetEthCheckpoint(state, args.Hash, args.Number)
+		log.Info("Ethereum checkpoint trusted", "hash", args.Hash, "number", args.Number)
+	}
+	return nil
+}
+
+func submitEthCheckpointValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatabi.SubmitEthCheckpointArgs, error) {
+	var args neatabi.SubmitEthCheckpointArgs
+	data := tx.Data()
+	if err := neatabi.ChainABI.UnpackMethodInputs(&args, neatabi.SubmitEthCheckpoint.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	ep, err := getEpoch(bc)
+	if err != nil {
+		return nil, err
+	}
+	if !ep.Validators.HasAddress(from.Bytes()) {
+		return nil, core.ErrNotValidator
+	}
+	if _, ok := vm.EthCheckpoint(state, args.Hash); ok {
+		return nil, errEthCheckpointTrusted
+	}
+	if vm.HasVotedEthCheckpoint(state, args.Hash, args.Number, from) {
+		return nil, errEthCheckpointVoted
+	}
+	return &args, nil
+}
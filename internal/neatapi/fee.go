@@ -0,0 +1,148 @@
+package neatapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/common/math"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/rpc"
+)
+
+const (
+	maxFeeHistory            = 1024 // Maximum number of blocks of a fee history
+	maxFeeHistoryPercentiles = 100  // Maximum number of reward percentiles of a fee history
+)
+
+var errInvalidPercentile = errors.New("invalid reward percentile")
+
+// FeeHistoryResult is the fee history of a range of blocks, in the format of
+// the eth_feeHistory of the wallets.
+type FeeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+}
+
+// MaxPriorityFeePerGas returns a suggestion for the priority fee of a
+// transaction. The blocks have no base fee, the whole gas price goes to the
+// validators.
+func (s *PublicNeatioAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, error) {
+	price, err := s.b.SuggestPrice(ctx)
+	return (*hexutil.Big)(price), err
+}
+
+// FeeHistory returns the fee history of the blockCount blocks up to lastBlock.
+// The blocks have no base fee, so the rewards are the gas prices paid by the
+// transactions of the block, at the given percentiles of its gas used.
+func (s *PublicNeatioAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*FeeHistoryResult, error) {
+	if len(rewardPercentiles) > maxFeeHistoryPercentiles {
+		return nil, fmt.Errorf("%v: more than %d percentiles", errInvalidPercentile, maxFeeHistoryPercentiles)
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 || (i > 0 && p < rewardPercentiles[i-1]) {
+			return nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+	}
+	if blockCount == 0 {
+		return &FeeHistoryResult{OldestBlock: (*hexutil.Big)(new(big.Int)), GasUsedRatio: []float64{}}, nil
+	}
+	if blockCount > maxFeeHistory {
+		blockCount = maxFeeHistory
+	}
+	last, err := s.b.HeaderByNumber(ctx, lastBlock)
+	if err != nil {
+		return nil, err
+	}
+	if last == nil {
+		return nil, fmt.Errorf("block %d not found", lastBlock)
+	}
+	count := uint64(blockCount)
+	if number := last.Number.Uint64(); count > number+1 {
+		count = number + 1
+	}
+	oldest := last.Number.Uint64() + 1 - count
+
+	result := &FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(new(big.Int).SetUint64(oldest)),
+		BaseFee:      make([]*hexutil.Big, count+1),
+		GasUsedRatio: make([]float64, count),
+	}
+	for i := range result.BaseFee {
+		result.BaseFee[i] = (*hexutil.Big)(new(big.Int))
+	}
+	for i := uint64(0); i < count; i++ {
+		header := last
+		if oldest+i != last.Number.Uint64() {
+			if header, err = s.b.HeaderByNumber(ctx, rpc.BlockNumber(oldest+i)); err != nil {
+				return nil, err
+			}
+			if header == nil {
+				return nil, fmt.Errorf("block %d not found", oldest+i)
+			}
+		}
+		if header.GasLimit > 0 {
+			result.GasUsedRatio[i] = float64(header.GasUsed) / float64(header.GasLimit)
+		}
+		if len(rewardPercentiles) > 0 {
+			reward, err := s.blockRewards(ctx, header, rewardPercentiles)
+			if err != nil {
+				return nil, err
+			}
+			result.Reward = append(result.Reward, reward)
+		}
+	}
+	return result, nil
+}
+
+// blockRewards returns the gas prices paid at the percentiles of the gas used
+// by the block, the transactions being weighted by the gas they used. They
+// are all zero for an empty block.
+func (s *PublicNeatioAPI) blockRewards(ctx context.Context, header *types.Header, percentiles []float64) ([]*hexutil.Big, error) {
+	reward := make([]*hexutil.Big, len(percentiles))
+	for i := range reward {
+		reward[i] = (*hexutil.Big)(new(big.Int))
+	}
+	block, err := s.b.GetBlock(ctx, header.Hash())
+	if block == nil || err != nil || len(block.Transactions()) == 0 {
+		return reward, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("receipts of block %d not found", header.Number)
+	}
+
+	type txGas struct {
+		price   *big.Int
+		gasUsed uint64
+	}
+	txs := make([]txGas, len(receipts))
+	for i, tx := range block.Transactions() {
+		txs[i] = txGas{price: tx.GasPrice(), gasUsed: receipts[i].GasUsed}
+	}
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].price.Cmp(txs[j].price) < 0 })
+
+	var total uint64
+	for _, tx := range txs {
+		total += tx.gasUsed
+	}
+	var index int
+	sumGasUsed := txs[0].gasUsed
+	for i, p := range percentiles {
+		threshold := uint64(float64(total) * p / 100)
+		for sumGasUsed < threshold && index < len(txs)-1 {
+			index++
+			sumGasUsed += txs[index].gasUsed
+		}
+		reward[i] = (*hexutil.Big)(txs[index].price)
+	}
+	return reward, nil
+}
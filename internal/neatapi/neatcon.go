@@ -0,0 +1,184 @@
+package neatapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/consensus"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/rpc"
+)
+
+// RPCNeatconExtra is the consensus payload carried in the extra data of the
+// header of a block, decoded.
+type RPCNeatconExtra struct {
+	ChainID         string            `json:"chainId"`
+	Height          hexutil.Uint64    `json:"height"`
+	Time            time.Time         `json:"time"`
+	NeedToSave      bool              `json:"needToSave"`
+	NeedToBroadcast bool              `json:"needToBroadcast"`
+	EpochNumber     hexutil.Uint64    `json:"epochNumber"`
+	Proposer        string            `json:"proposer"`
+	ValidatorsHash  hexutil.Bytes     `json:"validatorsHash"`
+	SeenCommitHash  hexutil.Bytes     `json:"seenCommitHash"`
+	SeenCommit      *RPCCommitSummary `json:"seenCommit"`
+	EpochBytes      hexutil.Bytes     `json:"epochBytes,omitempty"`
+}
+
+// RPCCommitSummary sums up the commit of a block: the block the validators
+// signed, and how many of them did.
+type RPCCommitSummary struct {
+	BlockHash  hexutil.Bytes  `json:"blockHash"`
+	Height     hexutil.Uint64 `json:"height"`
+	Round      int            `json:"round"`
+	Signers    int            `json:"signers"`
+	Validators int            `json:"validators"`
+}
+
+func newRPCNeatconExtra(header *types.Header) (*RPCNeatconExtra, error) {
+	extra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid extra data of block %d: %v", header.Number, err)
+	}
+	result := &RPCNeatconExtra{
+		ChainID:         extra.ChainID,
+		Height:          hexutil.Uint64(extra.Height),
+		Time:            extra.Time,
+		NeedToSave:      extra.NeedToSave,
+		NeedToBroadcast: extra.NeedToBroadcast,
+		EpochNumber:     hexutil.Uint64(extra.EpochNumber),
+		Proposer:        header.Coinbase.String(),
+		ValidatorsHash:  extra.ValidatorsHash,
+		SeenCommitHash:  extra.SeenCommitHash,
+		EpochBytes:      extra.EpochBytes,
+	}
+	if extra.SeenCommit != nil {
+		result.SeenCommit = newRPCCommitSummary(extra.SeenCommit)
+	}
+	return result, nil
+}
+
+func newRPCCommitSummary(commit *ncTypes.Commit) *RPCCommitSummary {
+	summary := &RPCCommitSummary{
+		BlockHash: commit.BlockID.Hash,
+		Height:    hexutil.Uint64(commit.Height),
+		Round:     commit.Round,
+	}
+	if commit.BitArray != nil {
+		summary.Signers = commit.NumCommits()
+		summary.Validators = int(commit.BitArray.Size())
+	}
+	return summary
+}
+
+// GetBlockWithExtra returns the requested block, as GetBlockByNumber does,
+// with the consensus payload of its header decoded in the neatconExtra field.
+func (s *PublicBlockChainAPI) GetBlockWithExtra(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	if blockNr == rpc.PendingBlockNumber {
+		return nil, fmt.Errorf("pending block has no consensus payload")
+	}
+	block, err := s.b.BlockByNumber(ctx, blockNr)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	response, err := s.rpcOutputBlock(block, true, fullTx)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := newRPCNeatconExtra(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	response["neatconExtra"] = extra
+	return response, nil
+}
+
+// Finality statuses of a transaction.
+const (
+	TxPending   = "pending"   // In the transaction pool
+	TxIncluded  = "included"  // In a block without a commit of the validators
+	TxFinalized = "finalized" // In a block committed by more than two thirds of the validators
+)
+
+// RPCTransactionFinality tells whether a transaction is final, and the block
+// and commit it is final with.
+type RPCTransactionFinality struct {
+	Status           string            `json:"status"`
+	BlockHash        *common.Hash      `json:"blockHash,omitempty"`
+	BlockNumber      *hexutil.Uint64   `json:"blockNumber,omitempty"`
+	TransactionIndex *hexutil.Uint64   `json:"transactionIndex,omitempty"`
+	Commit           *RPCCommitSummary `json:"commit,omitempty"`
+}
+
+// hasCommit reports whether the header carries its own commit, signed by the
+// quorum of the voting power of valSet, the validators of the block, as the
+// commits are verified on import. Without the validators, the commit is not
+// counted.
+func hasCommit(header *types.Header, valSet *ncTypes.ValidatorSet) (*ncTypes.Commit, bool) {
+	extra, err := ncTypes.ExtractNeatconExtra(header)
+	if err != nil || extra.SeenCommit == nil || extra.SeenCommit.BitArray == nil {
+		return nil, false
+	}
+	commit := extra.SeenCommit
+	if commit.Height != header.Number.Uint64() || valSet == nil {
+		return commit, false
+	}
+	_, votes, total, err := valSet.TalliedVotingPower(commit.BitArray)
+	if err != nil {
+		return commit, false
+	}
+	return commit, votes.Cmp(ncTypes.Loose23MajorThreshold(total, commit.Round)) >= 0
+}
+
+// blockValidators returns the validators of the block at number, nil if its
+// epoch is not known.
+func blockValidators(engine consensus.Engine, number uint64) *ncTypes.ValidatorSet {
+	neatpos, ok := engine.(consensus.NeatPoS)
+	if !ok || neatpos.GetEpoch() == nil {
+		return nil
+	}
+	if ep := neatpos.GetEpoch().GetEpochByBlockNumber(number); ep != nil {
+		return ep.Validators
+	}
+	return nil
+}
+
+// GetTransactionFinality returns whether the transaction is pending, included
+// in a block, or final. The blocks of NeatPoS are final as soon as they have a
+// valid commit, so a transaction can be relied upon without waiting for any
+// confirmation. Unknown transactions return nil.
+func (s *PublicTransactionPoolAPI) GetTransactionFinality(ctx context.Context, hash common.Hash) (*RPCTransactionFinality, error) {
+	tx, _, block, index, err := s.receipts.transaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		if s.b.GetPoolTransaction(hash) != nil {
+			return &RPCTransactionFinality{Status: TxPending}, nil
+		}
+		return nil, nil
+	}
+
+	blockHash, number, txIndex := block.Hash(), hexutil.Uint64(block.NumberU64()), hexutil.Uint64(index)
+	result := &RPCTransactionFinality{
+		Status:           TxIncluded,
+		BlockHash:        &blockHash,
+		BlockNumber:      &number,
+		TransactionIndex: &txIndex,
+	}
+	if canonical, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(number)); err != nil || canonical == nil || canonical.Hash() != blockHash {
+		return result, err
+	}
+	commit, final := hasCommit(block.Header(), blockValidators(s.b.Engine(), block.NumberU64()))
+	if commit != nil {
+		result.Commit = newRPCCommitSummary(commit)
+	}
+	if final {
+		result.Status = TxFinalized
+	}
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package neatapi
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core/types"
+	. "github.com/neatlib/common-go"
+	"github.com/neatlib/wire-go"
+)
+
+func TestRPCNeatconExtra(t *testing.T) {
+	bitArray := NewBitArray(4)
+	bitArray.SetIndex(0, true)
+	bitArray.SetIndex(2, true)
+	extra := &ncTypes.NeatconExtra{
+		ChainID:        "neatio",
+		Height:         12,
+		Time:           time.Unix(1600000000, 0).UTC(),
+		EpochNumber:    3,
+		ValidatorsHash: []byte{0x01, 0x02},
+		SeenCommit: &ncTypes.Commit{
+			BlockID:  ncTypes.BlockID{Hash: []byte{0xaa, 0xbb}},
+			Height:   12,
+			Round:    1,
+			BitArray: bitArray,
+		},
+	}
+	header := &types.Header{Number: big.NewInt(12), Coinbase: common.Address{0x11}, Extra: wire.BinaryBytes(*extra)}
+
+	result, err := newRPCNeatconExtra(header)
+	if err != nil {
+		t.Fatalf("failed to decode extra data: %v", err)
+	}
+	if result.ChainID != "neatio" || result.Height != 12 || result.EpochNumber != 3 || !result.Time.Equal(extra.Time) {
+		t.Errorf("decoded extra mismatch: %+v", result)
+	}
+	if result.Proposer != header.Coinbase.String() || !bytes.Equal(result.ValidatorsHash, extra.ValidatorsHash) {
+		t.Errorf("proposer or validators hash mismatch: %+v", result)
+	}
+	commit := result.SeenCommit
+	if commit == nil || !bytes.Equal(commit.BlockHash, []byte{0xaa, 0xbb}) || commit.Round != 1 || commit.Signers != 2 || commit.Validators != 4 {
+		t.Errorf("seen commit summary mismatch: %+v", commit)
+	}
+
+	header.Extra = []byte{0xff}
+	if _, err := newRPCNeatconExtra(header); err == nil {
+		t.Error("invalid extra data decoded")
+	}
+}
+
+func TestHasCommit(t *testing.T) {
+	commitHeader := func(height uint64, validators int, signers ...int) *types.Header {
+		bitArray := NewBitArray(uint64(validators))
+		for _, i := range signers {
+			bitArray.SetIndex(uint64(i), true)
+		}
+		extra := &ncTypes.NeatconExtra{
+			ChainID:    "neatio",
+			Height:     height,
+			Time:       time.Unix(1600000000, 0).UTC(),
+			SeenCommit: &ncTypes.Commit{Height: height, BitArray: bitArray},
+		}
+		return &types.Header{Number: big.NewInt(12), Extra: wire.BinaryBytes(*extra)}
+	}
+	validators := func(powers ...int64) *ncTypes.ValidatorSet {
+		vals := make([]*ncTypes.Validator, len(powers))
+		for i, power := range powers {
+			vals[i] = ncTypes.NewValidator([]byte{byte(i + 1)}, nil, big.NewInt(power))
+		}
+		return ncTypes.NewValidatorSet(vals)
+	}
+	equal, weighted := validators(1, 1, 1, 1), validators(10, 1, 1, 1)
+
+	tests := []struct {
+		header *types.Header
+		valSet *ncTypes.ValidatorSet
+		commit bool
+		final  bool
+	}{
+		{commitHeader(12, 4, 0, 1, 2), equal, true, true},
+		{commitHeader(12, 4, 0, 1, 2, 3), equal, true, true},
+		{commitHeader(12, 4, 0, 1), equal, true, false},
+		{commitHeader(11, 4, 0, 1, 2, 3), equal, true, false},
+		// The voting power counts, not the signers
+		{commitHeader(12, 4, 1, 2, 3), weighted, true, false},
+		{commitHeader(12, 4, 0), weighted, true, true},
+		// Without the validators of the block, or with others, it is not final
+		{commitHeader(12, 4, 0, 1, 2, 3), nil, true, false},
+		{commitHeader(12, 3, 0, 1, 2), equal, true, false},
+		{&types.Header{Number: big.NewInt(12), Extra: []byte{0xff}}, equal, false, false},
+	}
+	for i, tt := range tests {
+		commit, final := hasCommit(tt.header, tt.valSet)
+		if (commit != nil) != tt.commit || final != tt.final {
+			t.Errorf("test %d: have commit %v final %v, want commit %v final %v", i, commit != nil, final, tt.commit, tt.final)
+		}
+	}
+}
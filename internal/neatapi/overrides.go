@@ -0,0 +1,41 @@
+package neatapi
+
+import (
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core/vm"
+)
+
+// BlockOverrides is the set of block fields overridden while simulating a
+// call, to run it as in a block which is not mined yet. The fields not set
+// keep the values of the block the call runs on. BaseFee is accepted for the
+// wallets and tools which send it, and ignored as the blocks have no base fee.
+type BlockOverrides struct {
+	Number   *hexutil.Big    `json:"number"`
+	Time     *hexutil.Uint64 `json:"time"`
+	GasLimit *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase *common.Address `json:"coinbase"`
+	BaseFee  *hexutil.Big    `json:"baseFee"`
+}
+
+// Apply overrides the block fields of the EVM context. It must run before the
+// EVM is created, the block number selecting the rules of the EVM.
+func (o *BlockOverrides) Apply(ctx *vm.Context) {
+	if o == nil {
+		return
+	}
+	if o.Number != nil {
+		ctx.BlockNumber = new(big.Int).Set(o.Number.ToInt())
+	}
+	if o.Time != nil {
+		ctx.Time = new(big.Int).SetUint64(uint64(*o.Time))
+	}
+	if o.GasLimit != nil {
+		ctx.GasLimit = uint64(*o.GasLimit)
+	}
+	if o.Coinbase != nil {
+		ctx.Coinbase = *o.Coinbase
+	}
+}
@@ -0,0 +1,99 @@
+package neatapi
+
+import (
+	"context"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+)
+
+// blockReceiptsCacheLimit is the number of blocks kept loaded with their
+// receipts, enough for a batch of lookups walking the transactions of the
+// recent blocks one by one.
+const blockReceiptsCacheLimit = 64
+
+// blockReceipts is a block loaded together with its receipts and the position
+// of its transactions.
+type blockReceipts struct {
+	block    *types.Block
+	receipts types.Receipts
+	index    map[common.Hash]uint64
+}
+
+// receiptLoader loads the receipts of a block in one go: the body and the
+// receipts are read once, the transaction hashes computed once and the senders
+// recovered on all cores, and the result is shared by all the lookups of the
+// transactions of the block which follow.
+type receiptLoader struct {
+	b     Backend
+	cache *lru.Cache
+}
+
+func newReceiptLoader(b Backend) *receiptLoader {
+	cache, _ := lru.New(blockReceiptsCacheLimit)
+	return &receiptLoader{b: b, cache: cache}
+}
+
+// transaction looks up a finalized transaction along with its receipt and the
+// block including it. The receipt is nil if it is not known yet.
+func (l *receiptLoader) transaction(ctx context.Context, hash common.Hash) (*types.Transaction, *types.Receipt, *types.Block, uint64, error) {
+	blockHash := rawdb.ReadTxLookupEntry(l.b.ChainDb(), hash)
+	if blockHash == (common.Hash{}) {
+		return nil, nil, nil, 0, nil
+	}
+	loaded, err := l.load(ctx, blockHash)
+	if loaded == nil || err != nil {
+		return nil, nil, nil, 0, err
+	}
+	index, ok := loaded.index[hash]
+	if !ok {
+		return nil, nil, nil, 0, nil
+	}
+	var receipt *types.Receipt
+	if int(index) < len(loaded.receipts) {
+		receipt = loaded.receipts[index]
+	}
+	return loaded.block.Transactions()[index], receipt, loaded.block, index, nil
+}
+
+// load returns the block with the given hash along with its receipts.
+func (l *receiptLoader) load(ctx context.Context, blockHash common.Hash) (*blockReceipts, error) {
+	if cached, ok := l.cache.Get(blockHash); ok {
+		return cached.(*blockReceipts), nil
+	}
+	block, err := l.b.GetBlock(ctx, blockHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return l.loadBlock(ctx, block)
+}
+
+// loadBlock returns the given block along with its receipts.
+func (l *receiptLoader) loadBlock(ctx context.Context, block *types.Block) (*blockReceipts, error) {
+	if cached, ok := l.cache.Get(block.Hash()); ok {
+		return cached.(*blockReceipts), nil
+	}
+	receipts, err := l.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	loaded := &blockReceipts{
+		block:    block,
+		receipts: receipts,
+		index:    make(map[common.Hash]uint64, len(txs)),
+	}
+	for i, tx := range txs {
+		loaded.index[tx.Hash()] = uint64(i)
+	}
+	core.SenderCacher.RecoverAndWait(types.NewEIP155Signer(l.b.ChainConfig().ChainId), txs)
+
+	// Blocks still being persisted have no receipts yet, look them up again
+	if len(receipts) == len(txs) {
+		l.cache.Add(block.Hash(), loaded)
+	}
+	return loaded, nil
+}
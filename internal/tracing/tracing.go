@@ -0,0 +1,317 @@
+// Package tracing records spans of the block lifecycle and exports them to an
+// OpenTelemetry collector using the OTLP/HTTP JSON encoding.
+//
+// All the spans of a block share a trace id derived from the chain id and the
+// height, so the spans recorded by different validators for the same block end
+// up in the same trace.
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/neatlab/neatio/log"
+)
+
+const (
+	queueSize     = 4096            // Spans buffered before new ones are dropped
+	batchSize     = 512             // Maximum number of spans sent in one request
+	flushInterval = 5 * time.Second // Time after which incomplete batches are sent
+)
+
+var (
+	enabled  int32
+	exporter *spanExporter
+)
+
+// Enabled reports whether spans are recorded.
+func Enabled() bool {
+	return atomic.LoadInt32(&enabled) == 1
+}
+
+// Setup starts exporting spans to the OTLP/HTTP traces endpoint of a
+// collector, e.g. http://127.0.0.1:4318/v1/traces.
+func Setup(endpoint, service string) {
+	exporter = newSpanExporter(endpoint, service)
+	go exporter.loop()
+	atomic.StoreInt32(&enabled, 1)
+	log.Info("Exporting traces", "endpoint", endpoint)
+}
+
+// Stop sends the buffered spans and stops the exporter.
+func Stop() {
+	if !atomic.CompareAndSwapInt32(&enabled, 1, 0) {
+		return
+	}
+	exporter.stop()
+}
+
+// Span is a timed operation of the block lifecycle. A nil span is valid and
+// records nothing, which is what StartBlockSpan returns if tracing is off.
+type Span struct {
+	traceID [16]byte
+	spanID  [8]byte
+	name    string
+	start   time.Time
+	end     time.Time
+	attrs   []attribute
+	err     error
+}
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// StartBlockSpan starts a span of the block at the given height of a chain.
+func StartBlockSpan(chainId string, height uint64, round int, name string) *Span {
+	if !Enabled() {
+		return nil
+	}
+	span := &Span{
+		traceID: blockTraceID(chainId, height),
+		name:    name,
+		start:   time.Now(),
+		attrs: []attribute{
+			{"chain", chainId},
+			{"height", height},
+			{"round", round},
+		},
+	}
+	rand.Read(span.spanID[:])
+	return span
+}
+
+// SetAttribute attaches a key/value pair to the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attribute{key, value})
+}
+
+// SetError marks the span as failed.
+func (s *Span) SetError(err error) {
+	if s == nil {
+		return
+	}
+	s.err = err
+}
+
+// End finishes the span and queues it for export.
+func (s *Span) End() {
+	if s == nil || !Enabled() {
+		return
+	}
+	s.end = time.Now()
+	exporter.add(s)
+}
+
+// blockTraceID derives the trace id of a block, identical on all nodes.
+func blockTraceID(chainId string, height uint64) (id [16]byte) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], height)
+	hash := sha256.Sum256(append([]byte(chainId), buf[:]...))
+	copy(id[:], hash[:])
+	return id
+}
+
+// spanExporter batches finished spans and posts them to the collector.
+type spanExporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+
+	queue   chan *Span
+	quit    chan chan struct{}
+	dropped uint64
+}
+
+func newSpanExporter(endpoint, service string) *spanExporter {
+	return &spanExporter{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		queue:    make(chan *Span, queueSize),
+		quit:     make(chan chan struct{}),
+	}
+}
+
+// add queues a span, dropping it if the collector can not keep up.
+func (e *spanExporter) add(s *Span) {
+	select {
+	case e.queue <- s:
+	default:
+		if atomic.AddUint64(&e.dropped, 1) == 1 {
+			log.Warn("Trace queue full, dropping spans")
+		}
+	}
+}
+
+func (e *spanExporter) loop() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case s := <-e.queue:
+			if batch = append(batch, s); len(batch) >= batchSize {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.send(batch)
+				batch = nil
+			}
+		case done := <-e.quit:
+			for len(e.queue) > 0 {
+				batch = append(batch, <-e.queue)
+			}
+			if len(batch) > 0 {
+				e.send(batch)
+			}
+			close(done)
+			return
+		}
+	}
+}
+
+func (e *spanExporter) stop() {
+	done := make(chan struct{})
+	e.quit <- done
+	<-done
+}
+
+func (e *spanExporter) send(batch []*Span) {
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		log.Warn("Failed to encode trace spans", "err", err)
+		return
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Debug("Failed to export trace spans", "spans", len(batch), "err", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		log.Debug("Trace collector rejected spans", "spans", len(batch), "status", resp.Status)
+	}
+}
+
+// The types below are the subset of the OTLP/JSON trace request used by the
+// exporter. Ids are hex encoded and 64 bit integers are strings, as required
+// by the protobuf JSON mapping.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    *string `json:"intValue,omitempty"`
+		BoolValue   *bool   `json:"boolValue,omitempty"`
+	}
+)
+
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+func (e *spanExporter) encode(batch []*Span) *otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		for _, attr := range s.attrs {
+			span.Attributes = append(span.Attributes, keyValue(attr.key, attr.value))
+		}
+		if s.err != nil {
+			span.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+		}
+		spans = append(spans, span)
+	}
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{keyValue("service.name", e.service)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/neatlab/neatio"},
+				Spans: spans,
+			}},
+		}},
+	}
+}
+
+func keyValue(key string, value interface{}) otlpKeyValue {
+	var v otlpValue
+	switch value := value.(type) {
+	case int:
+		s := strconv.FormatInt(int64(value), 10)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(value, 10)
+		v.IntValue = &s
+	case uint64:
+		s := strconv.FormatUint(value, 10)
+		v.IntValue = &s
+	case bool:
+		v.BoolValue = &value
+	case string:
+		v.StringValue = &value
+	default:
+		s := fmt.Sprint(value)
+		v.StringValue = &s
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBlockTraceID(t *testing.T) {
+	if blockTraceID("neatio", 1) != blockTraceID("neatio", 1) {
+		t.Fatal("trace id of a block differs")
+	}
+	if blockTraceID("neatio", 1) == blockTraceID("neatio", 2) {
+		t.Fatal("trace ids of different heights collide")
+	}
+	if blockTraceID("neatio", 1) == blockTraceID("side_0", 1) {
+		t.Fatal("trace ids of different chains collide")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	span := StartBlockSpan("neatio", 1, 0, "test")
+	if span != nil {
+		t.Fatal("span recorded with tracing disabled")
+	}
+	// Methods of disabled spans must be no-ops
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("failure"))
+	span.End()
+}
+
+func TestExport(t *testing.T) {
+	requests := make(chan *otlpRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req := new(otlpRequest)
+		if err := json.Unmarshal(body, req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	Setup(server.URL, "neatio-test")
+	span := StartBlockSpan("neatio", 10, 1, "block.commit")
+	span.SetAttribute("txs", 3)
+	span.SetError(errors.New("failure"))
+	span.End()
+	Stop()
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected request layout: %+v", req)
+	}
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 1 {
+		t.Fatalf("span count mismatch: have %d, want 1", len(spans))
+	}
+	have := spans[0]
+	if have.Name != "block.commit" || len(have.TraceID) != 32 || len(have.SpanID) != 16 {
+		t.Errorf("unexpected span: %+v", have)
+	}
+	if have.Status.Code != statusCodeError || have.Status.Message != "failure" {
+		t.Errorf("status mismatch: %+v", have.Status)
+	}
+	attrs := make(map[string]otlpValue)
+	for _, attr := range have.Attributes {
+		attrs[attr.Key] = attr.Value
+	}
+	if v := attrs["height"].IntValue; v == nil || *v != "10" {
+		t.Errorf("height attribute mismatch: %+v", attrs["height"])
+	}
+	if v := attrs["chain"].StringValue; v == nil || *v != "neatio" {
+		t.Errorf("chain attribute mismatch: %+v", attrs["chain"])
+	}
+	if v := attrs["txs"].IntValue; v == nil || *v != "3" {
+		t.Errorf("txs attribute mismatch: %+v", attrs["txs"])
+	}
+}
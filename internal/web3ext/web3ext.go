@@ -24,6 +24,7 @@ var Modules = map[string]string{
 	"debug":      Debug_JS,
 	"eth":        Eth_JS,
 	"neat":       NEAT_JS,
+	"operator":   Operator_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
@@ -129,6 +130,26 @@ web3._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'resumeAfterFork',
+			call: 'admin_resumeAfterFork',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'allowPeer',
+			call: 'admin_allowPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'denyPeer',
+			call: 'admin_denyPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removePeerRule',
+			call: 'admin_removePeerRule',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -140,6 +161,12 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'backup',
+			call: 'admin_backup',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -179,6 +206,10 @@ web3._extend({
 			name: 'peers',
 			getter: 'admin_peers'
 		}),
+		new web3._extend.Property({
+			name: 'peerRules',
+			getter: 'admin_peerRules'
+		}),
 		new web3._extend.Property({
 			name: 'datadir',
 			getter: 'admin_datadir'
@@ -241,6 +272,18 @@ web3._extend({
 			call: 'debug_vmodule',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setVerbosity',
+			call: 'debug_setVerbosity',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'setVmoduleLevel',
+			call: 'debug_setVmoduleLevel',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'backtraceAt',
 			call: 'debug_backtraceAt',
@@ -287,6 +330,21 @@ web3._extend({
 			call: 'debug_stopCPUProfile',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'startPProf',
+			call: 'debug_startPProf',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'stopPProf',
+			call: 'debug_stopPProf',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'captureProfiles',
+			call: 'debug_captureProfiles',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'goTrace',
 			call: 'debug_goTrace',
@@ -367,6 +425,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -378,6 +442,11 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'dryRunProposal',
+			call: 'debug_dryRunProposal',
+			params: 0,
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
@@ -410,6 +479,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData_v4',
+			call: 'eth_signTypedData_v4',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'resend',
 			call: 'eth_resend',
@@ -447,8 +522,25 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: 'eth_getBlockReceipts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'feeHistory',
+			call: 'eth_feeHistory',
+			params: 3,
+			inputFormatter: [web3._extend.utils.toHex, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 	],
 	properties: [
+		new web3._extend.Property({
+			name: 'maxPriorityFeePerGas',
+			getter: 'eth_maxPriorityFeePerGas',
+			outputFormatter: web3._extend.utils.toBigNumber
+		}),
 		new web3._extend.Property({
 			name: 'pendingTransactions',
 			getter: 'eth_pendingTransactions',
@@ -475,6 +567,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData_v4',
+			call: 'neat_signTypedData_v4',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'resend',
 			call: 'neat_resend',
@@ -523,10 +621,10 @@ web3._extend({
 			params: 1
 		}),
 		new web3._extend.Method({
-			name: 'withdrawReward',
-			call: 'neat_withdrawReward',
-			params: 3,
-			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null]
+			name: 'getRewards',
+			call: 'neat_getRewards',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
 		}),
 		new web3._extend.Method({
 			name: 'voteNextEpoch',
@@ -562,16 +660,40 @@ web3._extend({
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
 		}),
 		new web3._extend.Method({
-			name: 'unBanned',
-			call: 'neat_unBanned',
+			name: 'getBridgeClients',
+			call: 'neat_getBridgeClients',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBridgeClient',
+			call: 'neat_getBridgeClient',
 			params: 2,
-			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
 		}),
 		new web3._extend.Method({
-			name: 'editValidator',
-			call: 'neat_editValidator',
-			params: 6,
-			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null]
+			name: 'getBridgeVoucherBalance',
+			call: 'neat_getBridgeVoucherBalance',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getAssets',
+			call: 'neat_getAssets',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getAsset',
+			call: 'neat_getAsset',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getAssetByContract',
+			call: 'neat_getAssetByContract',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
 		}),
 		new web3._extend.Method({
 			name: 'getVoteHash',
@@ -590,34 +712,77 @@ web3._extend({
 			params: 1
 		}),
 		new web3._extend.Method({
-			name: 'getCandidateList',
-			call: 'neat_getCandidateList',
+			name: 'getValidatorSetProof',
+			call: 'neat_getValidatorSetProof',
+			params: 1,
+			inputFormatter: [web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionFinality',
+			call: 'neat_getTransactionFinality',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getEpochVoteProgress',
+			call: 'neat_getEpochVoteProgress',
 			params: 0
 		}),
 		new web3._extend.Method({
-			name: 'getBannedList',
-			call: 'neat_getBannedList',
+			name: 'getProposerSchedule',
+			call: 'neat_getProposerSchedule',
+			params: 1,
+			inputFormatter: [web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'forkReports',
+			call: 'neat_forkReports',
 			params: 0
 		}),
 		new web3._extend.Method({
-			name: 'delegate',
-			call: 'neat_delegate',
-			params: 4
+			name: 'chainStats',
+			call: 'neat_chainStats',
+			params: 2,
+			inputFormatter: [web3._extend.utils.toHex, web3._extend.utils.toHex]
 		}),
 		new web3._extend.Method({
-			name: 'unDelegate',
-			call: 'neat_unDelegate',
-			params: 4
+			name: 'validatorUptime',
+			call: 'neat_validatorUptime',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.toHex]
 		}),
 		new web3._extend.Method({
-			name: 'register',
-			call: 'neat_register',
-			params: 6
+			name: 'epochRewards',
+			call: 'neat_epochRewards',
+			params: 1,
+			inputFormatter: [web3._extend.utils.toHex]
 		}),
 		new web3._extend.Method({
-			name: 'unRegister',
-			call: 'neat_unRegister',
-			params: 2
+			name: 'getCommit',
+			call: 'neat_getCommit',
+			params: 1,
+			inputFormatter: [web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockWithExtra',
+			call: 'neat_getBlockWithExtra',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getLastSignedBlock',
+			call: 'neat_getLastSignedBlock',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.toHex]
+		}),
+		new web3._extend.Method({
+			name: 'getCandidateList',
+			call: 'neat_getCandidateList',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getBannedList',
+			call: 'neat_getBannedList',
+			params: 0
 		}),
 		new web3._extend.Method({
 			name: 'checkCandidate',
@@ -632,13 +797,105 @@ web3._extend({
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
 		}),
 		new web3._extend.Method({
-			name: 'setCommission',
-			call: 'neat_setCommission',
+			name: 'convertAddress',
+			call: 'neat_convertAddress',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getTreasury',
+			call: 'neat_getTreasury',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'upgradeStatus',
+			call: 'neat_upgradeStatus',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getGovProposals',
+			call: 'neat_getGovProposals',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getGovProposal',
+			call: 'neat_getGovProposal',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getChainParams',
+			call: 'neat_getChainParams',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getGovParams',
+			call: 'neat_getGovParams',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getConsensusState',
+			call: 'neat_getConsensusState',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'propagationStats',
+			call: 'neat_propagationStats',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'misbehavior',
+			call: 'neat_misbehavior',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getSideChains',
+			call: 'neat_getSideChains',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorSideChains',
+			call: 'neat_getValidatorSideChains',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getPendingWithdrawals',
+			call: 'neat_getPendingWithdrawals',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEthCheckpoint',
+			call: 'neat_getEthCheckpoint',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'txSearch',
+			call: 'neat_txSearch',
 			params: 3,
-			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+			inputFormatter: [null, null, null]
 		})
 	],
 	properties: [
+		new web3._extend.Property({
+			name: 'currentEpochNumber',
+			getter: 'neat_getCurrentEpochNumber',
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Property({
+			name: 'consensusState',
+			getter: 'neat_getConsensusState'
+		}),
+		new web3._extend.Property({
+			name: 'sideChains',
+			getter: 'neat_getSideChains'
+		}),
 		new web3._extend.Property({
 			name: 'pendingTransactions',
 			getter: 'eth_pendingTransactions',
@@ -655,6 +912,178 @@ web3._extend({
 });
 `
 
+const Operator_JS = `
+web3._extend({
+	property: 'operator',
+	methods: [
+		new web3._extend.Method({
+			name: 'withdrawReward',
+			call: 'operator_withdrawReward',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'setAutoCompound',
+			call: 'operator_setAutoCompound',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'delegate',
+			call: 'operator_delegate',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'unDelegate',
+			call: 'operator_unDelegate',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'register',
+			call: 'operator_register',
+			params: 6
+		}),
+		new web3._extend.Method({
+			name: 'unRegister',
+			call: 'operator_unRegister',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setCommission',
+			call: 'operator_setCommission',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'setMinSelfBond',
+			call: 'operator_setMinSelfBond',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'rotateConsensusKey',
+			call: 'operator_rotateConsensusKey',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'submitGovProposal',
+			call: 'operator_submitGovProposal',
+			params: 8,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'submitTreasurySpend',
+			call: 'operator_submitTreasurySpend',
+			params: 7,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'depositGovProposal',
+			call: 'operator_depositGovProposal',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'voteGovProposal',
+			call: 'operator_voteGovProposal',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'signalUpgrade',
+			call: 'operator_signalUpgrade',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'editValidator',
+			call: 'operator_editValidator',
+			params: 6,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'setSecurityContact',
+			call: 'operator_setSecurityContact',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'unBanned',
+			call: 'operator_unBanned',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'depositInMainChain',
+			call: 'operator_depositInMainChain',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'withdrawFromSideChain',
+			call: 'operator_withdrawFromSideChain',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'challengeWithdrawal',
+			call: 'operator_challengeWithdrawal',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'registerAsset',
+			call: 'operator_registerAsset',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'setAssetContract',
+			call: 'operator_setAssetContract',
+			params: 5,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, web3._extend.formatters.inputAddressFormatter, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'createBridgeClient',
+			call: 'operator_createBridgeClient',
+			params: 9,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'updateBridgeClient',
+			call: 'operator_updateBridgeClient',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'recvBridgePacket',
+			call: 'operator_recvBridgePacket',
+			params: 6,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, web3._extend.utils.fromDecimal, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'sendBridgePacket',
+			call: 'operator_sendBridgePacket',
+			params: 8,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, web3._extend.utils.fromDecimal, null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal, null]
+		}),
+		new web3._extend.Method({
+			name: 'acknowledgeBridgePacket',
+			call: 'operator_acknowledgeBridgePacket',
+			params: 7,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, web3._extend.utils.fromDecimal, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'submitEthCheckpoint',
+			call: 'operator_submitEthCheckpoint',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		})
+	]
+});
+`
+
 const Miner_JS = `
 web3._extend({
 	property: 'miner',
@@ -723,6 +1152,12 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData',
+			call: 'personal_signTypedData',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'ecRecover',
 			call: 'personal_ecRecover',
@@ -757,7 +1192,13 @@ web3._extend({
 const RPC_JS = `
 web3._extend({
 	property: 'rpc',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'discover',
+			call: 'rpc_discover',
+			params: 0
+		}),
+	],
 	properties: [
 		new web3._extend.Property({
 			name: 'modules',
@@ -44,6 +44,7 @@ type GlogHandler struct {
 	backtrace uint32 // Flag whether backtrace location is set
 
 	patterns  []pattern       // Current list of patterns to override with
+	ruleset   string          // Vmodule ruleset the patterns were compiled from
 	siteCache map[uintptr]Lvl // Cache of callsite pattern evaluations
 	location  string          // file:line location where to do a stackdump at
 	lock      sync.RWMutex    // Lock protecting the override pattern list
@@ -137,12 +138,40 @@ func (h *GlogHandler) Vmodule(ruleset string) error {
 	defer h.lock.Unlock()
 
 	h.patterns = filter
+	h.ruleset = ruleset
 	h.siteCache = make(map[uintptr]Lvl)
 	atomic.StoreUint32(&h.override, uint32(len(filter)))
 
 	return nil
 }
 
+// VmoduleRules returns the current verbosity pattern.
+func (h *GlogHandler) VmoduleRules() string {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	return h.ruleset
+}
+
+// SetVmoduleLevel sets the V level of a single pattern, keeping the other rules
+// of the verbosity pattern. A level of 0 removes the rule of the pattern.
+func (h *GlogHandler) SetVmoduleLevel(module string, level Lvl) error {
+	module = strings.TrimSpace(module)
+	if module == "" || strings.ContainsAny(module, ",=") {
+		return errVmoduleSyntax
+	}
+	var rules []string
+	for _, rule := range strings.Split(h.VmoduleRules(), ",") {
+		if parts := strings.Split(rule, "="); len(rule) > 0 && strings.TrimSpace(parts[0]) != module {
+			rules = append(rules, rule)
+		}
+	}
+	if level > 0 {
+		rules = append(rules, fmt.Sprintf("%s=%d", module, level))
+	}
+	return h.Vmodule(strings.Join(rules, ","))
+}
+
 // BacktraceAt sets the glog backtrace location. When set to a file and line
 // number holding a logging statement, a stack trace will be written to the Info
 // log whenever execution hits that statement.
@@ -11,22 +11,45 @@ var loggerMap sync.Map
 
 func newChainLogger(chainID string) Logger {
 
-	chainLogger := &logger{[]interface{}{}, new(swapHandler)}
+	chainLogger := &logger{[]interface{}{"chain", chainID}, new(swapHandler)}
 
 	loggerMap.Store(chainID, chainLogger)
 
 	return chainLogger
 }
 
+// ValidFormat reports whether name is a supported log format: terminal,
+// logfmt or json.
+func ValidFormat(name string) bool {
+	switch name {
+	case "", "terminal", "logfmt", "json":
+		return true
+	}
+	return false
+}
+
+// formatByName returns the record format called name, terminal records are
+// colored if usecolor is set.
+func formatByName(name string, usecolor bool) Format {
+	switch name {
+	case "json":
+		return JSONFormat()
+	case "logfmt":
+		return LogfmtFormat()
+	default:
+		return TerminalFormat(usecolor)
+	}
+}
+
 // NewLogger Create a new Logger for a particular Chain and return it
-func NewLogger(chainID, logDir string, logLevel int, fileLine bool, vmodule, backtrace string) Logger {
+func NewLogger(chainID, logDir string, logLevel int, fileLine bool, vmodule, backtrace, format string) Logger {
 
 	// logging
 	PrintOrigins(fileLine)
 
 	// Console Log
 	output := colorable.NewColorableStdout()
-	ostream := StreamHandler(output, TerminalFormat(true))
+	ostream := StreamHandler(output, formatByName(format, true))
 	glogger := NewGlogHandler(ostream)
 
 	// Normal Rotation Log
@@ -42,7 +65,7 @@ func NewLogger(chainID, logDir string, logLevel int, fileLine bool, vmodule, bac
 		rfh, err := RotatingFileHandler(
 			logDir,
 			10*1024*1024,
-			TerminalFormat(false),
+			formatByName(format, false),
 			//JSONFormatOrderedEx(false, true),
 		)
 		if err != nil {
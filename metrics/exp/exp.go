@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"sync"
 
+	"github.com/neatlab/neatio/log"
 	"github.com/neatlab/neatio/metrics"
+	"github.com/neatlab/neatio/metrics/prometheus"
 )
 
 type exp struct {
@@ -44,6 +46,21 @@ func Exp(r metrics.Registry) {
 	http.Handle("/debug/metrics", h)
 }
 
+// Setup starts a dedicated metrics server at the given address, serving the
+// Prometheus metrics on /metrics and the expvar ones on /debug/metrics.
+// This function enables metrics reporting separate from pprof.
+func Setup(address string) {
+	m := http.NewServeMux()
+	m.Handle("/metrics", prometheus.Handler(metrics.DefaultRegistry))
+	m.Handle("/debug/metrics", ExpHandler(metrics.DefaultRegistry))
+	log.Info("Starting metrics server", "addr", fmt.Sprintf("http://%s/metrics", address))
+	go func() {
+		if err := http.ListenAndServe(address, m); err != nil {
+			log.Error("Failure in running metrics server", "err", err)
+		}
+	}()
+}
+
 // ExpHandler will return an expvar powered metrics handler.
 func ExpHandler(r metrics.Registry) http.Handler {
 	e := exp{sync.Mutex{}, r}
@@ -134,6 +151,20 @@ func (exp *exp) publishTimer(name string, metric metrics.Timer) {
 	exp.getFloat(name + ".mean-rate").Set(t.RateMean())
 }
 
+func (exp *exp) publishResettingTimer(name string, metric metrics.ResettingTimer) {
+	t := metric.Snapshot()
+	exp.getInt(name + ".count").Set(int64(len(t.Values())))
+	if len(t.Values()) == 0 {
+		return
+	}
+	ps := t.Percentiles([]float64{50, 75, 95, 99})
+	exp.getFloat(name + ".mean").Set(t.Mean())
+	exp.getInt(name + ".50-percentile").Set(ps[0])
+	exp.getInt(name + ".75-percentile").Set(ps[1])
+	exp.getInt(name + ".95-percentile").Set(ps[2])
+	exp.getInt(name + ".99-percentile").Set(ps[3])
+}
+
 func (exp *exp) syncToExpvar() {
 	exp.registry.Each(func(name string, i interface{}) {
 		switch i.(type) {
@@ -149,6 +180,8 @@ func (exp *exp) syncToExpvar() {
 			exp.publishMeter(name, i.(metrics.Meter))
 		case metrics.Timer:
 			exp.publishTimer(name, i.(metrics.Timer))
+		case metrics.ResettingTimer:
+			exp.publishResettingTimer(name, i.(metrics.ResettingTimer))
 		default:
 			panic(fmt.Sprintf("unsupported type for '%s': %T", name, i))
 		}
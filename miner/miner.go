@@ -188,3 +188,9 @@ func (self *Miner) SetCoinbase(addr common.Address) {
 	self.coinbase = addr
 	self.worker.setCoinbase(addr)
 }
+
+// DryRunProposal builds a block proposal from the pending transactions without
+// sealing or broadcasting it.
+func (self *Miner) DryRunProposal() (*ProposalResult, error) {
+	return self.worker.dryRun()
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
 	"github.com/neatlab/neatio/consensus"
 	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
 	"github.com/neatlab/neatio/core"
@@ -314,6 +315,15 @@ func (self *worker) mainLoop() {
 
 				self.commitTransactionsEx(txset, self.coinbase, big.NewInt(0), self.cch)
 				self.currentMu.Unlock()
+			} else if self.isRunning() && self.skipEmptyBlocks() {
+				// The consensus waits for a block with transactions, rebuild
+				// the pending block if it was built empty
+				self.currentMu.Lock()
+				empty := self.current != nil && self.current.tcount == 0
+				self.currentMu.Unlock()
+				if empty {
+					self.commitNewWork()
+				}
 			}
 
 		// System stopped
@@ -329,6 +339,13 @@ func (self *worker) mainLoop() {
 	}
 }
 
+// skipEmptyBlocks reports whether the consensus engine only produces blocks
+// with transactions, besides the heartbeat.
+func (self *worker) skipEmptyBlocks() bool {
+	neatpos, ok := self.engine.(consensus.NeatPoS)
+	return ok && neatpos.SkipEmptyBlocks()
+}
+
 func (self *worker) resultLoop() {
 	for {
 		mustCommitNewWork := true
@@ -505,7 +522,7 @@ func (self *worker) commitNewWork() {
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     num.Add(num, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent, self.gasFloor, self.gasCeil),
+		GasLimit:   self.calcGasLimit(parent),
 		Extra:      self.extra,
 		Time:       big.NewInt(tstamp),
 	}
@@ -529,6 +546,10 @@ func (self *worker) commitNewWork() {
 	}
 	// Create the current work task and check any fork transitions needed
 	work := self.current
+	if err := core.CheckUpgrades(work.state, header.Number.Uint64()); err != nil {
+		self.logger.Error("Chain halted for upgrade, not mining", "number", header.Number, "err", err)
+		return
+	}
 	//if self.config.DAOForkSupport && self.config.DAOForkBlock != nil && self.config.DAOForkBlock.Cmp(header.Number) == 0 {
 	//	misc.ApplyDAOHardFork(work.state)
 	//}
@@ -706,3 +727,141 @@ func (self *worker) commitTransactionEx(tx *types.Transaction, coinbase common.A
 
 	return receipt.Logs, nil
 }
+
+// ProposalTx describes a transaction considered for a dry-run proposal.
+type ProposalTx struct {
+	Hash     common.Hash    `json:"hash"`
+	From     common.Address `json:"from"`
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	GasPrice *hexutil.Big   `json:"gasPrice"`
+	GasUsed  hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Reason   string         `json:"reason,omitempty"`
+}
+
+// ProposalResult is the outcome of building a block proposal without sealing
+// or broadcasting it.
+type ProposalResult struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Parent    common.Hash    `json:"parentHash"`
+	Coinbase  common.Address `json:"coinbase"`
+	GasLimit  hexutil.Uint64 `json:"gasLimit"`
+	GasUsed   hexutil.Uint64 `json:"gasUsed"`
+	StateRoot common.Hash    `json:"stateRoot"`
+	Pending   int            `json:"pending"`
+	Included  []*ProposalTx  `json:"included"`
+	Skipped   []*ProposalTx  `json:"skipped"`
+}
+
+// calcGasLimit returns the gas limit of the block on top of parent, honing
+// towards the block gas limit set by governance if any, instead of the
+// configured gas floor and ceiling.
+func (self *worker) calcGasLimit(parent *types.Block) uint64 {
+	gasFloor, gasCeil := self.gasFloor, self.gasCeil
+	if statedb, err := self.chain.StateAt(parent.Root()); err == nil {
+		if target := statedb.GetGovParam(state.GovParamBlockGasLimit); target != nil {
+			gasFloor, gasCeil = target.Uint64(), target.Uint64()
+		}
+	}
+	return core.CalcGasLimit(parent, gasFloor, gasCeil)
+}
+
+// dryRun builds a block on top of the current head from the pending
+// transactions the same way commitNewWork does, recording why transactions
+// were left out. The current work, the transaction pool and the subscribers
+// of the worker are not touched.
+func (self *worker) dryRun() (*ProposalResult, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	parent := self.chain.CurrentBlock()
+	num := parent.Number()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     num.Add(num, common.Big1),
+		GasLimit:   self.calcGasLimit(parent),
+		Extra:      self.extra,
+		Coinbase:   self.coinbase,
+		Time:       big.NewInt(time.Now().Unix()),
+	}
+	if err := self.engine.Prepare(self.chain, header); err != nil {
+		return nil, err
+	}
+	statedb, err := self.chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	pending, err := self.eth.TxPool().Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProposalResult{
+		Number:   hexutil.Uint64(header.Number.Uint64()),
+		Parent:   parent.Hash(),
+		Coinbase: header.Coinbase,
+		GasLimit: hexutil.Uint64(header.GasLimit),
+		Included: make([]*ProposalTx, 0),
+		Skipped:  make([]*ProposalTx, 0),
+	}
+	for _, txs := range pending {
+		result.Pending += len(txs)
+	}
+
+	var (
+		signer         = types.NewEIP155Signer(self.config.ChainId)
+		ops            = new(types.PendingOps)
+		gp             = new(core.GasPool).AddGas(header.GasLimit)
+		totalUsedMoney = big.NewInt(0)
+		included       []*types.Transaction
+		receipts       []*types.Receipt
+	)
+	skip := func(tx *types.Transaction, from common.Address, reason string) {
+		result.Skipped = append(result.Skipped, &ProposalTx{Hash: tx.Hash(), From: from, Nonce: hexutil.Uint64(tx.Nonce()), GasPrice: (*hexutil.Big)(tx.GasPrice()), Reason: reason})
+	}
+	txs := types.NewTransactionsByPriceAndNonce(signer, pending)
+	for {
+		tx := txs.Peek()
+		if tx == nil {
+			break
+		}
+		from, _ := types.Sender(signer, tx)
+		if gp.Gas() < params.TxGas {
+			skip(tx, from, "block gas limit reached")
+			txs.Pop()
+			continue
+		}
+		if tx.Protected() && !self.config.IsEIP155(header.Number) {
+			skip(tx, from, "replay protected transaction before EIP155")
+			txs.Pop()
+			continue
+		}
+
+		statedb.Prepare(tx.Hash(), common.Hash{}, len(included))
+		snap := statedb.Snapshot()
+		gasUsed := header.GasUsed
+		receipt, _, err := core.ApplyTransactionEx(self.config, self.chain, nil, gp, statedb, ops, header, tx, &header.GasUsed, totalUsedMoney, vm.Config{}, self.cch, true)
+		if err != nil {
+			statedb.RevertToSnapshot(snap)
+			skip(tx, from, err.Error())
+			// Same as commitTransactionsEx, these errors skip the account
+			if err == core.ErrGasLimitReached || err == core.ErrNonceTooHigh {
+				txs.Pop()
+			} else {
+				txs.Shift()
+			}
+			continue
+		}
+		included = append(included, tx)
+		receipts = append(receipts, receipt)
+		result.Included = append(result.Included, &ProposalTx{Hash: tx.Hash(), From: from, Nonce: hexutil.Uint64(tx.Nonce()), GasPrice: (*hexutil.Big)(tx.GasPrice()), GasUsed: hexutil.Uint64(header.GasUsed - gasUsed)})
+		txs.Shift()
+	}
+
+	block, err := self.engine.Finalize(self.chain, header, statedb, included, totalUsedMoney, nil, receipts, ops)
+	if err != nil {
+		return nil, err
+	}
+	result.GasUsed = hexutil.Uint64(block.GasUsed())
+	result.StateRoot = block.Root()
+	return result, nil
+}
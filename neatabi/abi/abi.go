@@ -25,17 +25,34 @@ var (
 	WithdrawFromMainChain = FunctionType{5, true, true, false}
 	SaveDataToMainChain   = FunctionType{6, true, true, false}
 	SetBlockReward        = FunctionType{7, true, false, true}
+	ChallengeWithdrawal   = FunctionType{8, true, true, false}
 	// Non-Cross Chain Function
-	VoteNextEpoch  = FunctionType{10, false, true, true}
-	RevealVote     = FunctionType{11, false, true, true}
-	Delegate       = FunctionType{12, false, true, true}
-	UnDelegate     = FunctionType{13, false, true, true}
-	Register       = FunctionType{14, false, true, true}
-	UnRegister     = FunctionType{15, false, true, true}
-	EditValidator  = FunctionType{16, false, true, true}
-	WithdrawReward = FunctionType{17, false, true, true}
-	UnBanned       = FunctionType{18, false, true, true}
-	SetCommission  = FunctionType{19, false, true, true}
+	VoteNextEpoch           = FunctionType{10, false, true, true}
+	RevealVote              = FunctionType{11, false, true, true}
+	Delegate                = FunctionType{12, false, true, true}
+	UnDelegate              = FunctionType{13, false, true, true}
+	Register                = FunctionType{14, false, true, true}
+	UnRegister              = FunctionType{15, false, true, true}
+	EditValidator           = FunctionType{16, false, true, true}
+	WithdrawReward          = FunctionType{17, false, true, true}
+	UnBanned                = FunctionType{18, false, true, true}
+	SetCommission           = FunctionType{19, false, true, true}
+	RotateConsensusKey      = FunctionType{20, false, true, true}
+	SubmitGovProposal       = FunctionType{21, false, true, true}
+	VoteGovProposal         = FunctionType{22, false, true, true}
+	SignalUpgrade           = FunctionType{23, false, true, true}
+	DepositGovProposal      = FunctionType{24, false, true, true}
+	SetAutoCompound         = FunctionType{25, false, true, true}
+	SetMinSelfBond          = FunctionType{26, false, true, true}
+	SetSecurityContact      = FunctionType{27, false, true, true}
+	CreateBridgeClient      = FunctionType{28, false, true, false}
+	UpdateBridgeClient      = FunctionType{29, false, true, false}
+	RecvBridgePacket        = FunctionType{30, false, true, false}
+	SendBridgePacket        = FunctionType{31, false, true, false}
+	AcknowledgeBridgePacket = FunctionType{32, false, true, false}
+	RegisterAsset           = FunctionType{33, false, true, false}
+	SetAssetContract        = FunctionType{34, false, true, false}
+	SubmitEthCheckpoint     = FunctionType{35, false, true, true}
 	// Unknown
 	Unknown = FunctionType{-1, false, false, false}
 )
@@ -76,6 +93,8 @@ func (t FunctionType) RequiredGas() uint64 {
 		return 21000
 	case SetBlockReward:
 		return 21000
+	case ChallengeWithdrawal:
+		return 21000
 	case EditValidator:
 		return 21000
 	case WithdrawReward:
@@ -84,6 +103,38 @@ func (t FunctionType) RequiredGas() uint64 {
 		return 21000
 	case SetCommission:
 		return 21000
+	case RotateConsensusKey:
+		return 21000
+	case SubmitGovProposal:
+		return 21000
+	case VoteGovProposal:
+		return 21000
+	case SignalUpgrade:
+		return 21000
+	case DepositGovProposal:
+		return 21000
+	case SetAutoCompound:
+		return 21000
+	case SetMinSelfBond:
+		return 21000
+	case SetSecurityContact:
+		return 21000
+	case CreateBridgeClient:
+		return 21000
+	case UpdateBridgeClient:
+		return 21000
+	case RecvBridgePacket:
+		return 21000
+	case SendBridgePacket:
+		return 21000
+	case AcknowledgeBridgePacket:
+		return 21000
+	case RegisterAsset:
+		return 42000
+	case SetAssetContract:
+		return 21000
+	case SubmitEthCheckpoint:
+		return 21000
 	default:
 		return 0
 	}
@@ -119,6 +170,8 @@ func (t FunctionType) String() string {
 		return "UnRegister"
 	case SetBlockReward:
 		return "SetBlockReward"
+	case ChallengeWithdrawal:
+		return "ChallengeWithdrawal"
 	case EditValidator:
 		return "EditValidator"
 	case WithdrawReward:
@@ -127,6 +180,38 @@ func (t FunctionType) String() string {
 		return "UnBanned"
 	case SetCommission:
 		return "SetCommission"
+	case RotateConsensusKey:
+		return "RotateConsensusKey"
+	case SubmitGovProposal:
+		return "SubmitGovProposal"
+	case VoteGovProposal:
+		return "VoteGovProposal"
+	case SignalUpgrade:
+		return "SignalUpgrade"
+	case DepositGovProposal:
+		return "DepositGovProposal"
+	case SetAutoCompound:
+		return "SetAutoCompound"
+	case SetMinSelfBond:
+		return "SetMinSelfBond"
+	case SetSecurityContact:
+		return "SetSecurityContact"
+	case CreateBridgeClient:
+		return "CreateBridgeClient"
+	case UpdateBridgeClient:
+		return "UpdateBridgeClient"
+	case RecvBridgePacket:
+		return "RecvBridgePacket"
+	case SendBridgePacket:
+		return "SendBridgePacket"
+	case AcknowledgeBridgePacket:
+		return "AcknowledgeBridgePacket"
+	case RegisterAsset:
+		return "RegisterAsset"
+	case SetAssetContract:
+		return "SetAssetContract"
+	case SubmitEthCheckpoint:
+		return "SubmitEthCheckpoint"
 	default:
 		return "UnKnown"
 	}
@@ -162,6 +247,8 @@ func StringToFunctionType(s string) FunctionType {
 		return UnRegister
 	case "SetBlockReward":
 		return SetBlockReward
+	case "ChallengeWithdrawal":
+		return ChallengeWithdrawal
 	case "EditValidator":
 		return EditValidator
 	case "WithdrawReward":
@@ -170,6 +257,38 @@ func StringToFunctionType(s string) FunctionType {
 		return UnBanned
 	case "SetCommission":
 		return SetCommission
+	case "RotateConsensusKey":
+		return RotateConsensusKey
+	case "SubmitGovProposal":
+		return SubmitGovProposal
+	case "VoteGovProposal":
+		return VoteGovProposal
+	case "SignalUpgrade":
+		return SignalUpgrade
+	case "DepositGovProposal":
+		return DepositGovProposal
+	case "SetAutoCompound":
+		return SetAutoCompound
+	case "SetMinSelfBond":
+		return SetMinSelfBond
+	case "SetSecurityContact":
+		return SetSecurityContact
+	case "CreateBridgeClient":
+		return CreateBridgeClient
+	case "UpdateBridgeClient":
+		return UpdateBridgeClient
+	case "RecvBridgePacket":
+		return RecvBridgePacket
+	case "SendBridgePacket":
+		return SendBridgePacket
+	case "AcknowledgeBridgePacket":
+		return AcknowledgeBridgePacket
+	case "RegisterAsset":
+		return RegisterAsset
+	case "SetAssetContract":
+		return SetAssetContract
+	case "SubmitEthCheckpoint":
+		return SubmitEthCheckpoint
 	default:
 		return Unknown
 	}
@@ -208,6 +327,12 @@ type WithdrawFromMainChainArgs struct {
 	TxHash  common.Hash
 }
 
+type ChallengeWithdrawalArgs struct {
+	TxHash common.Hash
+	Proof  []byte
+	Header []byte
+}
+
 type VoteNextEpochArgs struct {
 	VoteHash common.Hash
 }
@@ -257,6 +382,102 @@ type SetCommissionArgs struct {
 	Commission uint8
 }
 
+type RotateConsensusKeyArgs struct {
+	Pubkey    []byte
+	Signature []byte
+}
+
+type SubmitGovProposalArgs struct {
+	Title       string
+	Description string
+	Param       string
+	Value       *big.Int
+	Recipient   common.Address
+	Epoch       uint64
+}
+
+type DepositGovProposalArgs struct {
+	Id uint64
+}
+
+type VoteGovProposalArgs struct {
+	Id      uint64
+	Approve bool
+}
+
+type SignalUpgradeArgs struct {
+	Name string
+}
+
+type SetAutoCompoundArgs struct {
+	Candidate common.Address
+	Enabled   bool
+}
+
+type SetMinSelfBondArgs struct {
+	Amount *big.Int
+}
+
+type SetSecurityContactArgs struct {
+	SecurityContact string
+}
+
+type CreateBridgeClientArgs struct {
+	ClientType          string
+	ClientState         []byte
+	ConsensusState      []byte
+	Port                string
+	Channel             string
+	CounterpartyPort    string
+	CounterpartyChannel string
+}
+
+type UpdateBridgeClientArgs struct {
+	ClientId string
+	Header   []byte
+}
+
+type RecvBridgePacketArgs struct {
+	ClientId    string
+	Packet      []byte
+	ProofHeight uint64
+	Proof       []byte
+}
+
+type SendBridgePacketArgs struct {
+	ClientId         string
+	Denom            string
+	Amount           *big.Int
+	Receiver         string
+	TimeoutHeight    uint64
+	TimeoutTimestamp uint64
+}
+
+type AcknowledgeBridgePacketArgs struct {
+	ClientId        string
+	Packet          []byte
+	Acknowledgement []byte
+	ProofHeight     uint64
+	Proof           []byte
+}
+
+type RegisterAssetArgs struct {
+	Symbol      string
+	OriginChain string
+	Contract    common.Address
+}
+
+type SetAssetContractArgs struct {
+	AssetId  string
+	Chain    string
+	Contract common.Address
+}
+
+type SubmitEthCheckpointArgs struct {
+	Hash   common.Hash
+	Number uint64
+}
+
 const jsonChainABI = `
 [
 	{
@@ -361,6 +582,25 @@ const jsonChainABI = `
 			}
 		]
 	},
+	{
+		"type": "function",
+		"name": "ChallengeWithdrawal",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "txHash",
+				"type": "bytes32"
+			},
+			{
+				"name": "proof",
+				"type": "bytes"
+			},
+			{
+				"name": "header",
+				"type": "bytes"
+			}
+		]
+	},
 	{
 		"type": "function",
 		"name": "SaveDataToMainChain",
@@ -522,9 +762,367 @@ const jsonChainABI = `
 				"type": "uint8"
 			}
 		]
+	},
+	{
+		"type": "function",
+		"name": "RotateConsensusKey",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "pubkey",
+				"type": "bytes"
+			},
+			{
+				"name": "signature",
+				"type": "bytes"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SubmitGovProposal",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "title",
+				"type": "string"
+			},
+			{
+				"name": "description",
+				"type": "string"
+			},
+			{
+				"name": "param",
+				"type": "string"
+			},
+			{
+				"name": "value",
+				"type": "uint256"
+			},
+			{
+				"name": "recipient",
+				"type": "address"
+			},
+			{
+				"name": "epoch",
+				"type": "uint64"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "VoteGovProposal",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "id",
+				"type": "uint64"
+			},
+			{
+				"name": "approve",
+				"type": "bool"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SignalUpgrade",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "name",
+				"type": "string"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "DepositGovProposal",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "id",
+				"type": "uint64"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SetAutoCompound",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "candidate",
+				"type": "address"
+			},
+			{
+				"name": "enabled",
+				"type": "bool"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SetMinSelfBond",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "amount",
+				"type": "uint256"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SetSecurityContact",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "securityContact",
+				"type": "string"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "CreateBridgeClient",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "clientType",
+				"type": "string"
+			},
+			{
+				"name": "clientState",
+				"type": "bytes"
+			},
+			{
+				"name": "consensusState",
+				"type": "bytes"
+			},
+			{
+				"name": "port",
+				"type": "string"
+			},
+			{
+				"name": "channel",
+				"type": "string"
+			},
+			{
+				"name": "counterpartyPort",
+				"type": "string"
+			},
+			{
+				"name": "counterpartyChannel",
+				"type": "string"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "UpdateBridgeClient",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "clientId",
+				"type": "string"
+			},
+			{
+				"name": "header",
+				"type": "bytes"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "RecvBridgePacket",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "clientId",
+				"type": "string"
+			},
+			{
+				"name": "packet",
+				"type": "bytes"
+			},
+			{
+				"name": "proofHeight",
+				"type": "uint64"
+			},
+			{
+				"name": "proof",
+				"type": "bytes"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SendBridgePacket",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "clientId",
+				"type": "string"
+			},
+			{
+				"name": "denom",
+				"type": "string"
+			},
+			{
+				"name": "amount",
+				"type": "uint256"
+			},
+			{
+				"name": "receiver",
+				"type": "string"
+			},
+			{
+				"name": "timeoutHeight",
+				"type": "uint64"
+			},
+			{
+				"name": "timeoutTimestamp",
+				"type": "uint64"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "AcknowledgeBridgePacket",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "clientId",
+				"type": "string"
+			},
+			{
+				"name": "packet",
+				"type": "bytes"
+			},
+			{
+				"name": "acknowledgement",
+				"type": "bytes"
+			},
+			{
+				"name": "proofHeight",
+				"type": "uint64"
+			},
+			{
+				"name": "proof",
+				"type": "bytes"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "RegisterAsset",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "symbol",
+				"type": "string"
+			},
+			{
+				"name": "originChain",
+				"type": "string"
+			},
+			{
+				"name": "contract",
+				"type": "address"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SetAssetContract",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "assetId",
+				"type": "string"
+			},
+			{
+				"name": "chain",
+				"type": "string"
+			},
+			{
+				"name": "contract",
+				"type": "address"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SubmitEthCheckpoint",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "hash",
+				"type": "bytes32"
+			},
+			{
+				"name": "number",
+				"type": "uint64"
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "CommissionChange",
+		"inputs": [
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "oldCommission",
+				"type": "uint8"
+			},
+			{
+				"name": "commission",
+				"type": "uint8"
+			},
+			{
+				"name": "epoch",
+				"type": "uint64"
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "BridgePacketSent",
+		"inputs": [
+			{
+				"name": "sender",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "clientId",
+				"type": "string"
+			},
+			{
+				"name": "sequence",
+				"type": "uint64"
+			},
+			{
+				"name": "packet",
+				"type": "bytes"
+			}
+		]
 	}
 ]`
 
+// CommissionChangeEvent is logged by the chain contract when a candidate
+// sets its commission, with the epoch it takes effect from.
+const CommissionChangeEvent = "CommissionChange"
+
+// BridgePacketSentEvent is logged by the chain contract when a packet is sent
+// through a bridge client, for the relayers to deliver it.
+const BridgePacketSentEvent = "BridgePacketSent"
+
 // Neatio Side Chain Token Incentive Address
 var SideChainTokenIncentiveAddr = common.StringToAddress("NEATEEEEEEEEEEEEEEEEEEEEEEEEEEEE")
 
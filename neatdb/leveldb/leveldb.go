@@ -68,6 +68,7 @@ type Database struct {
 	writeDelayMeter  metrics.Meter // Meter for measuring the write delay duration due to database compaction
 	diskReadMeter    metrics.Meter // Meter for measuring the effective amount of data read
 	diskWriteMeter   metrics.Meter // Meter for measuring the effective amount of data written
+	diskSizeGauge    metrics.Gauge // Gauge for tracking the size of all the levels in the database
 
 	quitLock sync.Mutex      // Mutex protecting the quit channel access
 	quitChan chan chan error // Quit channel to stop the metrics collection before closing the database
@@ -113,6 +114,7 @@ func New(file string, cache int, handles int, namespace string) (*Database, erro
 	ldb.compWriteMeter = metrics.NewRegisteredMeter(namespace+"compact/output", nil)
 	ldb.diskReadMeter = metrics.NewRegisteredMeter(namespace+"disk/read", nil)
 	ldb.diskWriteMeter = metrics.NewRegisteredMeter(namespace+"disk/write", nil)
+	ldb.diskSizeGauge = metrics.NewRegisteredGauge(namespace+"disk/size", nil)
 	ldb.writeDelayMeter = metrics.NewRegisteredMeter(namespace+"compact/writedelay/duration", nil)
 	ldb.writeDelayNMeter = metrics.NewRegisteredMeter(namespace+"compact/writedelay/counter", nil)
 
@@ -205,6 +207,12 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
 }
 
+// LDB retrieves the inner LevelDB instance, e.g. to take a consistent
+// snapshot of it.
+func (db *Database) LDB() *leveldb.DB {
+	return db.db
+}
+
 // Path returns the path to the database directory.
 func (db *Database) Path() string {
 	return db.fn
@@ -272,11 +280,15 @@ func (db *Database) meter(refresh time.Duration) {
 		for j := 0; j < len(compactions[i%2]); j++ {
 			compactions[i%2][j] = 0
 		}
+		var size float64
 		for _, line := range lines {
 			parts := strings.Split(line, "|")
 			if len(parts) != 6 {
 				break
 			}
+			if value, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64); err == nil {
+				size += value
+			}
 			for idx, counter := range parts[3:] {
 				value, err := strconv.ParseFloat(strings.TrimSpace(counter), 64)
 				if err != nil {
@@ -288,6 +300,9 @@ func (db *Database) meter(refresh time.Duration) {
 			}
 		}
 		// Update all the requested meters
+		if db.diskSizeGauge != nil {
+			db.diskSizeGauge.Update(int64(size * 1024 * 1024))
+		}
 		if db.compTimeMeter != nil {
 			db.compTimeMeter.Mark(int64((compactions[i%2][0] - compactions[(i-1)%2][0]) * 1000 * 1000 * 1000))
 		}
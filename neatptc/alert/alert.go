@@ -0,0 +1,198 @@
+// Package alert delivers notifications about consensus anomalies to a webhook
+// or a local command, for simple alerting without a monitoring stack.
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/log"
+)
+
+const (
+	queueSize       = 64               // Alerts waiting for delivery before new ones are dropped
+	deliveryTimeout = 10 * time.Second // Limit of a webhook request or a command
+)
+
+// Config configures when and where alerts are sent.
+type Config struct {
+	Webhook string // URL the alerts are posted to as JSON
+	Command string // Shell command run for each alert, with the alert as JSON on stdin
+
+	StallThreshold time.Duration // Time without a new block before alerting, 0 = off
+	RoundThreshold int           // Highest round a block may commit in without alerting, 0 = off
+	MissedCommits  uint64        // Number of consecutive commits without our precommit before alerting, 0 = off
+}
+
+// Enabled reports whether alerts have a destination.
+func (c *Config) Enabled() bool {
+	return c.Webhook != "" || c.Command != ""
+}
+
+// Alert types
+const (
+	TypeStall         = "stall"
+	TypeRound         = "round"
+	TypeMissedCommits = "missed_commits"
+)
+
+// Alert is delivered when an anomaly is detected and again, with Resolved
+// set, once it is over.
+type Alert struct {
+	Chain    string    `json:"chain"`
+	Type     string    `json:"type"`
+	Resolved bool      `json:"resolved"`
+	Message  string    `json:"message"`
+	Number   uint64    `json:"number"` // Head block at the time of the alert
+	Time     time.Time `json:"time"`
+}
+
+// Notifier delivers alerts in the background, so detecting anomalies never
+// waits on a slow webhook or command.
+type Notifier struct {
+	chain  string
+	config Config
+	client *http.Client
+	queue  chan *Alert
+	quit   chan struct{}
+	logger log.Logger
+
+	active map[string]bool // Alert types raised and not resolved yet
+	lock   sync.Mutex
+}
+
+// NewNotifier starts delivering the alerts of a chain to the destinations of
+// the config.
+func NewNotifier(chain string, config Config, logger log.Logger) *Notifier {
+	n := &Notifier{
+		chain:  chain,
+		config: config,
+		client: &http.Client{Timeout: deliveryTimeout},
+		queue:  make(chan *Alert, queueSize),
+		quit:   make(chan struct{}),
+		logger: logger,
+		active: make(map[string]bool),
+	}
+	go n.loop()
+	return n
+}
+
+// Update reports the state of an anomaly. An alert is raised when the anomaly
+// starts and resolved when it ends, repeated reports in between are ignored.
+func (n *Notifier) Update(typ string, anomaly bool, number uint64, message string) {
+	n.lock.Lock()
+	changed := n.active[typ] != anomaly
+	n.active[typ] = anomaly
+	n.lock.Unlock()
+
+	if changed {
+		n.notify(&Alert{
+			Chain:    n.chain,
+			Type:     typ,
+			Resolved: !anomaly,
+			Message:  message,
+			Number:   number,
+			Time:     time.Now(),
+		})
+	}
+}
+
+// notify queues an alert for delivery.
+func (n *Notifier) notify(alert *Alert) {
+	if alert.Resolved {
+		n.logger.Info("Alert resolved", "type", alert.Type, "number", alert.Number, "msg", alert.Message)
+	} else {
+		n.logger.Warn("Alert raised", "type", alert.Type, "number", alert.Number, "msg", alert.Message)
+	}
+	select {
+	case n.queue <- alert:
+	default:
+		n.logger.Warn("Alert queue full, dropping alert", "type", alert.Type)
+	}
+}
+
+// Stop terminates the delivery, dropping the alerts still queued.
+func (n *Notifier) Stop() {
+	close(n.quit)
+}
+
+func (n *Notifier) loop() {
+	for {
+		select {
+		case alert := <-n.queue:
+			if err := n.deliver(alert); err != nil {
+				n.logger.Warn("Failed to deliver alert", "type", alert.Type, "err", err)
+			}
+		case <-n.quit:
+			return
+		}
+	}
+}
+
+func (n *Notifier) deliver(alert *Alert) error {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	if n.config.Webhook != "" {
+		if err := n.post(payload); err != nil {
+			return err
+		}
+	}
+	if n.config.Command != "" {
+		if err := n.run(alert, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *Notifier) post(payload []byte) error {
+	resp, err := n.client.Post(n.config.Webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook replied %s", resp.Status)
+	}
+	return nil
+}
+
+// run executes the command with the alert as JSON on stdin, the main fields
+// are also passed in NEATIO_ALERT_* environment variables.
+func (n *Notifier) run(alert *Alert, payload []byte) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", n.config.Command)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", n.config.Command)
+	}
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"NEATIO_ALERT_CHAIN="+alert.Chain,
+		"NEATIO_ALERT_TYPE="+alert.Type,
+		fmt.Sprintf("NEATIO_ALERT_RESOLVED=%t", alert.Resolved),
+		"NEATIO_ALERT_MESSAGE="+alert.Message,
+		fmt.Sprintf("NEATIO_ALERT_NUMBER=%d", alert.Number),
+	)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(deliveryTimeout):
+		cmd.Process.Kill()
+		return fmt.Errorf("command timed out after %v", deliveryTimeout)
+	}
+}
@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/neatlab/neatio/log"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	alerts := make(chan *Alert, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alert := new(Alert)
+		if err := json.NewDecoder(r.Body).Decode(alert); err != nil {
+			t.Errorf("invalid alert: %v", err)
+		}
+		alerts <- alert
+	}))
+	defer server.Close()
+
+	n := NewNotifier("test", Config{Webhook: server.URL}, log.New())
+	defer n.Stop()
+
+	// Only the changes of an anomaly are delivered
+	n.Update(TypeStall, false, 1, "block imported")
+	n.Update(TypeStall, true, 1, "no block")
+	n.Update(TypeStall, true, 1, "no block")
+	n.Update(TypeStall, false, 2, "block imported")
+
+	for i, want := range []struct {
+		resolved bool
+		number   uint64
+	}{{false, 1}, {true, 2}} {
+		select {
+		case alert := <-alerts:
+			if alert.Chain != "test" || alert.Type != TypeStall || alert.Resolved != want.resolved || alert.Number != want.number {
+				t.Errorf("alert %d mismatch: %+v", i, alert)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("alert %d not delivered", i)
+		}
+	}
+	select {
+	case alert := <-alerts:
+		t.Errorf("unexpected alert: %+v", alert)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,105 @@
+package neatptc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	ncTypes "github.com/neatlab/neatio/consensus/neatpos/types"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/neatptc/alert"
+)
+
+// watchAlerts checks every new block for consensus anomalies and raises the
+// configured alerts, until the chain is stopped.
+func (s *NeatChain) watchAlerts() {
+	config := s.config.Alerts
+	notifier := alert.NewNotifier(s.chainConfig.NeatChainId, config, s.chainConfig.ChainLogger)
+	defer notifier.Stop()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	headSub := s.blockchain.SubscribeChainHeadEvent(heads)
+	defer headSub.Unsubscribe()
+
+	// The stall timer is stopped while a stall alert is raised, a nil channel
+	// never fires if the alert is disabled.
+	var (
+		stallTimer *time.Timer
+		stall      <-chan time.Time
+	)
+	if config.StallThreshold > 0 {
+		stallTimer = time.NewTimer(config.StallThreshold)
+		defer stallTimer.Stop()
+		stall = stallTimer.C
+	}
+
+	for {
+		select {
+		case head := <-heads:
+			number := head.Block.NumberU64()
+			if stallTimer != nil {
+				if !stallTimer.Stop() {
+					select {
+					case <-stallTimer.C:
+					default:
+					}
+				}
+				stallTimer.Reset(config.StallThreshold)
+				notifier.Update(alert.TypeStall, false, number, fmt.Sprintf("block %d imported", number))
+			}
+			if config.RoundThreshold > 0 {
+				s.checkCommitRound(notifier, head.Block)
+			}
+			if config.MissedCommits > 0 {
+				s.checkMissedCommits(notifier, number)
+			}
+
+		case <-stall:
+			number := s.blockchain.CurrentBlock().NumberU64()
+			notifier.Update(alert.TypeStall, true, number, fmt.Sprintf("no block for %v", config.StallThreshold))
+
+		case <-headSub.Err():
+			return
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
+
+// checkCommitRound alerts if the block needed more rounds than configured.
+func (s *NeatChain) checkCommitRound(notifier *alert.Notifier, block *types.Block) {
+	ncExtra, err := ncTypes.ExtractNeatconExtra(block.Header())
+	if err != nil || ncExtra.SeenCommit == nil {
+		return
+	}
+	round := ncExtra.SeenCommit.Round
+	notifier.Update(alert.TypeRound, round > s.config.Alerts.RoundThreshold, block.NumberU64(),
+		fmt.Sprintf("block %d committed in round %d, threshold %d", block.NumberU64(), round, s.config.Alerts.RoundThreshold))
+}
+
+// checkMissedCommits alerts if none of the last commits carries the precommit
+// of this node, provided it is a validator of the current epoch.
+func (s *NeatChain) checkMissedCommits(notifier *alert.Notifier, number uint64) {
+	window := s.config.Alerts.MissedCommits
+	neatpos, ok := s.engine.(consensus.NeatPoS)
+	if !ok || number < window {
+		return
+	}
+	address := neatpos.PrivateValidator()
+	if ep := neatpos.GetEpoch(); ep == nil || address == (common.Address{}) || !ep.Validators.HasAddress(address.Bytes()) {
+		notifier.Update(alert.TypeMissedCommits, false, number, "not a validator of the current epoch")
+		return
+	}
+	signed, err := neatpos.LastSignedBlock(address, number-window)
+	if err != nil {
+		s.chainConfig.ChainLogger.Debug("Failed to check own precommits", "err", err)
+		return
+	}
+	if signed == 0 {
+		notifier.Update(alert.TypeMissedCommits, true, number, fmt.Sprintf("precommit missing from the last %d commits", window))
+	} else {
+		notifier.Update(alert.TypeMissedCommits, false, number, fmt.Sprintf("precommit included in block %d", signed))
+	}
+}
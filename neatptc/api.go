@@ -24,6 +24,7 @@ import (
 	"io"
 	"math/big"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/neatlab/neatio/common"
@@ -292,6 +293,9 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 }
 
 func (api *PrivateAdminAPI) PruneStateData(height *hexutil.Uint64) (bool, error) {
+	if api.eth.chainConfig.IsMainChain() && !api.eth.config.PruneMainChain {
+		return false, errors.New("pruning of the main chain is not enabled")
+	}
 	var blockNumber uint64
 	if height != nil && *height > 0 {
 		blockNumber = uint64(*height)
@@ -307,6 +311,15 @@ func (api *PrivateAdminAPI) LatestPruneState() (*datareduction.PruneStatus, erro
 	return status, nil
 }
 
+// Backup writes an online backup of the chain database, the keystore and the
+// priv validator into dir, see NeatChain.Backup.
+func (api *PrivateAdminAPI) Backup(dir string, incremental *bool) (*BackupResult, error) {
+	if !filepath.IsAbs(dir) {
+		return nil, errors.New("backup directory must be an absolute path")
+	}
+	return api.eth.Backup(dir, incremental != nil && *incremental)
+}
+
 // PublicDebugAPI is the collection of NeatChain full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -411,11 +424,19 @@ func (api *PrivateDebugAPI) FindBadPreimage(ctx context.Context) (interface{}, e
 }
 
 // GetBadBLocks returns a list of the last 'bad blocks' that the client has seen on the network
-// and returns them as a JSON list of block-hashes
+// and returns them as a JSON list, each with the block RLP, the expected and actual state roots,
+// the receipts of its execution and the validation error
 func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]core.BadBlockArgs, error) {
 	return api.eth.BlockChain().BadBlocks()
 }
 
+// DryRunProposal builds a block on top of the current head from the pending
+// transactions, without sealing or broadcasting it, and reports which
+// transactions were included and why the others were skipped.
+func (api *PrivateDebugAPI) DryRunProposal(ctx context.Context) (*miner.ProposalResult, error) {
+	return api.eth.Miner().DryRunProposal()
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
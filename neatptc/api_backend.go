@@ -30,6 +30,7 @@ import (
 	"github.com/neatlab/neatio/core/types"
 	"github.com/neatlab/neatio/core/vm"
 	"github.com/neatlab/neatio/event"
+	"github.com/neatlab/neatio/internal/neatapi"
 	"github.com/neatlab/neatio/neatdb"
 	"github.com/neatlab/neatio/neatptc/downloader"
 	"github.com/neatlab/neatio/neatptc/gasprice"
@@ -123,11 +124,13 @@ func (b *EthApiBackend) GetTd(blockHash common.Hash) *big.Int {
 	return b.eth.blockchain.GetTdByHash(blockHash)
 }
 
-func (b *EthApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+func (b *EthApiBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, overrides *neatapi.BlockOverrides, vmCfg vm.Config) (*vm.EVM, func() error, error) {
 	state.SetBalance(msg.From(), math.MaxBig256)
 	vmError := func() error { return nil }
 
+	// The overridden block number selects the rules of the EVM
 	context := core.NewEVMContext(msg, header, b.eth.BlockChain(), nil)
+	overrides.Apply(&context)
 	return vm.NewEVM(context, state, b.eth.chainConfig, vmCfg), vmError, nil
 }
 
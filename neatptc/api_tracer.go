@@ -28,6 +28,7 @@ import (
 
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/common/math"
 	"github.com/neatlab/neatio/core"
 	"github.com/neatlab/neatio/core/rawdb"
 	"github.com/neatlab/neatio/core/state"
@@ -60,6 +61,12 @@ type TraceConfig struct {
 	Reexec  *uint64
 }
 
+// TraceCallConfig holds extra parameters to call tracing functions.
+type TraceCallConfig struct {
+	TraceConfig
+	BlockOverrides *neatapi.BlockOverrides
+}
+
 // StdTraceConfig holds extra parameters to standard-json trace functions.
 type StdTraceConfig struct {
 	*vm.LogConfig
@@ -680,6 +687,29 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// TraceCall lets you trace a given eth_call. It collects the structured logs created
+// during the execution of EVM if the given transaction was added on top of the
+// provided block and returns them as a JSON object. The block fields may be
+// overridden through the config, as for eth_call.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args neatapi.CallArgs, blockNr rpc.BlockNumber, config *TraceCallConfig) (interface{}, error) {
+	statedb, header, err := api.eth.ApiBackend.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	msg := args.ToMessage(api.eth.AccountManager())
+
+	// As for eth_call, the sender can pay for all the gas
+	statedb.SetBalance(msg.From(), math.MaxBig256)
+	vmctx := core.NewEVMContext(msg, header, api.eth.blockchain, nil)
+
+	var traceConfig *TraceConfig
+	if config != nil {
+		config.BlockOverrides.Apply(&vmctx)
+		traceConfig = &config.TraceConfig
+	}
+	return api.traceTx(ctx, msg, vmctx, statedb, traceConfig)
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
@@ -0,0 +1,96 @@
+package neatptc
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+)
+
+const (
+	defaultTxSearchPerPage = 30
+	maxTxSearchPerPage     = 100
+)
+
+var errTxSearchDisabled = errors.New("transaction search disabled, the node must be started with --txsearch")
+
+// PublicTxSearchAPI searches the transactions of the chain by tag.
+type PublicTxSearchAPI struct {
+	eth *NeatChain
+}
+
+// NewPublicTxSearchAPI creates the transaction search API of the chain.
+func NewPublicTxSearchAPI(eth *NeatChain) *PublicTxSearchAPI {
+	return &PublicTxSearchAPI{eth: eth}
+}
+
+// TxSearchResult is a page of the transactions matching a query.
+type TxSearchResult struct {
+	Txs        []*TxSearchTransaction `json:"txs"`
+	TotalCount int                    `json:"totalCount"`
+}
+
+// TxSearchTransaction is a transaction matching a query, with its position in
+// the chain.
+type TxSearchTransaction struct {
+	Hash             common.Hash        `json:"hash"`
+	BlockHash        common.Hash        `json:"blockHash"`
+	BlockNumber      hexutil.Uint64     `json:"blockNumber"`
+	TransactionIndex hexutil.Uint       `json:"transactionIndex"`
+	From             common.Address     `json:"from"`
+	Tx               *types.Transaction `json:"tx"`
+}
+
+// TxSearch returns the transactions matching a query in the order of the
+// chain, paginated with perPage transactions per page. The query is made of
+// conditions on the tags of the transactions joined by AND, like
+//
+//	sender = 'NEAT...' AND tx.height >= 100
+//
+// The transactions are tagged with tx.hash, tx.height, sender, and for the
+// special transactions with cross_chain.dest and validator.address.
+func (api *PublicTxSearchAPI) TxSearch(query string, page, perPage *int) (*TxSearchResult, error) {
+	if api.eth.txIndex == nil {
+		return nil, errTxSearchDisabled
+	}
+	pageNr, size := 1, defaultTxSearchPerPage
+	if page != nil {
+		pageNr = *page
+	}
+	if perPage != nil {
+		size = *perPage
+	}
+	if pageNr < 1 {
+		return nil, fmt.Errorf("invalid page %d", pageNr)
+	}
+	if size < 1 || size > maxTxSearchPerPage {
+		return nil, fmt.Errorf("invalid number of transactions per page %d, must be between 1 and %d", size, maxTxSearchPerPage)
+	}
+
+	results, total, err := api.eth.txIndex.Search(query, (pageNr-1)*size, size)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*TxSearchTransaction, 0, len(results))
+	for _, result := range results {
+		tx, blockHash, _, _ := rawdb.ReadTransaction(api.eth.chainDb, result.Hash)
+		if tx == nil {
+			continue
+		}
+		signer := types.MakeSigner(api.eth.chainConfig, new(big.Int).SetUint64(result.Height))
+		from, _ := types.Sender(signer, tx)
+		txs = append(txs, &TxSearchTransaction{
+			Hash:             result.Hash,
+			BlockHash:        blockHash,
+			BlockNumber:      hexutil.Uint64(result.Height),
+			TransactionIndex: hexutil.Uint(result.Index),
+			From:             from,
+			Tx:               tx,
+		})
+	}
+	return &TxSearchResult{Txs: txs, TotalCount: total}, nil
+}
@@ -24,6 +24,7 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/neatlab/neatio/accounts"
 	"github.com/neatlab/neatio/common"
@@ -35,6 +36,7 @@ import (
 	"github.com/neatlab/neatio/core/bloombits"
 	"github.com/neatlab/neatio/core/datareduction"
 	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/txindex"
 	"github.com/neatlab/neatio/core/types"
 	"github.com/neatlab/neatio/core/vm"
 	"github.com/neatlab/neatio/event"
@@ -44,6 +46,7 @@ import (
 	"github.com/neatlab/neatio/neatdb"
 	"github.com/neatlab/neatio/neatptc/downloader"
 	"github.com/neatlab/neatio/neatptc/filters"
+	"github.com/neatlab/neatio/neatptc/firehose"
 	"github.com/neatlab/neatio/neatptc/gasprice"
 	"github.com/neatlab/neatio/node"
 	"github.com/neatlab/neatio/p2p"
@@ -83,6 +86,8 @@ type NeatChain struct {
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	txIndex       *txindex.Index                 // Transaction tag index, if enabled
+	firehose      *firehose.Streamer             // Streamer of the executed blocks, if enabled
 
 	ApiBackend *EthApiBackend
 
@@ -94,6 +99,10 @@ type NeatChain struct {
 	networkId     uint64
 	netRPCService *neatapi.PublicNetAPI
 
+	keyStoreDir       string // Copied by admin_backup
+	privValidatorFile string
+	profileDir        string // Stall profiles are written here
+
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
 }
 
@@ -105,11 +114,11 @@ func New(ctx *node.ServiceContext, config *Config, cliCtx *cli.Context,
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
-	chainDb, err := ctx.OpenDatabase("chaindata", config.DatabaseCache, config.DatabaseHandles, "neatio/db/chaindata/")
+	chainDb, err := ctx.OpenDatabase("chaindata", config.DatabaseCache, config.DatabaseHandles, ctx.ChainId()+"/db/chaindata/")
 	if err != nil {
 		return nil, err
 	}
-	pruneDb, err := ctx.OpenDatabase("prunedata", config.DatabaseCache, config.DatabaseHandles, "neatio/db/prune/")
+	pruneDb, err := ctx.OpenDatabase("prunedata", config.DatabaseCache, config.DatabaseHandles, ctx.ChainId()+"/db/prune/")
 	if err != nil {
 		return nil, err
 	}
@@ -138,6 +147,10 @@ func New(ctx *node.ServiceContext, config *Config, cliCtx *cli.Context,
 		solcPath:       config.SolcPath,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
+
+		keyStoreDir:       ctx.KeyStoreDir(),
+		privValidatorFile: neatpos.GetNeatConConfig(chainConfig.NeatChainId, cliCtx).GetString("priv_validator_file"),
+		profileDir:        ctx.ResolvePath("profiles"),
 	}
 
 	bcVersion := rawdb.ReadDatabaseVersion(chainDb)
@@ -164,6 +177,7 @@ func New(ctx *node.ServiceContext, config *Config, cliCtx *cli.Context,
 			TrieDirtyLimit:    config.TrieDirtyCache,
 			TrieDirtyDisabled: config.NoPruning,
 			TrieTimeLimit:     config.TrieTimeout,
+			PipelinedCommit:   config.PipelinedCommit,
 		}
 	)
 	//eth.engine = CreateConsensusEngine(ctx, config, chainConfig, chainDb, cliCtx, cch)
@@ -181,6 +195,22 @@ func New(ctx *node.ServiceContext, config *Config, cliCtx *cli.Context,
 	}
 	neatChain.bloomIndexer.Start(neatChain.blockchain)
 
+	if config.TxSearch {
+		mainChain := params.MainnetChainConfig.NeatChainId
+		if isTestnet {
+			mainChain = params.TestnetChainConfig.NeatChainId
+		}
+		neatChain.txIndex = txindex.New(chainDb, chainConfig, mainChain)
+		neatChain.txIndex.Start(neatChain.blockchain)
+	}
+	if config.Firehose != "" {
+		output, err := firehose.Open(config.Firehose)
+		if err != nil {
+			return nil, err
+		}
+		neatChain.firehose = firehose.NewStreamer(ctx.ChainId(), neatChain.blockchain, output, config.FirehoseStart)
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
@@ -269,6 +299,11 @@ func (s *NeatChain) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   downloader.NewPublicDownloaderAPI(s.protocolManager.downloader, s.eventMux),
 			Public:    true,
+		}, {
+			Namespace: "neat",
+			Version:   "1.0",
+			Service:   NewPublicTxSearchAPI(s),
+			Public:    true,
 		}, {
 			Namespace: "miner",
 			Version:   "1.0",
@@ -395,6 +430,7 @@ func (s *NeatChain) IsListening() bool                  { return true } // Alway
 func (s *NeatChain) EthVersion() int                    { return int(s.protocolManager.SubProtocols[0].Version) }
 func (s *NeatChain) NetVersion() uint64                 { return s.networkId }
 func (s *NeatChain) Downloader() *downloader.Downloader { return s.protocolManager.downloader }
+func (s *NeatChain) ShutdownTimeout() time.Duration     { return s.config.ShutdownTimeout }
 
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
@@ -420,8 +456,20 @@ func (s *NeatChain) Start(srvr *p2p.Server) error {
 	// Start the Auto Mining Loop
 	go s.loopForMiningEvent()
 
+	s.registerChainMetrics()
+
+	if s.config.StallProfileThreshold > 0 {
+		go s.profileStalls()
+	}
+	if s.config.Alerts.Enabled() {
+		go s.watchAlerts()
+	}
+	if s.firehose != nil {
+		s.firehose.Start()
+	}
+
 	// Start the Data Reduction
-	if s.config.PruneStateData && s.chainConfig.NeatChainId == "side_0" {
+	if s.config.PruneEnabled(s.chainConfig) {
 		go s.StartScanAndPrune(0)
 	}
 
@@ -431,11 +479,28 @@ func (s *NeatChain) Start(srvr *p2p.Server) error {
 // Stop implements node.Service, terminating all internal goroutines used by the
 // NeatChain protocol.
 func (s *NeatChain) Stop() error {
+	// Let the block this node is proposing get committed before leaving the
+	// consensus, half of the shutdown time is kept for flushing the state.
+	if neatpos, ok := s.engine.(consensus.NeatPoS); ok && s.config.ShutdownTimeout > 0 {
+		if !neatpos.WaitForProposedBlock(s.config.ShutdownTimeout / 2) {
+			s.chainConfig.ChainLogger.Warn("Proposed block not committed before shutdown")
+		}
+	}
+	s.unregisterChainMetrics()
+	s.miner.Stop()
+	s.protocolManager.Stop()
+
+	// No new blocks or transactions arrive anymore, flush the tries and the
+	// transaction journal.
+	if s.firehose != nil {
+		s.firehose.Stop()
+	}
 	s.bloomIndexer.Close()
+	if s.txIndex != nil {
+		s.txIndex.Close()
+	}
 	s.blockchain.Stop()
-	s.protocolManager.Stop()
 	s.txPool.Stop()
-	s.miner.Stop()
 	s.engine.Close()
 	s.miner.Close()
 	s.eventMux.Stop()
@@ -489,8 +554,8 @@ func (s *NeatChain) loopForMiningEvent() {
 
 func (s *NeatChain) StartScanAndPrune(blockNumber uint64) {
 
-	if datareduction.StartPruning() {
-		log.Info("Data Reduction - Start")
+	if datareduction.StartPruning(s.pruneDb) {
+		log.Info("Data Reduction - Start", "chain", s.chainConfig.NeatChainId, "retention", s.config.PruneRetention, "blocks", s.config.PruneBlockData)
 	} else {
 		log.Info("Data Reduction - Pruning is already running")
 		return
@@ -517,7 +582,7 @@ func (s *NeatChain) StartScanAndPrune(blockNumber uint64) {
 	}
 	log.Infof("Data Reduction - Last scan number %v, prune number %v", scanNumber, pruneNumber)
 
-	pruneProcessor := datareduction.NewPruneProcessor(s.chainDb, s.pruneDb, s.blockchain, s.config.PruneBlockData)
+	pruneProcessor := datareduction.NewPruneProcessor(s.chainDb, s.pruneDb, s.blockchain, s.config.PruneBlockData, s.config.PruneRetention)
 
 	lastScanNumber, lastPruneNumber := pruneProcessor.Process(blockNumber, scanNumber, pruneNumber)
 	log.Infof("Data Reduction - After prune, last number scan %v, prune number %v", lastScanNumber, lastPruneNumber)
@@ -529,5 +594,5 @@ func (s *NeatChain) StartScanAndPrune(blockNumber uint64) {
 	}
 	log.Info("Data Reduction - Completed")
 
-	datareduction.StopPruning()
+	datareduction.StopPruning(s.pruneDb)
 }
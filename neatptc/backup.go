@@ -0,0 +1,248 @@
+package neatptc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+const backupManifestFile = "backup.json"
+
+// BackupManifest is written last into a backup directory, a backup without it
+// is incomplete.
+type BackupManifest struct {
+	ChainId string         `json:"chainId"`
+	Number  hexutil.Uint64 `json:"number"`
+	Hash    common.Hash    `json:"hash"`
+	Time    time.Time      `json:"time"`
+}
+
+// BackupResult describes a backup written by Backup.
+type BackupResult struct {
+	BackupManifest
+	Dir     string `json:"dir"`
+	Written int    `json:"written"`
+	Deleted int    `json:"deleted"`
+	Files   int    `json:"files"`
+	Elapsed string `json:"elapsed"`
+}
+
+// leveldbSource is implemented by the LevelDB backed chain and epoch
+// databases.
+type leveldbSource interface {
+	LDB() *leveldb.DB
+}
+
+type goLevelDBSource interface {
+	DB() *leveldb.DB
+}
+
+// Backup copies the chain database, the epoch database, the keystore and the
+// priv validator of the chain into dir/<chainId>. The databases are read from
+// LevelDB snapshots, so the copy is consistent while the node keeps running:
+// restoring it is equivalent to restarting the node after a crash at the time
+// of the snapshot. With incremental set an earlier backup in dir is updated
+// in place, only changed entries and files are written.
+func (s *NeatChain) Backup(dir string, incremental bool) (*BackupResult, error) {
+	start := time.Now()
+	chainId := s.chainConfig.NeatChainId
+	out := filepath.Join(dir, chainId)
+	if _, err := os.Stat(out); err == nil && !incremental {
+		return nil, fmt.Errorf("backup %s already exists", out)
+	}
+	if err := os.MkdirAll(out, 0700); err != nil {
+		return nil, err
+	}
+	// Invalidate an earlier backup until this one is complete
+	os.Remove(filepath.Join(out, backupManifestFile))
+
+	chainSrc, ok := s.chainDb.(leveldbSource)
+	if !ok {
+		return nil, errors.New("chain database does not support snapshots")
+	}
+	var epochDB *leveldb.DB
+	if src, ok := s.engine.GetEpoch().GetDB().(goLevelDBSource); ok {
+		epochDB = src.DB()
+	} else {
+		return nil, errors.New("epoch database does not support snapshots")
+	}
+
+	chainSnap, err := chainSrc.LDB().GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer chainSnap.Release()
+	epochSnap, err := epochDB.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer epochSnap.Release()
+
+	result := &BackupResult{Dir: out}
+	result.ChainId = chainId
+	if hash, err := chainSnap.Get(headBlockKey, nil); err == nil {
+		result.Hash = common.BytesToHash(hash)
+		if number := rawdb.ReadHeaderNumber(s.chainDb, result.Hash); number != nil {
+			result.Number = hexutil.Uint64(*number)
+		}
+	}
+
+	for _, target := range []struct {
+		snap *leveldb.Snapshot
+		path string
+	}{
+		{chainSnap, filepath.Join(out, "chaindata")},
+		{epochSnap, filepath.Join(out, "epoch.db")},
+	} {
+		written, deleted, err := syncSnapshot(target.snap, target.path)
+		if err != nil {
+			return nil, fmt.Errorf("backup of %s failed: %v", target.path, err)
+		}
+		result.Written += written
+		result.Deleted += deleted
+	}
+
+	if s.keyStoreDir != "" {
+		files, err := syncFiles(s.keyStoreDir, filepath.Join(out, "keystore"))
+		if err != nil {
+			return nil, fmt.Errorf("backup of the keystore failed: %v", err)
+		}
+		result.Files += files
+	}
+	if s.privValidatorFile != "" {
+		if _, err := os.Stat(s.privValidatorFile); err == nil {
+			copied, err := syncFile(s.privValidatorFile, filepath.Join(out, filepath.Base(s.privValidatorFile)))
+			if err != nil {
+				return nil, fmt.Errorf("backup of the priv validator failed: %v", err)
+			}
+			if copied {
+				result.Files++
+			}
+		}
+	}
+
+	result.Time = time.Now()
+	manifest, err := json.MarshalIndent(&result.BackupManifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(out, backupManifestFile), manifest, 0600); err != nil {
+		return nil, err
+	}
+	result.Elapsed = common.PrettyDuration(time.Since(start)).String()
+	s.chainConfig.ChainLogger.Info("Backup written", "dir", out, "number", result.Number, "written", result.Written, "deleted", result.Deleted, "files", result.Files, "elapsed", result.Elapsed)
+	return result, nil
+}
+
+// headBlockKey mirrors the key rawdb stores the hash of the head block under,
+// it is read from the snapshot rather than the live database.
+var headBlockKey = []byte("LastBlock")
+
+// syncSnapshot makes the LevelDB database at path equal to the snapshot,
+// writing only the entries that differ.
+func syncSnapshot(snap *leveldb.Snapshot, path string) (written, deleted int, err error) {
+	db, err := leveldb.OpenFile(path, &opt.Options{})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer db.Close()
+
+	batch := new(leveldb.Batch)
+	flush := func() error {
+		if batch.Len() < 1000 {
+			return nil
+		}
+		err := db.Write(batch, nil)
+		batch.Reset()
+		return err
+	}
+
+	it := snap.NewIterator(nil, nil)
+	for it.Next() {
+		if old, err := db.Get(it.Key(), nil); err == nil && bytes.Equal(old, it.Value()) {
+			continue
+		}
+		batch.Put(common.CopyBytes(it.Key()), common.CopyBytes(it.Value()))
+		written++
+		if err := flush(); err != nil {
+			it.Release()
+			return 0, 0, err
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return 0, 0, err
+	}
+
+	it = db.NewIterator(nil, nil)
+	for it.Next() {
+		if ok, _ := snap.Has(it.Key(), nil); !ok {
+			batch.Delete(common.CopyBytes(it.Key()))
+			deleted++
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return 0, 0, err
+	}
+	if batch.Len() > 0 {
+		if err := db.Write(batch, nil); err != nil {
+			return 0, 0, err
+		}
+	}
+	return written, deleted, nil
+}
+
+// syncFiles copies the regular files of src into dst, skipping the ones that
+// are already identical.
+func syncFiles(src, dst string) (int, error) {
+	entries, err := ioutil.ReadDir(src)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(dst, 0700); err != nil {
+		return 0, err
+	}
+	copied := 0
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		ok, err := syncFile(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()))
+		if err != nil {
+			return copied, err
+		}
+		if ok {
+			copied++
+		}
+	}
+	return copied, nil
+}
+
+func syncFile(src, dst string) (bool, error) {
+	content, err := ioutil.ReadFile(src)
+	if err != nil {
+		return false, err
+	}
+	if old, err := ioutil.ReadFile(dst); err == nil && bytes.Equal(old, content) {
+		return false, nil
+	}
+	tmp := dst + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0600); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp, dst)
+}
@@ -0,0 +1,74 @@
+package neatptc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+func TestSyncSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "neatio-backup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "chaindata")
+
+	src, err := leveldb.Open(storage.NewMemStorage(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	backup := func(wantWritten, wantDeleted int) {
+		t.Helper()
+		snap, err := src.GetSnapshot()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer snap.Release()
+		written, deleted, err := syncSnapshot(snap, target)
+		if err != nil {
+			t.Fatalf("backup failed: %v", err)
+		}
+		if written != wantWritten || deleted != wantDeleted {
+			t.Fatalf("written/deleted mismatch: have %d/%d, want %d/%d", written, deleted, wantWritten, wantDeleted)
+		}
+	}
+	src.Put([]byte("a"), []byte("1"), nil)
+	src.Put([]byte("b"), []byte("2"), nil)
+	src.Put([]byte("c"), []byte("3"), nil)
+	backup(3, 0)
+
+	// Only the changes are applied to the existing backup
+	backup(0, 0)
+	src.Put([]byte("b"), []byte("4"), nil)
+	src.Delete([]byte("c"), nil)
+	src.Put([]byte("d"), []byte("5"), nil)
+	backup(2, 1)
+
+	db, err := leveldb.OpenFile(target, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	want := map[string]string{"a": "1", "b": "4", "d": "5"}
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	have := make(map[string]string)
+	for it.Next() {
+		have[string(it.Key())] = string(it.Value())
+	}
+	if len(have) != len(want) {
+		t.Fatalf("entry count mismatch: have %v, want %v", have, want)
+	}
+	for k, v := range want {
+		if have[k] != v {
+			t.Errorf("entry %s mismatch: have %q, want %q", k, have[k], v)
+		}
+	}
+}
@@ -29,6 +29,8 @@ import (
 	"github.com/neatlab/neatio/common/hexutil"
 	"github.com/neatlab/neatio/consensus/neatpos"
 	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/datareduction"
+	"github.com/neatlab/neatio/neatptc/alert"
 	"github.com/neatlab/neatio/neatptc/downloader"
 	"github.com/neatlab/neatio/neatptc/gasprice"
 	"github.com/neatlab/neatio/params"
@@ -39,20 +41,27 @@ var DefaultConfig = Config{
 	//SyncMode: downloader.FastSync,
 	SyncMode: downloader.FullSync,
 	//NetworkId:      1,
-	NetworkId:      9910,
-	DatabaseCache:  512,
-	TrieCleanCache: 256,
-	TrieDirtyCache: 256,
-	TrieTimeout:    60 * time.Minute,
-	MinerGasFloor:  120000000,
-	MinerGasCeil:   120000000,
-	MinerGasPrice:  big.NewInt(params.GWei),
+	NetworkId:       9910,
+	DatabaseCache:   512,
+	TrieCleanCache:  256,
+	TrieDirtyCache:  256,
+	TrieTimeout:     60 * time.Minute,
+	ShutdownTimeout: 30 * time.Second,
+	PruneRetention:  datareduction.DefaultRetention,
+	MinerGasFloor:   120000000,
+	MinerGasCeil:    120000000,
+	MinerGasPrice:   big.NewInt(params.GWei),
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
 	},
+	Alerts: alert.Config{
+		StallThreshold: time.Minute,
+		RoundThreshold: 3,
+		MissedCommits:  10,
+	},
 }
 
 func init() {
@@ -82,6 +91,10 @@ type Config struct {
 
 	NoPruning bool // Whether to disable pruning and flush everything to disk
 
+	TxSearch bool // Whether to index the transactions by tag for neat_txSearch
+
+	PipelinedCommit bool // Whether to persist the committed blocks in the background
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
@@ -107,6 +120,9 @@ type Config struct {
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Consensus anomaly alerts, sent if a webhook or command is configured
+	Alerts alert.Config
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
@@ -119,6 +135,26 @@ type Config struct {
 	// Data Reduction options
 	PruneStateData bool
 	PruneBlockData bool
+	PruneRetention uint64 // Most recent blocks whose state, and body with PruneBlockData, are kept
+	PruneMainChain bool   // Prune the main chain too, the Data Reduction only prunes the side chains otherwise
+
+	// Time limit for stopping the node, after which it exits forcibly
+	ShutdownTimeout time.Duration
+
+	// Time without a new block after which CPU and heap profiles are captured
+	// into the profiles directory, 0 disables the profiler
+	StallProfileThreshold time.Duration
+
+	// Output the execution data of the blocks are streamed to, from the block
+	// FirehoseStart or from the next one imported if 0
+	Firehose      string `toml:",omitempty"`
+	FirehoseStart uint64 `toml:",omitempty"`
+}
+
+// PruneEnabled tells whether the Data Reduction runs on the chain, the main
+// chain is pruned only when PruneMainChain is set.
+func (c *Config) PruneEnabled(chainConfig *params.ChainConfig) bool {
+	return c.PruneStateData && (!chainConfig.IsMainChain() || c.PruneMainChain)
 }
 
 type configMarshaling struct {
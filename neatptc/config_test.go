@@ -0,0 +1,28 @@
+package neatptc
+
+import (
+	"testing"
+
+	"github.com/neatlab/neatio/params"
+)
+
+func TestPruneEnabled(t *testing.T) {
+	sideChain := &params.ChainConfig{NeatChainId: "side_0"}
+	tests := []struct {
+		config Config
+		chain  *params.ChainConfig
+		want   bool
+	}{
+		{Config{}, sideChain, false},
+		{Config{PruneStateData: true}, sideChain, true},
+		{Config{PruneStateData: true}, params.MainnetChainConfig, false},
+		{Config{PruneStateData: true}, params.TestnetChainConfig, false},
+		{Config{PruneStateData: true, PruneMainChain: true}, params.MainnetChainConfig, true},
+		{Config{PruneMainChain: true}, params.MainnetChainConfig, false},
+	}
+	for i, tt := range tests {
+		if have := tt.config.PruneEnabled(tt.chain); have != tt.want {
+			t.Errorf("test %d: pruning of %s: have %v, want %v", i, tt.chain.NeatChainId, have, tt.want)
+		}
+	}
+}
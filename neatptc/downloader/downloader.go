@@ -27,6 +27,7 @@ import (
 
 	neatio "github.com/neatlab/neatio"
 	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core"
 	"github.com/neatlab/neatio/core/rawdb"
 	"github.com/neatlab/neatio/core/types"
 	"github.com/neatlab/neatio/event"
@@ -1348,6 +1349,10 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 		blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
 	}
 	if index, err := d.blockchain.InsertChain(blocks); err != nil {
+		if _, ok := err.(*core.UnsupportedUpgradeError); ok {
+			// The chain halted for an upgrade, the peer is not at fault
+			return err
+		}
 		d.logger.Debug("Downloaded item processing failed", "number", results[index].Header.Number, "hash", results[index].Header.Hash(), "err", err)
 		return errInvalidChain
 	}
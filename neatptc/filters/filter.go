@@ -19,6 +19,8 @@ package filters
 import (
 	"context"
 	"math/big"
+	"sync"
+	"sync/atomic"
 
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/core"
@@ -29,6 +31,16 @@ import (
 	"github.com/neatlab/neatio/rpc"
 )
 
+const (
+	// unindexedScanThreads is the number of goroutines fetching headers and
+	// testing their blooms for the part of a range which is not indexed yet.
+	unindexedScanThreads = 8
+
+	// unindexedScanBatch is the number of blocks scanned concurrently before
+	// the matching ones are checked against their logs.
+	unindexedScanBatch = 1024
+)
+
 type Backend interface {
 	ChainDb() neatdb.Database
 	EventMux() *event.TypeMux
@@ -177,27 +189,87 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
-// indexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
+// unindexedLogs returns the logs matching the filter criteria based on raw block
+// iteration and bloom matching. The headers are fetched and their blooms tested
+// concurrently, a batch at a time, so that the part of the range not covered by
+// the bloombits index yet does not scan serially block by block.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
 	var logs []*types.Log
 
-	for ; f.begin <= int64(end); f.begin++ {
-		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
-		if header == nil || err != nil {
-			return logs, err
+	for f.begin <= int64(end) {
+		last := uint64(f.begin) + unindexedScanBatch - 1
+		if last > end {
+			last = end
 		}
-		if bloomFilter(header.Bloom, f.addresses, f.topics) {
+		first := uint64(f.begin)
+		headers, scanned, scanErr := f.scanBlooms(ctx, first, last)
+		for _, header := range headers {
 			found, err := f.checkMatches(ctx, header)
 			if err != nil {
 				return logs, err
 			}
 			logs = append(logs, found...)
 		}
+		f.begin += int64(scanned)
+		if scanErr != nil || first+scanned <= last {
+			// Failed or ran past the known headers, stop at the first missing one
+			return logs, scanErr
+		}
 	}
 	return logs, nil
 }
 
+// scanBlooms fetches the headers of the blocks [first, last] on several threads
+// and returns the ones whose bloom may match the filter, in order, together with
+// the number of blocks scanned before the first missing header.
+func (f *Filter) scanBlooms(ctx context.Context, first, last uint64) ([]*types.Header, uint64, error) {
+	var (
+		count   = int(last - first + 1)
+		headers = make([]*types.Header, count)
+		matched = make([]bool, count)
+		errs    = make([]error, count)
+		next    = int32(-1)
+		wg      sync.WaitGroup
+	)
+	threads := unindexedScanThreads
+	if count < threads {
+		threads = count
+	}
+	wg.Add(threads)
+	for i := 0; i < threads; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				index := int(atomic.AddInt32(&next, 1))
+				if index >= count || ctx.Err() != nil {
+					return
+				}
+				header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(first+uint64(index)))
+				if header == nil || err != nil {
+					errs[index] = err
+					continue
+				}
+				headers[index] = header
+				matched[index] = bloomFilter(header.Bloom, f.addresses, f.topics)
+			}
+		}()
+	}
+	wg.Wait()
+	var matches []*types.Header
+	for i, header := range headers {
+		if header == nil {
+			if errs[i] == nil {
+				errs[i] = ctx.Err()
+			}
+			return matches, uint64(i), errs[i]
+		}
+		if matched[i] {
+			matches = append(matches, header)
+		}
+	}
+	return matches, uint64(count), nil
+}
+
 // checkMatches checks if the receipts belonging to the given header contain any log events that
 // match the filter criteria. This function is called when the bloom filter signals a potential match.
 func (f *Filter) checkMatches(ctx context.Context, header *types.Header) (logs []*types.Log, err error) {
@@ -0,0 +1,62 @@
+package filters
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/event"
+)
+
+// TestFilterUnindexedLogs checks that scanning a range not covered by the bloom
+// index concurrently still returns every match, in block order, and stops at
+// the head of the chain.
+func TestFilterUnindexedLogs(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{mux: new(event.TypeMux), db: db}
+		addr    = common.HexToAddress("0x1000")
+		topic   = common.HexToHash("0x2000")
+		blocks  = 3000
+		parent  common.Hash
+	)
+	for i := 0; i < blocks; i++ {
+		var receipts types.Receipts
+		if i%7 == 0 {
+			receipt := types.NewReceipt(nil, false, 21000)
+			receipt.TxHash = common.BigToHash(big.NewInt(int64(i + 1)))
+			receipt.Logs = []*types.Log{{Address: addr, Topics: []common.Hash{topic}}}
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			receipts = append(receipts, receipt)
+		}
+		header := &types.Header{Number: big.NewInt(int64(i)), ParentHash: parent, Bloom: types.CreateBloom(receipts), Difficulty: big.NewInt(1)}
+		hash := header.Hash()
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteCanonicalHash(db, hash, uint64(i))
+		rawdb.WriteReceipts(db, hash, uint64(i), receipts)
+		rawdb.WriteHeadBlockHash(db, hash)
+		parent = hash
+	}
+
+	for _, end := range []int64{int64(blocks - 1), int64(blocks + 500)} {
+		filter := New(backend, 0, end, []common.Address{addr}, [][]common.Hash{{topic}})
+		logs, err := filter.Logs(context.Background())
+		if err != nil {
+			t.Fatalf("end %d: %v", end, err)
+		}
+		if want := (blocks + 6) / 7; len(logs) != want {
+			t.Fatalf("end %d: have %d logs, want %d", end, len(logs), want)
+		}
+		for i, log := range logs {
+			if log.BlockNumber != uint64(i*7) {
+				t.Fatalf("end %d: log %d in block %d, want %d", end, i, log.BlockNumber, i*7)
+			}
+		}
+		if filter.begin != int64(blocks) {
+			t.Errorf("end %d: filter stopped at %d, want %d", end, filter.begin, blocks)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package firehose
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/rlp"
+	"github.com/neatlab/neatio/trie"
+)
+
+// none is the value of the fields without value, like the recipient of a
+// contract creation.
+const none = "-"
+
+// record writes a line of the output, the fields are separated by a space.
+func record(buf *bytes.Buffer, kind string, fields ...string) {
+	buf.WriteString("FIRE ")
+	buf.WriteString(kind)
+	for _, field := range fields {
+		buf.WriteByte(' ')
+		buf.WriteString(field)
+	}
+	buf.WriteByte('\n')
+}
+
+func uint64Field(n uint64) string { return strconv.FormatUint(n, 10) }
+
+func bigField(n *big.Int) string {
+	if n == nil {
+		return "0"
+	}
+	return n.String()
+}
+
+func addressField(addr *common.Address) string {
+	if addr == nil || *addr == (common.Address{}) {
+		return none
+	}
+	return addr.String()
+}
+
+// encodeBlock encodes the execution of a block: its transactions and their
+// receipts and logs, then the changes of the accounts from the state of the
+// parent block.
+func encodeBlock(chainId string, block *types.Block, receipts types.Receipts, signer types.Signer, db state.Database, parentRoot common.Hash) ([]byte, error) {
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("%d receipts of %d transactions", len(receipts), len(block.Transactions()))
+	}
+	buf := new(bytes.Buffer)
+	header := block.Header()
+	record(buf, "BLOCK_BEGIN", chainId, uint64Field(block.NumberU64()), block.Hash().Hex(), header.ParentHash.Hex(),
+		uint64Field(block.Time()), addressField(&header.Coinbase), header.Root.Hex(), strconv.Itoa(len(block.Transactions())))
+
+	var logIndex int
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+		record(buf, "TRX", strconv.Itoa(i), tx.Hash().Hex(), from.String(), addressField(tx.To()), uint64Field(tx.Nonce()),
+			bigField(tx.Value()), uint64Field(tx.Gas()), bigField(tx.GasPrice()), hexutil.Encode(tx.Data()))
+
+		receipt := receipts[i]
+		record(buf, "RECEIPT", strconv.Itoa(i), uint64Field(receipt.Status), uint64Field(receipt.GasUsed),
+			uint64Field(receipt.CumulativeGasUsed), addressField(&receipt.ContractAddress))
+		for _, l := range receipt.Logs {
+			topics := none
+			if len(l.Topics) > 0 {
+				hexes := make([]string, len(l.Topics))
+				for j, topic := range l.Topics {
+					hexes[j] = topic.Hex()
+				}
+				topics = strings.Join(hexes, ",")
+			}
+			record(buf, "LOG", strconv.Itoa(i), strconv.Itoa(logIndex), l.Address.String(), topics, hexutil.Encode(l.Data))
+			logIndex++
+		}
+	}
+
+	diffs, err := stateDiffs(db, parentRoot, header.Root)
+	if err != nil {
+		return nil, err
+	}
+	for _, diff := range diffs {
+		for _, field := range diff.fields {
+			record(buf, "ACCOUNT", diff.address.String(), field.name, field.old, field.new)
+		}
+		for _, slot := range diff.storage {
+			record(buf, "STORAGE", diff.address.String(), slot.key.Hex(), slot.old.Hex(), slot.new.Hex())
+		}
+	}
+
+	record(buf, "BLOCK_END", chainId, uint64Field(block.NumberU64()), block.Hash().Hex(), uint64Field(header.GasUsed))
+	return buf.Bytes(), nil
+}
+
+type fieldDiff struct {
+	name, old, new string
+}
+
+type slotDiff struct {
+	key, old, new common.Hash
+}
+
+// accountDiff is the change of an account, with the fields and the storage
+// slots which changed.
+type accountDiff struct {
+	address common.Address
+	fields  []fieldDiff
+	storage []slotDiff
+}
+
+// accountFields returns the fields of an account compared by the state diffs,
+// the zero ones for a missing account.
+func accountFields(account *state.Account) [][2]string {
+	if account == nil {
+		account = new(state.Account)
+	}
+	codeHash := none
+	if len(account.CodeHash) > 0 && !bytes.Equal(account.CodeHash, crypto.Keccak256(nil)) {
+		codeHash = hexutil.Encode(account.CodeHash)
+	}
+	return [][2]string{
+		{"nonce", uint64Field(account.Nonce)},
+		{"balance", bigField(account.Balance)},
+		{"deposit_balance", bigField(account.DepositBalance)},
+		{"chain_balance", bigField(account.ChainBalance)},
+		{"delegate_balance", bigField(account.DelegateBalance)},
+		{"proxied_balance", bigField(account.ProxiedBalance)},
+		{"deposit_proxied_balance", bigField(account.DepositProxiedBalance)},
+		{"pending_refund_balance", bigField(account.PendingRefundBalance)},
+		{"reward_balance", bigField(account.RewardBalance)},
+		{"available_reward_balance", bigField(account.AvailableRewardBalance)},
+		{"code_hash", codeHash},
+	}
+}
+
+// stateDiffs returns the changes of the accounts from the state root before
+// to the state root after, sorted by address, found by walking the accounts
+// which differ.
+func stateDiffs(db state.Database, before, after common.Hash) ([]*accountDiff, error) {
+	beforeTrie, err := db.OpenTrie(before)
+	if err != nil {
+		return nil, err
+	}
+	afterTrie, err := db.OpenTrie(after)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := changedKeys(beforeTrie, afterTrie)
+	if err != nil {
+		return nil, fmt.Errorf("accounts: %v", err)
+	}
+
+	var diffs []*accountDiff
+	for _, key := range changed {
+		oldAccount, err := decodeAccount(beforeTrie, key.preimage)
+		if err != nil {
+			return nil, err
+		}
+		newAccount, err := decodeAccount(afterTrie, key.preimage)
+		if err != nil {
+			return nil, err
+		}
+		diff := &accountDiff{address: common.BytesToAddress(key.preimage)}
+		oldFields, newFields := accountFields(oldAccount), accountFields(newAccount)
+		for i := range oldFields {
+			if oldFields[i][1] != newFields[i][1] {
+				diff.fields = append(diff.fields, fieldDiff{oldFields[i][0], oldFields[i][1], newFields[i][1]})
+			}
+		}
+		if diff.storage, err = storageDiffs(db, key.hash, oldAccount, newAccount); err != nil {
+			return nil, fmt.Errorf("storage of %s: %v", diff.address.String(), err)
+		}
+		if len(diff.fields) > 0 || len(diff.storage) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return bytes.Compare(diffs[i].address[:], diffs[j].address[:]) < 0 })
+	return diffs, nil
+}
+
+func decodeAccount(tr state.Trie, address []byte) (*state.Account, error) {
+	data, err := tr.TryGet(address)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	account := new(state.Account)
+	if err := rlp.DecodeBytes(data, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// storageDiffs returns the changes of the storage of an account, sorted by
+// slot.
+func storageDiffs(db state.Database, addrHash common.Hash, oldAccount, newAccount *state.Account) ([]slotDiff, error) {
+	var oldRoot, newRoot common.Hash
+	if oldAccount != nil {
+		oldRoot = oldAccount.Root
+	}
+	if newAccount != nil {
+		newRoot = newAccount.Root
+	}
+	if oldRoot == newRoot {
+		return nil, nil
+	}
+	oldTrie, err := db.OpenStorageTrie(addrHash, oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := db.OpenStorageTrie(addrHash, newRoot)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := changedKeys(oldTrie, newTrie)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]slotDiff, 0, len(changed))
+	for _, key := range changed {
+		oldValue, err := storageValue(oldTrie, key.preimage)
+		if err != nil {
+			return nil, err
+		}
+		newValue, err := storageValue(newTrie, key.preimage)
+		if err != nil {
+			return nil, err
+		}
+		if oldValue != newValue {
+			slots = append(slots, slotDiff{common.BytesToHash(key.preimage), oldValue, newValue})
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return bytes.Compare(slots[i].key[:], slots[j].key[:]) < 0 })
+	return slots, nil
+}
+
+func storageValue(tr state.Trie, key []byte) (common.Hash, error) {
+	enc, err := tr.TryGet(key)
+	if err != nil || len(enc) == 0 {
+		return common.Hash{}, err
+	}
+	_, content, _, err := rlp.Split(enc)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(content), nil
+}
+
+type trieKey struct {
+	hash     common.Hash
+	preimage []byte
+}
+
+// changedKeys returns the keys of the leaves which differ between two tries,
+// the updated and created ones first, then the deleted ones.
+func changedKeys(a, b state.Trie) ([]trieKey, error) {
+	var (
+		keys []trieKey
+		seen = make(map[common.Hash]bool)
+	)
+	walk := func(a, b state.Trie) error {
+		it, _ := trie.NewDifferenceIterator(a.NodeIterator(nil), b.NodeIterator(nil))
+		for it.Next(true) {
+			if !it.Leaf() {
+				continue
+			}
+			hash := common.BytesToHash(it.LeafKey())
+			if seen[hash] {
+				continue
+			}
+			seen[hash] = true
+			preimage := b.GetKey(it.LeafKey())
+			if preimage == nil {
+				return fmt.Errorf("no preimage of key %x", it.LeafKey())
+			}
+			keys = append(keys, trieKey{hash, preimage})
+		}
+		return it.Error()
+	}
+	if err := walk(a, b); err != nil {
+		return nil, err
+	}
+	if err := walk(b, a); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
@@ -0,0 +1,282 @@
+// Package firehose streams the execution data of the blocks of the chains as
+// they are imported, for the indexers ingesting the chains without querying
+// the RPC APIs.
+//
+// The blocks are written in order and without gaps, each one as a sequence of
+// lines of space separated fields:
+//
+//	FIRE BLOCK_BEGIN <chain> <number> <hash> <parent hash> <time> <coinbase> <state root> <transactions>
+//	FIRE TRX <index> <hash> <from> <to> <nonce> <value> <gas> <gas price> <input>
+//	FIRE RECEIPT <index> <status> <gas used> <cumulative gas used> <contract address>
+//	FIRE LOG <transaction index> <index> <address> <topics> <data>
+//	FIRE ACCOUNT <address> <field> <old value> <new value>
+//	FIRE STORAGE <address> <slot> <old value> <new value>
+//	FIRE BLOCK_END <chain> <number> <hash> <gas used>
+//
+// The amounts are decimal, the hashes and the data hexadecimal, the topics
+// are separated by commas and the missing values are "-". The accounts are
+// the ones changed by the block, sorted by address, with their fields which
+// changed, then their storage slots which changed, sorted by slot.
+package firehose
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/event"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/params"
+)
+
+const (
+	// clientBuffer is the number of blocks buffered for a client of a socket,
+	// the clients falling further behind are disconnected.
+	clientBuffer = 256
+
+	// clientWriteTimeout is the time limit to write a block to a client.
+	clientWriteTimeout = 30 * time.Second
+)
+
+var (
+	outputsLock sync.Mutex
+	outputs     = make(map[string]*Output)
+)
+
+// Output is where the blocks are streamed, shared by the chains streaming to
+// the same target.
+type Output struct {
+	target string
+	refs   int
+
+	lock     sync.Mutex
+	writer   *bufio.Writer
+	listener net.Listener
+	clients  map[chan []byte]struct{}
+}
+
+// Open opens the output of a target, "stdout", or "unix:<path>" or
+// "tcp:<host:port>" to stream the blocks to the clients of a socket. The
+// output of a target is opened once, the chains streaming to it share it.
+func Open(target string) (*Output, error) {
+	outputsLock.Lock()
+	defer outputsLock.Unlock()
+
+	if out := outputs[target]; out != nil {
+		out.refs++
+		return out, nil
+	}
+	out := &Output{target: target, refs: 1}
+	switch {
+	case target == "stdout":
+		out.writer = bufio.NewWriter(os.Stdout)
+	case strings.HasPrefix(target, "unix:") || strings.HasPrefix(target, "tcp:"):
+		idx := strings.IndexByte(target, ':')
+		listener, err := net.Listen(target[:idx], target[idx+1:])
+		if err != nil {
+			return nil, err
+		}
+		out.listener, out.clients = listener, make(map[chan []byte]struct{})
+		go out.accept()
+		log.Info("Firehose socket opened", "addr", listener.Addr())
+	default:
+		return nil, fmt.Errorf("invalid firehose output %q, must be stdout, unix:<path> or tcp:<host:port>", target)
+	}
+	outputs[target] = out
+	return out, nil
+}
+
+// Close closes the output once the chains sharing it closed it.
+func (out *Output) Close() error {
+	outputsLock.Lock()
+	defer outputsLock.Unlock()
+
+	if out.refs--; out.refs > 0 {
+		return nil
+	}
+	delete(outputs, out.target)
+
+	out.lock.Lock()
+	defer out.lock.Unlock()
+	if out.writer != nil {
+		return out.writer.Flush()
+	}
+	for client := range out.clients {
+		close(client)
+		delete(out.clients, client)
+	}
+	return out.listener.Close()
+}
+
+// write writes a block, at once so that the blocks of the chains are not
+// interleaved.
+func (out *Output) write(block []byte) error {
+	out.lock.Lock()
+	defer out.lock.Unlock()
+
+	if out.writer != nil {
+		if _, err := out.writer.Write(block); err != nil {
+			return err
+		}
+		return out.writer.Flush()
+	}
+	for client := range out.clients {
+		select {
+		case client <- block:
+		default:
+			// The client is too slow, disconnecting it lets it know blocks
+			// are missing
+			close(client)
+			delete(out.clients, client)
+		}
+	}
+	return nil
+}
+
+func (out *Output) accept() {
+	for {
+		conn, err := out.listener.Accept()
+		if err != nil {
+			return
+		}
+		client := make(chan []byte, clientBuffer)
+		out.lock.Lock()
+		out.clients[client] = struct{}{}
+		out.lock.Unlock()
+		go out.serve(conn, client)
+	}
+}
+
+// serve writes the blocks to a client until it is disconnected.
+func (out *Output) serve(conn net.Conn, client chan []byte) {
+	defer conn.Close()
+	for block := range client {
+		conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+		if _, err := conn.Write(block); err != nil {
+			log.Debug("Firehose client disconnected", "addr", conn.RemoteAddr(), "err", err)
+			break
+		}
+	}
+	out.lock.Lock()
+	if _, ok := out.clients[client]; ok {
+		delete(out.clients, client)
+		close(client)
+	}
+	out.lock.Unlock()
+	// Drain the blocks sent before the client was removed
+	for range client {
+	}
+}
+
+// Chain is the chain whose blocks are streamed, implemented by
+// core.BlockChain.
+type Chain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	StateCache() state.Database
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Streamer streams the blocks of a chain to an output, in order from a block
+// on.
+type Streamer struct {
+	chainId string
+	chain   Chain
+	output  *Output
+	next    uint64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewStreamer creates a streamer of the blocks of a chain from the block
+// start, or from the next block imported if start is 0.
+func NewStreamer(chainId string, chain Chain, output *Output, start uint64) *Streamer {
+	if start == 0 {
+		start = chain.CurrentBlock().NumberU64() + 1
+	}
+	return &Streamer{
+		chainId: chainId,
+		chain:   chain,
+		output:  output,
+		next:    start,
+		quit:    make(chan struct{}),
+	}
+}
+
+// Start starts streaming the blocks.
+func (s *Streamer) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop stops streaming the blocks and closes the output.
+func (s *Streamer) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+	s.output.Close()
+}
+
+// loop streams the blocks up to the head of the chain, each time it changes.
+// The blocks are read from the chain rather than from the events so that
+// none is missed.
+func (s *Streamer) loop() {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	log.Info("Streaming blocks to firehose", "chain", s.chainId, "output", s.output.target, "from", s.next)
+	for {
+		if err := s.streamToHead(); err != nil {
+			log.Error("Firehose streaming stopped", "chain", s.chainId, "number", s.next, "err", err)
+			return
+		}
+		select {
+		case <-heads:
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Streamer) streamToHead() error {
+	for s.next <= s.chain.CurrentBlock().NumberU64() {
+		select {
+		case <-s.quit:
+			return nil
+		default:
+		}
+		block := s.chain.GetBlockByNumber(s.next)
+		if block == nil || block.NumberU64() == 0 {
+			return fmt.Errorf("block #%d not found", s.next)
+		}
+		parent := s.chain.GetBlockByNumber(s.next - 1)
+		if parent == nil {
+			return fmt.Errorf("block #%d not found", s.next-1)
+		}
+		signer := types.MakeSigner(s.chain.Config(), block.Number())
+		data, err := encodeBlock(s.chainId, block, s.chain.GetReceiptsByHash(block.Hash()), signer, s.chain.StateCache(), parent.Root())
+		if err != nil {
+			return err
+		}
+		if err := s.output.write(data); err != nil {
+			return err
+		}
+		s.next++
+	}
+	return nil
+}
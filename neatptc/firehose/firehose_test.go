@@ -0,0 +1,132 @@
+package firehose
+
+import (
+	"bufio"
+	"bytes"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	"github.com/neatlab/neatio/params"
+)
+
+func TestEncodeBlock(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.Address{1}
+		contract = common.Address{2}
+		signer   = types.NewEIP155Signer(params.TestChainConfig.ChainId)
+		db       = state.NewDatabase(rawdb.NewMemoryDatabase())
+	)
+	// The parent state, and the state after the block
+	statedb, _ := state.New(common.Hash{}, db)
+	statedb.SetBalance(sender, big.NewInt(1000000))
+	statedb.SetState(contract, common.Hash{1}, common.Hash{1})
+	statedb.SetState(contract, common.Hash{2}, common.Hash{2})
+	parentRoot, _ := statedb.Commit(false)
+
+	statedb, _ = state.New(parentRoot, db)
+	statedb.SetBalance(sender, big.NewInt(978900))
+	statedb.SetNonce(sender, 1)
+	statedb.SetBalance(receiver, big.NewInt(100))
+	statedb.SetState(contract, common.Hash{2}, common.Hash{})
+	statedb.SetState(contract, common.Hash{3}, common.Hash{3})
+	root, _ := statedb.Commit(false)
+
+	tx, _ := types.SignTx(types.NewTransaction(0, receiver, big.NewInt(100), params.TxGas, big.NewInt(1), nil), signer, key)
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, GasUsed: params.TxGas, CumulativeGasUsed: params.TxGas}
+	receipt.Logs = []*types.Log{{Address: contract, Topics: []common.Hash{{1}, {2}}, Data: []byte{1}}, {Address: contract}}
+	header := &types.Header{Number: big.NewInt(1), Time: big.NewInt(1600000000), Root: root, GasUsed: params.TxGas}
+	block := types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt})
+
+	data, err := encodeBlock("neatio", block, types.Receipts{receipt}, signer, db, parentRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"FIRE BLOCK_BEGIN neatio 1 " + block.Hash().Hex() + " " + common.Hash{}.Hex() + " 1600000000 - " + root.Hex() + " 1",
+		"FIRE TRX 0 " + tx.Hash().Hex() + " " + sender.String() + " " + receiver.String() + " 0 100 21000 1 0x",
+		"FIRE RECEIPT 0 1 21000 21000 -",
+		"FIRE LOG 0 0 " + contract.String() + " " + common.Hash{1}.Hex() + "," + common.Hash{2}.Hex() + " 0x01",
+		"FIRE LOG 0 1 " + contract.String() + " - 0x",
+	}
+	// The accounts are sorted by address
+	accounts := map[common.Address][]string{
+		sender: {
+			"FIRE ACCOUNT " + sender.String() + " nonce 0 1",
+			"FIRE ACCOUNT " + sender.String() + " balance 1000000 978900",
+		},
+		receiver: {
+			"FIRE ACCOUNT " + receiver.String() + " balance 0 100",
+		},
+		contract: {
+			"FIRE STORAGE " + contract.String() + " " + common.Hash{2}.Hex() + " " + common.Hash{2}.Hex() + " " + common.Hash{}.Hex(),
+			"FIRE STORAGE " + contract.String() + " " + common.Hash{3}.Hex() + " " + common.Hash{}.Hex() + " " + common.Hash{3}.Hex(),
+		},
+	}
+	for _, addr := range sortedAddresses(sender, receiver, contract) {
+		want = append(want, accounts[addr]...)
+	}
+	want = append(want, "FIRE BLOCK_END neatio 1 "+block.Hash().Hex()+" 21000")
+
+	if got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("unexpected output\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func sortedAddresses(addrs ...common.Address) []common.Address {
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs
+}
+
+func TestSocketOutput(t *testing.T) {
+	out, err := Open("tcp:127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := Open("tcp:127.0.0.1:0")
+	if err != nil || shared != out {
+		t.Fatalf("output not shared: %v", err)
+	}
+	conn, err := net.Dial("tcp", out.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Wait for the client to be accepted before writing the blocks
+	for {
+		out.lock.Lock()
+		accepted := len(out.clients) > 0
+		out.lock.Unlock()
+		if accepted {
+			break
+		}
+	}
+	out.write([]byte("FIRE BLOCK_BEGIN a\n"))
+	out.write([]byte("FIRE BLOCK_BEGIN b\n"))
+
+	r := bufio.NewReader(conn)
+	for _, want := range []string{"FIRE BLOCK_BEGIN a\n", "FIRE BLOCK_BEGIN b\n"} {
+		if line, err := r.ReadString('\n'); err != nil || line != want {
+			t.Fatalf("read %q, %v, want %q", line, err, want)
+		}
+	}
+
+	out.Close()
+	if _, ok := outputs[out.target]; !ok {
+		t.Fatal("output closed while shared")
+	}
+	out.Close()
+	if _, ok := outputs[out.target]; ok {
+		t.Fatal("output not closed")
+	}
+}
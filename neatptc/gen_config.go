@@ -4,10 +4,12 @@ package neatptc
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/common/hexutil"
 	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/neatptc/alert"
 	"github.com/neatlab/neatio/neatptc/downloader"
 	"github.com/neatlab/neatio/neatptc/gasprice"
 )
@@ -19,6 +21,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
+		NoPruning               bool
+		TxSearch                bool
+		PipelinedCommit         bool
 		LightServ               int  `toml:",omitempty"`
 		LightPeers              int  `toml:",omitempty"`
 		SkipBcVersionCheck      bool `toml:"-"`
@@ -31,13 +36,25 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		MinerGasPrice           *big.Int
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
+		Alerts                  alert.Config
 		EnablePreimageRecording bool
 		DocRoot                 string `toml:"-"`
+		PruneStateData          bool
+		PruneBlockData          bool
+		PruneRetention          uint64
+		PruneMainChain          bool
+		ShutdownTimeout         time.Duration
+		StallProfileThreshold   time.Duration
+		Firehose                string `toml:",omitempty"`
+		FirehoseStart           uint64 `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.NoPruning = c.NoPruning
+	enc.TxSearch = c.TxSearch
+	enc.PipelinedCommit = c.PipelinedCommit
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
@@ -48,8 +65,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.MinerGasPrice = c.MinerGasPrice
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
+	enc.Alerts = c.Alerts
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
+	enc.PruneStateData = c.PruneStateData
+	enc.PruneBlockData = c.PruneBlockData
+	enc.PruneRetention = c.PruneRetention
+	enc.PruneMainChain = c.PruneMainChain
+	enc.ShutdownTimeout = c.ShutdownTimeout
+	enc.StallProfileThreshold = c.StallProfileThreshold
+	enc.Firehose = c.Firehose
+	enc.FirehoseStart = c.FirehoseStart
 	return &enc, nil
 }
 
@@ -58,6 +84,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
+		NoPruning               *bool
+		TxSearch                *bool
+		PipelinedCommit         *bool
 		LightServ               *int  `toml:",omitempty"`
 		LightPeers              *int  `toml:",omitempty"`
 		SkipBcVersionCheck      *bool `toml:"-"`
@@ -70,8 +99,17 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		MinerGasPrice           *big.Int
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
+		Alerts                  *alert.Config
 		EnablePreimageRecording *bool
 		DocRoot                 *string `toml:"-"`
+		PruneStateData          *bool
+		PruneBlockData          *bool
+		PruneRetention          *uint64
+		PruneMainChain          *bool
+		ShutdownTimeout         *time.Duration
+		StallProfileThreshold   *time.Duration
+		Firehose                *string `toml:",omitempty"`
+		FirehoseStart           *uint64 `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -86,6 +124,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.NoPruning != nil {
+		c.NoPruning = *dec.NoPruning
+	}
+	if dec.TxSearch != nil {
+		c.TxSearch = *dec.TxSearch
+	}
+	if dec.PipelinedCommit != nil {
+		c.PipelinedCommit = *dec.PipelinedCommit
+	}
 
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
@@ -118,6 +165,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
+	if dec.Alerts != nil {
+		c.Alerts = *dec.Alerts
+	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
@@ -125,5 +175,29 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
+	if dec.PruneStateData != nil {
+		c.PruneStateData = *dec.PruneStateData
+	}
+	if dec.PruneBlockData != nil {
+		c.PruneBlockData = *dec.PruneBlockData
+	}
+	if dec.PruneRetention != nil {
+		c.PruneRetention = *dec.PruneRetention
+	}
+	if dec.PruneMainChain != nil {
+		c.PruneMainChain = *dec.PruneMainChain
+	}
+	if dec.ShutdownTimeout != nil {
+		c.ShutdownTimeout = *dec.ShutdownTimeout
+	}
+	if dec.StallProfileThreshold != nil {
+		c.StallProfileThreshold = *dec.StallProfileThreshold
+	}
+	if dec.Firehose != nil {
+		c.Firehose = *dec.Firehose
+	}
+	if dec.FirehoseStart != nil {
+		c.FirehoseStart = *dec.FirehoseStart
+	}
 	return nil
 }
@@ -363,7 +363,9 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if err := msg.Decode(&msgBytes); err != nil {
 				return errResp(ErrDecode, "msg %v: %v", msg, err)
 			}
-			handler.HandleMsg(msg.Code, p, msgBytes)
+			if _, err := handler.HandleMsg(msg.Code, p, msgBytes); err != nil {
+				return err
+			}
 		}
 	case msg.Code == StatusMsg:
 		// Status messages should never arrive after the handshake
@@ -0,0 +1,83 @@
+package neatptc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/consensus"
+	"github.com/neatlab/neatio/core/rawdb"
+)
+
+// HealthStatus reports whether a chain is alive and ready to serve requests.
+type HealthStatus struct {
+	Healthy bool     `json:"healthy"` // The database is readable
+	Ready   bool     `json:"ready"`   // Healthy, synced, connected and signing
+	Errors  []string `json:"errors,omitempty"`
+
+	Number     uint64  `json:"number"`
+	HeadAge    float64 `json:"headAge"` // Seconds since the head block was produced
+	Syncing    bool    `json:"syncing"`
+	Peers      int     `json:"peers"`
+	Validator  bool    `json:"validator"`
+	LastSigned *uint64 `json:"lastSigned,omitempty"` // Only reported for validators
+}
+
+// Health checks the chain. The chain is ready if it is not syncing, has at
+// least minPeers peers and, if this node is a validator of the current epoch,
+// one of the last signWindow blocks carries its signature.
+func (s *NeatChain) Health(minPeers int, signWindow uint64) *HealthStatus {
+	status := &HealthStatus{Healthy: true}
+	fail := func(format string, args ...interface{}) {
+		status.Errors = append(status.Errors, fmt.Sprintf(format, args...))
+	}
+
+	// The database must resolve the persisted head to a header
+	hash := rawdb.ReadHeadBlockHash(s.chainDb)
+	if hash == (common.Hash{}) {
+		status.Healthy = false
+		fail("head block hash missing from database")
+	} else if number := rawdb.ReadHeaderNumber(s.chainDb, hash); number == nil {
+		status.Healthy = false
+		fail("head block %x missing from database", hash)
+	} else if rawdb.ReadHeader(s.chainDb, hash, *number) == nil {
+		status.Healthy = false
+		fail("head header %d missing from database", *number)
+	}
+
+	head := s.blockchain.CurrentHeader()
+	status.Number = head.Number.Uint64()
+	status.HeadAge = time.Since(time.Unix(head.Time.Int64(), 0)).Seconds()
+
+	progress := s.Downloader().Progress()
+	if status.Syncing = progress.CurrentBlock < progress.HighestBlock; status.Syncing {
+		fail("syncing, %d blocks behind", progress.HighestBlock-progress.CurrentBlock)
+	}
+	if status.Peers = s.protocolManager.peers.Len(); status.Peers < minPeers {
+		fail("%d peers, want at least %d", status.Peers, minPeers)
+	}
+
+	if neatpos, ok := s.engine.(consensus.NeatPoS); ok && signWindow > 0 {
+		address := neatpos.PrivateValidator()
+		if ep := neatpos.GetEpoch(); ep != nil && address != (common.Address{}) && ep.Validators.HasAddress(address.Bytes()) {
+			status.Validator = true
+
+			var after uint64
+			if status.Number > signWindow {
+				after = status.Number - signWindow
+			}
+			signed, err := neatpos.LastSignedBlock(address, after)
+			switch {
+			case err != nil:
+				fail("signature check failed: %v", err)
+			case signed == 0:
+				fail("no signature in the last %d blocks", signWindow)
+			default:
+				status.LastSigned = &signed
+			}
+		}
+	}
+
+	status.Ready = len(status.Errors) == 0
+	return status
+}
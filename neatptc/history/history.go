@@ -0,0 +1,337 @@
+// Package history exports the history of the movements of value of an
+// account, for accounting and tax reporting.
+//
+// The history is built from the transactions of the blocks and their
+// receipts: the transfers sent and received, the fees paid, the special
+// transactions moving value between the chains or into and out of staking,
+// and the staking rewards credited by the blocks, found from the reward
+// balances of the states. The transfers made by contracts during the
+// execution of a transaction are not listed, they are not recorded by the
+// receipts.
+package history
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+)
+
+// The types of the entries.
+const (
+	TypeTransferIn            = "transfer_in"              // Value received from a transaction
+	TypeTransferOut           = "transfer_out"             // Value sent by a transaction
+	TypeFee                   = "fee"                      // Fee of a transaction without value
+	TypeDepositToSideChain    = "deposit_to_side_chain"    // Value deposited from the main chain to a side chain
+	TypeWithdrawFromSideChain = "withdraw_from_side_chain" // Value withdrawn from a side chain to the main chain
+	TypeWithdrawFromMainChain = "withdraw_from_main_chain" // Value of a withdrawal from a side chain credited on the main chain
+	TypeDelegate              = "delegate"                 // Value delegated to a candidate
+	TypeUndelegate            = "undelegate"               // Value undelegated from a candidate, refunded at the end of the epoch
+	TypeRegister              = "register"                 // Value deposited to become a candidate
+	TypeRewardWithdrawal      = "reward_withdrawal"        // Rewards withdrawn to the balance
+	TypeStakingReward         = "staking_reward"           // Rewards credited by a block
+)
+
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Entry is a movement of value of the account.
+type Entry struct {
+	Block  uint64
+	Time   uint64
+	Tx     common.Hash // Zero for the staking rewards
+	Type   string
+	From   common.Address
+	To     common.Address // Zero if there is no recipient, like for the staking
+	Chain  string         // The other chain of a cross-chain movement
+	Amount *big.Int       // Nil if unknown
+	Fee    *big.Int       // Paid by the account, nil if it did not send the transaction
+	Status string
+}
+
+// Chain is the chain whose history is exported, implemented by
+// core.BlockChain.
+type Chain interface {
+	Config() *params.ChainConfig
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	StateCache() state.Database
+}
+
+// Exporter exports the history of an account.
+type Exporter struct {
+	chain     Chain
+	mainChain string
+	address   common.Address
+
+	// Rewards enables the export of the staking rewards, which needs the
+	// states of all the blocks exported.
+	Rewards bool
+}
+
+// NewExporter creates an exporter of the history of address in chain, a chain
+// of the main chain mainChain.
+func NewExporter(chain Chain, mainChain string, address common.Address) *Exporter {
+	return &Exporter{chain: chain, mainChain: mainChain, address: address, Rewards: true}
+}
+
+// Export exports the entries of the blocks from to to, in the order of the
+// chain.
+func (e *Exporter) Export(from, to uint64, emit func(*Entry) error) error {
+	if from == 0 {
+		from = 1
+	}
+	for number := from; number <= to; number++ {
+		block := e.chain.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("block #%d not found", number)
+		}
+		entries, err := e.blockEntries(block)
+		if err != nil {
+			return fmt.Errorf("block #%d: %v", number, err)
+		}
+		for _, entry := range entries {
+			if err := emit(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// blockEntries returns the entries of the transactions of a block, then the
+// staking rewards it credited.
+func (e *Exporter) blockEntries(block *types.Block) ([]*Entry, error) {
+	receipts := e.chain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("%d receipts of %d transactions", len(receipts), len(block.Transactions()))
+	}
+
+	// The states are only read when the rewards are exported
+	var parentState, blockState *state.StateDB
+	if e.Rewards {
+		parent := e.chain.GetBlockByNumber(block.NumberU64() - 1)
+		if parent == nil {
+			return nil, fmt.Errorf("parent block not found")
+		}
+		var err error
+		if parentState, err = state.New(parent.Root(), e.chain.StateCache()); err != nil {
+			return nil, fmt.Errorf("state of the parent not available, the rewards need the states of an archive node: %v", err)
+		}
+		if blockState, err = state.New(block.Root(), e.chain.StateCache()); err != nil {
+			return nil, fmt.Errorf("state not available, the rewards need the states of an archive node: %v", err)
+		}
+	}
+
+	var (
+		entries   []*Entry
+		signer    = types.MakeSigner(e.chain.Config(), block.Number())
+		withdrawn = make(map[common.Address]*big.Int) // Rewards withdrawn by candidate
+		delegated = make(map[common.Address]*big.Int) // Value delegated by candidate
+	)
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+		receipt := receipts[i]
+		entry := &Entry{Block: block.NumberU64(), Time: block.Time(), Tx: tx.Hash(), From: from, Status: StatusSuccess}
+		if receipt.Status == types.ReceiptStatusFailed {
+			entry.Status = StatusFailed
+		}
+
+		if from != e.address {
+			// Only plain transfers credit another account
+			if tx.To() != nil && *tx.To() == e.address && tx.Value().Sign() > 0 {
+				entry.Type, entry.To, entry.Amount = TypeTransferIn, e.address, tx.Value()
+				entries = append(entries, entry)
+			}
+			continue
+		}
+		entry.Fee = new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tx.GasPrice())
+
+		if !neatabi.IsNeatChainContractAddr(tx.To()) {
+			entry.To = receipt.ContractAddress
+			if tx.To() != nil {
+				entry.To = *tx.To()
+			}
+			entry.Type, entry.Amount = TypeFee, tx.Value()
+			if tx.Value().Sign() > 0 {
+				entry.Type = TypeTransferOut
+			}
+			entries = append(entries, entry)
+			continue
+		}
+
+		if err := e.specialEntry(entry, tx, parentState); err != nil {
+			return nil, fmt.Errorf("transaction %x: %v", tx.Hash(), err)
+		}
+		if entry.Status == StatusSuccess {
+			switch entry.Type {
+			case TypeRewardWithdrawal:
+				addAmount(withdrawn, entry.To, entry.Amount)
+			case TypeDelegate:
+				addAmount(delegated, entry.To, entry.Amount)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if e.Rewards {
+		entries = append(entries, e.rewardEntries(block, parentState, blockState, withdrawn, delegated)...)
+	}
+	return entries, nil
+}
+
+// specialEntry fills the entry of a special transaction sent by the account.
+// The state of the parent block gives the rewards withdrawn, it is nil if the
+// rewards are not exported, their amount is then unknown.
+func (e *Exporter) specialEntry(entry *Entry, tx *types.Transaction, parentState *state.StateDB) error {
+	data := tx.Data()
+	if len(data) < 4 {
+		return fmt.Errorf("special transaction without function")
+	}
+	function, err := neatabi.FunctionTypeFromId(data[:4])
+	if err != nil {
+		return err
+	}
+	entry.Amount = tx.Value()
+
+	switch function {
+	case neatabi.DepositInMainChain:
+		var args neatabi.DepositInMainChainArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), data[4:]); err != nil {
+			return err
+		}
+		entry.Type, entry.Chain = TypeDepositToSideChain, args.ChainId
+
+	case neatabi.WithdrawFromSideChain:
+		entry.Type, entry.Chain = TypeWithdrawFromSideChain, e.mainChain
+
+	case neatabi.WithdrawFromMainChain:
+		var args neatabi.WithdrawFromMainChainArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), data[4:]); err != nil {
+			return err
+		}
+		entry.Type, entry.To, entry.Chain, entry.Amount = TypeWithdrawFromMainChain, e.address, args.ChainId, args.Amount
+
+	case neatabi.Delegate:
+		var args neatabi.DelegateArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), data[4:]); err != nil {
+			return err
+		}
+		entry.Type, entry.To = TypeDelegate, args.Candidate
+
+	case neatabi.UnDelegate:
+		var args neatabi.UnDelegateArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), data[4:]); err != nil {
+			return err
+		}
+		entry.Type, entry.To, entry.Amount = TypeUndelegate, args.Candidate, args.Amount
+
+	case neatabi.Register:
+		entry.Type = TypeRegister
+
+	case neatabi.WithdrawReward:
+		var args neatabi.WithdrawRewardArgs
+		if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), data[4:]); err != nil {
+			return err
+		}
+		// The whole reward earned with the candidate is withdrawn
+		entry.Type, entry.To, entry.Amount = TypeRewardWithdrawal, args.DelegateAddress, nil
+		if parentState != nil {
+			entry.Amount = new(big.Int).Set(parentState.GetRewardBalanceByDelegateAddress(e.address, args.DelegateAddress))
+		}
+
+	default:
+		entry.Type = snakeCase(function.String())
+	}
+	return nil
+}
+
+// rewardEntries returns the staking rewards credited to the account by a
+// block, by candidate, from the changes of its reward balances: the rewards
+// withdrawn and the ones compounded in the delegation during the block are
+// added back.
+func (e *Exporter) rewardEntries(block *types.Block, parentState, blockState *state.StateDB, withdrawn, delegated map[common.Address]*big.Int) []*Entry {
+	earned := make(map[common.Address]*big.Int)
+	var candidates []common.Address
+	blockState.ForEachReward(e.address, func(candidate common.Address, balance *big.Int) bool {
+		earned[candidate] = new(big.Int).Set(balance)
+		candidates = append(candidates, candidate)
+		return true
+	})
+	parentState.ForEachReward(e.address, func(candidate common.Address, balance *big.Int) bool {
+		if earned[candidate] == nil {
+			earned[candidate] = new(big.Int)
+			candidates = append(candidates, candidate)
+		}
+		earned[candidate].Sub(earned[candidate], balance)
+		return true
+	})
+
+	var entries []*Entry
+	for _, candidate := range candidates {
+		reward := earned[candidate]
+		if amount := withdrawn[candidate]; amount != nil {
+			reward.Add(reward, amount)
+		}
+		if parentState.IsAutoCompound(e.address, candidate) {
+			// The delegation grew by more than delegated, by the rewards
+			// compounded at the end of the epoch
+			compounded := new(big.Int).Sub(blockState.GetProxiedBalanceByUser(candidate, e.address), parentState.GetProxiedBalanceByUser(candidate, e.address))
+			if amount := delegated[candidate]; amount != nil {
+				compounded.Sub(compounded, amount)
+			}
+			if compounded.Sign() > 0 {
+				reward.Add(reward, compounded)
+			}
+		}
+		if reward.Sign() > 0 {
+			entries = append(entries, &Entry{
+				Block:  block.NumberU64(),
+				Time:   block.Time(),
+				Type:   TypeStakingReward,
+				From:   candidate,
+				To:     e.address,
+				Amount: reward,
+				Status: StatusSuccess,
+			})
+		}
+	}
+	return entries
+}
+
+func addAmount(amounts map[common.Address]*big.Int, addr common.Address, amount *big.Int) {
+	if amount == nil {
+		return
+	}
+	if amounts[addr] == nil {
+		amounts[addr] = new(big.Int)
+	}
+	amounts[addr].Add(amounts[addr], amount)
+}
+
+// snakeCase converts the name of a function to the type of its entries, like
+// SetCommission to set_commission.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
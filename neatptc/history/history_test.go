@@ -0,0 +1,199 @@
+package history
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+)
+
+var testSigner = types.NewEIP155Signer(params.TestChainConfig.ChainId)
+
+type testChain struct {
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	db       state.Database
+}
+
+func newTestChain() *testChain {
+	return &testChain{receipts: make(map[common.Hash]types.Receipts), db: state.NewDatabase(rawdb.NewMemoryDatabase())}
+}
+
+func (c *testChain) Config() *params.ChainConfig { return params.TestChainConfig }
+func (c *testChain) StateCache() state.Database  { return c.db }
+
+func (c *testChain) GetBlockByNumber(number uint64) *types.Block {
+	if number >= uint64(len(c.blocks)) {
+		return nil
+	}
+	return c.blocks[number]
+}
+
+func (c *testChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
+	return c.receipts[hash]
+}
+
+// add adds a block of the transactions, all successful, on the state root.
+func (c *testChain) add(root common.Hash, txs ...*types.Transaction) *types.Block {
+	number := int64(len(c.blocks))
+	header := &types.Header{Number: big.NewInt(number), Time: big.NewInt(1600000000 + number), Root: root}
+	receipts := make(types.Receipts, len(txs))
+	for i := range txs {
+		receipts[i] = &types.Receipt{Status: types.ReceiptStatusSuccessful, GasUsed: params.TxGas}
+	}
+	block := types.NewBlock(header, txs, nil, receipts)
+	c.blocks = append(c.blocks, block)
+	c.receipts[block.Hash()] = receipts
+	return block
+}
+
+func signTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, to common.Address, value int64, data []byte) *types.Transaction {
+	tx, err := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(value), params.TxGas, big.NewInt(2), data), testSigner, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func specialTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, value int64, function neatabi.FunctionType, args ...interface{}) *types.Transaction {
+	data, err := neatabi.ChainABI.Pack(function.String(), args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signTx(t, key, nonce, neatabi.ChainContractMagicAddr, value, data)
+}
+
+func TestExport(t *testing.T) {
+	var (
+		key, _          = crypto.GenerateKey()
+		otherKey, _     = crypto.GenerateKey()
+		candidateKey, _ = crypto.GenerateKey()
+		addr            = crypto.PubkeyToAddress(key.PublicKey)
+		other           = crypto.PubkeyToAddress(otherKey.PublicKey)
+		candidate       = crypto.PubkeyToAddress(candidateKey.PublicKey)
+		chain           = newTestChain()
+	)
+	// Block 1 credits a reward of 50 with the candidate, block 2 withdraws it
+	// and then credits a reward of 30.
+	statedb, _ := state.New(common.Hash{}, chain.db)
+	statedb.SetBalance(addr, big.NewInt(1000000))
+	root0, _ := statedb.Commit(false)
+	chain.add(root0)
+
+	statedb, _ = state.New(root0, chain.db)
+	statedb.AddRewardBalanceByDelegateAddress(addr, candidate, big.NewInt(50))
+	root1, _ := statedb.Commit(false)
+	transfer := signTx(t, key, 0, other, 100, nil)
+	received := signTx(t, otherKey, 0, addr, 7, nil)
+	chain.add(root1, transfer, received)
+
+	statedb, _ = state.New(root1, chain.db)
+	statedb.WithdrawRewardBalance(addr, candidate, big.NewInt(50))
+	statedb.AddRewardBalanceByDelegateAddress(addr, candidate, big.NewInt(30))
+	root2, _ := statedb.Commit(false)
+	withdraw := specialTx(t, key, 1, 0, neatabi.WithdrawReward, candidate)
+	deposit := specialTx(t, key, 2, 500, neatabi.DepositInMainChain, "side_0")
+	delegate := specialTx(t, key, 3, 200, neatabi.Delegate, candidate)
+	commission := specialTx(t, key, 4, 0, neatabi.SetCommission, uint8(10))
+	chain.add(root2, withdraw, deposit, delegate, commission)
+
+	var entries []*Entry
+	exporter := NewExporter(chain, "neatio", addr)
+	if err := exporter.Export(1, 2, func(entry *Entry) error {
+		entries = append(entries, entry)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	fee := big.NewInt(2 * int64(params.TxGas))
+	want := []*Entry{
+		{Block: 1, Tx: transfer.Hash(), Type: TypeTransferOut, From: addr, To: other, Amount: big.NewInt(100), Fee: fee},
+		{Block: 1, Tx: received.Hash(), Type: TypeTransferIn, From: other, To: addr, Amount: big.NewInt(7)},
+		{Block: 1, Type: TypeStakingReward, From: candidate, To: addr, Amount: big.NewInt(50)},
+		{Block: 2, Tx: withdraw.Hash(), Type: TypeRewardWithdrawal, From: addr, To: candidate, Amount: big.NewInt(50), Fee: fee},
+		{Block: 2, Tx: deposit.Hash(), Type: TypeDepositToSideChain, From: addr, Chain: "side_0", Amount: big.NewInt(500), Fee: fee},
+		{Block: 2, Tx: delegate.Hash(), Type: TypeDelegate, From: addr, To: candidate, Amount: big.NewInt(200), Fee: fee},
+		{Block: 2, Tx: commission.Hash(), Type: "set_commission", From: addr, Amount: big.NewInt(0), Fee: fee},
+		{Block: 2, Type: TypeStakingReward, From: candidate, To: addr, Amount: big.NewInt(30)},
+	}
+	if len(entries) != len(want) {
+		for _, e := range entries {
+			t.Logf("%+v", e)
+		}
+		t.Fatalf("%d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		w := want[i]
+		if entry.Block != w.Block || entry.Time != 1600000000+w.Block || entry.Tx != w.Tx || entry.Type != w.Type ||
+			entry.From != w.From || entry.To != w.To || entry.Chain != w.Chain || entry.Status != StatusSuccess ||
+			entry.Amount.Cmp(w.Amount) != 0 || (entry.Fee == nil) != (w.Fee == nil) || (w.Fee != nil && entry.Fee.Cmp(w.Fee) != 0) {
+			t.Errorf("entry %d: got %+v, want %+v", i, entry, w)
+		}
+	}
+
+	// Without the rewards, the amounts withdrawn are unknown
+	entries = nil
+	exporter.Rewards = false
+	exporter.Export(2, 2, func(entry *Entry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if len(entries) != 4 || entries[0].Amount != nil {
+		t.Errorf("unexpected entries without the rewards: %+v", entries)
+	}
+}
+
+func TestWriters(t *testing.T) {
+	var (
+		from  = common.StringToAddress("NEATfromfromfromfromfromfromfrom")
+		to    = common.StringToAddress("NEATtotototototototototototototo")
+		entry = &Entry{Block: 3, Time: 1600000000, Tx: common.Hash{1}, Type: TypeTransferOut, From: from, To: to, Amount: big.NewInt(10), Fee: big.NewInt(1), Status: StatusSuccess}
+	)
+	reward := &Entry{Block: 4, Time: 1600000001, Type: TypeStakingReward, From: to, To: from, Amount: big.NewInt(5), Status: StatusSuccess}
+
+	tests := []struct {
+		format  string
+		entries []*Entry
+		want    string
+	}{
+		{"csv", nil, "block,time,tx,type,from,to,chain,amount,fee,status\n"},
+		{"csv", []*Entry{entry, reward}, "block,time,tx,type,from,to,chain,amount,fee,status\n" +
+			"3,2020-09-13T12:26:40Z," + common.Hash{1}.Hex() + ",transfer_out," + from.String() + "," + to.String() + ",,10,1,success\n" +
+			"4,2020-09-13T12:26:41Z,,staking_reward," + to.String() + "," + from.String() + ",,5,,success\n"},
+		{"json", nil, "[]\n"},
+		{"json", []*Entry{entry, reward}, "[\n" +
+			`{"block":3,"time":"2020-09-13T12:26:40Z","tx":"` + common.Hash{1}.Hex() + `","type":"transfer_out","from":"` + from.String() + `","to":"` + to.String() + `","amount":"10","fee":"1","status":"success"},` + "\n" +
+			`{"block":4,"time":"2020-09-13T12:26:41Z","type":"staking_reward","from":"` + to.String() + `","to":"` + from.String() + `","amount":"5","status":"success"}` + "\n]\n"},
+	}
+	for _, tt := range tests {
+		buf := new(bytes.Buffer)
+		w, err := NewWriter(buf, tt.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range tt.entries {
+			if err := w.Write(entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s output:\n%s\nwant:\n%s", tt.format, buf.String(), tt.want)
+		}
+	}
+	if _, err := NewWriter(new(bytes.Buffer), "xml"); err == nil || !strings.Contains(err.Error(), "invalid format") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/neatlab/neatio/common"
+)
+
+// Writer writes the entries of a history in a format.
+type Writer interface {
+	Write(entry *Entry) error
+	// Close completes the output, it does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter creates a writer of the entries to w in format, "csv" or "json".
+// The amounts are in wei, the times in UTC.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case "csv":
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &jsonWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("invalid format %q, must be csv or json", format)
+	}
+}
+
+var csvHeader = []string{"block", "time", "tx", "type", "from", "to", "chain", "amount", "fee", "status"}
+
+// csvWriter writes the entries as the rows of a CSV file, after a header.
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (w *csvWriter) Write(entry *Entry) error {
+	if !w.header {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	e := encodeEntry(entry)
+	return w.w.Write([]string{strconv.FormatUint(e.Block, 10), e.Time, e.Tx, e.Type, e.From, e.To, e.Chain, e.Amount, e.Fee, e.Status})
+}
+
+func (w *csvWriter) Close() error {
+	if !w.header {
+		if err := w.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// jsonWriter writes the entries as a JSON array, one entry per line.
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (w *jsonWriter) Write(entry *Entry) error {
+	data, err := json.Marshal(encodeEntry(entry))
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if w.count == 0 {
+		sep = "[\n"
+	}
+	w.count++
+	_, err = io.WriteString(w.w, sep+string(data))
+	return err
+}
+
+func (w *jsonWriter) Close() error {
+	end := "\n]\n"
+	if w.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(w.w, end)
+	return err
+}
+
+// encodedEntry is an entry with its fields formatted, the missing ones empty.
+type encodedEntry struct {
+	Block  uint64 `json:"block"`
+	Time   string `json:"time"`
+	Tx     string `json:"tx,omitempty"`
+	Type   string `json:"type"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Chain  string `json:"chain,omitempty"`
+	Amount string `json:"amount,omitempty"`
+	Fee    string `json:"fee,omitempty"`
+	Status string `json:"status"`
+}
+
+func encodeEntry(entry *Entry) *encodedEntry {
+	e := &encodedEntry{
+		Block:  entry.Block,
+		Time:   time.Unix(int64(entry.Time), 0).UTC().Format(time.RFC3339),
+		Type:   entry.Type,
+		From:   encodeAddress(entry.From),
+		To:     encodeAddress(entry.To),
+		Chain:  entry.Chain,
+		Amount: encodeAmount(entry.Amount),
+		Fee:    encodeAmount(entry.Fee),
+		Status: entry.Status,
+	}
+	if entry.Tx != (common.Hash{}) {
+		e.Tx = entry.Tx.Hex()
+	}
+	return e
+}
+
+func encodeAddress(addr common.Address) string {
+	if addr == (common.Address{}) {
+		return ""
+	}
+	return addr.String()
+}
+
+func encodeAmount(amount *big.Int) string {
+	if amount == nil {
+		return ""
+	}
+	return amount.String()
+}
@@ -138,3 +138,48 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 	// Send the packet to the p2p layer
 	return rw.MsgReadWriter.WriteMsg(msg)
 }
+
+// chainMetrics returns the gauges reporting the state of a chain. They are
+// prefixed with the chain id, as a node runs the main chain and its side
+// chains in a single process.
+func (s *NeatChain) chainMetrics() map[string]func() int64 {
+	prefix := "chain/" + s.chainConfig.NeatChainId + "/"
+	return map[string]func() int64{
+		prefix + "head/block": func() int64 {
+			return int64(s.blockchain.CurrentBlock().NumberU64())
+		},
+		prefix + "head/header": func() int64 {
+			return int64(s.blockchain.CurrentHeader().Number.Uint64())
+		},
+		prefix + "peers": func() int64 {
+			return int64(s.protocolManager.peers.Len())
+		},
+		prefix + "txpool/pending": func() int64 {
+			pending, _ := s.txPool.Stats()
+			return int64(pending)
+		},
+		prefix + "txpool/queued": func() int64 {
+			_, queued := s.txPool.Stats()
+			return int64(queued)
+		},
+	}
+}
+
+// registerChainMetrics registers the chain gauges if metrics collection is
+// enabled.
+func (s *NeatChain) registerChainMetrics() {
+	if !metrics.Enabled {
+		return
+	}
+	for name, f := range s.chainMetrics() {
+		metrics.NewRegisteredFunctionalGauge(name, nil, f)
+	}
+}
+
+// unregisterChainMetrics removes the chain gauges, so a stopped side chain is
+// not reported anymore.
+func (s *NeatChain) unregisterChainMetrics() {
+	for name := range s.chainMetrics() {
+		metrics.DefaultRegistry.Unregister(name)
+	}
+}
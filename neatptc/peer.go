@@ -17,6 +17,7 @@
 package neatptc
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -44,8 +45,16 @@ const (
 	maxKnownBlocks       = 1024  // Maximum block hashes to keep in the known list (prevent DOS)
 	maxKnownTX3ProofData = 32768 // Maximum TX3ProofData heights to keep in the known list (prevent DOS)
 	handshakeTimeout     = 5 * time.Second
+
+	maxPooledWireBufferSize = 4 * 1024 * 1024 // Maximum capacity of the wire encoding buffers kept for reuse
 )
 
+// wireBufferPool holds the buffers the consensus messages are wire encoded in,
+// the encoding being copied into the RLP payload of the message at once.
+var wireBufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
 // PeerInfo represents a short summary of the Ethereum sub-protocol metadata known
 // about a connected peer.
 type PeerInfo struct {
@@ -160,8 +169,20 @@ func (p *peer) MarkTX3ProofData(hash common.Hash) {
 // data should encode as an RLP list.
 func (p *peer) Send(msgcode uint64, data interface{}) error {
 	if msgcode >= 0x20 && msgcode <= 0x23 {
-		wirebytes := wire.BinaryBytes(data)
-		return p2p.Send(p.rw, msgcode, wirebytes)
+		buf := wireBufferPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer func() {
+			if buf.Cap() <= maxPooledWireBufferSize {
+				wireBufferPool.Put(buf)
+			}
+		}()
+		var n int
+		var err error
+		wire.WriteBinary(data, buf, &n, &err)
+		if err != nil {
+			return err
+		}
+		return p2p.Send(p.rw, msgcode, buf.Bytes())
 	} else {
 		return p2p.Send(p.rw, msgcode, data)
 	}
@@ -0,0 +1,53 @@
+package neatptc
+
+import (
+	"time"
+
+	"github.com/neatlab/neatio/core"
+	"github.com/neatlab/neatio/internal/debug"
+)
+
+// stallProfileDuration is the number of seconds the CPU is sampled for when
+// the chain stalls.
+const stallProfileDuration = 10
+
+// profileStalls captures CPU, heap and goroutine profiles into the profiles
+// directory if no new block arrives within the configured threshold. A stall
+// is captured once, the watchdog is re-armed by the next block.
+func (s *NeatChain) profileStalls() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	headSub := s.blockchain.SubscribeChainHeadEvent(heads)
+	defer headSub.Unsubscribe()
+
+	threshold := s.config.StallProfileThreshold
+	timer := time.NewTimer(threshold)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-heads:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(threshold)
+
+		case <-timer.C:
+			head := s.blockchain.CurrentBlock()
+			s.chainConfig.ChainLogger.Warn("Chain stalled, capturing profiles", "number", head.NumberU64(), "hash", head.Hash(), "threshold", threshold, "dir", s.profileDir)
+			// Profiling takes a while, don't hold up the head events meanwhile
+			go func() {
+				if err := debug.Handler.CaptureProfiles(s.profileDir, stallProfileDuration); err != nil {
+					s.chainConfig.ChainLogger.Warn("Failed to capture profiles", "err", err)
+				}
+			}()
+
+		case <-headSub.Err():
+			return
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
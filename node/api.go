@@ -75,6 +75,60 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// AllowPeer adds a node ID, enode URL, IP or CIDR range to the allow list of
+// the peer filter. Once the list is not empty, only the peers matching it are
+// connected, and the others are disconnected.
+func (api *PrivateAdminAPI) AllowPeer(rule string) (bool, error) {
+	return api.updatePeerFilter(func(filter *p2p.PeerFilter) error { return filter.Allow(rule) })
+}
+
+// DenyPeer adds a node ID, enode URL, IP or CIDR range to the deny list of the
+// peer filter, disconnecting the matching peers.
+func (api *PrivateAdminAPI) DenyPeer(rule string) (bool, error) {
+	return api.updatePeerFilter(func(filter *p2p.PeerFilter) error { return filter.Deny(rule) })
+}
+
+// RemovePeerRule removes a rule from both lists of the peer filter.
+func (api *PrivateAdminAPI) RemovePeerRule(rule string) (bool, error) {
+	removed := false
+	_, err := api.updatePeerFilter(func(filter *p2p.PeerFilter) (err error) {
+		removed, err = filter.Remove(rule)
+		return err
+	})
+	return removed, err
+}
+
+// PeerRules returns the allow and deny lists of the peer filter.
+func (api *PrivateAdminAPI) PeerRules() (*p2p.PeerFilterRules, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	if server.PeerFilter == nil {
+		return nil, errPeerFilterDisabled
+	}
+	rules := server.PeerFilter.Rules()
+	return &rules, nil
+}
+
+// updatePeerFilter changes the rules of the peer filter and disconnects the
+// peers not let through anymore.
+func (api *PrivateAdminAPI) updatePeerFilter(update func(*p2p.PeerFilter) error) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if server.PeerFilter == nil {
+		return false, errPeerFilterDisabled
+	}
+	if err := update(server.PeerFilter); err != nil {
+		return false, err
+	}
+	server.DropFilteredPeers()
+	return true, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
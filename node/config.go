@@ -30,6 +30,7 @@ import (
 	"github.com/neatlab/neatio/accounts"
 	"github.com/neatlab/neatio/accounts/keystore"
 	"github.com/neatlab/neatio/accounts/usbwallet"
+	"github.com/neatlab/neatio/accounts/watchonly"
 	"github.com/neatlab/neatio/common"
 	"github.com/neatlab/neatio/crypto"
 	"github.com/neatlab/neatio/log"
@@ -43,6 +44,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirPeerFilter      = "peer-filter.json"   // Path within the datadir to the peer allow and deny lists
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -89,6 +91,19 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KeyStoreScryptN and KeyStoreScryptP override the scrypt parameters used to
+	// encrypt keys. Zero values fall back to the standard (or lightweight) ones.
+	KeyStoreScryptN int `toml:",omitempty"`
+	KeyStoreScryptP int `toml:",omitempty"`
+
+	// KeyStoreKDF is the key derivation function used to encrypt new or updated
+	// keys, either "scrypt" (default) or "argon2id".
+	KeyStoreKDF string `toml:",omitempty"`
+
+	// WatchOnlyAddresses is a list of addresses tracked by the account manager
+	// without their keys. Transactions from them must be signed offline.
+	WatchOnlyAddresses []string `toml:",omitempty"`
+
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
@@ -156,6 +171,19 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// AuthHost is the host interface on which to serve the privileged API modules,
+	// authenticated with JWT bearer tokens, over HTTP and WebSocket. If this field
+	// is empty, no authenticated endpoint is started and the privileged modules
+	// are left to the HTTP and WebSocket module lists.
+	AuthHost string `toml:",omitempty"`
+
+	// AuthPort is the TCP port number on which to start the authenticated RPC server.
+	AuthPort int `toml:",omitempty"`
+
+	// JWTSecret is the path of the hex encoded secret the bearer tokens of the
+	// authenticated RPC server are signed with. It is generated if missing.
+	JWTSecret string `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -229,6 +257,24 @@ func (c *Config) WSEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.WSHost, c.WSPort)
 }
 
+// AuthEndpoint resolves the authenticated RPC endpoint based on the configured
+// host interface and port parameters.
+func (c *Config) AuthEndpoint() string {
+	if c.AuthHost == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.AuthHost, c.AuthPort)
+}
+
+// JWTSecretPath returns the path of the JWT secret, in the data directory
+// unless configured otherwise.
+func (c *Config) JWTSecretPath() string {
+	if c.JWTSecret != "" {
+		return c.JWTSecret
+	}
+	return filepath.Join(c.GeneralDataDir, "jwtsecret")
+}
+
 // DefaultWSEndpoint returns the websocket endpoint used by default.
 func DefaultWSEndpoint() string {
 	config := &Config{WSHost: DefaultWSHost, WSPort: DefaultWSPort}
@@ -278,6 +324,7 @@ var isGeneralResource = map[string]bool{
 	"nodekey":            true,
 	"static-nodes.json":  true,
 	"trusted-nodes.json": true,
+	"peer-filter.json":   true,
 }
 
 // ResolvePath resolves path in the instance directory.
@@ -371,6 +418,22 @@ func (c *Config) TrustedNodes() []*discover.Node {
 	return c.parsePersistentNodes(c.ResolvePath(datadirTrustedNodes))
 }
 
+// PeerFilter returns the peer filter persisted in the data directory, or an
+// in-memory one if there is no data directory. A filter file which can't be
+// loaded is an error: running without its deny rules would let the denied
+// peers in.
+func (c *Config) PeerFilter() (*p2p.PeerFilter, error) {
+	if c.DataDir == "" {
+		return p2p.NewPeerFilter(), nil
+	}
+	path := c.ResolvePath(datadirPeerFilter)
+	filter, err := p2p.LoadPeerFilter(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't load peer filter %s: %v", path, err)
+	}
+	return filter, nil
+}
+
 // parsePersistentNodes parses a list of discovery node URLs loaded from a .json
 // file from within the data directory.
 func (c *Config) parsePersistentNodes(path string) []*discover.Node {
@@ -411,6 +474,12 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 		scryptN = keystore.LightScryptN
 		scryptP = keystore.LightScryptP
 	}
+	if c.KeyStoreScryptN != 0 {
+		scryptN = c.KeyStoreScryptN
+	}
+	if c.KeyStoreScryptP != 0 {
+		scryptP = c.KeyStoreScryptP
+	}
 
 	var (
 		keydir string
@@ -443,12 +512,29 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	kdf := conf.KeyStoreKDF
+	if kdf == "" {
+		kdf = keystore.KDFScrypt
+	}
+	if err := keystore.ValidateKDF(kdf); err != nil {
+		return nil, "", err
+	}
 	if err := os.MkdirAll(keydir, 0700); err != nil {
 		return nil, "", err
 	}
 	// Assemble the account manager and supported backends
 	backends := []accounts.Backend{
-		keystore.NewKeyStore(keydir, scryptN, scryptP),
+		keystore.NewKeyStoreWithKDF(keydir, kdf, scryptN, scryptP),
+	}
+	if len(conf.WatchOnlyAddresses) > 0 {
+		addrs := make([]common.Address, 0, len(conf.WatchOnlyAddresses))
+		for _, addr := range conf.WatchOnlyAddresses {
+			if !crypto.ValidateNeatAddr(addr) {
+				return nil, "", fmt.Errorf("invalid watch-only address %q", addr)
+			}
+			addrs = append(addrs, common.StringToAddress(addr))
+		}
+		backends = append(backends, watchonly.NewBackend(addrs))
 	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
@@ -32,8 +32,15 @@ const (
 	DefaultHTTPPort = 9915        // Default TCP port for the HTTP RPC server
 	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
 	DefaultWSPort   = 9916        // Default TCP port for the websocket RPC server
+	DefaultAuthHost = "localhost" // Default host interface for the authenticated RPC server
+	DefaultAuthPort = 9917        // Default TCP port for the authenticated RPC server
 )
 
+// PrivilegedModules are the API modules controlling the node, its accounts and
+// its validator. With the authenticated endpoint enabled they are served there
+// only, never over the plain HTTP and WebSocket endpoints.
+var PrivilegedModules = []string{"admin", "debug", "personal", "miner", "operator"}
+
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
 	GeneralDataDir:   DefaultDataDir(),
@@ -44,6 +51,7 @@ var DefaultConfig = Config{
 	HTTPTimeouts:     rpc.DefaultHTTPTimeouts,
 	WSPort:           DefaultWSPort,
 	WSModules:        []string{"net", "web3"},
+	AuthPort:         DefaultAuthPort,
 	P2P: p2p.Config{
 		ListenAddr: ":9910",
 		MaxPeers:   200,
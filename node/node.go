@@ -187,6 +187,13 @@ func (n *Node) Start() error {
 	if n.serverConfig.NodeDatabase == "" {
 		n.serverConfig.NodeDatabase = n.config.NodeDB()
 	}
+	if n.serverConfig.PeerFilter == nil {
+		filter, err := n.config.PeerFilter()
+		if err != nil {
+			return err
+		}
+		n.serverConfig.PeerFilter = filter
+	}
 	running := &p2p.Server{Config: n.serverConfig}
 	n.log.Info("Starting peer-to-peer node", "instance", n.serverConfig.Name)
 
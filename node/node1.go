@@ -114,6 +114,9 @@ func (n *Node) GetHTTPHandler() (*rpc.Server, error) {
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
 	for _, api := range n.rpcAPIs {
+		if n.privileged(api.Namespace) {
+			continue
+		}
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 				return nil, err
@@ -139,6 +142,9 @@ func (n *Node) GetWSHandler() (*rpc.Server, error) {
 	// Register all the APIs exposed by the services
 	handler := rpc.NewServer()
 	for _, api := range n.rpcAPIs {
+		if n.privileged(api.Namespace) {
+			continue
+		}
 		if n.config.WSExposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 				return nil, err
@@ -155,6 +161,33 @@ func (n *Node) GetWSHandler() (*rpc.Server, error) {
 	return handler, nil
 }
 
+// GetAuthHandler returns the handler of the authenticated endpoint, serving
+// all the API modules, public and private.
+func (n *Node) GetAuthHandler() (*rpc.Server, error) {
+	handler := rpc.NewServer()
+	for _, api := range n.rpcAPIs {
+		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+			return nil, err
+		}
+		n.log.Debug("Authenticated RPC registered", "service", api.Service, "namespace", api.Namespace)
+	}
+	return handler, nil
+}
+
+// privileged reports whether the module is reserved to the authenticated
+// endpoint.
+func (n *Node) privileged(module string) bool {
+	if n.config.AuthHost == "" {
+		return false
+	}
+	for _, privileged := range PrivilegedModules {
+		if module == privileged {
+			return true
+		}
+	}
+	return false
+}
+
 func (n *Node) startRPC1(services map[reflect.Type]Service) error {
 	// Gather all the possible APIs to surface
 	apis := n.apis()
@@ -75,6 +75,13 @@ func (ctx *ServiceContext) NodeKey() *ecdsa.PrivateKey {
 	return ctx.config.NodeKey()
 }
 
+// KeyStoreDir returns the directory holding the keys of the node, empty if
+// the node uses an ephemeral keystore.
+func (ctx *ServiceContext) KeyStoreDir() string {
+	_, _, keydir, _ := ctx.config.AccountConfig()
+	return keydir
+}
+
 // ChainId returns current chain id from config
 func (ctx *ServiceContext) ChainId() string {
 	return ctx.config.ChainId
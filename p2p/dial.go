@@ -72,6 +72,7 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	filter      *PeerFilter
 
 	lookupRunning bool
 	dialing       map[discover.NodeID]connFlag
@@ -274,7 +275,7 @@ func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer)
 	case s.hist.contains(n.ID):
 		return errRecentlyDialed
 	}
-	return nil
+	return s.filter.Check(n.ID, n.IP)
 }
 
 func (s *dialstate) taskDone(t task, now time.Time) {
@@ -0,0 +1,328 @@
+package p2p
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/neatlab/neatio/p2p/discover"
+)
+
+var (
+	errPeerDenied     = errors.New("denied by peer filter")
+	errPeerNotAllowed = errors.New("not allowed by peer filter")
+)
+
+// PeerFilterRules is the set of rules of a peer filter, as persisted and
+// reported over RPC. Rules are hex node IDs or CIDR ranges.
+type PeerFilterRules struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// peerRules is a list of rules matching peers by node ID or IP range.
+type peerRules struct {
+	ids  map[discover.NodeID]struct{}
+	nets map[string]*net.IPNet
+}
+
+func newPeerRules() peerRules {
+	return peerRules{
+		ids:  make(map[discover.NodeID]struct{}),
+		nets: make(map[string]*net.IPNet),
+	}
+}
+
+func (r peerRules) copy() peerRules {
+	cpy := newPeerRules()
+	for id := range r.ids {
+		cpy.ids[id] = struct{}{}
+	}
+	for cidr, n := range r.nets {
+		cpy.nets[cidr] = n
+	}
+	return cpy
+}
+
+func (r peerRules) empty() bool {
+	return len(r.ids) == 0 && len(r.nets) == 0
+}
+
+func (r peerRules) containsIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range r.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r peerRules) contains(id discover.NodeID, ip net.IP) bool {
+	if _, ok := r.ids[id]; ok {
+		return true
+	}
+	return r.containsIP(ip)
+}
+
+func (r peerRules) add(rule peerRule) {
+	if rule.net != nil {
+		r.nets[rule.net.String()] = rule.net
+	} else {
+		r.ids[rule.id] = struct{}{}
+	}
+}
+
+func (r peerRules) remove(rule peerRule) bool {
+	if rule.net != nil {
+		_, ok := r.nets[rule.net.String()]
+		delete(r.nets, rule.net.String())
+		return ok
+	}
+	_, ok := r.ids[rule.id]
+	delete(r.ids, rule.id)
+	return ok
+}
+
+func (r peerRules) list() []string {
+	list := make([]string, 0, len(r.ids)+len(r.nets))
+	for id := range r.ids {
+		list = append(list, id.String())
+	}
+	for cidr := range r.nets {
+		list = append(list, cidr)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// peerRule matches either a single node ID or a range of IPs.
+type peerRule struct {
+	id  discover.NodeID
+	net *net.IPNet
+}
+
+// parsePeerRule parses an enode URL or a hex node ID into a node ID rule, and
+// a CIDR range or a single IP into an IP rule.
+func parsePeerRule(rule string) (peerRule, error) {
+	rule = strings.TrimSpace(rule)
+	switch {
+	case strings.HasPrefix(rule, "enode://"):
+		node, err := discover.ParseNode(rule)
+		if err != nil {
+			return peerRule{}, fmt.Errorf("invalid enode: %v", err)
+		}
+		return peerRule{id: node.ID}, nil
+	case strings.Contains(rule, "/"):
+		_, n, err := net.ParseCIDR(rule)
+		if err != nil {
+			return peerRule{}, err
+		}
+		return peerRule{net: n}, nil
+	}
+	if ip := net.ParseIP(rule); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		return peerRule{net: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}}, nil
+	}
+	if _, err := hex.DecodeString(strings.TrimPrefix(rule, "0x")); err != nil {
+		return peerRule{}, fmt.Errorf("invalid peer rule %q: not a node ID, enode URL, IP or CIDR range", rule)
+	}
+	id, err := discover.HexID(rule)
+	if err != nil {
+		return peerRule{}, fmt.Errorf("invalid node ID: %v", err)
+	}
+	return peerRule{id: id}, nil
+}
+
+// PeerFilter decides which remote nodes may be connected to, based on lists
+// of allowed and denied node IDs and IP ranges. Denied peers are never
+// connected, and if any allow rule is set, only the peers matching one are.
+// The rules apply to trusted and static nodes too.
+//
+// The filter is safe for concurrent use and can be changed while the server
+// is running. If it was loaded from a file, every change is written back.
+type PeerFilter struct {
+	path  string
+	lock  sync.RWMutex
+	allow peerRules
+	deny  peerRules
+}
+
+// NewPeerFilter creates an empty filter letting all peers through, which is
+// not persisted.
+func NewPeerFilter() *PeerFilter {
+	return &PeerFilter{allow: newPeerRules(), deny: newPeerRules()}
+}
+
+// LoadPeerFilter loads the filter rules from the given JSON file. A missing
+// file yields an empty filter, which is created once rules are added.
+func LoadPeerFilter(path string) (*PeerFilter, error) {
+	f := NewPeerFilter()
+	f.path = path
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var rules PeerFilterRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid peer filter %s: %v", path, err)
+	}
+	for _, list := range []struct {
+		rules []string
+		to    peerRules
+	}{{rules.Allow, f.allow}, {rules.Deny, f.deny}} {
+		for _, s := range list.rules {
+			rule, err := parsePeerRule(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid peer filter %s: %v", path, err)
+			}
+			list.to.add(rule)
+		}
+	}
+	return f, nil
+}
+
+// Allow adds a rule to the allow list, removing it from the deny list.
+func (f *PeerFilter) Allow(rule string) error {
+	return f.update(rule, true)
+}
+
+// Deny adds a rule to the deny list, removing it from the allow list.
+func (f *PeerFilter) Deny(rule string) error {
+	return f.update(rule, false)
+}
+
+// update applies the rule to copies of the lists, which replace the lists of
+// the filter once saved.
+func (f *PeerFilter) update(s string, allowed bool) error {
+	rule, err := parsePeerRule(s)
+	if err != nil {
+		return err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	allow, deny := f.allow.copy(), f.deny.copy()
+	if allowed {
+		allow.add(rule)
+		deny.remove(rule)
+	} else {
+		deny.add(rule)
+		allow.remove(rule)
+	}
+	if err := f.save(allow, deny); err != nil {
+		return err
+	}
+	f.allow, f.deny = allow, deny
+	return nil
+}
+
+// Remove drops a rule from both lists, reporting whether it was set.
+func (f *PeerFilter) Remove(s string) (bool, error) {
+	rule, err := parsePeerRule(s)
+	if err != nil {
+		return false, err
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	allow, deny := f.allow.copy(), f.deny.copy()
+	allowed, denied := allow.remove(rule), deny.remove(rule)
+	if !allowed && !denied {
+		return false, nil
+	}
+	if err := f.save(allow, deny); err != nil {
+		return false, err
+	}
+	f.allow, f.deny = allow, deny
+	return true, nil
+}
+
+// Rules returns the current rules of the filter.
+func (f *PeerFilter) Rules() PeerFilterRules {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return PeerFilterRules{Allow: f.allow.list(), Deny: f.deny.list()}
+}
+
+// Check returns an error if the node with the given ID and IP may not be
+// connected to. A nil filter lets all nodes through.
+func (f *PeerFilter) Check(id discover.NodeID, ip net.IP) error {
+	if f == nil {
+		return nil
+	}
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.deny.contains(id, ip) {
+		return errPeerDenied
+	}
+	if !f.allow.empty() && !f.allow.contains(id, ip) {
+		return errPeerNotAllowed
+	}
+	return nil
+}
+
+// CheckIP returns an error if no node at the given IP may be connected to,
+// whatever its ID. It is used to reject connections before the handshake.
+func (f *PeerFilter) CheckIP(ip net.IP) error {
+	if f == nil {
+		return nil
+	}
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.deny.containsIP(ip) {
+		return errPeerDenied
+	}
+	if len(f.allow.ids) == 0 && len(f.allow.nets) > 0 && !f.allow.containsIP(ip) {
+		return errPeerNotAllowed
+	}
+	return nil
+}
+
+// save writes the given rules to the file of the filter, if any. The caller
+// must hold the write lock.
+func (f *PeerFilter) save(allow, deny peerRules) error {
+	if f.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(PeerFilterRules{Allow: allow.list(), Deny: deny.list()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+// remoteIP returns the IP of the remote end of a connection, nil if it is not
+// a TCP connection.
+func remoteIP(addr net.Addr) net.IP {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package p2p
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/neatlab/neatio/p2p/discover"
+)
+
+func TestPeerFilterCheck(t *testing.T) {
+	var (
+		idA = discover.NodeID{1}
+		idB = discover.NodeID{2}
+		lan = net.ParseIP("10.0.0.5")
+		wan = net.ParseIP("1.2.3.4")
+	)
+	f := NewPeerFilter()
+	if err := f.Check(idA, wan); err != nil {
+		t.Fatalf("empty filter rejected peer: %v", err)
+	}
+	// Allow rules are exclusive
+	if err := f.Allow("10.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Allow(idB.String()); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		id   discover.NodeID
+		ip   net.IP
+		want error
+	}{
+		{idA, lan, nil},
+		{idA, wan, errPeerNotAllowed},
+		{idB, wan, nil},
+		{idB, nil, nil},
+	}
+	for i, tt := range tests {
+		if err := f.Check(tt.id, tt.ip); err != tt.want {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// Deny rules win over allow rules
+	if err := f.Deny("10.0.0.5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Check(idA, lan); err != errPeerDenied {
+		t.Errorf("denied IP let through: %v", err)
+	}
+	if err := f.Deny("enode://" + idB.String() + "@127.0.0.1:9910"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Check(idB, wan); err != errPeerDenied {
+		t.Errorf("denied ID let through: %v", err)
+	}
+	want := PeerFilterRules{Allow: []string{"10.0.0.0/8"}, Deny: []string{idB.String(), "10.0.0.5/32"}}
+	if rules := f.Rules(); !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules mismatch: have %+v, want %+v", rules, want)
+	}
+}
+
+func TestPeerFilterCheckIP(t *testing.T) {
+	f := NewPeerFilter()
+	if err := f.Allow("192.168.0.0/16"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.CheckIP(net.ParseIP("8.8.8.8")); err != errPeerNotAllowed {
+		t.Errorf("IP outside the allowed range let through: %v", err)
+	}
+	// Once an ID is allowed, connections from anywhere need a handshake
+	if err := f.Allow(discover.NodeID{1}.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.CheckIP(net.ParseIP("8.8.8.8")); err != nil {
+		t.Errorf("IP rejected before the handshake: %v", err)
+	}
+	if err := f.Deny("8.8.0.0/16"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.CheckIP(net.ParseIP("8.8.8.8")); err != errPeerDenied {
+		t.Errorf("denied IP let through: %v", err)
+	}
+}
+
+func TestPeerFilterPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peer-filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peer-filter.json")
+
+	f, err := LoadPeerFilter(path)
+	if err != nil {
+		t.Fatalf("failed to load missing filter: %v", err)
+	}
+	if err := f.Deny("fe80::/10"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Allow(discover.NodeID{3}.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Allow("fe80::/10"); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := f.Remove(discover.NodeID{3}.String()); !removed || err != nil {
+		t.Fatalf("failed to remove rule: %v %v", removed, err)
+	}
+	loaded, err := LoadPeerFilter(path)
+	if err != nil {
+		t.Fatalf("failed to reload filter: %v", err)
+	}
+	want := PeerFilterRules{Allow: []string{"fe80::/10"}, Deny: []string{}}
+	if rules := loaded.Rules(); !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules mismatch: have %+v, want %+v", rules, want)
+	}
+}
+
+func TestPeerFilterSaveFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peer-filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peer-filter.json")
+
+	f, err := LoadPeerFilter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Deny(discover.NodeID{1}.String()); err != nil {
+		t.Fatal(err)
+	}
+	// The rules are left unchanged if they can't be written
+	if err := os.Mkdir(path+".tmp", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Allow(discover.NodeID{1}.String()); err == nil {
+		t.Fatal("rule set without being saved")
+	}
+	if removed, err := f.Remove(discover.NodeID{1}.String()); removed || err == nil {
+		t.Fatalf("rule removed without being saved: %v %v", removed, err)
+	}
+	want := PeerFilterRules{Allow: []string{}, Deny: []string{discover.NodeID{1}.String()}}
+	if rules := f.Rules(); !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules mismatch: have %+v, want %+v", rules, want)
+	}
+}
+
+func TestPeerFilterInvalidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peer-filter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peer-filter.json")
+
+	if err := ioutil.WriteFile(path, []byte(`{"deny": ["foo"]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPeerFilter(path); err == nil {
+		t.Error("invalid filter loaded")
+	}
+}
+
+func TestPeerFilterInvalidRule(t *testing.T) {
+	f := NewPeerFilter()
+	for _, rule := range []string{"", "foo", "10.0.0.0/33", "0x1234", "enode://1234@127.0.0.1:1"} {
+		if err := f.Deny(rule); err == nil {
+			t.Errorf("rule %q accepted", rule)
+		}
+	}
+}
+
+func TestDialStateFilter(t *testing.T) {
+	filter := NewPeerFilter()
+	if err := filter.Deny("127.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	s := newDialState(nil, nil, fakeTable{}, 0, nil)
+	s.filter = filter
+	n := &discover.Node{ID: discover.NodeID{1}, IP: net.ParseIP("127.0.0.1")}
+	if err := s.checkDial(n, nil); err != errPeerDenied {
+		t.Errorf("denied node dialed: %v", err)
+	}
+	if _, err := filter.Remove("127.0.0.0/8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.checkDial(n, nil); err != nil {
+		t.Errorf("node not dialed after rule removal: %v", err)
+	}
+}
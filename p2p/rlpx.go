@@ -29,7 +29,6 @@ import (
 	"fmt"
 	"hash"
 	"io"
-	"io/ioutil"
 	mrand "math/rand"
 	"net"
 	"sync"
@@ -60,6 +59,15 @@ const (
 	encAuthMsgLen  = authMsgLen + eciesOverhead  // size of encrypted pre-EIP-8 initiator handshake
 	encAuthRespLen = authRespLen + eciesOverhead // size of encrypted pre-EIP-8 handshake reply
 
+	// aeadHandshakeVersion is the handshake version from which frames are
+	// sealed with AES-256-GCM rather than encrypted with AES-CTR and
+	// authenticated with the legacy RLPx MAC. Both sides advertise their
+	// version in the handshake and fall back to the legacy framing if either
+	// is older.
+	aeadHandshakeVersion = 5
+
+	aeadHeaderLen = 3 + 16 // sealed frame size and GCM tag
+
 	// total timeout for encryption handshake and protocol
 	// handshake in both directions.
 	handshakeTimeout = 5 * time.Second
@@ -70,14 +78,41 @@ const (
 	discWriteTimeout = 1 * time.Second
 )
 
-// errPlainMessageTooLarge is returned if a decompressed message length exceeds
-// the allowed 24 bits (i.e. length >= 16MB).
-var errPlainMessageTooLarge = errors.New("message length >= 16MB")
+var (
+	// errPlainMessageTooLarge is returned if a decompressed message length exceeds
+	// the allowed 24 bits (i.e. length >= 16MB).
+	errPlainMessageTooLarge = errors.New("message length >= 16MB")
+
+	// errLegacyTransport is returned by the handshake of a transport requiring
+	// sealed frames with a peer which only supports the legacy framing.
+	errLegacyTransport = errors.New("peer does not support authenticated encryption")
+)
+
+// frameBufferPool holds the buffers the frames are compressed and decompressed
+// in, which are not needed once the frame is written or decompressed.
+var frameBufferPool = sync.Pool{
+	New: func() interface{} { return new([]byte) },
+}
+
+// getFrameBuffer returns a pooled buffer of size bytes.
+func getFrameBuffer(size int) *[]byte {
+	buf := frameBufferPool.Get().(*[]byte)
+	if cap(*buf) < size {
+		*buf = make([]byte, size)
+	}
+	*buf = (*buf)[:size]
+	return buf
+}
+
+func putFrameBuffer(buf *[]byte) {
+	frameBufferPool.Put(buf)
+}
 
 // rlpx is the transport protocol used by actual (non-test) connections.
 // It wraps the frame encoder with locks and read/write deadlines.
 type rlpx struct {
-	fd net.Conn
+	fd          net.Conn
+	requireAEAD bool // reject peers which only support the legacy framing
 
 	rmu, wmu sync.Mutex
 	rw       *rlpxFrameRW
@@ -88,6 +123,14 @@ func newRLPX(fd net.Conn) transport {
 	return &rlpx{fd: fd}
 }
 
+// newAEADRLPX creates a transport which only accepts peers supporting sealed
+// frames.
+func newAEADRLPX(fd net.Conn) transport {
+	t := newRLPX(fd).(*rlpx)
+	t.requireAEAD = true
+	return t
+}
+
 func (t *rlpx) ReadMsg() (Msg, error) {
 	t.rmu.Lock()
 	defer t.rmu.Unlock()
@@ -188,6 +231,9 @@ func (t *rlpx) doEncHandshake(prv *ecdsa.PrivateKey, dial *discover.Node) (disco
 	if err != nil {
 		return discover.NodeID{}, err
 	}
+	if t.requireAEAD && sec.EgressKey == nil {
+		return discover.NodeID{}, errLegacyTransport
+	}
 	t.wmu.Lock()
 	t.rw = newRLPXFrameRW(t.fd, sec)
 	t.wmu.Unlock()
@@ -203,6 +249,7 @@ type encHandshake struct {
 	initNonce, respNonce []byte            // nonce
 	randomPrivKey        *ecies.PrivateKey // ecdhe-random
 	remoteRandomPub      *ecies.PublicKey  // ecdhe-random-pubk
+	remoteVersion        uint              // handshake version of the remote side
 }
 
 // secrets represents the connection secrets
@@ -212,6 +259,9 @@ type secrets struct {
 	AES, MAC              []byte
 	EgressMAC, IngressMAC hash.Hash
 	Token                 []byte
+
+	// Keys of the sealed frames, nil if the legacy framing is used
+	EgressKey, IngressKey []byte
 }
 
 // RLPx v4 handshake auth (defined in EIP-8).
@@ -267,6 +317,18 @@ func (h *encHandshake) secrets(auth, authResp []byte) (secrets, error) {
 		s.EgressMAC, s.IngressMAC = mac2, mac1
 	}
 
+	// If both sides support sealed frames, derive a key per direction. The
+	// keys commit to the handshake messages, which carry the versions, so a
+	// man in the middle can't downgrade the connection to the legacy framing.
+	if h.remoteVersion >= aeadHandshakeVersion {
+		initKey := crypto.Keccak256(sharedSecret, []byte("initiator"), auth, authResp)
+		respKey := crypto.Keccak256(sharedSecret, []byte("recipient"), auth, authResp)
+		if h.initiator {
+			s.EgressKey, s.IngressKey = initKey, respKey
+		} else {
+			s.EgressKey, s.IngressKey = respKey, initKey
+		}
+	}
 	return s, nil
 }
 
@@ -338,12 +400,13 @@ func (h *encHandshake) makeAuthMsg(prv *ecdsa.PrivateKey, token []byte) (*authMs
 	copy(msg.Signature[:], signature)
 	copy(msg.InitiatorPubkey[:], crypto.FromECDSAPub(&prv.PublicKey)[1:])
 	copy(msg.Nonce[:], h.initNonce)
-	msg.Version = 4
+	msg.Version = aeadHandshakeVersion
 	return msg, nil
 }
 
 func (h *encHandshake) handleAuthResp(msg *authRespV4) (err error) {
 	h.respNonce = msg.Nonce[:]
+	h.remoteVersion = msg.Version
 	h.remoteRandomPub, err = importPublicKey(msg.RandomPubkey[:])
 	return err
 }
@@ -386,6 +449,7 @@ func receiverEncHandshake(conn io.ReadWriter, prv *ecdsa.PrivateKey, token []byt
 func (h *encHandshake) handleAuthMsg(msg *authMsgV4, prv *ecdsa.PrivateKey) error {
 	// Import the remote identity.
 	h.initNonce = msg.Nonce[:]
+	h.remoteVersion = msg.Version
 	h.remoteID = msg.InitiatorPubkey
 	rpub, err := h.remoteID.Pubkey()
 	if err != nil {
@@ -426,7 +490,7 @@ func (h *encHandshake) makeAuthResp() (msg *authRespV4, err error) {
 	msg = new(authRespV4)
 	copy(msg.Nonce[:], h.respNonce)
 	copy(msg.RandomPubkey[:], exportPubkey(&h.randomPrivKey.PublicKey))
-	msg.Version = 4
+	msg.Version = aeadHandshakeVersion
 	return msg, nil
 }
 
@@ -562,6 +626,10 @@ var (
 // chunked messages are not supported and all headers are equal to
 // zeroHeader.
 //
+// If the handshake negotiated sealed frames, each frame is instead a size
+// header and a body, both sealed with AES-256-GCM under the key of the
+// direction and a nonce counting the sealed messages.
+//
 // rlpxFrameRW is not safe for concurrent use from multiple goroutines.
 type rlpxFrameRW struct {
 	conn io.ReadWriter
@@ -572,6 +640,9 @@ type rlpxFrameRW struct {
 	egressMAC  hash.Hash
 	ingressMAC hash.Hash
 
+	egressAEAD, ingressAEAD   cipher.AEAD
+	egressNonce, ingressNonce uint64
+
 	snappy bool
 }
 
@@ -587,7 +658,7 @@ func newRLPXFrameRW(conn io.ReadWriter, s secrets) *rlpxFrameRW {
 	// we use an all-zeroes IV for AES because the key used
 	// for encryption is ephemeral.
 	iv := make([]byte, encc.BlockSize())
-	return &rlpxFrameRW{
+	rw := &rlpxFrameRW{
 		conn:       conn,
 		enc:        cipher.NewCTR(encc, iv),
 		dec:        cipher.NewCTR(encc, iv),
@@ -595,6 +666,32 @@ func newRLPXFrameRW(conn io.ReadWriter, s secrets) *rlpxFrameRW {
 		egressMAC:  s.EgressMAC,
 		ingressMAC: s.IngressMAC,
 	}
+	if s.EgressKey != nil {
+		rw.egressAEAD = newFrameAEAD(s.EgressKey)
+		rw.ingressAEAD = newFrameAEAD(s.IngressKey)
+	}
+	return rw
+}
+
+func newFrameAEAD(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic("invalid frame key: " + err.Error())
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic("invalid frame key: " + err.Error())
+	}
+	return aead
+}
+
+// nextNonce returns the GCM nonce of the next message sealed with the given
+// counter.
+func nextNonce(counter *uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], *counter)
+	*counter++
+	return nonce
 }
 
 func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
@@ -605,12 +702,21 @@ func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
 		if msg.Size > maxUint24 {
 			return errPlainMessageTooLarge
 		}
-		payload, _ := ioutil.ReadAll(msg.Payload)
-		payload = snappy.Encode(nil, payload)
+		plain := getFrameBuffer(int(msg.Size))
+		defer putFrameBuffer(plain)
+		if _, err := io.ReadFull(msg.Payload, *plain); err != nil {
+			return err
+		}
+		compressed := getFrameBuffer(snappy.MaxEncodedLen(len(*plain)))
+		defer putFrameBuffer(compressed)
+		payload := snappy.Encode(*compressed, *plain)
 
 		msg.Payload = bytes.NewReader(payload)
 		msg.Size = uint32(len(payload))
 	}
+	if rw.egressAEAD != nil {
+		return rw.writeSealed(ptype, msg)
+	}
 	// write header
 	headbuf := make([]byte, 32)
 	fsize := uint32(len(ptype)) + msg.Size
@@ -650,16 +756,97 @@ func (rw *rlpxFrameRW) WriteMsg(msg Msg) error {
 	return err
 }
 
+// writeSealed writes a message as a sealed frame.
+func (rw *rlpxFrameRW) writeSealed(ptype []byte, msg Msg) error {
+	fsize := uint32(len(ptype)) + msg.Size
+	if fsize > maxUint24 {
+		return errors.New("message size overflows uint24")
+	}
+	overhead := rw.egressAEAD.Overhead()
+	buf := getFrameBuffer(aeadHeaderLen + int(fsize) + overhead)
+	defer putFrameBuffer(buf)
+
+	frame := *buf
+	putInt24(fsize, frame)
+	rw.egressAEAD.Seal(frame[:0], nextNonce(&rw.egressNonce), frame[:3], nil)
+
+	body := frame[aeadHeaderLen:]
+	n := copy(body, ptype)
+	if _, err := io.ReadFull(msg.Payload, body[n:fsize]); err != nil {
+		return err
+	}
+	rw.egressAEAD.Seal(body[:0], nextNonce(&rw.egressNonce), body[:fsize], nil)
+
+	_, err := rw.conn.Write(frame)
+	return err
+}
+
 func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
+	var (
+		frame   []byte
+		release func()
+	)
+	if rw.ingressAEAD != nil {
+		frame, release, err = rw.readSealed()
+	} else {
+		frame, release, err = rw.readFrame()
+	}
+	if release != nil {
+		defer release()
+	}
+	if err != nil {
+		return msg, err
+	}
+
+	// decode message code
+	content := bytes.NewReader(frame)
+	if err := rlp.Decode(content, &msg.Code); err != nil {
+		return msg, err
+	}
+	msg.Size = uint32(content.Len())
+	msg.Payload = content
+
+	// if snappy is enabled, verify and decompress message
+	if rw.snappy {
+		payload := frame[len(frame)-content.Len():]
+		size, err := snappy.DecodedLen(payload)
+		if err != nil {
+			return msg, err
+		}
+		if size > int(maxUint24) {
+			return msg, errPlainMessageTooLarge
+		}
+		payload, err = snappy.Decode(nil, payload)
+		if err != nil {
+			return msg, err
+		}
+		msg.Size, msg.Payload = uint32(size), bytes.NewReader(payload)
+	}
+	return msg, nil
+}
+
+// frameBuffer returns a buffer to read a frame of size bytes in. Compressed
+// frames are not needed once decompressed, so their buffer is pooled and must
+// be released by calling release, if not nil, after decoding.
+func (rw *rlpxFrameRW) frameBuffer(size int) (buf []byte, release func()) {
+	if !rw.snappy {
+		return make([]byte, size), nil
+	}
+	pooled := getFrameBuffer(size)
+	return *pooled, func() { putFrameBuffer(pooled) }
+}
+
+// readFrame reads and decrypts a legacy frame, returning its content.
+func (rw *rlpxFrameRW) readFrame() (frame []byte, release func(), err error) {
 	// read the header
 	headbuf := make([]byte, 32)
 	if _, err := io.ReadFull(rw.conn, headbuf); err != nil {
-		return msg, err
+		return nil, nil, err
 	}
 	// verify header mac
 	shouldMAC := updateMAC(rw.ingressMAC, rw.macCipher, headbuf[:16])
 	if !hmac.Equal(shouldMAC, headbuf[16:]) {
-		return msg, errors.New("bad header MAC")
+		return nil, nil, errors.New("bad header MAC")
 	}
 	rw.dec.XORKeyStream(headbuf[:16], headbuf[:16]) // first half is now decrypted
 	fsize := readInt24(headbuf)
@@ -670,53 +857,47 @@ func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
 	if padding := fsize % 16; padding > 0 {
 		rsize += 16 - padding
 	}
-	framebuf := make([]byte, rsize)
+	framebuf, release := rw.frameBuffer(int(rsize))
 	if _, err := io.ReadFull(rw.conn, framebuf); err != nil {
-		return msg, err
+		return nil, release, err
 	}
 
 	// read and validate frame MAC. we can re-use headbuf for that.
 	rw.ingressMAC.Write(framebuf)
 	fmacseed := rw.ingressMAC.Sum(nil)
 	if _, err := io.ReadFull(rw.conn, headbuf[:16]); err != nil {
-		return msg, err
+		return nil, release, err
 	}
 	shouldMAC = updateMAC(rw.ingressMAC, rw.macCipher, fmacseed)
 	if !hmac.Equal(shouldMAC, headbuf[:16]) {
-		return msg, errors.New("bad frame MAC")
+		return nil, release, errors.New("bad frame MAC")
 	}
 
 	// decrypt frame content
 	rw.dec.XORKeyStream(framebuf, framebuf)
+	return framebuf[:fsize], release, nil
+}
 
-	// decode message code
-	content := bytes.NewReader(framebuf[:fsize])
-	if err := rlp.Decode(content, &msg.Code); err != nil {
-		return msg, err
+// readSealed reads and opens a sealed frame, returning its content.
+func (rw *rlpxFrameRW) readSealed() (frame []byte, release func(), err error) {
+	headbuf := make([]byte, aeadHeaderLen)
+	if _, err := io.ReadFull(rw.conn, headbuf); err != nil {
+		return nil, nil, err
 	}
-	msg.Size = uint32(content.Len())
-	msg.Payload = content
+	header, err := rw.ingressAEAD.Open(headbuf[:0], nextNonce(&rw.ingressNonce), headbuf, nil)
+	if err != nil {
+		return nil, nil, errors.New("bad header seal")
+	}
+	fsize := readInt24(header)
 
-	// if snappy is enabled, verify and decompress message
-	if rw.snappy {
-		payload, err := ioutil.ReadAll(msg.Payload)
-		if err != nil {
-			return msg, err
-		}
-		size, err := snappy.DecodedLen(payload)
-		if err != nil {
-			return msg, err
-		}
-		if size > int(maxUint24) {
-			return msg, errPlainMessageTooLarge
-		}
-		payload, err = snappy.Decode(nil, payload)
-		if err != nil {
-			return msg, err
-		}
-		msg.Size, msg.Payload = uint32(size), bytes.NewReader(payload)
+	framebuf, release := rw.frameBuffer(int(fsize) + rw.ingressAEAD.Overhead())
+	if _, err := io.ReadFull(rw.conn, framebuf); err != nil {
+		return nil, release, err
 	}
-	return msg, nil
+	if frame, err = rw.ingressAEAD.Open(framebuf[:0], nextNonce(&rw.ingressNonce), framebuf, nil); err != nil {
+		return nil, release, errors.New("bad frame seal")
+	}
+	return frame, release, nil
 }
 
 // updateMAC reseeds the given hash with encrypted seed.
@@ -353,8 +353,11 @@ func TestRLPXFrameRW(t *testing.T) {
 	s2.IngressMAC.Write(egressMACinit)
 	rw2 := newRLPXFrameRW(conn, s2)
 
-	// send some messages
+	// send some messages, the last ones compressed in the pooled frame buffers
 	for i := 0; i < 10; i++ {
+		if i == 5 {
+			rw1.snappy, rw2.snappy = true, true
+		}
 		// write message into conn buffer
 		wmsg := []interface{}{"foo", "bar", strings.Repeat("test", i)}
 		err := Send(rw1, uint64(i), wmsg)
@@ -378,6 +381,139 @@ func TestRLPXFrameRW(t *testing.T) {
 	}
 }
 
+func TestRLPXFrameRWSealed(t *testing.T) {
+	var (
+		key1 = make([]byte, 32)
+		key2 = make([]byte, 32)
+		conn = new(bytes.Buffer)
+	)
+	rand.Read(key1)
+	rand.Read(key2)
+	legacy := secrets{AES: make([]byte, 16), MAC: make([]byte, 16)}
+	s1, s2 := legacy, legacy
+	s1.EgressMAC, s1.IngressMAC = sha3.NewLegacyKeccak256(), sha3.NewLegacyKeccak256()
+	s2.EgressMAC, s2.IngressMAC = sha3.NewLegacyKeccak256(), sha3.NewLegacyKeccak256()
+	s1.EgressKey, s1.IngressKey = key1, key2
+	s2.EgressKey, s2.IngressKey = key2, key1
+	rw1, rw2 := newRLPXFrameRW(conn, s1), newRLPXFrameRW(conn, s2)
+
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			rw1.snappy, rw2.snappy = true, true
+		}
+		wmsg := []interface{}{"foo", "bar", strings.Repeat("test", i)}
+		if err := Send(rw1, uint64(i), wmsg); err != nil {
+			t.Fatalf("WriteMsg error (i=%d): %v", i, err)
+		}
+		msg, err := rw2.ReadMsg()
+		if err != nil {
+			t.Fatalf("ReadMsg error (i=%d): %v", i, err)
+		}
+		if msg.Code != uint64(i) {
+			t.Fatalf("msg code mismatch: got %d, want %d", msg.Code, i)
+		}
+		payload, _ := ioutil.ReadAll(msg.Payload)
+		wantPayload, _ := rlp.EncodeToBytes(wmsg)
+		if !bytes.Equal(payload, wantPayload) {
+			t.Fatalf("msg payload mismatch:\ngot  %x\nwant %x", payload, wantPayload)
+		}
+	}
+
+	// Any altered byte of a sealed frame must be detected
+	if err := Send(rw1, 1, []uint{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	frame := conn.Bytes()
+	frame[len(frame)-1] ^= 0x01
+	if _, err := rw2.ReadMsg(); err == nil {
+		t.Fatal("altered frame accepted")
+	}
+	// and so must be replayed frames
+	rw3 := newRLPXFrameRW(conn, s1)
+	Send(rw3, 1, []uint{1, 2, 3})
+	if _, err := rw2.ReadMsg(); err == nil {
+		t.Fatal("replayed frame accepted")
+	}
+}
+
+func TestHandshakeAEADNegotiation(t *testing.T) {
+	var (
+		initPriv, _ = ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+		respPriv, _ = ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+		nonce       = make([]byte, shaLen)
+		auth, resp  = []byte("auth"), []byte("resp")
+	)
+	handshakes := func(version uint) (*encHandshake, *encHandshake) {
+		init := &encHandshake{initiator: true, initNonce: nonce, respNonce: nonce, randomPrivKey: initPriv, remoteRandomPub: &respPriv.PublicKey, remoteVersion: version}
+		recv := &encHandshake{initNonce: nonce, respNonce: nonce, randomPrivKey: respPriv, remoteRandomPub: &initPriv.PublicKey, remoteVersion: version}
+		return init, recv
+	}
+	for _, version := range []uint{4, aeadHandshakeVersion} {
+		init, recv := handshakes(version)
+		s1, err := init.secrets(auth, resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, err := recv.secrets(auth, resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sealed := s1.EgressKey != nil; sealed != (version >= aeadHandshakeVersion) {
+			t.Errorf("version %d: sealed frames %v", version, sealed)
+		}
+		if !bytes.Equal(s1.EgressKey, s2.IngressKey) || !bytes.Equal(s1.IngressKey, s2.EgressKey) {
+			t.Errorf("version %d: frame keys mismatch", version)
+		}
+		if version >= aeadHandshakeVersion && bytes.Equal(s1.EgressKey, s1.IngressKey) {
+			t.Errorf("version %d: same key in both directions", version)
+		}
+	}
+	// The keys commit to the handshake messages
+	init, _ := handshakes(aeadHandshakeVersion)
+	s1, _ := init.secrets(auth, resp)
+	s2, _ := init.secrets(auth, []byte("other"))
+	if bytes.Equal(s1.EgressKey, s2.EgressKey) {
+		t.Error("frame keys don't depend on the handshake messages")
+	}
+}
+
+func TestRequireAEADRejectsLegacyPeer(t *testing.T) {
+	var (
+		prv0, _  = crypto.GenerateKey()
+		prv1, _  = crypto.GenerateKey()
+		fd0, fd1 = net.Pipe()
+		recv     = newAEADRLPX(fd1).(*rlpx)
+		errc     = make(chan error, 1)
+	)
+	defer fd0.Close()
+	defer fd1.Close()
+
+	go func() {
+		_, err := recv.doEncHandshake(prv1, nil)
+		errc <- err
+	}()
+	// Initiate the handshake as a peer with the legacy framing only
+	h := &encHandshake{initiator: true, remoteID: discover.PubkeyID(&prv1.PublicKey)}
+	authMsg, err := h.makeAuthMsg(prv0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authMsg.Version = 4
+	authPacket, err := sealEIP8(authMsg, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd0.Write(authPacket); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readHandshakeMsg(new(authRespV4), encAuthRespLen, prv0, fd0); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != errLegacyTransport {
+		t.Errorf("legacy peer accepted: %v", err)
+	}
+}
+
 type handshakeAuthTest struct {
 	input       string
 	isPlain     bool
@@ -114,6 +114,16 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// PeerFilter denies or exclusively allows peers by node ID and IP range,
+	// both when dialing and accepting connections. It can be changed while
+	// the server is running, see DropFilteredPeers.
+	PeerFilter *PeerFilter `toml:"-"`
+
+	// RequireAEAD rejects the peers which don't support frames sealed with
+	// AES-GCM, instead of falling back to the legacy RLPx framing with them.
+	// It should be set once all the nodes of the network have upgraded.
+	RequireAEAD bool `toml:",omitempty"`
+
 	// NodeDatabase is the path to the database containing the previously seen
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
@@ -320,6 +330,23 @@ func (srv *Server) RemovePeer(node *discover.Node) {
 	}
 }
 
+// DropFilteredPeers disconnects the connected peers the peer filter does not
+// let through anymore. It is to be called after the filter rules change.
+func (srv *Server) DropFilteredPeers() {
+	select {
+	case srv.peerOp <- func(peers map[discover.NodeID]*Peer) {
+		for _, p := range peers {
+			if err := srv.PeerFilter.Check(p.ID(), remoteIP(p.RemoteAddr())); err != nil {
+				p.log.Debug("Dropping filtered peer", "err", err)
+				p.Disconnect(DiscRequested)
+			}
+		}
+	}:
+		<-srv.peerOpDone
+	case <-srv.quit:
+	}
+}
+
 // SubscribePeers subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -422,6 +449,9 @@ func (srv *Server) Start() (err error) {
 	}
 	if srv.newTransport == nil {
 		srv.newTransport = newRLPX
+		if srv.RequireAEAD {
+			srv.newTransport = newAEADRLPX
+		}
 	}
 	if srv.Dialer == nil {
 		srv.Dialer = TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}
@@ -510,6 +540,7 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer.filter = srv.PeerFilter
 
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
@@ -781,6 +812,9 @@ func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, inbound
 }
 
 func (srv *Server) encHandshakeChecks(peers map[discover.NodeID]*Peer, inboundCount int, c *conn) error {
+	if err := srv.PeerFilter.Check(c.id, remoteIP(c.fd.RemoteAddr())); err != nil {
+		return err
+	}
 	switch {
 	case !c.is(trustedConn|staticDialedConn) && len(peers) >= srv.MaxPeers:
 		return DiscTooManyPeers
@@ -858,6 +892,13 @@ func (srv *Server) listenLoop() {
 				continue
 			}
 		}
+		// Reject connections from denied addresses before the handshake.
+		if err := srv.PeerFilter.CheckIP(remoteIP(fd.RemoteAddr())); err != nil {
+			srv.log.Debug("Rejected conn", "addr", fd.RemoteAddr(), "err", err)
+			fd.Close()
+			slots <- struct{}{}
+			continue
+		}
 
 		fd = newMeteredConn(fd, true)
 		srv.log.Trace("Accepted connection", "addr", fd.RemoteAddr())
@@ -17,6 +17,7 @@
 package params
 
 import (
+	"encoding/binary"
 	"fmt"
 	"math/big"
 
@@ -66,7 +67,7 @@ var (
 		},
 	}
 
-	TestChainConfig = &ChainConfig{"", big.NewInt(1), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil}
+	TestChainConfig = &ChainConfig{"", big.NewInt(1), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -97,6 +98,36 @@ type ChainConfig struct {
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
 
+	EthBridgeBlock *big.Int `json:"ethBridgeBlock,omitempty"` // Ethereum bridge precompile switch block (nil = no fork, 0 = already activated)
+
+	SideChainIdBlock *big.Int `json:"sideChainIdBlock,omitempty"` // Side chains starting from this block get wallet safe chain ids, and blocks take replay protected transactions only (nil = no fork, 0 = already activated)
+
+	TX3ReplayBlock *big.Int `json:"tx3ReplayBlock,omitempty"` // TX3s withdrawn on the main chain are recorded in state from this block (nil = no fork, 0 = already activated)
+
+	CrossChainFeeBlock *big.Int `json:"crossChainFeeBlock,omitempty"` // TX3s burn and TX4s credit the amounts transferred, charging the cross chain fees, from this block (nil = no fork, 0 = already activated)
+
+	SideChainCapacityBlock *big.Int `json:"sideChainCapacityBlock,omitempty"` // JoinSideChain records the side chains of the validators in state and enforces their limit from this block (nil = no fork, 0 = already activated)
+
+	AssetRegistryBlock *big.Int `json:"assetRegistryBlock,omitempty"` // Asset registry switch block, the side chains launched from it are recorded in state (nil = no fork, 0 = already activated)
+
+	BlockTimeBlock *big.Int `json:"blockTimeBlock,omitempty"` // Block time switch block, the headers must not go back in time from it (nil = no fork, 0 = already activated)
+
+	GovernanceBlock *big.Int `json:"governanceBlock,omitempty"` // Governance switch block, the governance proposals are accepted and the parameters they set applied from it (nil = no fork, 0 = already activated)
+
+	RewardClaimBlock *big.Int `json:"rewardClaimBlock,omitempty"` // Reward claim switch block, the rewards withdrawn are recorded in state from it (nil = no fork, 0 = already activated)
+
+	CommissionScheduleBlock *big.Int `json:"commissionScheduleBlock,omitempty"` // Commission schedule switch block, the commissions set change from the next epoch within the governed limits from it (nil = no fork, 0 = already activated)
+
+	ValidatorMetadataBlock *big.Int `json:"validatorMetadataBlock,omitempty"` // Validator metadata switch block, the metadata edited by the validators is recorded in state from it (nil = no fork, 0 = already activated)
+
+	AutoCompoundBlock *big.Int `json:"autoCompoundBlock,omitempty"` // Auto compound switch block, the delegators can have their rewards delegated again at each epoch from it (nil = no fork, 0 = already activated)
+
+	MinSelfBondBlock *big.Int `json:"minSelfBondBlock,omitempty"` // Minimum self bond switch block, the candidates can commit to a minimum self bond, banned at the epoch below it, from it (nil = no fork, 0 = already activated)
+
+	BridgeBlock *big.Int `json:"bridgeBlock,omitempty"` // Bridge switch block, the bridge light clients and packets between the chains are accepted from it (nil = no fork, 0 = already activated)
+
+	KeyRotationBlock *big.Int `json:"keyRotationBlock,omitempty"` // Key rotation switch block, the validators can rotate their consensus key, signing with the new key from the next epoch, from it (nil = no fork, 0 = already activated)
+
 	// Various consensus engines
 	NeatPoS *NeatPoSConfig `json:"neatpos,omitempty"`
 
@@ -115,9 +146,10 @@ func (c *NeatPoSConfig) String() string {
 }
 
 // Create a new Chain Config based on the Chain ID, for side chain creation purpose
-func NewSideChainConfig(sideChainID string) *ChainConfig {
+func NewSideChainConfig(sideChainID string, chainId *big.Int) *ChainConfig {
 	config := &ChainConfig{
 		NeatChainId:    sideChainID,
+		ChainId:        chainId,
 		HomesteadBlock: big.NewInt(0),
 		EIP150Block:    big.NewInt(0),
 		EIP150Hash:     common.HexToHash("0x2086799aeebeae135c246c65021c82b4e15a2c451340993aacfd2751886514f0"),
@@ -131,13 +163,39 @@ func NewSideChainConfig(sideChainID string) *ChainConfig {
 			ProposerPolicy: 0,
 		},
 	}
-
-	digest := crypto.Keccak256([]byte(config.NeatChainId))
-	config.ChainId = new(big.Int).SetBytes(digest[:])
+	// A side chain with a wallet safe chain id started past the fork, it takes
+	// replay protected transactions only from its genesis
+	if chainId != nil && chainId.Cmp(SideChainId(sideChainID)) == 0 {
+		config.SideChainIdBlock = big.NewInt(0)
+	}
 
 	return config
 }
 
+// Side chains get a chain id between SideChainIdBase and 2^53, above the ids
+// of the main chains and within the integers wallets represent exactly.
+const (
+	SideChainIdBase  = 1 << 32
+	sideChainIdRange = 1<<53 - SideChainIdBase
+)
+
+// SideChainId returns the chain id of the side chain sideChainID, derived from
+// its name.
+func SideChainId(sideChainID string) *big.Int {
+	digest := crypto.Keccak256([]byte(sideChainID))
+	return new(big.Int).SetUint64(SideChainIdBase + binary.BigEndian.Uint64(digest)%sideChainIdRange)
+}
+
+// SideChainIdAt returns the chain id of the side chain sideChainID, starting
+// at block startBlock of the main chain. The side chains started before
+// SideChainIdBlock keep the hash of their name as chain id.
+func (c *ChainConfig) SideChainIdAt(sideChainID string, startBlock *big.Int) *big.Int {
+	if c.IsSideChainId(startBlock) {
+		return SideChainId(sideChainID)
+	}
+	return new(big.Int).SetBytes(crypto.Keccak256([]byte(sideChainID)))
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -147,7 +205,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{NeatChainId: %s ChainID: %v Homestead: %v  EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v}",
+	return fmt.Sprintf("{NeatChainId: %s ChainID: %v Homestead: %v  EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v EthBridge: %v SideChainId: %v TX3Replay: %v CrossChainFee: %v SideChainCapacity: %v AssetRegistry: %v BlockTime: %v Governance: %v RewardClaim: %v CommissionSchedule: %v ValidatorMetadata: %v AutoCompound: %v MinSelfBond: %v Bridge: %v KeyRotation: %v Engine: %v}",
 		c.NeatChainId,
 		c.ChainId,
 		c.HomesteadBlock,
@@ -156,6 +214,21 @@ func (c *ChainConfig) String() string {
 		c.EIP158Block,
 		c.ByzantiumBlock,
 		c.ConstantinopleBlock,
+		c.EthBridgeBlock,
+		c.SideChainIdBlock,
+		c.TX3ReplayBlock,
+		c.CrossChainFeeBlock,
+		c.SideChainCapacityBlock,
+		c.AssetRegistryBlock,
+		c.BlockTimeBlock,
+		c.GovernanceBlock,
+		c.RewardClaimBlock,
+		c.CommissionScheduleBlock,
+		c.ValidatorMetadataBlock,
+		c.AutoCompoundBlock,
+		c.MinSelfBondBlock,
+		c.BridgeBlock,
+		c.KeyRotationBlock,
 		engine,
 	)
 }
@@ -185,6 +258,103 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsEthBridge returns whether num is either equal to the block activating the
+// Ethereum bridge precompile or greater.
+func (c *ChainConfig) IsEthBridge(num *big.Int) bool {
+	return isForked(c.EthBridgeBlock, num)
+}
+
+// IsSideChainId returns whether num is either equal to the block from which
+// the side chains get wallet safe chain ids, and the transactions must be
+// replay protected, or greater.
+func (c *ChainConfig) IsSideChainId(num *big.Int) bool {
+	return isForked(c.SideChainIdBlock, num)
+}
+
+// IsTX3Replay returns whether num is either equal to the block from which
+// the TX3s withdrawn are recorded in state or greater.
+func (c *ChainConfig) IsTX3Replay(num *big.Int) bool {
+	return isForked(c.TX3ReplayBlock, num)
+}
+
+// IsCrossChainFee returns whether num is either equal to the block from
+// which the cross chain transfers move their amounts and charge the cross
+// chain fees or greater.
+func (c *ChainConfig) IsCrossChainFee(num *big.Int) bool {
+	return isForked(c.CrossChainFeeBlock, num)
+}
+
+// IsSideChainCapacity returns whether num is either equal to the block from
+// which the side chains joined by the validators are recorded in state and
+// limited or greater.
+func (c *ChainConfig) IsSideChainCapacity(num *big.Int) bool {
+	return isForked(c.SideChainCapacityBlock, num)
+}
+
+// IsAssetRegistry returns whether num is either equal to the block
+// activating the asset registry and recording the side chains launched in
+// state or greater.
+func (c *ChainConfig) IsAssetRegistry(num *big.Int) bool {
+	return isForked(c.AssetRegistryBlock, num)
+}
+
+// IsBlockTime returns whether num is either equal to the block from which
+// the block times must increase or greater.
+func (c *ChainConfig) IsBlockTime(num *big.Int) bool {
+	return isForked(c.BlockTimeBlock, num)
+}
+
+// IsGovernance returns whether num is either equal to the block from which
+// the governance proposals are accepted and the parameters set by them are
+// applied or greater.
+func (c *ChainConfig) IsGovernance(num *big.Int) bool {
+	return isForked(c.GovernanceBlock, num)
+}
+
+// IsRewardClaim returns whether num is either equal to the block from which
+// the rewards withdrawn are recorded in state or greater.
+func (c *ChainConfig) IsRewardClaim(num *big.Int) bool {
+	return isForked(c.RewardClaimBlock, num)
+}
+
+// IsCommissionSchedule returns whether num is either equal to the block from
+// which the commissions set change from the next epoch within the governed
+// limits or greater.
+func (c *ChainConfig) IsCommissionSchedule(num *big.Int) bool {
+	return isForked(c.CommissionScheduleBlock, num)
+}
+
+// IsValidatorMetadata returns whether num is either equal to the block from
+// which the metadata edited by the validators is recorded in state or greater.
+func (c *ChainConfig) IsValidatorMetadata(num *big.Int) bool {
+	return isForked(c.ValidatorMetadataBlock, num)
+}
+
+// IsAutoCompound returns whether num is either equal to the block from which
+// the delegators can have their rewards delegated again at each epoch or
+// greater.
+func (c *ChainConfig) IsAutoCompound(num *big.Int) bool {
+	return isForked(c.AutoCompoundBlock, num)
+}
+
+// IsMinSelfBond returns whether num is either equal to the block from which the
+// candidates can commit to a minimum self bond or greater.
+func (c *ChainConfig) IsMinSelfBond(num *big.Int) bool {
+	return isForked(c.MinSelfBondBlock, num)
+}
+
+// IsBridge returns whether num is either equal to the block from which the
+// bridge light clients and packets are accepted or greater.
+func (c *ChainConfig) IsBridge(num *big.Int) bool {
+	return isForked(c.BridgeBlock, num)
+}
+
+// IsKeyRotation returns whether num is either equal to the block from which the
+// validators can rotate their consensus key or greater.
+func (c *ChainConfig) IsKeyRotation(num *big.Int) bool {
+	return isForked(c.KeyRotationBlock, num)
+}
+
 func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 	return false
 }
@@ -256,6 +426,51 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.EthBridgeBlock, newcfg.EthBridgeBlock, head) {
+		return newCompatError("Ethereum bridge fork block", c.EthBridgeBlock, newcfg.EthBridgeBlock)
+	}
+	if isForkIncompatible(c.SideChainIdBlock, newcfg.SideChainIdBlock, head) {
+		return newCompatError("Side chain id fork block", c.SideChainIdBlock, newcfg.SideChainIdBlock)
+	}
+	if isForkIncompatible(c.TX3ReplayBlock, newcfg.TX3ReplayBlock, head) {
+		return newCompatError("TX3 replay fork block", c.TX3ReplayBlock, newcfg.TX3ReplayBlock)
+	}
+	if isForkIncompatible(c.CrossChainFeeBlock, newcfg.CrossChainFeeBlock, head) {
+		return newCompatError("Cross chain fee fork block", c.CrossChainFeeBlock, newcfg.CrossChainFeeBlock)
+	}
+	if isForkIncompatible(c.SideChainCapacityBlock, newcfg.SideChainCapacityBlock, head) {
+		return newCompatError("Side chain capacity fork block", c.SideChainCapacityBlock, newcfg.SideChainCapacityBlock)
+	}
+	if isForkIncompatible(c.AssetRegistryBlock, newcfg.AssetRegistryBlock, head) {
+		return newCompatError("Asset registry fork block", c.AssetRegistryBlock, newcfg.AssetRegistryBlock)
+	}
+	if isForkIncompatible(c.BlockTimeBlock, newcfg.BlockTimeBlock, head) {
+		return newCompatError("Block time fork block", c.BlockTimeBlock, newcfg.BlockTimeBlock)
+	}
+	if isForkIncompatible(c.GovernanceBlock, newcfg.GovernanceBlock, head) {
+		return newCompatError("Governance fork block", c.GovernanceBlock, newcfg.GovernanceBlock)
+	}
+	if isForkIncompatible(c.RewardClaimBlock, newcfg.RewardClaimBlock, head) {
+		return newCompatError("Reward claim fork block", c.RewardClaimBlock, newcfg.RewardClaimBlock)
+	}
+	if isForkIncompatible(c.CommissionScheduleBlock, newcfg.CommissionScheduleBlock, head) {
+		return newCompatError("Commission schedule fork block", c.CommissionScheduleBlock, newcfg.CommissionScheduleBlock)
+	}
+	if isForkIncompatible(c.ValidatorMetadataBlock, newcfg.ValidatorMetadataBlock, head) {
+		return newCompatError("Validator metadata fork block", c.ValidatorMetadataBlock, newcfg.ValidatorMetadataBlock)
+	}
+	if isForkIncompatible(c.AutoCompoundBlock, newcfg.AutoCompoundBlock, head) {
+		return newCompatError("Auto compound fork block", c.AutoCompoundBlock, newcfg.AutoCompoundBlock)
+	}
+	if isForkIncompatible(c.MinSelfBondBlock, newcfg.MinSelfBondBlock, head) {
+		return newCompatError("Minimum self bond fork block", c.MinSelfBondBlock, newcfg.MinSelfBondBlock)
+	}
+	if isForkIncompatible(c.BridgeBlock, newcfg.BridgeBlock, head) {
+		return newCompatError("Bridge fork block", c.BridgeBlock, newcfg.BridgeBlock)
+	}
+	if isForkIncompatible(c.KeyRotationBlock, newcfg.KeyRotationBlock, head) {
+		return newCompatError("Key rotation fork block", c.KeyRotationBlock, newcfg.KeyRotationBlock)
+	}
 	return nil
 }
 
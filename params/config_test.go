@@ -0,0 +1,38 @@
+package params
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/neatlab/neatio/crypto"
+)
+
+func TestSideChainIdAt(t *testing.T) {
+	config := &ChainConfig{SideChainIdBlock: big.NewInt(100)}
+
+	legacy := config.SideChainIdAt("side_0", big.NewInt(99))
+	if want := new(big.Int).SetBytes(crypto.Keccak256([]byte("side_0"))); legacy.Cmp(want) != 0 {
+		t.Errorf("side chain id before the fork: have %v, want %v", legacy, want)
+	}
+
+	id := config.SideChainIdAt("side_0", big.NewInt(100))
+	if id.Cmp(SideChainId("side_0")) != 0 {
+		t.Errorf("side chain id after the fork: have %v, want %v", id, SideChainId("side_0"))
+	}
+	if id.Cmp(big.NewInt(SideChainIdBase)) < 0 || id.Cmp(big.NewInt(1<<53)) >= 0 {
+		t.Errorf("side chain id %v out of the wallet safe range", id)
+	}
+	if id.Cmp(SideChainId("side_1")) == 0 {
+		t.Errorf("side chains side_0 and side_1 share the chain id %v", id)
+	}
+}
+
+func TestNewSideChainConfigReplayProtection(t *testing.T) {
+	if config := NewSideChainConfig("side_0", SideChainId("side_0")); !config.IsSideChainId(big.NewInt(0)) {
+		t.Error("side chain with a wallet safe chain id takes unprotected transactions")
+	}
+	legacy := new(big.Int).SetBytes(crypto.Keccak256([]byte("side_0")))
+	if config := NewSideChainConfig("side_0", legacy); config.IsSideChainId(big.NewInt(0)) {
+		t.Error("side chain with a legacy chain id rejects unprotected transactions")
+	}
+}
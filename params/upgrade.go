@@ -0,0 +1,6 @@
+package params
+
+// SupportedUpgrades are the upgrades implemented by this release, by name. A
+// node stops importing blocks at the activation height of an upgrade
+// scheduled on chain which is not in the list.
+var SupportedUpgrades = map[string]bool{}
@@ -106,6 +106,33 @@ func EncodeToBytes(val interface{}) ([]byte, error) {
 	return eb.toBytes(), nil
 }
 
+// AppendToBytes appends the RLP encoding of val to dst and returns the extended
+// slice. It lets the hot paths encode into reused buffers, dst growing at most
+// once, to the size of the encoding.
+func AppendToBytes(dst []byte, val interface{}) ([]byte, error) {
+	eb := encbufPool.Get().(*encbuf)
+	defer encbufPool.Put(eb)
+	eb.reset()
+	if err := eb.encode(val); err != nil {
+		return dst, err
+	}
+	return eb.appendTo(dst), nil
+}
+
+// AppendUint64 appends the RLP encoding of i to b and returns the extended
+// slice.
+func AppendUint64(b []byte, i uint64) []byte {
+	if i == 0 {
+		return append(b, 0x80)
+	} else if i < 128 {
+		return append(b, byte(i))
+	}
+	var buf [9]byte
+	size := putint(buf[1:], i)
+	buf[0] = 0x80 + byte(size)
+	return append(b, buf[:size+1]...)
+}
+
 // EncodeToReader returns a reader from which the RLP encoding of val
 // can be read. The returned size is the total size of the encoded
 // data.
@@ -230,7 +257,19 @@ func (w *encbuf) size() int {
 }
 
 func (w *encbuf) toBytes() []byte {
-	out := make([]byte, w.size())
+	return w.appendTo(make([]byte, 0, w.size()))
+}
+
+// appendTo appends the encoding to dst.
+func (w *encbuf) appendTo(dst []byte) []byte {
+	start, size := len(dst), w.size()
+	if cap(dst)-start < size {
+		grown := make([]byte, start, start+size)
+		copy(grown, dst)
+		dst = grown
+	}
+	dst = dst[:start+size]
+	out := dst[start:]
 	strpos := 0
 	pos := 0
 	for _, head := range w.lheads {
@@ -244,7 +283,7 @@ func (w *encbuf) toBytes() []byte {
 	}
 	// copy string data after the last list header
 	copy(out[pos:], w.str[strpos:])
-	return out
+	return dst
 }
 
 func (w *encbuf) toWriter(out io.Writer) (err error) {
@@ -290,6 +290,30 @@ func TestEncodeToBytes(t *testing.T) {
 	runEncTests(t, EncodeToBytes)
 }
 
+func TestAppendToBytes(t *testing.T) {
+	// The encodings are appended after a prefix, in a buffer too small or
+	// large enough
+	for _, capacity := range []int{3, 1024} {
+		runEncTests(t, func(val interface{}) ([]byte, error) {
+			dst := append(make([]byte, 0, capacity), 1, 2, 3)
+			out, err := AppendToBytes(dst, val)
+			if !bytes.Equal(out[:3], []byte{1, 2, 3}) {
+				return nil, fmt.Errorf("prefix overwritten: %x", out[:3])
+			}
+			return out[3:], err
+		})
+	}
+}
+
+func TestAppendUint64(t *testing.T) {
+	for _, i := range []uint64{0, 1, 127, 128, 255, 256, 1 << 20, 1<<64 - 1} {
+		want, _ := EncodeToBytes(i)
+		if got := AppendUint64([]byte{0xff}, i); !bytes.Equal(got[1:], want) || got[0] != 0xff {
+			t.Errorf("encoding of %d mismatch: got %x, want %x", i, got[1:], want)
+		}
+	}
+}
+
 func TestEncodeToReader(t *testing.T) {
 	runEncTests(t, func(val interface{}) ([]byte, error) {
 		_, r, err := EncodeToReader(val)
@@ -348,3 +372,26 @@ func TestEncodeToReaderReturnToPool(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func BenchmarkEncodeToBytes(b *testing.B) {
+	val := make([][]byte, 100)
+	for i := range val {
+		val[i] = make([]byte, 100)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		EncodeToBytes(val)
+	}
+}
+
+func BenchmarkAppendToBytes(b *testing.B) {
+	val := make([][]byte, 100)
+	for i := range val {
+		val[i] = make([]byte, 100)
+	}
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = AppendToBytes(buf[:0], val)
+	}
+}
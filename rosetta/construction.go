@@ -0,0 +1,408 @@
+package rosetta
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/common/neataddr"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rlp"
+	"github.com/neatlab/neatio/rpc"
+)
+
+const (
+	curveSecp256k1     = "secp256k1"
+	signatureRecovery  = "ecdsa_recovery"
+	recoverySignatureN = 65
+)
+
+// intent is a transaction described by the operations of a construction
+// request: a transfer, or a cross chain deposit or withdrawal.
+type intent struct {
+	typ         string
+	from        common.Address
+	to          common.Address // Recipient of a transfer
+	value       *big.Int
+	sideChainId string // Side chain of a deposit
+}
+
+// unsignedTx is the unsigned transaction passed between the construction
+// endpoints, the sender is kept to parse it.
+type unsignedTx struct {
+	From string        `json:"from"`
+	Tx   hexutil.Bytes `json:"tx"`
+}
+
+// operationAmount returns the amount of an operation, in NEAT.
+func operationAmount(op *Operation) (common.Address, *big.Int, *Error) {
+	if op.Account == nil {
+		return common.Address{}, nil, ErrUnsupportedOps.wrap("operation without account")
+	}
+	addr, err := neataddr.Parse(op.Account.Address)
+	if err != nil {
+		return common.Address{}, nil, ErrInvalidAddress.wrap(err)
+	}
+	if op.Amount == nil || op.Amount.Currency == nil || *op.Amount.Currency != *NEAT {
+		return common.Address{}, nil, ErrUnsupportedOps.wrap("operation without NEAT amount")
+	}
+	value, ok := new(big.Int).SetString(op.Amount.Value, 10)
+	if !ok || value.Sign() == 0 {
+		return common.Address{}, nil, ErrUnsupportedOps.wrap("invalid amount " + op.Amount.Value)
+	}
+	return addr, value, nil
+}
+
+// parseIntent returns the transaction described by the operations.
+func parseIntent(config *params.ChainConfig, ops []*Operation) (*intent, *Error) {
+	switch {
+	case len(ops) == 2 && ops[0].Type == OpCall && ops[1].Type == OpCall:
+		a, aValue, err := operationAmount(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		b, bValue, err := operationAmount(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		if aValue.Sign() > 0 {
+			a, aValue, b, bValue = b, bValue, a, aValue
+		}
+		if aValue.Sign() > 0 || new(big.Int).Add(aValue, bValue).Sign() != 0 {
+			return nil, ErrUnsupportedOps.wrap("a transfer needs a debit and a credit of the same amount")
+		}
+		return &intent{typ: OpCall, from: a, to: b, value: bValue}, nil
+
+	case len(ops) == 1 && (ops[0].Type == OpDepositToSideChain || ops[0].Type == OpWithdrawFromSideChain):
+		from, value, err := operationAmount(ops[0])
+		if err != nil {
+			return nil, err
+		}
+		if value.Sign() > 0 {
+			return nil, ErrUnsupportedOps.wrap("a cross chain operation needs a debit")
+		}
+		in := &intent{typ: ops[0].Type, from: from, value: value.Neg(value)}
+		if in.typ == OpWithdrawFromSideChain {
+			if config.IsMainChain() {
+				return nil, ErrUnsupportedOps.wrap("withdraw must be sent to a side chain")
+			}
+			return in, nil
+		}
+		if !config.IsMainChain() {
+			return nil, ErrUnsupportedOps.wrap("deposit must be sent to the main chain")
+		}
+		in.sideChainId, _ = ops[0].Metadata["chain_id"].(string)
+		if in.sideChainId == "" || params.IsMainChain(in.sideChainId) {
+			return nil, ErrUnsupportedOps.wrap("deposit without side chain id")
+		}
+		return in, nil
+	}
+	return nil, ErrUnsupportedOps
+}
+
+// parseOptions returns the transaction described by the options of
+// /construction/preprocess.
+func parseOptions(options map[string]interface{}) (*intent, *Error) {
+	typ, _ := options["type"].(string)
+	from, _ := options["from"].(string)
+	in := &intent{typ: typ}
+	var err error
+	if in.from, err = neataddr.Parse(from); err != nil {
+		return nil, ErrInvalidAddress.wrap(err)
+	}
+	switch typ {
+	case OpCall:
+		to, _ := options["to"].(string)
+		if in.to, err = neataddr.Parse(to); err != nil {
+			return nil, ErrInvalidAddress.wrap(err)
+		}
+	case OpDepositToSideChain:
+		in.sideChainId, _ = options["chain_id"].(string)
+	case OpWithdrawFromSideChain:
+	default:
+		return nil, ErrUnsupportedOps.wrap("unknown type " + typ)
+	}
+	return in, nil
+}
+
+// options returns the options of the transaction, without its value.
+func (in *intent) options() map[string]interface{} {
+	options := map[string]interface{}{"type": in.typ, "from": in.from.String()}
+	switch in.typ {
+	case OpCall:
+		options["to"] = in.to.String()
+	case OpDepositToSideChain:
+		options["chain_id"] = in.sideChainId
+	}
+	return options
+}
+
+// function returns the special transaction of a cross chain operation.
+func (in *intent) function() (neatabi.FunctionType, bool) {
+	switch in.typ {
+	case OpDepositToSideChain:
+		return neatabi.DepositInMainChain, true
+	case OpWithdrawFromSideChain:
+		return neatabi.WithdrawFromSideChain, true
+	}
+	return neatabi.FunctionType{}, false
+}
+
+func (in *intent) gasLimit() uint64 {
+	if function, ok := in.function(); ok {
+		return function.RequiredGas()
+	}
+	return params.TxGas
+}
+
+// transaction returns the unsigned transaction of the intent on the chain.
+func (in *intent) transaction(config *params.ChainConfig, nonce uint64, gasPrice *big.Int, gasLimit uint64) (*types.Transaction, error) {
+	function, ok := in.function()
+	if !ok {
+		return types.NewTransaction(nonce, in.to, in.value, gasLimit, gasPrice, nil), nil
+	}
+	chainId := config.NeatChainId
+	if function == neatabi.DepositInMainChain {
+		chainId = in.sideChainId
+	}
+	data, err := neatabi.ChainABI.Pack(function.String(), chainId)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewTransaction(nonce, neatabi.ChainContractMagicAddr, in.value, gasLimit, gasPrice, data), nil
+}
+
+// metadataBig returns a hex encoded number of the metadata.
+func metadataBig(metadata map[string]interface{}, key string) (*big.Int, *Error) {
+	s, _ := metadata[key].(string)
+	value, err := hexutil.DecodeBig(s)
+	if err != nil {
+		return nil, ErrInvalidRequest.wrap("invalid " + key + ": " + err.Error())
+	}
+	return value, nil
+}
+
+func (c *chain) signer() types.Signer {
+	return types.NewEIP155Signer(c.ChainConfig().ChainId)
+}
+
+// decodeSigned decodes a signed transaction and returns its sender.
+func (c *chain) decodeSigned(signed string) (*types.Transaction, common.Address, *Error) {
+	data, err := hexutil.Decode(signed)
+	if err != nil {
+		return nil, common.Address{}, ErrInvalidTransaction.wrap(err)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(data, tx); err != nil {
+		return nil, common.Address{}, ErrInvalidTransaction.wrap(err)
+	}
+	from, err := types.Sender(c.signer(), tx)
+	if err != nil {
+		return nil, common.Address{}, ErrInvalidSignature.wrap(err)
+	}
+	return tx, from, nil
+}
+
+// decodeUnsigned decodes an unsigned transaction and returns its sender.
+func decodeUnsigned(unsigned string) (*types.Transaction, common.Address, *Error) {
+	var utx unsignedTx
+	if err := json.Unmarshal([]byte(unsigned), &utx); err != nil {
+		return nil, common.Address{}, ErrInvalidTransaction.wrap(err)
+	}
+	from, err := neataddr.Parse(utx.From)
+	if err != nil {
+		return nil, common.Address{}, ErrInvalidAddress.wrap(err)
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(utx.Tx, tx); err != nil {
+		return nil, common.Address{}, ErrInvalidTransaction.wrap(err)
+	}
+	return tx, from, nil
+}
+
+func (s *Server) constructionDerive(ctx context.Context, c *chain, req *ConstructionDeriveRequest) (interface{}, *Error) {
+	if req.PublicKey == nil || req.PublicKey.CurveType != curveSecp256k1 {
+		return nil, ErrInvalidPublicKey.wrap("curve must be " + curveSecp256k1)
+	}
+	key, err := hexutil.Decode("0x" + req.PublicKey.HexBytes)
+	if err != nil {
+		return nil, ErrInvalidPublicKey.wrap(err)
+	}
+	pub, err := crypto.DecompressPubkey(key)
+	if err != nil {
+		if pub, err = crypto.UnmarshalPubkey(key); err != nil {
+			return nil, ErrInvalidPublicKey.wrap(err)
+		}
+	}
+	return &ConstructionDeriveResponse{
+		AccountIdentifier: &AccountIdentifier{Address: crypto.PubkeyToAddress(*pub).String()},
+	}, nil
+}
+
+func (s *Server) constructionPreprocess(ctx context.Context, c *chain, req *ConstructionPreprocessRequest) (interface{}, *Error) {
+	in, err := parseIntent(c.ChainConfig(), req.Operations)
+	if err != nil {
+		return nil, err
+	}
+	return &ConstructionPreprocessResponse{
+		Options:            in.options(),
+		RequiredPublicKeys: []*AccountIdentifier{{Address: in.from.String()}},
+	}, nil
+}
+
+func (s *Server) constructionMetadata(ctx context.Context, c *chain, req *ConstructionMetadataRequest) (interface{}, *Error) {
+	in, rerr := parseOptions(req.Options)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if in.typ == OpCall {
+		// The gas of the calls of contracts can not be known in advance
+		statedb, _, err := c.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+		if err != nil || statedb == nil {
+			return nil, ErrNodeError.wrap("state not available")
+		}
+		if statedb.GetCodeSize(in.to) > 0 {
+			return nil, ErrUnsupportedOps.wrap("transfers to contracts are not supported")
+		}
+	}
+	nonce, err := c.GetPoolNonce(ctx, in.from)
+	if err != nil {
+		return nil, ErrNodeError.wrap(err)
+	}
+	gasPrice, err := c.SuggestPrice(ctx)
+	if err != nil {
+		return nil, ErrNodeError.wrap(err)
+	}
+	gasLimit := in.gasLimit()
+	fee := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasLimit))
+	return &ConstructionMetadataResponse{
+		Metadata: map[string]interface{}{
+			"nonce":     hexutil.Uint64(nonce).String(),
+			"gas_price": hexutil.EncodeBig(gasPrice),
+			"gas_limit": hexutil.Uint64(gasLimit).String(),
+		},
+		SuggestedFee: []*Amount{{Value: fee.String(), Currency: NEAT}},
+	}, nil
+}
+
+func (s *Server) constructionPayloads(ctx context.Context, c *chain, req *ConstructionPayloadsRequest) (interface{}, *Error) {
+	config := c.ChainConfig()
+	in, rerr := parseIntent(config, req.Operations)
+	if rerr != nil {
+		return nil, rerr
+	}
+	nonce, rerr := metadataBig(req.Metadata, "nonce")
+	if rerr != nil {
+		return nil, rerr
+	}
+	gasPrice, rerr := metadataBig(req.Metadata, "gas_price")
+	if rerr != nil {
+		return nil, rerr
+	}
+	gasLimit, rerr := metadataBig(req.Metadata, "gas_limit")
+	if rerr != nil {
+		return nil, rerr
+	}
+	tx, err := in.transaction(config, nonce.Uint64(), gasPrice, gasLimit.Uint64())
+	if err != nil {
+		return nil, ErrInvalidTransaction.wrap(err)
+	}
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, ErrInvalidTransaction.wrap(err)
+	}
+	unsigned, err := json.Marshal(&unsignedTx{From: in.from.String(), Tx: data})
+	if err != nil {
+		return nil, ErrInvalidTransaction.wrap(err)
+	}
+	return &ConstructionPayloadsResponse{
+		UnsignedTransaction: string(unsigned),
+		Payloads: []*SigningPayload{{
+			AccountIdentifier: &AccountIdentifier{Address: in.from.String()},
+			HexBytes:          common.Bytes2Hex(c.signer().Hash(tx).Bytes()),
+			SignatureType:     signatureRecovery,
+		}},
+	}, nil
+}
+
+func (s *Server) constructionParse(ctx context.Context, c *chain, req *ConstructionParseRequest) (interface{}, *Error) {
+	var (
+		tx   *types.Transaction
+		from common.Address
+		rerr *Error
+	)
+	if req.Signed {
+		tx, from, rerr = c.decodeSigned(req.Transaction)
+	} else {
+		tx, from, rerr = decodeUnsigned(req.Transaction)
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+	rtx, err := transactionOperations(from, tx, nil)
+	if err != nil {
+		return nil, ErrInvalidTransaction.wrap(err)
+	}
+	resp := &ConstructionParseResponse{
+		Operations: rtx.Operations,
+		Metadata: map[string]interface{}{
+			"nonce":     hexutil.Uint64(tx.Nonce()).String(),
+			"gas_price": hexutil.EncodeBig(tx.GasPrice()),
+			"gas_limit": hexutil.Uint64(tx.Gas()).String(),
+		},
+	}
+	if req.Signed {
+		resp.AccountIdentifierSigners = []*AccountIdentifier{{Address: from.String()}}
+	}
+	return resp, nil
+}
+
+func (s *Server) constructionCombine(ctx context.Context, c *chain, req *ConstructionCombineRequest) (interface{}, *Error) {
+	tx, from, rerr := decodeUnsigned(req.UnsignedTransaction)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if len(req.Signatures) != 1 || req.Signatures[0].SignatureType != signatureRecovery {
+		return nil, ErrInvalidSignature.wrap("one " + signatureRecovery + " signature is needed")
+	}
+	sig, err := hexutil.Decode("0x" + req.Signatures[0].HexBytes)
+	if err != nil || len(sig) != recoverySignatureN {
+		return nil, ErrInvalidSignature.wrap("signature must be 65 bytes")
+	}
+	signed, err := tx.WithSignature(c.signer(), sig)
+	if err != nil {
+		return nil, ErrInvalidSignature.wrap(err)
+	}
+	if sender, err := types.Sender(c.signer(), signed); err != nil || sender != from {
+		return nil, ErrInvalidSignature.wrap("signature not made by " + from.String())
+	}
+	data, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		return nil, ErrInvalidTransaction.wrap(err)
+	}
+	return &ConstructionCombineResponse{SignedTransaction: hexutil.Encode(data)}, nil
+}
+
+func (s *Server) constructionHash(ctx context.Context, c *chain, req *ConstructionHashRequest) (interface{}, *Error) {
+	tx, _, err := c.decodeSigned(req.SignedTransaction)
+	if err != nil {
+		return nil, err
+	}
+	return &TransactionIdentifierResponse{TransactionIdentifier: &TransactionIdentifier{Hash: tx.Hash().Hex()}}, nil
+}
+
+func (s *Server) constructionSubmit(ctx context.Context, c *chain, req *ConstructionSubmitRequest) (interface{}, *Error) {
+	tx, _, rerr := c.decodeSigned(req.SignedTransaction)
+	if rerr != nil {
+		return nil, rerr
+	}
+	if err := c.SendTx(ctx, tx); err != nil {
+		return nil, ErrSubmitFailed.wrap(err)
+	}
+	return &TransactionIdentifierResponse{TransactionIdentifier: &TransactionIdentifier{Hash: tx.Hash().Hex()}}, nil
+}
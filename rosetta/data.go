@@ -0,0 +1,157 @@
+package rosetta
+
+import (
+	"context"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/neataddr"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/rpc"
+)
+
+func blockIdentifier(block *types.Block) *BlockIdentifier {
+	return &BlockIdentifier{Index: block.Number().Int64(), Hash: block.Hash().Hex()}
+}
+
+// blockTimestamp returns the time of a block in milliseconds.
+func blockTimestamp(block *types.Block) int64 {
+	return int64(block.Time()) * 1000
+}
+
+// database returns the state database of the chain.
+func (c *chain) database(ctx context.Context) (state.Database, *Error) {
+	statedb, _, err := c.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil || statedb == nil {
+		return nil, ErrNodeError.wrap("state not available")
+	}
+	return statedb.Database(), nil
+}
+
+// findBlock returns the block identified by id, the current block if nil.
+func (c *chain) findBlock(ctx context.Context, id *PartialBlockIdentifier) (*types.Block, *Error) {
+	var (
+		block *types.Block
+		err   error
+	)
+	switch {
+	case id == nil || (id.Index == nil && id.Hash == nil):
+		block = c.CurrentBlock()
+	case id.Hash != nil:
+		block, err = c.GetBlock(ctx, common.HexToHash(*id.Hash))
+	default:
+		if *id.Index < 0 {
+			return nil, ErrInvalidRequest.wrap("negative block index")
+		}
+		block, err = c.BlockByNumber(ctx, rpc.BlockNumber(*id.Index))
+	}
+	if err != nil {
+		return nil, ErrNodeError.wrap(err)
+	}
+	if block == nil || (id != nil && id.Index != nil && block.Number().Int64() != *id.Index) {
+		return nil, ErrBlockNotFound
+	}
+	return block, nil
+}
+
+func (s *Server) accountBalance(ctx context.Context, c *chain, req *AccountBalanceRequest) (interface{}, *Error) {
+	if req.AccountIdentifier == nil {
+		return nil, ErrInvalidAddress
+	}
+	addr, err := neataddr.Parse(req.AccountIdentifier.Address)
+	if err != nil {
+		return nil, ErrInvalidAddress.wrap(err)
+	}
+	block, rerr := c.findBlock(ctx, req.BlockIdentifier)
+	if rerr != nil {
+		return nil, rerr
+	}
+	db, rerr := c.database(ctx)
+	if rerr != nil {
+		return nil, rerr
+	}
+	statedb, err := state.New(block.Root(), db)
+	if err != nil {
+		return nil, ErrStateUnavailable.wrap(err)
+	}
+	return &AccountBalanceResponse{
+		BlockIdentifier: blockIdentifier(block),
+		Balances:        []*Amount{{Value: statedb.GetBalance(addr).String(), Currency: NEAT}},
+		Metadata:        map[string]interface{}{"nonce": statedb.GetNonce(addr)},
+	}, nil
+}
+
+func (s *Server) block(ctx context.Context, c *chain, req *BlockRequest) (interface{}, *Error) {
+	block, err := c.findBlock(ctx, req.BlockIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	txs, err := c.blockTransactions(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	// The genesis block is its own parent
+	parent := blockIdentifier(block)
+	if block.NumberU64() > 0 {
+		parent = &BlockIdentifier{Index: parent.Index - 1, Hash: block.ParentHash().Hex()}
+	}
+	return &BlockResponse{Block: &Block{
+		BlockIdentifier:       blockIdentifier(block),
+		ParentBlockIdentifier: parent,
+		Timestamp:             blockTimestamp(block),
+		Transactions:          txs,
+	}}, nil
+}
+
+func (s *Server) blockTransaction(ctx context.Context, c *chain, req *BlockTransactionRequest) (interface{}, *Error) {
+	if req.BlockIdentifier == nil || req.TransactionIdentifier == nil {
+		return nil, ErrInvalidRequest
+	}
+	block, err := c.findBlock(ctx, &PartialBlockIdentifier{Index: &req.BlockIdentifier.Index, Hash: &req.BlockIdentifier.Hash})
+	if err != nil {
+		return nil, err
+	}
+	txs, err := c.blockTransactions(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	hash := common.HexToHash(req.TransactionIdentifier.Hash).Hex()
+	for _, tx := range txs {
+		if tx.TransactionIdentifier.Hash == hash {
+			return &BlockTransactionResponse{Transaction: tx}, nil
+		}
+	}
+	return nil, ErrTransactionNotFound
+}
+
+func (s *Server) mempool(ctx context.Context, c *chain, req *NetworkRequest) (interface{}, *Error) {
+	txs, err := c.GetPoolTransactions()
+	if err != nil {
+		return nil, ErrNodeError.wrap(err)
+	}
+	resp := &MempoolResponse{TransactionIdentifiers: make([]*TransactionIdentifier, len(txs))}
+	for i, tx := range txs {
+		resp.TransactionIdentifiers[i] = &TransactionIdentifier{Hash: tx.Hash().Hex()}
+	}
+	return resp, nil
+}
+
+func (s *Server) mempoolTransaction(ctx context.Context, c *chain, req *MempoolTransactionRequest) (interface{}, *Error) {
+	if req.TransactionIdentifier == nil {
+		return nil, ErrInvalidRequest
+	}
+	tx := c.GetPoolTransaction(common.HexToHash(req.TransactionIdentifier.Hash))
+	if tx == nil {
+		return nil, ErrTransactionNotFound
+	}
+	signer := types.MakeSigner(c.ChainConfig(), c.CurrentBlock().Number())
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, ErrInvalidTransaction.wrap(err)
+	}
+	rtx, err := transactionOperations(from, tx, nil)
+	if err != nil {
+		return nil, ErrInvalidTransaction.wrap(err)
+	}
+	return &MempoolTransactionResponse{Transaction: rtx}, nil
+}
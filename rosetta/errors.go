@@ -0,0 +1,48 @@
+package rosetta
+
+import "fmt"
+
+// Error is an error returned by the Rosetta API.
+type Error struct {
+	Code      int32                  `json:"code"`
+	Message   string                 `json:"message"`
+	Retriable bool                   `json:"retriable"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+var (
+	ErrUnknownNetwork      = &Error{Code: 1, Message: "network not found"}
+	ErrInvalidRequest      = &Error{Code: 2, Message: "invalid request"}
+	ErrBlockNotFound       = &Error{Code: 3, Message: "block not found"}
+	ErrTransactionNotFound = &Error{Code: 4, Message: "transaction not found"}
+	ErrStateUnavailable    = &Error{Code: 5, Message: "state not available, historical lookups need an archive node"}
+	ErrUnsupportedOps      = &Error{Code: 6, Message: "unsupported operations"}
+	ErrInvalidAddress      = &Error{Code: 7, Message: "invalid address"}
+	ErrInvalidPublicKey    = &Error{Code: 8, Message: "invalid public key"}
+	ErrInvalidTransaction  = &Error{Code: 9, Message: "invalid transaction"}
+	ErrInvalidSignature    = &Error{Code: 10, Message: "invalid signature"}
+	ErrSubmitFailed        = &Error{Code: 11, Message: "transaction rejected"}
+	ErrNodeError           = &Error{Code: 12, Message: "node error", Retriable: true}
+)
+
+// allErrors are the errors listed by /network/options.
+var allErrors = []*Error{
+	ErrUnknownNetwork, ErrInvalidRequest, ErrBlockNotFound, ErrTransactionNotFound,
+	ErrStateUnavailable, ErrUnsupportedOps, ErrInvalidAddress, ErrInvalidPublicKey,
+	ErrInvalidTransaction, ErrInvalidSignature, ErrSubmitFailed, ErrNodeError,
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if detail, ok := e.Details["error"]; ok {
+		return fmt.Sprintf("%s: %v", e.Message, detail)
+	}
+	return e.Message
+}
+
+// wrap returns a copy of e detailed by the cause.
+func (e *Error) wrap(cause interface{}) *Error {
+	err := *e
+	err.Details = map[string]interface{}{"error": fmt.Sprint(cause)}
+	return &err
+}
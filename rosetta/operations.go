@@ -0,0 +1,273 @@
+package rosetta
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/trie"
+)
+
+const (
+	StatusSuccess = "SUCCESS"
+	StatusFailure = "FAILURE"
+)
+
+// The types of the operations.
+const (
+	OpFee                   = "FEE"                      // Gas paid by the sender
+	OpCall                  = "CALL"                     // Value sent by a transaction
+	OpDepositToSideChain    = "DEPOSIT_TO_SIDE_CHAIN"    // Value deposited from the main chain to a side chain
+	OpWithdrawFromSideChain = "WITHDRAW_FROM_SIDE_CHAIN" // Value withdrawn from a side chain to the main chain
+	OpLock                  = "LOCK"                     // Value locked by the other special transactions
+	OpBalanceAdjustment     = "BALANCE_ADJUSTMENT"       // Balance changes not made by the transactions themselves
+)
+
+var operationTypes = []string{OpFee, OpCall, OpDepositToSideChain, OpWithdrawFromSideChain, OpLock, OpBalanceAdjustment}
+
+// operations accumulates the operations of a transaction.
+type operations []*Operation
+
+func (ops *operations) add(typ string, status *string, addr common.Address, value *big.Int) *Operation {
+	op := &Operation{
+		OperationIdentifier: &OperationIdentifier{Index: int64(len(*ops))},
+		Type:                typ,
+		Status:              status,
+		Account:             &AccountIdentifier{Address: addr.String()},
+	}
+	if value != nil {
+		op.Amount = &Amount{Value: value.String(), Currency: NEAT}
+	}
+	*ops = append(*ops, op)
+	return op
+}
+
+// specialOperationType returns the type of the operation of the value of a
+// special transaction.
+func specialOperationType(function neatabi.FunctionType) string {
+	switch function {
+	case neatabi.DepositInMainChain:
+		return OpDepositToSideChain
+	case neatabi.WithdrawFromSideChain:
+		return OpWithdrawFromSideChain
+	default:
+		return OpLock
+	}
+}
+
+// transactionOperations returns the operations of a transaction sent by from,
+// with their status and the fee if the receipt is given.
+func transactionOperations(from common.Address, tx *types.Transaction, receipt *types.Receipt) (*Transaction, error) {
+	result := &Transaction{TransactionIdentifier: &TransactionIdentifier{Hash: tx.Hash().Hex()}}
+
+	var ops operations
+	var status *string
+	if receipt != nil {
+		success, failure := StatusSuccess, StatusFailure
+		status = &success
+		if fee := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tx.GasPrice()); fee.Sign() > 0 {
+			ops.add(OpFee, &success, from, fee.Neg(fee))
+		}
+		if receipt.Status == types.ReceiptStatusFailed {
+			status = &failure
+		}
+	}
+
+	value := tx.Value()
+	if neatabi.IsNeatChainContractAddr(tx.To()) {
+		data := tx.Data()
+		if len(data) < 4 {
+			return nil, fmt.Errorf("special transaction %x without function", tx.Hash())
+		}
+		function, err := neatabi.FunctionTypeFromId(data[:4])
+		if err != nil {
+			return nil, err
+		}
+		result.Metadata = map[string]interface{}{"function": function.String()}
+		if value.Sign() > 0 {
+			op := ops.add(specialOperationType(function), status, from, new(big.Int).Neg(value))
+			if function == neatabi.DepositInMainChain {
+				var args neatabi.DepositInMainChainArgs
+				if err := neatabi.ChainABI.UnpackMethodInputs(&args, function.String(), data[4:]); err != nil {
+					return nil, err
+				}
+				op.Metadata = map[string]interface{}{"chain_id": args.ChainId}
+			}
+		}
+	} else if value.Sign() > 0 {
+		var to common.Address
+		switch {
+		case tx.To() != nil:
+			to = *tx.To()
+		case receipt != nil:
+			to = receipt.ContractAddress
+		default:
+			to = crypto.CreateAddress(from, tx.Nonce())
+		}
+		debit := ops.add(OpCall, status, from, new(big.Int).Neg(value))
+		credit := ops.add(OpCall, status, to, value)
+		credit.RelatedOperations = []*OperationIdentifier{debit.OperationIdentifier}
+	}
+	result.Operations = ops
+	if result.Operations == nil {
+		result.Operations = []*Operation{}
+	}
+	return result, nil
+}
+
+// balanceChanges returns the changes of the balances from the state root
+// before to the state root after, found by walking the accounts which differ.
+func balanceChanges(db state.Database, before, after common.Hash) (map[common.Address]*big.Int, error) {
+	beforeState, err := state.New(before, db)
+	if err != nil {
+		return nil, err
+	}
+	afterState, err := state.New(after, db)
+	if err != nil {
+		return nil, err
+	}
+	beforeTrie, err := db.OpenTrie(before)
+	if err != nil {
+		return nil, err
+	}
+	afterTrie, err := db.OpenTrie(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[common.Address]*big.Int)
+	seen := make(map[common.Address]bool)
+	// Walk the accounts of b not in a: updated and created accounts first,
+	// then the deleted ones.
+	walk := func(a, b state.Trie) error {
+		it, _ := trie.NewDifferenceIterator(a.NodeIterator(nil), b.NodeIterator(nil))
+		for it.Next(true) {
+			if !it.Leaf() {
+				continue
+			}
+			preimage := b.GetKey(it.LeafKey())
+			if preimage == nil {
+				return fmt.Errorf("no preimage of account %x", it.LeafKey())
+			}
+			addr := common.BytesToAddress(preimage)
+			if seen[addr] {
+				continue
+			}
+			seen[addr] = true
+			if delta := new(big.Int).Sub(afterState.GetBalance(addr), beforeState.GetBalance(addr)); delta.Sign() != 0 {
+				changes[addr] = delta
+			}
+		}
+		return it.Error()
+	}
+	if err := walk(beforeTrie, afterTrie); err != nil {
+		return nil, err
+	}
+	if err := walk(afterTrie, beforeTrie); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// adjustmentOperations returns the balance adjustments completing the
+// operations of the transactions to the balance changes of the block.
+func adjustmentOperations(changes map[common.Address]*big.Int, txs []*Transaction) ([]*Operation, error) {
+	residual := make(map[common.Address]*big.Int, len(changes))
+	for addr, delta := range changes {
+		residual[addr] = new(big.Int).Set(delta)
+	}
+	for _, tx := range txs {
+		for _, op := range tx.Operations {
+			if op.Amount == nil || (op.Status != nil && *op.Status != StatusSuccess) {
+				continue
+			}
+			value, ok := new(big.Int).SetString(op.Amount.Value, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid amount %q", op.Amount.Value)
+			}
+			addr := common.StringToAddress(op.Account.Address)
+			if residual[addr] == nil {
+				residual[addr] = new(big.Int)
+			}
+			residual[addr].Sub(residual[addr], value)
+		}
+	}
+
+	addrs := make([]common.Address, 0, len(residual))
+	for addr, value := range residual {
+		if value.Sign() != 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var ops operations
+	success := StatusSuccess
+	for _, addr := range addrs {
+		ops.add(OpBalanceAdjustment, &success, addr, residual[addr])
+	}
+	return ops, nil
+}
+
+// blockTransactions returns the transactions of a block with their
+// operations, followed by the balance adjustments of the block if any.
+func (c *chain) blockTransactions(ctx context.Context, block *types.Block) ([]*Transaction, *Error) {
+	receipts, err := c.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, ErrNodeError.wrap(err)
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, ErrNodeError.wrap(fmt.Sprintf("receipts of block %d not found", block.NumberU64()))
+	}
+
+	signer := types.MakeSigner(c.ChainConfig(), block.Number())
+	result := make([]*Transaction, 0, len(txs)+1)
+	for i, tx := range txs {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, ErrNodeError.wrap(err)
+		}
+		rtx, err := transactionOperations(from, tx, receipts[i])
+		if err != nil {
+			return nil, ErrNodeError.wrap(err)
+		}
+		result = append(result, rtx)
+	}
+
+	// The balances before the genesis are all zero
+	var parentRoot common.Hash
+	if block.NumberU64() > 0 {
+		parent, err := c.GetBlock(ctx, block.ParentHash())
+		if err != nil || parent == nil {
+			return nil, ErrNodeError.wrap(fmt.Sprintf("parent of block %d not found", block.NumberU64()))
+		}
+		parentRoot = parent.Root()
+	}
+	db, rerr := c.database(ctx)
+	if rerr != nil {
+		return nil, rerr
+	}
+	changes, err := balanceChanges(db, parentRoot, block.Root())
+	if err != nil {
+		return nil, ErrStateUnavailable.wrap(err)
+	}
+	adjustments, err := adjustmentOperations(changes, result)
+	if err != nil {
+		return nil, ErrNodeError.wrap(err)
+	}
+	if len(adjustments) > 0 {
+		result = append(result, &Transaction{
+			TransactionIdentifier: &TransactionIdentifier{Hash: block.Hash().Hex()},
+			Operations:            adjustments,
+		})
+	}
+	return result, nil
+}
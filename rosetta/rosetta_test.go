@@ -0,0 +1,318 @@
+package rosetta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/common/hexutil"
+	"github.com/neatlab/neatio/core/rawdb"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/crypto"
+	neatabi "github.com/neatlab/neatio/neatabi/abi"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rlp"
+	"github.com/neatlab/neatio/rpc"
+)
+
+// testBackend is a chain without blocks, its pool accepts every transaction.
+type testBackend struct {
+	config  *params.ChainConfig
+	statedb *state.StateDB
+	sent    []*types.Transaction
+}
+
+func newTestBackend(chainId string) *testBackend {
+	config := *params.TestChainConfig
+	config.NeatChainId = chainId
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	return &testBackend{config: &config, statedb: statedb}
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return b.config }
+func (b *testBackend) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)})
+}
+func (b *testBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	return b.CurrentBlock(), nil
+}
+func (b *testBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	return nil, nil
+}
+func (b *testBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	return nil, nil
+}
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.statedb, b.CurrentBlock().Header(), nil
+}
+func (b *testBackend) GetPoolTransactions() (types.Transactions, error) { return b.sent, nil }
+func (b *testBackend) GetPoolTransaction(txHash common.Hash) *types.Transaction {
+	for _, tx := range b.sent {
+		if tx.Hash() == txHash {
+			return tx
+		}
+	}
+	return nil
+}
+func (b *testBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	return 7, nil
+}
+func (b *testBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(params.GWei), nil
+}
+func (b *testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	b.sent = append(b.sent, signedTx)
+	return nil
+}
+
+// testAddress returns the address of the key generated from name.
+func testAddress(name string) common.Address {
+	prv, _ := crypto.ToECDSA(crypto.Keccak256([]byte(name)))
+	return crypto.PubkeyToAddress(prv.PublicKey)
+}
+
+// call posts req to path and decodes the response into resp, it returns the
+// error of the server if any.
+func call(t *testing.T, srv *httptest.Server, path string, req, resp interface{}) *Error {
+	body, _ := json.Marshal(req)
+	res, err := http.Post(srv.URL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		rerr := new(Error)
+		if err := json.NewDecoder(res.Body).Decode(rerr); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return rerr
+	}
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// construct runs the construction flow of ops signed by key, and returns the
+// transaction submitted.
+func construct(t *testing.T, srv *httptest.Server, network *NetworkIdentifier, ops []*Operation, key []byte) *types.Transaction {
+	prv, _ := crypto.ToECDSA(key)
+
+	var derive ConstructionDeriveResponse
+	pub := &PublicKey{HexBytes: common.Bytes2Hex(crypto.CompressPubkey(&prv.PublicKey)), CurveType: curveSecp256k1}
+	if err := call(t, srv, "/construction/derive", &ConstructionDeriveRequest{network, pub}, &derive); err != nil {
+		t.Fatalf("derive: %v", err)
+	}
+	if derive.AccountIdentifier.Address != ops[0].Account.Address {
+		t.Fatalf("derived %s, want %s", derive.AccountIdentifier.Address, ops[0].Account.Address)
+	}
+
+	var pre ConstructionPreprocessResponse
+	if err := call(t, srv, "/construction/preprocess", &ConstructionPreprocessRequest{network, ops}, &pre); err != nil {
+		t.Fatalf("preprocess: %v", err)
+	}
+	var meta ConstructionMetadataResponse
+	if err := call(t, srv, "/construction/metadata", &ConstructionMetadataRequest{network, pre.Options}, &meta); err != nil {
+		t.Fatalf("metadata: %v", err)
+	}
+	var payloads ConstructionPayloadsResponse
+	if err := call(t, srv, "/construction/payloads", &ConstructionPayloadsRequest{network, ops, meta.Metadata}, &payloads); err != nil {
+		t.Fatalf("payloads: %v", err)
+	}
+
+	var parsed ConstructionParseResponse
+	if err := call(t, srv, "/construction/parse", &ConstructionParseRequest{network, false, payloads.UnsignedTransaction}, &parsed); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want, _ := json.Marshal(ops)
+	if got, _ := json.Marshal(parsed.Operations); !bytes.Equal(got, want) {
+		t.Fatalf("parsed operations %s, want %s", got, want)
+	}
+
+	sig, err := crypto.Sign(common.Hex2Bytes(payloads.Payloads[0].HexBytes), prv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := &Signature{
+		SigningPayload: payloads.Payloads[0],
+		PublicKey:      pub,
+		SignatureType:  signatureRecovery,
+		HexBytes:       common.Bytes2Hex(sig),
+	}
+	var combined ConstructionCombineResponse
+	if err := call(t, srv, "/construction/combine", &ConstructionCombineRequest{network, payloads.UnsignedTransaction, []*Signature{signature}}, &combined); err != nil {
+		t.Fatalf("combine: %v", err)
+	}
+	if err := call(t, srv, "/construction/parse", &ConstructionParseRequest{network, true, combined.SignedTransaction}, &parsed); err != nil {
+		t.Fatalf("parse signed: %v", err)
+	}
+	if len(parsed.AccountIdentifierSigners) != 1 || parsed.AccountIdentifierSigners[0].Address != ops[0].Account.Address {
+		t.Fatalf("signers %v, want %s", parsed.AccountIdentifierSigners, ops[0].Account.Address)
+	}
+
+	var hash, submitted TransactionIdentifierResponse
+	if err := call(t, srv, "/construction/hash", &ConstructionHashRequest{network, combined.SignedTransaction}, &hash); err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	if err := call(t, srv, "/construction/submit", &ConstructionSubmitRequest{network, combined.SignedTransaction}, &submitted); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	if hash.TransactionIdentifier.Hash != submitted.TransactionIdentifier.Hash {
+		t.Fatalf("submitted %s, want %s", submitted.TransactionIdentifier.Hash, hash.TransactionIdentifier.Hash)
+	}
+
+	data, _ := hexutil.Decode(combined.SignedTransaction)
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(data, tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func TestConstructionTransfer(t *testing.T) {
+	key := crypto.Keccak256([]byte("sender"))
+	prv, _ := crypto.ToECDSA(key)
+	from := crypto.PubkeyToAddress(prv.PublicKey)
+	to := testAddress("recipient")
+
+	b := newTestBackend("neatio")
+	s := NewServer()
+	s.AddChain("neatio", b)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	network := &NetworkIdentifier{Blockchain: Blockchain, Network: "neatio"}
+	ops := []*Operation{
+		{OperationIdentifier: &OperationIdentifier{Index: 0}, Type: OpCall, Account: &AccountIdentifier{Address: from.String()}, Amount: &Amount{Value: "-1000", Currency: NEAT}},
+		{OperationIdentifier: &OperationIdentifier{Index: 1}, RelatedOperations: []*OperationIdentifier{{Index: 0}}, Type: OpCall, Account: &AccountIdentifier{Address: to.String()}, Amount: &Amount{Value: "1000", Currency: NEAT}},
+	}
+	tx := construct(t, srv, network, ops, key)
+	if *tx.To() != to || tx.Value().Int64() != 1000 || tx.Nonce() != 7 || tx.Gas() != params.TxGas || !tx.Protected() {
+		t.Fatalf("unexpected transaction %v", tx)
+	}
+	if len(b.sent) != 1 || b.sent[0].Hash() != tx.Hash() {
+		t.Fatal("transaction not sent")
+	}
+
+	var mempool MempoolResponse
+	if err := call(t, srv, "/mempool", &NetworkRequest{network}, &mempool); err != nil {
+		t.Fatalf("mempool: %v", err)
+	}
+	if len(mempool.TransactionIdentifiers) != 1 || mempool.TransactionIdentifiers[0].Hash != tx.Hash().Hex() {
+		t.Fatalf("mempool %v, want %s", mempool.TransactionIdentifiers, tx.Hash().Hex())
+	}
+
+	// Transfers to contracts are refused
+	b.statedb.SetCode(to, []byte{1})
+	var meta ConstructionMetadataResponse
+	options := map[string]interface{}{"type": OpCall, "from": from.String(), "to": to.String()}
+	if err := call(t, srv, "/construction/metadata", &ConstructionMetadataRequest{network, options}, &meta); err == nil || err.Code != ErrUnsupportedOps.Code {
+		t.Fatalf("metadata of a contract call: got %v, want %v", err, ErrUnsupportedOps)
+	}
+}
+
+func TestConstructionDeposit(t *testing.T) {
+	key := crypto.Keccak256([]byte("depositor"))
+	prv, _ := crypto.ToECDSA(key)
+	from := crypto.PubkeyToAddress(prv.PublicKey)
+
+	s := NewServer()
+	s.AddChain("neatio", newTestBackend("neatio"))
+	s.AddChain("side", newTestBackend("side"))
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	ops := []*Operation{{
+		OperationIdentifier: &OperationIdentifier{Index: 0},
+		Type:                OpDepositToSideChain,
+		Account:             &AccountIdentifier{Address: from.String()},
+		Amount:              &Amount{Value: "-5000", Currency: NEAT},
+		Metadata:            map[string]interface{}{"chain_id": "side"},
+	}}
+	main := &NetworkIdentifier{Blockchain: Blockchain, Network: "neatio"}
+	tx := construct(t, srv, main, ops, key)
+	if !neatabi.IsNeatChainContractAddr(tx.To()) || tx.Value().Int64() != 5000 || tx.Gas() != neatabi.DepositInMainChain.RequiredGas() {
+		t.Fatalf("unexpected transaction %v", tx)
+	}
+
+	// A deposit is not sent to a side chain, nor a withdrawal to the main chain
+	side := &NetworkIdentifier{Blockchain: Blockchain, Network: "side"}
+	var pre ConstructionPreprocessResponse
+	if err := call(t, srv, "/construction/preprocess", &ConstructionPreprocessRequest{side, ops}, &pre); err == nil || err.Code != ErrUnsupportedOps.Code {
+		t.Fatalf("deposit on a side chain: got %v, want %v", err, ErrUnsupportedOps)
+	}
+	ops[0].Type, ops[0].Metadata = OpWithdrawFromSideChain, nil
+	if err := call(t, srv, "/construction/preprocess", &ConstructionPreprocessRequest{main, ops}, &pre); err == nil || err.Code != ErrUnsupportedOps.Code {
+		t.Fatalf("withdraw on the main chain: got %v, want %v", err, ErrUnsupportedOps)
+	}
+	tx = construct(t, srv, side, ops, key)
+	if !neatabi.IsNeatChainContractAddr(tx.To()) || tx.Gas() != neatabi.WithdrawFromSideChain.RequiredGas() {
+		t.Fatalf("unexpected transaction %v", tx)
+	}
+}
+
+func TestUnknownNetwork(t *testing.T) {
+	s := NewServer()
+	s.AddChain("neatio", newTestBackend("neatio"))
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	var list NetworkListResponse
+	if err := call(t, srv, "/network/list", struct{}{}, &list); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(list.NetworkIdentifiers) != 1 || list.NetworkIdentifiers[0].Network != "neatio" {
+		t.Fatalf("networks %v", list.NetworkIdentifiers)
+	}
+	s.RemoveChain("neatio")
+	var options NetworkOptionsResponse
+	if err := call(t, srv, "/network/options", &NetworkRequest{list.NetworkIdentifiers[0]}, &options); err == nil || err.Code != ErrUnknownNetwork.Code {
+		t.Fatalf("removed network: got %v, want %v", err, ErrUnknownNetwork)
+	}
+}
+
+func TestBalanceAdjustments(t *testing.T) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	sender, recipient, validator := testAddress("sender"), testAddress("recipient"), testAddress("validator")
+
+	statedb, _ := state.New(common.Hash{}, db)
+	statedb.AddBalance(sender, big.NewInt(1000))
+	statedb.AddBalance(validator, big.NewInt(1))
+	before, _ := statedb.Commit(true)
+
+	// The block sends 100 from the sender with a fee of 10, and rewards the
+	// validator with 60
+	statedb, _ = state.New(before, db)
+	statedb.SubBalance(sender, big.NewInt(110))
+	statedb.AddBalance(recipient, big.NewInt(100))
+	statedb.AddBalance(validator, big.NewInt(60))
+	after, _ := statedb.Commit(true)
+
+	changes, err := balanceChanges(db, before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes[sender].Int64() != -110 || changes[recipient].Int64() != 100 || changes[validator].Int64() != 60 {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+
+	success := StatusSuccess
+	tx := &Transaction{Operations: []*Operation{
+		{Type: OpFee, Status: &success, Account: &AccountIdentifier{Address: sender.String()}, Amount: &Amount{Value: "-10", Currency: NEAT}},
+		{Type: OpCall, Status: &success, Account: &AccountIdentifier{Address: sender.String()}, Amount: &Amount{Value: "-100", Currency: NEAT}},
+		{Type: OpCall, Status: &success, Account: &AccountIdentifier{Address: recipient.String()}, Amount: &Amount{Value: "100", Currency: NEAT}},
+	}}
+	ops, err := adjustmentOperations(changes, []*Transaction{tx})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].Type != OpBalanceAdjustment || ops[0].Account.Address != validator.String() || ops[0].Amount.Value != "60" {
+		t.Fatalf("unexpected adjustments %v", ops)
+	}
+}
@@ -0,0 +1,256 @@
+// Package rosetta serves the Rosetta Data and Construction APIs of the chains
+// run by a node, so exchanges can integrate NEAT through their Rosetta tooling.
+//
+// Each chain is a network of the "neatio" blockchain, named after its chain id.
+// The operations of a transaction are its fee, its transfer and the value
+// locked by the special transactions (delegations, cross chain deposits...).
+// All the other balance changes of a block, such as the transfers of
+// contracts, the rewards and the refunds of the epochs, are reported as
+// balance adjustments of a block level transaction identified by the block
+// hash, computed from the states before and after the block. The blocks and
+// balances of the past need the state of an archive node.
+package rosetta
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/neatlab/neatio/common"
+	"github.com/neatlab/neatio/core/state"
+	"github.com/neatlab/neatio/core/types"
+	"github.com/neatlab/neatio/log"
+	"github.com/neatlab/neatio/params"
+	"github.com/neatlab/neatio/rpc"
+)
+
+const (
+	// Blockchain is the name of the blockchain of the networks.
+	Blockchain = "neatio"
+
+	// RosettaVersion is the version of the Rosetta specification implemented.
+	RosettaVersion = "1.4.13"
+
+	// maxRequestSize is the maximum size of a request body.
+	maxRequestSize = 1024 * 1024
+)
+
+// NEAT is the currency of the balances and operations.
+var NEAT = &Currency{Symbol: "NEAT", Decimals: 18}
+
+// Backend gives access to a chain, it is implemented by the API backend of
+// the chains.
+type Backend interface {
+	ChainConfig() *params.ChainConfig
+	CurrentBlock() *types.Block
+	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
+	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
+	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
+	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	GetPoolTransactions() (types.Transactions, error)
+	GetPoolTransaction(txHash common.Hash) *types.Transaction
+	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
+	SuggestPrice(ctx context.Context) (*big.Int, error)
+	SendTx(ctx context.Context, signedTx *types.Transaction) error
+}
+
+// chain is a network served by the server.
+type chain struct {
+	Backend
+	network *NetworkIdentifier
+}
+
+// Server serves the Rosetta APIs of the chains added to it.
+type Server struct {
+	lock   sync.RWMutex
+	chains map[string]*chain
+	mux    *http.ServeMux
+}
+
+// NewServer creates a server without chains.
+func NewServer() *Server {
+	s := &Server{chains: make(map[string]*chain), mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("/network/list", s.networkList)
+	s.handle("/network/status", s.networkStatus)
+	s.handle("/network/options", s.networkOptions)
+
+	s.handle("/account/balance", s.accountBalance)
+	s.handle("/block", s.block)
+	s.handle("/block/transaction", s.blockTransaction)
+	s.handle("/mempool", s.mempool)
+	s.handle("/mempool/transaction", s.mempoolTransaction)
+
+	s.handle("/construction/derive", s.constructionDerive)
+	s.handle("/construction/preprocess", s.constructionPreprocess)
+	s.handle("/construction/metadata", s.constructionMetadata)
+	s.handle("/construction/payloads", s.constructionPayloads)
+	s.handle("/construction/parse", s.constructionParse)
+	s.handle("/construction/combine", s.constructionCombine)
+	s.handle("/construction/hash", s.constructionHash)
+	s.handle("/construction/submit", s.constructionSubmit)
+	return s
+}
+
+// AddChain serves the chain chainId, replacing the chain served under the
+// same id if any.
+func (s *Server) AddChain(chainId string, b Backend) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.chains[chainId] = &chain{
+		Backend: b,
+		network: &NetworkIdentifier{Blockchain: Blockchain, Network: chainId},
+	}
+}
+
+// RemoveChain stops serving the chain chainId.
+func (s *Server) RemoveChain(chainId string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.chains, chainId)
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// lookup returns the chain of a network.
+func (s *Server) lookup(network *NetworkIdentifier) (*chain, *Error) {
+	if network == nil || network.Blockchain != Blockchain {
+		return nil, ErrUnknownNetwork
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	c, ok := s.chains[network.Network]
+	if !ok {
+		return nil, ErrUnknownNetwork
+	}
+	return c, nil
+}
+
+// networkRequest is implemented by the requests, all naming their network.
+type networkRequest interface {
+	network() *NetworkIdentifier
+}
+
+func (r *NetworkRequest) network() *NetworkIdentifier                { return r.NetworkIdentifier }
+func (r *AccountBalanceRequest) network() *NetworkIdentifier         { return r.NetworkIdentifier }
+func (r *BlockRequest) network() *NetworkIdentifier                  { return r.NetworkIdentifier }
+func (r *BlockTransactionRequest) network() *NetworkIdentifier       { return r.NetworkIdentifier }
+func (r *MempoolTransactionRequest) network() *NetworkIdentifier     { return r.NetworkIdentifier }
+func (r *ConstructionDeriveRequest) network() *NetworkIdentifier     { return r.NetworkIdentifier }
+func (r *ConstructionPreprocessRequest) network() *NetworkIdentifier { return r.NetworkIdentifier }
+func (r *ConstructionMetadataRequest) network() *NetworkIdentifier   { return r.NetworkIdentifier }
+func (r *ConstructionPayloadsRequest) network() *NetworkIdentifier   { return r.NetworkIdentifier }
+func (r *ConstructionParseRequest) network() *NetworkIdentifier      { return r.NetworkIdentifier }
+func (r *ConstructionCombineRequest) network() *NetworkIdentifier    { return r.NetworkIdentifier }
+func (r *ConstructionHashRequest) network() *NetworkIdentifier       { return r.NetworkIdentifier }
+func (r *ConstructionSubmitRequest) network() *NetworkIdentifier     { return r.NetworkIdentifier }
+
+// handle routes the requests of path to fn, a method taking the chain of
+// their network and the request decoded into its third parameter.
+func (s *Server) handle(path string, fn interface{}) {
+	method := reflect.ValueOf(fn)
+	reqType := method.Type().In(2).Elem()
+
+	s.mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		req := reflect.New(reqType)
+		if err := decodeRequest(r, req.Interface()); err != nil {
+			writeResponse(w, nil, err)
+			return
+		}
+		c, err := s.lookup(req.Interface().(networkRequest).network())
+		if err != nil {
+			writeResponse(w, nil, err)
+			return
+		}
+		out := method.Call([]reflect.Value{reflect.ValueOf(r.Context()), reflect.ValueOf(c), req})
+		resp, _ := out[1].Interface().(*Error)
+		writeResponse(w, out[0].Interface(), resp)
+	})
+}
+
+// networkList lists the networks of the chains.
+func (s *Server) networkList(w http.ResponseWriter, r *http.Request) {
+	if err := decodeRequest(r, new(struct{})); err != nil {
+		writeResponse(w, nil, err)
+		return
+	}
+	s.lock.RLock()
+	resp := &NetworkListResponse{NetworkIdentifiers: make([]*NetworkIdentifier, 0, len(s.chains))}
+	for _, c := range s.chains {
+		resp.NetworkIdentifiers = append(resp.NetworkIdentifiers, c.network)
+	}
+	s.lock.RUnlock()
+
+	sort.Slice(resp.NetworkIdentifiers, func(i, j int) bool {
+		return resp.NetworkIdentifiers[i].Network < resp.NetworkIdentifiers[j].Network
+	})
+	writeResponse(w, resp, nil)
+}
+
+func (s *Server) networkStatus(ctx context.Context, c *chain, req *NetworkRequest) (interface{}, *Error) {
+	genesis, err := c.BlockByNumber(ctx, 0)
+	if err != nil || genesis == nil {
+		return nil, ErrNodeError.wrap("genesis block not found")
+	}
+	current := c.CurrentBlock()
+	return &NetworkStatusResponse{
+		CurrentBlockIdentifier: blockIdentifier(current),
+		CurrentBlockTimestamp:  blockTimestamp(current),
+		GenesisBlockIdentifier: blockIdentifier(genesis),
+		Peers:                  []*Peer{},
+	}, nil
+}
+
+func (s *Server) networkOptions(ctx context.Context, c *chain, req *NetworkRequest) (interface{}, *Error) {
+	return &NetworkOptionsResponse{
+		Version: &Version{RosettaVersion: RosettaVersion, NodeVersion: params.Version},
+		Allow: &Allow{
+			OperationStatuses: []*OperationStatus{
+				{Status: StatusSuccess, Successful: true},
+				{Status: StatusFailure, Successful: false},
+			},
+			OperationTypes:          operationTypes,
+			Errors:                  allErrors,
+			HistoricalBalanceLookup: true,
+		},
+	}, nil
+}
+
+// decodeRequest decodes the JSON body of a POST request into req.
+func decodeRequest(r *http.Request, req interface{}) *Error {
+	if r.Method != http.MethodPost {
+		return ErrInvalidRequest.wrap("method " + r.Method + " not allowed")
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxRequestSize))
+	if err != nil {
+		return ErrInvalidRequest.wrap(err)
+	}
+	if err := json.Unmarshal(body, req); err != nil {
+		return ErrInvalidRequest.wrap(err)
+	}
+	return nil
+}
+
+// writeResponse writes the response, or the error with status 500 as the
+// specification requires.
+func writeResponse(w http.ResponseWriter, resp interface{}, err *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		resp = err
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Debug("Failed to write Rosetta response", "err", err)
+	}
+}
@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neatlab/neatio/log"
+)
+
+// auditLogLimit is the size after which the audit log rotates to a new file.
+const auditLogLimit = 100 * 1024 * 1024
+
+var (
+	auditLog     log.Logger // Records the calls served over HTTP and WebSocket, nil if disabled
+	auditLogLock sync.RWMutex
+)
+
+// SetAuditLog starts recording every call served over HTTP and WebSocket into
+// rotating JSON log files in dir. Parameters are recorded as a hash only, so
+// the log carries no signed transactions or passwords.
+func SetAuditLog(dir string) error {
+	handler, err := log.RotatingFileHandler(dir, auditLogLimit, log.JSONFormat())
+	if err != nil {
+		return err
+	}
+	logger := log.New()
+	logger.SetHandler(handler)
+	setAuditLogger(logger)
+	log.Info("RPC audit log enabled", "dir", dir)
+	return nil
+}
+
+func setAuditLogger(logger log.Logger) {
+	auditLogLock.Lock()
+	defer auditLogLock.Unlock()
+
+	auditLog = logger
+}
+
+func auditLogger() log.Logger {
+	auditLogLock.RLock()
+	defer auditLogLock.RUnlock()
+
+	return auditLog
+}
+
+// auditInfo identifies the origin of the calls of a connection.
+type auditInfo struct {
+	transport string
+	remote    string // IP address of the peer
+	forwarded string // X-Forwarded-For header set by a proxy in front of the node
+	identity  string // Basic auth user or bearer token fingerprint, if any
+}
+
+// auditedCodec attaches the origin of a connection to its codec.
+type auditedCodec struct {
+	ServerCodec
+	info *auditInfo
+}
+
+// withAudit attaches the origin of the request to the codec if the audit log
+// is enabled.
+func withAudit(codec ServerCodec, transport string, r *http.Request) ServerCodec {
+	if auditLogger() == nil {
+		return codec
+	}
+	info := &auditInfo{
+		transport: transport,
+		remote:    r.RemoteAddr,
+		forwarded: r.Header.Get("X-Forwarded-For"),
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		info.remote = host
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		info.identity = user
+	} else if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		hash := sha256.Sum256([]byte(strings.TrimPrefix(auth, "Bearer ")))
+		info.identity = "bearer:" + hex.EncodeToString(hash[:8])
+	}
+	return &auditedCodec{codec, info}
+}
+
+// audit records a served call if the connection is audited.
+func (info *auditInfo) audit(msg *jsonrpcMessage, resp *jsonrpcMessage, start time.Time) {
+	logger := auditLogger()
+	if info == nil || logger == nil {
+		return
+	}
+	ctx := []interface{}{
+		"transport", info.transport,
+		"method", msg.Method,
+		"params", paramsHash(msg.Params),
+		"remote", info.remote,
+		"elapsed", time.Since(start).Seconds(),
+	}
+	if info.forwarded != "" {
+		ctx = append(ctx, "forwarded", info.forwarded)
+	}
+	if info.identity != "" {
+		ctx = append(ctx, "identity", info.identity)
+	}
+	if resp != nil && resp.Error != nil {
+		ctx = append(ctx, "err", resp.Error.Message)
+	}
+	logger.Info("RPC call", ctx...)
+}
+
+// paramsHash fingerprints call parameters, equal parameters have equal hashes.
+func paramsHash(params json.RawMessage) string {
+	if len(params) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256(params)
+	return hex.EncodeToString(hash[:16])
+}
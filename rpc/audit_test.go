@@ -0,0 +1,60 @@
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/neatlab/neatio/log"
+)
+
+func TestAuditLogHTTP(t *testing.T) {
+	var (
+		records []*log.Record
+		mu      sync.Mutex
+	)
+	logger := log.New()
+	logger.SetHandler(log.FuncHandler(func(r *log.Record) error {
+		mu.Lock()
+		defer mu.Unlock()
+		records = append(records, r)
+		return nil
+	}))
+	setAuditLogger(logger)
+	defer setAuditLogger(nil)
+
+	server := newTestServer()
+	defer server.Stop()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["secret",1,{"S":"x"}]}`
+	req := httptest.NewRequest(http.MethodPost, "http://node/", strings.NewReader(body))
+	req.Header.Set("content-type", contentType)
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.SetBasicAuth("alice", "password")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(records) != 1 {
+		t.Fatalf("record count mismatch: have %d, want 1", len(records))
+	}
+	fields := make(map[string]interface{})
+	for i := 0; i+1 < len(records[0].Ctx); i += 2 {
+		fields[records[0].Ctx[i].(string)] = records[0].Ctx[i+1]
+	}
+	for key, want := range map[string]string{
+		"transport": "http",
+		"method":    "test_echo",
+		"remote":    "10.0.0.1",
+		"identity":  "alice",
+	} {
+		if fields[key] != want {
+			t.Errorf("%s mismatch: have %v, want %v", key, fields[key], want)
+		}
+	}
+	if params, _ := fields["params"].(string); params == "" || strings.Contains(params, "secret") {
+		t.Errorf("params not hashed: %v", fields["params"])
+	}
+}
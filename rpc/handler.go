@@ -60,6 +60,7 @@ type handler struct {
 	cancelRoot     func()                         // cancel function for rootCtx
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
+	audit          *auditInfo // origin of the calls, nil if not audited
 	allowSubscribe bool
 
 	subLock    sync.Mutex
@@ -88,6 +89,9 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 	if conn.RemoteAddr() != "" {
 		h.log = h.log.New("conn", conn.RemoteAddr())
 	}
+	if codec, ok := conn.(*auditedCodec); ok {
+		h.audit = codec.info
+	}
 	h.unsubscribeCb = newCallback(reflect.Value{}, reflect.ValueOf(h.unsubscribe))
 	return h
 }
@@ -291,11 +295,13 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	start := time.Now()
 	switch {
 	case msg.isNotification():
-		h.handleCall(ctx, msg)
+		resp := h.handleCall(ctx, msg)
+		h.audit.audit(msg, resp, start)
 		h.log.Debug("Served "+msg.Method, "t", time.Since(start))
 		return nil
 	case msg.isCall():
 		resp := h.handleCall(ctx, msg)
+		h.audit.audit(msg, resp, start)
 		if resp.Error != nil {
 			h.log.Info("Served "+msg.Method, "reqid", idForLog{msg.ID}, "t", time.Since(start), "err", resp.Error.Message)
 		} else {
@@ -330,7 +336,22 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 		return msg.errorResponse(&invalidParamsError{err.Error()})
 	}
 
-	return h.runMethod(cp.ctx, msg, callb, args)
+	start := time.Now()
+	answer := h.runMethod(cp.ctx, msg, callb, args)
+
+	// Collect the statistics for RPC calls if metrics is enabled.
+	// We only care about pure rpc call. Filter out subscription.
+	if callb != h.unsubscribeCb {
+		rpcRequestCounter.Inc(1)
+		if answer.Error != nil {
+			failedRequestCounter.Inc(1)
+		} else {
+			successfulRequestCounter.Inc(1)
+		}
+		rpcServingTimer.UpdateSince(start)
+		newRPCServingTimer(msg.Method, answer.Error == nil).UpdateSince(start)
+	}
+	return answer
 }
 
 // handleSubscribe processes *_subscribe method calls.
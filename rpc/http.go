@@ -264,7 +264,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("content-type", contentType)
-	codec := newHTTPServerConn(r, w)
+	codec := withAudit(newHTTPServerConn(r, w), "http", r)
 	defer codec.Close()
 	s.serveSingleRequest(ctx, codec)
 }
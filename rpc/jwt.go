@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/neatlab/neatio/log"
+)
+
+const (
+	// jwtSecretLength is the size of the JWT secrets generated by the node.
+	jwtSecretLength = 32
+
+	// jwtClockSkew is how far in the future the issuing time of a token may be,
+	// to tolerate clocks slightly off between the issuer and the node.
+	jwtClockSkew = time.Minute
+)
+
+var (
+	errMissingToken   = errors.New("missing bearer token")
+	errMalformedToken = errors.New("malformed token")
+	errTokenAlgorithm = errors.New("token algorithm not HS256")
+	errTokenSignature = errors.New("invalid token signature")
+	errTokenExpiry    = errors.New("token carries no expiry")
+	errTokenExpired   = errors.New("token expired")
+	errTokenNotYet    = errors.New("token issued in the future")
+)
+
+// jwtClaims are the claims of a token the node checks.
+type jwtClaims struct {
+	Exp *int64 `json:"exp"`
+	Iat *int64 `json:"iat"`
+}
+
+// LoadJWTSecret reads the hex encoded JWT secret from path. If the file does
+// not exist, a random secret is generated and written to it.
+func LoadJWTSecret(path string) ([]byte, error) {
+	if data, err := ioutil.ReadFile(path); err == nil {
+		secret, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT secret in %s: %v", path, err)
+		}
+		if len(secret) < jwtSecretLength {
+			return nil, fmt.Errorf("JWT secret in %s shorter than %d bytes", path, jwtSecretLength)
+		}
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	secret := make([]byte, jwtSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
+// jwtHandler lets through the requests carrying a valid token only.
+type jwtHandler struct {
+	secret []byte
+	next   http.Handler
+}
+
+// NewJWTHandler returns a handler passing the requests authenticated with an
+// HS256 signed, unexpired JWT bearer token on to next, and rejecting the others.
+func NewJWTHandler(secret []byte, next http.Handler) http.Handler {
+	return &jwtHandler{secret: secret, next: next}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		http.Error(w, errMissingToken.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err := verifyJWT(h.secret, strings.TrimPrefix(auth, "Bearer "), time.Now()); err != nil {
+		log.Debug("Rejected RPC request", "remote", r.RemoteAddr, "err", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// verifyJWT checks the signature and the validity period of a token.
+func verifyJWT(secret []byte, token string, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errMalformedToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return err
+	}
+	if header.Alg != "HS256" {
+		return errTokenAlgorithm
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errMalformedToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return errTokenSignature
+	}
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return err
+	}
+	if claims.Exp == nil {
+		return errTokenExpiry
+	}
+	if now.Unix() >= *claims.Exp {
+		return errTokenExpired
+	}
+	if claims.Iat != nil && time.Unix(*claims.Iat, 0).After(now.Add(jwtClockSkew)) {
+		return errTokenNotYet
+	}
+	return nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return errMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errMalformedToken
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package rpc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testJWT(secret []byte, alg string, claims string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + alg + `","typ":"JWT"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(claims))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(header + "." + payload))
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	var (
+		secret = bytes.Repeat([]byte{0x42}, 32)
+		now    = time.Unix(1700000000, 0)
+	)
+	tests := []struct {
+		token string
+		err   error
+	}{
+		{testJWT(secret, "HS256", `{"exp":1700000060}`), nil},
+		{testJWT(secret, "HS256", `{"exp":1700000060,"iat":1700000030}`), nil},
+		{testJWT(secret, "HS256", `{"exp":1700000000}`), errTokenExpired},
+		{testJWT(secret, "HS256", `{"iat":1700000000}`), errTokenExpiry},
+		{testJWT(secret, "HS256", `{"exp":1700009999,"iat":1700000600}`), errTokenNotYet},
+		{testJWT(secret, "none", `{"exp":1700000060}`), errTokenAlgorithm},
+		{testJWT([]byte("other"), "HS256", `{"exp":1700000060}`), errTokenSignature},
+		{"not.a-token", errMalformedToken},
+	}
+	for i, test := range tests {
+		if err := verifyJWT(secret, test.token, now); err != test.err {
+			t.Errorf("test %d: have error %v, want %v", i, err, test.err)
+		}
+	}
+}
+
+func TestJWTHandler(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x42}, 32)
+	handler := NewJWTHandler(secret, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	exp := time.Now().Add(time.Minute).Unix()
+	for _, test := range []struct {
+		auth string
+		code int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer " + testJWT(secret, "HS256", `{"exp":1}`), http.StatusUnauthorized},
+		{"Bearer " + testJWT(secret, "HS256", `{"exp":`+strconv.FormatInt(exp, 10)+`}`), http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.code {
+			t.Errorf("auth %q: have status %d, want %d", test.auth, rec.Code, test.code)
+		}
+	}
+}
+
+func TestLoadJWTSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "sub", "jwtsecret")
+	secret, err := LoadJWTSecret(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != jwtSecretLength {
+		t.Fatalf("generated secret length %d, want %d", len(secret), jwtSecretLength)
+	}
+	loaded, err := LoadJWTSecret(path)
+	if err != nil || !bytes.Equal(loaded, secret) {
+		t.Fatalf("reloaded secret mismatch: %x, %v", loaded, err)
+	}
+	if err := ioutil.WriteFile(path, []byte("0x1234"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadJWTSecret(path); err == nil {
+		t.Fatal("short secret accepted")
+	}
+}
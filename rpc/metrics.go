@@ -0,0 +1,24 @@
+package rpc
+
+import (
+	"fmt"
+
+	"github.com/neatlab/neatio/metrics"
+)
+
+var (
+	rpcRequestCounter        = metrics.NewRegisteredCounter("rpc/requests", nil)
+	successfulRequestCounter = metrics.NewRegisteredCounter("rpc/success", nil)
+	failedRequestCounter     = metrics.NewRegisteredCounter("rpc/failure", nil)
+	rpcServingTimer          = metrics.NewRegisteredTimer("rpc/duration/all", nil)
+)
+
+// newRPCServingTimer returns the timer tracking the serving time of a method,
+// separately for successful and failed calls.
+func newRPCServingTimer(method string, valid bool) metrics.Timer {
+	flag := "success"
+	if !valid {
+		flag = "failure"
+	}
+	return metrics.GetOrRegisterTimer(fmt.Sprintf("rpc/duration/%s/%s", method, flag), nil)
+}
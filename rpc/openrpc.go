@@ -0,0 +1,228 @@
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// OpenRPCVersion is the version of the OpenRPC specification of the documents
+// returned by rpc_discover.
+const OpenRPCVersion = "1.2.6"
+
+var (
+	bigIntType          = reflect.TypeOf(big.Int{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// OpenRPCDocument describes the methods of a server, see https://spec.open-rpc.org.
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []*OpenRPCMethod  `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+}
+
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type OpenRPCMethod struct {
+	Name   string               `json:"name"`
+	Params []*OpenRPCDescriptor `json:"params"`
+	Result *OpenRPCDescriptor   `json:"result,omitempty"`
+}
+
+// OpenRPCDescriptor describes a parameter or the result of a method.
+type OpenRPCDescriptor struct {
+	Name     string         `json:"name"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenRPCSchema `json:"schema"`
+}
+
+type OpenRPCComponents struct {
+	Schemas map[string]*OpenRPCSchema `json:"schemas"`
+}
+
+// OpenRPCSchema is the JSON schema of a value.
+type OpenRPCSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Title                string                    `json:"title,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *OpenRPCSchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenRPCSchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *OpenRPCSchema            `json:"additionalProperties,omitempty"`
+}
+
+// Discover returns the OpenRPC document of the methods and subscriptions of
+// the server. The parameters are named after their position, the names of the
+// Go parameters are not known.
+func (s *RPCService) Discover() *OpenRPCDocument {
+	s.server.services.mu.Lock()
+	defer s.server.services.mu.Unlock()
+
+	b := &schemaBuilder{schemas: make(map[string]*OpenRPCSchema), names: make(map[reflect.Type]string)}
+	doc := &OpenRPCDocument{
+		OpenRPC:    OpenRPCVersion,
+		Info:       OpenRPCInfo{Title: "Neatio JSON-RPC API", Version: "1.0"},
+		Methods:    []*OpenRPCMethod{},
+		Components: OpenRPCComponents{Schemas: b.schemas},
+	}
+	for name, svc := range s.server.services.services {
+		for method, cb := range svc.callbacks {
+			doc.Methods = append(doc.Methods, b.method(name+serviceMethodSeparator+method, cb))
+		}
+		// The subscriptions are made by the subscribe method of the service,
+		// named by its first parameter
+		if len(svc.subscriptions) > 0 {
+			names := make([]string, 0, len(svc.subscriptions))
+			for sub := range svc.subscriptions {
+				names = append(names, sub)
+			}
+			sort.Strings(names)
+			doc.Methods = append(doc.Methods, &OpenRPCMethod{
+				Name: name + subscribeMethodSuffix,
+				Params: []*OpenRPCDescriptor{
+					{Name: "subscription", Required: true, Schema: &OpenRPCSchema{Type: "string", Enum: names}},
+					{Name: "params", Schema: &OpenRPCSchema{}},
+				},
+				Result: &OpenRPCDescriptor{Name: "id", Schema: &OpenRPCSchema{Type: "string"}},
+			}, &OpenRPCMethod{
+				Name:   name + unsubscribeMethodSuffix,
+				Params: []*OpenRPCDescriptor{{Name: "id", Required: true, Schema: &OpenRPCSchema{Type: "string"}}},
+				Result: &OpenRPCDescriptor{Name: "result", Schema: &OpenRPCSchema{Type: "boolean"}},
+			})
+		}
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool { return doc.Methods[i].Name < doc.Methods[j].Name })
+	return doc
+}
+
+// schemaBuilder builds the schemas of the Go types, the named structs are
+// shared as components.
+type schemaBuilder struct {
+	schemas map[string]*OpenRPCSchema
+	names   map[reflect.Type]string
+}
+
+func (b *schemaBuilder) method(name string, cb *callback) *OpenRPCMethod {
+	m := &OpenRPCMethod{Name: name, Params: make([]*OpenRPCDescriptor, len(cb.argTypes))}
+	for i, t := range cb.argTypes {
+		m.Params[i] = &OpenRPCDescriptor{Name: fmt.Sprintf("arg%d", i), Schema: b.schema(t)}
+	}
+	// The trailing pointers may be omitted, see parsePositionalArguments
+	for i := len(cb.argTypes) - 1; i >= 0; i-- {
+		if cb.argTypes[i].Kind() == reflect.Ptr {
+			continue
+		}
+		for j := 0; j <= i; j++ {
+			m.Params[j].Required = true
+		}
+		break
+	}
+	if fntype := cb.fn.Type(); fntype.NumOut() > 0 && cb.errPos != 0 {
+		m.Result = &OpenRPCDescriptor{Name: "result", Schema: b.schema(fntype.Out(0))}
+	}
+	return m
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) *OpenRPCSchema {
+	if t.Kind() == reflect.Ptr {
+		return b.schema(t.Elem())
+	}
+	// The types encoding themselves are strings if they encode as text
+	ptr := reflect.PtrTo(t)
+	switch {
+	case t == bigIntType:
+		return &OpenRPCSchema{Type: "integer"}
+	case ptr.Implements(textMarshalerType) || ptr.Implements(textUnmarshalerType):
+		return &OpenRPCSchema{Title: t.String(), Type: "string"}
+	case ptr.Implements(jsonMarshalerType) || ptr.Implements(jsonUnmarshalerType):
+		return &OpenRPCSchema{Title: t.String()}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenRPCSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &OpenRPCSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &OpenRPCSchema{Type: "number"}
+	case reflect.String:
+		return &OpenRPCSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &OpenRPCSchema{Type: "string"} // Base64
+		}
+		return &OpenRPCSchema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &OpenRPCSchema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name, ok := b.names[t]
+		if !ok {
+			name = strings.Replace(t.String(), " ", "", -1)
+			b.names[t] = name
+			b.schemas[name] = b.object(t)
+		}
+		return &OpenRPCSchema{Ref: "#/components/schemas/" + name}
+	}
+	return &OpenRPCSchema{}
+}
+
+// object returns the schema of a struct, with the fields encoded by
+// encoding/json.
+func (b *schemaBuilder) object(t reflect.Type) *OpenRPCSchema {
+	s := &OpenRPCSchema{Type: "object", Properties: make(map[string]*OpenRPCSchema)}
+	b.fields(s, t)
+	sort.Strings(s.Required)
+	return s
+}
+
+func (b *schemaBuilder) fields(s *OpenRPCSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		// The fields of embedded structs are promoted
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.fields(s, ft)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // Not exported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = b.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
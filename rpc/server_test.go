@@ -150,3 +150,45 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+func TestServerDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenRPC != OpenRPCVersion {
+		t.Errorf("openrpc %q, want %q", doc.OpenRPC, OpenRPCVersion)
+	}
+	methods := make(map[string]*OpenRPCMethod)
+	for _, m := range doc.Methods {
+		methods[m.Name] = m
+	}
+	for _, name := range []string{"rpc_modules", "rpc_discover", "test_echo", "nftest_subscribe", "nftest_unsubscribe"} {
+		if methods[name] == nil {
+			t.Errorf("method %s not described", name)
+		}
+	}
+
+	echo := methods["test_echo"]
+	if len(echo.Params) != 3 || !echo.Params[0].Required || !echo.Params[1].Required || echo.Params[2].Required {
+		t.Fatalf("unexpected params of test_echo: %+v", echo.Params)
+	}
+	if echo.Params[0].Schema.Type != "string" || echo.Params[1].Schema.Type != "integer" {
+		t.Errorf("unexpected param schemas of test_echo: %+v %+v", echo.Params[0].Schema, echo.Params[1].Schema)
+	}
+	if ref := echo.Result.Schema.Ref; ref != "#/components/schemas/rpc.Result" {
+		t.Fatalf("result of test_echo refers to %q", ref)
+	}
+	result := doc.Components.Schemas["rpc.Result"]
+	if result == nil || result.Properties["String"].Type != "string" || result.Properties["Args"].Ref != "#/components/schemas/rpc.Args" {
+		t.Errorf("unexpected schema of rpc.Result: %+v", result)
+	}
+	if methods["test_noArgsRets"].Result != nil {
+		t.Error("test_noArgsRets has a result")
+	}
+}
@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+)
+
+// TLSConfig holds the files the HTTP and WebSocket endpoints are served over
+// TLS with.
+type TLSConfig struct {
+	CertFile     string // PEM encoded certificate chain of the server
+	KeyFile      string // PEM encoded private key of the server
+	ClientCAFile string // PEM encoded CAs client certificates must be signed by, none required if empty
+}
+
+// Enabled reports whether the endpoints are to be served over TLS.
+func (c *TLSConfig) Enabled() bool {
+	return c != nil && (c.CertFile != "" || c.KeyFile != "")
+}
+
+// ServerConfig loads the certificates and returns the TLS configuration of the
+// server. If a client CA is set, clients have to present a certificate signed
+// by it.
+func (c *TLSConfig) ServerConfig() (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, errors.New("both the TLS certificate and key are required")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS client CA: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in TLS client CA %s", c.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// Listen opens a TCP listener on endpoint, serving TLS if enabled.
+func (c *TLSConfig) Listen(endpoint string) (net.Listener, error) {
+	var config *tls.Config
+	if c.Enabled() {
+		var err error
+		if config, err = c.ServerConfig(); err != nil {
+			return nil, err
+		}
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if config != nil {
+		listener = tls.NewListener(listener, config)
+	}
+	return listener, nil
+}
+
+// Scheme returns the URL scheme of an endpoint, the secure variant of the
+// given plain one if TLS is enabled.
+func (c *TLSConfig) Scheme(plain string) string {
+	if c.Enabled() {
+		return plain + "s"
+	}
+	return plain
+}
@@ -61,7 +61,7 @@ func (s *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 	return websocket.Server{
 		Handshake: wsHandshakeValidator(allowedOrigins),
 		Handler: func(conn *websocket.Conn) {
-			codec := newWebsocketCodec(conn)
+			codec := withAudit(newWebsocketCodec(conn), "ws", conn.Request())
 			s.ServeCodec(codec, OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
@@ -572,96 +572,119 @@ func (db *Database) dereference(side common.Hash, parent common.Hash) {
 	}
 }
 
+// capBatchSize is the size of the batches written by Cap. The flushes running
+// in the background, their batches are larger than the ideal ones to write the
+// nodes in fewer and larger database writes.
+const capBatchSize = 4 * neatdb.IdealBatchSize
+
+// flushedNode is a node gathered by Cap to be written to disk.
+type flushedNode struct {
+	hash common.Hash
+	rlp  []byte
+}
+
 // Cap iteratively flushes old but still referenced trie nodes until the total
 // memory usage goes below the given threshold.
 //
-// Note, this method is a non-synchronized mutator. It is unsafe to call this
-// concurrently with other mutators.
+// The nodes to flush are gathered under the read lock and only uncached under
+// the write lock once written, so Cap can run in the background while other
+// tries are inserted, referenced and dereferenced. It is unsafe to call it
+// concurrently with Commit or with another Cap.
 func (db *Database) Cap(limit common.StorageSize) error {
-	// Create a database batch to flush persistent data out. It is important that
-	// outside code doesn't see an inconsistent state (referenced data removed from
-	// memory cache during commit but not yet in persistent storage). This is ensured
-	// by only uncaching existing data when the database write finalizes.
-	nodes, storage, start := len(db.dirties), db.dirtiesSize, time.Now()
-	batch := db.diskdb.NewBatch()
+	start := time.Now()
 
+	// Gather the data to flush. It is important that outside code doesn't see an
+	// inconsistent state (referenced data removed from memory cache during commit
+	// but not yet in persistent storage). This is ensured by only uncaching
+	// existing data when the database write finalizes.
+	db.lock.RLock()
+
+	// If the preimage cache got large enough, push to disk. If it's still small
+	// leave for later to deduplicate writes.
+	var preimages map[common.Hash][]byte
+	if db.preimagesSize > 4*1024*1024 {
+		preimages = make(map[common.Hash][]byte, len(db.preimages))
+		for hash, preimage := range db.preimages {
+			preimages[hash] = preimage
+		}
+	}
 	// db.dirtiesSize only contains the useful data in the cache, but when reporting
 	// the total memory consumption, the maintenance metadata is also needed to be
 	// counted. For every useful node, we track 2 extra hashes as the flushlist.
 	size := db.dirtiesSize + common.StorageSize((len(db.dirties)-1)*2*common.HashLength)
 
-	// If the preimage cache got large enough, push to disk. If it's still small
-	// leave for later to deduplicate writes.
-	flushPreimages := db.preimagesSize > 4*1024*1024
-	if flushPreimages {
-		for hash, preimage := range db.preimages {
-			if err := batch.Put(db.secureKey(hash[:]), preimage); err != nil {
-				log.Error("Failed to commit preimage from trie database", "err", err)
+	// Gather the nodes from the flush-list until we're below allowance
+	var (
+		nodes   []flushedNode
+		storage common.StorageSize
+	)
+	for oldest := db.oldest; size > limit && oldest != (common.Hash{}); {
+		node := db.dirties[oldest]
+		nodes = append(nodes, flushedNode{hash: oldest, rlp: node.rlp()})
+		storage += common.StorageSize(common.HashLength + int(node.size))
+
+		// Size is the total size, including both the useful cached data (hash -> blob),
+		// as well as the flushlist metadata (2*hash). When flushing items from the
+		// cache, we need to reduce both.
+		size -= common.StorageSize(3*common.HashLength + int(node.size))
+		oldest = node.flushNext
+	}
+	db.lock.RUnlock()
+
+	// Write the preimages and the nodes without holding the lock
+	batch := db.diskdb.NewBatch()
+	for hash, preimage := range preimages {
+		if err := batch.Put(db.secureKey(hash[:]), preimage); err != nil {
+			log.Error("Failed to commit preimage from trie database", "err", err)
+			return err
+		}
+		if batch.ValueSize() >= capBatchSize {
+			if err := batch.Write(); err != nil {
 				return err
 			}
-			if batch.ValueSize() > neatdb.IdealBatchSize {
-				if err := batch.Write(); err != nil {
-					return err
-				}
-				batch.Reset()
-			}
+			batch.Reset()
 		}
 	}
-	// Keep committing nodes from the flush-list until we're below allowance
-	oldest := db.oldest
-	for size > limit && oldest != (common.Hash{}) {
-		// Fetch the oldest referenced node and push into the batch
-		node := db.dirties[oldest]
-		if err := batch.Put(oldest[:], node.rlp()); err != nil {
+	for _, node := range nodes {
+		if err := batch.Put(node.hash[:], node.rlp); err != nil {
 			return err
 		}
-		// If we exceeded the ideal batch size, commit and reset
-		if batch.ValueSize() >= neatdb.IdealBatchSize {
+		// If we exceeded the batch size, commit and reset
+		if batch.ValueSize() >= capBatchSize {
 			if err := batch.Write(); err != nil {
 				log.Error("Failed to write flush list to disk", "err", err)
 				return err
 			}
 			batch.Reset()
 		}
-		// Iterate to the next flush item, or abort if the size cap was achieved. Size
-		// is the total size, including both the useful cached data (hash -> blob), as
-		// well as the flushlist metadata (2*hash). When flushing items from the cache,
-		// we need to reduce both.
-		size -= common.StorageSize(3*common.HashLength + int(node.size))
-		oldest = node.flushNext
 	}
 	// Flush out any remainder data from the last batch
 	if err := batch.Write(); err != nil {
 		log.Error("Failed to write flush list to disk", "err", err)
 		return err
 	}
-	// Write successful, clear out the flushed data
+	// Write successful, clear out the flushed data. The nodes dereferenced since
+	// were gathered are already gone.
 	db.lock.Lock()
 	defer db.lock.Unlock()
 
-	if flushPreimages {
-		db.preimages = make(map[common.Hash][]byte)
-		db.preimagesSize = 0
+	for hash, preimage := range preimages {
+		delete(db.preimages, hash)
+		db.preimagesSize -= common.StorageSize(common.HashLength + len(preimage))
 	}
-	for db.oldest != oldest {
-		node := db.dirties[db.oldest]
-		delete(db.dirties, db.oldest)
-		db.oldest = node.flushNext
-
-		db.dirtiesSize -= common.StorageSize(common.HashLength + int(node.size))
-	}
-	if db.oldest != (common.Hash{}) {
-		db.dirties[db.oldest].flushPrev = common.Hash{}
+	uncacher := &cleaner{db}
+	for _, node := range nodes {
+		uncacher.Put(node.hash[:], node.rlp)
 	}
-	db.flushnodes += uint64(nodes - len(db.dirties))
-	db.flushsize += storage - db.dirtiesSize
+	db.flushnodes += uint64(len(nodes))
+	db.flushsize += storage
 	db.flushtime += time.Since(start)
 
 	memcacheFlushTimeTimer.Update(time.Since(start))
-	memcacheFlushSizeMeter.Mark(int64(storage - db.dirtiesSize))
-	memcacheFlushNodesMeter.Mark(int64(nodes - len(db.dirties)))
+	memcacheFlushSizeMeter.Mark(int64(storage))
+	memcacheFlushNodesMeter.Mark(int64(len(nodes)))
 
-	log.Debug("Persisted nodes from memory database", "nodes", nodes-len(db.dirties), "size", storage-db.dirtiesSize, "time", time.Since(start),
+	log.Debug("Persisted nodes from memory database", "nodes", len(nodes), "size", storage, "time", time.Since(start),
 		"flushnodes", db.flushnodes, "flushsize", db.flushsize, "flushtime", db.flushtime, "livenodes", len(db.dirties), "livesize", db.dirtiesSize)
 
 	return nil
@@ -671,8 +694,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 // to disk, forcefully tearing down all references in both directions. As a side
 // effect, all pre-images accumulated up to this point are also written.
 //
-// Note, this method is a non-synchronized mutator. It is unsafe to call this
-// concurrently with other mutators.
+// Note, the nodes are looked up under the read lock, so other tries can be
+// inserted while the commit runs. It is unsafe to call it concurrently with
+// the other mutators.
 func (db *Database) Commit(node common.Hash, report bool) error {
 	// Create a database batch to flush persistent data out. It is important that
 	// outside code doesn't see an inconsistent state (referenced data removed from
@@ -682,7 +706,14 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 	batch := db.diskdb.NewBatch()
 
 	// Move all of the accumulated preimages into a write batch
+	db.lock.RLock()
+	preimages := make(map[common.Hash][]byte, len(db.preimages))
 	for hash, preimage := range db.preimages {
+		preimages[hash] = preimage
+	}
+	db.lock.RUnlock()
+
+	for hash, preimage := range preimages {
 		if err := batch.Put(db.secureKey(hash[:]), preimage); err != nil {
 			log.Error("Failed to commit preimage from trie database", "err", err)
 			return err
@@ -703,7 +734,9 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 	batch.Reset()
 
 	// Move the trie itself into the batch, flushing if enough data is accumulated
+	db.lock.RLock()
 	nodes, storage := len(db.dirties), db.dirtiesSize
+	db.lock.RUnlock()
 
 	uncacher := &cleaner{db}
 	if err := db.commit(node, batch, uncacher); err != nil {
@@ -723,8 +756,10 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 	batch.Reset()
 
 	// Reset the storage counters and bumpd metrics
-	db.preimages = make(map[common.Hash][]byte)
-	db.preimagesSize = 0
+	for hash, preimage := range preimages {
+		delete(db.preimages, hash)
+		db.preimagesSize -= common.StorageSize(common.HashLength + len(preimage))
+	}
 
 	memcacheCommitTimeTimer.Update(time.Since(start))
 	memcacheCommitSizeMeter.Mark(int64(storage - db.dirtiesSize))
@@ -747,7 +782,9 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 // commit is the private locked version of Commit.
 func (db *Database) commit(hash common.Hash, batch neatdb.Batch, uncacher *cleaner) error {
 	// If the node does not exist, it's a previously committed node
+	db.lock.RLock()
 	node, ok := db.dirties[hash]
+	db.lock.RUnlock()
 	if !ok {
 		return nil
 	}
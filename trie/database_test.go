@@ -17,6 +17,7 @@
 package trie
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/neatlab/neatio/common"
@@ -31,3 +32,46 @@ func TestDatabaseMetarootFetch(t *testing.T) {
 		t.Fatalf("metaroot retrieval succeeded")
 	}
 }
+
+// Tests that the nodes flushed by Cap running in the background of the commit
+// of another trie are persisted and uncached, the other trie staying intact.
+func TestDatabaseConcurrentCap(t *testing.T) {
+	triedb, trie, content := makeTestTrie()
+	root := trie.Hash()
+	triedb.Reference(root, common.Hash{})
+
+	done := make(chan error)
+	go func() {
+		done <- triedb.Cap(0)
+	}()
+	other, _ := New(common.Hash{}, triedb)
+	for i := byte(0); i < 255; i++ {
+		other.Update(common.LeftPadBytes([]byte{13, i}, 32), []byte{13, i})
+	}
+	otherRoot, _ := other.Commit(nil)
+	triedb.Reference(otherRoot, common.Hash{})
+
+	if err := <-done; err != nil {
+		t.Fatalf("failed to cap the trie database: %v", err)
+	}
+	for _, hash := range triedb.Nodes() {
+		if hash == root {
+			t.Fatalf("root of the flushed trie still dirty")
+		}
+	}
+	diskdb := NewDatabase(triedb.diskdb)
+	checkTrieContents(t, diskdb, root[:], content)
+
+	if err := triedb.Commit(otherRoot, false); err != nil {
+		t.Fatalf("failed to commit the other trie: %v", err)
+	}
+	reopened, err := New(otherRoot, diskdb)
+	if err != nil {
+		t.Fatalf("failed to open the other trie: %v", err)
+	}
+	for i := byte(0); i < 255; i++ {
+		if val := reopened.Get(common.LeftPadBytes([]byte{13, i}, 32)); !bytes.Equal(val, []byte{13, i}) {
+			t.Fatalf("value %d of the other trie mismatch: %x", i, val)
+		}
+	}
+}